@@ -0,0 +1,295 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// usageError is returned for a browser command with missing or invalid
+// arguments.
+type usageError struct {
+	usage  string // the command and its arguments
+	reason string // what is wrong, if more than a missing argument
+}
+
+func (e *usageError) Error() string {
+	if e.reason != "" {
+		return fmt.Sprintf("%s (usage: %s)", e.reason, e.usage)
+	}
+	return "usage: " + e.usage
+}
+
+// errUnknownCommand is returned by runCommand for a command it does not know.
+var errUnknownCommand = errors.New("unknown command")
+
+// runCommand runs one browser command, given as its name and arguments the
+// way the command line, interactive mode, and scripts all take them.
+func runCommand(ctx context.Context, cs *mcp.ClientSession, args []string) error {
+	command := args[0]
+	// need checks the number of arguments of command.
+	need := func(min, max int, usage string) error {
+		if len(args)-1 < min {
+			return &usageError{usage: usage}
+		}
+		if len(args)-1 > max {
+			return &usageError{usage: usage, reason: "too many arguments; quote arguments that contain spaces"}
+		}
+		return nil
+	}
+	// boolArg parses the optional boolean argument i, or returns def.
+	boolArg := func(i int, def bool, usage string) (bool, error) {
+		if len(args) <= i {
+			return def, nil
+		}
+		b, err := strconv.ParseBool(args[i])
+		if err != nil {
+			return false, &usageError{usage: usage, reason: fmt.Sprintf("invalid boolean value %q", args[i])}
+		}
+		return b, nil
+	}
+
+	switch command {
+	case "navigate":
+		if err := need(1, 1, "navigate <url>"); err != nil {
+			return err
+		}
+		return navigate(ctx, cs, args[1])
+
+	case "click":
+		if err := need(1, 1, "click <css-selector>"); err != nil {
+			return err
+		}
+		return click(ctx, cs, args[1])
+
+	case "screenshot":
+		if err := need(0, 0, "screenshot"); err != nil {
+			return err
+		}
+		return screenshot(ctx, cs)
+
+	case "aria-snapshot":
+		const usage = "aria-snapshot [format] [focus] [--only section] [--grep regexp] [--max n] [--format table|json|md]"
+		rest, filter, err := parseSnapshotFilter(args[1:])
+		if err != nil {
+			return &usageError{usage: usage, reason: err.Error()}
+		}
+		args = append([]string{command}, rest...)
+		if err := need(0, 2, usage); err != nil {
+			return err
+		}
+		format := "llm-text"
+		focus := "all"
+		if len(args) > 1 {
+			format = args[1]
+		}
+		if len(args) > 2 {
+			focus = args[2]
+		}
+		if filter != nil {
+			return filteredAriaSnapshot(ctx, cs, format, focus, filter)
+		}
+		return ariaSnapshot(ctx, cs, format, focus)
+
+	case "save-snapshot":
+		if err := need(1, 1, "save-snapshot <file>"); err != nil {
+			return err
+		}
+		return saveSnapshot(ctx, cs, args[1])
+
+	case "snapshot-diff":
+		if err := need(2, 2, "snapshot-diff <before.json> <after.json>"); err != nil {
+			return err
+		}
+		return snapshotDiff(args[1], args[2])
+
+	case "diff-since-last":
+		if err := need(0, 0, "diff-since-last"); err != nil {
+			return err
+		}
+		return diffSinceLast(ctx, cs)
+
+	case "type-text":
+		const usage = "type-text <selector> <text> [clear]"
+		if err := need(2, 3, usage); err != nil {
+			return err
+		}
+		clear, err := boolArg(3, false, usage)
+		if err != nil {
+			return err
+		}
+		return typeText(ctx, cs, args[1], args[2], clear)
+
+	case "click-button":
+		if err := need(1, 1, "click-button <selector>"); err != nil {
+			return err
+		}
+		return clickButton(ctx, cs, args[1])
+
+	case "click-link":
+		if err := need(1, 1, "click-link <selector>"); err != nil {
+			return err
+		}
+		return clickLink(ctx, cs, args[1])
+
+	case "select-dropdown":
+		if err := need(2, 2, "select-dropdown <selector> <value>"); err != nil {
+			return err
+		}
+		return selectDropdown(ctx, cs, args[1], args[2])
+
+	case "choose-option":
+		const usage = "choose-option <selector> [checked]"
+		if err := need(1, 2, usage); err != nil {
+			return err
+		}
+		checked, err := boolArg(2, true, usage)
+		if err != nil {
+			return err
+		}
+		return chooseOption(ctx, cs, args[1], checked)
+
+	case "refresh":
+		if err := need(0, 0, "refresh"); err != nil {
+			return err
+		}
+		return refreshPage(ctx, cs)
+
+	case "close":
+		if err := need(0, 0, "close"); err != nil {
+			return err
+		}
+		return closeBrowser(ctx, cs)
+
+	case "lifecycle":
+		const usage = "lifecycle <true|false>"
+		if err := need(1, 1, usage); err != nil {
+			return err
+		}
+		keepOpen, err := boolArg(1, false, usage)
+		if err != nil {
+			return err
+		}
+		return setLifecycle(ctx, cs, keepOpen)
+
+	case "list-tools":
+		if err := need(0, 0, "list-tools"); err != nil {
+			return err
+		}
+		return listTools(ctx, cs)
+
+	case "list-resources":
+		if err := need(0, 0, "list-resources"); err != nil {
+			return err
+		}
+		return listResources(ctx, cs)
+
+	case "read-resource":
+		if err := need(1, 1, "read-resource <uri>"); err != nil {
+			return err
+		}
+		return readResource(ctx, cs, args[1])
+
+	case "list-prompts":
+		if err := need(0, 0, "list-prompts"); err != nil {
+			return err
+		}
+		return listPrompts(ctx, cs)
+
+	case "get-prompt":
+		const usage = "get-prompt <name> [argument=value]..."
+		if err := need(1, len(args)-1, usage); err != nil {
+			return err
+		}
+		arguments, err := promptArguments(args[2:])
+		if err != nil {
+			return &usageError{usage: usage, reason: err.Error()}
+		}
+		return getPrompt(ctx, cs, args[1], arguments)
+
+	case "assert-text":
+		if err := need(1, 2, "assert-text <text> [selector]"); err != nil {
+			return err
+		}
+		selector := ""
+		if len(args) > 2 {
+			selector = args[2]
+		}
+		return assertText(ctx, cs, args[1], selector)
+
+	case "assert-url":
+		if err := need(1, 1, "assert-url <pattern>"); err != nil {
+			return err
+		}
+		return assertURL(ctx, cs, args[1])
+
+	case "assert-visible":
+		if err := need(1, 1, "assert-visible <selector>"); err != nil {
+			return err
+		}
+		return assertVisible(ctx, cs, args[1])
+
+	case "call":
+		if err := need(1, 2, "call <tool> [json-arguments]"); err != nil {
+			return err
+		}
+		arguments := ""
+		if len(args) > 2 {
+			arguments = args[2]
+		}
+		return callTool(ctx, cs, args[1], arguments)
+
+	case "watch":
+		const usage = "watch [on [level] | off]"
+		if err := need(0, 2, usage); err != nil {
+			return err
+		}
+		if len(args) > 1 && args[1] == "off" {
+			if len(args) > 2 {
+				return &usageError{usage: usage}
+			}
+			return notifications.stop(ctx, cs)
+		}
+		if len(args) > 1 && args[1] != "on" {
+			return &usageError{usage: usage, reason: fmt.Sprintf("unknown watch mode %q", args[1])}
+		}
+		level := mcp.LoggingLevel("info")
+		if len(args) > 2 {
+			var ok bool
+			if level, ok = parseLoggingLevel(args[2]); !ok {
+				return &usageError{usage: usage, reason: fmt.Sprintf("unknown log level %q", args[2])}
+			}
+		}
+		notifications.start(ctx, cs, level)
+		return nil
+
+	case "wait":
+		// Waits for a number of seconds between actions.
+		const usage = "wait <seconds>"
+		if err := need(1, 1, usage); err != nil {
+			return err
+		}
+		seconds, err := strconv.Atoi(args[1])
+		if err != nil || seconds < 0 {
+			return &usageError{usage: usage, reason: fmt.Sprintf("invalid wait duration %q", args[1])}
+		}
+		fmt.Printf("Waiting %d seconds...\n", seconds)
+		select {
+		case <-time.After(time.Duration(seconds) * time.Second):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+	default:
+		return fmt.Errorf("%w %q", errUnknownCommand, command)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// socketPath is the Unix socket the daemon listens on.
+var socketPath = flag.String("socket", defaultSocketPath(), "talk to the daemon on the Unix socket at `path`")
+
+// daemonStartTimeout is how long daemon start waits for the server to
+// launch the browser and listen.
+const daemonStartTimeout = time.Minute
+
+// daemonStopTimeout is how long daemon stop waits for the server to finish
+// its tool calls in flight and exit.
+const daemonStopTimeout = 45 * time.Second
+
+// defaultSocketPath returns the socket of the user's daemon in the temporary
+// directory.
+func defaultSocketPath() string {
+	name := "cdpbrowser-client.sock"
+	if uid := os.Getuid(); uid >= 0 {
+		name = fmt.Sprintf("cdpbrowser-client-%d.sock", uid)
+	}
+	return filepath.Join(os.TempDir(), name)
+}
+
+// daemonLogFile returns the file the daemon on socket logs to.
+func daemonLogFile(socket string) string {
+	return strings.TrimSuffix(socket, filepath.Ext(socket)) + ".log"
+}
+
+// daemonRunning reports whether a daemon is listening on socket.
+func daemonRunning(socket string) bool {
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// daemonTransport returns a transport to the daemon on socket, which serves
+// MCP over streamable HTTP.
+func daemonTransport(socket string) mcp.Transport {
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	// The host is not used: every request goes to the socket.
+	return mcp.NewStreamableClientTransport("http://cdpbrowser/", &mcp.StreamableClientTransportOptions{HTTPClient: client})
+}
+
+// runDaemonCommand runs daemon start, stop, or status and returns the exit
+// code.
+func runDaemonCommand(ctx context.Context, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--socket path] daemon start|stop|status\n", os.Args[0])
+		return 1
+	}
+	var err error
+	switch args[0] {
+	case "start":
+		err = startDaemon(*socketPath)
+	case "stop":
+		err = stopDaemon(ctx, *socketPath)
+	case "status":
+		if !daemonRunning(*socketPath) {
+			fmt.Printf("No daemon is running at %s\n", *socketPath)
+			return 1
+		}
+		fmt.Printf("The daemon is running at %s, logging to %s\n", *socketPath, daemonLogFile(*socketPath))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown daemon command: %s (use start, stop, or status)\n", args[0])
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// startDaemon starts a server in the background that listens on socket,
+// and waits until it does. The server keeps running, with its browser,
+// after the client exits.
+func startDaemon(socket string) error {
+	if daemonRunning(socket) {
+		return fmt.Errorf("a daemon is already running at %s", socket)
+	}
+	logFile := daemonLogFile(socket)
+	logOut, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the daemon log: %w", err)
+	}
+	defer logOut.Close()
+
+	cmd := serverCommand("-listen", socket)
+	// The browser belongs to the daemon, and goes with it.
+	cmd.Env = append(os.Environ(), "CLOSE_CHROME_ON_EXIT=true")
+	cmd.Stdout = logOut
+	cmd.Stderr = logOut
+	fmt.Printf("Starting cdpbrowser server: %s\n", cmd.Path)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start the server: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.After(daemonStartTimeout)
+	for !daemonRunning(socket) {
+		select {
+		case err := <-exited:
+			return fmt.Errorf("the server exited (%v); see %s", err, logFile)
+		case <-deadline:
+			cmd.Process.Kill()
+			return fmt.Errorf("the server did not listen on %s within %v; see %s", socket, daemonStartTimeout, logFile)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	fmt.Printf("Daemon started (pid %d) at %s, logging to %s\n", cmd.Process.Pid, socket, logFile)
+	fmt.Printf("Commands now use its browser until '%s daemon stop'.\n", os.Args[0])
+	return nil
+}
+
+// stopDaemon shuts down the daemon on socket with the shutdown_server tool,
+// and waits until it no longer listens.
+func stopDaemon(ctx context.Context, socket string) error {
+	if !daemonRunning(socket) {
+		return fmt.Errorf("no daemon is running at %s", socket)
+	}
+	cs, err := connectTransport(ctx, daemonTransport(socket))
+	if err != nil {
+		return fmt.Errorf("failed to connect to the daemon: %w", err)
+	}
+	result, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "shutdown_server", Arguments: map[string]any{}})
+	cs.Close()
+	if err := toolResult(ctx, "shutdown_server", result, err); err != nil {
+		return err
+	}
+	for start := time.Now(); daemonRunning(socket); time.Sleep(200 * time.Millisecond) {
+		if time.Since(start) > daemonStopTimeout {
+			return fmt.Errorf("the daemon is still running after %v; see %s", daemonStopTimeout, daemonLogFile(socket))
+		}
+	}
+	fmt.Println("Daemon stopped")
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestDaemonTransport(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, which t.TempDir may
+	// exceed.
+	dir, err := os.MkdirTemp("", "cdpbrowser-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "daemon.sock")
+	if daemonRunning(socket) {
+		t.Fatal("daemonRunning without a socket = true")
+	}
+	if got, want := daemonLogFile(socket), filepath.Join(dir, "daemon.log"); got != want {
+		t.Errorf("daemonLogFile = %s, want %s", got, want)
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "daemon"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "navigate"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{ URL string }]]) (*mcp.CallToolResultFor[any], error) {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "Navigated to " + req.Params.Arguments.URL}}}, nil
+	})
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	if !daemonRunning(socket) {
+		t.Fatal("daemonRunning with a server listening = false")
+	}
+	// Each command is a session of its own.
+	ctx := context.Background()
+	for range 2 {
+		cs, err := connectTransport(ctx, daemonTransport(socket))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "navigate", Arguments: map[string]any{"URL": "https://example.com"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := res.Content[0].(*mcp.TextContent).Text; text != "Navigated to https://example.com" {
+			t.Errorf("navigate through the daemon = %q", text)
+		}
+		cs.Close()
+	}
+}
@@ -2,11 +2,15 @@ module cdpbrowser-client
 
 go 1.23.0
 
-require github.com/modelcontextprotocol/go-sdk v1.0.0
+require (
+	github.com/chzyer/readline v1.5.1
+	github.com/modelcontextprotocol/go-sdk v1.0.0
+)
 
 require (
 	github.com/google/jsonschema-go v0.2.0 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/sys v0.34.0 // indirect
 )
 
 replace github.com/modelcontextprotocol/go-sdk => ../../..
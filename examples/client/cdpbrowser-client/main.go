@@ -17,6 +17,13 @@
 //	demo              - Run a demo sequence
 //	list-tools        - List available tools from the server
 //
+// The exit code is 1 if a tool reports an error, the server fails, or the
+// arguments are invalid. Scripts stop at the first failed command unless
+// --continue-on-error is given before the command, and --report writes a
+// JUnit XML or JSON report of their commands for CI. parallel runs a script
+// against many URLs at once, each session with a server and browser of its
+// own.
+//
 // Example:
 //
 //	cdpbrowser-client navigate https://example.com
@@ -26,48 +33,87 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/chzyer/readline"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// continueOnError keeps a script running after a command fails.
+var continueOnError = flag.Bool("continue-on-error", false, "keep running a script after a command fails; the exit code still reports the failure")
+
+// reportFile is where run-script writes a pass/fail report of its commands.
+var reportFile = flag.String("report", "", "write a report of each script command to `file`, as JUnit XML (.xml) or JSON (.json)")
+
 func main() {
-	if len(os.Args) < 2 {
+	os.Exit(run())
+}
+
+// run runs the command given on the command line and returns the exit code:
+// 0 on success, 1 if a tool reported an error, the server failed, or the
+// arguments were invalid.
+func run() int {
+	flag.Usage = printUsage
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 1 {
 		printUsage()
-		os.Exit(1)
+		return 1
 	}
 
-	command := os.Args[1]
+	command := args[0]
+	if *reportFile != "" {
+		if err := checkReportFile(*reportFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if err := checkFilenameTemplate(*filenameTemplate); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := checkCallFlags(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
 
-	// Create the MCP client
 	ctx := context.Background()
-	client := mcp.NewClient(&mcp.Implementation{
-		Name:    "cdpbrowser-client",
-		Version: "v1.0.0",
-	}, nil)
-
-	// Get the path to the server executable
-	// Assuming we're running from the client directory, the server is at ../../server/cdpbrowser/
-	serverPath := filepath.Join("..", "..", "server", "cdpbrowser", "cdpbrowser")
-
-	// Create command to run the server
-	serverCmd := exec.Command(serverPath)
-
-	fmt.Printf("Starting cdpbrowser server: %s\n", serverPath)
+	switch command {
+	case "parallel":
+		return runParallelCommand(ctx, args[1:])
+	case "snapshot-diff":
+		// Comparing saved snapshots needs no server.
+		if err := runCommand(ctx, nil, args); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	case "daemon":
+		return runDaemonCommand(ctx, args[1:])
+	}
 
-	// Connect to the server via STDIO transport
-	cs, err := client.Connect(ctx, &mcp.CommandTransport{Command: serverCmd}, nil)
+	// A running daemon keeps the browser session of earlier commands.
+	var cs *mcp.ClientSession
+	var err error
+	if daemonRunning(*socketPath) {
+		fmt.Printf("Using the cdpbrowser daemon at %s\n", *socketPath)
+		cs, err = connectTransport(ctx, daemonTransport(*socketPath))
+	} else {
+		cs, err = connect(ctx, serverCommand())
+	}
 	if err != nil {
-		log.Fatalf("Failed to connect to cdpbrowser server: %v", err)
+		fmt.Fprintf(os.Stderr, "Failed to connect to cdpbrowser server: %v\n", err)
+		return 1
 	}
 	defer func() {
 		fmt.Println("Closing connection to server...")
@@ -75,116 +121,90 @@ func main() {
 	}()
 
 	switch command {
-	case "navigate":
-		if len(os.Args) < 3 {
-			fmt.Fprintf(os.Stderr, "Usage: %s navigate <url>\n", os.Args[0])
-			os.Exit(1)
-		}
-		navigate(ctx, cs, os.Args[2])
+	case "demo":
+		err = runDemo(ctx, cs)
 
-	case "click":
-		if len(os.Args) < 3 {
-			fmt.Fprintf(os.Stderr, "Usage: %s click <css-selector>\n", os.Args[0])
-			os.Exit(1)
+	case "watch":
+		level := mcp.LoggingLevel("info")
+		if len(args) > 1 {
+			var ok bool
+			if level, ok = parseLoggingLevel(args[1]); !ok {
+				fmt.Fprintf(os.Stderr, "Usage: %s watch [debug|info|notice|warning|error|critical|alert|emergency]\n", os.Args[0])
+				return 1
+			}
 		}
-		click(ctx, cs, os.Args[2])
-
-	case "screenshot":
-		screenshot(ctx, cs)
+		err = notifications.follow(ctx, cs, level)
 
-	case "aria-snapshot":
-		format := "llm-text"
-		focus := "all"
-		if len(os.Args) > 2 {
-			format = os.Args[2]
-		}
-		if len(os.Args) > 3 {
-			focus = os.Args[3]
-		}
-		ariaSnapshot(ctx, cs, format, focus)
+	case "interactive":
+		err = runInteractive(ctx, cs, nil)
 
-	case "type-text":
-		if len(os.Args) < 4 {
-			fmt.Fprintf(os.Stderr, "Usage: %s type-text <selector> <text> [clear]\n", os.Args[0])
-			os.Exit(1)
-		}
-		clear := false
-		if len(os.Args) > 4 {
-			clear = os.Args[4] == "true"
+	case "record":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Usage: %s record <script-file>\n", os.Args[0])
+			return 1
 		}
-		typeText(ctx, cs, os.Args[2], os.Args[3], clear)
-
-	case "click-button":
-		if len(os.Args) < 3 {
-			fmt.Fprintf(os.Stderr, "Usage: %s click-button <selector>\n", os.Args[0])
-			os.Exit(1)
+		rec, rerr := newRecorder(args[1])
+		if rerr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", rerr)
+			return 1
 		}
-		clickButton(ctx, cs, os.Args[2])
-
-	case "click-link":
-		if len(os.Args) < 3 {
-			fmt.Fprintf(os.Stderr, "Usage: %s click-link <selector>\n", os.Args[0])
-			os.Exit(1)
+		err = runInteractive(ctx, cs, rec)
+		if cerr := rec.close(); cerr != nil && err == nil {
+			err = cerr
 		}
-		clickLink(ctx, cs, os.Args[2])
 
-	case "select-dropdown":
-		if len(os.Args) < 4 {
-			fmt.Fprintf(os.Stderr, "Usage: %s select-dropdown <selector> <value>\n", os.Args[0])
-			os.Exit(1)
+	case "run-script":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Usage: %s [--continue-on-error] run-script <script-file>\n", os.Args[0])
+			return 1
 		}
-		selectDropdown(ctx, cs, os.Args[2], os.Args[3])
+		err = runScript(ctx, cs, args[1])
 
-	case "choose-option":
-		if len(os.Args) < 3 {
-			fmt.Fprintf(os.Stderr, "Usage: %s choose-option <selector> [checked]\n", os.Args[0])
-			os.Exit(1)
-		}
-		checked := true
-		if len(os.Args) > 3 {
-			checked = os.Args[3] == "true"
+	default:
+		err = runCommand(ctx, cs, args)
+		if errors.Is(err, errUnknownCommand) {
+			fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
+			printUsage()
+			return 1
 		}
-		chooseOption(ctx, cs, os.Args[2], checked)
-
-	case "refresh":
-		refreshPage(ctx, cs)
-
-	case "close":
-		closeBrowser(ctx, cs)
+	}
 
-	case "lifecycle":
-		if len(os.Args) < 3 {
-			fmt.Fprintf(os.Stderr, "Usage: %s lifecycle <true|false>\n", os.Args[0])
-			os.Exit(1)
-		}
-		keepOpen, err := strconv.ParseBool(os.Args[2])
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Invalid boolean value: %s\n", os.Args[2])
-			os.Exit(1)
-		}
-		setLifecycle(ctx, cs, keepOpen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
 
-	case "demo":
-		runDemo(ctx, cs)
+// serverCommand returns the command that runs the cdpbrowser server, with
+// args added to its flags.
+func serverCommand(args ...string) *exec.Cmd {
+	// Get the path to the server executable
+	// Assuming we're running from the client directory, the server is at ../../server/cdpbrowser/
+	serverPath := filepath.Join("..", "..", "server", "cdpbrowser", "cdpbrowser")
 
-	case "list-tools":
-		listTools(ctx, cs)
+	// Create command to run the server. The lifecycle and close commands
+	// need the admin tools, which the server does not expose by default.
+	return exec.Command(serverPath, append([]string{"-tool-profile", "admin"}, args...)...)
+}
 
-	case "interactive":
-		runInteractive(ctx, cs)
+// connect starts the server with serverCmd and connects to it via STDIO.
+func connect(ctx context.Context, serverCmd *exec.Cmd) (*mcp.ClientSession, error) {
+	fmt.Printf("Starting cdpbrowser server: %s\n", serverCmd.Path)
 
-	case "run-script":
-		if len(os.Args) < 3 {
-			fmt.Fprintf(os.Stderr, "Usage: %s run-script <script-file>\n", os.Args[0])
-			os.Exit(1)
-		}
-		runScript(ctx, cs, os.Args[2])
+	// Connect to the server via STDIO transport
+	return connectTransport(ctx, &mcp.CommandTransport{Command: serverCmd})
+}
 
-	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
-		printUsage()
-		os.Exit(1)
-	}
+// connectTransport connects the client to a server over transport.
+func connectTransport(ctx context.Context, transport mcp.Transport) (*mcp.ClientSession, error) {
+	// Create the MCP client
+	client := mcp.NewClient(&mcp.Implementation{
+		Name:    "cdpbrowser-client",
+		Version: "v1.0.0",
+	}, notifications.clientOptions())
+	client.AddSendingMiddleware(notifications.addProgressToken, retryCalls)
+	return client.Connect(ctx, transport, nil)
 }
 
 func printUsage() {
@@ -193,35 +213,78 @@ func printUsage() {
 	fmt.Println("  navigate <url>     - Navigate to a URL")
 	fmt.Println("  click <selector>   - Click on an element using CSS selector")
 	fmt.Println("  screenshot         - Take a screenshot of the current page")
-	fmt.Println("  aria-snapshot [format] [focus] - Capture ARIA accessibility structure")
+	fmt.Println("  aria-snapshot [format] [focus] - Capture ARIA accessibility structure;")
+	fmt.Println("                       --only, --grep, --max, and --format filter it")
+	fmt.Println("  save-snapshot <file> - Save the ARIA snapshot as JSON, for snapshot-diff")
+	fmt.Println("  snapshot-diff <before.json> <after.json> - Print the interactive elements")
+	fmt.Println("                       added and removed between two saved snapshots")
+	fmt.Println("  diff-since-last    - Compare the page with the session's last snapshot")
 	fmt.Println("  type-text <selector> <text> [clear] - Type text into an input field")
 	fmt.Println("  click-button <selector> - Click a button element")
 	fmt.Println("  click-link <selector> - Click a link element")
 	fmt.Println("  select-dropdown <selector> <value> - Select an option from a dropdown")
 	fmt.Println("  choose-option <selector> [checked] - Check/uncheck a radio button or checkbox")
+	fmt.Println("  assert-text <text> [selector] - Fail unless the page shows text")
+	fmt.Println("  assert-url <pattern> - Fail unless the URL matches a regular expression")
+	fmt.Println("  assert-visible <selector> - Fail unless an element is visible")
 	fmt.Println("  refresh            - Refresh the current page")
 	fmt.Println("  close              - Close the browser")
 	fmt.Println("  lifecycle <bool>   - Set Chrome lifecycle (true=keep open, false=close on exit)")
 	fmt.Println("  list-tools         - List all available tools from the server")
+	fmt.Println("  call <tool> [json] - Call any tool, with its arguments as a JSON object")
+	fmt.Println("  list-resources     - List the resources and resource templates of the server")
+	fmt.Println("  read-resource <uri> - Print or save a resource")
+	fmt.Println("  list-prompts       - List the prompts of the server")
+	fmt.Println("  get-prompt <name> [argument=value]... - Print the messages of a prompt")
+	fmt.Println("  watch [level]      - Stream server log messages, progress, and state updates")
 	fmt.Println("  interactive        - Start interactive mode for multiple commands")
+	fmt.Println("  record <file>      - Interactive mode that saves the commands as a script")
 	fmt.Println("  run-script <file>  - Execute commands from a script file")
+	fmt.Println("  parallel [-sessions n] <file> <url|@url-file>... - Run a script against")
+	fmt.Println("                       many URLs at once, each session with its own browser")
+	fmt.Println("  daemon start|stop|status - Keep one server and browser running for the")
+	fmt.Println("                       commands that follow, on a local socket")
 	fmt.Println("  demo              - Run a demo sequence")
 	fmt.Println()
+	fmt.Println("Options (before the command):")
+	fmt.Println("  --continue-on-error - Keep running a script after a failed command")
+	fmt.Println("  --report <file>     - Write a pass/fail report of a script's commands,")
+	fmt.Println("                        as JUnit XML (.xml) or JSON (.json)")
+	fmt.Println("  --output-dir <dir>  - Save screenshots and other binary content in dir")
+	fmt.Println("  --filename-template <template> - Name saved files, with {kind}, {tool},")
+	fmt.Println("                        {time}, {n}, and {ext} (default {kind}_{time}.{ext})")
+	fmt.Println("  --stdout-base64     - Print binary content as base64 data URLs instead")
+	fmt.Println("  --timeout <duration> - Give up on a tool call after duration, e.g. 30s")
+	fmt.Println("  --retries <n>       - Call a tool up to n more times after a timeout or")
+	fmt.Println("                        a tool error")
+	fmt.Println("  --retry-delay <duration> - Wait between the tries of a call (default 1s)")
+	fmt.Println("  --socket <path>     - The socket of the daemon")
+	fmt.Println()
+	fmt.Println("The exit code is 1 if a tool reports an error, the server fails, or the")
+	fmt.Println("arguments are invalid.")
+	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Printf("  %s navigate https://example.com\n", os.Args[0])
 	fmt.Printf("  %s click \"button.submit\"\n", os.Args[0])
 	fmt.Printf("  %s screenshot\n", os.Args[0])
 	fmt.Printf("  %s aria-snapshot\n", os.Args[0])
+	fmt.Printf("  %s aria-snapshot --only interactive --grep \"sign in\" --max 20\n", os.Args[0])
+	fmt.Printf("  %s snapshot-diff before.json after.json\n", os.Args[0])
 	fmt.Printf("  %s type-text \"#email\" \"user@example.com\"\n", os.Args[0])
 	fmt.Printf("  %s click-button \"Submit\"\n", os.Args[0])
 	fmt.Printf("  %s select-dropdown \"country\" \"United States\"\n", os.Args[0])
 	fmt.Printf("  %s choose-option \"newsletter\" true\n", os.Args[0])
+	fmt.Printf("  %s read-resource browser://state\n", os.Args[0])
 	fmt.Printf("  %s interactive\n", os.Args[0])
+	fmt.Printf("  %s record checkout.txt\n", os.Args[0])
 	fmt.Printf("  %s run-script actions.txt\n", os.Args[0])
+	fmt.Printf("  %s --report results.xml run-script smoke-test.txt\n", os.Args[0])
+	fmt.Printf("  %s parallel -sessions 3 smoke-test.txt @urls.txt\n", os.Args[0])
+	fmt.Printf("  %s daemon start\n", os.Args[0])
 	fmt.Printf("  %s demo\n", os.Args[0])
 }
 
-func navigate(ctx context.Context, cs *mcp.ClientSession, url string) {
+func navigate(ctx context.Context, cs *mcp.ClientSession, url string) error {
 	fmt.Printf("Navigating to: %s\n", url)
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -231,14 +294,10 @@ func navigate(ctx context.Context, cs *mcp.ClientSession, url string) {
 		},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call navigate tool: %v", err)
-	}
-
-	printToolResult(result)
+	return toolResult(ctx, "navigate", result, err)
 }
 
-func click(ctx context.Context, cs *mcp.ClientSession, selector string) {
+func click(ctx context.Context, cs *mcp.ClientSession, selector string) error {
 	fmt.Printf("Clicking element with selector: %s\n", selector)
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -248,14 +307,10 @@ func click(ctx context.Context, cs *mcp.ClientSession, selector string) {
 		},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call click tool: %v", err)
-	}
-
-	printToolResult(result)
+	return toolResult(ctx, "click", result, err)
 }
 
-func screenshot(ctx context.Context, cs *mcp.ClientSession) {
+func screenshot(ctx context.Context, cs *mcp.ClientSession) error {
 	fmt.Println("Taking screenshot...")
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -263,14 +318,10 @@ func screenshot(ctx context.Context, cs *mcp.ClientSession) {
 		Arguments: map[string]interface{}{},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call screenshot tool: %v", err)
-	}
-
-	printToolResult(result)
+	return toolResult(ctx, "screenshot", result, err)
 }
 
-func ariaSnapshot(ctx context.Context, cs *mcp.ClientSession, format, focus string) {
+func ariaSnapshot(ctx context.Context, cs *mcp.ClientSession, format, focus string) error {
 	fmt.Printf("Taking ARIA snapshot (format: %s, focus: %s)...\n", format, focus)
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -281,14 +332,17 @@ func ariaSnapshot(ctx context.Context, cs *mcp.ClientSession, format, focus stri
 		},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call aria_snapshot tool: %v", err)
+	if err := toolResult(ctx, "aria_snapshot", result, err); err != nil {
+		return err
 	}
-
-	printToolResult(result)
+	// diff-since-last compares with the snapshot, if it can be decoded.
+	if s, err := decodeSnapshot(result.StructuredContent); err == nil && listsInteractive(focus) {
+		rememberSnapshot(cs, s)
+	}
+	return nil
 }
 
-func typeText(ctx context.Context, cs *mcp.ClientSession, selector, text string, clear bool) {
+func typeText(ctx context.Context, cs *mcp.ClientSession, selector, text string, clear bool) error {
 	fmt.Printf("Typing text \"%s\" into element: %s (clear: %t)\n", text, selector, clear)
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -300,14 +354,10 @@ func typeText(ctx context.Context, cs *mcp.ClientSession, selector, text string,
 		},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call type_text tool: %v", err)
-	}
-
-	printToolResult(result)
+	return toolResult(ctx, "type_text", result, err)
 }
 
-func clickButton(ctx context.Context, cs *mcp.ClientSession, selector string) {
+func clickButton(ctx context.Context, cs *mcp.ClientSession, selector string) error {
 	fmt.Printf("Clicking button: %s\n", selector)
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -317,14 +367,10 @@ func clickButton(ctx context.Context, cs *mcp.ClientSession, selector string) {
 		},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call click_button tool: %v", err)
-	}
-
-	printToolResult(result)
+	return toolResult(ctx, "click_button", result, err)
 }
 
-func clickLink(ctx context.Context, cs *mcp.ClientSession, selector string) {
+func clickLink(ctx context.Context, cs *mcp.ClientSession, selector string) error {
 	fmt.Printf("Clicking link: %s\n", selector)
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -334,14 +380,10 @@ func clickLink(ctx context.Context, cs *mcp.ClientSession, selector string) {
 		},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call click_link tool: %v", err)
-	}
-
-	printToolResult(result)
+	return toolResult(ctx, "click_link", result, err)
 }
 
-func selectDropdown(ctx context.Context, cs *mcp.ClientSession, selector, value string) {
+func selectDropdown(ctx context.Context, cs *mcp.ClientSession, selector, value string) error {
 	fmt.Printf("Selecting \"%s\" from dropdown: %s\n", value, selector)
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -352,14 +394,10 @@ func selectDropdown(ctx context.Context, cs *mcp.ClientSession, selector, value
 		},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call select_dropdown tool: %v", err)
-	}
-
-	printToolResult(result)
+	return toolResult(ctx, "select_dropdown", result, err)
 }
 
-func chooseOption(ctx context.Context, cs *mcp.ClientSession, selector string, checked bool) {
+func chooseOption(ctx context.Context, cs *mcp.ClientSession, selector string, checked bool) error {
 	fmt.Printf("Setting option %s to %t\n", selector, checked)
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -370,14 +408,10 @@ func chooseOption(ctx context.Context, cs *mcp.ClientSession, selector string, c
 		},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call choose_option tool: %v", err)
-	}
-
-	printToolResult(result)
+	return toolResult(ctx, "choose_option", result, err)
 }
 
-func refreshPage(ctx context.Context, cs *mcp.ClientSession) {
+func refreshPage(ctx context.Context, cs *mcp.ClientSession) error {
 	fmt.Println("Refreshing page...")
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -385,14 +419,10 @@ func refreshPage(ctx context.Context, cs *mcp.ClientSession) {
 		Arguments: map[string]interface{}{},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call refresh_page tool: %v", err)
-	}
-
-	printToolResult(result)
+	return toolResult(ctx, "refresh_page", result, err)
 }
 
-func closeBrowser(ctx context.Context, cs *mcp.ClientSession) {
+func closeBrowser(ctx context.Context, cs *mcp.ClientSession) error {
 	fmt.Println("Closing browser...")
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -400,26 +430,40 @@ func closeBrowser(ctx context.Context, cs *mcp.ClientSession) {
 		Arguments: map[string]interface{}{},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call close_browser tool: %v", err)
-	}
-
-	printToolResult(result)
+	return toolResult(ctx, "close_browser", result, err)
 }
 
-func listTools(ctx context.Context, cs *mcp.ClientSession) {
+func listTools(ctx context.Context, cs *mcp.ClientSession) error {
 	fmt.Println("Listing available tools from server...")
 
 	// Get tools from the server
 	for tool, err := range cs.Tools(ctx, nil) {
 		if err != nil {
-			log.Fatalf("Failed to list tools: %v", err)
+			return fmt.Errorf("failed to list tools: %w", err)
 		}
 		fmt.Printf("- %s: %s\n", tool.Name, tool.Description)
 	}
+	return nil
 }
 
-func setLifecycle(ctx context.Context, cs *mcp.ClientSession, keepOpen bool) {
+func callTool(ctx context.Context, cs *mcp.ClientSession, name, argumentsJSON string) error {
+	fmt.Printf("Calling tool: %s\n", name)
+
+	arguments := map[string]interface{}{}
+	if argumentsJSON != "" {
+		if err := json.Unmarshal([]byte(argumentsJSON), &arguments); err != nil {
+			return fmt.Errorf("arguments of %s are not a JSON object: %w", name, err)
+		}
+	}
+	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name:      name,
+		Arguments: arguments,
+	})
+
+	return toolResult(ctx, name, result, err)
+}
+
+func setLifecycle(ctx context.Context, cs *mcp.ClientSession, keepOpen bool) error {
 	fmt.Printf("Setting Chrome lifecycle - keep open: %t\n", keepOpen)
 
 	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
@@ -429,388 +473,243 @@ func setLifecycle(ctx context.Context, cs *mcp.ClientSession, keepOpen bool) {
 		},
 	})
 
-	if err != nil {
-		log.Fatalf("Failed to call set_chrome_lifecycle tool: %v", err)
+	return toolResult(ctx, "set_chrome_lifecycle", result, err)
+}
+
+func assertText(ctx context.Context, cs *mcp.ClientSession, text, selector string) error {
+	fmt.Printf("Asserting the page shows: %s\n", text)
+
+	arguments := map[string]interface{}{
+		"text": text,
 	}
+	if selector != "" {
+		arguments["selector"] = selector
+	}
+	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "assert_text_present",
+		Arguments: arguments,
+	})
 
-	printToolResult(result)
+	return toolResult(ctx, "assert_text_present", result, err)
 }
 
-func runDemo(ctx context.Context, cs *mcp.ClientSession) {
+func assertURL(ctx context.Context, cs *mcp.ClientSession, pattern string) error {
+	fmt.Printf("Asserting the URL matches: %s\n", pattern)
+
+	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name: "assert_url_matches",
+		Arguments: map[string]interface{}{
+			"pattern": pattern,
+		},
+	})
+
+	return toolResult(ctx, "assert_url_matches", result, err)
+}
+
+func assertVisible(ctx context.Context, cs *mcp.ClientSession, selector string) error {
+	fmt.Printf("Asserting an element is visible: %s\n", selector)
+
+	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name: "assert_element_visible",
+		Arguments: map[string]interface{}{
+			"selector": selector,
+		},
+	})
+
+	return toolResult(ctx, "assert_element_visible", result, err)
+}
+
+func runDemo(ctx context.Context, cs *mcp.ClientSession) error {
 	fmt.Println("Running demo sequence...")
 
 	// Set Chrome to stay open
 	fmt.Println("\n1. Setting Chrome to stay open...")
-	setLifecycle(ctx, cs, true)
+	if err := setLifecycle(ctx, cs, true); err != nil {
+		return err
+	}
 	time.Sleep(1 * time.Second)
 
 	// Navigate to example.com
 	fmt.Println("\n2. Navigating to example.com...")
-	navigate(ctx, cs, "https://example.com")
+	if err := navigate(ctx, cs, "https://example.com"); err != nil {
+		return err
+	}
 	time.Sleep(2 * time.Second)
 
 	// Take a screenshot
 	fmt.Println("\n3. Taking screenshot...")
-	screenshot(ctx, cs)
+	if err := screenshot(ctx, cs); err != nil {
+		return err
+	}
 	time.Sleep(1 * time.Second)
 
 	// Navigate to another site
 	fmt.Println("\n4. Navigating to httpbin.org...")
-	navigate(ctx, cs, "https://httpbin.org")
+	if err := navigate(ctx, cs, "https://httpbin.org"); err != nil {
+		return err
+	}
 	time.Sleep(2 * time.Second)
 
 	// Take another screenshot
 	fmt.Println("\n5. Taking another screenshot...")
-	screenshot(ctx, cs)
+	if err := screenshot(ctx, cs); err != nil {
+		return err
+	}
 
 	fmt.Println("\nDemo completed! Chrome browser will remain open.")
+	return nil
 }
 
-func runInteractive(ctx context.Context, cs *mcp.ClientSession) {
+// runInteractive reads commands from standard input until exit. If rec is
+// not nil, the commands that succeed are recorded with it.
+func runInteractive(ctx context.Context, cs *mcp.ClientSession, rec *recorder) error {
 	fmt.Println("Starting interactive mode. Type 'help' for commands or 'exit' to quit.")
 	fmt.Println("Server connection maintained for multiple commands.")
+	if rec != nil {
+		fmt.Printf("Recording successful commands to %s\n", rec.file)
+	}
 
-	scanner := bufio.NewScanner(os.Stdin)
+	lines, err := newLineReader(ctx, cs)
+	if err != nil {
+		return fmt.Errorf("failed to start the prompt: %w", err)
+	}
+	defer lines.Close()
 
 	for {
-		fmt.Print("cdp> ")
-		if !scanner.Scan() {
-			break
+		line, err := lines.Readline()
+		if err == readline.ErrInterrupt {
+			// Ctrl-C drops the line being typed.
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 
-		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
 
-		parts := strings.Fields(line)
-		command := parts[0]
+		args, err := tokenize(line, nil)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
 
-		switch command {
+		switch args[0] {
 		case "exit", "quit":
 			fmt.Println("Exiting interactive mode...")
-			return
+			return nil
 
 		case "help":
 			fmt.Println("Available commands:")
 			fmt.Println("  navigate <url>     - Navigate to a URL")
 			fmt.Println("  click <selector>   - Click on an element")
 			fmt.Println("  screenshot         - Take a screenshot")
-			fmt.Println("  aria-snapshot [format] [focus] - Capture ARIA structure")
+			fmt.Println("  aria-snapshot [format] [focus] [--only section] [--grep regexp] [--max n]")
+			fmt.Println("               [--format table|json|md] - Capture ARIA structure")
+			fmt.Println("  save-snapshot <file> - Save the ARIA snapshot as JSON")
+			fmt.Println("  snapshot-diff <before.json> <after.json> - Compare saved snapshots")
+			fmt.Println("  diff-since-last    - Compare the page with the last snapshot")
 			fmt.Println("  type-text <selector> <text> [clear] - Type text into an input field")
 			fmt.Println("  click-button <selector> - Click a button element")
 			fmt.Println("  click-link <selector> - Click a link element")
 			fmt.Println("  select-dropdown <selector> <value> - Select dropdown option")
 			fmt.Println("  choose-option <selector> [checked] - Check/uncheck option")
+			fmt.Println("  assert-text <text> [selector] - Check that the page shows text")
+			fmt.Println("  assert-url <pattern> - Check that the URL matches a pattern")
+			fmt.Println("  assert-visible <selector> - Check that an element is visible")
 			fmt.Println("  refresh            - Refresh the current page")
 			fmt.Println("  close              - Close browser")
 			fmt.Println("  lifecycle <bool>   - Set Chrome lifecycle")
 			fmt.Println("  list-tools         - List available tools")
+			fmt.Println("  call <tool> [json] - Call any tool with JSON arguments")
+			fmt.Println("  list-resources     - List the server's resources")
+			fmt.Println("  read-resource <uri> - Print or save a resource")
+			fmt.Println("  list-prompts       - List the server's prompts")
+			fmt.Println("  get-prompt <name> [argument=value]... - Show a prompt's messages")
+			fmt.Println("  wait <seconds>     - Wait before the next command")
+			fmt.Println("  watch [on [level] | off] - Show server log messages, progress, and state updates")
 			fmt.Println("  help               - Show this help")
 			fmt.Println("  exit/quit          - Exit interactive mode")
-
-		case "navigate":
-			if len(parts) < 2 {
-				fmt.Println("Usage: navigate <url>")
-				continue
-			}
-			navigate(ctx, cs, parts[1])
-
-		case "click":
-			if len(parts) < 2 {
-				fmt.Println("Usage: click <css-selector>")
-				continue
-			}
-			click(ctx, cs, parts[1])
-
-		case "screenshot":
-			screenshot(ctx, cs)
-
-		case "aria-snapshot":
-			format := "llm-text"
-			focus := "all"
-			if len(parts) > 1 {
-				format = parts[1]
-			}
-			if len(parts) > 2 {
-				focus = parts[2]
-			}
-			ariaSnapshot(ctx, cs, format, focus)
-
-		case "type-text":
-			if len(parts) < 3 {
-				fmt.Println("Usage: type-text <selector> <text> [clear]")
-				continue
-			}
-			clear := false
-			if len(parts) > 3 {
-				clear = parts[3] == "true"
-			}
-			// Join all parts from index 2 onwards to handle text with spaces
-			text := strings.Join(parts[2:len(parts)], " ")
-			if len(parts) > 3 && parts[len(parts)-1] == "true" {
-				text = strings.Join(parts[2:len(parts)-1], " ")
-				clear = true
-			}
-			typeText(ctx, cs, parts[1], text, clear)
-
-		case "click-button":
-			if len(parts) < 2 {
-				fmt.Println("Usage: click-button <selector>")
-				continue
-			}
-			clickButton(ctx, cs, parts[1])
-
-		case "click-link":
-			if len(parts) < 2 {
-				fmt.Println("Usage: click-link <selector>")
-				continue
-			}
-			clickLink(ctx, cs, parts[1])
-
-		case "select-dropdown":
-			if len(parts) < 3 {
-				fmt.Println("Usage: select-dropdown <selector> <value>")
-				continue
-			}
-			// Join all parts from index 2 onwards to handle values with spaces
-			value := strings.Join(parts[2:], " ")
-			selectDropdown(ctx, cs, parts[1], value)
-
-		case "choose-option":
-			if len(parts) < 2 {
-				fmt.Println("Usage: choose-option <selector> [checked]")
-				continue
-			}
-			checked := true
-			if len(parts) > 2 {
-				checked = parts[2] == "true"
-			}
-			chooseOption(ctx, cs, parts[1], checked)
-
-		case "refresh":
-			refreshPage(ctx, cs)
-
-		case "close":
-			closeBrowser(ctx, cs)
-
-		case "lifecycle":
-			if len(parts) < 2 {
-				fmt.Println("Usage: lifecycle <true|false>")
-				continue
-			}
-			keepOpen, err := strconv.ParseBool(parts[1])
-			if err != nil {
-				fmt.Printf("Invalid boolean value: %s\n", parts[1])
-				continue
-			}
-			setLifecycle(ctx, cs, keepOpen)
-
-		case "list-tools":
-			listTools(ctx, cs)
-
-		case "wait":
-			if len(parts) < 2 {
-				fmt.Println("Usage: wait <seconds>")
-				continue
-			}
-			seconds, err := strconv.Atoi(parts[1])
-			if err != nil {
-				fmt.Printf("Invalid wait duration: %s\n", parts[1])
-				continue
-			}
-			fmt.Printf("Waiting %d seconds...\n", seconds)
-			time.Sleep(time.Duration(seconds) * time.Second)
-
-		default:
-			fmt.Printf("Unknown command: %s (type 'help' for available commands)\n", command)
-		}
-	}
-}
-
-func runScript(ctx context.Context, cs *mcp.ClientSession, scriptFile string) {
-	fmt.Printf("Running script file: %s\n", scriptFile)
-
-	// Read the script file
-	file, err := os.Open(scriptFile)
-	if err != nil {
-		log.Fatalf("Failed to open script file %s: %v", scriptFile, err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-
-	fmt.Println("Executing script commands...")
-	fmt.Println("=" + strings.Repeat("=", 50))
-
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
-
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
+			fmt.Println("Quote arguments that contain spaces: click \"button.submit large\"")
+			fmt.Println("Use the arrow keys to edit and recall commands, and tab to complete them.")
 			continue
 		}
 
-		fmt.Printf("Line %d: %s\n", lineNum, line)
-
-		parts := strings.Fields(line)
-		if len(parts) == 0 {
+		if rec != nil {
+			err = rec.run(ctx, cs, args)
+		} else {
+			err = runCommand(ctx, cs, args)
+		}
+		if errors.Is(err, errUnknownCommand) {
+			fmt.Printf("Unknown command: %s (type 'help' for available commands)\n", args[0])
 			continue
 		}
 
-		command := parts[0]
-
-		switch command {
-		case "navigate":
-			if len(parts) < 2 {
-				fmt.Printf("  Error: navigate requires URL (line %d)\n", lineNum)
-				continue
-			}
-			navigate(ctx, cs, parts[1])
-
-		case "click":
-			if len(parts) < 2 {
-				fmt.Printf("  Error: click requires CSS selector (line %d)\n", lineNum)
-				continue
-			}
-			click(ctx, cs, parts[1])
-
-		case "screenshot":
-			screenshot(ctx, cs)
-
-		case "aria-snapshot":
-			format := "llm-text"
-			focus := "all"
-			if len(parts) > 1 {
-				format = parts[1]
-			}
-			if len(parts) > 2 {
-				focus = parts[2]
-			}
-			ariaSnapshot(ctx, cs, format, focus)
-
-		case "type-text":
-			if len(parts) < 3 {
-				fmt.Printf("  Error: type-text requires selector and text (line %d)\n", lineNum)
-				continue
-			}
-			clear := false
-			text := strings.Join(parts[2:], " ")
-			// Check if last argument is "true" for clear flag
-			if len(parts) > 3 && parts[len(parts)-1] == "true" {
-				text = strings.Join(parts[2:len(parts)-1], " ")
-				clear = true
-			}
-			typeText(ctx, cs, parts[1], text, clear)
-
-		case "click-button":
-			if len(parts) < 2 {
-				fmt.Printf("  Error: click-button requires selector (line %d)\n", lineNum)
-				continue
-			}
-			clickButton(ctx, cs, parts[1])
-
-		case "click-link":
-			if len(parts) < 2 {
-				fmt.Printf("  Error: click-link requires selector (line %d)\n", lineNum)
-				continue
-			}
-			clickLink(ctx, cs, parts[1])
-
-		case "select-dropdown":
-			if len(parts) < 3 {
-				fmt.Printf("  Error: select-dropdown requires selector and value (line %d)\n", lineNum)
-				continue
-			}
-			value := strings.Join(parts[2:], " ")
-			selectDropdown(ctx, cs, parts[1], value)
-
-		case "choose-option":
-			if len(parts) < 2 {
-				fmt.Printf("  Error: choose-option requires selector (line %d)\n", lineNum)
-				continue
+		// A failed call does not end the session, unless the server is gone.
+		if err != nil {
+			if !isToolError(err) {
+				fmt.Printf("Error: %v\n", err)
 			}
-			checked := true
-			if len(parts) > 2 {
-				checked = parts[2] == "true"
+			if errors.Is(err, mcp.ErrConnectionClosed) {
+				return fmt.Errorf("lost the connection to the server: %w", err)
 			}
-			chooseOption(ctx, cs, parts[1], checked)
-
-		case "refresh":
-			refreshPage(ctx, cs)
+		}
+	}
+}
 
-		case "close":
-			closeBrowser(ctx, cs)
+// toolError is returned for a tool call whose result is an error.
+type toolError struct {
+	tool    string
+	message string // the text the tool returned
+}
 
-		case "lifecycle":
-			if len(parts) < 2 {
-				fmt.Printf("  Error: lifecycle requires boolean value (line %d)\n", lineNum)
-				continue
-			}
-			keepOpen, err := strconv.ParseBool(parts[1])
-			if err != nil {
-				fmt.Printf("  Error: invalid boolean value '%s' (line %d)\n", parts[1], lineNum)
-				continue
-			}
-			setLifecycle(ctx, cs, keepOpen)
+func (e *toolError) Error() string {
+	return fmt.Sprintf("tool %s reported an error", e.tool)
+}
 
-		case "list-tools":
-			listTools(ctx, cs)
+// isToolError reports whether err is a toolError, whose result was already
+// printed.
+func isToolError(err error) bool {
+	var te *toolError
+	return errors.As(err, &te)
+}
 
-		case "wait":
-			// Optional wait command for delays between actions
-			if len(parts) < 2 {
-				fmt.Printf("  Error: wait requires duration in seconds (line %d)\n", lineNum)
-				continue
-			}
-			seconds, err := strconv.Atoi(parts[1])
-			if err != nil {
-				fmt.Printf("  Error: invalid wait duration '%s' (line %d)\n", parts[1], lineNum)
-				continue
-			}
-			fmt.Printf("  Waiting %d seconds...\n", seconds)
-			time.Sleep(time.Duration(seconds) * time.Second)
+// resultObserverKey is the context key of a function that toolResult calls
+// with the result of every tool call, as the recorder does.
+type resultObserverKey struct{}
 
-		default:
-			fmt.Printf("  Error: unknown command '%s' (line %d)\n", command, lineNum)
-		}
+// withResultObserver returns a context in which toolResult passes the
+// results of tool calls to observe.
+func withResultObserver(ctx context.Context, observe func(tool string, result *mcp.CallToolResult)) context.Context {
+	return context.WithValue(ctx, resultObserverKey{}, observe)
+}
 
-		fmt.Println() // Add spacing between commands
+// toolResult prints the result of a call to tool and returns the error of
+// the call, or a toolError if the tool reported one.
+func toolResult(ctx context.Context, tool string, result *mcp.CallToolResult, err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to call %s tool: %w", tool, err)
 	}
-
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("Error reading script file: %v", err)
+	printToolResult(tool, result)
+	if observe, ok := ctx.Value(resultObserverKey{}).(func(string, *mcp.CallToolResult)); ok {
+		observe(tool, result)
 	}
-
-	fmt.Println("=" + strings.Repeat("=", 50))
-	fmt.Println("Script execution completed")
-}
-
-func printToolResult(result *mcp.CallToolResult) {
 	if result.IsError {
-		fmt.Printf("Error: ")
-	} else {
-		fmt.Printf("Success: ")
-	}
-
-	for _, content := range result.Content {
-		switch c := content.(type) {
-		case *mcp.TextContent:
-			fmt.Println(c.Text)
-		case *mcp.ImageContent:
-			// Save screenshot to file with timestamp
-			timestamp := time.Now().Format("20060102_150405")
-			filename := fmt.Sprintf("screenshot_%s.png", timestamp)
-
-			if err := os.WriteFile(filename, c.Data, 0644); err != nil {
-				fmt.Printf("Image: %s (size: %d bytes) - Failed to save: %v\n", c.MIMEType, len(c.Data), err)
-			} else {
-				fmt.Printf("Screenshot saved to: %s (size: %d bytes)\n", filename, len(c.Data))
+		var message []string
+		for _, content := range result.Content {
+			if c, ok := content.(*mcp.TextContent); ok {
+				message = append(message, c.Text)
 			}
-		default:
-			fmt.Printf("Unknown content type: %T\n", content)
 		}
+		return &toolError{tool: tool, message: strings.Join(message, "\n")}
 	}
+	return nil
 }
@@ -0,0 +1,91 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// An operator is the person running a script, who answers its pause and
+// confirm commands, as when a step such as a login or a CAPTCHA needs a
+// human.
+type operator struct {
+	in  io.Reader
+	out io.Writer
+
+	// mu lets one script ask at a time, since the sessions of a parallel
+	// run share the terminal.
+	mu    sync.Mutex
+	once  sync.Once
+	lines chan string // the lines of in, closed at its end
+}
+
+// stdinOperator answers on the standard input.
+var stdinOperator = newOperator(os.Stdin, os.Stdout)
+
+// errNoOperator is returned when a script asks the operator, but the input
+// has ended, as when the standard input is not a terminal.
+var errNoOperator = errors.New("no operator input: the standard input has ended")
+
+func newOperator(in io.Reader, out io.Writer) *operator {
+	return &operator{in: in, out: out, lines: make(chan string)}
+}
+
+// ask prints prompt and returns the line the operator answers with.
+func (o *operator) ask(ctx context.Context, prompt string) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// The input is read in the background, so that a script whose context
+	// is done does not wait for a line.
+	o.once.Do(func() {
+		go func() {
+			scanner := bufio.NewScanner(o.in)
+			for scanner.Scan() {
+				o.lines <- scanner.Text()
+			}
+			close(o.lines)
+		}()
+	})
+	fmt.Fprint(o.out, prompt)
+	select {
+	case line, ok := <-o.lines:
+		if !ok {
+			fmt.Fprintln(o.out)
+			return "", errNoOperator
+		}
+		return strings.TrimSpace(line), nil
+	case <-ctx.Done():
+		fmt.Fprintln(o.out)
+		return "", ctx.Err()
+	}
+}
+
+// pause waits until the operator presses Enter.
+func (o *operator) pause(ctx context.Context, who, message string) error {
+	fmt.Fprintf(o.out, "  PAUSED%s: %s\n", who, message)
+	_, err := o.ask(ctx, "  Press Enter to continue... ")
+	return err
+}
+
+// confirm asks the operator a yes/no question, and fails unless the answer
+// is yes.
+func (o *operator) confirm(ctx context.Context, who, message string) error {
+	answer, err := o.ask(ctx, fmt.Sprintf("  CONFIRM%s: %s [y/N] ", who, message))
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("the operator did not confirm %q", message)
+}
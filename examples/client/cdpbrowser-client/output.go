@@ -0,0 +1,206 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var (
+	// outputDir is where images, audio, and binary resources returned by
+	// tools are saved.
+	outputDir = flag.String("output-dir", ".", "save images and other binary content returned by tools in `dir`")
+	// filenameTemplate names the saved files.
+	filenameTemplate = flag.String("filename-template", "{kind}_{time}.{ext}", "name saved files after `template`, with {kind}, {tool}, {time}, {n}, and {ext} replaced")
+	// stdoutBase64 prints binary content as data URLs instead of saving it.
+	stdoutBase64 = flag.Bool("stdout-base64", false, "print images and other binary content to stdout as base64 data URLs instead of saving them")
+)
+
+// templatePlaceholder matches the placeholders of a filename template.
+var templatePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// savedFiles counts the files saved, for the {n} placeholder.
+var savedFiles atomic.Int64
+
+// checkFilenameTemplate reports an error if template has an unknown
+// placeholder or names no file.
+func checkFilenameTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return errors.New("the filename template is empty")
+	}
+	for _, p := range templatePlaceholder.FindAllString(template, -1) {
+		switch p {
+		case "{kind}", "{tool}", "{time}", "{n}", "{ext}":
+		default:
+			return fmt.Errorf("unknown placeholder %s in filename template %q", p, template)
+		}
+	}
+	return nil
+}
+
+// contentExtensions are the file extensions of the MIME types tools return,
+// where mime.ExtensionsByType has none or several.
+var contentExtensions = map[string]string{
+	"image/png":            "png",
+	"image/jpeg":           "jpg",
+	"image/webp":           "webp",
+	"image/gif":            "gif",
+	"audio/wav":            "wav",
+	"audio/mpeg":           "mp3",
+	"audio/webm":           "webm",
+	"video/webm":           "webm",
+	"video/mp4":            "mp4",
+	"application/pdf":      "pdf",
+	"application/json":     "json",
+	"application/har+json": "har",
+	"text/html":            "html",
+	"text/markdown":        "md",
+	"text/plain":           "txt",
+	"text/csv":             "csv",
+}
+
+// extension returns the file extension, without the dot, for content of
+// mimeType.
+func extension(mimeType string) string {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return "bin"
+	}
+	if ext, ok := contentExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return strings.TrimPrefix(exts[0], ".")
+	}
+	return "bin"
+}
+
+// outputFilename expands the filename template for content of a kind and
+// MIME type returned by tool at t, as the nth saved file.
+func outputFilename(template, kind, tool, mimeType string, t time.Time, n int) string {
+	return templatePlaceholder.ReplaceAllStringFunc(template, func(p string) string {
+		switch p {
+		case "{kind}":
+			return kind
+		case "{tool}":
+			return tool
+		case "{time}":
+			return t.Format("20060102_150405")
+		case "{n}":
+			return strconv.Itoa(n)
+		case "{ext}":
+			return extension(mimeType)
+		}
+		return p
+	})
+}
+
+// uniquePath returns path, or if a file of that name exists, path with
+// _2, _3, ... added before the extension.
+func uniquePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return path
+		}
+		path = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+}
+
+// outputContent saves binary content of a kind ("screenshot", "audio", or
+// "resource") returned by tool under --output-dir, or prints it as a data
+// URL with --stdout-base64, and reports what it did.
+func outputContent(tool, kind, mimeType string, data []byte) {
+	if *stdoutBase64 {
+		fmt.Printf("data:%s;base64,%s\n", mimeType, base64.StdEncoding.EncodeToString(data))
+		return
+	}
+	n := int(savedFiles.Add(1))
+	name := outputFilename(*filenameTemplate, kind, tool, mimeType, time.Now(), n)
+	path := uniquePath(filepath.Join(*outputDir, name))
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	label := strings.ToUpper(kind[:1]) + kind[1:]
+	if err != nil {
+		fmt.Printf("%s: %s (size: %d bytes) - Failed to save: %v\n", label, mimeType, len(data), err)
+		return
+	}
+	fmt.Printf("%s saved to: %s (size: %d bytes)\n", label, path, len(data))
+}
+
+// isTextMIMEType reports whether a text resource of mimeType is best
+// printed rather than saved.
+func isTextMIMEType(mimeType string) bool {
+	return mimeType == "" || strings.HasPrefix(mimeType, "text/")
+}
+
+// printResourceContents prints a text resource, or saves a binary one or a
+// text one, such as a HAR, that is a document of its own.
+func printResourceContents(tool string, rc *mcp.ResourceContents) {
+	switch {
+	case rc.Blob != nil:
+		outputContent(tool, "resource", rc.MIMEType, rc.Blob)
+	case isTextMIMEType(rc.MIMEType):
+		fmt.Printf("Resource %s:\n%s\n", rc.URI, rc.Text)
+	default:
+		outputContent(tool, "resource", rc.MIMEType, []byte(rc.Text))
+	}
+}
+
+func printToolResult(tool string, result *mcp.CallToolResult) {
+	if result.IsError {
+		fmt.Printf("Error: ")
+	} else {
+		fmt.Printf("Success: ")
+	}
+
+	for _, content := range result.Content {
+		printContent(tool, content)
+	}
+}
+
+// printContent prints text content, and saves or prints binary content,
+// returned by tool.
+func printContent(tool string, content mcp.Content) {
+	switch c := content.(type) {
+	case *mcp.TextContent:
+		fmt.Println(c.Text)
+	case *mcp.ImageContent:
+		outputContent(tool, "screenshot", c.MIMEType, c.Data)
+	case *mcp.AudioContent:
+		outputContent(tool, "audio", c.MIMEType, c.Data)
+	case *mcp.EmbeddedResource:
+		if c.Resource != nil {
+			printResourceContents(tool, c.Resource)
+		}
+	case *mcp.ResourceLink:
+		fmt.Printf("Resource link: %s", c.URI)
+		if c.MIMEType != "" {
+			fmt.Printf(" (%s)", c.MIMEType)
+		}
+		if c.Description != "" {
+			fmt.Printf(" - %s", c.Description)
+		}
+		fmt.Println()
+	default:
+		fmt.Printf("Unknown content type: %T\n", content)
+	}
+}
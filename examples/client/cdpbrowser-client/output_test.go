@@ -0,0 +1,54 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOutputFilename(t *testing.T) {
+	at := time.Date(2025, 10, 24, 0, 22, 21, 0, time.UTC)
+	tests := []struct {
+		template, kind, tool, mimeType string
+		want                           string
+	}{
+		{"{kind}_{time}.{ext}", "screenshot", "screenshot", "image/png", "screenshot_20251024_002221.png"},
+		{"{tool}/{n}.{ext}", "resource", "print_to_pdf", "application/pdf", "print_to_pdf/7.pdf"},
+		{"{kind}.{ext}", "resource", "export_har", "application/har+json", "resource.har"},
+		{"{kind}.{ext}", "resource", "record", "video/webm; codecs=vp8", "resource.webm"},
+		{"{kind}.{ext}", "resource", "download", "application/x-unknown-thing", "resource.bin"},
+	}
+	for _, tt := range tests {
+		if got := outputFilename(tt.template, tt.kind, tt.tool, tt.mimeType, at, 7); got != tt.want {
+			t.Errorf("outputFilename(%q, %q) = %q, want %q", tt.template, tt.mimeType, got, tt.want)
+		}
+	}
+
+	if err := checkFilenameTemplate("{kind}_{date}.{ext}"); err == nil {
+		t.Error("checkFilenameTemplate accepted the unknown placeholder {date}")
+	}
+	if err := checkFilenameTemplate("shots/{tool}-{n}.{ext}"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "screenshot.png")
+	if got := uniquePath(path); got != path {
+		t.Errorf("uniquePath of a new file = %s", got)
+	}
+	for _, name := range []string{"screenshot.png", "screenshot_2.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := uniquePath(path), filepath.Join(dir, "screenshot_3.png"); got != want {
+		t.Errorf("uniquePath = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// A parallelResult is the outcome of the script against one URL of a
+// parallel run.
+type parallelResult struct {
+	script   string
+	url      string
+	session  int // the session that ran the script, from 1, or 0 if none did
+	commands int
+	failed   int
+	err      error // what stopped the script, or kept it from running
+	duration time.Duration
+	steps    []scriptStep
+}
+
+func (r *parallelResult) passed() bool {
+	return r.session > 0 && r.err == nil && r.failed == 0
+}
+
+// errorSummary returns the first line of the first error of r, or "".
+func (r *parallelResult) errorSummary() string {
+	message := ""
+	for _, step := range r.steps {
+		if !step.Passed {
+			message = step.Error
+			break
+		}
+	}
+	if message == "" && r.err != nil {
+		message = r.err.Error()
+	}
+	message, _, _ = strings.Cut(message, "\n")
+	return message
+}
+
+// runParallelCommand runs the parallel command with its arguments and
+// returns the exit code.
+func runParallelCommand(ctx context.Context, args []string) int {
+	const usage = "parallel [-sessions n] <script-file> <url|@url-file>..."
+	fs := flag.NewFlagSet("parallel", flag.ContinueOnError)
+	sessions := fs.Int("sessions", 4, "run the script in up to `n` sessions at once")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s\n", os.Args[0], usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() < 2 || *sessions < 1 {
+		fs.Usage()
+		return 1
+	}
+	urls, err := parallelURLs(fs.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	results := runParallel(ctx, fs.Arg(0), urls, *sessions)
+	if err := parallelSummary(os.Stdout, results); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parallelURLs returns the URLs given to parallel. An argument starting
+// with @ names a file of URLs, one per line, where empty lines and comments
+// starting with # are skipped.
+func parallelURLs(args []string) ([]string, error) {
+	var urls []string
+	for _, arg := range args {
+		file, ok := strings.CutPrefix(arg, "@")
+		if !ok {
+			urls = append(urls, arg)
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open URL file: %w", err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				urls = append(urls, line)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading URL file %s: %w", file, err)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("no URLs to run the script against")
+	}
+	return urls, nil
+}
+
+// runParallel runs scriptFile against each of urls, with ${URL} set to the
+// URL, in up to sessions sessions at once. Each session starts a server
+// with a browser of its own, and runs the script against the URLs left, one
+// after the other.
+func runParallel(ctx context.Context, scriptFile string, urls []string, sessions int) []*parallelResult {
+	sessions = min(sessions, len(urls))
+	fmt.Printf("Running %s against %d URLs in %d sessions\n", scriptFile, len(urls), sessions)
+
+	results := make([]*parallelResult, len(urls))
+	jobs := make(chan *parallelResult, len(urls))
+	for i, url := range urls {
+		results[i] = &parallelResult{script: scriptFile, url: url}
+		jobs <- results[i]
+	}
+	close(jobs)
+
+	var mu sync.Mutex
+	var startErr error // why the last session that failed to start did
+	var wg sync.WaitGroup
+	for n := 1; n <= sessions; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runSession(ctx, n, scriptFile, jobs); err != nil {
+				fmt.Printf("[session %d] %v\n", n, err)
+				mu.Lock()
+				startErr = err
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		if r.session == 0 && r.err == nil {
+			r.err = fmt.Errorf("no session could run the script: %w", startErr)
+		}
+	}
+	return results
+}
+
+// runSession starts session n and runs the script for the jobs it takes,
+// until there are none left or the server is lost. It returns an error if
+// the session could not start.
+func runSession(ctx context.Context, n int, scriptFile string, jobs <-chan *parallelResult) error {
+	// Sessions must not share Chrome's debugging port or profile.
+	port, err := freePort()
+	if err != nil {
+		return fmt.Errorf("failed to find a port for Chrome: %w", err)
+	}
+	userDataDir, err := os.MkdirTemp("", "cdpbrowser-client-")
+	if err != nil {
+		return fmt.Errorf("failed to create a Chrome profile directory: %w", err)
+	}
+	defer os.RemoveAll(userDataDir)
+
+	cmd := serverCommand("-chrome-port", strconv.Itoa(port), "-chrome-user-data-dir", userDataDir)
+	cmd.Env = append(os.Environ(), "CLOSE_CHROME_ON_EXIT=true")
+	cs, err := connect(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cdpbrowser server: %w", err)
+	}
+	defer cs.Close()
+
+	for r := range jobs {
+		fmt.Printf("[session %d] Running %s against %s\n", n, scriptFile, r.url)
+		start := time.Now()
+		runner := &scriptRunner{
+			ctx:  ctx,
+			cs:   cs,
+			vars: map[string]string{"URL": r.url},
+			name: fmt.Sprintf("session %d, %s", n, r.url),
+		}
+		err := runner.runFile(scriptFile)
+		r.session = n
+		r.duration = time.Since(start)
+		r.commands, r.failed, r.steps = runner.commands, runner.failed, runner.steps
+		if err != nil && !errors.Is(err, errScriptStopped) {
+			// The script could not be read or parsed.
+			r.steps = append(r.steps, scriptStep{File: scriptFile, Command: "run-script " + scriptFile, Error: err.Error()})
+		}
+		r.err = err
+		status := "passed"
+		if !r.passed() {
+			status = "failed"
+		}
+		fmt.Printf("[session %d] %s %s in %.1fs\n", n, r.url, status, r.duration.Seconds())
+		// The URLs left go to the other sessions.
+		if errors.Is(err, mcp.ErrConnectionClosed) {
+			return fmt.Errorf("lost the connection to the server: %w", err)
+		}
+	}
+	return nil
+}
+
+// freePort returns a TCP port that is free on localhost.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// parallelSummary prints a table of results, writes the --report file if
+// one was asked for, and returns an error unless every URL passed.
+func parallelSummary(w io.Writer, results []*parallelResult) error {
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tURL\tSESSION\tCOMMANDS\tTIME\tERROR")
+	passed := 0
+	for _, r := range results {
+		result := "FAIL"
+		if r.passed() {
+			result = "PASS"
+			passed++
+		}
+		session := "-"
+		if r.session > 0 {
+			session = strconv.Itoa(r.session)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%.1fs\t%s\n", result, r.url, session,
+			r.commands-r.failed, r.commands, r.duration.Seconds(), r.errorSummary())
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d of %d URLs passed\n", passed, len(results))
+
+	if *reportFile != "" {
+		reports := make([]scriptReport, len(results))
+		for i, r := range results {
+			steps := r.steps
+			if r.session == 0 {
+				steps = []scriptStep{{Command: "parallel " + r.url, Error: r.err.Error()}}
+			}
+			reports[i] = newScriptReport(r.script, steps, r.duration)
+			reports[i].URL = r.url
+		}
+		if err := writeReports(*reportFile, reports); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Report written to %s\n", *reportFile)
+	}
+
+	if passed < len(results) {
+		return fmt.Errorf("%d of %d URLs failed", len(results)-passed, len(results))
+	}
+	return nil
+}
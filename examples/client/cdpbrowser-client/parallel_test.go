@@ -0,0 +1,86 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParallelURLs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "urls.txt")
+	if err := os.WriteFile(file, []byte("# Staging\nhttps://staging.example.com\n\n  https://qa.example.com  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := parallelURLs([]string{"https://example.com", "@" + file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://example.com", "https://staging.example.com", "https://qa.example.com"}
+	if !slices.Equal(got, want) {
+		t.Errorf("parallelURLs = %q, want %q", got, want)
+	}
+
+	if _, err := parallelURLs([]string{"@" + filepath.Join(t.TempDir(), "missing.txt")}); err == nil {
+		t.Error("parallelURLs with a missing file succeeded, want an error")
+	}
+	empty := filepath.Join(t.TempDir(), "empty.txt")
+	os.WriteFile(empty, []byte("# none yet\n"), 0644)
+	if _, err := parallelURLs([]string{"@" + empty}); err == nil {
+		t.Error("parallelURLs without URLs succeeded, want an error")
+	}
+}
+
+func TestParallelSummary(t *testing.T) {
+	stmt := &scriptStmt{file: "smoke.txt", line: 2, text: `assert-text "Example"`}
+	results := []*parallelResult{
+		{script: "smoke.txt", url: "https://example.com", session: 1, commands: 2, duration: time.Second,
+			steps: []scriptStep{newScriptStep(stmt, time.Second, nil)}},
+		{script: "smoke.txt", url: "https://example.org", session: 2, commands: 2, failed: 1, duration: time.Second,
+			err:   errScriptStopped,
+			steps: []scriptStep{newScriptStep(stmt, time.Second, &toolError{tool: "assert_text_present", message: "Assertion failed\nPage text: none"})}},
+		{script: "smoke.txt", url: "https://example.net", err: errors.New("no session could run the script")},
+	}
+
+	report := filepath.Join(t.TempDir(), "results.xml")
+	old := *reportFile
+	*reportFile = report
+	defer func() { *reportFile = old }()
+
+	var b strings.Builder
+	err := parallelSummary(&b, results)
+	if err == nil || err.Error() != "2 of 3 URLs failed" {
+		t.Errorf("parallelSummary error = %v, want 2 of 3 URLs failed", err)
+	}
+	for _, want := range []string{
+		"PASS    https://example.com  1        2/2",
+		"FAIL    https://example.org  2        1/2       1.0s  Assertion failed\n",
+		"FAIL    https://example.net  -        0/0       0.0s  no session could run the script\n",
+		"1 of 3 URLs passed",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("summary does not contain %q:\n%s", want, b.String())
+		}
+	}
+
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<testsuite name="smoke.txt https://example.com" tests="1" failures="0"`,
+		`<testsuite name="smoke.txt https://example.org" tests="1" failures="1"`,
+		`<testsuite name="smoke.txt https://example.net" tests="1" failures="1"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JUnit report does not contain %s:\n%s", want, data)
+		}
+	}
+}
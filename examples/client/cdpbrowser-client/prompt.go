@@ -0,0 +1,128 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// historyFile is where interactive mode keeps the commands entered, across
+// sessions.
+var historyFile = flag.String("history-file", defaultHistoryFile(), "keep the history of interactive mode in `file`; empty to not keep it")
+
+// defaultHistoryFile returns ~/.cdpbrowser-client_history, or "" if there is
+// no home directory.
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cdpbrowser-client_history")
+}
+
+// interactiveCommands are the commands of interactive mode, for completion.
+var interactiveCommands = []string{
+	"navigate", "click", "screenshot", "aria-snapshot", "save-snapshot",
+	"snapshot-diff", "diff-since-last", "type-text",
+	"click-button", "click-link", "select-dropdown", "choose-option",
+	"assert-text", "assert-url", "assert-visible", "refresh", "close",
+	"lifecycle", "list-tools", "call", "list-resources", "read-resource",
+	"list-prompts", "get-prompt", "wait", "watch", "help", "exit", "quit",
+}
+
+// A lineReader reads the lines of interactive mode.
+type lineReader interface {
+	// Readline returns the next line, or io.EOF at the end of the input.
+	Readline() (string, error)
+	Close() error
+}
+
+// newLineReader returns a reader for the cdp> prompt. On a terminal, it
+// edits lines with the arrow keys, keeps their history in --history-file,
+// and completes commands, and the tool names, resource URIs, and prompt names
+// of the server after call, read-resource, and get-prompt, with tab.
+// Otherwise, as when commands are piped in, it reads plain lines.
+func newLineReader(ctx context.Context, cs *mcp.ClientSession) (lineReader, error) {
+	if !readline.IsTerminal(int(os.Stdin.Fd())) || !readline.IsTerminal(int(os.Stdout.Fd())) {
+		return &scannerReader{prompt: "cdp> ", scanner: bufio.NewScanner(os.Stdin)}, nil
+	}
+
+	var tools, uris, prompts []string
+	for tool, err := range cs.Tools(ctx, nil) {
+		if err != nil {
+			// Commands still complete without the tool names.
+			fmt.Printf("Warning: failed to list tools for completion: %v\n", err)
+			break
+		}
+		tools = append(tools, tool.Name)
+	}
+	// Servers without resources or prompts only leave these empty.
+	for resource, err := range cs.Resources(ctx, nil) {
+		if err != nil {
+			break
+		}
+		uris = append(uris, resource.URI)
+	}
+	for prompt, err := range cs.Prompts(ctx, nil) {
+		if err != nil {
+			break
+		}
+		prompts = append(prompts, prompt.Name)
+	}
+	sort.Strings(tools)
+	sort.Strings(uris)
+	sort.Strings(prompts)
+	names := map[string][]string{"call": tools, "read-resource": uris, "get-prompt": prompts}
+	items := make([]readline.PrefixCompleterInterface, len(interactiveCommands))
+	for i, command := range interactiveCommands {
+		if names, ok := names[command]; ok {
+			items[i] = readline.PcItem(command, readline.PcItemDynamic(func(string) []string { return names }))
+		} else {
+			items[i] = readline.PcItem(command)
+		}
+	}
+
+	return readline.NewEx(&readline.Config{
+		Prompt:            "cdp> ",
+		HistoryFile:       *historyFile,
+		HistorySearchFold: true,
+		AutoComplete:      readline.NewPrefixCompleter(items...),
+		InterruptPrompt:   "^C",
+		EOFPrompt:         "exit",
+	})
+}
+
+// scannerReader reads lines without editing, showing the prompt before
+// each.
+type scannerReader struct {
+	prompt  string
+	scanner *bufio.Scanner
+}
+
+func (r *scannerReader) Readline() (string, error) {
+	fmt.Print(r.prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return strings.TrimRight(r.scanner.Text(), "\r"), nil
+}
+
+func (r *scannerReader) Close() error {
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// selectorCommands are the commands whose first argument is a selector the
+// server may resolve to another one.
+var selectorCommands = map[string]bool{
+	"click":           true,
+	"type-text":       true,
+	"click-button":    true,
+	"click-link":      true,
+	"select-dropdown": true,
+	"choose-option":   true,
+}
+
+// A recorder writes the commands run in interactive mode to a script that
+// run-script can replay.
+type recorder struct {
+	file string
+	f    *os.File
+	w    *bufio.Writer
+}
+
+// newRecorder creates the script file, replacing any file of that name.
+func newRecorder(file string) (*recorder, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create script file: %w", err)
+	}
+	rec := &recorder{file: file, f: f, w: bufio.NewWriter(f)}
+	fmt.Fprintf(rec.w, "# Recorded by cdpbrowser-client on %s\n", time.Now().Format(time.RFC1123))
+	fmt.Fprintf(rec.w, "# Replay with: cdpbrowser-client run-script %s\n\n", quoteArg(file))
+	return rec, rec.w.Flush()
+}
+
+// run runs a command and, if it succeeds, records it. A selector the server
+// resolved to another one is recorded as resolved, so the replay does not
+// depend on the smart selector matching the same element again; the command
+// as typed is kept in a comment.
+func (r *recorder) run(ctx context.Context, cs *mcp.ClientSession, args []string) error {
+	var resolved string
+	ctx = withResultObserver(ctx, func(tool string, result *mcp.CallToolResult) {
+		if result.IsError {
+			return
+		}
+		if m, ok := result.StructuredContent.(map[string]any); ok {
+			resolved = matchedSelector(m)
+		}
+	})
+	if err := runCommand(ctx, cs, args); err != nil {
+		return err
+	}
+	recorded := args
+	if selectorCommands[args[0]] && len(args) > 1 && resolved != "" && resolved != args[1] {
+		fmt.Fprintf(r.w, "# %s\n", quoteArgs(args))
+		recorded = append([]string{args[0], resolved}, args[2:]...)
+	}
+	fmt.Fprintln(r.w, quoteArgs(recorded))
+	// Flush each command, so that the script survives a crash.
+	if err := r.w.Flush(); err != nil {
+		return fmt.Errorf("failed to record the command: %w", err)
+	}
+	return nil
+}
+
+// close closes the script file.
+func (r *recorder) close() error {
+	err := r.w.Flush()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write script file: %w", err)
+	}
+	fmt.Printf("Recorded script saved to %s\n", r.file)
+	return nil
+}
+
+// matchedSelector returns the selector from the structured result of a
+// smart targeting tool, a selector match, or "" if there is none. XPath is
+// prefixed with xpath= unless it starts with / or (/, so that the replay
+// does not take it for CSS.
+func matchedSelector(m map[string]any) string {
+	selector, _ := m["selector"].(string)
+	if _, ok := m["strategy"]; !ok || selector == "" {
+		return ""
+	}
+	if xpath, _ := m["xpath"].(bool); xpath && !strings.HasPrefix(selector, "/") && !strings.HasPrefix(selector, "(/") {
+		return "xpath=" + selector
+	}
+	return selector
+}
+
+// quoteArgs formats args as a line that tokenize splits back into args.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteArg quotes arg for tokenize, if it needs quoting. Single quotes keep
+// ${ from being expanded when the script runs.
+func quoteArg(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,#?&", r))
+	}) < 0 && !strings.HasPrefix(arg, "#") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
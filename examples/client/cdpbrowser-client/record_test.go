@@ -0,0 +1,47 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestQuoteArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"navigate", "https://example.com/search?q=mcp&page=2"},
+		{"type-text", "#q", "it's ${HOME} \\ \"quoted\""},
+		{"click-button", `input[name="btnK"]`},
+		{"select-dropdown", "country", ""},
+	} {
+		line := quoteArgs(args)
+		got, err := tokenize(line, func(name string) (string, error) { return "expanded", nil })
+		if err != nil || !slices.Equal(got, args) {
+			t.Errorf("tokenize(quoteArgs(%q)) = %q, %v (line %s)", args, got, err, line)
+		}
+	}
+	if got := quoteArgs([]string{"navigate", "https://example.com/"}); got != "navigate https://example.com/" {
+		t.Errorf("quoteArgs quoted a plain URL: %s", got)
+	}
+}
+
+func TestMatchedSelector(t *testing.T) {
+	tests := []struct {
+		result map[string]any
+		want   string
+	}{
+		{map[string]any{"selector": `[aria-label="Submit"]`, "strategy": "aria-label"}, `[aria-label="Submit"]`},
+		{map[string]any{"selector": `//button[text()="Log in"]`, "xpath": true, "strategy": "fallback"}, `//button[text()="Log in"]`},
+		{map[string]any{"selector": `.//a`, "xpath": true, "strategy": "text"}, `xpath=.//a`},
+		// Results of other tools are not selector matches.
+		{map[string]any{"selector": "#q"}, ""},
+		{map[string]any{"url": "https://example.com"}, ""},
+	}
+	for _, tt := range tests {
+		if got := matchedSelector(tt.result); got != tt.want {
+			t.Errorf("matchedSelector(%v) = %q, want %q", tt.result, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A scriptStep is the outcome of one command of a script, for the report.
+type scriptStep struct {
+	File     string  `json:"file"`
+	Line     int     `json:"line"`
+	Command  string  `json:"command"`
+	Passed   bool    `json:"passed"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+}
+
+// newScriptStep records the outcome of s, which took d and failed with err
+// if it is not nil.
+func newScriptStep(s *scriptStmt, d time.Duration, err error) scriptStep {
+	step := scriptStep{File: s.file, Line: s.line, Command: s.text, Passed: err == nil, Duration: d.Seconds()}
+	if err != nil {
+		step.Error = err.Error()
+		// The tool's own message says what went wrong.
+		var te *toolError
+		if errors.As(err, &te) && te.message != "" {
+			step.Error = te.message
+		}
+	}
+	return step
+}
+
+// scriptReport is the JSON report of a script.
+type scriptReport struct {
+	Script string `json:"script"`
+	// URL is the URL the script ran against, in a parallel run.
+	URL      string       `json:"url,omitempty"`
+	Passed   int          `json:"passed"`
+	Failed   int          `json:"failed"`
+	Duration float64      `json:"duration_seconds"`
+	Steps    []scriptStep `json:"steps"`
+}
+
+// newScriptReport counts the passed and failed steps of script, which took
+// d.
+func newScriptReport(script string, steps []scriptStep, d time.Duration) scriptReport {
+	report := scriptReport{Script: script, Duration: d.Seconds(), Steps: steps}
+	for _, step := range steps {
+		if step.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+	}
+	if report.Steps == nil {
+		report.Steps = []scriptStep{}
+	}
+	return report
+}
+
+// JUnit XML, as read by CI systems: the script is a test suite and each
+// step a test case.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// checkReportFile reports an error if the format of a --report file cannot
+// be told from its extension.
+func checkReportFile(file string) error {
+	switch filepath.Ext(file) {
+	case ".json", ".xml":
+		return nil
+	}
+	return fmt.Errorf("report file %s must end in .xml (JUnit) or .json", file)
+}
+
+// writeReport writes the steps of script, which took d, to file, as JUnit
+// XML or JSON depending on its extension.
+func writeReport(file, script string, steps []scriptStep, d time.Duration) error {
+	report := newScriptReport(script, steps, d)
+	return writeReportFile(file, report, []scriptReport{report})
+}
+
+// writeReports writes the reports of a parallel run to file: a JUnit test
+// suite for each, or a JSON array of them.
+func writeReports(file string, reports []scriptReport) error {
+	return writeReportFile(file, reports, reports)
+}
+
+// writeReportFile writes v to a .json file, or reports as JUnit XML to a
+// .xml file.
+func writeReportFile(file string, v any, reports []scriptReport) error {
+	if err := checkReportFile(file); err != nil {
+		return err
+	}
+	var data []byte
+	var err error
+	if filepath.Ext(file) == ".json" {
+		data, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		data, err = junitReport(reports)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// junitReport formats reports as JUnit XML, with a test suite for each. The
+// suites of a parallel run are named after the script and the URL.
+func junitReport(reports []scriptReport) ([]byte, error) {
+	seconds := func(s float64) string { return fmt.Sprintf("%.3f", s) }
+	var suites junitTestSuites
+	for _, r := range reports {
+		suite := junitTestSuite{
+			Name:     r.Script,
+			Tests:    len(r.Steps),
+			Failures: r.Failed,
+			Time:     seconds(r.Duration),
+		}
+		if r.URL != "" {
+			suite.Name += " " + r.URL
+		}
+		classname := strings.TrimSuffix(filepath.Base(r.Script), filepath.Ext(r.Script))
+		for _, step := range r.Steps {
+			c := junitTestCase{
+				Name:      fmt.Sprintf("%s:%d %s", step.File, step.Line, step.Command),
+				Classname: classname,
+				Time:      seconds(step.Duration),
+			}
+			if !step.Passed {
+				message, _, _ := strings.Cut(step.Error, "\n")
+				c.Failure = &junitFailure{Message: message, Text: step.Error}
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+		suites.Suites = append(suites.Suites, suite)
+	}
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
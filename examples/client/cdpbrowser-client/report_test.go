@@ -0,0 +1,66 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteReport(t *testing.T) {
+	steps := []scriptStep{
+		newScriptStep(&scriptStmt{file: "login.txt", line: 1, text: "navigate https://example.com"}, time.Second, nil),
+		newScriptStep(&scriptStmt{file: "login.txt", line: 2, text: `assert-text "Welcome"`}, 2*time.Second,
+			&toolError{tool: "assert_text_present", message: "Assertion failed: \"Welcome\" is not shown\nPage text: Sign in"}),
+		newScriptStep(&scriptStmt{file: "login.txt", line: 3, text: "wait x"}, 0, errors.New("invalid wait duration")),
+	}
+	dir := t.TempDir()
+
+	jsonFile := filepath.Join(dir, "report.json")
+	if err := writeReport(jsonFile, "login.txt", steps, 3*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report scriptReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Passed != 1 || report.Failed != 2 || len(report.Steps) != 3 {
+		t.Errorf("JSON report = %+v, want 1 passed and 2 failed steps", report)
+	}
+	if got := report.Steps[1].Error; !strings.HasPrefix(got, "Assertion failed") {
+		t.Errorf("error of a failed tool = %q, want the tool's message", got)
+	}
+
+	xmlFile := filepath.Join(dir, "report.xml")
+	if err := writeReport(xmlFile, "login.txt", steps, 3*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(xmlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<testsuite name="login.txt" tests="3" failures="2" time="3.000">`,
+		`<testcase name="login.txt:1 navigate https://example.com" classname="login" time="1.000"></testcase>`,
+		`<failure message="Assertion failed: &#34;Welcome&#34; is not shown">`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JUnit report does not contain %s:\n%s", want, data)
+		}
+	}
+
+	if err := writeReport(filepath.Join(dir, "report.txt"), "login.txt", steps, 0); err == nil {
+		t.Error("writeReport to a .txt file succeeded, want an error")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func listResources(ctx context.Context, cs *mcp.ClientSession) error {
+	fmt.Println("Listing available resources from server...")
+
+	for resource, err := range cs.Resources(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("failed to list resources: %w", err)
+		}
+		fmt.Printf("- %s%s: %s\n", resource.URI, mimeTypeSuffix(resource.MIMEType), describe(resource.Name, resource.Description))
+	}
+	for template, err := range cs.ResourceTemplates(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("failed to list resource templates: %w", err)
+		}
+		fmt.Printf("- %s%s (template): %s\n", template.URITemplate, mimeTypeSuffix(template.MIMEType), describe(template.Name, template.Description))
+	}
+	return nil
+}
+
+func readResource(ctx context.Context, cs *mcp.ClientSession, uri string) error {
+	fmt.Printf("Reading resource: %s\n", uri)
+
+	result, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", uri, err)
+	}
+	for _, rc := range result.Contents {
+		printResourceContents("read-resource", rc)
+	}
+	return nil
+}
+
+func listPrompts(ctx context.Context, cs *mcp.ClientSession) error {
+	fmt.Println("Listing available prompts from server...")
+
+	for prompt, err := range cs.Prompts(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("failed to list prompts: %w", err)
+		}
+		var args []string
+		for _, arg := range prompt.Arguments {
+			if arg.Required {
+				args = append(args, arg.Name+"=...")
+			} else {
+				args = append(args, "["+arg.Name+"=...]")
+			}
+		}
+		usage := strings.Join(append([]string{prompt.Name}, args...), " ")
+		fmt.Printf("- %s: %s\n", usage, prompt.Description)
+	}
+	return nil
+}
+
+func getPrompt(ctx context.Context, cs *mcp.ClientSession, name string, arguments map[string]string) error {
+	fmt.Printf("Getting prompt: %s\n", name)
+
+	result, err := cs.GetPrompt(ctx, &mcp.GetPromptParams{
+		Name:      name,
+		Arguments: arguments,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get prompt %s: %w", name, err)
+	}
+	if result.Description != "" {
+		fmt.Println(result.Description)
+	}
+	for _, m := range result.Messages {
+		fmt.Printf("[%s] ", m.Role)
+		printContent("get-prompt", m.Content)
+	}
+	return nil
+}
+
+// promptArguments parses the name=value arguments of get-prompt.
+func promptArguments(args []string) (map[string]string, error) {
+	arguments := make(map[string]string)
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("prompt argument %q is not name=value", arg)
+		}
+		arguments[name] = value
+	}
+	return arguments, nil
+}
+
+// mimeTypeSuffix returns " (mimeType)", or "" if mimeType is empty.
+func mimeTypeSuffix(mimeType string) string {
+	if mimeType == "" {
+		return ""
+	}
+	return " (" + mimeType + ")"
+}
+
+// describe returns the description of a resource, or its name if it has
+// none.
+func describe(name, description string) string {
+	if description != "" {
+		return description
+	}
+	return name
+}
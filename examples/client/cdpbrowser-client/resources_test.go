@@ -0,0 +1,27 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestPromptArguments(t *testing.T) {
+	got, err := promptArguments([]string{"url=https://example.com/?q=a=b", "goal=", "style=brief"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"url": "https://example.com/?q=a=b", "goal": "", "style": "brief"}
+	if !maps.Equal(got, want) {
+		t.Errorf("promptArguments = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"url", "=value"} {
+		if _, err := promptArguments([]string{bad}); err == nil {
+			t.Errorf("promptArguments(%q) succeeded, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var (
+	// callTimeout bounds each tool call.
+	callTimeout = flag.Duration("timeout", 0, "give up on a tool call after `duration`, e.g. 30s; 0 waits as long as it takes")
+	// callRetries is how many times a failed tool call is tried again.
+	callRetries = flag.Int("retries", 0, "call a tool up to `n` more times when the call times out or the tool reports an error")
+	// retryDelay is the wait between the tries of a tool call.
+	retryDelay = flag.Duration("retry-delay", time.Second, "wait `duration` before calling a tool again")
+)
+
+// checkCallFlags reports an error if --timeout, --retries, or --retry-delay
+// is negative.
+func checkCallFlags() error {
+	switch {
+	case *callTimeout < 0:
+		return fmt.Errorf("--timeout must not be negative, got %v", *callTimeout)
+	case *callRetries < 0:
+		return fmt.Errorf("--retries must not be negative, got %d", *callRetries)
+	case *retryDelay < 0:
+		return fmt.Errorf("--retry-delay must not be negative, got %v", *retryDelay)
+	}
+	return nil
+}
+
+// retryCalls is sending middleware that applies --timeout to each tool call,
+// and tries it again up to --retries times if it times out or the tool
+// reports an error. A call that fails otherwise, as when the server is gone,
+// or whose context is done, is not tried again. When a call times out, the
+// client tells the server to cancel it.
+func retryCalls(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != "tools/call" {
+			return next(ctx, method, req)
+		}
+		return callWithRetries(ctx, *callTimeout, *callRetries, *retryDelay, func(ctx context.Context) (mcp.Result, error) {
+			return next(ctx, method, req)
+		})
+	}
+}
+
+// callWithRetries makes a tool call with call, each try bounded by timeout
+// if it is not 0, and tries it again up to retries times, delay apart.
+func callWithRetries(ctx context.Context, timeout time.Duration, retries int, delay time.Duration, call func(context.Context) (mcp.Result, error)) (mcp.Result, error) {
+	for try := 0; ; try++ {
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		res, err := call(callCtx)
+		cancel()
+
+		var failure string
+		switch {
+		case err == nil && !isErrorResult(res):
+			return res, nil
+		case err == nil:
+			failure = "the tool reported an error"
+		case ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded):
+			err = fmt.Errorf("timed out after %v: %w", timeout, err)
+			failure = "the call timed out"
+		default:
+			return res, err
+		}
+		if try == retries {
+			return res, err
+		}
+		fmt.Printf("Retrying in %v (%d of %d): %s\n", delay, try+1, retries, failure)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// isErrorResult reports whether res is the result of a tool that reported
+// an error.
+func isErrorResult(res mcp.Result) bool {
+	r, ok := res.(*mcp.CallToolResult)
+	return ok && r.IsError
+}
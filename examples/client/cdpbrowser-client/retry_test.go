@@ -0,0 +1,62 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestCallWithRetries(t *testing.T) {
+	ctx := context.Background()
+	errorResult := &mcp.CallToolResult{IsError: true}
+	okResult := &mcp.CallToolResult{}
+
+	// Each call returns the next of results, or waits for its context to
+	// time out if that is nil.
+	calls := func(results ...mcp.Result) (func(context.Context) (mcp.Result, error), *int) {
+		n := 0
+		return func(ctx context.Context) (mcp.Result, error) {
+			res := results[n]
+			n++
+			if res == nil {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return res, nil
+		}, &n
+	}
+
+	call, n := calls(nil, errorResult, okResult)
+	res, err := callWithRetries(ctx, 10*time.Millisecond, 2, time.Millisecond, call)
+	if err != nil || res != okResult || *n != 3 {
+		t.Errorf("after a timeout and a tool error: got %v, %v in %d calls, want success in 3", res, err, *n)
+	}
+
+	call, n = calls(nil, nil)
+	_, err = callWithRetries(ctx, 10*time.Millisecond, 1, time.Millisecond, call)
+	if !errors.Is(err, context.DeadlineExceeded) || *n != 2 {
+		t.Errorf("after two timeouts: got %v in %d calls, want a timeout in 2", err, *n)
+	}
+
+	call, n = calls(errorResult, okResult)
+	res, err = callWithRetries(ctx, 0, 0, time.Millisecond, call)
+	if err != nil || res != errorResult || *n != 1 {
+		t.Errorf("without retries: got %v, %v in %d calls, want the error result in 1", res, err, *n)
+	}
+
+	lost := 0
+	_, err = callWithRetries(ctx, 0, 3, time.Millisecond, func(context.Context) (mcp.Result, error) {
+		lost++
+		return nil, mcp.ErrConnectionClosed
+	})
+	if !errors.Is(err, mcp.ErrConnectionClosed) || lost != 1 {
+		t.Errorf("with the connection closed: got %v in %d calls, want it in 1", err, lost)
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// pageTextURI is the server resource holding the text of the current page,
+// which if-contains searches.
+const pageTextURI = "page://current/text"
+
+// A scriptStmt is a line of a script: a command, or a repeat or if-contains
+// block with the statements up to its end.
+type scriptStmt struct {
+	file string // the script file, as given to run-script or include
+	line int
+	text string
+	// command is the first word of the line, before variables are
+	// expanded.
+	command string
+	body    []*scriptStmt // of a repeat or if-contains block
+	// orElse holds the statements between else and end of if-contains.
+	orElse  []*scriptStmt
+	hasElse bool
+}
+
+func (s *scriptStmt) String() string {
+	return fmt.Sprintf("%s:%d", s.file, s.line)
+}
+
+// isBlock reports whether s starts a repeat or if-contains block.
+func (s *scriptStmt) isBlock() bool {
+	return s.command == "repeat" || s.command == "if-contains"
+}
+
+// parseScript reads the statements of a script, matching the blocks
+// started by repeat and if-contains with their else and end lines. Empty
+// lines and comments, starting with #, are left out.
+func parseScript(file string, r io.Reader) ([]*scriptStmt, error) {
+	var top []*scriptStmt
+	var open []*scriptStmt // the blocks being parsed, innermost last
+	add := func(s *scriptStmt) {
+		if len(open) == 0 {
+			top = append(top, s)
+			return
+		}
+		b := open[len(open)-1]
+		if b.hasElse {
+			b.orElse = append(b.orElse, s)
+		} else {
+			b.body = append(b.body, s)
+		}
+	}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		s := &scriptStmt{file: file, line: n, text: text}
+		// Variables are expanded when the line runs; only the command
+		// matters here.
+		args, _ := tokenize(text, func(string) (string, error) { return "", nil })
+		if len(args) > 0 {
+			s.command = args[0]
+		}
+		switch s.command {
+		case "repeat", "if-contains":
+			add(s)
+			open = append(open, s)
+		case "else":
+			if len(open) == 0 || open[len(open)-1].command != "if-contains" || open[len(open)-1].hasElse {
+				return nil, fmt.Errorf("%s: else without if-contains", s)
+			}
+			open[len(open)-1].hasElse = true
+		case "end":
+			if len(open) == 0 {
+				return nil, fmt.Errorf("%s: end without repeat or if-contains", s)
+			}
+			open = open[:len(open)-1]
+		default:
+			add(s)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading script file: %w", err)
+	}
+	if len(open) > 0 {
+		b := open[len(open)-1]
+		return nil, fmt.Errorf("%s: %s has no end", b, b.command)
+	}
+	return top, nil
+}
+
+// A scriptRunner runs the statements of a script and the scripts it
+// includes, sharing their variables.
+type scriptRunner struct {
+	ctx       context.Context
+	cs        *mcp.ClientSession
+	vars      map[string]string
+	including []string // the script files being run, outermost first
+	commands  int
+	failed    int
+	steps     []scriptStep // the commands run, for the report
+	// operator answers pause and confirm; nil means stdinOperator.
+	operator *operator
+	// name identifies the run to the operator, as the session and URL of
+	// a parallel run; it may be empty.
+	name string
+}
+
+// errScriptStopped is returned by the scriptRunner once a failed command
+// has ended the script.
+var errScriptStopped = errors.New("script stopped")
+
+// lookup expands ${NAME}: a variable set by the script, or else an
+// environment variable.
+func (r *scriptRunner) lookup(name string) (string, error) {
+	if v, ok := r.vars[name]; ok {
+		return v, nil
+	}
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("undefined variable %s", name)
+}
+
+// runFile parses and runs the script at path.
+func (r *scriptRunner) runFile(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(r.including, abs) {
+		return fmt.Errorf("script %s includes itself", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open script file %s: %w", path, err)
+	}
+	stmts, err := parseScript(path, f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	r.including = append(r.including, abs)
+	defer func() { r.including = r.including[:len(r.including)-1] }()
+	return r.run(stmts)
+}
+
+// run runs stmts in order. It returns errScriptStopped, wrapped with the
+// failure that stopped the script, or nil.
+func (r *scriptRunner) run(stmts []*scriptStmt) error {
+	for _, s := range stmts {
+		fmt.Printf("%s: %s\n", s, s.text)
+		start := time.Now()
+		err := r.exec(s)
+		if errors.Is(err, errScriptStopped) {
+			return err
+		}
+		// Blocks and includes are reported through their commands, unless
+		// they fail themselves.
+		if err != nil || !s.isBlock() && s.command != "include" {
+			r.steps = append(r.steps, newScriptStep(s, time.Since(start), err))
+		}
+		if err != nil {
+			r.failed++
+			fmt.Printf("  Error: %v (%s)\n", err, s)
+			// Later commands cannot run without the server.
+			if errors.Is(err, mcp.ErrConnectionClosed) {
+				return fmt.Errorf("%w: lost the connection to the server at %s: %w", errScriptStopped, s, err)
+			}
+			if !*continueOnError {
+				return fmt.Errorf("%w at %s: %w", errScriptStopped, s, err)
+			}
+		}
+		fmt.Println() // Add spacing between commands
+	}
+	return nil
+}
+
+// exec runs one statement, including the statements of its block.
+func (r *scriptRunner) exec(s *scriptStmt) error {
+	r.commands++
+	args, err := tokenize(s.text, r.lookup)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "set":
+		if len(args) != 3 {
+			return &usageError{usage: "set <name> <value>"}
+		}
+		if !validVariableName(args[1]) {
+			return fmt.Errorf("invalid variable name %q", args[1])
+		}
+		r.vars[args[1]] = args[2]
+		return nil
+
+	case "repeat":
+		const usage = "repeat <count> ... end"
+		if len(args) != 2 {
+			return &usageError{usage: usage}
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return &usageError{usage: usage, reason: fmt.Sprintf("invalid count %q", args[1])}
+		}
+		for i := range n {
+			fmt.Printf("  Iteration %d of %d\n", i+1, n)
+			if err := r.run(s.body); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "if-contains":
+		if len(args) != 2 {
+			return &usageError{usage: "if-contains <text> ... [else ...] end"}
+		}
+		text, err := pageText(r.ctx, r.cs)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(text, args[1]) {
+			fmt.Printf("  The page contains %q\n", args[1])
+			return r.run(s.body)
+		}
+		fmt.Printf("  The page does not contain %q\n", args[1])
+		return r.run(s.orElse)
+
+	case "pause", "confirm":
+		if len(args) != 2 {
+			return &usageError{usage: args[0] + " <message>"}
+		}
+		o := r.operator
+		if o == nil {
+			o = stdinOperator
+		}
+		who := ""
+		if r.name != "" {
+			who = " (" + r.name + ")"
+		}
+		if args[0] == "pause" {
+			return o.pause(r.ctx, who, args[1])
+		}
+		return o.confirm(r.ctx, who, args[1])
+
+	case "include":
+		if len(args) != 2 {
+			return &usageError{usage: "include <script-file>"}
+		}
+		path := args[1]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(s.file), path)
+		}
+		return r.runFile(path)
+
+	default:
+		return runCommand(r.ctx, r.cs, args)
+	}
+}
+
+// pageText returns the text of the current page.
+func pageText(ctx context.Context, cs *mcp.ClientSession) (string, error) {
+	res, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: pageTextURI})
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", pageTextURI, err)
+	}
+	var b strings.Builder
+	for _, c := range res.Contents {
+		b.WriteString(c.Text)
+	}
+	return b.String(), nil
+}
+
+func runScript(ctx context.Context, cs *mcp.ClientSession, scriptFile string) error {
+	fmt.Printf("Running script file: %s\n", scriptFile)
+	fmt.Println("Executing script commands...")
+	fmt.Println("=" + strings.Repeat("=", 50))
+
+	start := time.Now()
+	r := &scriptRunner{ctx: ctx, cs: cs, vars: make(map[string]string)}
+	err := r.runFile(scriptFile)
+	if err != nil && !errors.Is(err, errScriptStopped) {
+		// The script could not be read or parsed.
+		r.steps = append(r.steps, scriptStep{File: scriptFile, Command: "run-script " + scriptFile, Error: err.Error()})
+	}
+	if *reportFile != "" {
+		if werr := writeReport(*reportFile, scriptFile, r.steps, time.Since(start)); werr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", werr)
+			if err == nil {
+				err = werr
+			}
+		} else {
+			fmt.Printf("Report written to %s\n", *reportFile)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("=" + strings.Repeat("=", 50))
+	if r.failed > 0 {
+		return fmt.Errorf("%d of %d script commands failed", r.failed, r.commands)
+	}
+	fmt.Println("Script execution completed")
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	const script = `# Search twice.
+set QUERY "mcp browser"
+repeat 2
+	navigate https://example.com
+	if-contains "${QUERY}"
+		screenshot
+	else
+		include search.txt
+	end
+end
+list-tools
+`
+	stmts, err := parseScript("test.txt", strings.NewReader(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// format lists the statements as line numbers, with blocks in brackets.
+	var format func([]*scriptStmt) string
+	format = func(stmts []*scriptStmt) string {
+		var b strings.Builder
+		for _, s := range stmts {
+			b.WriteString(" " + s.String())
+			if s.isBlock() {
+				b.WriteString(" [" + format(s.body))
+				if s.hasElse {
+					b.WriteString(" | " + format(s.orElse))
+				}
+				b.WriteString(" ]")
+			}
+		}
+		return strings.TrimSpace(b.String())
+	}
+	want := "test.txt:2 test.txt:3 [test.txt:4 test.txt:5 [test.txt:6 | test.txt:8 ] ] test.txt:11"
+	if got := format(stmts); got != want {
+		t.Errorf("parseScript:\ngot  %s\nwant %s", got, want)
+	}
+
+	for _, bad := range []string{
+		"repeat 2\nscreenshot\n",
+		"screenshot\nend\n",
+		"repeat 2\nelse\nend\n",
+		"if-contains x\nelse\nelse\nend\n",
+	} {
+		if _, err := parseScript("bad.txt", strings.NewReader(bad)); err == nil {
+			t.Errorf("parseScript(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestPauseConfirm(t *testing.T) {
+	const script = `pause "Log in, then press Enter"
+confirm "Is the CAPTCHA solved?"
+`
+	run := func(input string) (string, *scriptRunner, error) {
+		stmts, err := parseScript("login.txt", strings.NewReader(script))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		r := &scriptRunner{
+			ctx:      context.Background(),
+			vars:     make(map[string]string),
+			operator: newOperator(strings.NewReader(input), &out),
+			name:     "session 1",
+		}
+		err = r.run(stmts)
+		return out.String(), r, err
+	}
+
+	out, r, err := run("\n yes \n")
+	if err != nil {
+		t.Fatalf("run = %v", err)
+	}
+	if r.commands != 2 || r.failed != 0 {
+		t.Errorf("commands, failed = %d, %d, want 2, 0", r.commands, r.failed)
+	}
+	want := "  PAUSED (session 1): Log in, then press Enter\n" +
+		"  Press Enter to continue... " +
+		"  CONFIRM (session 1): Is the CAPTCHA solved? [y/N] "
+	if out != want {
+		t.Errorf("prompts:\ngot  %q\nwant %q", out, want)
+	}
+
+	if _, _, err := run("\nno\n"); !errors.Is(err, errScriptStopped) || !strings.Contains(err.Error(), "did not confirm") {
+		t.Errorf("declined confirm: run = %v", err)
+	}
+	if _, _, err := run(""); !errors.Is(err, errNoOperator) {
+		t.Errorf("without input: run = %v, want %v", err, errNoOperator)
+	}
+}
@@ -0,0 +1,444 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// A snapshotElement is an element of an ARIA snapshot, as in the
+// structured result of aria_snapshot, with the section it was listed in.
+type snapshotElement struct {
+	Category  string `json:"category"`
+	ID        int    `json:"id,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Selector  string `json:"selector"`
+	AriaLabel string `json:"ariaLabel,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+	Href      string `json:"href,omitempty"`
+	Value     string `json:"value,omitempty"`
+	Level     int    `json:"level,omitempty"`
+	Text      string `json:"text,omitempty"`
+}
+
+// label returns what the element shows: its accessible name, or else its
+// text, ARIA label, or value.
+func (e *snapshotElement) label() string {
+	for _, s := range []string{e.Name, e.Text, e.AriaLabel, e.Value} {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// A snapshot is the structured result of aria_snapshot.
+type snapshot struct {
+	Page struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	} `json:"page"`
+	Landmarks   []snapshotElement `json:"landmarks,omitempty"`
+	Interactive []snapshotElement `json:"interactive,omitempty"`
+	Headings    []snapshotElement `json:"headings,omitempty"`
+	Content     []snapshotElement `json:"content,omitempty"`
+}
+
+// snapshotCategories are the sections of a snapshot, as --only takes them.
+var snapshotCategories = []string{"landmarks", "interactive", "headings", "content"}
+
+// elements returns the elements of s, section by section.
+func (s *snapshot) elements() []snapshotElement {
+	var all []snapshotElement
+	for i, section := range [][]snapshotElement{s.Landmarks, s.Interactive, s.Headings, s.Content} {
+		for _, e := range section {
+			e.Category = snapshotCategories[i]
+			all = append(all, e)
+		}
+	}
+	return all
+}
+
+// A snapshotFilter picks the elements of an ARIA snapshot to print, and how
+// to print them, for the options of aria-snapshot.
+type snapshotFilter struct {
+	only   string         // a section of snapshotCategories, or "" for all
+	grep   *regexp.Regexp // matched against the fields that identify an element
+	max    int            // the most elements to print, or 0 for all
+	format string         // table, json, or md
+}
+
+// snapshotFormats are the formats of --format.
+var snapshotFormats = []string{"table", "json", "md"}
+
+// parseSnapshotFilter separates the --only, --grep, --max, and --format
+// options of aria-snapshot, given as --name value or --name=value anywhere
+// among args, from the other arguments. It returns nil for the filter if no
+// option was given.
+func parseSnapshotFilter(args []string) ([]string, *snapshotFilter, error) {
+	var rest []string
+	var f *snapshotFilter
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			rest = append(rest, args[i])
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, nil, fmt.Errorf("option %s needs a value", args[i])
+			}
+			i++
+			value = args[i]
+		}
+		if f == nil {
+			f = &snapshotFilter{format: "table"}
+		}
+		switch name {
+		case "only":
+			if !slices.Contains(snapshotCategories, value) {
+				return nil, nil, fmt.Errorf("invalid --only %q (use %s)", value, strings.Join(snapshotCategories, ", "))
+			}
+			f.only = value
+		case "grep":
+			// Element names are matched regardless of case.
+			re, err := regexp.Compile("(?i)" + value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid --grep pattern: %w", err)
+			}
+			f.grep = re
+		case "max":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, nil, fmt.Errorf("invalid --max %q", value)
+			}
+			f.max = n
+		case "format":
+			if !slices.Contains(snapshotFormats, value) {
+				return nil, nil, fmt.Errorf("invalid --format %q (use %s)", value, strings.Join(snapshotFormats, ", "))
+			}
+			f.format = value
+		default:
+			return nil, nil, fmt.Errorf("unknown option %s", args[i])
+		}
+	}
+	return rest, f, nil
+}
+
+// apply returns the elements of s that pass f, up to its maximum, and the
+// number that passed in all.
+func (f *snapshotFilter) apply(s *snapshot) ([]snapshotElement, int) {
+	var matched []snapshotElement
+	for _, e := range s.elements() {
+		if f.only != "" && e.Category != f.only {
+			continue
+		}
+		if f.grep != nil && !f.grep.MatchString(strings.Join([]string{e.Role, e.Name, e.Text, e.AriaLabel, e.Value, e.Selector, e.Href}, "\n")) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	total := len(matched)
+	if f.max > 0 && len(matched) > f.max {
+		matched = matched[:f.max]
+	}
+	return matched, total
+}
+
+// print writes the elements of s that pass f to w.
+func (f *snapshotFilter) print(w io.Writer, s *snapshot) error {
+	elements, total := f.apply(s)
+	switch f.format {
+	case "json":
+		if elements == nil {
+			elements = []snapshotElement{}
+		}
+		data, err := json.MarshalIndent(elements, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", data)
+		return nil
+
+	case "md":
+		fmt.Fprintln(w, "| Category | ID | Role | Name | Selector |")
+		fmt.Fprintln(w, "| --- | --- | --- | --- | --- |")
+		for _, e := range elements {
+			fmt.Fprintf(w, "| %s | %s | %s | %s | `%s` |\n", e.Category, elementID(e), markdownCell(e.Role), markdownCell(e.label()), strings.ReplaceAll(e.Selector, "|", `\|`))
+		}
+
+	default:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CATEGORY\tID\tROLE\tNAME\tSELECTOR")
+		for _, e := range elements {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Category, elementID(e), e.Role, oneLine(e.label()), e.Selector)
+		}
+		tw.Flush()
+	}
+	if len(elements) < total {
+		fmt.Fprintf(w, "%d of %d matching elements shown; raise --max to see more\n", len(elements), total)
+	} else {
+		fmt.Fprintf(w, "%d matching elements\n", total)
+	}
+	return nil
+}
+
+// elementID returns the ID of e, or "-" if it has none.
+func elementID(e snapshotElement) string {
+	if e.ID == 0 {
+		return "-"
+	}
+	return strconv.Itoa(e.ID)
+}
+
+// oneLine joins the lines of s, and shortens it to 60 characters, for a
+// table cell.
+func oneLine(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > 60 {
+		s = string(r[:59]) + "…"
+	}
+	return s
+}
+
+// markdownCell escapes s for a cell of a Markdown table.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(oneLine(s), "|", `\|`)
+}
+
+// filteredAriaSnapshot takes an ARIA snapshot and prints the elements that
+// pass f instead of the server's text.
+func filteredAriaSnapshot(ctx context.Context, cs *mcp.ClientSession, format, focus string, f *snapshotFilter) error {
+	fmt.Printf("Taking ARIA snapshot (focus: %s)...\n", focus)
+
+	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name: "aria_snapshot",
+		Arguments: map[string]interface{}{
+			"format": format,
+			"focus":  focus,
+		},
+	})
+	if err != nil || result.IsError {
+		return toolResult(ctx, "aria_snapshot", result, err)
+	}
+	s, err := decodeSnapshot(result.StructuredContent)
+	if err != nil {
+		return err
+	}
+	if listsInteractive(focus) {
+		rememberSnapshot(cs, s)
+	}
+	return f.print(os.Stdout, s)
+}
+
+// decodeSnapshot decodes the structured result of aria_snapshot.
+func decodeSnapshot(structured any) (*snapshot, error) {
+	if structured == nil {
+		return nil, fmt.Errorf("aria_snapshot returned no structured result to filter")
+	}
+	data, err := json.Marshal(structured)
+	if err != nil {
+		return nil, err
+	}
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode the ARIA snapshot: %w", err)
+	}
+	return &s, nil
+}
+
+// snapshotKey identifies an element across snapshots, by what it is and
+// where, since element IDs are only stable within a session.
+func snapshotKey(e snapshotElement) string {
+	return e.Role + "\x00" + oneLine(e.label()) + "\x00" + e.Selector
+}
+
+// diffSnapshots returns the interactive elements of after that are not in
+// before, and those of before that are not in after, in page order.
+func diffSnapshots(before, after []snapshotElement) (added, removed []snapshotElement) {
+	count := func(elements []snapshotElement) map[string]int {
+		m := make(map[string]int)
+		for _, e := range elements {
+			m[snapshotKey(e)]++
+		}
+		return m
+	}
+	// An element listed twice, as a repeated "Delete" button, counts
+	// twice, so that removing one of them shows.
+	inBefore, inAfter := count(before), count(after)
+	for _, e := range after {
+		if k := snapshotKey(e); inBefore[k] > 0 {
+			inBefore[k]--
+		} else {
+			added = append(added, e)
+		}
+	}
+	for _, e := range before {
+		if k := snapshotKey(e); inAfter[k] > 0 {
+			inAfter[k]--
+		} else {
+			removed = append(removed, e)
+		}
+	}
+	return added, removed
+}
+
+// printSnapshotDiff prints the interactive elements added and removed
+// between two snapshots.
+func printSnapshotDiff(w io.Writer, before, after []snapshotElement) {
+	added, removed := diffSnapshots(before, after)
+	line := func(sign string, e snapshotElement) {
+		fmt.Fprintf(w, "  %s %s %q  %s\n", sign, e.Role, oneLine(e.label()), e.Selector)
+	}
+	fmt.Fprintf(w, "Removed (%d):\n", len(removed))
+	for _, e := range removed {
+		line("-", e)
+	}
+	fmt.Fprintf(w, "Added (%d):\n", len(added))
+	for _, e := range added {
+		line("+", e)
+	}
+	fmt.Fprintf(w, "%d interactive elements before, %d after\n", len(before), len(after))
+}
+
+// interactiveElements returns the interactive elements of s.
+func (s *snapshot) interactiveElements() []snapshotElement {
+	var elements []snapshotElement
+	for _, e := range s.elements() {
+		if e.Category == "interactive" {
+			elements = append(elements, e)
+		}
+	}
+	return elements
+}
+
+// loadSnapshotFile reads the interactive elements of a snapshot saved by
+// save-snapshot or by the json format of aria_snapshot, or of a JSON array
+// of elements as printed by aria-snapshot --format json.
+func loadSnapshotFile(file string) ([]snapshotElement, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		var elements []snapshotElement
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot %s: %w", file, err)
+		}
+		var interactive []snapshotElement
+		for _, e := range elements {
+			if e.Category == "interactive" {
+				interactive = append(interactive, e)
+			}
+		}
+		return interactive, nil
+	}
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %w", file, err)
+	}
+	return s.interactiveElements(), nil
+}
+
+// lastSnapshots holds the snapshot each session took last, for
+// diff-since-last.
+var lastSnapshots = struct {
+	sync.Mutex
+	m map[*mcp.ClientSession]*snapshot
+}{m: make(map[*mcp.ClientSession]*snapshot)}
+
+// rememberSnapshot keeps s as the last snapshot of cs, and returns the one
+// before it, or nil.
+func rememberSnapshot(cs *mcp.ClientSession, s *snapshot) *snapshot {
+	lastSnapshots.Lock()
+	defer lastSnapshots.Unlock()
+	last := lastSnapshots.m[cs]
+	lastSnapshots.m[cs] = s
+	return last
+}
+
+// listsInteractive reports whether a snapshot with focus lists the
+// interactive elements of the page, so that diff-since-last can compare
+// with it.
+func listsInteractive(focus string) bool {
+	return focus == "all" || focus == "interactive"
+}
+
+// takeSnapshot takes a full ARIA snapshot of the page, without printing it,
+// and remembers it for diff-since-last.
+func takeSnapshot(ctx context.Context, cs *mcp.ClientSession) (s, last *snapshot, err error) {
+	result, err := cs.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "aria_snapshot",
+		Arguments: map[string]interface{}{"focus": "all"},
+	})
+	if err != nil || result.IsError {
+		return nil, nil, toolResult(ctx, "aria_snapshot", result, err)
+	}
+	if s, err = decodeSnapshot(result.StructuredContent); err != nil {
+		return nil, nil, err
+	}
+	return s, rememberSnapshot(cs, s), nil
+}
+
+func saveSnapshot(ctx context.Context, cs *mcp.ClientSession, file string) error {
+	fmt.Printf("Saving ARIA snapshot to %s...\n", file)
+
+	s, _, err := takeSnapshot(ctx, cs)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	fmt.Printf("Saved %d interactive elements of %s\n", len(s.interactiveElements()), s.Page.URL)
+	return nil
+}
+
+func snapshotDiff(beforeFile, afterFile string) error {
+	before, err := loadSnapshotFile(beforeFile)
+	if err != nil {
+		return err
+	}
+	after, err := loadSnapshotFile(afterFile)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Interactive elements of %s compared with %s:\n", afterFile, beforeFile)
+	printSnapshotDiff(os.Stdout, before, after)
+	return nil
+}
+
+func diffSinceLast(ctx context.Context, cs *mcp.ClientSession) error {
+	fmt.Println("Taking ARIA snapshot to compare with the last one...")
+
+	s, last, err := takeSnapshot(ctx, cs)
+	if err != nil {
+		return err
+	}
+	if last == nil {
+		fmt.Println("No earlier snapshot in this session; diff-since-last will compare with this one.")
+		return nil
+	}
+	fmt.Printf("Interactive elements of %s compared with the last snapshot (of %s):\n", s.Page.URL, last.Page.URL)
+	printSnapshotDiff(os.Stdout, last.interactiveElements(), s.interactiveElements())
+	return nil
+}
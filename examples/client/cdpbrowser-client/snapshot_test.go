@@ -0,0 +1,157 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseSnapshotFilter(t *testing.T) {
+	rest, f, err := parseSnapshotFilter([]string{"json", "--only", "interactive", "all", "--grep=sign in", "--max", "5", "--format", "md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(rest, []string{"json", "all"}) {
+		t.Errorf("arguments = %q, want json all", rest)
+	}
+	if f.only != "interactive" || f.grep.String() != "(?i)sign in" || f.max != 5 || f.format != "md" {
+		t.Errorf("filter = %+v", f)
+	}
+
+	if _, f, err := parseSnapshotFilter([]string{"llm-text"}); err != nil || f != nil {
+		t.Errorf("without options: filter = %+v, %v, want none", f, err)
+	}
+	for _, bad := range [][]string{
+		{"--only", "buttons"},
+		{"--grep", "("},
+		{"--max", "-1"},
+		{"--format", "yaml"},
+		{"--color", "red"},
+		{"--max"},
+	} {
+		if _, _, err := parseSnapshotFilter(bad); err == nil {
+			t.Errorf("parseSnapshotFilter(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestSnapshotFilterPrint(t *testing.T) {
+	const result = `{
+		"page": {"title": "Shop", "url": "https://shop.example.com"},
+		"landmarks": [{"role": "navigation", "selector": "nav"}],
+		"interactive": [
+			{"id": 1, "role": "link", "name": "Home", "selector": "a.home", "tag": "a"},
+			{"id": 2, "role": "button", "name": "Sign In", "selector": "#login", "tag": "button"},
+			{"id": 3, "role": "button", "name": "Add | remove", "selector": "button.cart", "tag": "button"}
+		],
+		"headings": [{"selector": "h1", "level": 1, "text": "Welcome, sign in"}]
+	}`
+	var structured any
+	if err := json.Unmarshal([]byte(result), &structured); err != nil {
+		t.Fatal(err)
+	}
+	s, err := decodeSnapshot(structured)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	f := mustFilter(t, "--grep", "sign in")
+	if err := f.print(&b, s); err != nil {
+		t.Fatal(err)
+	}
+	want := `CATEGORY     ID  ROLE    NAME              SELECTOR
+interactive  2   button  Sign In           #login
+headings     -           Welcome, sign in  h1
+2 matching elements
+`
+	if b.String() != want {
+		t.Errorf("table:\ngot\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	f = mustFilter(t, "--only", "interactive", "--max", "2", "--format", "md")
+	f.print(&b, s)
+	want = "| Category | ID | Role | Name | Selector |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| interactive | 1 | link | Home | `a.home` |\n" +
+		"| interactive | 2 | button | Sign In | `#login` |\n" +
+		"2 of 3 matching elements shown; raise --max to see more\n"
+	if b.String() != want {
+		t.Errorf("markdown:\ngot\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	f = mustFilter(t, "--only", "interactive", "--grep", "cart", "--format", "json")
+	f.print(&b, s)
+	var elements []snapshotElement
+	if err := json.Unmarshal([]byte(b.String()), &elements); err != nil {
+		t.Fatalf("JSON output %s: %v", b.String(), err)
+	}
+	if len(elements) != 1 || elements[0].Name != "Add | remove" || elements[0].Category != "interactive" {
+		t.Errorf("JSON elements = %+v", elements)
+	}
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	dir := t.TempDir()
+	before := filepath.Join(dir, "before.json")
+	os.WriteFile(before, []byte(`{
+		"page": {"url": "https://shop.example.com/cart"},
+		"interactive": [
+			{"id": 1, "role": "button", "name": "Delete", "selector": "button.delete"},
+			{"id": 2, "role": "button", "name": "Delete", "selector": "button.delete"},
+			{"id": 3, "role": "button", "name": "Next", "selector": "#next"}
+		],
+		"headings": [{"selector": "h1", "level": 1, "text": "Cart"}]
+	}`), 0644)
+	// As printed by aria-snapshot --format json, with other IDs.
+	after := filepath.Join(dir, "after.json")
+	os.WriteFile(after, []byte(`[
+		{"category": "landmarks", "role": "main", "selector": "main"},
+		{"category": "interactive", "id": 7, "role": "button", "name": "Delete", "selector": "button.delete"},
+		{"category": "interactive", "id": 8, "role": "button", "name": "Next", "selector": "#next"},
+		{"category": "interactive", "id": 9, "role": "link", "text": "Checkout", "selector": "a.checkout"}
+	]`), 0644)
+
+	b, err := loadSnapshotFile(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := loadSnapshotFile(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	printSnapshotDiff(&out, b, a)
+	want := `Removed (1):
+  - button "Delete"  button.delete
+Added (1):
+  + link "Checkout"  a.checkout
+3 interactive elements before, 3 after
+`
+	if out.String() != want {
+		t.Errorf("diff:\ngot\n%s\nwant\n%s", out.String(), want)
+	}
+
+	if _, err := loadSnapshotFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("loading a missing snapshot succeeded")
+	}
+}
+
+// mustFilter parses the options of aria-snapshot.
+func mustFilter(t *testing.T, args ...string) *snapshotFilter {
+	t.Helper()
+	_, f, err := parseSnapshotFilter(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
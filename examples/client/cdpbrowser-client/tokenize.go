@@ -0,0 +1,133 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// tokenize splits a command line into arguments the way a POSIX shell does:
+// arguments are separated by unquoted whitespace, single quotes keep
+// everything up to the next single quote, and double quotes keep everything
+// up to the next unescaped double quote, where a backslash escapes a double
+// quote, a backslash, or a dollar sign. Outside quotes, a backslash escapes
+// any character.
+//
+// If expand is not nil, ${NAME} outside single quotes is replaced by
+// expand(NAME). Unlike in a shell, the value is never split into several
+// arguments. A $ not followed by { is kept as is.
+func tokenize(line string, expand func(name string) (string, error)) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false // arg holds an argument, possibly empty like ""
+	runes := []rune(line)
+	// variable expands the ${NAME} at i, if any, and returns the index of
+	// its last rune.
+	variable := func(i int) (int, error) {
+		if expand == nil || i+1 == len(runes) || runes[i+1] != '{' {
+			arg.WriteRune('$')
+			return i, nil
+		}
+		end := i + 2
+		for end < len(runes) && runes[end] != '}' {
+			end++
+		}
+		if end == len(runes) {
+			return 0, errors.New("unterminated ${")
+		}
+		name := string(runes[i+2 : end])
+		if !validVariableName(name) {
+			return 0, fmt.Errorf("invalid variable name %q", name)
+		}
+		value, err := expand(name)
+		if err != nil {
+			return 0, err
+		}
+		arg.WriteString(value)
+		return end, nil
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case r == '\'':
+			inArg = true
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, errors.New("unterminated single quote")
+			}
+			arg.WriteString(string(runes[i+1 : end]))
+			i = end
+		case r == '"':
+			inArg = true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				switch {
+				case runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`, runes[i+1]):
+					i++
+					arg.WriteRune(runes[i])
+				case runes[i] == '$':
+					var err error
+					if i, err = variable(i); err != nil {
+						return nil, err
+					}
+				default:
+					arg.WriteRune(runes[i])
+				}
+			}
+			if i == len(runes) {
+				return nil, errors.New("unterminated double quote")
+			}
+		case r == '\\':
+			inArg = true
+			if i+1 == len(runes) {
+				return nil, errors.New("backslash at the end of the line")
+			}
+			i++
+			arg.WriteRune(runes[i])
+		case r == '$':
+			inArg = true
+			var err error
+			if i, err = variable(i); err != nil {
+				return nil, err
+			}
+		default:
+			inArg = true
+			arg.WriteRune(r)
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// validVariableName reports whether name can be set and expanded in a
+// script: a letter or underscore followed by letters, digits, and
+// underscores.
+func validVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case '0' <= r && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"navigate https://example.com", []string{"navigate", "https://example.com"}},
+		{`click "button.submit large"`, []string{"click", "button.submit large"}},
+		{"  type-text  '#q'   'is it true'  ", []string{"type-text", "#q", "is it true"}},
+		{`click-button "input[name=\"btnK\"]"`, []string{"click-button", `input[name="btnK"]`}},
+		{`type-text #q "C:\temp \\ \$HOME"`, []string{"type-text", "#q", `C:\temp \ $HOME`}},
+		{`type-text #q hello\ world`, []string{"type-text", "#q", "hello world"}},
+		{`type-text #q ""`, []string{"type-text", "#q", ""}},
+		{`select-dropdown country "United "'States'`, []string{"select-dropdown", "country", "United States"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := tokenize(tt.line, nil)
+		if err != nil {
+			t.Errorf("tokenize(%q) error = %v", tt.line, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{`click "unterminated`, "click 'unterminated", `click trailing\`} {
+		if got, err := tokenize(line, nil); err == nil {
+			t.Errorf("tokenize(%q) = %q, want an error", line, got)
+		}
+	}
+}
+
+func TestTokenizeExpand(t *testing.T) {
+	vars := map[string]string{"QUERY": "model context protocol", "ID": "q"}
+	expand := func(name string) (string, error) {
+		if v, ok := vars[name]; ok {
+			return v, nil
+		}
+		return "", fmt.Errorf("undefined variable %s", name)
+	}
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"type-text #${ID} ${QUERY}", []string{"type-text", "#q", "model context protocol"}},
+		{`type-text "#${ID}" "${QUERY} spec"`, []string{"type-text", "#q", "model context protocol spec"}},
+		{`type-text '#${ID}' "\${ID}" \${ID}`, []string{"type-text", "#${ID}", "${ID}", "${ID}"}},
+		{"navigate https://example.com/$ID?x=$", []string{"navigate", "https://example.com/$ID?x=$"}},
+	}
+	for _, tt := range tests {
+		got, err := tokenize(tt.line, expand)
+		if err != nil {
+			t.Errorf("tokenize(%q) error = %v", tt.line, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{"navigate ${MISSING}", "navigate ${ID", "navigate ${1D}"} {
+		if got, err := tokenize(line, expand); err == nil {
+			t.Errorf("tokenize(%q) = %q, want an error", line, got)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// stateURI is the server resource describing the browser, whose updates
+// watch follows.
+const stateURI = "browser://state"
+
+// A watcher prints the log messages, progress notifications, and resource
+// updates of the server while watching is on.
+type watcher struct {
+	on     atomic.Bool
+	tokens atomic.Int64 // the progress tokens given out
+}
+
+// notifications is the watcher of the client's sessions.
+var notifications watcher
+
+// clientOptions returns the options that deliver the server's notifications
+// to w.
+func (w *watcher) clientOptions() *mcp.ClientOptions {
+	return &mcp.ClientOptions{
+		LoggingMessageHandler:       w.logMessage,
+		ProgressNotificationHandler: w.progress,
+		ResourceUpdatedHandler:      w.resourceUpdated,
+	}
+}
+
+// addProgressToken is sending middleware that asks for progress
+// notifications of tool calls while watching is on. The server only reports
+// the progress of requests that carry a progress token.
+func (w *watcher) addProgressToken(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method == "tools/call" && w.on.Load() {
+			if p, ok := req.GetParams().(mcp.RequestParams); ok && p.GetProgressToken() == nil {
+				// SetProgressToken stores the token in the existing metadata.
+				if p.GetMeta() == nil {
+					p.SetMeta(map[string]any{})
+				}
+				p.SetProgressToken(fmt.Sprintf("cdpbrowser-client-%d", w.tokens.Add(1)))
+			}
+		}
+		return next(ctx, method, req)
+	}
+}
+
+// print prints a notification with the time it arrived.
+func (w *watcher) print(format string, args ...any) {
+	if w.on.Load() {
+		fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+	}
+}
+
+func (w *watcher) logMessage(ctx context.Context, req *mcp.ClientRequest[*mcp.LoggingMessageParams]) {
+	p := req.Params
+	data, ok := p.Data.(string)
+	if !ok {
+		b, _ := json.Marshal(p.Data)
+		data = string(b)
+	}
+	logger := ""
+	if p.Logger != "" {
+		logger = " " + p.Logger
+	}
+	w.print("log %s%s: %s", strings.ToUpper(string(p.Level)), logger, data)
+}
+
+func (w *watcher) progress(ctx context.Context, req *mcp.ClientRequest[*mcp.ProgressNotificationParams]) {
+	p := req.Params
+	progress := fmt.Sprintf("%g", p.Progress)
+	if p.Total > 0 {
+		progress = fmt.Sprintf("%g/%g (%.0f%%)", p.Progress, p.Total, 100*p.Progress/p.Total)
+	}
+	if p.Message != "" {
+		progress += " " + p.Message
+	}
+	w.print("progress %s", progress)
+}
+
+func (w *watcher) resourceUpdated(ctx context.Context, req *mcp.ClientRequest[*mcp.ResourceUpdatedNotificationParams]) {
+	w.print("updated %s", req.Params.URI)
+}
+
+// start turns watching on for cs: it asks for log messages at level and
+// above, and subscribes to browser://state. A server that does not support
+// one of these is only warned about.
+func (w *watcher) start(ctx context.Context, cs *mcp.ClientSession, level mcp.LoggingLevel) {
+	if err := cs.SetLevel(ctx, &mcp.SetLevelParams{Level: level}); err != nil {
+		fmt.Printf("Warning: the server does not send log messages: %v\n", err)
+	}
+	if err := cs.Subscribe(ctx, &mcp.SubscribeParams{URI: stateURI}); err != nil {
+		fmt.Printf("Warning: failed to subscribe to %s: %v\n", stateURI, err)
+	}
+	w.on.Store(true)
+	fmt.Printf("Watching log messages (level %s and above), progress, and updates of %s\n", level, stateURI)
+}
+
+// stop turns watching off.
+func (w *watcher) stop(ctx context.Context, cs *mcp.ClientSession) error {
+	w.on.Store(false)
+	if err := cs.Unsubscribe(ctx, &mcp.UnsubscribeParams{URI: stateURI}); err != nil {
+		return fmt.Errorf("failed to unsubscribe from %s: %w", stateURI, err)
+	}
+	fmt.Println("Stopped watching notifications")
+	return nil
+}
+
+// follow watches until the user interrupts it, for the watch command given
+// on the command line.
+func (w *watcher) follow(ctx context.Context, cs *mcp.ClientSession, level mcp.LoggingLevel) error {
+	w.start(ctx, cs, level)
+	fmt.Println("Press Ctrl-C to stop.")
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	<-ctx.Done()
+	stop()
+	// The interrupt ended ctx; unsubscribing needs a live one.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	return w.stop(ctx, cs)
+}
+
+// loggingLevels are the levels of log messages, from the least severe.
+var loggingLevels = []mcp.LoggingLevel{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// parseLoggingLevel checks a level given to watch.
+func parseLoggingLevel(level string) (mcp.LoggingLevel, bool) {
+	for _, l := range loggingLevels {
+		if string(l) == level {
+			return l, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,33 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestAddProgressToken(t *testing.T) {
+	var w watcher
+	send := w.addProgressToken(func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		return nil, nil
+	})
+	call := func() any {
+		params := &mcp.CallToolParams{Name: "crawl"}
+		send(context.Background(), "tools/call", &mcp.ClientRequest[*mcp.CallToolParams]{Params: params})
+		return params.GetProgressToken()
+	}
+
+	if token := call(); token != nil {
+		t.Errorf("progress token without watching = %v, want none", token)
+	}
+	w.on.Store(true)
+	first, second := call(), call()
+	if first == nil || second == nil || first == second {
+		t.Errorf("progress tokens while watching = %v, %v, want two different tokens", first, second)
+	}
+}
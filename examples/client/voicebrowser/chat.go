@@ -0,0 +1,204 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// A conversation is a chat with the model that drives the browser. Its
+// messages, including tool calls and their results, carry over from one
+// turn to the next, so the user can follow up on what the model did.
+type conversation struct {
+	client  *openai.Client
+	model   string
+	session *mcp.ClientSession
+	tools   []openai.Tool
+	// serverNames maps the aliases of tools to their server names.
+	serverNames map[string]string
+	messages    []openai.ChatCompletionMessage
+	// prompt renders the system prompt, again on each reset.
+	prompt *systemPrompt
+	// vision shows the images tools return to the model.
+	vision bool
+	// pauseOn are the sites where the user takes over for a while.
+	pauseOn []*pauseRule
+	// usage counts the tokens of the run, which budget caps.
+	usage  usageMeter
+	budget budget
+	// limits keep the conversation within the model's context.
+	limits contextLimits
+	// turnLimits bound the work of each turn.
+	turnLimits turnLimits
+	// verifier checks the effects of actions, if it is not nil.
+	verifier *verifier
+	// guard checks the tool calls of the model, if it is not nil.
+	guard *guardrails
+	// retry says how to retry failed calls to the model and tools.
+	retry retryConfig
+	// log records the run, if it is not nil.
+	log *runLog
+}
+
+// stdin reads the lines the user types, both at the chat prompt and when
+// the server asks for help, so that neither loses what the other buffered.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine reads a line from stdin, without its line ending. It returns
+// io.EOF only if the input ended before any text.
+func readLine() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// An interrupter turns Ctrl-C into canceling the turn in progress, so the
+// user can stop a plan and give new instructions. At the prompt, Ctrl-C
+// only reminds the user how to quit.
+type interrupter struct {
+	mu      sync.Mutex
+	cancel  context.CancelFunc // of the turn in progress, or nil
+	message string             // what Ctrl-C does to the turn
+}
+
+func newInterrupter() *interrupter {
+	in := &interrupter{}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		for range signals {
+			in.mu.Lock()
+			if in.cancel != nil {
+				fmt.Println("\n⏹️  " + in.message)
+				in.cancel()
+				in.cancel = nil
+			} else {
+				fmt.Print("\n(type 'exit' or press Ctrl-D to quit)\nyou> ")
+			}
+			in.mu.Unlock()
+		}
+	}()
+	return in
+}
+
+// turn returns a context for a turn that Ctrl-C cancels, printing message,
+// and a function to call when the turn is over.
+func (in *interrupter) turn(ctx context.Context, message string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	in.mu.Lock()
+	in.cancel = cancel
+	in.message = message
+	in.mu.Unlock()
+	return ctx, func() {
+		in.mu.Lock()
+		in.cancel = nil
+		in.mu.Unlock()
+		cancel()
+	}
+}
+
+// chat runs a turn for each line the user types, or each thing they say if
+// voice is not nil, until the input ends or the user types exit. If first
+// is not empty, it is sent before the prompt.
+func chat(ctx context.Context, c *conversation, first string, voice *voiceInput) error {
+	in := newInterrupter()
+	fmt.Println("\nChat with the browser agent. Ctrl-C interrupts a running plan;")
+	fmt.Println("'reset' forgets the conversation, 'exit' quits.")
+
+	message := first
+	for {
+		if message == "" {
+			var line string
+			var err error
+			if voice != nil && voice.mode == "continuous" {
+				// Listening does not read the terminal, so Ctrl-C quits
+				listenCtx, done := in.turn(ctx, "Stopped listening.")
+				line, err = voice.listen(listenCtx)
+				stopped := listenCtx.Err() != nil && ctx.Err() == nil
+				done()
+				if stopped {
+					return nil
+				}
+			} else if voice != nil {
+				line, err = voice.listen(ctx)
+			} else {
+				fmt.Print("\nyou> ")
+				line, err = readLine()
+			}
+			if err == io.EOF {
+				fmt.Println()
+				return nil
+			}
+			if err != nil && voice != nil && ctx.Err() == nil {
+				// Try again rather than end the chat over a bad recording
+				fmt.Printf("Error: %v\n", err)
+				if err := sleep(ctx, time.Second); err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			message = strings.TrimSpace(line)
+		}
+		switch spokenCommand(message) {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		case "reset":
+			if err := c.reset(); err != nil {
+				// Keep the conversation rather than lose the prompt
+				fmt.Printf("Error: %v\n", err)
+			} else {
+				fmt.Println("Conversation reset; the browser keeps its page.")
+			}
+			message = ""
+			continue
+		}
+
+		turnCtx, done := in.turn(ctx, "Interrupting the plan...")
+		reply, err := c.send(turnCtx, message)
+		interrupted := turnCtx.Err() != nil && ctx.Err() == nil
+		done()
+		message = ""
+		switch {
+		case interrupted && errors.Is(err, context.Canceled):
+			fmt.Println("Plan interrupted. What should I do instead?")
+			say(ctx, "Plan interrupted. What should I do instead?")
+		case errors.Is(err, errLimitReached):
+			if reply != "" {
+				fmt.Printf("\nassistant> %s\n", reply)
+				say(ctx, reply)
+			}
+			fmt.Printf("Stopped: %v. Say 'continue' to carry on.\n", err)
+		case errors.Is(err, errBudgetExceeded):
+			fmt.Printf("Stopping: %v\n", err)
+			say(ctx, "Stopping: the run is over budget.")
+			return nil
+		case err != nil:
+			// The turn failed, but the conversation can go on
+			fmt.Printf("Error: %v\n", err)
+		default:
+			fmt.Printf("\nassistant> %s\n", reply)
+			say(ctx, reply)
+		}
+	}
+}
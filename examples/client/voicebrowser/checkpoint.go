@@ -0,0 +1,177 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// After each iteration, a recorded run saves a checkpoint.json in its
+// directory with everything needed to pick it up again: the conversation,
+// the usage so far, the page the browser was on, and the instruction being
+// carried out, if any. -resume <run-id> loads it into a new session, opens
+// the page again, and, if the run stopped partway through an instruction,
+// tells the model to carry on with it.
+
+// stateURI is the server resource describing the browser's current page.
+const stateURI = "browser://state"
+
+// A checkpoint is the saved state of a run.
+type checkpoint struct {
+	RunID    string                         `json:"run_id"`
+	Saved    time.Time                      `json:"saved"`
+	Turn     int                            `json:"turn"`
+	Messages []openai.ChatCompletionMessage `json:"messages"`
+	// Pending is the instruction the run was carrying out when it stopped,
+	// or empty if it had finished it.
+	Pending string `json:"pending,omitempty"`
+	Page    struct {
+		URL   string `json:"url"`
+		Title string `json:"title"`
+	} `json:"page"`
+	Usage struct {
+		Requests         int     `json:"requests"`
+		PromptTokens     int     `json:"prompt_tokens"`
+		CompletionTokens int     `json:"completion_tokens"`
+		Cost             float64 `json:"cost"`
+	} `json:"usage"`
+	// Paused lists the pause_on patterns that have paused already.
+	Paused []string `json:"paused,omitempty"`
+	// Navigations counts the navigations the guardrails allowed.
+	Navigations int `json:"navigations,omitempty"`
+}
+
+// saveCheckpoint saves the state of the run, with pending as the
+// instruction in progress. It does nothing if the run is not recorded.
+func (c *conversation) saveCheckpoint(ctx context.Context, pending string) {
+	if c.log == nil {
+		return
+	}
+	cp := checkpoint{
+		RunID:    c.log.id,
+		Saved:    time.Now(),
+		Turn:     c.log.turn,
+		Messages: c.messages,
+		Pending:  pending,
+	}
+	cp.Page.URL, cp.Page.Title = currentPage(ctx, c.session)
+	cp.Usage.Requests = c.usage.requests
+	cp.Usage.PromptTokens = c.usage.promptTokens
+	cp.Usage.CompletionTokens = c.usage.completionTokens
+	cp.Usage.Cost = c.usage.cost
+	if c.guard != nil {
+		cp.Navigations = c.guard.navigations
+	}
+	for _, rule := range c.pauseOn {
+		if rule.paused {
+			cp.Paused = append(cp.Paused, rule.Pattern)
+		}
+	}
+
+	data, err := json.Marshal(cp)
+	if err == nil {
+		data = []byte(redact(string(data)))
+		// Write the checkpoint whole or not at all
+		file := filepath.Join(c.log.dir, "checkpoint.json")
+		if err = os.WriteFile(file+".tmp", data, 0600); err == nil {
+			err = os.Rename(file+".tmp", file)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to save a checkpoint: %v\n", err)
+	}
+}
+
+// currentPage returns the URL and title of the browser's page, or empty
+// strings if the server cannot tell.
+func currentPage(ctx context.Context, session *mcp.ClientSession) (url, title string) {
+	// A canceled turn still deserves a checkpoint
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	res, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: stateURI})
+	if err != nil || len(res.Contents) == 0 {
+		return "", ""
+	}
+	var state struct {
+		URL   string `json:"url"`
+		Title string `json:"title"`
+	}
+	if json.Unmarshal([]byte(res.Contents[0].Text), &state) != nil {
+		return "", ""
+	}
+	return state.URL, state.Title
+}
+
+// loadCheckpoint reads the checkpoint of the run id under root.
+func loadCheckpoint(root, id string) (*checkpoint, error) {
+	data, err := os.ReadFile(filepath.Join(root, id, "checkpoint.json"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot resume run %s: %v", id, err)
+	}
+	cp := &checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("cannot resume run %s: bad checkpoint: %v", id, err)
+	}
+	if len(cp.Messages) == 0 {
+		return nil, fmt.Errorf("cannot resume run %s: the checkpoint has no conversation", id)
+	}
+	return cp, nil
+}
+
+// resume restores the conversation of cp, reopens its page, and returns the
+// message that carries on with its pending instruction, or "" if it had
+// none.
+func (c *conversation) resume(ctx context.Context, cp *checkpoint) string {
+	// Keep this version's system prompt, which may have changed
+	c.messages = append(c.messages[:1], cp.Messages[1:]...)
+	c.usage.requests = cp.Usage.Requests
+	c.usage.promptTokens = cp.Usage.PromptTokens
+	c.usage.completionTokens = cp.Usage.CompletionTokens
+	c.usage.cost = cp.Usage.Cost
+	if c.guard != nil {
+		c.guard.navigations = cp.Navigations
+	}
+	for _, rule := range c.pauseOn {
+		for _, pattern := range cp.Paused {
+			if rule.Pattern == pattern {
+				rule.paused = true
+			}
+		}
+	}
+	fmt.Printf("Resuming run %s from %s (%d messages, turn %d)\n",
+		cp.RunID, cp.Saved.Format(time.DateTime), len(cp.Messages), cp.Turn)
+
+	page := "a new browser session"
+	if cp.Page.URL != "" && cp.Page.URL != "about:blank" {
+		fmt.Printf("Reopening %s\n", cp.Page.URL)
+		args, _ := json.Marshal(map[string]string{"url": cp.Page.URL})
+		if _, _, err := executeMCPTool(ctx, c.session, "navigate", string(args), false); err != nil {
+			fmt.Printf("Warning: failed to reopen %s: %v\n", cp.Page.URL, err)
+		} else {
+			page = fmt.Sprintf("a new browser session, reopened at %s (%s)", cp.Page.URL, cp.Page.Title)
+		}
+	}
+	if cp.Pending == "" {
+		c.messages = append(c.messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: fmt.Sprintf("[The run was stopped and has been resumed in %s. Logins and page state may need to be redone. Wait for my next instruction.]", page),
+		}, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleAssistant,
+			Content: "Understood. I'll check the page before acting on your next instruction.",
+		})
+		return ""
+	}
+	fmt.Printf("Continuing with: %s\n", cp.Pending)
+	return fmt.Sprintf("The run was interrupted and has been resumed in %s. Logins and page state may need to be redone, "+
+		"so take an ARIA snapshot first. Then carry on from where you left off with my instruction: %s", page, cp.Pending)
+}
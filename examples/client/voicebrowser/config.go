@@ -0,0 +1,73 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// A config is the JSON file given by -config, which adapts the agent to
+// the sites it works on without code changes:
+//
+//	{
+//	  "pause_on": [
+//	    {"pattern": "canva\\.com", "message": "Log in to Canva and close any pop-ups."}
+//	  ],
+//	  "tools": {"exclude": ["close_browser", "shutdown_server"]},
+//	  "prompt": {"guardrails": ["Never buy anything or enter payment details."]},
+//	  "guardrails": {"allow_urls": ["*.canva.com"], "max_navigations": 30},
+//	  "retry": {"tools": {"retry_on": ["network", "timeout"]}},
+//	  "flags": {"model": "gpt-4o-mini", "budget": "$2", "vision": false}
+//	}
+type config struct {
+	// PauseOn are the sites that need the user before the agent goes on.
+	PauseOn []*pauseRule `json:"pause_on"`
+	// Tools selects and rewords the tools the model is offered.
+	Tools toolConfig `json:"tools"`
+	// Prompt fills in the site hints and guardrails of the system prompt.
+	Prompt promptConfig `json:"prompt"`
+	// Guardrails are the policies every tool call must keep to.
+	Guardrails *guardrails `json:"guardrails"`
+	// Retry says how to retry failed calls to the model and to tools.
+	Retry retryConfig `json:"retry"`
+	// Flags gives flags the values they take unless the command line or
+	// the environment sets them.
+	Flags map[string]json.RawMessage `json:"flags"`
+}
+
+// loadConfig reads the config file at path, or returns an empty config if
+// path is empty.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{Retry: defaultRetry()}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	for i, rule := range cfg.PauseOn {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("%s: pause_on[%d]: %v", path, i, err)
+		}
+	}
+	if cfg.Guardrails != nil {
+		if err := cfg.Guardrails.compile(); err != nil {
+			return nil, fmt.Errorf("%s: guardrails: %v", path, err)
+		}
+	}
+	if err := cfg.Retry.check(); err != nil {
+		return nil, fmt.Errorf("%s: retry: %v", path, err)
+	}
+	if err := cfg.Tools.check(); err != nil {
+		return nil, fmt.Errorf("%s: tools: %v", path, err)
+	}
+	return cfg, nil
+}
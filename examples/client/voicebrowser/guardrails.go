@@ -0,0 +1,255 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Guardrails check each tool call the model proposes before it runs, as a
+// second line of defense behind the server's own policies. A call that
+// breaks one is not run; the model gets the violation as the tool's error
+// and can choose another way. The "guardrails" object of the config file
+// sets them:
+//
+//	"guardrails": {
+//	  "allow_urls": ["example.com", "*.example.com"],
+//	  "forbid_tools": ["close_browser", "shutdown_*"],
+//	  "arguments": [
+//	    {"tool": "type_text", "argument": "text", "deny": "(?i)password", "message": "Ask the user to type passwords."}
+//	  ],
+//	  "max_navigations": 30
+//	}
+//
+// The tool calls nested in arguments, such as the steps of batch or of a
+// macro being defined, are checked like the calls of the model, and so are
+// the URLs in lists. A URL argument that is a template, such as
+// "{{site}}/login", is not on an allowed site, as where it leads is unknown.
+//
+// Unlike the guardrails of the prompt, which ask the model to behave, these
+// are enforced.
+
+// guardrails are the policies of the tool calls of a run. A nil *guardrails
+// allows everything.
+type guardrails struct {
+	// AllowURLs are the host patterns of the pages the browser may open,
+	// or empty to allow any. about:blank is always allowed.
+	AllowURLs []string `json:"allow_urls"`
+	// ForbidTools are the patterns of tools that must not run.
+	ForbidTools []string `json:"forbid_tools"`
+	// Arguments check the values the model passes to tools.
+	Arguments []*argumentRule `json:"arguments"`
+	// MaxNavigations caps the navigate calls of the run, or is 0 for no
+	// cap.
+	MaxNavigations int `json:"max_navigations"`
+
+	navigations int
+}
+
+// An argumentRule checks the values of an argument of the tools Tool
+// matches. A value must not match Deny and must match Allow, if they are
+// set.
+type argumentRule struct {
+	Tool     string `json:"tool"`     // glob pattern; empty for every tool
+	Argument string `json:"argument"` // name; empty for every argument
+	Deny     string `json:"deny"`     // regular expression
+	Allow    string `json:"allow"`    // regular expression
+	Message  string `json:"message"`  // why, for the model
+
+	deny, allow *regexp.Regexp
+}
+
+// compile checks the patterns of g.
+func (g *guardrails) compile() error {
+	for _, pattern := range append(g.AllowURLs, g.ForbidTools...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad pattern %q", pattern)
+		}
+	}
+	if g.MaxNavigations < 0 {
+		return fmt.Errorf("max_navigations cannot be negative")
+	}
+	for i, rule := range g.Arguments {
+		if _, err := path.Match(rule.Tool, ""); err != nil {
+			return fmt.Errorf("arguments[%d]: bad tool pattern %q", i, rule.Tool)
+		}
+		if rule.Deny == "" && rule.Allow == "" {
+			return fmt.Errorf("arguments[%d]: needs deny or allow", i)
+		}
+		var err error
+		if rule.Deny != "" {
+			if rule.deny, err = regexp.Compile(rule.Deny); err != nil {
+				return fmt.Errorf("arguments[%d]: deny: %v", i, err)
+			}
+		}
+		if rule.Allow != "" {
+			if rule.allow, err = regexp.Compile(rule.Allow); err != nil {
+				return fmt.Errorf("arguments[%d]: allow: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// A guardrailError is a violation of the guardrails.
+type guardrailError struct {
+	why string
+}
+
+func (e *guardrailError) Error() string {
+	return "blocked by a guardrail: " + e.why
+}
+
+// check returns a *guardrailError if the call of the server's tool with the
+// arguments argsJSON must not run. The tool calls nested in the arguments,
+// such as the steps of batch or define_macro, are checked as well. The
+// navigations of a call that may run are counted.
+func (g *guardrails) check(tool, argsJSON string) error {
+	if g == nil {
+		return nil
+	}
+	var args any
+	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+		// The call fails anyway, with a better message
+		args = nil
+	}
+	navigations := 0
+	if err := g.checkCall(tool, args, &navigations); err != nil {
+		return err
+	}
+	if g.MaxNavigations > 0 && g.navigations+navigations > g.MaxNavigations {
+		return &guardrailError{fmt.Sprintf("the run has used %d of the %d navigations it is allowed; finish with the pages already open or ask the user", g.navigations, g.MaxNavigations)}
+	}
+	g.navigations += navigations
+	return nil
+}
+
+// checkCall checks the call of tool with the decoded arguments args, and
+// adds its navigations to navigations.
+func (g *guardrails) checkCall(tool string, args any, navigations *int) error {
+	if matchAny(g.ForbidTools, tool) {
+		return &guardrailError{fmt.Sprintf("the tool %s is forbidden", tool)}
+	}
+	if tool == "navigate" {
+		*navigations++
+	}
+	return g.checkValue(tool, "", args, navigations)
+}
+
+// checkValue checks value, passed to tool as the argument name or nested in
+// it. Objects and arrays are walked to their strings, numbers, and booleans,
+// which are checked under the name of the nearest key. An object with a
+// "tool" is a nested tool call.
+func (g *guardrails) checkValue(tool, name string, value any, navigations *int) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		nested, isCall := v["tool"].(string)
+		if isCall {
+			if err := g.checkCall(nested, v["arguments"], navigations); err != nil {
+				return err
+			}
+		}
+		// Check every key in order, for the same answer every time
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if isCall && (key == "tool" || key == "arguments") {
+				continue
+			}
+			if err := g.checkValue(tool, key, v[key], navigations); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		for _, e := range v {
+			if err := g.checkValue(tool, name, e, navigations); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	text, ok := value.(string)
+	if ok && (isURLArgument(name) || hasURLScheme(text)) && !g.allowsURL(text) {
+		return &guardrailError{fmt.Sprintf("%s is not on an allowed site (%s)", text, strings.Join(g.AllowURLs, ", "))}
+	}
+	if !ok {
+		data, _ := json.Marshal(value)
+		text = string(data)
+	}
+	for _, rule := range g.Arguments {
+		if (rule.Tool != "" && !matchAny([]string{rule.Tool}, tool)) || (rule.Argument != "" && rule.Argument != name) {
+			continue
+		}
+		if (rule.deny != nil && rule.deny.MatchString(text)) || (rule.allow != nil && !rule.allow.MatchString(text)) {
+			why := rule.Message
+			if why == "" {
+				why = fmt.Sprintf("the %s argument of %s is not allowed", name, tool)
+			}
+			return &guardrailError{why}
+		}
+	}
+	return nil
+}
+
+// isURLArgument reports whether the argument name holds URLs to open.
+func isURLArgument(name string) bool {
+	return name == "url" || name == "urls" || name == "sitemap" || strings.HasSuffix(name, "_url")
+}
+
+// hasURLScheme reports whether s is a web URL.
+func hasURLScheme(s string) bool {
+	for _, scheme := range []string{"http://", "https://", "ws://", "wss://"} {
+		if len(s) >= len(scheme) && strings.EqualFold(s[:len(scheme)], scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsURL reports whether the browser may open rawURL.
+func (g *guardrails) allowsURL(rawURL string) bool {
+	if g == nil || len(g.AllowURLs) == 0 || rawURL == "about:blank" {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "ws", "wss":
+	default:
+		return false
+	}
+	return matchAny(g.AllowURLs, u.Hostname())
+}
+
+// offSite returns a warning for the model if the browser has left the
+// allowed sites, such as by following a link, or "" if it has not.
+func (c *conversation) offSite(ctx context.Context) string {
+	if c.guard == nil || len(c.guard.AllowURLs) == 0 {
+		return ""
+	}
+	page, _ := currentPage(ctx, c.session)
+	if page == "" || c.guard.allowsURL(page) {
+		return ""
+	}
+	fmt.Printf("🛡️  The browser left the allowed sites for %s\n", page)
+	return fmt.Sprintf("\n\nWarning from a guardrail: the browser is now at %s, which is not on an allowed site (%s). "+
+		"Do not act on this page; navigate back to an allowed site.", page, strings.Join(c.guard.AllowURLs, ", "))
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGuardrailsCheck(t *testing.T) {
+	g := &guardrails{
+		AllowURLs:   []string{"example.com", "*.example.com"},
+		ForbidTools: []string{"close_browser", "shutdown_*"},
+		Arguments: []*argumentRule{
+			{Tool: "type_*", Argument: "text", Deny: "(?i)password", Message: "Ask the user to type passwords."},
+		},
+	}
+	if err := g.compile(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, tool, args string
+		blocked          bool
+	}{
+		{"allowed navigation", "navigate", `{"url":"https://www.example.com/a"}`, false},
+		{"other site", "navigate", `{"url":"https://evil.test/"}`, true},
+		{"file URL", "navigate", `{"url":"file:///etc/passwd"}`, true},
+		{"template URL", "navigate", `{"url":"{{site}}/login"}`, true},
+		{"URL in another argument", "open_tab", `{"target":"https://evil.test/"}`, true},
+		{"forbidden tool", "shutdown_server", `{}`, true},
+		{"denied argument", "type_text", `{"selector":"#pw","text":"my Password"}`, true},
+		{"rule of another argument", "type_text", `{"selector":"password","text":"hello"}`, false},
+		{"bad JSON", "close_browser", `{`, true},
+		{"URL list", "bulk_screenshot", `{"urls":["https://example.com/","https://evil.test/"]}`, true},
+		{"allowed URL list", "bulk_screenshot", `{"urls":["https://example.com/","https://docs.example.com/"]}`, false},
+		{"sitemap", "bulk_screenshot", `{"sitemap":"https://evil.test/sitemap.xml"}`, true},
+		{"nested navigation", "batch", `{"steps":[{"tool":"navigate","arguments":{"url":"https://evil"}}]}`, true},
+		{"nested forbidden tool", "batch", `{"steps":[{"tool":"click","arguments":{"selector":"#a"}},{"tool":"close_browser"}]}`, true},
+		{"nested argument rule", "define_macro", `{"name":"login","steps":[{"tool":"type_text","arguments":{"text":"password1"}}]}`, true},
+		{"deeply nested", "batch", `{"steps":[{"tool":"batch","arguments":{"steps":[{"tool":"navigate","arguments":{"url":"https://evil.test/"}}]}}]}`, true},
+		{"allowed batch", "batch", `{"steps":[{"tool":"navigate","arguments":{"url":"https://example.com/"}},{"tool":"click","arguments":{"selector":"#go"}}]}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := g.check(tt.tool, tt.args)
+			if got := err != nil; got != tt.blocked {
+				t.Fatalf("check(%s, %s) = %v, want blocked=%t", tt.tool, tt.args, err, tt.blocked)
+			}
+			var gerr *guardrailError
+			if err != nil && !errors.As(err, &gerr) {
+				t.Errorf("check(%s, %s) error type = %T, want *guardrailError", tt.tool, tt.args, err)
+			}
+		})
+	}
+}
+
+func TestGuardrailsMaxNavigations(t *testing.T) {
+	g := &guardrails{MaxNavigations: 3}
+	if err := g.check("navigate", `{"url":"https://a.test/"}`); err != nil {
+		t.Fatal(err)
+	}
+	// A batch of three navigations would go past the cap, and counts none
+	batch := `{"steps":[{"tool":"navigate","arguments":{"url":"https://b.test/"}},` +
+		`{"tool":"navigate","arguments":{"url":"https://c.test/"}},` +
+		`{"tool":"navigate","arguments":{"url":"https://d.test/"}}]}`
+	if err := g.check("batch", batch); err == nil {
+		t.Error("a batch past max_navigations ran")
+	}
+	if g.navigations != 1 {
+		t.Errorf("navigations = %d after a blocked batch, want 1", g.navigations)
+	}
+	if err := g.check("batch", `{"steps":[{"tool":"navigate","arguments":{"url":"https://b.test/"}},{"tool":"navigate","arguments":{"url":"https://c.test/"}}]}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.check("navigate", `{"url":"https://e.test/"}`); err == nil {
+		t.Error("a navigation past max_navigations ran")
+	}
+	if g.navigations != 3 {
+		t.Errorf("navigations = %d, want 3", g.navigations)
+	}
+}
+
+func TestGuardrailsAllowURL(t *testing.T) {
+	var none *guardrails
+	if !none.allowsURL("file:///etc/passwd") {
+		t.Error("nil guardrails block a URL")
+	}
+	g := &guardrails{AllowURLs: []string{"*.example.com"}}
+	for url, want := range map[string]bool{
+		"about:blank":               true,
+		"https://www.example.com/":  true,
+		"wss://feed.example.com/ws": true,
+		"https://example.com/":      false,
+		"chrome://settings":         false,
+		"javascript:alert(1)":       false,
+	} {
+		if got := g.allowsURL(url); got != want {
+			t.Errorf("allowsURL(%s) = %t, want %t", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ARIA snapshots and extracted HTML can run to hundreds of kilobytes, and a
+// long run piles up dozens of them. A contextLimits keeps the conversation
+// within the model's context: it truncates, or has a cheap model
+// summarize, each large tool result before it joins the conversation, and
+// before each request it compacts the oldest tool results and screenshots
+// until the conversation fits its token budget.
+type contextLimits struct {
+	// maxResult is the length in characters above which a tool result is
+	// cut down; 0 means no limit.
+	maxResult int
+	// summaryModel summarizes large tool results instead of truncating
+	// them, if it is not empty.
+	summaryModel string
+	// maxTokens is the estimated size of the conversation above which old
+	// tool results are compacted; 0 means no limit.
+	maxTokens int
+}
+
+const (
+	// charsPerToken estimates the tokens of English text and markup.
+	charsPerToken = 4
+	// imageTokens estimates the tokens of an image.
+	imageTokens = 1000
+	// compactedPreview is how much of a compacted tool result is kept.
+	compactedPreview = 200
+)
+
+// fitResult returns the tool result to add to the conversation: result
+// itself if it is short enough, or else a summary or a truncation of it.
+func (c *conversation) fitResult(ctx context.Context, toolName, result string) string {
+	max := c.limits.maxResult
+	if max <= 0 || len(result) <= max {
+		return result
+	}
+	if c.limits.summaryModel != "" {
+		summary, err := c.summarize(ctx, toolName, result)
+		if err == nil {
+			fmt.Printf("📝 Summarized the %d-character result of %s\n", len(result), toolName)
+			return fmt.Sprintf("[Summary of a %d-character result]\n%s", len(result), summary)
+		}
+		fmt.Printf("Warning: failed to summarize the result of %s, truncating it: %v\n", toolName, err)
+	}
+	fmt.Printf("✂️  Truncated the %d-character result of %s\n", len(result), toolName)
+	return truncateMiddle(result, max)
+}
+
+// truncateMiddle cuts s down to about max characters, keeping its start,
+// where snapshots list the page's landmarks and controls, and its end,
+// where errors usually are.
+func truncateMiddle(s string, max int) string {
+	head := max * 3 / 4
+	tail := max - head
+	// Cut at line breaks when there is one nearby
+	if i := strings.LastIndexByte(s[:head], '\n'); i > head/2 {
+		head = i + 1
+	}
+	if i := strings.IndexByte(s[len(s)-tail:], '\n'); i >= 0 && i < tail/2 {
+		tail -= i + 1
+	}
+	omitted := len(s) - head - tail
+	// A cut may split a multibyte character
+	return fmt.Sprintf("%s\n[... %d characters omitted; ask with a narrower focus or selector to see them ...]\n%s",
+		strings.ToValidUTF8(s[:head], ""), omitted, strings.ToValidUTF8(s[len(s)-tail:], ""))
+}
+
+// summarize has the summary model condense a tool result, keeping what the
+// agent needs to act on the page.
+func (c *conversation) summarize(ctx context.Context, toolName, result string) (string, error) {
+	req := openai.ChatCompletionRequest{
+		Model: c.limits.summaryModel,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role: openai.ChatMessageRoleSystem,
+				Content: "You condense browser tool results for a browser automation agent. " +
+					"Keep every interactive element with its exact selector, ID, role, and name, " +
+					"the page URL and title, headings, error messages, and any text the task may need. " +
+					"Drop decorative and repeated content. Answer with the condensed result only.",
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: fmt.Sprintf("Result of the %s tool:\n\n%s", toolName, result),
+			},
+		},
+		Temperature: 0,
+	}
+	var resp openai.ChatCompletionResponse
+	err := c.retry.LLM.do(ctx, "Summary request", func(ctx context.Context) error {
+		var err error
+		resp, err = c.client.CreateChatCompletion(ctx, req)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	c.usage.add(c.limits.summaryModel, resp.Usage)
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// estimateTokens estimates the tokens of messages.
+func estimateTokens(messages []openai.ChatCompletionMessage) int {
+	tokens := 0
+	for _, m := range messages {
+		chars := len(m.Content)
+		for _, p := range m.MultiContent {
+			if p.Type == openai.ChatMessagePartTypeImageURL {
+				tokens += imageTokens
+			}
+			chars += len(p.Text)
+		}
+		for _, call := range m.ToolCalls {
+			chars += len(call.Function.Name) + len(call.Function.Arguments)
+		}
+		tokens += chars / charsPerToken
+	}
+	return tokens
+}
+
+// compact shrinks the oldest tool results and screenshots of the
+// conversation, leaving the latest iteration alone, until the conversation
+// fits the token budget. Tool results stay in place, as short notes, since
+// every tool call needs one.
+func (c *conversation) compact() {
+	if c.limits.maxTokens <= 0 {
+		return
+	}
+	before := estimateTokens(c.messages)
+	if before <= c.limits.maxTokens {
+		return
+	}
+
+	// The latest iteration starts at the last assistant message
+	recent := len(c.messages)
+	for i := len(c.messages) - 1; i > 0; i-- {
+		if c.messages[i].Role == openai.ChatMessageRoleAssistant {
+			recent = i
+			break
+		}
+	}
+	toolNames := make(map[string]string)
+	tokens, compacted := before, 0
+	for i := 1; i < recent && tokens > c.limits.maxTokens; i++ {
+		m := &c.messages[i]
+		for _, call := range m.ToolCalls {
+			toolNames[call.ID] = call.Function.Name
+		}
+		switch {
+		case m.Role == openai.ChatMessageRoleTool && len(m.Content) > compactedPreview && !strings.HasPrefix(m.Content, "[Compacted"):
+			old := len(m.Content) / charsPerToken
+			m.Content = fmt.Sprintf("[Compacted to save context: this earlier result of %s had %d characters and began with]\n%s...",
+				toolNames[m.ToolCallID], len(m.Content), strings.ToValidUTF8(m.Content[:compactedPreview], ""))
+			tokens -= old - len(m.Content)/charsPerToken
+			compacted++
+		case len(m.MultiContent) > 0:
+			for j, p := range m.MultiContent {
+				if p.Type == openai.ChatMessagePartTypeImageURL {
+					m.MultiContent[j] = openai.ChatMessagePart{
+						Type: openai.ChatMessagePartTypeText,
+						Text: "[Earlier image removed to save context]",
+					}
+					tokens -= imageTokens
+					compacted++
+				}
+			}
+		}
+	}
+	if compacted > 0 {
+		fmt.Printf("🗜️  Compacted %d earlier tool results and images: ~%d -> ~%d tokens\n", compacted, before, tokens)
+	}
+}
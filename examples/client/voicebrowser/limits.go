@@ -0,0 +1,82 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// turnLimits bound the work of a turn: the requests to the model, the time
+// it takes, and the tools it calls. A turn that reaches one does not just
+// stop; the model gets one more request, without tools, to sum up what it
+// did and what is left, so the user can tell it to continue or change
+// course. A zero limit is no limit.
+type turnLimits struct {
+	maxIterations int
+	maxDuration   time.Duration
+	maxToolCalls  int
+}
+
+// errLimitReached ends a turn that reached one of its limits.
+var errLimitReached = errors.New("turn limit reached")
+
+// check returns an error wrapping errLimitReached if a turn that started
+// at started, made iterations requests, and called toolCalls tools has
+// reached a limit of l.
+func (l turnLimits) check(iterations, toolCalls int, started time.Time) error {
+	switch {
+	case l.maxIterations > 0 && iterations >= l.maxIterations:
+		return fmt.Errorf("%w: the turn used all %d iterations", errLimitReached, l.maxIterations)
+	case l.maxDuration > 0 && time.Since(started) >= l.maxDuration:
+		return fmt.Errorf("%w: the turn ran for its %v", errLimitReached, l.maxDuration)
+	case l.maxToolCalls > 0 && toolCalls >= l.maxToolCalls:
+		return fmt.Errorf("%w: the turn made all %d tool calls", errLimitReached, l.maxToolCalls)
+	}
+	return nil
+}
+
+// wrapUp asks the model to sum up a turn that reached a limit, as
+// iteration, and returns the summary with why.
+func (c *conversation) wrapUp(ctx context.Context, iteration int, why error) (string, error) {
+	fmt.Printf("\n⏹️  Stopping: %v. Asking for a summary of the progress...\n", why)
+	c.messages = append(c.messages, openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleUser,
+		Content: fmt.Sprintf("[Stop here: %v. Do not call any more tools. Sum up briefly what you have done, "+
+			"what is left to do, and what the next step would be, so I can tell you to continue.]", why),
+	})
+	req := openai.ChatCompletionRequest{
+		Model:       c.model,
+		Messages:    c.messages,
+		Tools:       c.tools,
+		ToolChoice:  "none",
+		Temperature: 0.2,
+	}
+	var resp openai.ChatCompletionResponse
+	err := c.retry.LLM.do(ctx, "Wrap-up request", func(ctx context.Context) error {
+		var err error
+		resp, err = c.client.CreateChatCompletion(ctx, req)
+		return err
+	})
+	if err == nil && len(resp.Choices) == 0 {
+		err = errors.New("no choices in the response")
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w (and the summary failed: %v)", why, err)
+	}
+	c.usage.add(cmp.Or(resp.Model, req.Model), resp.Usage)
+
+	msg := resp.Choices[0].Message
+	c.messages = append(c.messages, msg)
+	// Some models call tools anyway, and every call needs an answer
+	c.skipToolCalls(msg.ToolCalls, why.Error())
+	c.log.assistantMessage(iteration, msg.Content, 0)
+	return msg.Content, why
+}
@@ -6,9 +6,10 @@
 package main
 
 import (
-	"bufio"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -19,106 +20,113 @@ import (
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	openai "github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/codes"
 )
 
-// Global MCP session for tool execution
-var globalMCPSession *mcp.ClientSession
-
-// Global flag to track if initial login prompt has been shown
-var initialLoginPromptShown bool = false
-
-// loadEnvFile loads environment variables from a file
-func loadEnvFile(envFilePath string) error {
-	if envFilePath == "" {
-		return nil // No env file specified
-	}
-
-	fmt.Printf("Loading environment variables from: %s\n", envFilePath)
-
-	file, err := os.Open(envFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to open env file %s: %v", envFilePath, err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
-
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Parse KEY=VALUE format
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			fmt.Printf("Warning: Invalid format on line %d: %s\n", lineNum, line)
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		// Remove quotes if present
-		if (strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"")) ||
-			(strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'")) {
-			value = value[1 : len(value)-1]
-		}
-
-		// Set environment variable
-		if err := os.Setenv(key, value); err != nil {
-			fmt.Printf("Warning: Failed to set environment variable %s: %v\n", key, err)
-		} else {
-			fmt.Printf("Loaded env var: %s\n", key)
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading env file: %v", err)
-	}
-
-	return nil
-}
-
 func main() {
 	// Define command-line flags
 	var filePath string
 	var cdpbrowserPath string
 	var envFilePath string
+	var configPath string
+	var provider, model string
+	var prompt systemPrompt
+	var budgetFlag string
+	var limits contextLimits
+	var runDir string
+	var resumeID string
+	var replayPath string
+	var resolveSelectors bool
+	var once bool
+	var turnLimits turnLimits
+	var verifyMode, verifyModel string
+	var vision bool
+	var voiceMode, recordCmd, stt, whisperCmd string
+	var ttsEngine, ttsVoice, playCmd, sayCmd string
 	flag.StringVar(&filePath, "file", "", "Path to a file whose content will be sent to OpenAI")
 	flag.StringVar(&cdpbrowserPath, "cdpbrowser", "../server/cdpbrowser/cdpbrowser", "Path to the cdpbrowser server executable")
-	flag.StringVar(&envFilePath, "env", "", "Path to environment file containing API keys (e.g., .vscode/voicebrowser.env)")
+	flag.StringVar(&envFilePath, "env", "", "Path to a .env file with API keys (default: the first .env or .vscode/voicebrowser.env in this directory or its parents)")
+	flag.StringVar(&configPath, "config", "", "Path to a JSON config file, e.g. with pause_on rules for sites that need manual login, or defaults for flags")
+	flag.StringVar(&provider, "provider", "openai", "Provider of the OpenAI API: openai, azure, or compatible (a server at OPENAI_BASE_URL)")
+	flag.StringVar(&model, "model", openai.GPT4o, "Chat model, or deployment for -provider azure")
+	flag.StringVar(&prompt.file, "system-prompt", "", "Path to a text/template of the system prompt (default: the built-in system_prompt.tmpl)")
+	flag.Var((*listFlag)(&prompt.overlays), "prompt-overlay", "Path to a template of instructions for the task, added to the system prompt; may be repeated or comma separated")
+	flag.StringVar(&budgetFlag, "budget", "", "Stop the run at an estimated cost, e.g. $2.50, a number of tokens, e.g. 200k, or both, e.g. $2.50,200k")
+	flag.IntVar(&limits.maxResult, "max-tool-result", 20000, "Cut tool results longer than this many characters down to size (0 keeps them whole)")
+	flag.StringVar(&limits.summaryModel, "summary-model", "", "Summarize long tool results with this model, e.g. gpt-4o-mini, instead of truncating them")
+	flag.IntVar(&limits.maxTokens, "context-tokens", 100000, "Compact the oldest tool results and images once the conversation exceeds about this many tokens (0 never does)")
+	flag.StringVar(&runDir, "run-dir", "voicebrowser-runs", "Record each run (transcript, screenshots, and a Markdown report) in a directory under this one; empty records nothing")
+	flag.StringVar(&resumeID, "resume", "", "Resume the recorded run with this ID (a directory under -run-dir) where it stopped")
+	flag.IntVar(&turnLimits.maxIterations, "max-iterations", 50, "Wrap up a turn after this many requests to the model (0 for no limit)")
+	flag.DurationVar(&turnLimits.maxDuration, "max-duration", 0, "Wrap up a turn that has run this long, e.g. 10m (0 for no limit)")
+	flag.IntVar(&turnLimits.maxToolCalls, "max-tool-calls", 0, "Wrap up a turn after this many tool calls (0 for no limit)")
+	flag.StringVar(&verifyMode, "verify", "off", "Check that each click or typing changed the page: off, heuristic, or model (-verify-model)")
+	flag.StringVar(&verifyModel, "verify-model", openai.GPT4oMini, "Cheap model that judges the changes, for -verify model")
+	flag.StringVar(&replayPath, "replay", "", "Run the tool calls of a recorded run (its transcript.jsonl or directory) again, without the model")
+	flag.BoolVar(&resolveSelectors, "resolve-selectors", false, "With -replay, retry a failed call with the element of the same role and name on the page now")
+	flag.BoolVar(&once, "once", false, "Send the file (or a demonstration request) and exit instead of chatting")
+	flag.BoolVar(&vision, "vision", true, "Show screenshots and other images from tools to the model (needs a vision-capable model)")
+	flag.StringVar(&voiceMode, "voice", "off", "Speak instructions: off, push (Enter starts and stops recording), or continuous (each pause ends an instruction)")
+	flag.StringVar(&recordCmd, "record-cmd", "", "Command that records from the microphone to {file} (default: sox, stopping at silence in continuous mode)")
+	flag.StringVar(&stt, "stt", "api", "Speech-to-text engine: api (OpenAI Whisper) or local (-whisper-cmd)")
+	flag.StringVar(&whisperCmd, "whisper-cmd", defaultWhisperCmd, "Command that prints the text spoken in {file}, for -stt local")
+	flag.StringVar(&ttsEngine, "tts", "off", "Speak replies and requests for help: off, openai (OpenAI TTS), or system (-say-cmd)")
+	flag.StringVar(&ttsVoice, "tts-voice", string(openai.VoiceAlloy), "Voice of OpenAI TTS, e.g. alloy, nova, or onyx")
+	flag.StringVar(&playCmd, "play-cmd", "", "Command that plays the audio {file} of OpenAI TTS (default: afplay on macOS, sox play elsewhere)")
+	flag.StringVar(&sayCmd, "say-cmd", "", "System speech command, given the text as {text} or as its last argument (default: say on macOS, espeak elsewhere)")
 	flag.Parse()
 
-	// Load environment variables from file if specified
-	if err := loadEnvFile(envFilePath); err != nil {
-		log.Fatalf("Failed to load environment file: %v", err)
-	}
+	// Keep API keys out of the log
+	log.SetOutput(redactingWriter{os.Stderr})
 
-	// Show updated usage information
+	// Fill in the flags from the environment, .env file, and config file
 	fmt.Println("VoiceBrowser: OpenAI-powered browser automation using CDP browser server")
+	cfg, sources, err := configure(flag.CommandLine)
+	if err != nil {
+		log.Fatalf("Failed to load settings: %v", err)
+	}
+	printSettings(flag.CommandLine, sources)
+	runBudget, err := parseBudget(budgetFlag)
+	if err != nil {
+		log.Fatalf("Invalid -budget: %v", err)
+	}
 	fmt.Printf("Using cdpbrowser server: %s\n", cdpbrowserPath)
-	if envFilePath != "" {
-		fmt.Printf("Loaded environment from: %s\n", envFilePath)
+
+	var replayEvents []runEvent
+	if replayPath != "" {
+		if resumeID != "" {
+			log.Fatal("-replay and -resume cannot be used together")
+		}
+		if replayEvents, err = loadReplay(replayPath); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	// Get OpenAI API key from environment
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		log.Fatal("OPENAI_API_KEY environment variable is required")
+	// Initialize OpenAI client, which a replay only needs to speak
+	var openaiClient *openai.Client
+	if replayPath == "" || ttsEngine == "openai" {
+		if openaiClient, err = newOpenAIClient(provider); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Using %s with provider %s\n", model, describeProvider(provider))
 	}
 
-	// Initialize OpenAI client
-	openaiClient := openai.NewClient(apiKey)
+	// Trace the run; tool calls continue the trace on the server
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+	ctx, journey := startJourney(context.Background())
+	defer journey.End()
 
 	// Initialize MCP connection to cdpbrowser server
-	ctx := context.Background()
 	cmd := exec.Command(cdpbrowserPath)
-	client := mcp.NewClient(&mcp.Implementation{Name: "voicebrowser-client", Version: "v1.0.0"}, nil)
+	// Ctrl-C interrupts the plan, not the server
+	cmd.SysProcAttr = detachedProcAttr()
+	client := mcp.NewClient(&mcp.Implementation{Name: "voicebrowser-client", Version: "v1.0.0"}, &mcp.ClientOptions{
+		ElicitationHandler: askOperator,
+	})
 
 	fmt.Printf("Starting cdpbrowser server: %s\n", cdpbrowserPath)
 	session, err := client.Connect(ctx, &mcp.CommandTransport{Command: cmd}, nil)
@@ -127,10 +135,8 @@ func main() {
 	}
 	defer session.Close()
 
-	// Store session globally for tool execution
-	globalMCPSession = session
-
 	fmt.Println("Connected to cdpbrowser server successfully")
+	fmt.Printf("Trace ID for this run: %s\n", journey.SpanContext().TraceID())
 
 	// Get available tools
 	tools := listTools(ctx, session)
@@ -143,8 +149,18 @@ func main() {
 	}
 
 	fmt.Printf("cdpbrowser successfully connected! Found %d browser tools.\n", len(browserTools))
-	// Use the browser tools for OpenAI interaction
-	tools = browserTools
+	// Use the browser tools for OpenAI interaction, as the config selects them
+	tools = cfg.Tools.selectTools(tools, browserTools)
+	if len(tools) == 0 {
+		log.Fatal("The tools config leaves the model no tools.")
+	}
+	if len(cfg.Tools.Include) > 0 || len(cfg.Tools.Exclude) > 0 {
+		fmt.Printf("Offering %d tools, as configured:", len(tools))
+		for _, t := range tools {
+			fmt.Printf(" %s", t.Name)
+		}
+		fmt.Println()
+	}
 
 	// Prepare message for OpenAI
 	var message string
@@ -156,20 +172,109 @@ func main() {
 		}
 		message = fmt.Sprintf("Here's a document that contains a numbered sequence of steps between {steps} and {/steps} delimiters, that require to be automated.\n\n{steps}%s{/steps}\n\nAnalyze one step at a time and return the next step to be performed. Think step by step. Use the cdpbrowser tools provided.\n", string(content))
 		fmt.Printf("Using content from file: %s\n", filePath)
-	} else {
+	} else if once {
 		// Use default message focused on element discovery
 		message = "Please demonstrate browser automation by going to Google.com, taking an ARIA snapshot to understand the page structure, then typing 'artificial intelligence' in the search box and clicking the search button. Show me how you use the ARIA snapshot to find the correct element selectors."
 		fmt.Println("Using default demonstration message")
 	}
 
-	// Send request to OpenAI with verified browser tools
-	resp, err := sendChatRequest(ctx, openaiClient, message, tools)
-	if err != nil {
-		log.Fatalf("Error calling OpenAI API: %v", err)
+	if ttsEngine != "off" {
+		if narrator, err = newSpeaker(ttsEngine, ttsVoice, playCmd, sayCmd, openaiClient); err != nil {
+			log.Fatalf("Text-to-speech unavailable: %v", err)
+		}
+		fmt.Printf("Speaking replies with %s text-to-speech\n", ttsEngine)
+	}
+
+	// Talk to OpenAI with verified browser tools
+	conv := newConversation(openaiClient, session, tools)
+	conv.model = model
+	prompt.config = cfg.Prompt
+	conv.prompt = &prompt
+	if err := conv.applyAliases(cfg.Tools.Aliases); err != nil {
+		log.Fatalf("Invalid tools config: %v", err)
+	}
+	conv.vision = vision
+	conv.pauseOn = cfg.PauseOn
+	conv.budget = runBudget
+	conv.limits = limits
+	conv.turnLimits = turnLimits
+	if conv.verifier, err = newVerifier(verifyMode, verifyModel); err != nil {
+		log.Fatal(err)
+	}
+	conv.retry = cfg.Retry
+	conv.guard = cfg.Guardrails
+	if err := conv.reset(); err != nil {
+		log.Fatal(err)
+	}
+	if prompt.file != "" || len(prompt.overlays) > 0 {
+		fmt.Printf("System prompt: %s\n", strings.Join(append([]string{cmp.Or(prompt.file, "built in")}, prompt.overlays...), " + "))
+	}
+	if resumeID != "" {
+		if runDir == "" {
+			log.Fatal("-resume needs -run-dir, where the run was recorded")
+		}
+		cp, err := loadCheckpoint(runDir, resumeID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if conv.log, err = reopenRunLog(runDir, resumeID, cp.Turn); err != nil {
+			log.Fatalf("Failed to record the run: %v", err)
+		}
+		// The run carries on with its own instruction, if it has one left
+		message = conv.resume(ctx, cp)
+	} else if runDir != "" {
+		if conv.log, err = newRunLog(runDir); err != nil {
+			log.Fatalf("Failed to record the run: %v", err)
+		}
+		fmt.Printf("Recording run %s in %s (resume it with -resume %s)\n", conv.log.id, conv.log.dir, conv.log.id)
+	}
+	endRun := func() {
+		fmt.Printf("\nOpenAI usage: %s\n", conv.usage.summary())
+		conv.log.close(conv.usage.summary())
+	}
+	defer endRun()
+	if replayPath != "" {
+		if err := conv.replay(ctx, replayPath, replayEvents, resolveSelectors); err != nil {
+			endRun()
+			log.Fatalf("Replay failed: %v", err)
+		}
+		return
+	}
+	if once && message == "" {
+		fmt.Println("The resumed run has no instruction left to carry out.")
+		return
 	}
+	if once {
+		resp, err := conv.send(ctx, message)
+		if errors.Is(err, errLimitReached) && resp != "" {
+			fmt.Println("\nOpenAI Response:")
+			fmt.Println(resp)
+			say(ctx, resp)
+			endRun()
+			log.Fatalf("Stopped: %v", err)
+		}
+		if err != nil {
+			endRun()
+			log.Fatalf("Error calling OpenAI API: %v", err)
+		}
 
-	fmt.Println("\nOpenAI Response:")
-	fmt.Println(resp)
+		fmt.Println("\nOpenAI Response:")
+		fmt.Println(resp)
+		say(ctx, resp)
+		return
+	}
+	var voice *voiceInput
+	if voiceMode != "off" {
+		if voice, err = newVoiceInput(voiceMode, recordCmd, stt, whisperCmd, openaiClient); err != nil {
+			log.Fatalf("Voice input unavailable: %v", err)
+		}
+		defer voice.close()
+		fmt.Printf("Voice input: %s mode, transcribed by %s\n", voiceMode, stt)
+	}
+	if err := chat(ctx, conv, message, voice); err != nil {
+		endRun()
+		log.Fatalf("Error reading input: %v", err)
+	}
 }
 
 // List available tools from the MCP server
@@ -298,19 +403,9 @@ func convertToOpenAITools(mcpTools []*mcp.Tool) []openai.Tool {
 	return tools
 }
 
-// Get MCP session helper function
-func getMCPSession() *mcp.ClientSession {
-	return globalMCPSession
-}
-
-// Send a chat request to OpenAI
-func sendChatRequest(ctx context.Context, client *openai.Client, userMessage string, mcpTools []*mcp.Tool) (string, error) {
-	// Get the MCP session for tool execution
-	mcpSession := getMCPSession()
-	if mcpSession == nil {
-		return "", fmt.Errorf("MCP session not available for tool execution")
-	}
-
+// Start a conversation with the model, with the system prompt and the MCP
+// tools converted for OpenAI
+func newConversation(client *openai.Client, session *mcp.ClientSession, mcpTools []*mcp.Tool) *conversation {
 	// Convert MCP tools to OpenAI format
 	tools := convertToOpenAITools(mcpTools)
 
@@ -324,51 +419,67 @@ func sendChatRequest(ctx context.Context, client *openai.Client, userMessage str
 		}
 	}
 
-	// Keep track of all messages in the conversation
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role: openai.ChatMessageRoleSystem,
-			Content: "You are an expert browser automation assistant using cdpbrowser MCP tools. " +
-				"When the user asks you to interact with web pages, you MUST:\n" +
-				"1. Use 'navigate' to go to websites\n" +
-				"2. Use 'aria_snapshot' to understand page structure and find element selectors\n" +
-				"3. Use element interaction tools (type_text, click_button, click_link, etc.) with the selectors you found\n" +
-				"4. Use 'screenshot' to capture results when helpful\n\n" +
-				"For element selection:\n" +
-				"- CSS selectors like 'input[name=\"q\"]' for Google search\n" +
-				"- ARIA selectors like 'button[aria-label=\"Search\"]'\n" +
-				"- Text-based selectors like 'Submit' for buttons\n" +
-				"- ID selectors like '#search-box'\n\n" +
-				"CRITICAL: When analyzing ARIA snapshots, carefully scan ALL INTERACTIVE ELEMENTS for the exact text you need. " +
-				"Look for buttons, links, and other elements that match the target text exactly. " +
-				"For example, if looking for 'Canva AI', scan through the entire INTERACTIVE ELEMENTS section for buttons or links containing 'Canva AI'. " +
-				"If you find the element, USE IT IMMEDIATELY - don't ignore it or claim it doesn't exist.\n\n" +
-				"Always take an ARIA snapshot first to understand the page before interacting with elements. " +
-				"Don't guess selectors - use the snapshot to find the correct ones. " +
-				"When you find the target element in the snapshot, proceed with the action immediately.",
-		},
+	return &conversation{client: client, session: session, tools: tools, prompt: &systemPrompt{}, retry: defaultRetry()}
+}
+
+// Forget the conversation, keeping only the system prompt
+func (c *conversation) reset() error {
+	prompt, err := c.prompt.render(c)
+	if err != nil {
+		return fmt.Errorf("failed to render the system prompt: %v", err)
+	}
+	c.messages = []openai.ChatCompletionMessage{
 		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: userMessage,
+			Role:    openai.ChatMessageRoleSystem,
+			Content: prompt,
 		},
 	}
+	return nil
+}
 
-	var finalResponse strings.Builder
-	finalResponse.WriteString("Tool Execution Flow:\n\n")
-
-	// Create a conversation loop for tool calls - continue until no more tool calls
-	iteration := 0
-	maxIterations := 50 // Safety limit to prevent infinite loops - can be increased if needed
-	for iteration < maxIterations {
-		iteration++
+// Send a user message to OpenAI and run the tool calls it asks for until it
+// replies without any, returning its reply. The message, the replies, and
+// the tool results stay in the conversation for the next turn. If ctx is
+// canceled, the turn stops and the tool calls not yet run are answered as
+// interrupted.
+func (c *conversation) send(ctx context.Context, userMessage string) (reply string, err error) {
+	c.messages = append(c.messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: userMessage,
+	})
+	c.log.userMessage(userMessage)
+	defer func() {
+		c.log.reply(reply, err)
+		// An instruction stays pending unless it was done or the user
+		// interrupted it, so -resume can carry on with it
+		pending := userMessage
+		if err == nil || errors.Is(err, context.Canceled) {
+			pending = ""
+		}
+		c.saveCheckpoint(ctx, pending)
+	}()
+
+	// Create a conversation loop for tool calls - continue until no more
+	// tool calls, or until the turn reaches a limit
+	started := time.Now()
+	toolCalls := 0
+	for iteration := 1; ; iteration++ {
+		if err := c.turnLimits.check(iteration-1, toolCalls, started); err != nil {
+			return c.wrapUp(ctx, iteration, err)
+		}
 		// Sleep for a short duration to avoid hitting rate limits
-		time.Sleep(2 * time.Second)
+		if err := sleep(ctx, 2*time.Second); err != nil {
+			return "", err
+		}
+
+		// Keep the conversation within the model's context
+		c.compact()
 
 		// Create chat completion request with current messages
 		req := openai.ChatCompletionRequest{
-			Model:       openai.GPT4o,
-			Messages:    messages,
-			Tools:       tools,
+			Model:       c.model,
+			Messages:    c.messages,
+			Tools:       c.tools,
 			ToolChoice:  "auto", // Allow model to decide whether to use tools
 			Temperature: 0.2,    // Lower temperature for more deterministic responses
 		}
@@ -377,36 +488,16 @@ func sendChatRequest(ctx context.Context, client *openai.Client, userMessage str
 		if os.Getenv("DEBUG") == "1" {
 			requestJSON, _ := json.MarshalIndent(req, "", "  ")
 			fmt.Printf("\n==== FULL OPENAI REQUEST (Iteration %d) ====\n%s\n==== END REQUEST ====\n\n",
-				iteration, string(requestJSON))
+				iteration, redact(string(requestJSON)))
 		}
 
-		// Call OpenAI API with rate limit handling
+		// Call OpenAI API, retrying rate limits and passing failures
 		var resp openai.ChatCompletionResponse
-		var err error
-		maxRetries := 5
-		backoffDuration := 2 * time.Second
-
-		for retryCount := 0; retryCount < maxRetries; retryCount++ {
-			resp, err = client.CreateChatCompletion(ctx, req)
-
-			if err == nil {
-				// Success, break out of retry loop
-				break
-			}
-
-			// Check if it's a rate limit error
-			if apiErr, ok := err.(*openai.APIError); ok && (apiErr.Type == "rate_limit_exceeded" || apiErr.Code == "rate_limit_exceeded") {
-				retryAfter := backoffDuration * time.Duration(retryCount+1)
-				fmt.Printf("Rate limit exceeded. Retrying in %v (attempt %d/%d)...\n",
-					retryAfter, retryCount+1, maxRetries)
-				time.Sleep(retryAfter)
-				continue
-			}
-
-			// Not a rate limit error, break and return the error
-			break
-		}
-
+		err := c.retry.LLM.do(ctx, "OpenAI request", func(ctx context.Context) error {
+			var err error
+			resp, err = c.client.CreateChatCompletion(ctx, req)
+			return err
+		})
 		if err != nil {
 			// If we get an error, try to extract more details
 			if apiErr, ok := err.(*openai.APIError); ok {
@@ -420,124 +511,225 @@ func sendChatRequest(ctx context.Context, client *openai.Client, userMessage str
 		if os.Getenv("DEBUG") == "1" {
 			respJSON, _ := json.MarshalIndent(resp, "", "  ")
 			fmt.Printf("\n==== FULL OPENAI RESPONSE (Iteration %d) ====\n%s\n==== END RESPONSE ====\n\n",
-				iteration, string(respJSON))
+				iteration, redact(string(respJSON)))
 		}
 
 		// Process the response
 		choice := resp.Choices[0]
-		finalResponse.WriteString(fmt.Sprintf("**Iteration %d:**\n", iteration))
-		finalResponse.WriteString(fmt.Sprintf("OpenAI: %s\n\n", choice.Message.Content))
+		model := resp.Model
+		if model == "" {
+			model = req.Model
+		}
+		c.usage.add(model, resp.Usage)
 
 		// Add assistant's message to conversation
-		messages = append(messages, choice.Message)
+		c.messages = append(c.messages, choice.Message)
+		c.log.assistantMessage(iteration, choice.Message.Content, len(choice.Message.ToolCalls))
+
+		// Stop once the run has used up its budget
+		if err := c.budget.check(&c.usage); err != nil {
+			c.skipToolCalls(choice.Message.ToolCalls, "the run is over budget")
+			return "", err
+		}
 
 		// Check if the model wants to call tools
 		if len(choice.Message.ToolCalls) == 0 {
-			// No tool calls, but model may have provided final response
-			fmt.Printf("OpenAI completed without tool calls. Response: %s\n", choice.Message.Content)
-			break
+			// No tool calls, so this is the model's reply to the user
+			return choice.Message.Content, nil
+		}
+		if choice.Message.Content != "" {
+			fmt.Printf("OpenAI: %s\n", choice.Message.Content)
 		}
 
 		// Execute tool calls
-		for _, toolCall := range choice.Message.ToolCalls {
-			fmt.Printf("Executing tool: %s\n", toolCall.Function.Name)
-			finalResponse.WriteString(fmt.Sprintf("Executing tool: %s\n", toolCall.Function.Name))
+		var images []toolImage
+		for i, toolCall := range choice.Message.ToolCalls {
+			// Every tool call needs a result before the next request, so
+			// answer the rest once the user interrupts
+			if ctx.Err() != nil {
+				c.skipToolCalls(choice.Message.ToolCalls[i:], "the user interrupted the plan")
+				return "", ctx.Err()
+			}
+			if err := c.turnLimits.check(0, toolCalls, started); err != nil {
+				c.skipToolCalls(choice.Message.ToolCalls[i:], err.Error())
+				break
+			}
+			toolCalls++
 
-			// Execute the MCP tool
-			result, err := executeMCPTool(ctx, mcpSession, toolCall.Function.Name, toolCall.Function.Arguments)
+			// The model may know the tool by an alias
+			toolName := c.serverName(toolCall.Function.Name)
+			fmt.Printf("Executing tool: %s\n", toolName)
+
+			// Execute the MCP tool, unless it breaks a guardrail
+			var result string
+			var toolImages []toolImage
+			err := c.guard.check(toolName, toolCall.Function.Arguments)
+			if err != nil {
+				fmt.Printf("🛡️  Blocked %s: %v\n", toolName, err)
+			} else {
+				verify := c.baseline(ctx, toolName)
+				result, toolImages, err = c.callTool(ctx, toolName, toolCall.Function.Arguments)
+				if err == nil {
+					result += c.offSite(ctx)
+				}
+				if err == nil && verify {
+					result += c.verify(ctx, choice.Message.Content, toolName, toolCall.Function.Arguments, result)
+				}
+			}
+			if c.vision {
+				images = append(images, toolImages...)
+			}
 			if err != nil {
 				result = fmt.Sprintf("Error: %v", err)
 				fmt.Printf("Tool execution error: %v\n", err)
 			}
 
 			fmt.Printf("Tool result: %s\n\n", result)
-			finalResponse.WriteString(fmt.Sprintf("Result: %s\n\n", result))
-
-			// Check if this was the first navigate to the target website - if so, pause for manual login/cleanup
-			if toolCall.Function.Name == "navigate" && !initialLoginPromptShown {
-				// Parse the arguments to see if this is navigating to the target website
-				var args map[string]interface{}
-				if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err == nil {
-					if url, ok := args["url"].(string); ok {
-						// Check if this is a target website (not just any navigation)
-						if strings.Contains(strings.ToLower(url), "canva.com") {
-							fmt.Printf("\n🌐 Navigation to target website completed. Pausing for manual intervention...\n")
-							fmt.Println("Please complete any necessary login to Canva and close any popup dialogues that may impede the workflow.")
-							fmt.Print("Press Enter when ready to continue automation: ")
-
-							// Wait for user input
-							reader := bufio.NewReader(os.Stdin)
-							reader.ReadLine()
-
-							fmt.Println("✅ Continuing automation...")
-							initialLoginPromptShown = true
-						}
-					}
-				}
+			c.log.toolCall(iteration, toolCall.ID, toolName, toolCall.Function.Arguments, result, err != nil)
+			for _, img := range toolImages {
+				c.log.image(iteration, img)
+			}
+
+			// Let the user log in or clean up the page first, if the
+			// config asks for it
+			if err == nil {
+				pauseAfter(ctx, c.pauseOn, toolName, toolCall.Function.Arguments)
 			}
 
-			// Add tool result to conversation
+			// Add tool result to conversation, cut down to size
 			toolMessage := openai.ChatCompletionMessage{
 				Role:       openai.ChatMessageRoleTool,
-				Content:    result,
+				Content:    c.fitResult(ctx, toolName, result),
 				ToolCallID: toolCall.ID,
 			}
-			messages = append(messages, toolMessage)
+			c.messages = append(c.messages, toolMessage)
 		}
 
+		// Let the model look at the screenshots it took
+		if len(images) > 0 {
+			c.messages = append(c.messages, imageMessage(images))
+		}
+		c.saveCheckpoint(ctx, userMessage)
+
 		// Add a 30-second delay between steps to avoid rate limits
-		if len(choice.Message.ToolCalls) > 0 {
-			fmt.Printf("\n⏱️  Waiting 30 seconds to avoid rate limits...\n")
-			time.Sleep(30 * time.Second)
-			fmt.Printf("✅ Continuing to next step...\n\n")
+		fmt.Printf("\n⏱️  Waiting 30 seconds to avoid rate limits...\n")
+		if err := sleep(ctx, 30*time.Second); err != nil {
+			return "", err
 		}
+		fmt.Printf("✅ Continuing to next step...\n\n")
 
 		// Continue to next iteration for model to process tool results
 	}
+}
 
-	// Check if we hit the safety limit
-	if iteration >= maxIterations {
-		finalResponse.WriteString(fmt.Sprintf("\n**Reached maximum iterations (%d). Stopping for safety.**\n", maxIterations))
-		fmt.Printf("Warning: Reached maximum iterations (%d). Consider increasing the limit if more automation is needed.\n", maxIterations)
+// Answer tool calls that were not run with why, since every tool call needs
+// a result before the next request
+func (c *conversation) skipToolCalls(calls []openai.ToolCall, why string) {
+	for _, call := range calls {
+		c.messages = append(c.messages, openai.ChatCompletionMessage{
+			Role:       openai.ChatMessageRoleTool,
+			Content:    "Not executed: " + why,
+			ToolCallID: call.ID,
+		})
 	}
+}
 
-	return finalResponse.String(), nil
+// Sleep for d, or until ctx is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// Execute an MCP tool with the given name and arguments
-func executeMCPTool(ctx context.Context, mcpSession *mcp.ClientSession, toolName string, argsJSON string) (string, error) {
+// Execute an MCP tool with the given name and arguments. The images it
+// returns are returned too, to be saved and, with vision, shown to the
+// model.
+func executeMCPTool(ctx context.Context, mcpSession *mcp.ClientSession, toolName string, argsJSON string, vision bool) (string, []toolImage, error) {
 	if mcpSession == nil {
-		return "", fmt.Errorf("MCP session is not available")
+		return "", nil, fmt.Errorf("MCP session is not available")
 	}
 
 	// Parse the arguments JSON
 	var args map[string]interface{}
 	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
-		return "", fmt.Errorf("failed to parse tool arguments: %v", err)
+		return "", nil, fmt.Errorf("failed to parse tool arguments: %v", err)
 	}
 
 	// Execute the tool
+	ctx, span, meta := startToolSpan(ctx, toolName)
+	defer span.End()
 	result, err := mcpSession.CallTool(ctx, &mcp.CallToolParams{
+		Meta:      meta,
 		Name:      toolName,
 		Arguments: args,
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("failed to execute tool %s: %v", toolName, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return "", nil, fmt.Errorf("failed to execute tool %s: %w", toolName, err)
 	}
 
 	// Convert result to string
 	var resultText strings.Builder
+	var images []toolImage
 	for _, content := range result.Content {
 		switch c := content.(type) {
 		case *mcp.TextContent:
 			resultText.WriteString(c.Text)
 		case *mcp.ImageContent:
+			images = append(images, toolImage{tool: toolName, image: c})
+			if vision {
+				resultText.WriteString(fmt.Sprintf("[Image %d: %s, %d bytes, shown in the next message]", len(images), c.MIMEType, len(c.Data)))
+				break
+			}
 			resultText.WriteString(fmt.Sprintf("[Image: %s, %d bytes]", c.MIMEType, len(c.Data)))
 		default:
 			resultText.WriteString(fmt.Sprintf("[Unknown content type: %T]", content))
 		}
 	}
 
-	return resultText.String(), nil
+	if result.IsError {
+		span.SetStatus(codes.Error, "tool returned an error result")
+		return resultText.String(), images, &toolError{text: resultText.String()}
+	}
+	return resultText.String(), images, nil
+}
+
+// Ask the user on the terminal when the server needs human input, such as a
+// one-time code or a CAPTCHA solved in the browser window
+func askOperator(ctx context.Context, req *mcp.ClientRequest[*mcp.ElicitParams]) (*mcp.ElicitResult, error) {
+	fmt.Printf("\n🙋 The browser needs your help:\n%s\n", req.Params.Message)
+	say(ctx, "The browser needs your help. "+req.Params.Message)
+	var wantsValue bool
+	if schema := req.Params.RequestedSchema; schema != nil {
+		_, wantsValue = schema.Properties["value"]
+	}
+	if wantsValue {
+		fmt.Print("Answer (empty to decline): ")
+	} else {
+		fmt.Print("Press Enter when done, or type 'no' to decline: ")
+	}
+
+	line, err := readLine()
+	if err != nil {
+		return &mcp.ElicitResult{Action: "cancel"}, nil
+	}
+	answer := strings.TrimSpace(line)
+
+	if wantsValue {
+		if answer == "" {
+			return &mcp.ElicitResult{Action: "decline"}, nil
+		}
+		fmt.Println("✅ Continuing automation...")
+		return &mcp.ElicitResult{Action: "accept", Content: map[string]any{"value": answer}}, nil
+	}
+	if strings.EqualFold(answer, "no") {
+		return &mcp.ElicitResult{Action: "decline"}, nil
+	}
+	fmt.Println("✅ Continuing automation...")
+	return &mcp.ElicitResult{Action: "accept", Content: map[string]any{}}, nil
 }
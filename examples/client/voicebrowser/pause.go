@@ -0,0 +1,65 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// A pauseRule stops the agent the first time it navigates to a URL that
+// matches Pattern, so the user can log in, dismiss a cookie banner, or
+// otherwise get the page ready before the automation goes on.
+type pauseRule struct {
+	Pattern string `json:"pattern"` // regular expression
+	Message string `json:"message"` // what the user should do
+
+	re     *regexp.Regexp
+	paused bool
+}
+
+func (r *pauseRule) compile() error {
+	if r.Pattern == "" {
+		return errors.New("no pattern")
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return err
+	}
+	r.re = re
+	if r.Message == "" {
+		r.Message = "Complete any login, and close any pop-ups that may get in the way."
+	}
+	return nil
+}
+
+// pauseAfter waits for the user if the tool call toolName, with arguments
+// argsJSON, navigated to a URL of a rule that has not paused yet.
+func pauseAfter(ctx context.Context, rules []*pauseRule, toolName, argsJSON string) {
+	if toolName != "navigate" {
+		return
+	}
+	var args struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+		return
+	}
+	for _, rule := range rules {
+		if rule.paused || !rule.re.MatchString(args.URL) {
+			continue
+		}
+		rule.paused = true
+		fmt.Printf("\n🌐 Navigated to %s. Pausing for manual intervention...\n", args.URL)
+		fmt.Println(rule.Message)
+		say(ctx, rule.Message)
+		fmt.Print("Press Enter when ready to continue automation: ")
+		readLine()
+		fmt.Println("✅ Continuing automation...")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "syscall"
+
+// detachedProcAttr returns nil, starting processes with the default
+// attributes.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// The system prompt is a text/template, built in or given by
+// -system-prompt, followed by the overlays of -prompt-overlay, which are
+// templates too and add instructions for a kind of task, such as filling
+// in forms on one site. The files are read each time the conversation is
+// reset, so a prompt can be changed between turns with 'reset'.
+
+//go:embed system_prompt.tmpl
+var defaultSystemPrompt string
+
+// A promptConfig is the "prompt" object of the config file:
+//
+//	"prompt": {
+//	  "site_hints": [{"site": "canva.com", "hint": "The AI tools are under 'Canva AI' in the side bar."}],
+//	  "guardrails": ["Never buy anything or enter payment details."]
+//	}
+type promptConfig struct {
+	SiteHints  []siteHint `json:"site_hints"`
+	Guardrails []string   `json:"guardrails"`
+}
+
+// A siteHint tells the model something about a site.
+type siteHint struct {
+	Site string `json:"site"`
+	Hint string `json:"hint"`
+}
+
+// A promptTool is a tool as the system prompt describes it.
+type promptTool struct {
+	Name        string // as the model sees it
+	ServerName  string
+	Description string
+}
+
+// A systemPrompt renders the system prompt of a conversation.
+type systemPrompt struct {
+	file     string   // the template, or "" for the built-in one
+	overlays []string // templates appended to it
+	config   promptConfig
+}
+
+// render renders the system prompt for the tools of c.
+func (p *systemPrompt) render(c *conversation) (string, error) {
+	data := struct {
+		Tools      []promptTool
+		SiteHints  []siteHint
+		Guardrails []string
+		// AllowedSites are the guardrails' allow_urls.
+		AllowedSites []string
+	}{SiteHints: p.config.SiteHints, Guardrails: p.config.Guardrails}
+	if c.guard != nil {
+		data.AllowedSites = c.guard.AllowURLs
+	}
+	modelNames := make(map[string]string)
+	for _, t := range c.tools {
+		server := c.serverName(t.Function.Name)
+		modelNames[server] = t.Function.Name
+		data.Tools = append(data.Tools, promptTool{Name: t.Function.Name, ServerName: server, Description: t.Function.Description})
+	}
+	funcs := template.FuncMap{
+		"tool": func(server string) string {
+			if name, ok := modelNames[server]; ok {
+				return name
+			}
+			return server
+		},
+		"has": func(server string) bool {
+			_, ok := modelNames[server]
+			return ok
+		},
+	}
+
+	var parts []string
+	for i, file := range append([]string{p.file}, p.overlays...) {
+		text := defaultSystemPrompt
+		name := "system prompt"
+		if i > 0 || file != "" {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return "", fmt.Errorf("failed to read prompt: %v", err)
+			}
+			text, name = string(data), file
+		}
+		tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return "", err
+		}
+		if s := strings.TrimSpace(b.String()); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// A listFlag is a flag that may be given more than once, or as a comma
+// separated list.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// -replay runs the tool calls of a recorded run again, in order, without
+// the model: a run that worked once can be repeated for free and always
+// the same way. Calls that failed in the recording are skipped, since the
+// model went another way after them. The replay stops at the first call
+// that fails, as the calls after it depend on it.
+//
+// Pages change, so a selector or element id that worked in the recording
+// may no longer match. With -resolve-selectors, a call that fails is tried
+// once more with the element that has the same role and name in a fresh
+// ARIA snapshot, as the snapshots of the recording described the element.
+// Elements without a name are not re-resolved.
+
+// An elementRef is what a recorded snapshot said about an element.
+type elementRef struct {
+	role, name string
+}
+
+// loadReplay reads the events of the transcript at path, which may also be
+// the directory of a recorded run.
+func loadReplay(path string) ([]runEvent, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, "transcript.jsonl")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot replay: %v", err)
+	}
+	defer f.Close()
+	var events []runEvent
+	scanner := bufio.NewScanner(f)
+	// Tool results, such as snapshots, can be long lines
+	scanner.Buffer(nil, 64<<20)
+	for n := 1; scanner.Scan(); n++ {
+		var e runEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("cannot replay %s: line %d: %v", path, n, err)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot replay %s: %v", path, err)
+	}
+	return events, nil
+}
+
+// replay runs the tool calls of the recorded events, re-resolving the
+// elements of calls that fail if resolve is set.
+func (c *conversation) replay(ctx context.Context, name string, events []runEvent, resolve bool) error {
+	results := make(map[string]runEvent)
+	calls := 0
+	for _, e := range events {
+		switch e.Type {
+		case "tool_result":
+			results[e.CallID] = e
+		case "tool_call":
+			calls++
+		}
+	}
+	if calls == 0 {
+		return fmt.Errorf("%s recorded no tool calls", name)
+	}
+	fmt.Printf("Replaying %d tool calls of %s\n", calls, name)
+	c.log.userMessage("Replay of " + name)
+
+	refs := make(map[string]elementRef)
+	step, replayed, skipped, resolved := 0, 0, 0, 0
+	for _, e := range events {
+		if e.Type == "user" {
+			fmt.Printf("\n📝 Turn %d: %s\n", e.Turn, e.Content)
+			continue
+		}
+		if e.Type != "tool_call" {
+			continue
+		}
+		step++
+		recorded := results[e.CallID]
+		// Later calls may use the elements a snapshot found, even a
+		// snapshot that is not run again
+		if e.Tool == "aria_snapshot" && !recorded.IsError {
+			learnElements(refs, recorded.Content)
+		}
+		if recorded.IsError {
+			fmt.Printf("⏭️  [%d/%d] Skipping %s, which failed in the recording\n", step, calls, e.Tool)
+			skipped++
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		fmt.Printf("▶️  [%d/%d] %s %s\n", step, calls, e.Tool, e.Arguments)
+		args := e.Arguments
+		result, images, err := c.replayCall(ctx, e.Tool, args)
+		if err != nil && resolve {
+			if fixed, how := c.resolveElement(ctx, e.Tool, args, refs); fixed != "" {
+				fmt.Printf("🔁 Re-resolved %s\n", how)
+				args = fixed
+				if result, images, err = c.replayCall(ctx, e.Tool, args); err == nil {
+					resolved++
+				}
+			}
+		}
+		if err != nil {
+			result = fmt.Sprintf("Error: %v", err)
+		}
+		c.log.toolCall(e.Iteration, e.CallID, e.Tool, args, result, err != nil)
+		for _, img := range images {
+			c.log.image(e.Iteration, img)
+		}
+		if err != nil {
+			c.log.reply("", err)
+			return fmt.Errorf("step %d, %s, failed: %v", step, e.Tool, err)
+		}
+		replayed++
+		pauseAfter(ctx, c.pauseOn, e.Tool, args)
+	}
+
+	summary := fmt.Sprintf("Replayed %d tool calls (%d re-resolved), skipped %d that failed in the recording.", replayed, resolved, skipped)
+	fmt.Printf("\n✅ %s\n", summary)
+	c.log.reply(summary, nil)
+	return nil
+}
+
+// replayCall runs a recorded tool call within the guardrails.
+func (c *conversation) replayCall(ctx context.Context, tool, args string) (string, []toolImage, error) {
+	if err := c.guard.check(tool, args); err != nil {
+		return "", nil, err
+	}
+	return c.callTool(ctx, tool, args)
+}
+
+// resolveElement returns the arguments args of tool with the selector or
+// element id replaced by that of the same element on the page now, and a
+// description of the change, or "" if it cannot find the element.
+func (c *conversation) resolveElement(ctx context.Context, tool, argsJSON string, refs map[string]elementRef) (string, string) {
+	var args map[string]any
+	if json.Unmarshal([]byte(argsJSON), &args) != nil {
+		return "", ""
+	}
+	selector, bySelector := args["selector"].(string)
+	id, byID := args["id"].(float64)
+	var ref elementRef
+	var ok bool
+	switch {
+	case bySelector:
+		ref, ok = refs["selector:"+selector]
+	case byID:
+		ref, ok = refs["id:"+strconv.Itoa(int(id))]
+	}
+	if !ok {
+		return "", ""
+	}
+
+	text, _, err := executeMCPTool(ctx, c.session, "aria_snapshot", `{"focus":"interactive","format":"json"}`, false)
+	if err != nil {
+		return "", ""
+	}
+	var snapshot struct {
+		Interactive []struct {
+			ID       int    `json:"id"`
+			Role     string `json:"role"`
+			Name     string `json:"name"`
+			Selector string `json:"selector"`
+		} `json:"interactive"`
+	}
+	if json.Unmarshal([]byte(text), &snapshot) != nil {
+		return "", ""
+	}
+	for _, e := range snapshot.Interactive {
+		if e.Role != ref.role || !strings.EqualFold(e.Name, ref.name) {
+			continue
+		}
+		var how string
+		if bySelector {
+			if e.Selector == "" || e.Selector == selector {
+				continue
+			}
+			args["selector"] = e.Selector
+			how = fmt.Sprintf("%s [%s] %q: %s -> %s", tool, ref.role, ref.name, selector, e.Selector)
+		} else {
+			if e.ID == 0 || e.ID == int(id) {
+				continue
+			}
+			args["id"] = e.ID
+			how = fmt.Sprintf("%s [%s] %q: id %d -> %d", tool, ref.role, ref.name, int(id), e.ID)
+		}
+		fixed, _ := json.Marshal(args)
+		return string(fixed), how
+	}
+	return "", ""
+}
+
+// learnElements adds the elements of a recorded ARIA snapshot to refs, by
+// selector and by id. It reads snapshots in the json format and in the
+// default text format.
+func learnElements(refs map[string]elementRef, snapshot string) {
+	var data struct {
+		Interactive []struct {
+			ID        int      `json:"id"`
+			Role      string   `json:"role"`
+			Name      string   `json:"name"`
+			Selectors []string `json:"selectors"`
+			Selector  string   `json:"selector"`
+		} `json:"interactive"`
+	}
+	if json.Unmarshal([]byte(snapshot), &data) == nil {
+		for _, e := range data.Interactive {
+			if e.Name == "" {
+				continue
+			}
+			ref := elementRef{e.Role, e.Name}
+			for _, s := range append(e.Selectors, e.Selector) {
+				if s != "" {
+					refs["selector:"+s] = ref
+				}
+			}
+			if e.ID != 0 {
+				refs["id:"+strconv.Itoa(e.ID)] = ref
+			}
+		}
+		return
+	}
+
+	// Lines such as
+	//
+	//	• #12 [button] "Search" (selector: #search)
+	//	• #13 [link] "Docs" (aria-label: "Read the docs") -> /docs
+	//	  - Primary selector: a[aria-label="Read the docs"]
+	//	  - Alternative selectors: #docs, text=Docs
+	var ref elementRef
+	for _, line := range strings.Split(snapshot, "\n") {
+		switch {
+		case strings.HasPrefix(line, "• "):
+			var id, selector string
+			ref, id, selector = parseElementLine(strings.TrimPrefix(line, "• "))
+			// An element without a name shows its tag, such as <input>,
+			// and its role alone could match many
+			if ref.role == "" || strings.HasPrefix(ref.name, "<") {
+				ref = elementRef{}
+				continue
+			}
+			if id != "" {
+				refs["id:"+id] = ref
+			}
+			if selector != "" {
+				refs["selector:"+selector] = ref
+			}
+		case ref.role != "" && strings.HasPrefix(line, "  - Primary selector: "):
+			refs["selector:"+strings.TrimPrefix(line, "  - Primary selector: ")] = ref
+		case ref.role != "" && strings.HasPrefix(line, "  - Alternative selectors: "):
+			for _, s := range strings.Split(strings.TrimPrefix(line, "  - Alternative selectors: "), ", ") {
+				refs["selector:"+s] = ref
+			}
+		default:
+			ref = elementRef{}
+		}
+	}
+}
+
+// parseElementLine parses the line of an interactive element of a text
+// snapshot, without its bullet, into the element, its id, and its
+// selector, if the line has them.
+func parseElementLine(line string) (ref elementRef, id, selector string) {
+	if rest, ok := strings.CutPrefix(line, "#"); ok {
+		id, line, _ = strings.Cut(rest, " ")
+	}
+	role, rest, ok := strings.Cut(strings.TrimPrefix(line, "["), "] \"")
+	if !ok || !strings.HasPrefix(line, "[") {
+		return elementRef{}, "", ""
+	}
+	if i := strings.LastIndex(rest, " (selector: "); i >= 0 && strings.HasSuffix(rest, ")") {
+		selector = rest[i+len(" (selector: ") : len(rest)-1]
+		rest = rest[:i]
+	}
+	// The name ends at the aria-label, link, or value that may follow it
+	for _, sep := range []string{`" (aria-label: "`, `" -> `, `" value="`} {
+		if i := strings.Index(rest, sep); i >= 0 {
+			rest = rest[:i+1]
+		}
+	}
+	return elementRef{role: role, name: strings.TrimSuffix(rest, `"`)}, id, selector
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+// textSnapshot is what the server's default aria_snapshot format prints,
+// from examples/server/cdpbrowser/pkg/browserserver/formatters.go.
+const textSnapshot = `PAGE: Shop (https://shop.example/)
+
+LANDMARKS:
+• [navigation] Main
+
+INTERACTIVE ELEMENTS:
+• #12 [button] "Search" (selector: #search)
+• #13 [link] "Docs" (aria-label: "Read the docs") -> /docs
+  - Primary selector: a[aria-label="Read the docs"]
+  - Alternative selectors: #docs, text=Docs
+• #14 [textbox] "Email" value="me@example.com" (selector: input[name="email"])
+• #15 [textbox] "<input>" (selector: #q)
+• #16 [link] "Say "hi" (now)" -> https://shop.example/hi?a=1 (selector: #hi)
+• #17 [combobox] "Size" (aria-label: "Pick a size") value="M"
+  - Primary selector: select[aria-label="Pick a size"]
+• [button] "Close" (selector: button.close)
+
+HEADINGS:
+• [h1] "Welcome"
+`
+
+func TestLearnElementsText(t *testing.T) {
+	refs := make(map[string]elementRef)
+	learnElements(refs, textSnapshot)
+	search := elementRef{"button", "Search"}
+	docs := elementRef{"link", "Docs"}
+	email := elementRef{"textbox", "Email"}
+	hi := elementRef{"link", `Say "hi" (now)`}
+	size := elementRef{"combobox", "Size"}
+	want := map[string]elementRef{
+		"id:12":                                  search,
+		"selector:#search":                       search,
+		"id:13":                                  docs,
+		`selector:a[aria-label="Read the docs"]`: docs,
+		"selector:#docs":                         docs,
+		"selector:text=Docs":                     docs,
+		"id:14":                                  email,
+		`selector:input[name="email"]`:           email,
+		"id:16":                                  hi,
+		"selector:#hi":                           hi,
+		"id:17":                                  size,
+		`selector:select[aria-label="Pick a size"]`: size,
+		"selector:button.close":                     {"button", "Close"},
+	}
+	if !maps.Equal(refs, want) {
+		t.Errorf("learnElements() =\n%v\nwant\n%v", refs, want)
+	}
+}
+
+func TestLearnElementsJSON(t *testing.T) {
+	refs := make(map[string]elementRef)
+	learnElements(refs, `{"page":{"title":"Shop"},"interactive":[
+		{"id":13,"role":"link","name":"Docs","selector":"a[aria-label=\"Read the docs\"]","selectors":["a[aria-label=\"Read the docs\"]","#docs"],"ariaLabel":"Read the docs","tag":"a","href":"/docs"},
+		{"id":15,"role":"textbox","selector":"#q","tag":"input"}
+	]}`)
+	docs := elementRef{"link", "Docs"}
+	want := map[string]elementRef{
+		"id:13":                                  docs,
+		`selector:a[aria-label="Read the docs"]`: docs,
+		"selector:#docs":                         docs,
+	}
+	if !maps.Equal(refs, want) {
+		t.Errorf("learnElements() =\n%v\nwant\n%v", refs, want)
+	}
+}
+
+func TestParseElementLine(t *testing.T) {
+	tests := []struct {
+		line         string
+		want         elementRef
+		id, selector string
+	}{
+		{`#12 [button] "Search" (selector: #search)`, elementRef{"button", "Search"}, "12", "#search"},
+		{`#13 [link] "Docs" (aria-label: "Read the docs") -> /docs`, elementRef{"link", "Docs"}, "13", ""},
+		{`#14 [textbox] "Email" value="me@example.com" (selector: input[name="email"])`, elementRef{"textbox", "Email"}, "14", `input[name="email"]`},
+		{`#17 [combobox] "Size" (aria-label: "Pick a size") value="M"`, elementRef{"combobox", "Size"}, "17", ""},
+		{`[button] "Close" (selector: button.close)`, elementRef{"button", "Close"}, "", "button.close"},
+		{`[navigation] Main`, elementRef{}, "", ""},
+	}
+	for _, tt := range tests {
+		ref, id, selector := parseElementLine(tt.line)
+		if ref != tt.want || id != tt.id || selector != tt.selector {
+			t.Errorf("parseElementLine(%s) = %v, %q, %q, want %v, %q, %q", tt.line, ref, id, selector, tt.want, tt.id, tt.selector)
+		}
+	}
+}
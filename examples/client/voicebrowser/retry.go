@@ -0,0 +1,258 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Calls to the model and to tools that fail for a passing reason are tried
+// again, waiting longer each time, as the "retry" object of the config file
+// sets out:
+//
+//	"retry": {
+//	  "llm":   {"max_attempts": 6, "max_backoff": "2m"},
+//	  "tools": {"retry_on": ["network", "timeout"], "attempt_timeout": "45s"}
+//	}
+//
+// The classes of errors a policy can retry are:
+//
+//	rate_limit  the API's rate limit (but not an exhausted quota)
+//	5xx         a server error of the API
+//	network     a dropped or refused connection, to the API or of the
+//	            browser
+//	timeout     a call that took longer than its deadline
+//
+// Tools only retry network errors by default, since a tool that timed out
+// may have clicked or typed before it did.
+
+// A retryPolicy says how often and how patiently to retry a call.
+type retryPolicy struct {
+	MaxAttempts    int      `json:"max_attempts"`
+	InitialBackoff duration `json:"initial_backoff"`
+	MaxBackoff     duration `json:"max_backoff"`
+	// Multiplier grows the backoff after each attempt.
+	Multiplier float64 `json:"multiplier"`
+	// Jitter is the part of each backoff that is random, from 0 to 1, so
+	// that clients that failed together do not retry together.
+	Jitter float64 `json:"jitter"`
+	// AttemptTimeout bounds each attempt, or is 0 for no bound.
+	AttemptTimeout duration `json:"attempt_timeout"`
+	RetryOn        []string `json:"retry_on"`
+}
+
+// A retryConfig is the "retry" object of the config file.
+type retryConfig struct {
+	LLM   retryPolicy `json:"llm"`
+	Tools retryPolicy `json:"tools"`
+}
+
+// defaultRetry returns the policies that apply unless the config file
+// changes them.
+func defaultRetry() retryConfig {
+	return retryConfig{
+		LLM: retryPolicy{
+			MaxAttempts:    5,
+			InitialBackoff: duration(2 * time.Second),
+			MaxBackoff:     duration(time.Minute),
+			Multiplier:     2,
+			Jitter:         0.5,
+			RetryOn:        []string{"rate_limit", "5xx", "network", "timeout"},
+		},
+		Tools: retryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: duration(time.Second),
+			MaxBackoff:     duration(10 * time.Second),
+			Multiplier:     2,
+			Jitter:         0.5,
+			RetryOn:        []string{"network"},
+		},
+	}
+}
+
+// retryClasses are the classes of errors a policy can retry.
+var retryClasses = []string{"rate_limit", "5xx", "network", "timeout"}
+
+// check reports what is wrong with p, if anything.
+func (p *retryPolicy) check() error {
+	switch {
+	case p.MaxAttempts < 1:
+		return fmt.Errorf("max_attempts must be at least 1")
+	case p.InitialBackoff < 0 || p.MaxBackoff < p.InitialBackoff:
+		return fmt.Errorf("max_backoff must be at least initial_backoff, which cannot be negative")
+	case p.Multiplier < 1:
+		return fmt.Errorf("multiplier must be at least 1")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("jitter must be from 0 to 1")
+	case p.AttemptTimeout < 0:
+		return fmt.Errorf("attempt_timeout cannot be negative")
+	}
+	for _, class := range p.RetryOn {
+		if !slices.Contains(retryClasses, class) {
+			return fmt.Errorf("unknown retry_on class %q (use %s)", class, strings.Join(retryClasses, ", "))
+		}
+	}
+	return nil
+}
+
+// check reports what is wrong with the policies of c, if anything.
+func (c *retryConfig) check() error {
+	if err := c.LLM.check(); err != nil {
+		return fmt.Errorf("llm: %v", err)
+	}
+	if err := c.Tools.check(); err != nil {
+		return fmt.Errorf("tools: %v", err)
+	}
+	return nil
+}
+
+// backoff returns how long to wait after the failed attempt, counting from
+// 1.
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	d = min(d, float64(p.MaxBackoff))
+	d -= d * p.Jitter * rand.Float64()
+	return time.Duration(d)
+}
+
+// do calls fn until it succeeds, fails with an error p does not retry, or
+// has been tried p.MaxAttempts times, and returns its last error. what
+// names the call in messages.
+func (p *retryPolicy) do(ctx context.Context, what string, fn func(context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if p.AttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, time.Duration(p.AttemptTimeout))
+		}
+		err := fn(attemptCtx)
+		cancel()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		class := errorClass(err)
+		if attempt >= p.MaxAttempts || !slices.Contains(p.RetryOn, class) {
+			return err
+		}
+		wait := p.backoff(attempt)
+		fmt.Printf("%s failed (%s: %v). Retrying in %v (attempt %d/%d)...\n",
+			what, class, err, wait.Round(100*time.Millisecond), attempt+1, p.MaxAttempts)
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// A toolError is the error result of a tool, which the server reports as a
+// result rather than as a failed call.
+type toolError struct {
+	text string
+}
+
+func (e *toolError) Error() string {
+	return e.text
+}
+
+// Parts of the messages of passing tool errors, such as the browser's
+// DevTools connection dropping, by class.
+var (
+	networkMessages = []string{"connection reset", "connection refused", "broken pipe", "unexpected eof", "websocket: close", "use of closed network connection"}
+	timeoutMessages = []string{"context deadline exceeded", "i/o timeout", "timed out"}
+)
+
+// errorClass returns the class of err a retryPolicy may retry, or "" if
+// trying again would not help.
+func errorClass(err error) string {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	var netErr net.Error
+	var tErr *toolError
+	status := 0
+	switch {
+	case errors.As(err, &apiErr):
+		// A 429 for an exhausted quota lasts until someone pays
+		if apiErr.Code == "insufficient_quota" || apiErr.Type == "insufficient_quota" {
+			return ""
+		}
+		if apiErr.Code == "rate_limit_exceeded" || apiErr.Type == "rate_limit_exceeded" {
+			return "rate_limit"
+		}
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+	switch {
+	case status == 429:
+		return "rate_limit"
+	case status >= 500:
+		return "5xx"
+	case status != 0:
+		return ""
+	}
+
+	switch {
+	case errors.Is(err, mcp.ErrConnectionClosed):
+		// The server is gone, and the session with it
+		return ""
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &netErr), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return "network"
+	case errors.As(err, &tErr):
+		text := strings.ToLower(tErr.text)
+		for _, m := range networkMessages {
+			if strings.Contains(text, m) {
+				return "network"
+			}
+		}
+		for _, m := range timeoutMessages {
+			if strings.Contains(text, m) {
+				return "timeout"
+			}
+		}
+	}
+	return ""
+}
+
+// callTool runs the tool name of the server with the arguments argsJSON,
+// retrying it as the tools policy says.
+func (c *conversation) callTool(ctx context.Context, name, argsJSON string) (result string, images []toolImage, err error) {
+	err = c.retry.Tools.do(ctx, "Tool "+name, func(ctx context.Context) error {
+		var err error
+		result, images, err = executeMCPTool(ctx, c.session, name, argsJSON, c.vision)
+		return err
+	})
+	return result, images, err
+}
+
+// A duration is a time.Duration written as a string in JSON, such as "2s".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("want a duration such as \"2s\", got %s", data)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
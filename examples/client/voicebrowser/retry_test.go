@@ -0,0 +1,160 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"rate limit status", &openai.APIError{HTTPStatusCode: 429}, "rate_limit"},
+		{"rate limit code", &openai.APIError{Code: "rate_limit_exceeded", HTTPStatusCode: 400}, "rate_limit"},
+		{"insufficient quota code", &openai.APIError{Code: "insufficient_quota", HTTPStatusCode: 429}, ""},
+		{"insufficient quota type", &openai.APIError{Type: "insufficient_quota", HTTPStatusCode: 429}, ""},
+		{"server error", &openai.APIError{HTTPStatusCode: 503}, "5xx"},
+		{"bad request", &openai.APIError{HTTPStatusCode: 400}, ""},
+		{"unauthorized", &openai.APIError{HTTPStatusCode: 401}, ""},
+		{"request error 502", &openai.RequestError{HTTPStatusCode: 502, Err: errors.New("bad gateway")}, "5xx"},
+		{"request error 404", &openai.RequestError{HTTPStatusCode: 404, Err: errors.New("not found")}, ""},
+		{"wrapped API error", fmt.Errorf("chat: %w", &openai.APIError{HTTPStatusCode: 500}), "5xx"},
+		{"connection closed", mcp.ErrConnectionClosed, ""},
+		{"wrapped connection closed", fmt.Errorf("calling click: %w", mcp.ErrConnectionClosed), ""},
+		{"deadline", context.DeadlineExceeded, "timeout"},
+		{"net timeout", &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, "timeout"},
+		{"net error", &net.OpError{Op: "dial", Err: errors.New("no route to host")}, "network"},
+		{"unexpected EOF", io.ErrUnexpectedEOF, "network"},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), "network"},
+		{"connection refused", syscall.ECONNREFUSED, "network"},
+		{"broken pipe", syscall.EPIPE, "network"},
+		{"tool network error", &toolError{"Error clicking: websocket: close 1006 (abnormal closure)"}, "network"},
+		{"tool timeout", &toolError{"Error navigating: context deadline exceeded"}, "timeout"},
+		{"tool failure", &toolError{"Error clicking: element not found"}, ""},
+		{"canceled", context.Canceled, ""},
+		{"plain error", errors.New("invalid arguments"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorClass(tt.err); got != tt.want {
+				t.Errorf("errorClass(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := retryPolicy{
+		InitialBackoff: duration(time.Second),
+		MaxBackoff:     duration(10 * time.Second),
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+	for attempt, full := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 5: 10 * time.Second, 50: 10 * time.Second} {
+		for range 100 {
+			// Jitter takes up to half of the backoff off, never more
+			if d := p.backoff(attempt); d > full || d < full/2 {
+				t.Fatalf("backoff(%d) = %v, want from %v to %v", attempt, d, full/2, full)
+			}
+		}
+	}
+
+	p.Jitter = 0
+	if d := p.backoff(3); d != 4*time.Second {
+		t.Errorf("backoff(3) without jitter = %v, want 4s", d)
+	}
+}
+
+func TestRetryDo(t *testing.T) {
+	ctx := context.Background()
+	p := retryPolicy{MaxAttempts: 3, Multiplier: 1, RetryOn: []string{"network"}}
+
+	// Each call returns the next of errs
+	calls := func(errs ...error) (func(context.Context) error, *int) {
+		n := 0
+		return func(context.Context) error {
+			err := errs[n]
+			n++
+			return err
+		}, &n
+	}
+
+	fn, n := calls(io.ErrUnexpectedEOF, io.ErrUnexpectedEOF, nil)
+	if err := p.do(ctx, "Test", fn); err != nil || *n != 3 {
+		t.Errorf("after two network errors: got %v in %d calls, want success in 3", err, *n)
+	}
+
+	fn, n = calls(io.ErrUnexpectedEOF, io.ErrUnexpectedEOF, io.ErrUnexpectedEOF, nil)
+	if err := p.do(ctx, "Test", fn); !errors.Is(err, io.ErrUnexpectedEOF) || *n != 3 {
+		t.Errorf("after max_attempts: got %v in %d calls, want the network error in 3", err, *n)
+	}
+
+	// Timeouts are not in RetryOn: a tool that timed out may have acted
+	fn, n = calls(context.DeadlineExceeded, nil)
+	if err := p.do(ctx, "Test", fn); !errors.Is(err, context.DeadlineExceeded) || *n != 1 {
+		t.Errorf("after a timeout: got %v in %d calls, want it in 1", err, *n)
+	}
+
+	fn, n = calls(mcp.ErrConnectionClosed, nil)
+	if err := p.do(ctx, "Test", fn); !errors.Is(err, mcp.ErrConnectionClosed) || *n != 1 {
+		t.Errorf("with the connection closed: got %v in %d calls, want it in 1", err, *n)
+	}
+
+	quota := &openai.APIError{Code: "insufficient_quota", HTTPStatusCode: 429}
+	llm := defaultRetry().LLM
+	fn, n = calls(quota, nil)
+	if err := llm.do(ctx, "Test", fn); !errors.Is(err, quota) || *n != 1 {
+		t.Errorf("with the quota exhausted: got %v in %d calls, want it in 1", err, *n)
+	}
+
+	// Each attempt gets its own deadline
+	p.AttemptTimeout = duration(10 * time.Millisecond)
+	p.RetryOn = []string{"timeout"}
+	attempts := 0
+	err := p.do(ctx, "Test", func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("after two attempt timeouts: got %v in %d calls, want success in 3", err, attempts)
+	}
+
+	// A cancelled run is not retried
+	p.RetryOn = retryClasses
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	fn, n = calls(io.ErrUnexpectedEOF, nil)
+	if err := p.do(cctx, "Test", fn); err == nil || *n != 1 {
+		t.Errorf("with the run cancelled: got %v in %d calls, want an error in 1", err, *n)
+	}
+}
+
+func TestDefaultRetry(t *testing.T) {
+	c := defaultRetry()
+	if err := c.check(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Tools.RetryOn) != 1 || c.Tools.RetryOn[0] != "network" {
+		t.Errorf("tools retry on %v, want only network errors", c.Tools.RetryOn)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Each run is recorded in a directory of its own under -run-dir, named by
+// its run ID:
+//
+//	transcript.jsonl  one runEvent per line: the messages, tool calls,
+//	                  and tool results of the run, in order
+//	report.md         the same, for people, written when the run ends
+//	NNN-tool.png      the images tools returned, such as screenshots
+
+// A runEvent is a line of transcript.jsonl.
+type runEvent struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"` // user, assistant, tool_call, tool_result, image, error, or end
+	Turn      int       `json:"turn,omitempty"`
+	Iteration int       `json:"iteration,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	Tool      string    `json:"tool,omitempty"`
+	CallID    string    `json:"call_id,omitempty"`
+	Arguments string    `json:"arguments,omitempty"`
+	IsError   bool      `json:"is_error,omitempty"`
+	File      string    `json:"file,omitempty"` // of an image, relative to the run directory
+}
+
+// reportResultChars is how much of a tool result the Markdown report
+// shows; the transcript has all of it.
+const reportResultChars = 2000
+
+// A runLog records a run. A nil *runLog records nothing.
+type runLog struct {
+	id         string
+	dir        string
+	transcript *os.File
+	report     strings.Builder
+	turn       int
+	images     int
+}
+
+// newRunLog creates the directory of a new run under root.
+func newRunLog(root string) (*runLog, error) {
+	id := time.Now().Format("20060102-150405")
+	dir := filepath.Join(root, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create run directory: %v", err)
+	}
+	f, err := os.Create(filepath.Join(dir, "transcript.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transcript: %v", err)
+	}
+	l := &runLog{id: id, dir: dir, transcript: f}
+	fmt.Fprintf(&l.report, "# voicebrowser run %s\n\n", id)
+	return l, nil
+}
+
+// reopenRunLog continues recording the run id under root, which is being
+// resumed after turn turns.
+func reopenRunLog(root, id string, turn int) (*runLog, error) {
+	dir := filepath.Join(root, id)
+	f, err := os.OpenFile(filepath.Join(dir, "transcript.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %v", err)
+	}
+	l := &runLog{id: id, dir: dir, transcript: f, turn: turn}
+	// A run that crashed has no report yet
+	if report, err := os.ReadFile(filepath.Join(dir, "report.md")); err == nil {
+		l.report.Write(report)
+	} else {
+		fmt.Fprintf(&l.report, "# voicebrowser run %s\n\n", id)
+	}
+	fmt.Fprintf(&l.report, "---\n\n_Resumed at %s._\n\n", time.Now().Format(time.DateTime))
+	// Number new images after the saved ones
+	saved, _ := filepath.Glob(filepath.Join(dir, "[0-9][0-9][0-9]-*"))
+	l.images = len(saved)
+	return l, nil
+}
+
+// record appends e to the transcript.
+func (l *runLog) record(e runEvent) {
+	e.Time = time.Now()
+	if e.Turn == 0 {
+		e.Turn = l.turn
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	// Tool results and typed text may hold credentials
+	if _, err := io.WriteString(l.transcript, redact(string(data))+"\n"); err != nil {
+		fmt.Printf("Warning: failed to write the run transcript: %v\n", err)
+	}
+}
+
+// userMessage records an instruction of the user, which starts a turn.
+func (l *runLog) userMessage(text string) {
+	if l == nil {
+		return
+	}
+	l.turn++
+	l.record(runEvent{Type: "user", Content: text})
+	fmt.Fprintf(&l.report, "## Turn %d\n\n**User:** %s\n\n", l.turn, text)
+}
+
+// assistantMessage records a message of the model in an iteration, with
+// the number of tool calls it asks for. The report shows a message without
+// tool calls as the reply of the turn.
+func (l *runLog) assistantMessage(iteration int, text string, calls int) {
+	if l == nil {
+		return
+	}
+	l.record(runEvent{Type: "assistant", Iteration: iteration, Content: text})
+	if calls == 0 {
+		return
+	}
+	fmt.Fprintf(&l.report, "### Iteration %d\n\n", iteration)
+	if text != "" {
+		fmt.Fprintf(&l.report, "**Assistant:** %s\n\n", text)
+	}
+}
+
+// toolCall records a tool call and its result.
+func (l *runLog) toolCall(iteration int, id, tool, arguments, result string, failed bool) {
+	if l == nil {
+		return
+	}
+	l.record(runEvent{Type: "tool_call", Iteration: iteration, Tool: tool, CallID: id, Arguments: arguments})
+	l.record(runEvent{Type: "tool_result", Iteration: iteration, Tool: tool, CallID: id, Content: result, IsError: failed})
+	shown := result
+	if len(shown) > reportResultChars {
+		shown = strings.ToValidUTF8(shown[:reportResultChars], "") + fmt.Sprintf("\n... (%d more characters in transcript.jsonl)", len(result)-reportResultChars)
+	}
+	status := ""
+	if failed {
+		status = " ❌"
+	}
+	fmt.Fprintf(&l.report, "- `%s`%s `%s`\n\n  ```\n%s\n  ```\n\n", tool, status, arguments, indent(shown, "  "))
+}
+
+// image saves an image a tool returned in the run directory.
+func (l *runLog) image(iteration int, img toolImage) {
+	if l == nil {
+		return
+	}
+	l.images++
+	ext := ".png"
+	if _, sub, ok := strings.Cut(img.image.MIMEType, "/"); ok && sub != "" {
+		ext = "." + strings.TrimSuffix(sub, "+xml")
+	}
+	name := fmt.Sprintf("%03d-%s%s", l.images, img.tool, ext)
+	if err := os.WriteFile(filepath.Join(l.dir, name), img.image.Data, 0644); err != nil {
+		fmt.Printf("Warning: failed to save image: %v\n", err)
+		return
+	}
+	l.record(runEvent{Type: "image", Iteration: iteration, Tool: img.tool, File: name})
+	fmt.Fprintf(&l.report, "  ![%s](%s)\n\n", img.tool, name)
+}
+
+// reply records the model's answer that ends a turn, and the error that
+// ended it, if any.
+func (l *runLog) reply(text string, err error) {
+	if l == nil {
+		return
+	}
+	if text != "" {
+		fmt.Fprintf(&l.report, "**Reply:** %s\n\n", text)
+	}
+	if err != nil {
+		l.record(runEvent{Type: "error", Content: err.Error()})
+		fmt.Fprintf(&l.report, "**Ended with an error:** %v\n\n", err)
+	}
+}
+
+// close ends the run with its usage summary and writes the report.
+func (l *runLog) close(summary string) {
+	if l == nil {
+		return
+	}
+	l.record(runEvent{Type: "end", Content: summary})
+	l.transcript.Close()
+	fmt.Fprintf(&l.report, "---\n\nOpenAI usage: %s\n", summary)
+	if err := os.WriteFile(filepath.Join(l.dir, "report.md"), []byte(redact(l.report.String())), 0644); err != nil {
+		fmt.Printf("Warning: failed to write the run report: %v\n", err)
+		return
+	}
+	fmt.Printf("Run %s recorded in %s\n", l.id, l.dir)
+}
+
+// indent starts every line of s with prefix, to keep them in a Markdown
+// list item.
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
@@ -0,0 +1,395 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Every flag can be set in three places, which take precedence in this
+// order: the command line, the environment, and the "flags" object of the
+// -config file. The environment variable of a flag is VOICEBROWSER_ and its
+// name in upper case with - as _, such as VOICEBROWSER_MAX_TOOL_RESULT for
+// -max-tool-result.
+//
+// Before any of that, a .env file fills in the environment variables that
+// are not set already: the -env file, or else the first .env or
+// .vscode/voicebrowser.env found in the current directory or its parents,
+// up to the root of the repository.
+
+// envPrefix starts the environment variables of the flags.
+const envPrefix = "VOICEBROWSER_"
+
+// envName returns the environment variable of the flag name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Where a flag got its value.
+const (
+	fromCommandLine = "command line"
+	fromEnvironment = "environment"
+	fromConfig      = "config file"
+)
+
+// configure loads the .env file and the config file of fs, gives the flags
+// not set on the command line their values from the environment or the
+// config file, and remembers the secrets in the environment for redact. It
+// returns the config and where each flag that was set got its value.
+func configure(fs *flag.FlagSet) (*config, map[string]string, error) {
+	sources := map[string]string{}
+	fs.Visit(func(f *flag.Flag) { sources[f.Name] = fromCommandLine })
+	fromEnv := func(name string) error {
+		if sources[name] != "" {
+			return nil
+		}
+		v, ok := os.LookupEnv(envName(name))
+		if !ok {
+			return nil
+		}
+		if err := fs.Set(name, v); err != nil {
+			return fmt.Errorf("%s: %v", envName(name), err)
+		}
+		sources[name] = fromEnvironment
+		return nil
+	}
+
+	// The .env file may name the config file, but neither can name itself
+	if err := fromEnv("env"); err != nil {
+		return nil, nil, err
+	}
+	envFile := fs.Lookup("env").Value.String()
+	if envFile == "" {
+		envFile = findDotEnv()
+	}
+	if envFile != "" {
+		loaded, kept, err := loadDotEnv(envFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		fmt.Printf("Loaded environment from %s: %s\n", envFile, strings.Join(loaded, ", "))
+		if len(kept) > 0 {
+			fmt.Printf("\tkept from the environment: %s\n", strings.Join(kept, ", "))
+		}
+	}
+	collectSecrets()
+	if err := fromEnv("config"); err != nil {
+		return nil, nil, err
+	}
+	configFile := fs.Lookup("config").Value.String()
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for name := range cfg.Flags {
+		if fs.Lookup(name) == nil || name == "env" || name == "config" {
+			return nil, nil, fmt.Errorf("%s: flags: there is no flag %q to set", configFile, name)
+		}
+	}
+	var ferr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if ferr != nil || sources[f.Name] != "" {
+			return
+		}
+		if ferr = fromEnv(f.Name); ferr != nil || sources[f.Name] != "" {
+			return
+		}
+		raw, ok := cfg.Flags[f.Name]
+		if !ok {
+			return
+		}
+		// Numbers and booleans may be written as JSON, not as strings
+		var v string
+		if json.Unmarshal(raw, &v) != nil {
+			v = string(raw)
+		}
+		if err := fs.Set(f.Name, v); err != nil {
+			ferr = fmt.Errorf("%s: flags: %s: %v", configFile, f.Name, err)
+			return
+		}
+		sources[f.Name] = fromConfig
+	})
+	if ferr != nil {
+		return nil, nil, ferr
+	}
+	return cfg, sources, nil
+}
+
+// printSettings prints the flags of fs that were set, with their values
+// and where they came from.
+func printSettings(fs *flag.FlagSet, sources map[string]string) {
+	var names []string
+	for name, from := range sources {
+		if from != fromCommandLine {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	fmt.Println("Settings from the environment and config file:")
+	for _, name := range names {
+		fmt.Printf("\t-%s=%s (%s)\n", name, redact(fs.Lookup(name).Value.String()), sources[name])
+	}
+}
+
+// findDotEnv returns the first .env file in the current directory or its
+// parents, up to the root of the repository, or "" if there is none.
+func findDotEnv() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		for _, name := range []string{".env", filepath.Join(".vscode", "voicebrowser.env")} {
+			file := filepath.Join(dir, name)
+			if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+				return file
+			}
+		}
+		// Files outside the repository belong to other projects
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadDotEnv sets the environment variables of the .env file at path that
+// are not set already. It returns the names of those it set and of those
+// it kept.
+func loadDotEnv(path string) (loaded, kept []string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read env file: %v", err)
+	}
+	vars, err := parseDotEnv(string(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, kv := range vars {
+		if _, ok := os.LookupEnv(kv[0]); ok {
+			kept = append(kept, kv[0])
+			continue
+		}
+		if err := os.Setenv(kv[0], kv[1]); err != nil {
+			return nil, nil, fmt.Errorf("%s: %s: %v", path, kv[0], err)
+		}
+		loaded = append(loaded, kv[0])
+	}
+	return loaded, kept, nil
+}
+
+// envKey matches the names of environment variables.
+var envKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// dotEnvEscapes are the escapes of double-quoted .env values.
+var dotEnvEscapes = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+
+// parseDotEnv parses the text of a .env file into name and value pairs, in
+// order. Each line is KEY=value, optionally after export; blank lines and #
+// comments are ignored. A value in single quotes is taken as is; one in
+// double quotes may span lines and have the escapes \n, \r, \t, \", and \\;
+// an unquoted value ends at " #". Errors give line numbers but never the
+// lines, which may hold secrets.
+func parseDotEnv(text string) ([][2]string, error) {
+	var vars [][2]string
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		n := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envKey.MatchString(key) {
+			return nil, fmt.Errorf("line %d: want KEY=value", n)
+		}
+		value = strings.TrimSpace(value)
+
+		var rest string
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: the value of %s has no closing '", n, key)
+			}
+			value, rest = value[1:end+1], value[end+2:]
+		case strings.HasPrefix(value, `"`):
+			raw := value[1:]
+			for {
+				if end := closingQuote(raw); end >= 0 {
+					raw, rest = raw[:end], raw[end+1:]
+					break
+				}
+				if i+1 == len(lines) {
+					return nil, fmt.Errorf("line %d: the value of %s has no closing \"", n, key)
+				}
+				i++
+				raw += "\n" + lines[i]
+			}
+			value = dotEnvEscapes.Replace(raw)
+		default:
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+		if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+			return nil, fmt.Errorf("line %d: unexpected text after the quoted value of %s", n, key)
+		}
+		vars = append(vars, [2]string{key, value})
+	}
+	return vars, nil
+}
+
+// closingQuote returns the index of the first unescaped " in s, or -1.
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// providerKeys lists the environment variables each provider of the OpenAI
+// API needs. "compatible" is any other server with the same API, such as
+// Ollama or vLLM, which may not need a key.
+var providerKeys = map[string][]string{
+	"openai":     {"OPENAI_API_KEY"},
+	"azure":      {"AZURE_OPENAI_API_KEY", "AZURE_OPENAI_ENDPOINT"},
+	"compatible": {"OPENAI_BASE_URL"},
+}
+
+// newOpenAIClient checks the environment variables of provider and returns
+// a client for it. OPENAI_BASE_URL and OPENAI_ORG_ID are optional for
+// openai, and AZURE_OPENAI_API_VERSION for azure, where -model names the
+// deployment.
+func newOpenAIClient(provider string) (*openai.Client, error) {
+	keys, ok := providerKeys[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (use openai, azure, or compatible)", provider)
+	}
+	var missing []string
+	for _, key := range keys {
+		if os.Getenv(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("provider %s needs %s in the environment or a .env file", provider, strings.Join(missing, " and "))
+	}
+
+	var cc openai.ClientConfig
+	if provider == "azure" {
+		cc = openai.DefaultAzureConfig(os.Getenv("AZURE_OPENAI_API_KEY"), os.Getenv("AZURE_OPENAI_ENDPOINT"))
+		if v := os.Getenv("AZURE_OPENAI_API_VERSION"); v != "" {
+			cc.APIVersion = v
+		}
+	} else {
+		cc = openai.DefaultConfig(os.Getenv("OPENAI_API_KEY"))
+		if v := os.Getenv("OPENAI_BASE_URL"); v != "" {
+			cc.BaseURL = strings.TrimSuffix(v, "/")
+		}
+		cc.OrgID = os.Getenv("OPENAI_ORG_ID")
+	}
+	if u, err := url.Parse(cc.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("provider %s: %q is not an http or https URL", provider, cc.BaseURL)
+	}
+	return openai.NewClientWithConfig(cc), nil
+}
+
+// describeProvider returns provider and the settings it uses, showing only
+// the last characters of keys, for the startup banner.
+func describeProvider(provider string) string {
+	keys := providerKeys[provider]
+	if provider == "openai" {
+		keys = append(keys, "OPENAI_BASE_URL")
+	}
+	var parts []string
+	for _, key := range keys {
+		if os.Getenv(key) == "" {
+			continue
+		}
+		v := os.Getenv(key)
+		if secretName.MatchString(key) && len(v) >= 12 {
+			v = "..." + v[len(v)-4:]
+		} else if secretName.MatchString(key) {
+			v = "***"
+		}
+		parts = append(parts, key+"="+v)
+	}
+	return fmt.Sprintf("%s (%s)", provider, strings.Join(parts, ", "))
+}
+
+// A secret is an environment variable that holds a credential.
+type secret struct {
+	name, value string
+}
+
+// secrets are the credentials in the environment, longest value first,
+// which redact hides.
+var secrets []secret
+
+// secretName matches the names of environment variables that hold
+// credentials.
+var secretName = regexp.MustCompile(`(?i)(KEY|TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL)`)
+
+// collectSecrets remembers the credentials in the environment. Values
+// shorter than 8 characters are left alone, since hiding them would hide
+// ordinary text too.
+func collectSecrets() {
+	secrets = nil
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if secretName.MatchString(name) && len(value) >= 8 {
+			secrets = append(secrets, secret{name, value})
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i].value) > len(secrets[j].value) })
+}
+
+// redact replaces the credentials in s with their names, such as
+// [OPENAI_API_KEY], so that logs and run records never hold them.
+func redact(s string) string {
+	for _, sec := range secrets {
+		s = strings.ReplaceAll(s, sec.value, "["+sec.name+"]")
+	}
+	return s
+}
+
+// A redactingWriter redacts what it writes to w, for the log.
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// A speaker reads text aloud, so the user can follow the agent without
+// watching the terminal.
+type speaker interface {
+	speak(ctx context.Context, text string) error
+}
+
+// narrator speaks the agent's replies and its requests for help, or is nil
+// if -tts is off.
+var narrator speaker
+
+// say speaks text with the narrator, if there is one. A failure is only
+// reported, since the text is on the terminal too.
+func say(ctx context.Context, text string) {
+	if narrator == nil || strings.TrimSpace(text) == "" {
+		return
+	}
+	// Markdown emphasis and code marks are not read aloud
+	text = strings.NewReplacer("**", "", "__", "", "`", "", "#", "").Replace(text)
+	if err := narrator.speak(ctx, text); err != nil && ctx.Err() == nil {
+		fmt.Printf("Warning: text-to-speech failed: %v\n", err)
+	}
+}
+
+// newSpeaker returns the speaker of engine: "openai" for OpenAI TTS with
+// voice, played by the command template playCmd, or "system" for the
+// command template sayCmd. An empty command picks the default for the
+// platform.
+func newSpeaker(engine, voice, playCmd, sayCmd string, client *openai.Client) (speaker, error) {
+	switch engine {
+	case "openai":
+		if playCmd == "" {
+			playCmd = defaultPlayCmd()
+		}
+		if !strings.Contains(playCmd, "{file}") {
+			return nil, fmt.Errorf("command %q has no {file}", playCmd)
+		}
+		if _, err := exec.LookPath(strings.Fields(playCmd)[0]); err != nil {
+			return nil, fmt.Errorf("cannot play audio: %v", err)
+		}
+		return &openaiSpeaker{client: client, voice: openai.SpeechVoice(voice), playCmd: playCmd}, nil
+	case "system":
+		if sayCmd == "" {
+			sayCmd = defaultSayCmd()
+		}
+		if _, err := exec.LookPath(strings.Fields(sayCmd)[0]); err != nil {
+			return nil, fmt.Errorf("cannot speak: %v", err)
+		}
+		return &systemSpeaker{cmd: sayCmd}, nil
+	}
+	return nil, fmt.Errorf("unknown text-to-speech engine %q (use off, openai, or system)", engine)
+}
+
+// defaultPlayCmd returns the command that plays an audio file on this
+// platform.
+func defaultPlayCmd() string {
+	if runtime.GOOS == "darwin" {
+		return "afplay {file}"
+	}
+	return "play -q {file}"
+}
+
+// defaultSayCmd returns the system speech command of this platform.
+func defaultSayCmd() string {
+	switch {
+	case runtime.GOOS == "darwin":
+		return "say"
+	case runtime.GOOS == "windows":
+		return `powershell -NoProfile -Command Add-Type -AssemblyName System.Speech; (New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak($input)`
+	}
+	if _, err := exec.LookPath("espeak-ng"); err == nil {
+		return "espeak-ng"
+	}
+	return "espeak"
+}
+
+// A systemSpeaker speaks with a command such as say or espeak. The text
+// replaces {text} in the command template, or is the standard input of a
+// PowerShell command that reads $input, or else is the last argument.
+type systemSpeaker struct {
+	cmd string
+}
+
+func (s *systemSpeaker) speak(ctx context.Context, text string) error {
+	args := strings.Fields(s.cmd)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if strings.Contains(s.cmd, "$input") {
+		// PowerShell reads the text from its input
+		cmd.Stdin = strings.NewReader(text)
+	} else if strings.Contains(s.cmd, "{text}") {
+		for i, arg := range cmd.Args {
+			cmd.Args[i] = strings.ReplaceAll(arg, "{text}", text)
+		}
+	} else {
+		cmd.Args = append(cmd.Args, text)
+	}
+	cmd.SysProcAttr = detachedProcAttr()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// An openaiSpeaker speaks with OpenAI text-to-speech, playing the audio
+// with a command.
+type openaiSpeaker struct {
+	client  *openai.Client
+	voice   openai.SpeechVoice
+	playCmd string
+}
+
+func (s *openaiSpeaker) speak(ctx context.Context, text string) error {
+	audio, err := s.client.CreateSpeech(ctx, openai.CreateSpeechRequest{
+		Model:          openai.TTSModel1,
+		Input:          text,
+		Voice:          s.voice,
+		ResponseFormat: openai.SpeechResponseFormatWav,
+	})
+	if err != nil {
+		return err
+	}
+	defer audio.Close()
+
+	dir, err := os.MkdirTemp("", "voicebrowser-tts-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "speech.wav")
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, audio)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save speech: %v", err)
+	}
+
+	play := command(ctx, s.playCmd, file)
+	play.SysProcAttr = detachedProcAttr()
+	if out, err := play.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
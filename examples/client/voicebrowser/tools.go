@@ -0,0 +1,125 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// A toolConfig narrows and rewords the server's tools before the model sees
+// them, to constrain what it can do and steer it toward the right tools:
+//
+//	"tools": {
+//	  "include": ["navigate", "click*", "type_text", "aria_snapshot"],
+//	  "exclude": ["close_browser"],
+//	  "aliases": {
+//	    "aria_snapshot": {"name": "look_at_page", "description": "See the controls of the page before acting."}
+//	  }
+//	}
+//
+// Include and exclude take glob patterns. Without include, the model gets
+// the usual browser tools.
+type toolConfig struct {
+	Include []string             `json:"include"`
+	Exclude []string             `json:"exclude"`
+	Aliases map[string]toolAlias `json:"aliases"`
+}
+
+// A toolAlias is how a tool is presented to the model. Empty fields keep
+// the tool's own.
+type toolAlias struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// validToolName matches the function names OpenAI accepts.
+var validToolName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// check reports an error in the patterns or aliases of tc.
+func (tc *toolConfig) check() error {
+	for _, pattern := range append(tc.Include, tc.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad tool pattern %q", pattern)
+		}
+	}
+	for tool, alias := range tc.Aliases {
+		if alias.Name != "" && !validToolName.MatchString(alias.Name) {
+			return fmt.Errorf("alias %q of %s is not a valid tool name (letters, digits, _ and -, at most 64)", alias.Name, tool)
+		}
+	}
+	return nil
+}
+
+// matchAny reports whether name matches one of patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// selectTools returns the tools to offer the model: those of all that
+// Include matches, or else defaults, less those Exclude matches.
+func (tc *toolConfig) selectTools(all, defaults []*mcp.Tool) []*mcp.Tool {
+	tools := defaults
+	if len(tc.Include) > 0 {
+		tools = nil
+		for _, t := range all {
+			if matchAny(tc.Include, t.Name) {
+				tools = append(tools, t)
+			}
+		}
+	}
+	var selected []*mcp.Tool
+	for _, t := range tools {
+		if !matchAny(tc.Exclude, t.Name) {
+			selected = append(selected, t)
+		}
+	}
+	return selected
+}
+
+// applyAliases renames and redescribes the tools of c as aliases say, and
+// remembers the server name of each alias.
+func (c *conversation) applyAliases(aliases map[string]toolAlias) error {
+	c.serverNames = make(map[string]string)
+	offered := make(map[string]bool)
+	for _, t := range c.tools {
+		offered[t.Function.Name] = true
+	}
+	for i := range c.tools {
+		f := c.tools[i].Function
+		alias, ok := aliases[f.Name]
+		if !ok {
+			continue
+		}
+		if alias.Description != "" {
+			f.Description = alias.Description
+		}
+		if alias.Name != "" && alias.Name != f.Name {
+			if offered[alias.Name] {
+				return fmt.Errorf("alias %q of %s is the name of another tool", alias.Name, f.Name)
+			}
+			offered[alias.Name] = true
+			c.serverNames[alias.Name] = f.Name
+			f.Name = alias.Name
+		}
+	}
+	return nil
+}
+
+// serverName returns the server's name of the tool the model calls name.
+func (c *conversation) serverName(name string) string {
+	if s, ok := c.serverNames[name]; ok {
+		return s
+	}
+	return name
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Each run is one trace. main starts a "voicebrowser journey" span, every
+// tool call gets a child "tools/call <tool>" span, and the call carries that
+// span's W3C trace context in its _meta, so the cdpbrowser server (and the
+// chromedp spans it creates) join the trace.
+//
+// Spans are exported over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set, like the server's.
+
+// tracer creates the client's spans once setupTracing has installed the
+// provider.
+var tracer = otel.Tracer("voicebrowser")
+
+// propagator writes trace context into tool call _meta.
+var propagator = propagation.TraceContext{}
+
+// setupTracing installs an SDK tracer provider, exporting to OTLP/HTTP if
+// the standard endpoint variables are set. The returned function flushes
+// and stops the provider.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "voicebrowser"))),
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	return provider.Shutdown, nil
+}
+
+// startJourney starts the root span of the run.
+func startJourney(ctx context.Context) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "voicebrowser journey")
+}
+
+// startToolSpan starts the client span for a call to the named tool and
+// returns the _meta that continues it on the server.
+func startToolSpan(ctx context.Context, tool string) (context.Context, trace.Span, mcp.Meta) {
+	ctx, span := tracer.Start(ctx, "tools/call "+tool,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("mcp.tool", tool)))
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	meta := mcp.Meta{}
+	for k, v := range carrier {
+		meta[k] = v
+	}
+	return ctx, span, meta
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// A modelPrice is what a model costs, in US dollars per million tokens.
+type modelPrice struct {
+	prefix           string // of the model names it applies to
+	input, output    float64
+	cachedInputShare float64 // of the input price that cached tokens cost
+}
+
+// modelPrices are OpenAI's list prices for chat models, most specific
+// first. They are estimates: check the pricing page for current ones.
+var modelPrices = []modelPrice{
+	{"gpt-4o-mini", 0.15, 0.60, 0.5},
+	{"gpt-4o", 2.50, 10.00, 0.5},
+	{"gpt-4.1-nano", 0.10, 0.40, 0.25},
+	{"gpt-4.1-mini", 0.40, 1.60, 0.25},
+	{"gpt-4.1", 2.00, 8.00, 0.25},
+	{"gpt-4-turbo", 10.00, 30.00, 1},
+	{"o4-mini", 1.10, 4.40, 0.25},
+	{"o3-mini", 1.10, 4.40, 0.5},
+	{"o3", 2.00, 8.00, 0.25},
+}
+
+// priceOf returns the price of model, or false if it is not known.
+func priceOf(model string) (modelPrice, bool) {
+	for _, p := range modelPrices {
+		if strings.HasPrefix(model, p.prefix) {
+			return p, true
+		}
+	}
+	return modelPrice{}, false
+}
+
+// estimateCost returns the estimated cost in US dollars of u with model,
+// and false if the model's price is not known.
+func estimateCost(model string, u openai.Usage) (float64, bool) {
+	p, ok := priceOf(model)
+	if !ok {
+		return 0, false
+	}
+	cached := 0
+	if u.PromptTokensDetails != nil {
+		cached = u.PromptTokensDetails.CachedTokens
+	}
+	input := float64(u.PromptTokens-cached)*p.input + float64(cached)*p.input*p.cachedInputShare
+	return (input + float64(u.CompletionTokens)*p.output) / 1e6, true
+}
+
+// A usageMeter adds up the tokens and the estimated cost of the chat
+// requests of a run.
+type usageMeter struct {
+	requests         int
+	promptTokens     int
+	completionTokens int
+	cost             float64
+	unpriced         []string // models whose cost is not known
+}
+
+// add records the usage of a request to model, prints it with the totals
+// so far, and returns the cost of the request.
+func (m *usageMeter) add(model string, u openai.Usage) float64 {
+	m.requests++
+	m.promptTokens += u.PromptTokens
+	m.completionTokens += u.CompletionTokens
+	cost, ok := estimateCost(model, u)
+	if !ok && !slices.Contains(m.unpriced, model) {
+		m.unpriced = append(m.unpriced, model)
+	}
+	m.cost += cost
+	fmt.Printf("📊 Tokens: %d prompt + %d completion (~$%.4f); run total %d tokens (~$%.4f)\n",
+		u.PromptTokens, u.CompletionTokens, cost, m.tokens(), m.cost)
+	return cost
+}
+
+// tokens returns the tokens used so far.
+func (m *usageMeter) tokens() int {
+	return m.promptTokens + m.completionTokens
+}
+
+// summary describes the usage of the run.
+func (m *usageMeter) summary() string {
+	s := fmt.Sprintf("%d requests, %d prompt + %d completion = %d tokens, estimated cost $%.4f",
+		m.requests, m.promptTokens, m.completionTokens, m.tokens(), m.cost)
+	if len(m.unpriced) > 0 {
+		s += fmt.Sprintf(" (not counting %s, whose price is unknown)", strings.Join(m.unpriced, ", "))
+	}
+	return s
+}
+
+// A budget caps the tokens or the estimated cost of a run. Zero means no
+// limit.
+type budget struct {
+	maxCost   float64
+	maxTokens int
+}
+
+// errBudgetExceeded ends a run that used up its budget.
+var errBudgetExceeded = errors.New("budget exceeded")
+
+// parseBudget parses -budget: a cost in dollars such as "$2.50", a number
+// of tokens such as "200000" or "200k", or both, separated by a comma.
+func parseBudget(s string) (budget, error) {
+	var b budget
+	if s == "" {
+		return b, nil
+	}
+	for _, limit := range strings.Split(s, ",") {
+		limit = strings.ToLower(strings.TrimSpace(limit))
+		if cost, ok := strings.CutPrefix(limit, "$"); ok {
+			v, err := strconv.ParseFloat(cost, 64)
+			if err != nil || v <= 0 {
+				return b, fmt.Errorf("invalid cost limit %q", limit)
+			}
+			b.maxCost = v
+			continue
+		}
+		scale := 1
+		if n, ok := strings.CutSuffix(limit, "k"); ok {
+			limit, scale = n, 1000
+		} else if n, ok := strings.CutSuffix(limit, "m"); ok {
+			limit, scale = n, 1000000
+		}
+		v, err := strconv.ParseFloat(limit, 64)
+		if err != nil || v <= 0 {
+			return b, fmt.Errorf("invalid token limit %q (use e.g. 200000, 200k, or $2.50)", limit)
+		}
+		b.maxTokens = int(v * float64(scale))
+	}
+	return b, nil
+}
+
+// check returns an error wrapping errBudgetExceeded if m has reached a
+// limit of b.
+func (b budget) check(m *usageMeter) error {
+	switch {
+	case b.maxCost > 0 && m.cost >= b.maxCost:
+		return fmt.Errorf("%w: estimated cost $%.4f reached the limit of $%.2f", errBudgetExceeded, m.cost, b.maxCost)
+	case b.maxTokens > 0 && m.tokens() >= b.maxTokens:
+		return fmt.Errorf("%w: %d tokens reached the limit of %d", errBudgetExceeded, m.tokens(), b.maxTokens)
+	}
+	return nil
+}
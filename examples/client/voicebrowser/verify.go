@@ -0,0 +1,184 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// With -verify, each action that should change the page, such as a click or
+// typing, is checked against the page: an ARIA snapshot of the interactive
+// elements is saved before it, and aria_diff compares the page with it
+// after. A heuristic, or with -verify model a cheap verifier model, decides
+// whether the page changed as the action meant, and the verdict is added to
+// the tool result. A click that silently did nothing is then caught at
+// once, not ten iterations later.
+//
+// The diff becomes the server's previous snapshot, so an aria_diff of the
+// model compares with the page as the last verified action left it.
+
+// verifyBaseline names the snapshot taken before an action.
+const verifyBaseline = "voicebrowser-verify"
+
+// verifiedTools are the tools whose effects are verified.
+var verifiedTools = []string{
+	"click", "click_button", "click_link", "type_text", "select_dropdown", "choose_option",
+	"click_element_by_id", "type_into_element_by_id",
+}
+
+// How much of a tool result and of a diff the verifier model sees.
+const (
+	verifyResultChars = 2000
+	verifyDiffChars   = 4000
+)
+
+// A verifier checks the effects of actions. A nil *verifier checks
+// nothing.
+type verifier struct {
+	mode  string // "heuristic" or "model"
+	model string // the verifier model, for mode "model"
+}
+
+// newVerifier returns the verifier of mode, or nil if mode is "off".
+func newVerifier(mode, model string) (*verifier, error) {
+	switch mode {
+	case "off":
+		return nil, nil
+	case "heuristic":
+		return &verifier{mode: mode}, nil
+	case "model":
+		if model == "" {
+			return nil, fmt.Errorf("-verify model needs -verify-model")
+		}
+		return &verifier{mode: mode, model: model}, nil
+	}
+	return nil, fmt.Errorf("unknown verification %q (use off, heuristic, or model)", mode)
+}
+
+// baseline saves the page before the server's tool runs, if c verifies
+// the tool, and reports whether it did.
+func (c *conversation) baseline(ctx context.Context, tool string) bool {
+	if c.verifier == nil || !slices.Contains(verifiedTools, tool) {
+		return false
+	}
+	args := fmt.Sprintf(`{"focus":"interactive","format":"compact","max_elements":1,"save_as":%q}`, verifyBaseline)
+	_, _, err := executeMCPTool(ctx, c.session, "aria_snapshot", args, false)
+	return err == nil
+}
+
+// verify checks that the tool call with argsJSON, which the model made
+// meaning intent and which returned result, changed the page as it should
+// have, and returns the verdict to add to the result.
+func (c *conversation) verify(ctx context.Context, intent, tool, argsJSON, result string) string {
+	diff, _, err := executeMCPTool(ctx, c.session, "aria_diff", fmt.Sprintf(`{"against":%q}`, verifyBaseline), false)
+	if err != nil {
+		return ""
+	}
+
+	var ok bool
+	var why string
+	if c.verifier.mode == "model" {
+		if ok, why, err = c.askVerifier(ctx, intent, tool, argsJSON, result, diff); err != nil {
+			fmt.Printf("Warning: verification failed: %v\n", err)
+			return ""
+		}
+	} else {
+		ok, why = checkEffects(tool, argsJSON, result, diff)
+	}
+	if ok {
+		fmt.Printf("🔎 Verified %s: %s\n", tool, why)
+		return "\n\nVerified: " + why
+	}
+	fmt.Printf("⚠️  %s may not have worked: %s\n", tool, why)
+	return fmt.Sprintf("\n\nVerification warning: %s may not have worked: %s. Check the page before going on.\n%s",
+		tool, why, truncateMiddle(diff, verifyDiffChars))
+}
+
+// checkEffects decides by heuristic whether the tool call with argsJSON
+// worked, from its result and the ARIA diff of the page, and says why.
+func checkEffects(tool, argsJSON, result, diff string) (bool, string) {
+	navigated := strings.Contains(result, "URL changed") || strings.Contains(result, "Navigated to")
+	changed := !strings.Contains(diff, "No changes.")
+	if strings.HasPrefix(tool, "type_") {
+		var args struct {
+			Text     string `json:"text"`
+			Selector string `json:"selector"`
+		}
+		json.Unmarshal([]byte(argsJSON), &args)
+		// Password fields do not show what was typed
+		text := args.Text
+		if len(text) > 20 {
+			text = strings.ToValidUTF8(text[:20], "")
+		}
+		if text == "" || navigated || strings.Contains(strings.ToLower(args.Selector), "password") ||
+			strings.Contains(diff, text) || strings.Contains(result, text) {
+			return true, "the text shows on the page"
+		}
+		return false, fmt.Sprintf("no field shows the text %q", text)
+	}
+	switch {
+	case navigated:
+		return true, "the page navigated"
+	case changed:
+		return true, "the page changed"
+	case strings.Contains(result, "DOM: "):
+		return true, "the document changed"
+	}
+	return false, "nothing on the page changed"
+}
+
+// askVerifier has the verifier model judge whether the tool call worked.
+func (c *conversation) askVerifier(ctx context.Context, intent, tool, argsJSON, result, diff string) (bool, string, error) {
+	if intent == "" {
+		intent = "(not stated)"
+	}
+	req := openai.ChatCompletionRequest{
+		Model: c.verifier.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role: openai.ChatMessageRoleSystem,
+				Content: "You check the actions of a browser automation agent. Given what the agent meant to do, " +
+					"the tool call it made, the tool's result, and how the page's interactive elements changed, " +
+					"decide whether the action had the effect it should have had. Typing should change a field's value; " +
+					"a click should open, close, submit, select, or navigate. Answer with JSON only: " +
+					`{"ok": true or false, "reason": "one short sentence"}.`,
+			},
+			{
+				Role: openai.ChatMessageRoleUser,
+				Content: fmt.Sprintf("Intent: %s\n\nTool call: %s %s\n\nResult:\n%s\n\nChanges on the page:\n%s",
+					intent, tool, argsJSON, truncateMiddle(result, verifyResultChars), truncateMiddle(diff, verifyDiffChars)),
+			},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+		Temperature:    0,
+	}
+	var resp openai.ChatCompletionResponse
+	err := c.retry.LLM.do(ctx, "Verifier request", func(ctx context.Context) error {
+		var err error
+		resp, err = c.client.CreateChatCompletion(ctx, req)
+		return err
+	})
+	if err != nil {
+		return false, "", err
+	}
+	c.usage.add(c.verifier.model, resp.Usage)
+	if len(resp.Choices) == 0 {
+		return false, "", fmt.Errorf("no verdict")
+	}
+	var verdict struct {
+		OK     bool   `json:"ok"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &verdict); err != nil {
+		return false, "", fmt.Errorf("bad verdict: %v", err)
+	}
+	return verdict.OK, verdict.Reason, nil
+}
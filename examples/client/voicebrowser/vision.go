@@ -0,0 +1,47 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// A toolImage is an image a tool returned, such as a screenshot.
+type toolImage struct {
+	tool  string
+	image *mcp.ImageContent
+}
+
+// imageMessage returns a user message that shows images to a vision-capable
+// model. Tool results can only be text, so the images the tool calls of an
+// iteration return follow their results in a message of their own.
+func imageMessage(images []toolImage) openai.ChatCompletionMessage {
+	parts := []openai.ChatMessagePart{{
+		Type: openai.ChatMessagePartTypeText,
+		Text: "Images returned by the tool calls above, in order. Look at them to check the page before the next action.",
+	}}
+	for i, img := range images {
+		parts = append(parts,
+			openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: fmt.Sprintf("Image %d, from %s:", i+1, img.tool),
+			},
+			openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{
+					URL:    "data:" + img.image.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(img.image.Data),
+					Detail: openai.ImageURLDetailAuto,
+				},
+			})
+	}
+	return openai.ChatCompletionMessage{
+		Role:         openai.ChatMessageRoleUser,
+		MultiContent: parts,
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2025 The Go MCP SDK Authors. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Voice input records what the user says with an external recorder, by
+// default SoX, and transcribes it with Whisper, either the OpenAI API or a
+// local command such as whisper.cpp's whisper-cli. Commands are given as
+// templates in which {file} stands for the WAV file.
+//
+// In push-to-talk mode, the user presses Enter to start and again to stop
+// recording, or types a message instead. In continuous mode, the recorder
+// listens for speech and stops after a pause, and each utterance is a turn.
+
+const (
+	// defaultRecordCmd records 16 kHz mono audio from the default input
+	// device until it is interrupted.
+	defaultRecordCmd = "sox -q -d -c 1 -r 16000 -b 16 {file}"
+	// silenceEffect makes SoX wait for speech and stop after 1.5 seconds
+	// of silence, for continuous mode.
+	silenceEffect = " silence 1 0.1 2% 1 1.5 2%"
+	// defaultWhisperCmd transcribes with whisper.cpp, printing only the
+	// text.
+	defaultWhisperCmd = "whisper-cli -nt -np -f {file}"
+)
+
+// A voiceInput turns what the user says into chat messages.
+type voiceInput struct {
+	mode       string // "push" or "continuous"
+	recordCmd  string // template of the recorder command
+	stt        string // "api" or "local"
+	whisperCmd string // template of the local transcriber command
+	client     *openai.Client
+	dir        string // where recordings are kept until transcribed
+}
+
+// newVoiceInput checks the voice options and returns the input for mode.
+// An empty recordCmd or whisperCmd picks the default for the mode.
+func newVoiceInput(mode, recordCmd, stt, whisperCmd string, client *openai.Client) (*voiceInput, error) {
+	if mode != "push" && mode != "continuous" {
+		return nil, fmt.Errorf("unknown voice mode %q (use off, push, or continuous)", mode)
+	}
+	if stt != "api" && stt != "local" {
+		return nil, fmt.Errorf("unknown speech-to-text engine %q (use api or local)", stt)
+	}
+	if recordCmd == "" {
+		recordCmd = defaultRecordCmd
+		if mode == "continuous" {
+			recordCmd += silenceEffect
+		}
+	}
+	if whisperCmd == "" {
+		whisperCmd = defaultWhisperCmd
+	}
+	for _, tmpl := range []string{recordCmd, whisperCmd} {
+		if !strings.Contains(tmpl, "{file}") {
+			return nil, fmt.Errorf("command %q has no {file}", tmpl)
+		}
+	}
+	if _, err := exec.LookPath(strings.Fields(recordCmd)[0]); err != nil {
+		return nil, fmt.Errorf("cannot record audio: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "voicebrowser-")
+	if err != nil {
+		return nil, err
+	}
+	return &voiceInput{mode: mode, recordCmd: recordCmd, stt: stt, whisperCmd: whisperCmd, client: client, dir: dir}, nil
+}
+
+// close removes the recordings.
+func (v *voiceInput) close() {
+	os.RemoveAll(v.dir)
+}
+
+// command returns the command of the template tmpl for file.
+func command(ctx context.Context, tmpl, file string) *exec.Cmd {
+	args := strings.Fields(tmpl)
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, "{file}", file)
+	}
+	return exec.CommandContext(ctx, args[0], args[1:]...)
+}
+
+// listen returns the next message of the user: what they say, or in
+// push-to-talk mode, what they type.
+func (v *voiceInput) listen(ctx context.Context) (string, error) {
+	file := filepath.Join(v.dir, "utterance.wav")
+	os.Remove(file)
+	rec := command(ctx, v.recordCmd, file)
+	// Ctrl-C is for the chat, not the recorder
+	rec.SysProcAttr = detachedProcAttr()
+	var stderr bytes.Buffer
+	rec.Stderr = &stderr
+
+	if v.mode == "push" {
+		fmt.Print("\nyou (Enter to talk, or type)> ")
+		line, err := readLine()
+		if err != nil || strings.TrimSpace(line) != "" {
+			return line, err
+		}
+		if err := rec.Start(); err != nil {
+			return "", fmt.Errorf("failed to start recording: %v", err)
+		}
+		fmt.Print("🎙️  Recording... press Enter to stop ")
+		readLine()
+		// SoX and most recorders finish the file when interrupted
+		rec.Process.Signal(os.Interrupt)
+		rec.Wait()
+	} else {
+		fmt.Print("\n🎙️  Listening... ")
+		if err := rec.Run(); err != nil && ctx.Err() == nil {
+			return "", fmt.Errorf("recording failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if info, err := os.Stat(file); err != nil || info.Size() == 0 {
+		return "", fmt.Errorf("nothing was recorded: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	text, err := v.transcribe(ctx, file)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("\nyou (voice)> %s\n", text)
+	return text, nil
+}
+
+// transcribe returns the text spoken in file.
+func (v *voiceInput) transcribe(ctx context.Context, file string) (string, error) {
+	if v.stt == "api" {
+		resp, err := v.client.CreateTranscription(ctx, openai.AudioRequest{
+			Model:    openai.Whisper1,
+			FilePath: file,
+		})
+		if err != nil {
+			return "", fmt.Errorf("transcription failed: %v", err)
+		}
+		return strings.TrimSpace(resp.Text), nil
+	}
+	var stderr bytes.Buffer
+	cmd := command(ctx, v.whisperCmd, file)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("transcription failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.Join(strings.Fields(string(out)), " "), nil
+}
+
+// spokenCommand returns text as a chat command, such as "exit", if it is
+// one, ignoring case and the punctuation transcription adds.
+func spokenCommand(text string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(text), ".!?, "))
+}
@@ -0,0 +1,211 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/chromedp"
+)
+
+// defaultActionTimeout bounds how long an interaction waits for its element
+// to become actionable when the server was not given -action-timeout.
+const defaultActionTimeout = 10 * time.Second
+
+// actionRetryDelays are the pauses between actionability checks. The last
+// delay repeats until the timeout expires.
+var actionRetryDelays = []time.Duration{0, 20 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond, 500 * time.Millisecond}
+
+// detachedReason is the reason an element that has been removed from the
+// document is not actionable.
+const detachedReason = "element is detached from the document"
+
+// noElementReason is the reason reported while no element matches the
+// selector.
+const noElementReason = "no element matches"
+
+// actionabilityError is returned when an element did not become actionable
+// in time.
+type actionabilityError struct {
+	timeout time.Duration
+	// reason is why the element was not actionable at the last check.
+	reason string
+}
+
+func (e *actionabilityError) Error() string {
+	return fmt.Sprintf("not actionable after %v: %s", e.timeout, e.reason)
+}
+
+// actionChecks selects the actionability checks an interaction needs on top
+// of the element being attached, visible, enabled and stable.
+type actionChecks struct {
+	// pointer requires the element to be the target of a pointer event at
+	// its center, which fails when another element covers it.
+	pointer bool
+	// editable requires the element to accept typed text.
+	editable bool
+	// attachedOnly skips all checks but the element being attached, for
+	// actions that handle hidden or disabled elements themselves.
+	attachedOnly bool
+}
+
+// actionabilityJS is called on an element with the checks to perform, and
+// resolves to "" if the element is actionable or to the reason it is not.
+// The element is stable if its bounding box is the same over two animation
+// frames; the timer bounds the wait in pages that do not render frames.
+const actionabilityJS = `
+async function(pointer, editable) {
+	const el = this;
+	if (!(el instanceof Element)) {
+		return 'node is not an element';
+	}
+	if (!el.isConnected) {
+		return 'element is detached from the document';
+	}
+	const describe = (e) => e.tagName.toLowerCase() + (e.id ? '#' + e.id : '') +
+		(typeof e.className === 'string' && e.className.trim() ? '.' + e.className.trim().split(/\s+/).join('.') : '');
+	const style = getComputedStyle(el);
+	const rect = el.getBoundingClientRect();
+	if (style.visibility !== 'visible' || rect.width === 0 || rect.height === 0) {
+		return 'element is not visible';
+	}
+	const form = el instanceof HTMLButtonElement || el instanceof HTMLInputElement ||
+		el instanceof HTMLSelectElement || el instanceof HTMLTextAreaElement || el instanceof HTMLOptionElement;
+	if ((form && el.matches(':disabled')) || el.closest('[aria-disabled="true"]')) {
+		return 'element is disabled';
+	}
+	if (editable) {
+		if (el.readOnly) {
+			return 'element is read-only';
+		}
+		const text = (el instanceof HTMLInputElement && !['button', 'checkbox', 'radio', 'submit', 'reset', 'image', 'file', 'color', 'range', 'hidden'].includes(el.type)) ||
+			el instanceof HTMLTextAreaElement || el.isContentEditable;
+		if (!text) {
+			return 'element is not an editable field';
+		}
+	}
+	await new Promise(resolve => {
+		requestAnimationFrame(() => requestAnimationFrame(resolve));
+		setTimeout(resolve, 100);
+	});
+	const after = el.getBoundingClientRect();
+	if (after.x !== rect.x || after.y !== rect.y || after.width !== rect.width || after.height !== rect.height) {
+		return 'element is not stable; it is moving or animating';
+	}
+	if (pointer) {
+		const x = after.left + after.width / 2, y = after.top + after.height / 2;
+		const root = el.getRootNode();
+		const hit = (root.elementFromPoint ? root : document).elementFromPoint(x, y);
+		if (!hit) {
+			return 'element is outside the viewport';
+		}
+		if (hit !== el && !el.contains(hit)) {
+			return 'element is covered by ' + describe(hit);
+		}
+	}
+	return '';
+}
+`
+
+// checkActionable scrolls the element with backend node ID b into view and
+// returns "" if it is actionable, or the reason it is not. Scrolling fails
+// for elements without a layout box; the script reports those as not visible.
+func checkActionable(ctx context.Context, b cdp.BackendNodeID, checks actionChecks) (string, error) {
+	dom.ScrollIntoViewIfNeeded().WithBackendNodeID(b).Do(ctx)
+	var reason string
+	fn := fmt.Sprintf("function() { return (%s).call(this, %t, %t); }", actionabilityJS, checks.pointer, checks.editable)
+	if err := callOnElement(ctx, b, fn, &reason); err != nil {
+		return "", err
+	}
+	return reason, nil
+}
+
+// pollActionable calls check until it reports no reason, an error occurs, or
+// timeout expires. The error after a timeout is an *actionabilityError
+// naming the last reason.
+func pollActionable(ctx context.Context, timeout time.Duration, check func(context.Context) (string, error)) error {
+	deadline := time.Now().Add(timeout)
+	for i := 0; ; i++ {
+		delay := actionRetryDelays[min(i, len(actionRetryDelays)-1)]
+		if time.Now().Add(delay).After(deadline) {
+			delay = time.Until(deadline)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		reason, err := check(ctx)
+		if err != nil {
+			return err
+		}
+		if reason == "" {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return &actionabilityError{timeout: timeout, reason: reason}
+		}
+	}
+}
+
+// actionTimeoutOrDefault returns the time interactions wait for their
+// element to become actionable.
+func (s *CDPBrowserServer) actionTimeoutOrDefault() time.Duration {
+	if s.actionTimeout > 0 {
+		return s.actionTimeout
+	}
+	return defaultActionTimeout
+}
+
+// waitActionable returns an action that waits until the first element
+// matching loc is attached, visible, enabled, stable, and passes checks.
+// Like Playwright's actionability checks, every condition is retried, so an
+// element that is still rendering, animating, or behind a closing overlay
+// does not fail the interaction.
+func (s *CDPBrowserServer) waitActionable(loc SelectorLocator, checks actionChecks) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, err := s.resolveActionable(ctx, loc, checks)
+		return err
+	})
+}
+
+// resolveActionable waits like waitActionable and returns the backend node
+// ID of the element once it is actionable.
+func (s *CDPBrowserServer) resolveActionable(ctx context.Context, loc SelectorLocator, checks actionChecks) (cdp.BackendNodeID, error) {
+	var b cdp.BackendNodeID
+	err := pollActionable(ctx, s.actionTimeoutOrDefault(), func(ctx context.Context) (string, error) {
+		var nodes []*cdp.Node
+		if err := chromedp.Nodes(loc.Query, &nodes, loc.by(), chromedp.AtLeast(0)).Do(ctx); err != nil {
+			return "", err
+		}
+		if len(nodes) == 0 {
+			return noElementReason, nil
+		}
+		b = nodes[0].BackendNodeID
+		if checks.attachedOnly {
+			return "", nil
+		}
+		return checkActionable(ctx, b, checks)
+	})
+	return b, err
+}
+
+// waitElementActionable is waitActionable for the element with backend node
+// ID b, as used by the *_by_id tools. An element that has left the document
+// cannot come back, so that is reported without waiting.
+func (s *CDPBrowserServer) waitElementActionable(b cdp.BackendNodeID, checks actionChecks) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if checks.attachedOnly {
+			return nil
+		}
+		return pollActionable(ctx, s.actionTimeoutOrDefault(), func(ctx context.Context) (string, error) {
+			reason, err := checkActionable(ctx, b, checks)
+			if reason == detachedReason {
+				return "", notFoundError{fmt.Errorf("element no longer exists")}
+			}
+			return reason, err
+		})
+	})
+}
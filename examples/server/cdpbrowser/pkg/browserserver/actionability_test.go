@@ -0,0 +1,38 @@
+package browserserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPollActionable(t *testing.T) {
+	reasons := []string{"no element matches", "element is disabled", ""}
+	calls := 0
+	err := pollActionable(t.Context(), time.Second, func(context.Context) (string, error) {
+		calls++
+		return reasons[calls-1], nil
+	})
+	if err != nil || calls != len(reasons) {
+		t.Errorf("pollActionable() = %v after %d checks, want success after %d", err, calls, len(reasons))
+	}
+
+	err = pollActionable(t.Context(), 50*time.Millisecond, func(context.Context) (string, error) {
+		return "element is covered by div#overlay", nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "not actionable after 50ms: element is covered by div#overlay") {
+		t.Errorf("pollActionable() of a covered element = %v", err)
+	}
+
+	gone := errors.New("element no longer exists")
+	calls = 0
+	err = pollActionable(t.Context(), time.Second, func(context.Context) (string, error) {
+		calls++
+		return "", gone
+	})
+	if !errors.Is(err, gone) || calls != 1 {
+		t.Errorf("pollActionable() = %v after %d checks, want %v after 1", err, calls, gone)
+	}
+}
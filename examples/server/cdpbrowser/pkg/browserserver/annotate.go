@@ -0,0 +1,132 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// annotationOverlayID is the id of the element that holds the numbered boxes
+// drawn by annotated_screenshot.
+const annotationOverlayID = "__cdpbrowser_marks"
+
+// annotateJS is a function that draws a numbered box over every interactive
+// element in the viewport and returns the marks it drew. It is called with
+// the stable element IDs of the elements listed by
+// collectInteractiveElements, which label the boxes.
+const annotateJS = `
+function(ids) {
+const old = document.getElementById('` + annotationOverlayID + `');
+if (old) old.remove();
+const overlay = document.createElement('div');
+overlay.id = '` + annotationOverlayID + `';
+overlay.style.cssText = 'position:fixed;left:0;top:0;width:100%;height:100%;pointer-events:none;z-index:2147483647;';
+const colors = ['#e6194b', '#3cb44b', '#4363d8', '#f58231', '#911eb4', '#008080', '#9a6324', '#800000'];
+const marks = [];
+(window.__cdpbrowserElements || []).forEach((el, i) => {
+	const rect = el.getBoundingClientRect();
+	if (!ids[i] || rect.width === 0 || rect.height === 0 ||
+		rect.bottom < 0 || rect.right < 0 ||
+		rect.top > window.innerHeight || rect.left > window.innerWidth) {
+		return;
+	}
+	const color = colors[ids[i] % colors.length];
+	const box = document.createElement('div');
+	box.style.cssText = 'position:fixed;box-sizing:border-box;border:2px solid ' + color + ';' +
+		'left:' + rect.left + 'px;top:' + rect.top + 'px;width:' + rect.width + 'px;height:' + rect.height + 'px;';
+	const label = document.createElement('span');
+	label.textContent = String(ids[i]);
+	label.style.cssText = 'position:absolute;left:-2px;top:-2px;transform:translateY(-100%);' +
+		'background:' + color + ';color:#fff;font:bold 12px/14px monospace;padding:0 3px;';
+	box.appendChild(label);
+	overlay.appendChild(box);
+	marks.push({
+		id: ids[i],
+		selector: getSelector(el),
+		name: getAccessibleName(el),
+		tag: el.tagName.toLowerCase(),
+		x: Math.round(rect.left),
+		y: Math.round(rect.top),
+		width: Math.round(rect.width),
+		height: Math.round(rect.height)
+	});
+});
+document.documentElement.appendChild(overlay);
+return marks;
+}
+`
+
+// ElementMark is a numbered box drawn by annotated_screenshot.
+type ElementMark struct {
+	ID       int    `json:"id" jsonschema:"Element id drawn on the screenshot, as used by aria_snapshot and the *_by_id tools"`
+	Selector string `json:"selector"`
+	Name     string `json:"name,omitempty"`
+	Tag      string `json:"tag"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+// AnnotatedScreenshotResult is the structured result of annotated_screenshot.
+type AnnotatedScreenshotResult struct {
+	Marks []ElementMark `json:"marks"`
+}
+
+// AnnotatedScreenshot tool - captures the viewport with numbered boxes over
+// interactive elements ("set-of-marks") and returns the id → selector map
+func (s *CDPBrowserServer) AnnotatedScreenshot(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[AnnotatedScreenshotResult], error) {
+	var marks []ElementMark
+	var buf []byte
+	collectJS := "(function() {" + ariaHelpersJS + "return collectInteractiveElements().length; })()"
+	err := s.run(ctx, chromedp.Evaluate(collectJS, nil))
+	var ids []int
+	if err == nil {
+		ids, err = s.interactiveElementIDs(ctx)
+	}
+	if err == nil {
+		idsJSON, _ := json.Marshal(ids)
+		drawJS := fmt.Sprintf("(function() {%s return (%s)(%s); })()", ariaHelpersJS, annotateJS, idsJSON)
+		err = s.run(ctx,
+			chromedp.Evaluate(drawJS, &marks),
+			chromedp.CaptureScreenshot(&buf),
+		)
+	}
+	// Always remove the overlay so it cannot intercept later interactions or
+	// show up in plain screenshots.
+	removeJS := `document.getElementById('` + annotationOverlayID + `')?.remove()`
+	if rmErr := s.run(ctx, chromedp.Evaluate(removeJS, nil)); rmErr != nil {
+		log.Printf("AnnotatedScreenshot: failed to remove overlay: %v", rmErr)
+	}
+	if err != nil {
+		return &mcp.CallToolResultFor[AnnotatedScreenshotResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error taking annotated screenshot: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%d marked elements (id: selector):\n", len(marks)))
+	for _, m := range marks {
+		name := m.Name
+		if name == "" {
+			name = fmt.Sprintf("<%s>", m.Tag)
+		}
+		output.WriteString(fmt.Sprintf("%d: %s %q\n", m.ID, m.Selector, name))
+	}
+
+	return &mcp.CallToolResultFor[AnnotatedScreenshotResult]{
+		Content: []mcp.Content{
+			&mcp.ImageContent{Data: buf, MIMEType: "image/png"},
+			&mcp.TextContent{Text: output.String()},
+		},
+		StructuredContent: AnnotatedScreenshotResult{Marks: marks},
+	}, nil
+}
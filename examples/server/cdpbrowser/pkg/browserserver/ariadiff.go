@@ -0,0 +1,263 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// savedSnapshot is an ARIA snapshot kept for comparison by aria_diff.
+type savedSnapshot struct {
+	focus    string
+	region   string
+	snapshot *ARIASnapshotResult
+}
+
+// snapshotStore holds the most recent ARIA snapshot and any snapshots saved
+// under a name.
+type snapshotStore struct {
+	mu    sync.Mutex
+	last  *savedSnapshot
+	named map[string]*savedSnapshot
+}
+
+// save makes snapshot the most recent one and, if name is set, also saves it
+// under that name.
+func (st *snapshotStore) save(name, focus, region string, snapshot *ARIASnapshotResult) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	saved := &savedSnapshot{focus: focus, region: region, snapshot: snapshot}
+	st.last = saved
+	if name != "" {
+		if st.named == nil {
+			st.named = make(map[string]*savedSnapshot)
+		}
+		st.named[name] = saved
+	}
+}
+
+// get returns the snapshot saved under name, or the most recent snapshot if
+// name is empty.
+func (st *snapshotStore) get(name string) (*savedSnapshot, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if name == "" {
+		if st.last == nil {
+			return nil, fmt.Errorf("no previous snapshot; call aria_snapshot first")
+		}
+		return st.last, nil
+	}
+	saved, ok := st.named[name]
+	if !ok {
+		return nil, fmt.Errorf("no snapshot saved as %q", name)
+	}
+	return saved, nil
+}
+
+// ARIADiffEntry is an element that was added, removed, or changed between two
+// ARIA snapshots.
+type ARIADiffEntry struct {
+	Section string      `json:"section" jsonschema:"Snapshot section: landmarks, interactive, headings, or content"`
+	Element ARIAElement `json:"element" jsonschema:"The element as it is now, or as it was if it was removed"`
+	Changes []string    `json:"changes,omitempty" jsonschema:"For changed elements, the fields that changed with their old and new values"`
+}
+
+// ARIADiffResult is the structured result of aria_diff.
+type ARIADiffResult struct {
+	Against     string          `json:"against" jsonschema:"The snapshot the page was compared with"`
+	PageChanges []string        `json:"page_changes,omitempty"`
+	Added       []ARIADiffEntry `json:"added"`
+	Removed     []ARIADiffEntry `json:"removed"`
+	Changed     []ARIADiffEntry `json:"changed"`
+}
+
+// empty reports whether the diff found no differences.
+func (d *ARIADiffResult) empty() bool {
+	return len(d.PageChanges) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// diffKey identifies an element across snapshots. Interactive elements are
+// matched by their stable ID; everything else by selector, which is the best
+// identity the page-side script provides.
+func diffKey(e ARIAElement) string {
+	if e.ID != 0 {
+		return fmt.Sprintf("id:%d", e.ID)
+	}
+	return "selector:" + e.Selector
+}
+
+// keyedElements returns elements keyed by diffKey, in order. Repeated keys
+// are disambiguated by their occurrence so that duplicates are matched
+// pairwise.
+func keyedElements(elements []ARIAElement) ([]string, map[string]ARIAElement) {
+	keys := make([]string, 0, len(elements))
+	byKey := make(map[string]ARIAElement, len(elements))
+	seen := make(map[string]int)
+	for _, e := range elements {
+		key := diffKey(e)
+		seen[key]++
+		if n := seen[key]; n > 1 {
+			key = fmt.Sprintf("%s#%d", key, n)
+		}
+		keys = append(keys, key)
+		byKey[key] = e
+	}
+	return keys, byKey
+}
+
+// fieldChanges describes the fields that differ between two versions of an
+// element.
+func fieldChanges(before, after ARIAElement) []string {
+	var changes []string
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, fmt.Sprintf("%s %q → %q", field, old, new))
+		}
+	}
+	add("role", before.Role, after.Role)
+	add("name", before.Name, after.Name)
+	add("selector", before.Selector, after.Selector)
+	add("ariaLabel", before.AriaLabel, after.AriaLabel)
+	add("tag", before.Tag, after.Tag)
+	add("href", before.Href, after.Href)
+	add("value", before.Value, after.Value)
+	add("text", before.Text, after.Text)
+	if before.Level != after.Level {
+		changes = append(changes, fmt.Sprintf("level %d → %d", before.Level, after.Level))
+	}
+	return changes
+}
+
+// diffSnapshots compares two ARIA snapshots section by section.
+func diffSnapshots(before, after *ARIASnapshotResult) ARIADiffResult {
+	diff := ARIADiffResult{Added: []ARIADiffEntry{}, Removed: []ARIADiffEntry{}, Changed: []ARIADiffEntry{}}
+	if before.Page.Title != after.Page.Title {
+		diff.PageChanges = append(diff.PageChanges, fmt.Sprintf("title %q → %q", before.Page.Title, after.Page.Title))
+	}
+	if before.Page.URL != after.Page.URL {
+		diff.PageChanges = append(diff.PageChanges, fmt.Sprintf("url %q → %q", before.Page.URL, after.Page.URL))
+	}
+
+	for _, section := range []struct {
+		name          string
+		before, after []ARIAElement
+	}{
+		{"landmarks", before.Landmarks, after.Landmarks},
+		{"interactive", before.Interactive, after.Interactive},
+		{"headings", before.Headings, after.Headings},
+		{"content", before.Content, after.Content},
+	} {
+		beforeKeys, beforeByKey := keyedElements(section.before)
+		afterKeys, afterByKey := keyedElements(section.after)
+		for _, key := range beforeKeys {
+			if _, ok := afterByKey[key]; !ok {
+				diff.Removed = append(diff.Removed, ARIADiffEntry{Section: section.name, Element: beforeByKey[key]})
+			}
+		}
+		for _, key := range afterKeys {
+			e := afterByKey[key]
+			old, ok := beforeByKey[key]
+			if !ok {
+				diff.Added = append(diff.Added, ARIADiffEntry{Section: section.name, Element: e})
+			} else if changes := fieldChanges(old, e); len(changes) > 0 {
+				diff.Changed = append(diff.Changed, ARIADiffEntry{Section: section.name, Element: e, Changes: changes})
+			}
+		}
+	}
+	return diff
+}
+
+// describeDiffElement renders e on one line, in the style of the compact
+// snapshot format.
+func describeDiffElement(section string, e ARIAElement) string {
+	switch section {
+	case "headings":
+		return fmt.Sprintf("h%d %s", e.Level, e.Text)
+	case "interactive":
+		return fmt.Sprintf("%s%s %q %s", idPrefix(e), e.Role, elementName(e), e.Selector)
+	default:
+		return fmt.Sprintf("%s %q %s", e.Role, elementName(e), e.Selector)
+	}
+}
+
+// String renders the diff as text, listing only what changed.
+func (d *ARIADiffResult) String() string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("DIFF against %s\n", d.Against))
+	if d.empty() {
+		output.WriteString("No changes.\n")
+		return output.String()
+	}
+	for _, c := range d.PageChanges {
+		output.WriteString(fmt.Sprintf("PAGE: %s\n", c))
+	}
+	for _, group := range []struct {
+		title   string
+		mark    string
+		entries []ARIADiffEntry
+	}{
+		{"ADDED", "+", d.Added},
+		{"REMOVED", "-", d.Removed},
+		{"CHANGED", "~", d.Changed},
+	} {
+		if len(group.entries) == 0 {
+			continue
+		}
+		output.WriteString(fmt.Sprintf("%s (%d):\n", group.title, len(group.entries)))
+		for _, entry := range group.entries {
+			output.WriteString(fmt.Sprintf("  %s [%s] %s\n", group.mark, entry.Section, describeDiffElement(entry.Section, entry.Element)))
+			for _, c := range entry.Changes {
+				output.WriteString(fmt.Sprintf("      %s\n", c))
+			}
+		}
+	}
+	return output.String()
+}
+
+type ARIADiffArgs struct {
+	Against string `json:"against,omitempty" jsonschema:"Name of a snapshot saved with save_as to compare with (default: the previous snapshot)"`
+	SaveAs  string `json:"save_as,omitempty" jsonschema:"Optional name to save the new snapshot under"`
+}
+
+// ARIADiff tool - compares the page with an earlier ARIA snapshot and returns
+// only what changed
+func (s *CDPBrowserServer) ARIADiff(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ARIADiffArgs]]) (*mcp.CallToolResultFor[ARIADiffResult], error) {
+	args := req.Params.Arguments
+	base, err := s.snapshots.get(args.Against)
+	if err != nil {
+		return &mcp.CallToolResultFor[ARIADiffResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error comparing ARIA snapshots: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	// Take the new snapshot with the same focus and region so that elements
+	// the base snapshot did not capture are not reported as added.
+	current, err := s.takeARIASnapshot(ctx, base.focus, base.region)
+	if err != nil {
+		return &mcp.CallToolResultFor[ARIADiffResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error extracting ARIA snapshot: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	s.snapshots.save(args.SaveAs, base.focus, base.region, current)
+
+	diff := diffSnapshots(base.snapshot, current)
+	diff.Against = "previous snapshot"
+	if args.Against != "" {
+		diff.Against = fmt.Sprintf("snapshot %q", args.Against)
+	}
+	return &mcp.CallToolResultFor[ARIADiffResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: diff.String()},
+		},
+		StructuredContent: diff,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package browserserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	before := &ARIASnapshotResult{
+		Page: ARIAPageInfo{Title: "Form", URL: "http://example.test/form"},
+		Interactive: []ARIAElement{
+			{ID: 1, Role: "textbox", Name: "Name", Selector: "#name", Tag: "input"},
+			{ID: 2, Role: "button", Name: "Submit", Selector: "#submit", Tag: "button"},
+		},
+		Headings: []ARIAElement{{Level: 1, Text: "Sign up", Selector: "h1", Tag: "h1"}},
+	}
+	after := &ARIASnapshotResult{
+		Page: ARIAPageInfo{Title: "Form", URL: "http://example.test/form"},
+		Interactive: []ARIAElement{
+			{ID: 1, Role: "textbox", Name: "Name", Selector: "#name", Tag: "input", Value: "bob"},
+			{ID: 3, Role: "link", Name: "Continue", Selector: "#next", Tag: "a"},
+		},
+		Headings: []ARIAElement{{Level: 1, Text: "Sign up", Selector: "h1", Tag: "h1"}},
+	}
+
+	diff := diffSnapshots(before, after)
+	if len(diff.PageChanges) != 0 {
+		t.Errorf("PageChanges = %v, want none", diff.PageChanges)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Element.ID != 3 {
+		t.Errorf("Added = %+v, want element 3", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Element.ID != 2 {
+		t.Errorf("Removed = %+v, want element 2", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Element.ID != 1 {
+		t.Fatalf("Changed = %+v, want element 1", diff.Changed)
+	}
+	if got, want := diff.Changed[0].Changes, []string{`value "" → "bob"`}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("Changes = %q, want %q", got, want)
+	}
+
+	diff.Against = "previous snapshot"
+	text := diff.String()
+	for _, want := range []string{"ADDED (1):", `+ [interactive] #3 link "Continue" #next`, "REMOVED (1):", "CHANGED (1):", `value "" → "bob"`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("diff text missing %q:\n%s", want, text)
+		}
+	}
+
+	if diff := diffSnapshots(before, before); !diff.empty() {
+		t.Errorf("diff of identical snapshots = %+v, want empty", diff)
+	}
+}
+
+func TestSnapshotStore(t *testing.T) {
+	var st snapshotStore
+	if _, err := st.get(""); err == nil {
+		t.Error("get(\"\") on an empty store succeeded, want error")
+	}
+	first := &ARIASnapshotResult{Page: ARIAPageInfo{Title: "first"}}
+	second := &ARIASnapshotResult{Page: ARIAPageInfo{Title: "second"}}
+	st.save("login", "interactive", "", first)
+	st.save("", "region", "#main", second)
+
+	if saved, err := st.get(""); err != nil || saved.snapshot != second || saved.focus != "region" || saved.region != "#main" {
+		t.Errorf("get(\"\") = %+v, %v, want the second snapshot", saved, err)
+	}
+	if saved, err := st.get("login"); err != nil || saved.snapshot != first || saved.focus != "interactive" {
+		t.Errorf("get(\"login\") = %+v, %v, want the first snapshot", saved, err)
+	}
+	if _, err := st.get("missing"); err == nil {
+		t.Error("get(\"missing\") succeeded, want error")
+	}
+}
@@ -0,0 +1,80 @@
+package browserserver
+
+// ariaHelpersJS defines the page-side helpers annotated_screenshot uses to
+// find, name, and select the interactive elements it marks. The marks are
+// labelled with the same backend-node-keyed element IDs as aria_snapshot.
+const ariaHelpersJS = `
+// Helper function to get accessible name
+function getAccessibleName(element) {
+	return element.getAttribute('aria-label') || 
+		   element.getAttribute('aria-labelledby') && document.getElementById(element.getAttribute('aria-labelledby'))?.textContent ||
+		   element.textContent?.trim().substring(0, 100) ||
+		   element.getAttribute('title') ||
+		   element.getAttribute('placeholder') ||
+		   element.getAttribute('alt') ||
+		   '';
+}
+
+// Helper function to generate CSS selector with aria-label priority
+function getSelector(element) {
+	// Priority 1: aria-label (most specific and semantic)
+	const ariaLabel = element.getAttribute('aria-label');
+	if (ariaLabel) {
+		return '[aria-label="' + ariaLabel.replace(/"/g, '\\"') + '"]';
+	}
+	
+	// Priority 2: ID selector
+	if (element.id) return '#' + element.id;
+	
+	// Priority 3: href for links (semantic)
+	if (element.tagName === 'A' && element.getAttribute('href')) {
+		return 'a[href="' + element.getAttribute('href') + '"]';
+	}
+	
+	// Priority 4: name attribute for inputs
+	if (element.getAttribute('name')) {
+		return element.tagName.toLowerCase() + '[name="' + element.getAttribute('name') + '"]';
+	}
+	
+	// Priority 5: type for inputs/buttons
+	if (element.getAttribute('type')) {
+		return element.tagName.toLowerCase() + '[type="' + element.getAttribute('type') + '"]';
+	}
+	
+	// Priority 6: CSS class (fallback, least reliable)
+	let selector = element.tagName.toLowerCase();
+	if (element.className) {
+		const classes = element.className.split(' ').filter(c => c.length > 0);
+		if (classes.length > 0) {
+			selector += '.' + classes[0];
+		}
+	}
+	
+	return selector;
+}
+
+// Helper function to list visible, enabled interactive elements in document
+// order. The list is kept in window.__cdpbrowserElements so that the server
+// can resolve the elements to stable IDs.
+function collectInteractiveElements() {
+	const interactiveSelectors = [
+		'button', 'input', 'select', 'textarea', 'a[href]', 
+		'[role="button"]', '[role="link"]', '[role="menuitem"]', 
+		'[role="tab"]', '[role="checkbox"]', '[role="radio"]',
+		'[tabindex]:not([tabindex="-1"])', '[onclick]'
+	];
+	
+	const seen = new Set();
+	const elements = [];
+	interactiveSelectors.forEach(selector => {
+		document.querySelectorAll(selector).forEach(el => {
+			if (el.offsetParent !== null && !el.disabled && !seen.has(el)) { // visible and enabled
+				seen.add(el);
+				elements.push(el);
+			}
+		});
+	});
+	window.__cdpbrowserElements = elements;
+	return elements;
+}
+`
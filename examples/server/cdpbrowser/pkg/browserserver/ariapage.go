@@ -0,0 +1,106 @@
+package browserserver
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ARIASnapshotPagination describes which part of a snapshot was returned.
+// Elements are counted across sections in the order landmarks, interactive,
+// headings, content.
+type ARIASnapshotPagination struct {
+	Offset    int `json:"offset" jsonschema:"Number of elements skipped"`
+	Returned  int `json:"returned" jsonschema:"Number of elements returned"`
+	Total     int `json:"total" jsonschema:"Number of elements in the full snapshot"`
+	Remaining int `json:"remaining" jsonschema:"Number of elements after the returned ones; pass offset+returned to get them"`
+}
+
+// estimateTokens approximates the number of model tokens in text, at about
+// four bytes per token.
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// snapshotSections returns pointers to the element sections of snapshot in
+// pagination order.
+func snapshotSections(snapshot *ARIASnapshotResult) []*[]ARIAElement {
+	return []*[]ARIAElement{&snapshot.Landmarks, &snapshot.Interactive, &snapshot.Headings, &snapshot.Content}
+}
+
+// snapshotElementCount returns the number of elements in all sections of
+// snapshot.
+func snapshotElementCount(snapshot *ARIASnapshotResult) int {
+	n := 0
+	for _, section := range snapshotSections(snapshot) {
+		n += len(*section)
+	}
+	return n
+}
+
+// sliceSnapshot returns a copy of snapshot holding only limit elements,
+// starting offset elements in.
+func sliceSnapshot(snapshot *ARIASnapshotResult, offset, limit int) *ARIASnapshotResult {
+	page := &ARIASnapshotResult{Page: snapshot.Page}
+	from, to := offset, offset+limit
+	pos := 0
+	for i, section := range snapshotSections(snapshot) {
+		elements := *section
+		lo, hi := max(from-pos, 0), min(to-pos, len(elements))
+		if lo < hi {
+			*snapshotSections(page)[i] = elements[lo:hi]
+		}
+		pos += len(elements)
+	}
+	return page
+}
+
+// paginationFooter describes a partial snapshot, or returns "" if p covers
+// the whole snapshot.
+func paginationFooter(p ARIASnapshotPagination) string {
+	if p.Offset == 0 && p.Remaining == 0 {
+		return ""
+	}
+	if p.Returned == 0 {
+		return fmt.Sprintf("\n[truncated: no elements shown of %d; %d more elements]\n", p.Total, p.Remaining)
+	}
+	footer := fmt.Sprintf("\n[showing elements %d-%d of %d", p.Offset+1, p.Offset+p.Returned, p.Total)
+	if p.Remaining > 0 {
+		footer += fmt.Sprintf("; truncated, %d more elements: call aria_snapshot again with offset %d", p.Remaining, p.Offset+p.Returned)
+	}
+	return footer + "]\n"
+}
+
+// paginateSnapshot formats the part of snapshot selected by offset and
+// maxElements, leaving out further elements until the text fits in
+// maxTokens. Zero limits mean no limit. It returns the text, with a footer
+// when elements were left out, and the returned part of the snapshot.
+func paginateSnapshot(snapshot *ARIASnapshotResult, formatter SnapshotFormatter, offset, maxElements, maxTokens int) (string, *ARIASnapshotResult) {
+	total := snapshotElementCount(snapshot)
+	offset = min(max(offset, 0), total)
+	limit := total - offset
+	if maxElements > 0 {
+		limit = min(limit, maxElements)
+	}
+	if offset == 0 && limit == total && maxTokens <= 0 {
+		return formatter.Format(snapshot), snapshot
+	}
+
+	render := func(n int) (string, *ARIASnapshotResult) {
+		page := sliceSnapshot(snapshot, offset, n)
+		p := ARIASnapshotPagination{Offset: offset, Returned: n, Total: total, Remaining: total - offset - n}
+		text := formatter.Format(page) + paginationFooter(p)
+		if p.Offset > 0 || p.Remaining > 0 {
+			page.Pagination = &p
+		}
+		return text, page
+	}
+	if maxTokens > 0 {
+		// Keep as many elements as fit; the output grows with the number of
+		// elements, so the largest fitting count can be found by bisection.
+		limit = sort.Search(limit, func(n int) bool {
+			text, _ := render(n + 1)
+			return estimateTokens(text) > maxTokens
+		})
+	}
+	return render(limit)
+}
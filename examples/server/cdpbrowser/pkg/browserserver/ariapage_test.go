@@ -0,0 +1,75 @@
+package browserserver
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// pagedSnapshot returns a snapshot with two landmarks, five interactive
+// elements and three headings.
+func pagedSnapshot() *ARIASnapshotResult {
+	snapshot := &ARIASnapshotResult{Page: ARIAPageInfo{Title: "Paged", URL: "http://example.test/"}}
+	for i := 1; i <= 2; i++ {
+		snapshot.Landmarks = append(snapshot.Landmarks, ARIAElement{Role: "region", Name: fmt.Sprintf("Region %d", i), Selector: fmt.Sprintf("#r%d", i), Tag: "section"})
+	}
+	for i := 1; i <= 5; i++ {
+		snapshot.Interactive = append(snapshot.Interactive, ARIAElement{ID: i, Role: "button", Name: fmt.Sprintf("Button %d", i), Selector: fmt.Sprintf("#b%d", i), Tag: "button"})
+	}
+	for i := 1; i <= 3; i++ {
+		snapshot.Headings = append(snapshot.Headings, ARIAElement{Level: 2, Text: fmt.Sprintf("Heading %d", i), Selector: fmt.Sprintf("#h%d", i), Tag: "h2"})
+	}
+	return snapshot
+}
+
+func TestSliceSnapshot(t *testing.T) {
+	page := sliceSnapshot(pagedSnapshot(), 1, 3)
+	if len(page.Landmarks) != 1 || page.Landmarks[0].Selector != "#r2" {
+		t.Errorf("Landmarks = %+v, want only #r2", page.Landmarks)
+	}
+	if len(page.Interactive) != 2 || page.Interactive[0].ID != 1 || page.Interactive[1].ID != 2 {
+		t.Errorf("Interactive = %+v, want elements 1 and 2", page.Interactive)
+	}
+	if len(page.Headings) != 0 {
+		t.Errorf("Headings = %+v, want none", page.Headings)
+	}
+}
+
+func TestPaginateSnapshot(t *testing.T) {
+	formatter := SnapshotFormatterFunc(formatCompact)
+
+	text, page := paginateSnapshot(pagedSnapshot(), formatter, 0, 0, 0)
+	if page.Pagination != nil || strings.Contains(text, "showing elements") {
+		t.Errorf("unlimited snapshot was paginated: %+v\n%s", page.Pagination, text)
+	}
+
+	text, page = paginateSnapshot(pagedSnapshot(), formatter, 2, 4, 0)
+	want := ARIASnapshotPagination{Offset: 2, Returned: 4, Total: 10, Remaining: 4}
+	if page.Pagination == nil || *page.Pagination != want {
+		t.Errorf("Pagination = %+v, want %+v", page.Pagination, want)
+	}
+	if !strings.Contains(text, "[showing elements 3-6 of 10; truncated, 4 more elements: call aria_snapshot again with offset 6]") {
+		t.Errorf("footer missing or wrong:\n%s", text)
+	}
+	if strings.Contains(text, "Button 5") || !strings.Contains(text, "Button 4") {
+		t.Errorf("page holds the wrong elements:\n%s", text)
+	}
+
+	text, page = paginateSnapshot(pagedSnapshot(), formatter, 8, 0, 0)
+	if page.Pagination == nil || page.Pagination.Remaining != 0 || !strings.Contains(text, "[showing elements 9-10 of 10]") {
+		t.Errorf("last page = %+v:\n%s", page.Pagination, text)
+	}
+
+	full, _ := paginateSnapshot(pagedSnapshot(), formatter, 0, 0, 0)
+	budget := estimateTokens(full) - 5
+	text, page = paginateSnapshot(pagedSnapshot(), formatter, 0, 0, budget)
+	if estimateTokens(text) > budget {
+		t.Errorf("output is %d tokens, over the budget of %d:\n%s", estimateTokens(text), budget, text)
+	}
+	if page.Pagination == nil || page.Pagination.Returned == 0 || page.Pagination.Remaining == 0 {
+		t.Errorf("Pagination = %+v, want a partial page", page.Pagination)
+	}
+	if !strings.Contains(text, fmt.Sprintf("%d more elements", page.Pagination.Remaining)) {
+		t.Errorf("footer does not report the %d remaining elements:\n%s", page.Pagination.Remaining, text)
+	}
+}
@@ -0,0 +1,183 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Error artifact modes, selected with -error-artifacts.
+const (
+	// errorArtifactsOff leaves failed interaction results as they are.
+	errorArtifactsOff = "off"
+	// errorArtifactsInline attaches the screenshot to the result as an image.
+	errorArtifactsInline = "inline"
+	// errorArtifactsResource keeps the screenshot on the server and links it
+	// from the result as a browser://errors resource, so that clients fetch
+	// it only when they need it.
+	errorArtifactsResource = "resource"
+)
+
+const (
+	// errorArtifactsTemplate is the URI template of screenshots kept in
+	// resource mode.
+	errorArtifactsTemplate = "browser://errors/{id}"
+	// maxErrorArtifacts is the number of screenshots kept in resource mode;
+	// older ones are dropped.
+	maxErrorArtifacts = 20
+	// errorExcerptTokens bounds the page excerpt attached to a failure.
+	errorExcerptTokens = 400
+	// errorArtifactsTimeout bounds the capture, so that a wedged page does
+	// not also hang the error result.
+	errorArtifactsTimeout = 5 * time.Second
+)
+
+// interactionTools are the tools whose failures get error artifacts.
+var interactionTools = map[string]bool{
+	"click":                   true,
+	"type_text":               true,
+	"click_button":            true,
+	"click_link":              true,
+	"select_dropdown":         true,
+	"choose_option":           true,
+	"click_element_by_id":     true,
+	"type_into_element_by_id": true,
+	"click_at":                true,
+	"swipe":                   true,
+	"login_with_credentials":  true,
+}
+
+// checkErrorArtifactsMode reports whether mode is a valid -error-artifacts
+// value.
+func checkErrorArtifactsMode(mode string) error {
+	switch mode {
+	case errorArtifactsOff, errorArtifactsInline, errorArtifactsResource:
+		return nil
+	}
+	return fmt.Errorf("unknown error artifacts mode %q (want %s, %s, or %s)", mode, errorArtifactsOff, errorArtifactsInline, errorArtifactsResource)
+}
+
+// errorArtifactStore keeps the most recent failure screenshots for the
+// browser://errors resources.
+type errorArtifactStore struct {
+	mu          sync.Mutex
+	nextID      int
+	screenshots map[int][]byte
+}
+
+// add stores a screenshot and returns its URI, dropping the oldest one when
+// the store is full.
+func (st *errorArtifactStore) add(png []byte) string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.screenshots == nil {
+		st.screenshots = make(map[int][]byte)
+	}
+	st.nextID++
+	st.screenshots[st.nextID] = png
+	delete(st.screenshots, st.nextID-maxErrorArtifacts)
+	return errorArtifactURI(st.nextID)
+}
+
+// get returns the screenshot stored under uri.
+func (st *errorArtifactStore) get(uri string) ([]byte, bool) {
+	id, err := strconv.Atoi(strings.TrimPrefix(uri, "browser://errors/"))
+	if err != nil {
+		return nil, false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	png, ok := st.screenshots[id]
+	return png, ok
+}
+
+// errorArtifactURI returns the resource URI of the screenshot with the given
+// id.
+func errorArtifactURI(id int) string {
+	return strings.Replace(errorArtifactsTemplate, "{id}", strconv.Itoa(id), 1)
+}
+
+// captureErrorArtifacts takes a screenshot of the viewport and a compact
+// ARIA excerpt of the page. Either may be missing if its capture fails.
+func (s *CDPBrowserServer) captureErrorArtifacts() (png []byte, excerpt string) {
+	ctx, cancel := context.WithTimeout(s.ctx, errorArtifactsTimeout)
+	defer cancel()
+	if err := chromedp.Run(ctx, chromedp.CaptureScreenshot(&png)); err != nil {
+		log.Printf("Failed to capture error screenshot: %v", err)
+	}
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		page, err := captureAXPage(ctx)
+		if err != nil {
+			return err
+		}
+		snapshot := buildARIASnapshot(page.nodes, page.dom, "all", 0, s.elements.idFor)
+		snapshot.Page = ARIAPageInfo{Title: page.title, URL: page.url}
+		excerpt, _ = paginateSnapshot(snapshot, SnapshotFormatterFunc(formatCompact), 0, 0, errorExcerptTokens)
+		return nil
+	}))
+	if err != nil {
+		log.Printf("Failed to capture error page excerpt: %v", err)
+	}
+	return png, excerpt
+}
+
+// attachErrorArtifacts adds the screenshot and page excerpt of a failure to
+// res, according to s.errorArtifacts.
+func (s *CDPBrowserServer) attachErrorArtifacts(res *mcp.CallToolResult) {
+	png, excerpt := s.captureErrorArtifacts()
+	if excerpt != "" {
+		res.Content = append(res.Content, &mcp.TextContent{Text: "Page at the time of the error:\n" + excerpt})
+	}
+	if len(png) == 0 {
+		return
+	}
+	if s.errorArtifacts == errorArtifactsResource {
+		res.Content = append(res.Content, &mcp.ResourceLink{
+			URI:         s.errorScreenshots.add(png),
+			Name:        "error screenshot",
+			Description: "Screenshot of the page when the tool failed",
+			MIMEType:    "image/png",
+		})
+		return
+	}
+	res.Content = append(res.Content, &mcp.ImageContent{Data: png, MIMEType: "image/png"})
+}
+
+// errorArtifactsMiddleware attaches error artifacts to failed calls of
+// interaction tools, unless they are turned off.
+func (s *CDPBrowserServer) errorArtifactsMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		params, ok := req.GetParams().(*mcp.CallToolParamsFor[json.RawMessage])
+		if !ok || !interactionTools[params.Name] || s.errorArtifacts == "" || s.errorArtifacts == errorArtifactsOff {
+			return next(ctx, method, req)
+		}
+
+		result, err := next(ctx, method, req)
+		if res, ok := result.(*mcp.CallToolResult); ok && err == nil && res.IsError {
+			s.attachErrorArtifacts(res)
+		}
+		return result, err
+	}
+}
+
+// readErrorArtifact serves the browser://errors resources.
+func (s *CDPBrowserServer) readErrorArtifact(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	png, ok := s.errorScreenshots.get(uri)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: uri, MIMEType: "image/png", Blob: png},
+		},
+	}, nil
+}
@@ -0,0 +1,59 @@
+package browserserver
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestCheckErrorArtifactsMode(t *testing.T) {
+	for _, mode := range []string{"off", "inline", "resource"} {
+		if err := checkErrorArtifactsMode(mode); err != nil {
+			t.Errorf("checkErrorArtifactsMode(%q) = %v", mode, err)
+		}
+	}
+	if err := checkErrorArtifactsMode("always"); err == nil {
+		t.Error(`checkErrorArtifactsMode("always") succeeded`)
+	}
+}
+
+func TestErrorArtifactResources(t *testing.T) {
+	s := &CDPBrowserServer{}
+	first := s.errorScreenshots.add([]byte("png 1"))
+	var last string
+	for i := 2; i <= maxErrorArtifacts+1; i++ {
+		last = s.errorScreenshots.add([]byte("png"))
+	}
+	if first != "browser://errors/1" || last != errorArtifactURI(maxErrorArtifacts+1) {
+		t.Errorf("URIs = %s ... %s", first, last)
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	server.AddResourceTemplate(&mcp.ResourceTemplate{URITemplate: errorArtifactsTemplate, Name: "errors"}, s.readErrorArtifact)
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	res, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: last})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := res.Contents[0]; c.MIMEType != "image/png" || !bytes.Equal(c.Blob, []byte("png")) {
+		t.Errorf("ReadResource(%s) = %+v", last, c)
+	}
+	// The first screenshot was dropped to make room.
+	if _, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: first}); err == nil {
+		t.Errorf("ReadResource(%s) of a dropped screenshot succeeded", first)
+	}
+}
@@ -0,0 +1,464 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultAssertTimeout is how long an assertion keeps retrying until it
+// holds, so that an assertion right after an action gives the page time to
+// update.
+const defaultAssertTimeout = 5 * time.Second
+
+// maxAssertTimeout bounds the timeout_seconds argument of the assertions.
+const maxAssertTimeout = 2 * time.Minute
+
+// maxAssertElements is the number of matching elements an assertion
+// describes in its diagnostics.
+const maxAssertElements = 5
+
+// assertHelpersJS defines the functions shared by the assertion scripts.
+// queryAll matches a CSS selector, or an XPath expression if xpath is set,
+// against the document. An element is visible if it is
+// rendered with a non-empty box, as for actionability.
+const assertHelpersJS = pageStateJS + `
+function queryAll(sel, xpath) {
+	if (xpath) {
+		const res = document.evaluate(sel, document, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+		const nodes = [];
+		for (let i = 0; i < res.snapshotLength; i++) {
+			if (res.snapshotItem(i).nodeType === 1) {
+				nodes.push(res.snapshotItem(i));
+			}
+		}
+		return nodes;
+	}
+	return Array.from(document.querySelectorAll(sel));
+}
+function hiddenReason(el) {
+	if (!el.isConnected) {
+		return 'detached from the document';
+	}
+	const style = getComputedStyle(el);
+	if (!el.getClientRects().length) {
+		return 'not rendered (display: ' + style.display + ' on it or an ancestor)';
+	}
+	if (style.visibility !== 'visible') {
+		return 'visibility: ' + style.visibility;
+	}
+	const rect = el.getBoundingClientRect();
+	if (rect.width === 0 || rect.height === 0) {
+		return 'empty box (' + Math.round(rect.width) + 'x' + Math.round(rect.height) + ')';
+	}
+	return '';
+}
+const normalize = s => (s || '').replace(/\s+/g, ' ').trim();
+`
+
+// assertElementsJS describes the elements matching a selector: how many
+// there are and how many of them are visible, and the first few in detail.
+const assertElementsJS = `function(sel, xpath, max) {` + assertHelpersJS + `
+	const els = queryAll(sel, xpath);
+	const result = { count: els.length, visible: 0, elements: [] };
+	for (const el of els) {
+		const reason = hiddenReason(el);
+		if (!reason) {
+			result.visible++;
+		}
+		if (result.elements.length < max) {
+			result.elements.push({ element: describeElement(el), hidden: reason });
+		}
+	}
+	return result;
+}`
+
+// assertTextJS looks for text in the rendered text of the document, or of
+// the elements matching a selector. Besides an exact match, it reports
+// near misses: the text in a different case, or only in hidden elements.
+const assertTextJS = `function(text, sel, xpath) {` + assertHelpersJS + `
+	const scopes = sel ? queryAll(sel, xpath) : [document.body || document.documentElement];
+	const rendered = scopes.map(el => normalize(el.innerText)).join('\n');
+	const all = scopes.map(el => normalize(el.textContent)).join('\n');
+	const needle = normalize(text);
+	const i = rendered.toLowerCase().indexOf(needle.toLowerCase());
+	return {
+		scopes: scopes.length,
+		present: rendered.includes(needle),
+		other_case: i >= 0 ? rendered.slice(i, i + needle.length) : '',
+		hidden: !rendered.includes(needle) && all.includes(needle),
+		excerpt: rendered.slice(0, 200)
+	};
+}`
+
+// assertURLJS returns the URL and title of the page.
+const assertURLJS = `({ url: location.href, title: document.title })`
+
+// callJS returns an expression that calls the function fn with args.
+func callJS(fn string, args ...any) string {
+	data, _ := json.Marshal(args)
+	return fmt.Sprintf("(%s).apply(null, %s)", fn, data)
+}
+
+// AssertionResult is the structured result of the assert_* tools.
+type AssertionResult struct {
+	Assertion   string   `json:"assertion" jsonschema:"What was asserted"`
+	Passed      bool     `json:"passed"`
+	Expected    string   `json:"expected"`
+	Actual      string   `json:"actual" jsonschema:"What the page showed at the last attempt"`
+	Diagnostics []string `json:"diagnostics,omitempty" jsonschema:"Details that help explain a failure"`
+	Attempts    int      `json:"attempts" jsonschema:"Number of times the assertion was checked"`
+}
+
+// String describes r, with its diagnostics if it failed.
+func (r *AssertionResult) String() string {
+	if r.Passed {
+		return fmt.Sprintf("PASS: %s (actual: %s)", r.Assertion, r.Actual)
+	}
+	lines := []string{
+		fmt.Sprintf("FAIL: %s", r.Assertion),
+		"Expected: " + r.Expected,
+		"Actual: " + r.Actual,
+	}
+	for _, d := range r.Diagnostics {
+		lines = append(lines, "- "+d)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// assertTimeout returns the timeout for a timeout_seconds argument.
+func assertTimeout(seconds *int) time.Duration {
+	if seconds == nil {
+		return defaultAssertTimeout
+	}
+	return min(time.Duration(max(*seconds, 0))*time.Second, maxAssertTimeout)
+}
+
+// assert calls check until the assertion it evaluates passes, check fails,
+// or timeout expires, and returns the last result.
+func assert(ctx context.Context, timeout time.Duration, check func(context.Context) (*AssertionResult, error)) (*AssertionResult, error) {
+	deadline := time.Now().Add(timeout)
+	for i := 0; ; i++ {
+		res, err := check(ctx)
+		if err != nil {
+			return nil, err
+		}
+		res.Attempts = i + 1
+		if res.Passed || !time.Now().Before(deadline) {
+			return res, nil
+		}
+		delay := min(actionRetryDelays[min(i+1, len(actionRetryDelays)-1)], time.Until(deadline))
+		select {
+		case <-ctx.Done():
+			return res, nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// assertionResult returns the tool result for an assertion. A failed
+// assertion is a tool error, so that replays and batches stop at it.
+func assertionResult(ctx context.Context, name string, res *AssertionResult, err error) (*mcp.CallToolResultFor[AssertionResult], error) {
+	if err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[AssertionResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error running %s: %v", name, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	if !res.Passed {
+		noteToolError(ctx, nil, CodeAssertionFailed)
+	}
+	return &mcp.CallToolResultFor[AssertionResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: res.String()},
+		},
+		StructuredContent: *res,
+		IsError:           !res.Passed,
+	}, nil
+}
+
+type AssertURLMatchesArgs struct {
+	Pattern        string `json:"pattern" jsonschema:"Regular expression (RE2 syntax) the URL must match somewhere; anchor it with ^ and $ to match the whole URL"`
+	TimeoutSeconds *int   `json:"timeout_seconds,omitempty" jsonschema:"How long to keep checking until the assertion holds, up to 120; 0 checks once (default: 5)"`
+}
+
+// AssertURLMatches tool - asserts that the page URL matches a pattern
+func (s *CDPBrowserServer) AssertURLMatches(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[AssertURLMatchesArgs]]) (*mcp.CallToolResultFor[AssertionResult], error) {
+	args := req.Params.Arguments
+	re, err := regexp.Compile(args.Pattern)
+	if err != nil {
+		return assertionResult(ctx, "assert_url_matches", nil, invalidArgumentError{fmt.Errorf("invalid pattern: %v", err)})
+	}
+	res, err := assert(ctx, assertTimeout(args.TimeoutSeconds), func(ctx context.Context) (*AssertionResult, error) {
+		var page struct {
+			URL   string `json:"url"`
+			Title string `json:"title"`
+		}
+		if err := s.backend().Evaluate(ctx, assertURLJS, &page); err != nil {
+			return nil, err
+		}
+		return checkURL(re, page.URL, page.Title), nil
+	})
+	return assertionResult(ctx, "assert_url_matches", res, err)
+}
+
+// checkURL evaluates assert_url_matches for a page with url and title.
+func checkURL(re *regexp.Regexp, url, title string) *AssertionResult {
+	res := &AssertionResult{
+		Assertion: fmt.Sprintf("URL matches %q", re),
+		Expected:  fmt.Sprintf("a URL matching %q", re),
+		Actual:    url,
+		Passed:    re.MatchString(url),
+	}
+	if !res.Passed {
+		res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("page title: %q", title))
+		if ci, err := regexp.Compile("(?i)" + re.String()); err == nil && ci.MatchString(url) {
+			res.Diagnostics = append(res.Diagnostics, "the URL matches if case is ignored")
+		}
+	}
+	return res
+}
+
+type AssertTextPresentArgs struct {
+	Text           string `json:"text" jsonschema:"Text that must be shown; runs of whitespace match any whitespace"`
+	Selector       string `json:"selector,omitempty" jsonschema:"CSS selector, XPath expression starting with /, or xpath= or text= prefixed selector of the elements to search (default: the whole page)"`
+	Absent         bool   `json:"absent,omitempty" jsonschema:"Assert that the text is not shown instead (default: false)"`
+	TimeoutSeconds *int   `json:"timeout_seconds,omitempty" jsonschema:"How long to keep checking until the assertion holds, up to 120; 0 checks once (default: 5)"`
+}
+
+// textFacts is what assertTextJS returns.
+type textFacts struct {
+	Scopes    int    `json:"scopes"`
+	Present   bool   `json:"present"`
+	OtherCase string `json:"other_case"`
+	Hidden    bool   `json:"hidden"`
+	Excerpt   string `json:"excerpt"`
+}
+
+// AssertTextPresent tool - asserts that text is shown on the page, or in the
+// elements matching a selector
+func (s *CDPBrowserServer) AssertTextPresent(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[AssertTextPresentArgs]]) (*mcp.CallToolResultFor[AssertionResult], error) {
+	args := req.Params.Arguments
+	if strings.TrimSpace(args.Text) == "" {
+		return assertionResult(ctx, "assert_text_present", nil, invalidArgumentError{fmt.Errorf("text is required")})
+	}
+	res, err := assert(ctx, assertTimeout(args.TimeoutSeconds), func(ctx context.Context) (*AssertionResult, error) {
+		var facts textFacts
+		loc := literalLocator(args.Selector)
+		if err := s.backend().Evaluate(ctx, callJS(assertTextJS, args.Text, loc.Query, loc.XPath), &facts); err != nil {
+			return nil, err
+		}
+		return checkText(&args, &facts), nil
+	})
+	return assertionResult(ctx, "assert_text_present", res, err)
+}
+
+// checkText evaluates assert_text_present from the facts assertTextJS found.
+func checkText(args *AssertTextPresentArgs, facts *textFacts) *AssertionResult {
+	where := "the page"
+	if args.Selector != "" {
+		where = args.Selector
+	}
+	res := &AssertionResult{
+		Assertion: fmt.Sprintf("%q is shown in %s", args.Text, where),
+		Expected:  "text shown",
+		Actual:    "text not shown",
+		Passed:    facts.Present,
+	}
+	if facts.Present {
+		res.Actual = "text shown"
+	}
+	if args.Absent {
+		res.Assertion = fmt.Sprintf("%q is not shown in %s", args.Text, where)
+		res.Expected = "text not shown"
+		res.Passed = !facts.Present
+	}
+	if res.Passed {
+		return res
+	}
+
+	if args.Selector != "" {
+		res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("%d elements match %s", facts.Scopes, args.Selector))
+	}
+	if !facts.Present {
+		if facts.OtherCase != "" {
+			res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("found with different case: %q", facts.OtherCase))
+		}
+		if facts.Hidden {
+			res.Diagnostics = append(res.Diagnostics, "the text is in the document but not shown")
+		}
+		if facts.Excerpt != "" {
+			res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("shown text starts: %q", facts.Excerpt))
+		}
+	}
+	return res
+}
+
+// elementFacts is what assertElementsJS returns.
+type elementFacts struct {
+	Count    int               `json:"count"`
+	Visible  int               `json:"visible"`
+	Elements []assertedElement `json:"elements"`
+}
+
+// assertedElement describes a matching element and why it is hidden, if it
+// is.
+type assertedElement struct {
+	Element string `json:"element"`
+	Hidden  string `json:"hidden"`
+}
+
+// describe returns a diagnostic line for each element in f.
+func (f *elementFacts) describe() []string {
+	var lines []string
+	for _, el := range f.Elements {
+		state := "visible"
+		if el.Hidden != "" {
+			state = "hidden: " + el.Hidden
+		}
+		lines = append(lines, fmt.Sprintf("%s is %s", el.Element, state))
+	}
+	if more := f.Count - len(f.Elements); more > 0 {
+		lines = append(lines, fmt.Sprintf("and %d more", more))
+	}
+	return lines
+}
+
+// queryElements runs assertElementsJS for selector.
+func (s *CDPBrowserServer) queryElements(ctx context.Context, selector string) (*elementFacts, error) {
+	var facts elementFacts
+	loc := literalLocator(selector)
+	if err := s.backend().Evaluate(ctx, callJS(assertElementsJS, loc.Query, loc.XPath, maxAssertElements), &facts); err != nil {
+		return nil, err
+	}
+	return &facts, nil
+}
+
+type AssertElementVisibleArgs struct {
+	Selector       string `json:"selector" jsonschema:"CSS selector, XPath expression starting with /, or xpath= or text= prefixed selector of the element"`
+	Hidden         bool   `json:"hidden,omitempty" jsonschema:"Assert that no matching element is visible instead (default: false)"`
+	TimeoutSeconds *int   `json:"timeout_seconds,omitempty" jsonschema:"How long to keep checking until the assertion holds, up to 120; 0 checks once (default: 5)"`
+}
+
+// AssertElementVisible tool - asserts that an element matching a selector is
+// visible
+func (s *CDPBrowserServer) AssertElementVisible(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[AssertElementVisibleArgs]]) (*mcp.CallToolResultFor[AssertionResult], error) {
+	args := req.Params.Arguments
+	if args.Selector == "" {
+		return assertionResult(ctx, "assert_element_visible", nil, invalidArgumentError{fmt.Errorf("selector is required")})
+	}
+	res, err := assert(ctx, assertTimeout(args.TimeoutSeconds), func(ctx context.Context) (*AssertionResult, error) {
+		facts, err := s.queryElements(ctx, args.Selector)
+		if err != nil {
+			return nil, err
+		}
+		return checkVisible(&args, facts), nil
+	})
+	return assertionResult(ctx, "assert_element_visible", res, err)
+}
+
+// checkVisible evaluates assert_element_visible from the elements
+// assertElementsJS found.
+func checkVisible(args *AssertElementVisibleArgs, facts *elementFacts) *AssertionResult {
+	res := &AssertionResult{
+		Assertion: fmt.Sprintf("%s is visible", args.Selector),
+		Expected:  "at least 1 visible element",
+		Actual:    fmt.Sprintf("%d matching elements, %d visible", facts.Count, facts.Visible),
+		Passed:    facts.Visible > 0,
+	}
+	if args.Hidden {
+		res.Assertion = fmt.Sprintf("%s is hidden", args.Selector)
+		res.Expected = "no visible element"
+		res.Passed = facts.Visible == 0
+	}
+	if !res.Passed {
+		res.Diagnostics = facts.describe()
+		if facts.Count == 0 {
+			res.Diagnostics = append(res.Diagnostics, "no element matches the selector")
+		}
+	}
+	return res
+}
+
+type AssertElementCountArgs struct {
+	Selector       string `json:"selector" jsonschema:"CSS selector, XPath expression starting with /, or xpath= or text= prefixed selector of the elements to count"`
+	Count          *int   `json:"count,omitempty" jsonschema:"Exact number of matching elements"`
+	Min            *int   `json:"min,omitempty" jsonschema:"Least number of matching elements"`
+	Max            *int   `json:"max,omitempty" jsonschema:"Greatest number of matching elements"`
+	VisibleOnly    bool   `json:"visible_only,omitempty" jsonschema:"Count only visible elements (default: false)"`
+	TimeoutSeconds *int   `json:"timeout_seconds,omitempty" jsonschema:"How long to keep checking until the assertion holds, up to 120; 0 checks once (default: 5)"`
+}
+
+// expected describes the counts args accepts, or returns an error if it
+// accepts none.
+func (args *AssertElementCountArgs) expected() (string, error) {
+	switch {
+	case args.Count != nil && (args.Min != nil || args.Max != nil):
+		return "", fmt.Errorf("count cannot be combined with min or max")
+	case args.Count != nil:
+		return fmt.Sprintf("exactly %d", *args.Count), nil
+	case args.Min != nil && args.Max != nil:
+		if *args.Min > *args.Max {
+			return "", fmt.Errorf("min %d is greater than max %d", *args.Min, *args.Max)
+		}
+		return fmt.Sprintf("between %d and %d", *args.Min, *args.Max), nil
+	case args.Min != nil:
+		return fmt.Sprintf("at least %d", *args.Min), nil
+	case args.Max != nil:
+		return fmt.Sprintf("at most %d", *args.Max), nil
+	}
+	return "", fmt.Errorf("one of count, min, or max is required")
+}
+
+// AssertElementCount tool - asserts how many elements match a selector
+func (s *CDPBrowserServer) AssertElementCount(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[AssertElementCountArgs]]) (*mcp.CallToolResultFor[AssertionResult], error) {
+	args := req.Params.Arguments
+	if args.Selector == "" {
+		return assertionResult(ctx, "assert_element_count", nil, invalidArgumentError{fmt.Errorf("selector is required")})
+	}
+	if _, err := args.expected(); err != nil {
+		return assertionResult(ctx, "assert_element_count", nil, invalidArgumentError{err})
+	}
+	res, err := assert(ctx, assertTimeout(args.TimeoutSeconds), func(ctx context.Context) (*AssertionResult, error) {
+		facts, err := s.queryElements(ctx, args.Selector)
+		if err != nil {
+			return nil, err
+		}
+		return checkCount(&args, facts), nil
+	})
+	return assertionResult(ctx, "assert_element_count", res, err)
+}
+
+// checkCount evaluates assert_element_count from the elements
+// assertElementsJS found. args must be valid.
+func checkCount(args *AssertElementCountArgs, facts *elementFacts) *AssertionResult {
+	expected, _ := args.expected()
+	what := "elements"
+	n := facts.Count
+	if args.VisibleOnly {
+		what = "visible elements"
+		n = facts.Visible
+	}
+	passed := (args.Count == nil || n == *args.Count) &&
+		(args.Min == nil || n >= *args.Min) &&
+		(args.Max == nil || n <= *args.Max)
+	res := &AssertionResult{
+		Assertion: fmt.Sprintf("%s matches %s %s", args.Selector, expected, what),
+		Expected:  fmt.Sprintf("%s %s", expected, what),
+		Actual:    fmt.Sprintf("%d %s", n, what),
+		Passed:    passed,
+	}
+	if !passed {
+		res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("%d matching elements, %d visible", facts.Count, facts.Visible))
+		res.Diagnostics = append(res.Diagnostics, facts.describe()...)
+	}
+	return res
+}
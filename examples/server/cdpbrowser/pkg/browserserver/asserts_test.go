@@ -0,0 +1,126 @@
+package browserserver
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCheckURL(t *testing.T) {
+	res := checkURL(regexp.MustCompile(`/order/\d+$`), "https://shop.example/order/42", "Order")
+	if !res.Passed || res.Actual != "https://shop.example/order/42" || len(res.Diagnostics) != 0 {
+		t.Errorf("matching URL = %+v", res)
+	}
+	res = checkURL(regexp.MustCompile(`/checkout`), "https://shop.example/CHECKOUT", "Pay")
+	want := []string{`page title: "Pay"`, "the URL matches if case is ignored"}
+	if res.Passed || !slices.Equal(res.Diagnostics, want) {
+		t.Errorf("mismatching URL = %+v, want diagnostics %q", res, want)
+	}
+}
+
+func TestCheckText(t *testing.T) {
+	args := &AssertTextPresentArgs{Text: "Order confirmed"}
+	if res := checkText(args, &textFacts{Scopes: 1, Present: true}); !res.Passed || res.Actual != "text shown" {
+		t.Errorf("present text = %+v", res)
+	}
+
+	facts := &textFacts{Scopes: 1, OtherCase: "ORDER CONFIRMED", Hidden: true, Excerpt: "Checkout"}
+	res := checkText(args, facts)
+	want := []string{`found with different case: "ORDER CONFIRMED"`, "the text is in the document but not shown", `shown text starts: "Checkout"`}
+	if res.Passed || !slices.Equal(res.Diagnostics, want) {
+		t.Errorf("missing text = %+v, want diagnostics %q", res, want)
+	}
+
+	args = &AssertTextPresentArgs{Text: "Error", Selector: ".alert", Absent: true}
+	if res := checkText(args, &textFacts{Scopes: 2}); !res.Passed || res.Assertion != `"Error" is not shown in .alert` {
+		t.Errorf("absent text = %+v", res)
+	}
+	res = checkText(args, &textFacts{Scopes: 2, Present: true})
+	if res.Passed || res.Actual != "text shown" || !slices.Equal(res.Diagnostics, []string{"2 elements match .alert"}) {
+		t.Errorf("text that should be absent = %+v", res)
+	}
+}
+
+func TestCheckVisible(t *testing.T) {
+	facts := &elementFacts{Count: 7, Elements: []assertedElement{{Element: "div#toast", Hidden: "visibility: hidden"}}}
+	args := &AssertElementVisibleArgs{Selector: "#toast"}
+	res := checkVisible(args, facts)
+	want := []string{"div#toast is hidden: visibility: hidden", "and 6 more"}
+	if res.Passed || res.Actual != "7 matching elements, 0 visible" || !slices.Equal(res.Diagnostics, want) {
+		t.Errorf("hidden element = %+v, want diagnostics %q", res, want)
+	}
+	args.Hidden = true
+	if res := checkVisible(args, facts); !res.Passed {
+		t.Errorf("asserting hidden on a hidden element = %+v", res)
+	}
+
+	res = checkVisible(&AssertElementVisibleArgs{Selector: "#none"}, &elementFacts{})
+	if res.Passed || !slices.Equal(res.Diagnostics, []string{"no element matches the selector"}) {
+		t.Errorf("missing element = %+v", res)
+	}
+}
+
+func TestAssertElementCountArgs(t *testing.T) {
+	n := func(i int) *int { return &i }
+	tests := []struct {
+		args    AssertElementCountArgs
+		want    string
+		wantErr bool
+	}{
+		{args: AssertElementCountArgs{Count: n(3)}, want: "exactly 3"},
+		{args: AssertElementCountArgs{Min: n(1)}, want: "at least 1"},
+		{args: AssertElementCountArgs{Max: n(0)}, want: "at most 0"},
+		{args: AssertElementCountArgs{Min: n(2), Max: n(4)}, want: "between 2 and 4"},
+		{args: AssertElementCountArgs{Min: n(4), Max: n(2)}, wantErr: true},
+		{args: AssertElementCountArgs{Count: n(1), Max: n(2)}, wantErr: true},
+		{args: AssertElementCountArgs{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.args.expected()
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("expected() = %q, %v, want %q, error %t", got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	facts := &elementFacts{Count: 3, Visible: 2}
+	if res := checkCount(&AssertElementCountArgs{Selector: "li", Count: n(3)}, facts); !res.Passed || res.Actual != "3 elements" {
+		t.Errorf("count 3 = %+v", res)
+	}
+	res := checkCount(&AssertElementCountArgs{Selector: "li", Min: n(3), VisibleOnly: true}, facts)
+	if res.Passed || res.Expected != "at least 3 visible elements" || res.Actual != "2 visible elements" {
+		t.Errorf("at least 3 visible = %+v", res)
+	}
+}
+
+func TestAssertRetries(t *testing.T) {
+	calls := 0
+	res, err := assert(context.Background(), time.Second, func(context.Context) (*AssertionResult, error) {
+		calls++
+		return &AssertionResult{Passed: calls == 3}, nil
+	})
+	if err != nil || !res.Passed || res.Attempts != 3 {
+		t.Errorf("assert = %+v, %v, want a pass on attempt 3", res, err)
+	}
+
+	calls = 0
+	res, err = assert(context.Background(), 0, func(context.Context) (*AssertionResult, error) {
+		calls++
+		return &AssertionResult{}, nil
+	})
+	if err != nil || res.Passed || calls != 1 {
+		t.Errorf("assert with no timeout = %+v, %v after %d calls, want one failed attempt", res, err, calls)
+	}
+
+	zero, long := 0, 600
+	if got := assertTimeout(nil); got != defaultAssertTimeout {
+		t.Errorf("default timeout = %v", got)
+	}
+	if got := assertTimeout(&zero); got != 0 {
+		t.Errorf("timeout 0 = %v", got)
+	}
+	if got := assertTimeout(&long); got != maxAssertTimeout {
+		t.Errorf("timeout 600s = %v, want %v", got, maxAssertTimeout)
+	}
+}
@@ -0,0 +1,384 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/accessibility"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/domsnapshot"
+	"github.com/chromedp/chromedp"
+)
+
+// ARIA snapshots are built from the browser's own accessibility tree
+// (Accessibility.getFullAXTree), so roles and names are the ones assistive
+// technology sees, and no script has to run in the page. The DOM attributes
+// needed for selectors come from DOMSnapshot.captureSnapshot, which does not
+// disturb the node IDs chromedp tracks the way DOM.getDocument would.
+
+// axLandmarkRoles are the roles listed as landmarks.
+var axLandmarkRoles = map[string]bool{
+	"banner":        true,
+	"navigation":    true,
+	"main":          true,
+	"contentinfo":   true,
+	"complementary": true,
+	"region":        true,
+	"search":        true,
+	"form":          true,
+}
+
+// axInteractiveRoles are the roles listed as interactive elements. Focusable
+// generic elements, such as a div with a tabindex, are listed as well.
+var axInteractiveRoles = map[string]bool{
+	"button":           true,
+	"link":             true,
+	"textbox":          true,
+	"searchbox":        true,
+	"combobox":         true,
+	"listbox":          true,
+	"checkbox":         true,
+	"radio":            true,
+	"switch":           true,
+	"slider":           true,
+	"spinbutton":       true,
+	"menuitem":         true,
+	"menuitemcheckbox": true,
+	"menuitemradio":    true,
+	"tab":              true,
+	"treeitem":         true,
+}
+
+// axContentRoles are the roles listed as content regions.
+var axContentRoles = map[string]bool{
+	"article": true,
+	"region":  true,
+}
+
+// snapshotStyles are the computed styles captured for each rendered element,
+// in the order DOMSnapshot reports them.
+var snapshotStyles = []string{"color", "background-color"}
+
+// domElement is an element from a DOM snapshot.
+type domElement struct {
+	tag     string
+	attrs   map[string]string
+	parent  int // index into domIndex.elements, or -1
+	baseURL string
+
+	// Layout, for rendered elements only.
+	bounds     []float64 // x, y, width, height in document coordinates
+	color      string
+	background string
+}
+
+// domIndex gives access to the elements of a DOM snapshot by backend node
+// ID.
+type domIndex struct {
+	elements  []domElement
+	byBackend map[cdp.BackendNodeID]int
+}
+
+// newDOMIndex indexes the nodes of all documents of a DOM snapshot.
+func newDOMIndex(docs []*domsnapshot.DocumentSnapshot, strs []string) *domIndex {
+	str := func(i int64) string {
+		if i < 0 || int(i) >= len(strs) {
+			return ""
+		}
+		return strs[i]
+	}
+	d := &domIndex{byBackend: make(map[cdp.BackendNodeID]int)}
+	for _, doc := range docs {
+		nodes := doc.Nodes
+		if nodes == nil {
+			continue
+		}
+		base := len(d.elements)
+		for i := range nodes.BackendNodeID {
+			e := domElement{parent: -1, baseURL: str(int64(doc.BaseURL))}
+			if i < len(nodes.NodeName) {
+				e.tag = strings.ToLower(str(int64(nodes.NodeName[i])))
+			}
+			if i < len(nodes.ParentIndex) && nodes.ParentIndex[i] >= 0 {
+				e.parent = base + int(nodes.ParentIndex[i])
+			}
+			if i < len(nodes.Attributes) {
+				attrs := nodes.Attributes[i]
+				e.attrs = make(map[string]string, len(attrs)/2)
+				for j := 0; j+1 < len(attrs); j += 2 {
+					e.attrs[str(attrs[j])] = str(attrs[j+1])
+				}
+			}
+			d.elements = append(d.elements, e)
+			d.byBackend[nodes.BackendNodeID[i]] = base + i
+		}
+		if layout := doc.Layout; layout != nil {
+			for i, n := range layout.NodeIndex {
+				if n < 0 || base+int(n) >= len(d.elements) {
+					continue
+				}
+				e := &d.elements[base+int(n)]
+				if i < len(layout.Bounds) && len(layout.Bounds[i]) == 4 {
+					e.bounds = layout.Bounds[i]
+				}
+				if i < len(layout.Styles) && len(layout.Styles[i]) == len(snapshotStyles) {
+					e.color, e.background = str(layout.Styles[i][0]), str(layout.Styles[i][1])
+				}
+			}
+		}
+	}
+	return d
+}
+
+// element returns the element with backend node ID b.
+func (d *domIndex) element(b cdp.BackendNodeID) (*domElement, bool) {
+	i, ok := d.byBackend[b]
+	if !ok {
+		return nil, false
+	}
+	return &d.elements[i], true
+}
+
+// within reports whether the node with backend node ID b is ancestor or one
+// of its descendants.
+func (d *domIndex) within(b, ancestor cdp.BackendNodeID) bool {
+	i, ok := d.byBackend[b]
+	a, aok := d.byBackend[ancestor]
+	for ok && aok && i >= 0 {
+		if i == a {
+			return true
+		}
+		i = d.elements[i].parent
+	}
+	return false
+}
+
+// cssSelectors returns the candidate CSS selectors for e, most specific
+// first: aria-label, id, href for links, name, type, and finally the tag with
+// its first class.
+func cssSelectors(e *domElement) []string {
+	var selectors []string
+	if label := e.attrs["aria-label"]; label != "" {
+		selectors = append(selectors, `[aria-label="`+strings.ReplaceAll(label, `"`, `\"`)+`"]`)
+	}
+	if id := e.attrs["id"]; id != "" {
+		selectors = append(selectors, "#"+id)
+	}
+	if href := e.attrs["href"]; e.tag == "a" && href != "" {
+		selectors = append(selectors, `a[href="`+href+`"]`)
+	}
+	if name := e.attrs["name"]; name != "" {
+		selectors = append(selectors, e.tag+`[name="`+name+`"]`)
+	}
+	if typ := e.attrs["type"]; typ != "" {
+		selectors = append(selectors, e.tag+`[type="`+typ+`"]`)
+	}
+	classSelector := e.tag
+	if classes := strings.Fields(e.attrs["class"]); len(classes) > 0 {
+		classSelector += "." + classes[0]
+	}
+	return append(selectors, classSelector)
+}
+
+// resolvedHref returns the absolute URL of e's href attribute, or "".
+func resolvedHref(e *domElement) string {
+	href, ok := e.attrs["href"]
+	if !ok {
+		return ""
+	}
+	base, err := url.Parse(e.baseURL)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// axString returns an accessibility value as a string.
+func axString(v *accessibility.Value) string {
+	if v == nil || len(v.Value) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(v.Value, &s); err == nil {
+		return s
+	}
+	return string(v.Value)
+}
+
+// axProperty returns the value of the named property of n, or nil.
+func axProperty(n *accessibility.Node, name accessibility.PropertyName) *accessibility.Value {
+	for _, p := range n.Properties {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return nil
+}
+
+// axDocumentOrder returns the nodes of an accessibility tree in document
+// order, starting from the root.
+func axDocumentOrder(nodes []*accessibility.Node) []*accessibility.Node {
+	byID := make(map[accessibility.NodeID]*accessibility.Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.NodeID] = n
+	}
+	ordered := make([]*accessibility.Node, 0, len(nodes))
+	var walk func(n *accessibility.Node)
+	walk = func(n *accessibility.Node) {
+		ordered = append(ordered, n)
+		for _, id := range n.ChildIDs {
+			if child, ok := byID[id]; ok {
+				walk(child)
+			}
+		}
+	}
+	for _, n := range nodes {
+		if _, ok := byID[n.ParentID]; !ok {
+			walk(n)
+		}
+	}
+	return ordered
+}
+
+// axInteractive reports whether n is an enabled interactive element.
+func axInteractive(n *accessibility.Node) bool {
+	role := axString(n.Role)
+	focusable := axString(axProperty(n, accessibility.PropertyNameFocusable)) == "true"
+	disabled := axString(axProperty(n, accessibility.PropertyNameDisabled)) == "true"
+	return (axInteractiveRoles[role] || (role == "generic" && focusable)) && !disabled
+}
+
+// interactiveElement describes the interactive element n, whose DOM element
+// is e, for a snapshot.
+func interactiveElement(n *accessibility.Node, e *domElement, idFor func(cdp.BackendNodeID) int) ARIAElement {
+	selectors := cssSelectors(e)
+	return ARIAElement{
+		ID:        idFor(n.BackendDOMNodeID),
+		Role:      axString(n.Role),
+		Name:      axString(n.Name),
+		Selector:  selectors[0],
+		Selectors: selectors,
+		AriaLabel: e.attrs["aria-label"],
+		Tag:       e.tag,
+		Href:      resolvedHref(e),
+		Value:     axString(n.Value),
+	}
+}
+
+// buildARIASnapshot turns an accessibility tree into the sections of an
+// ARIA snapshot for the given focus area. If region is non-zero, only nodes
+// within that DOM node are included. idFor assigns the stable IDs of
+// interactive elements.
+func buildARIASnapshot(nodes []*accessibility.Node, dom *domIndex, focus string, region cdp.BackendNodeID, idFor func(cdp.BackendNodeID) int) *ARIASnapshotResult {
+	everything := focus == "all" || focus == "region"
+	snapshot := &ARIASnapshotResult{}
+	for _, n := range axDocumentOrder(nodes) {
+		if n.Ignored || n.BackendDOMNodeID == 0 {
+			continue
+		}
+		e, ok := dom.element(n.BackendDOMNodeID)
+		if !ok || (region != 0 && !dom.within(n.BackendDOMNodeID, region)) {
+			continue
+		}
+		role := axString(n.Role)
+		name := axString(n.Name)
+		selectors := cssSelectors(e)
+		element := ARIAElement{Role: role, Name: name, Selector: selectors[0], Tag: e.tag}
+
+		if axLandmarkRoles[role] && (everything || focus == "landmarks") {
+			snapshot.Landmarks = append(snapshot.Landmarks, element)
+		}
+		if axInteractive(n) && (everything || focus == "interactive") {
+			snapshot.Interactive = append(snapshot.Interactive, interactiveElement(n, e, idFor))
+		}
+		if role == "heading" && name != "" && (everything || focus == "headings") {
+			level, _ := strconv.Atoi(axString(axProperty(n, accessibility.PropertyNameLevel)))
+			snapshot.Headings = append(snapshot.Headings, ARIAElement{
+				Level:    max(level, 1),
+				Text:     name,
+				Selector: selectors[0],
+				Tag:      e.tag,
+			})
+		}
+		if axContentRoles[role] && everything {
+			snapshot.Content = append(snapshot.Content, element)
+		}
+	}
+	return snapshot
+}
+
+// axPage is the accessibility tree and DOM of a page.
+type axPage struct {
+	title, url string
+	nodes      []*accessibility.Node
+	dom        *domIndex
+}
+
+// captureAXPage reads the accessibility tree and DOM of the current page. It
+// must run inside a chromedp action.
+func captureAXPage(ctx context.Context) (*axPage, error) {
+	docs, strs, err := domsnapshot.CaptureSnapshot(snapshotStyles).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture DOM snapshot: %v", err)
+	}
+	nodes, err := accessibility.GetFullAXTree().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accessibility tree: %v", err)
+	}
+	page := &axPage{nodes: nodes, dom: newDOMIndex(docs, strs)}
+	if len(docs) > 0 {
+		if i := int(docs[0].Title); i >= 0 && i < len(strs) {
+			page.title = strs[i]
+		}
+		if i := int(docs[0].DocumentURL); i >= 0 && i < len(strs) {
+			page.url = strs[i]
+		}
+	}
+	return page, nil
+}
+
+// takeARIASnapshot captures the accessibility structure of the current page
+// for the given focus area, within the element matched by the region
+// selector if one is given, and assigns stable IDs to interactive elements.
+func (s *CDPBrowserServer) takeARIASnapshot(ctx context.Context, focus, region string) (*ARIASnapshotResult, error) {
+	if !s.usesCDP() {
+		return s.ariaSnapshotInPage(ctx, focus, region)
+	}
+	var snapshot *ARIASnapshotResult
+	err := s.run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var regionNode cdp.BackendNodeID
+		if region != "" {
+			var nodes []*cdp.Node
+			loc := literalLocator(region)
+			if err := chromedp.Nodes(loc.Query, &nodes, loc.by(), chromedp.AtLeast(0)).Do(ctx); err != nil {
+				return err
+			}
+			if len(nodes) == 0 {
+				return notFoundError{fmt.Errorf("no element matches region selector %s", region)}
+			}
+			regionNode = nodes[0].BackendNodeID
+		}
+
+		page, err := captureAXPage(ctx)
+		if err != nil {
+			return err
+		}
+		snapshot = buildARIASnapshot(page.nodes, page.dom, focus, regionNode, s.elements.idFor)
+		snapshot.Page = ARIAPageInfo{
+			Title:     page.title,
+			URL:       page.url,
+			Timestamp: time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+		}
+		return nil
+	}))
+	return snapshot, err
+}
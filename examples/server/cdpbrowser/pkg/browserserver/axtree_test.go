@@ -0,0 +1,86 @@
+package browserserver
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/accessibility"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/domsnapshot"
+)
+
+// axValue returns an accessibility value holding v.
+func axValue(v any) *accessibility.Value {
+	data, _ := json.Marshal(v)
+	return &accessibility.Value{Type: accessibility.ValueTypeString, Value: data}
+}
+
+// testAXPage returns the accessibility tree and DOM snapshot of a small sign
+// up page. DOM node i has backend node ID 100+i.
+func testAXPage() ([]*accessibility.Node, *domIndex) {
+	strs := []string{"", "#document", "HTML", "BODY", "FORM", "INPUT", "BUTTON", "H1", "A",
+		"id", "signup", "username", "aria-label", "Submit form", "href", "/next", "http://example.test/form", "Sign up"}
+	doc := &domsnapshot.DocumentSnapshot{
+		DocumentURL: 16,
+		BaseURL:     16,
+		Title:       17,
+		Nodes: &domsnapshot.NodeTreeSnapshot{
+			ParentIndex:   []int64{-1, 0, 1, 2, 3, 3, 2, 2},
+			NodeName:      []domsnapshot.StringIndex{1, 2, 3, 4, 5, 6, 7, 8},
+			BackendNodeID: []cdp.BackendNodeID{100, 101, 102, 103, 104, 105, 106, 107},
+			Attributes: []domsnapshot.ArrayOfStrings{
+				{}, {}, {}, {9, 10}, {9, 11}, {12, 13}, {}, {14, 15},
+			},
+		},
+	}
+	focusable := []*accessibility.Property{{Name: accessibility.PropertyNameFocusable, Value: axValue(true)}}
+	nodes := []*accessibility.Node{
+		{NodeID: "1", Role: axValue("RootWebArea"), Name: axValue("Sign up"), ChildIDs: []accessibility.NodeID{"6", "2", "7", "8"}, BackendDOMNodeID: 100},
+		{NodeID: "2", ParentID: "1", Role: axValue("form"), ChildIDs: []accessibility.NodeID{"3", "4"}, BackendDOMNodeID: 103},
+		{NodeID: "3", ParentID: "2", Role: axValue("textbox"), Name: axValue("Username"), Value: axValue("alice"), Properties: focusable, BackendDOMNodeID: 104},
+		{NodeID: "4", ParentID: "2", Role: axValue("button"), Name: axValue("Submit form"), Properties: focusable, BackendDOMNodeID: 105},
+		{NodeID: "6", ParentID: "1", Role: axValue("heading"), Name: axValue("Sign up"), Properties: []*accessibility.Property{{Name: accessibility.PropertyNameLevel, Value: axValue(1)}}, BackendDOMNodeID: 106},
+		{NodeID: "7", ParentID: "1", Role: axValue("link"), Name: axValue("Next"), Properties: focusable, BackendDOMNodeID: 107},
+		{NodeID: "8", ParentID: "1", Ignored: true, Role: axValue("generic"), Properties: focusable, BackendDOMNodeID: 102},
+	}
+	return nodes, newDOMIndex([]*domsnapshot.DocumentSnapshot{doc}, strs)
+}
+
+func TestBuildARIASnapshot(t *testing.T) {
+	nodes, dom := testAXPage()
+	var registry elementRegistry
+	snapshot := buildARIASnapshot(nodes, dom, "all", 0, registry.idFor)
+
+	wantLandmarks := []ARIAElement{{Role: "form", Selector: "#signup", Tag: "form"}}
+	if !reflect.DeepEqual(snapshot.Landmarks, wantLandmarks) {
+		t.Errorf("Landmarks = %+v, want %+v", snapshot.Landmarks, wantLandmarks)
+	}
+	wantInteractive := []ARIAElement{
+		{ID: 1, Role: "textbox", Name: "Username", Selector: "#username", Selectors: []string{"#username", "input"}, Tag: "input", Value: "alice"},
+		{ID: 2, Role: "button", Name: "Submit form", Selector: `[aria-label="Submit form"]`, Selectors: []string{`[aria-label="Submit form"]`, "button"}, AriaLabel: "Submit form", Tag: "button"},
+		{ID: 3, Role: "link", Name: "Next", Selector: `a[href="/next"]`, Selectors: []string{`a[href="/next"]`, "a"}, Tag: "a", Href: "http://example.test/next"},
+	}
+	if !reflect.DeepEqual(snapshot.Interactive, wantInteractive) {
+		t.Errorf("Interactive =\n%+v\nwant\n%+v", snapshot.Interactive, wantInteractive)
+	}
+	wantHeadings := []ARIAElement{{Level: 1, Text: "Sign up", Selector: "h1", Tag: "h1"}}
+	if !reflect.DeepEqual(snapshot.Headings, wantHeadings) {
+		t.Errorf("Headings = %+v, want %+v", snapshot.Headings, wantHeadings)
+	}
+
+	// The same elements keep their IDs, and a region limits the snapshot to
+	// the region's descendants.
+	region := buildARIASnapshot(nodes, dom, "region", 103, registry.idFor)
+	if len(region.Interactive) != 2 || region.Interactive[0].ID != 1 || region.Interactive[1].ID != 2 {
+		t.Errorf("region Interactive = %+v, want elements 1 and 2", region.Interactive)
+	}
+	if len(region.Headings) != 0 {
+		t.Errorf("region Headings = %+v, want none", region.Headings)
+	}
+
+	interactive := buildARIASnapshot(nodes, dom, "interactive", 0, registry.idFor)
+	if len(interactive.Landmarks) != 0 || len(interactive.Headings) != 0 || len(interactive.Interactive) != 3 {
+		t.Errorf("focus interactive = %+v, want only the 3 interactive elements", interactive)
+	}
+}
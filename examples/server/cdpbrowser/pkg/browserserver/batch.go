@@ -0,0 +1,85 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// BatchStep is one tool call in a batch.
+type BatchStep struct {
+	Tool      string         `json:"tool" jsonschema:"Name of the tool to call"`
+	Arguments map[string]any `json:"arguments,omitempty" jsonschema:"Arguments for the tool"`
+}
+
+type BatchArgs struct {
+	Steps           []BatchStep `json:"steps" jsonschema:"Ordered tool calls to run"`
+	ContinueOnError bool        `json:"continue_on_error,omitempty" jsonschema:"Keep running the remaining steps after a step fails (default: false, stop at the first failure)"`
+}
+
+// BatchResult is the structured result of batch.
+type BatchResult struct {
+	Passed bool         `json:"passed"`
+	Steps  []StepResult `json:"steps"`
+	// Skipped is the number of steps not run because an earlier step failed.
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// Batch tool - runs an ordered list of tool calls in a single request
+func (s *CDPBrowserServer) Batch(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[BatchArgs]]) (*mcp.CallToolResultFor[BatchResult], error) {
+	args := req.Params.Arguments
+	if len(args.Steps) == 0 {
+		return &mcp.CallToolResultFor[BatchResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Error: batch requires at least one step"},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	result := BatchResult{Passed: true, Steps: []StepResult{}}
+	var output strings.Builder
+	for i, step := range args.Steps {
+		var sr StepResult
+		if step.Tool == "batch" {
+			sr = StepResult{Step: i + 1, Tool: step.Tool, Message: "batch cannot be nested"}
+		} else if stepArgs, err := json.Marshal(step.Arguments); err != nil {
+			sr = StepResult{Step: i + 1, Tool: step.Tool, Message: fmt.Sprintf("invalid arguments: %v", err)}
+		} else {
+			sr = s.invokeStep(ctx, i+1, step.Tool, stepArgs)
+		}
+		result.Steps = append(result.Steps, sr)
+
+		mark := "ok"
+		if !sr.OK {
+			mark = "FAIL"
+		}
+		output.WriteString(fmt.Sprintf("%d. [%s] %s: %s\n", sr.Step, mark, sr.Tool, sr.Message))
+
+		if !sr.OK {
+			result.Passed = false
+			if !args.ContinueOnError {
+				result.Skipped = len(args.Steps) - i - 1
+				break
+			}
+		}
+	}
+
+	status := "all steps succeeded"
+	if !result.Passed {
+		status = "some steps failed"
+		if result.Skipped > 0 {
+			status = fmt.Sprintf("stopped at step %d, %d steps skipped", len(result.Steps), result.Skipped)
+		}
+	}
+	return &mcp.CallToolResultFor[BatchResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Batch of %d steps: %s\n%s", len(args.Steps), status, output.String())},
+		},
+		StructuredContent: result,
+		IsError:           !result.Passed,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package browserserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func runBatch(t *testing.T, s *CDPBrowserServer, args BatchArgs) *mcp.CallToolResultFor[BatchResult] {
+	t.Helper()
+	res, err := s.Batch(context.Background(), &mcp.ServerRequest[*mcp.CallToolParamsFor[BatchArgs]]{
+		Params: &mcp.CallToolParamsFor[BatchArgs]{Arguments: args},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestBatch(t *testing.T) {
+	steps := []BatchStep{
+		{Tool: "type_text", Arguments: map[string]any{"selector": "#q", "text": "go"}},
+		{Tool: "click_button", Arguments: map[string]any{"selector": "#broken"}},
+		{Tool: "click_button", Arguments: map[string]any{"selector": "Search"}},
+	}
+
+	for _, tc := range []struct {
+		name            string
+		continueOnError bool
+		wantCalls       string
+		wantSteps       int
+		wantSkipped     int
+	}{
+		{"stop on error", false, "#q=go;click #broken", 2, 1},
+		{"continue on error", true, "#q=go;click #broken;click Search", 3, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			s := newMacroTestServer(t, &calls)
+			res := runBatch(t, s, BatchArgs{Steps: steps, ContinueOnError: tc.continueOnError})
+			if !res.IsError || res.StructuredContent.Passed {
+				t.Error("batch with a failing step passed")
+			}
+			if got := strings.Join(calls, ";"); got != tc.wantCalls {
+				t.Errorf("calls = %q, want %q", got, tc.wantCalls)
+			}
+			if got := res.StructuredContent; len(got.Steps) != tc.wantSteps || got.Skipped != tc.wantSkipped {
+				t.Errorf("result = %+v, want %d steps and %d skipped", got, tc.wantSteps, tc.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestBatchRejectsNesting(t *testing.T) {
+	var calls []string
+	s := newMacroTestServer(t, &calls)
+	res := runBatch(t, s, BatchArgs{Steps: []BatchStep{{Tool: "batch"}, {Tool: "nonexistent"}}, ContinueOnError: true})
+	if !res.IsError {
+		t.Fatal("batch succeeded, want error")
+	}
+	for i, want := range []string{"cannot be nested", "unknown tool"} {
+		if msg := res.StructuredContent.Steps[i].Message; !strings.Contains(msg, want) {
+			t.Errorf("step %d message = %q, want it to contain %q", i+1, msg, want)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package browserserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// bidiCommandTimeout bounds BiDi commands whose context has no deadline.
+const bidiCommandTimeout = 60 * time.Second
+
+// bidiMessage is a WebDriver BiDi command response, error, or event.
+type bidiMessage struct {
+	Type    string          `json:"type"`
+	ID      *int64          `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// bidiConn is a WebDriver BiDi connection. Commands may be sent
+// concurrently; events are passed to the handler registered for their
+// method, if any, and otherwise ignored.
+type bidiConn struct {
+	conn    net.Conn
+	rw      io.ReadWriter
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	nextID   int64
+	pending  map[int64]chan bidiMessage
+	handlers map[string]func(params json.RawMessage)
+	err      error // set when the connection fails
+}
+
+// dialBiDi opens a WebDriver BiDi connection to the WebSocket at urlstr.
+func dialBiDi(ctx context.Context, urlstr string) (*bidiConn, error) {
+	conn, br, _, err := ws.Dial(ctx, urlstr)
+	if err != nil {
+		return nil, err
+	}
+	c := &bidiConn{conn: conn, rw: conn, pending: make(map[int64]chan bidiMessage)}
+	if br != nil {
+		// The server sent data right after the handshake.
+		c.rw = struct {
+			io.Reader
+			io.Writer
+		}{io.MultiReader(br, conn), conn}
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// readLoop delivers command responses until the connection fails.
+func (c *bidiConn) readLoop() {
+	for {
+		data, err := wsutil.ReadServerText(c.rw)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		var msg bidiMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Printf("Ignoring malformed BiDi message: %v", err)
+			continue
+		}
+		if msg.ID == nil {
+			c.mu.Lock()
+			handle := c.handlers[msg.Method]
+			c.mu.Unlock()
+			if handle != nil {
+				// Handlers may send commands, whose responses this loop
+				// must be free to read.
+				go handle(msg.Params)
+			}
+			continue
+		}
+		c.mu.Lock()
+		ch := c.pending[*msg.ID]
+		delete(c.pending, *msg.ID)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- msg
+		}
+	}
+}
+
+// fail records err and fails all pending commands.
+func (c *bidiConn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = fmt.Errorf("BiDi connection closed: %v", err)
+	}
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
+
+// call sends a command and decodes its result into res, which may be nil.
+func (c *bidiConn) call(ctx context.Context, method string, params, res any) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, bidiCommandTimeout)
+		defer cancel()
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan bidiMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	data, err := json.Marshal(map[string]any{"id": id, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	err = wsutil.WriteClientText(c.conn, data)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.fail(err)
+		return fmt.Errorf("sending %s: %v", method, err)
+	}
+
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.err
+		}
+		if msg.Type == "error" {
+			return fmt.Errorf("%s: %s: %s", method, msg.Error, msg.Message)
+		}
+		if res == nil {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, res)
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return fmt.Errorf("%s: %v", method, ctx.Err())
+	}
+}
+
+// on registers handle to be called, in its own goroutine, for each event
+// with the given method.
+func (c *bidiConn) on(method string, handle func(params json.RawMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.handlers == nil {
+		c.handlers = make(map[string]func(json.RawMessage))
+	}
+	c.handlers[method] = handle
+}
+
+// Close closes the connection.
+func (c *bidiConn) Close() error {
+	return c.conn.Close()
+}
+
+// bidiBrowser is the WebDriver BiDi backend. It connects to the BiDi
+// WebSocket at -bidi-url, such as a Selenium Grid session or a browser
+// started with --remote-debugging-port, or launches Firefox if no URL is
+// given. It drives the first top-level browsing context of the session.
+type bidiBrowser struct {
+	s       *CDPBrowserServer
+	cmd     *exec.Cmd // the Firefox process, if the backend launched it
+	profile string    // the profile directory of that process
+	conn    *bidiConn
+	context string // ID of the browsing context the tools act on
+}
+
+func newBiDiBrowser(s *CDPBrowserServer) Browser {
+	return &bidiBrowser{s: s}
+}
+
+func (b *bidiBrowser) Name() string { return "bidi" }
+
+func (b *bidiBrowser) Launch() error {
+	wsURL := b.s.bidiURL
+	if wsURL == "" {
+		var err error
+		if wsURL, err = b.launchFirefox(); err != nil {
+			b.Close()
+			return fmt.Errorf("failed to launch Firefox: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	log.Printf("Connecting to WebDriver BiDi at %s", wsURL)
+	conn, err := dialBiDi(ctx, wsURL)
+	if err != nil {
+		b.Close()
+		return fmt.Errorf("failed to connect to WebDriver BiDi at %s: %v", wsURL, err)
+	}
+	b.conn = conn
+
+	// A bare /session endpoint has no session yet; URLs handed out by a
+	// WebDriver classic session or a grid already belong to one.
+	if u, err := url.Parse(wsURL); err == nil && strings.TrimSuffix(u.Path, "/") == "/session" {
+		var session struct {
+			SessionID    string `json:"sessionId"`
+			Capabilities struct {
+				BrowserName    string `json:"browserName"`
+				BrowserVersion string `json:"browserVersion"`
+			} `json:"capabilities"`
+		}
+		if err := conn.call(ctx, "session.new", map[string]any{"capabilities": map[string]any{}}, &session); err != nil {
+			b.Close()
+			return err
+		}
+		log.Printf("Started BiDi session %s with %s %s", session.SessionID, session.Capabilities.BrowserName, session.Capabilities.BrowserVersion)
+	}
+
+	var tree struct {
+		Contexts []struct {
+			Context string `json:"context"`
+		} `json:"contexts"`
+	}
+	if err := conn.call(ctx, "browsingContext.getTree", map[string]any{"maxDepth": 0}, &tree); err != nil {
+		b.Close()
+		return err
+	}
+	if len(tree.Contexts) > 0 {
+		b.context = tree.Contexts[0].Context
+	} else {
+		var created struct {
+			Context string `json:"context"`
+		}
+		if err := conn.call(ctx, "browsingContext.create", map[string]any{"type": "tab"}, &created); err != nil {
+			b.Close()
+			return err
+		}
+		b.context = created.Context
+	}
+	log.Printf("Driving browsing context %s over WebDriver BiDi", b.context)
+	if err := b.enablePolicyInterception(ctx); err != nil {
+		b.Close()
+		return fmt.Errorf("failed to enable navigation policy: %v", err)
+	}
+	return nil
+}
+
+// enablePolicyInterception is the BiDi counterpart of the CDP version in
+// policy.go: it intercepts every request of the browsing context and fails
+// those the navigation policy blocks.
+func (b *bidiBrowser) enablePolicyInterception(ctx context.Context) error {
+	if !b.s.policy.enabled() {
+		return nil
+	}
+	conn := b.conn
+	conn.on("network.beforeRequestSent", func(params json.RawMessage) {
+		var event struct {
+			IsBlocked bool `json:"isBlocked"`
+			Request   struct {
+				Request string `json:"request"`
+				URL     string `json:"url"`
+			} `json:"request"`
+		}
+		if err := json.Unmarshal(params, &event); err != nil || !event.IsBlocked {
+			return
+		}
+		method := "network.continueRequest"
+		if violation := b.s.policy.check(event.Request.URL); violation != nil {
+			log.Printf("Navigation policy: %v", violation)
+			method = "network.failRequest"
+		}
+		if err := conn.call(context.Background(), method, map[string]any{"request": event.Request.Request}, nil); err != nil {
+			log.Printf("Navigation policy: %v", err)
+		}
+	})
+	contexts := []string{b.context}
+	if err := conn.call(ctx, "session.subscribe", map[string]any{"events": []string{"network.beforeRequestSent"}, "contexts": contexts}, nil); err != nil {
+		return err
+	}
+	return conn.call(ctx, "network.addIntercept", map[string]any{"phases": []string{"beforeRequestSent"}, "contexts": contexts}, nil)
+}
+
+// launchFirefox starts Firefox with WebDriver BiDi enabled on the server's
+// debugging port and a fresh profile, and returns its session endpoint.
+func (b *bidiBrowser) launchFirefox() (string, error) {
+	path := os.Getenv("FIREFOX_PATH")
+	if path == "" {
+		var err error
+		if path, err = exec.LookPath("firefox"); err != nil {
+			return "", fmt.Errorf("firefox not found; set FIREFOX_PATH or pass -bidi-url")
+		}
+	}
+	profile, err := os.MkdirTemp("", "cdpbrowser-firefox-")
+	if err != nil {
+		return "", err
+	}
+	b.profile = profile
+	args := []string{
+		fmt.Sprintf("--remote-debugging-port=%d", b.s.chromePort),
+		"--profile", profile,
+		"--no-remote",
+		"--new-instance",
+	}
+	log.Printf("Launching Firefox: %s %s", path, strings.Join(args, " "))
+	cmd := exec.Command(path, args...)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	b.cmd = cmd
+
+	found := make(chan string, 1)
+	go func() {
+		pattern := regexp.MustCompile(`WebDriver BiDi listening on (ws://\S+)`)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			if m := pattern.FindStringSubmatch(scanner.Text()); m != nil {
+				found <- strings.TrimSuffix(m[1], "/") + "/session"
+				break
+			}
+		}
+		// Keep draining so that Firefox does not block on a full pipe.
+		io.Copy(io.Discard, stderr)
+	}()
+	select {
+	case wsURL := <-found:
+		return wsURL, nil
+	case <-time.After(30 * time.Second):
+		return "", fmt.Errorf("timeout waiting for the WebDriver BiDi URL")
+	}
+}
+
+func (b *bidiBrowser) Navigate(ctx context.Context, url string) error {
+	return b.conn.call(ctx, "browsingContext.navigate", map[string]any{"context": b.context, "url": url, "wait": "complete"}, nil)
+}
+
+func (b *bidiBrowser) Reload(ctx context.Context) error {
+	return b.conn.call(ctx, "browsingContext.reload", map[string]any{"context": b.context, "wait": "complete"}, nil)
+}
+
+// Evaluate serializes the value of expression with JSON.stringify in the
+// page, which avoids decoding BiDi's typed remote values.
+func (b *bidiBrowser) Evaluate(ctx context.Context, expression string, res any) error {
+	var result struct {
+		Type   string `json:"type"`
+		Result struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"result"`
+		ExceptionDetails struct {
+			Text string `json:"text"`
+		} `json:"exceptionDetails"`
+	}
+	params := map[string]any{
+		"expression":      fmt.Sprintf("(async () => JSON.stringify(await (%s)))()", expression),
+		"target":          map[string]any{"context": b.context},
+		"awaitPromise":    true,
+		"resultOwnership": "none",
+	}
+	if err := b.conn.call(ctx, "script.evaluate", params, &result); err != nil {
+		return err
+	}
+	if result.Type == "exception" {
+		return fmt.Errorf("evaluation failed: %s", result.ExceptionDetails.Text)
+	}
+	if res == nil || result.Result.Type != "string" {
+		return nil // undefined, or no result wanted
+	}
+	return json.Unmarshal([]byte(result.Result.Value), res)
+}
+
+func (b *bidiBrowser) Screenshot(ctx context.Context) ([]byte, error) {
+	var result struct {
+		Data []byte `json:"data"` // base64 in JSON
+	}
+	err := b.conn.call(ctx, "browsingContext.captureScreenshot", map[string]any{"context": b.context}, &result)
+	return result.Data, err
+}
+
+func (b *bidiBrowser) Close() {
+	if b.conn != nil {
+		if b.cmd != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			b.conn.call(ctx, "browser.close", map[string]any{}, nil)
+			cancel()
+		}
+		b.conn.Close()
+		b.conn = nil
+	}
+	if b.cmd != nil && b.cmd.Process != nil {
+		log.Println("Terminating Firefox process...")
+		b.cmd.Process.Kill()
+		b.cmd.Wait()
+		b.cmd = nil
+	}
+	if b.profile != "" {
+		os.RemoveAll(b.profile)
+		b.profile = ""
+	}
+}
@@ -0,0 +1,179 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+)
+
+// newFakeBiDiServer serves a WebDriver BiDi endpoint that answers commands
+// with canned results, sending an event before each response. It returns
+// the WebSocket URL of the endpoint and the methods it received.
+func newFakeBiDiServer(t *testing.T) (string, *[]string) {
+	t.Helper()
+	var methods []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			data, err := wsutil.ReadClientText(conn)
+			if err != nil {
+				return
+			}
+			var cmd struct {
+				ID     int64          `json:"id"`
+				Method string         `json:"method"`
+				Params map[string]any `json:"params"`
+			}
+			if err := json.Unmarshal(data, &cmd); err != nil {
+				t.Errorf("malformed command %s", data)
+				return
+			}
+			methods = append(methods, cmd.Method)
+			resp := map[string]any{"type": "success", "id": cmd.ID}
+			switch cmd.Method {
+			case "session.new":
+				resp["result"] = map[string]any{"sessionId": "s1", "capabilities": map[string]any{"browserName": "firefox"}}
+			case "browsingContext.getTree":
+				resp["result"] = map[string]any{"contexts": []any{map[string]any{"context": "ctx1"}}}
+			case "browsingContext.navigate":
+				if cmd.Params["context"] != "ctx1" {
+					t.Errorf("navigate context = %v", cmd.Params["context"])
+				}
+				if cmd.Params["url"] == "https://blocked.test/" {
+					resp = map[string]any{"type": "error", "id": cmd.ID, "error": "unknown error", "message": "NS_ERROR_UNKNOWN_HOST"}
+				} else {
+					resp["result"] = map[string]any{"navigation": "n1", "url": cmd.Params["url"]}
+				}
+			case "script.evaluate":
+				if !strings.HasPrefix(cmd.Params["expression"].(string), "(async () => JSON.stringify(await (document.title") {
+					t.Errorf("evaluate expression = %v", cmd.Params["expression"])
+				}
+				resp["result"] = map[string]any{"type": "success", "result": map[string]any{"type": "string", "value": `"Fixture"`}}
+			case "browsingContext.captureScreenshot":
+				resp["result"] = map[string]any{"data": "cG5n"} // "png"
+			default:
+				resp["result"] = map[string]any{}
+			}
+			event, _ := json.Marshal(map[string]any{"type": "event", "method": "log.entryAdded", "params": map[string]any{}})
+			reply, _ := json.Marshal(resp)
+			if wsutil.WriteServerText(conn, event) != nil || wsutil.WriteServerText(conn, reply) != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return "ws" + strings.TrimPrefix(ts.URL, "http") + "/session", &methods
+}
+
+func TestBiDiBrowser(t *testing.T) {
+	wsURL, methods := newFakeBiDiServer(t)
+	b := newBiDiBrowser(&CDPBrowserServer{bidiURL: wsURL})
+	if err := b.Launch(); err != nil {
+		t.Fatalf("Launch() = %v", err)
+	}
+	defer b.Close()
+
+	ctx := context.Background()
+	if err := b.Navigate(ctx, "https://example.com/"); err != nil {
+		t.Errorf("Navigate() = %v", err)
+	}
+	if err := b.Navigate(ctx, "https://blocked.test/"); err == nil || !strings.Contains(err.Error(), "NS_ERROR_UNKNOWN_HOST") {
+		t.Errorf("Navigate() to an unknown host = %v", err)
+	}
+	var title string
+	if err := b.Evaluate(ctx, "document.title", &title); err != nil || title != "Fixture" {
+		t.Errorf("Evaluate() = %q, %v", title, err)
+	}
+	if png, err := b.Screenshot(ctx); err != nil || string(png) != "png" {
+		t.Errorf("Screenshot() = %q, %v", png, err)
+	}
+
+	want := []string{"session.new", "browsingContext.getTree", "browsingContext.navigate", "browsingContext.navigate", "script.evaluate", "browsingContext.captureScreenshot"}
+	if !slices.Equal(*methods, want) {
+		t.Errorf("methods = %q, want %q", *methods, want)
+	}
+}
+
+func TestBiDiPolicyInterception(t *testing.T) {
+	decisions := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			data, err := wsutil.ReadClientText(conn)
+			if err != nil {
+				return
+			}
+			var cmd struct {
+				ID     int64          `json:"id"`
+				Method string         `json:"method"`
+				Params map[string]any `json:"params"`
+			}
+			if err := json.Unmarshal(data, &cmd); err != nil {
+				t.Errorf("malformed command %s", data)
+				return
+			}
+			resp := map[string]any{"type": "success", "id": cmd.ID, "result": map[string]any{}}
+			if cmd.Method == "browsingContext.getTree" {
+				resp["result"] = map[string]any{"contexts": []any{map[string]any{"context": "ctx1"}}}
+			}
+			messages := []map[string]any{resp}
+			switch cmd.Method {
+			case "network.addIntercept":
+				for id, url := range map[string]string{"r1": "https://evil.test/x.js", "r2": "https://example.com/"} {
+					messages = append(messages, map[string]any{"type": "event", "method": "network.beforeRequestSent", "params": map[string]any{
+						"context": "ctx1", "isBlocked": true, "request": map[string]any{"request": id, "url": url},
+					}})
+				}
+			case "network.failRequest", "network.continueRequest":
+				decisions <- cmd.Method + " " + cmd.Params["request"].(string)
+			}
+			for _, m := range messages {
+				data, _ := json.Marshal(m)
+				if wsutil.WriteServerText(conn, data) != nil {
+					return
+				}
+			}
+		}
+	}))
+	defer ts.Close()
+
+	s := &CDPBrowserServer{
+		bidiURL: "ws" + strings.TrimPrefix(ts.URL, "http") + "/session",
+		policy:  &navigationPolicy{DenyDomains: []string{"evil.test"}},
+	}
+	b := newBiDiBrowser(s)
+	if err := b.Launch(); err != nil {
+		t.Fatalf("Launch() = %v", err)
+	}
+	defer b.Close()
+
+	var got []string
+	for range 2 {
+		select {
+		case d := <-decisions:
+			got = append(got, d)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("decisions = %q, want one per intercepted request", got)
+		}
+	}
+	slices.Sort(got)
+	if want := []string{"network.continueRequest r2", "network.failRequest r1"}; !slices.Equal(got, want) {
+		t.Errorf("decisions = %q, want %q", got, want)
+	}
+}
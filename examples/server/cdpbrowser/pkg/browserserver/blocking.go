@@ -0,0 +1,141 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// hostPatterns returns Network.setBlockedURLs patterns blocking the URLs on
+// hosts, and their subdomains.
+func hostPatterns(hosts ...string) []string {
+	patterns := make([]string, 0, 2*len(hosts))
+	for _, host := range hosts {
+		patterns = append(patterns, "*://"+host+"/*", "*."+host+"/*")
+	}
+	return patterns
+}
+
+// extensionPatterns returns Network.setBlockedURLs patterns blocking the URLs
+// of files with extensions, with or without a query.
+func extensionPatterns(extensions ...string) []string {
+	patterns := make([]string, 0, 2*len(extensions))
+	for _, ext := range extensions {
+		patterns = append(patterns, "*."+ext, "*."+ext+"?*")
+	}
+	return patterns
+}
+
+// blockingPresets are the URL patterns set_blocking_preset blocks for each
+// preset. The host lists cover the largest networks, not every ad or
+// tracker, and the file lists match URLs by extension, so a resource served
+// without one still loads.
+var blockingPresets = map[string][]string{
+	"ads": hostPatterns(
+		"doubleclick.net", "googlesyndication.com", "googleadservices.com", "adservice.google.com",
+		"amazon-adsystem.com", "adnxs.com", "criteo.com", "criteo.net", "taboola.com", "outbrain.com",
+		"pubmatic.com", "rubiconproject.com", "openx.net", "casalemedia.com", "moatads.com", "adsrvr.org",
+	),
+	"analytics": hostPatterns(
+		"google-analytics.com", "googletagmanager.com", "analytics.google.com", "segment.io", "segment.com",
+		"mixpanel.com", "amplitude.com", "hotjar.com", "fullstory.com", "heapanalytics.com", "clarity.ms",
+		"scorecardresearch.com", "quantserve.com", "nr-data.net", "connect.facebook.net",
+	),
+	"images": extensionPatterns("png", "jpg", "jpeg", "gif", "webp", "avif", "svg", "ico", "bmp"),
+	"fonts": append(extensionPatterns("woff", "woff2", "ttf", "otf", "eot"),
+		hostPatterns("fonts.googleapis.com", "fonts.gstatic.com", "use.typekit.net")...),
+	"media": extensionPatterns("mp4", "webm", "ogg", "ogv", "mp3", "wav", "m4a", "aac", "flac", "mov", "m3u8", "mpd"),
+}
+
+// blockingPresetNames returns the names of the presets, sorted.
+func blockingPresetNames() []string {
+	names := make([]string, 0, len(blockingPresets))
+	for name := range blockingPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+type SetBlockingPresetArgs struct {
+	Presets  []string `json:"presets" jsonschema:"Kinds of requests to block: ads, analytics, images, fonts, and/or media. Replaces the current blocking; an empty list unblocks everything"`
+	Patterns []string `json:"patterns,omitempty" jsonschema:"More URL patterns to block, with * as a wildcard, such as *://cdn.example.com/*"`
+}
+
+// BlockingState is the structured result of set_blocking_preset.
+type BlockingState struct {
+	Presets  []string `json:"presets"`
+	Patterns []string `json:"patterns" jsonschema:"The custom patterns passed with the presets"`
+	// Blocked is the number of URL patterns blocked, including those of the
+	// presets.
+	Blocked int `json:"blocked"`
+}
+
+// blockedURLs returns the URL patterns of args, checking the presets.
+func (args *SetBlockingPresetArgs) blockedURLs() ([]string, error) {
+	var urls []string
+	for _, name := range args.Presets {
+		patterns, ok := blockingPresets[name]
+		if !ok {
+			return nil, invalidArgumentError{fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(blockingPresetNames(), ", "))}
+		}
+		urls = append(urls, patterns...)
+	}
+	for _, p := range args.Patterns {
+		if strings.TrimSpace(p) == "" {
+			return nil, invalidArgumentError{fmt.Errorf("empty URL pattern")}
+		}
+		urls = append(urls, p)
+	}
+	slices.Sort(urls)
+	return slices.Compact(urls), nil
+}
+
+// SetBlockingPreset tool - blocks requests for ads, trackers, or heavy
+// resources, so pages load faster for agents that only read their text
+func (s *CDPBrowserServer) SetBlockingPreset(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[SetBlockingPresetArgs]]) (*mcp.CallToolResultFor[BlockingState], error) {
+	args := req.Params.Arguments
+	urls, err := args.blockedURLs()
+	if err == nil {
+		// The list replaces the one of the previous call.
+		err = s.run(ctx, network.SetBlockedURLs(urls))
+	}
+	if err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[BlockingState]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error setting the blocked URLs: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	state := BlockingState{Presets: args.Presets, Patterns: args.Patterns, Blocked: len(urls)}
+	if state.Presets == nil {
+		state.Presets = []string{}
+	}
+	if state.Patterns == nil {
+		state.Patterns = []string{}
+	}
+	text := "No requests are blocked"
+	if len(urls) > 0 {
+		var blocked []string
+		if len(args.Presets) > 0 {
+			blocked = append(blocked, strings.Join(args.Presets, ", "))
+		}
+		if len(args.Patterns) > 0 {
+			blocked = append(blocked, fmt.Sprintf("%d custom patterns", len(args.Patterns)))
+		}
+		text = fmt.Sprintf("Blocking %s (%d URL patterns); takes effect for new requests, so reload the page to load it without them", strings.Join(blocked, " and "), len(urls))
+	}
+	return &mcp.CallToolResultFor[BlockingState]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+		StructuredContent: state,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package browserserver
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// blockedBy reports whether url matches one of the Network.setBlockedURLs
+// patterns, where * matches any run of characters.
+func blockedBy(patterns []string, url string) bool {
+	for _, p := range patterns {
+		re := "^" + strings.ReplaceAll(regexp.QuoteMeta(p), `\*`, ".*") + "$"
+		if regexp.MustCompile(re).MatchString(url) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBlockingPresets(t *testing.T) {
+	tests := []struct {
+		preset  string
+		blocked []string
+		allowed []string
+	}{
+		{"ads", []string{"https://securepubads.g.doubleclick.net/tag/js/gpt.js", "https://doubleclick.net/x"}, []string{"https://example.com/doubleclick.net.html"}},
+		{"analytics", []string{"https://www.googletagmanager.com/gtag/js?id=G-1", "https://cdn.segment.com/analytics.js/v1/k/analytics.min.js"}, []string{"https://shop.example/analytics"}},
+		{"images", []string{"https://shop.example/logo.png", "https://cdn.example/a/b.webp?w=200"}, []string{"https://shop.example/png-guide.html"}},
+		{"fonts", []string{"https://fonts.gstatic.com/s/roboto/v30/KFOmCnqEu92Fr1Mu4mxK.woff2", "https://shop.example/f.ttf"}, []string{"https://shop.example/fonts.html"}},
+		{"media", []string{"https://video.example/intro.mp4", "https://video.example/live.m3u8?token=1"}, []string{"https://video.example/watch?v=1"}},
+	}
+	for _, tt := range tests {
+		patterns := blockingPresets[tt.preset]
+		for _, url := range tt.blocked {
+			if !blockedBy(patterns, url) {
+				t.Errorf("%s does not block %s", tt.preset, url)
+			}
+		}
+		for _, url := range tt.allowed {
+			if blockedBy(patterns, url) {
+				t.Errorf("%s blocks %s", tt.preset, url)
+			}
+		}
+	}
+}
+
+func TestBlockedURLs(t *testing.T) {
+	args := SetBlockingPresetArgs{Presets: []string{"fonts", "fonts"}, Patterns: []string{"*://cdn.example.com/*"}}
+	urls, err := args.blockedURLs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != len(blockingPresets["fonts"])+1 || !slices.Contains(urls, "*://cdn.example.com/*") {
+		t.Errorf("blockedURLs = %v", urls)
+	}
+	if urls, err := (&SetBlockingPresetArgs{}).blockedURLs(); err != nil || len(urls) != 0 {
+		t.Errorf("no presets = %v, %v", urls, err)
+	}
+	for _, args := range []SetBlockingPresetArgs{{Presets: []string{"popups"}}, {Patterns: []string{" "}}} {
+		if _, err := args.blockedURLs(); err == nil {
+			t.Errorf("blockedURLs(%+v) succeeded", args)
+		}
+	}
+}
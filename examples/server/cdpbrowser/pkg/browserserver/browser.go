@@ -0,0 +1,215 @@
+package browserserver
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+)
+
+// A Browser is a browser automation backend, selected with -browser.
+//
+// The interface covers the operations every automation protocol offers.
+// Tools built on it work with any backend; the remaining tools drive Chrome
+// through chromedp and are listed as unavailable with other backends.
+// Programs embedding the server can add backends, for example for WebKit,
+// with [RegisterBrowserBackend].
+type Browser interface {
+	// Name returns the name the backend is registered under.
+	Name() string
+	// Launch starts or connects to the browser and opens a page.
+	Launch() error
+	// Navigate loads url in the page and waits for it to load.
+	Navigate(ctx context.Context, url string) error
+	// Reload reloads the page.
+	Reload(ctx context.Context) error
+	// Evaluate evaluates a JavaScript expression in the page and decodes its
+	// JSON value into res, which may be nil.
+	Evaluate(ctx context.Context, expression string, res any) error
+	// Screenshot captures the viewport as a PNG image.
+	Screenshot(ctx context.Context) ([]byte, error)
+	// Close disconnects from the browser, stopping it if the backend
+	// launched it and was not asked to keep it open.
+	Close()
+}
+
+// A BrowserBackend creates the Browser a server drives.
+type BrowserBackend func(s *CDPBrowserServer) Browser
+
+// defaultBrowserBackend is the backend used when -browser is not given.
+const defaultBrowserBackend = "chrome"
+
+var (
+	browserBackendsMu sync.RWMutex
+	browserBackends   = map[string]BrowserBackend{
+		"chrome": newChromeBrowser,
+		"bidi":   newBiDiBrowser,
+	}
+)
+
+// RegisterBrowserBackend makes a backend available under name, replacing
+// any backend previously registered with that name.
+func RegisterBrowserBackend(name string, b BrowserBackend) {
+	browserBackendsMu.Lock()
+	defer browserBackendsMu.Unlock()
+	browserBackends[name] = b
+}
+
+// lookupBrowserBackend returns the backend registered under name.
+func lookupBrowserBackend(name string) (BrowserBackend, bool) {
+	browserBackendsMu.RLock()
+	defer browserBackendsMu.RUnlock()
+	b, ok := browserBackends[name]
+	return b, ok
+}
+
+// browserBackendNames returns the sorted names of all registered backends.
+func browserBackendNames() []string {
+	browserBackendsMu.RLock()
+	defer browserBackendsMu.RUnlock()
+	names := make([]string, 0, len(browserBackends))
+	for name := range browserBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// backendTools are the tools that work with every backend: they either use
+// only the Browser interface or do not touch the browser at all. The
+// interaction tools and aria_snapshot act on the page with scripts when the
+// backend does not speak CDP; see pageactions.go.
+var backendTools = map[string]bool{
+	"navigate":            true,
+	"screenshot":          true,
+	"refresh_page":        true,
+	"click":               true,
+	"type_text":           true,
+	"click_button":        true,
+	"click_link":          true,
+	"select_dropdown":     true,
+	"choose_option":       true,
+	"aria_snapshot":       true,
+	"get_page_metadata":   true,
+	"detect_captcha":      true,
+	"save_page_state":     true,
+	"compare_page_state":  true,
+	"shutdown_server":     true,
+	"start_recording":     true,
+	"stop_recording":      true,
+	"replay_recording":    true,
+	"batch":               true,
+	"define_macro":        true,
+	"run_macro":           true,
+	"run_workflow":        true,
+	"compare_screenshots": true,
+	"ocr_screenshot":      true,
+	"get_tool_stats":      true,
+
+	"assert_url_matches":     true,
+	"assert_text_present":    true,
+	"assert_element_visible": true,
+	"assert_element_count":   true,
+}
+
+// backend returns the browser backend of s. Servers that were not given one,
+// such as those built directly in tests, drive Chrome through s.ctx.
+func (s *CDPBrowserServer) backend() Browser {
+	if s.browser != nil {
+		return s.browser
+	}
+	return &chromeBrowser{s}
+}
+
+// usesCDP reports whether s drives Chrome over CDP, which all tools outside
+// backendTools require.
+func (s *CDPBrowserServer) usesCDP() bool {
+	_, ok := s.backend().(*chromeBrowser)
+	return ok
+}
+
+// chromeBrowser is the chromedp backend. It keeps its state in the server,
+// whose chromedp context the CDP-only tools use directly.
+type chromeBrowser struct {
+	s *CDPBrowserServer
+}
+
+func newChromeBrowser(s *CDPBrowserServer) Browser {
+	return &chromeBrowser{s}
+}
+
+func (b *chromeBrowser) Name() string { return "chrome" }
+
+func (b *chromeBrowser) Launch() error {
+	s := b.s
+	// Kill any existing Chrome processes first
+	s.killExistingChromeProcesses()
+
+	// Default to launching a new Chrome instance
+	if err := s.launchNewChrome(); err != nil {
+		return err
+	}
+
+	// Check that the browser answers and report what it is
+	if err := s.logBrowserInfo(); err != nil {
+		return err
+	}
+
+	// Detect missing CDP domains so dependent tools can be disabled
+	if err := s.probeCapabilities(); err != nil {
+		log.Printf("Failed to probe browser capabilities, assuming full support: %v", err)
+	}
+
+	// Block requests to domains the navigation policy disallows
+	if err := s.enablePolicyInterception(s.ctx); err != nil {
+		return err
+	}
+	if s.policy.enabled() {
+		log.Printf("Navigation policy enabled: allow=%v deny=%v", s.policy.AllowDomains, s.policy.DenyDomains)
+	}
+
+	// Follow tabs, downloads, and dialogs for browser://state
+	if err := s.state.start(s.ctx, s.stateChanged); err != nil {
+		log.Printf("Failed to track the browser state, browser://state will only report the current page: %v", err)
+	}
+	// Collect uncaught exceptions for get_page_errors
+	s.pageErrors.start(s.ctx)
+	// Follow WebSocket connections for list_websockets
+	s.websockets.start(s.ctx)
+	// Forget the elements of a page once it navigates away
+	s.elementCache.start(s.ctx)
+	s.elements.start(s.ctx)
+	// Follow single-page app route changes for wait_for_route_change
+	if err := s.routes.start(s.ctx); err != nil {
+		log.Printf("Failed to hook the History API, route changes will only report their URL: %v", err)
+	}
+	return nil
+}
+
+func (b *chromeBrowser) Navigate(ctx context.Context, url string) error {
+	return b.s.run(ctx, chromedp.Navigate(url))
+}
+
+func (b *chromeBrowser) Reload(ctx context.Context) error {
+	return b.s.run(ctx, chromedp.Reload())
+}
+
+func (b *chromeBrowser) Evaluate(ctx context.Context, expression string, res any) error {
+	return b.s.run(ctx, chromedp.Evaluate(expression, res))
+}
+
+func (b *chromeBrowser) Screenshot(ctx context.Context) ([]byte, error) {
+	var buf []byte
+	err := b.s.run(ctx, chromedp.CaptureScreenshot(&buf))
+	return buf, err
+}
+
+func (b *chromeBrowser) Close() {
+	if b.s.keepChromeOpen {
+		b.s.detachChrome()
+		return
+	}
+	b.s.closeChrome()
+}
@@ -0,0 +1,149 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// fakeBrowser is a Browser backend that records the calls made to it.
+type fakeBrowser struct {
+	calls []string
+	// eval, if set, answers Evaluate calls.
+	eval func(expression string, res any) error
+}
+
+func (b *fakeBrowser) Name() string  { return "fake" }
+func (b *fakeBrowser) Launch() error { return nil }
+func (b *fakeBrowser) Close()        {}
+
+func (b *fakeBrowser) Navigate(ctx context.Context, url string) error {
+	b.calls = append(b.calls, "navigate "+url)
+	return nil
+}
+
+func (b *fakeBrowser) Reload(ctx context.Context) error {
+	b.calls = append(b.calls, "reload")
+	return nil
+}
+
+func (b *fakeBrowser) Evaluate(ctx context.Context, expression string, res any) error {
+	b.calls = append(b.calls, "evaluate")
+	if b.eval != nil {
+		return b.eval(expression, res)
+	}
+	if expression == detectCaptchasJS {
+		return json.Unmarshal([]byte(`[{"provider": "hCaptcha", "evidence": "div.h-captcha", "visible": true}]`), res)
+	}
+	return json.Unmarshal([]byte(`{"url": "https://example.com/", "title": "Example"}`), res)
+}
+
+func (b *fakeBrowser) Screenshot(ctx context.Context) ([]byte, error) {
+	b.calls = append(b.calls, "screenshot")
+	return []byte("png"), nil
+}
+
+func TestRegisterBrowserBackend(t *testing.T) {
+	fake := &fakeBrowser{}
+	RegisterBrowserBackend("fake", func(*CDPBrowserServer) Browser { return fake })
+	defer func() {
+		browserBackendsMu.Lock()
+		delete(browserBackends, "fake")
+		browserBackendsMu.Unlock()
+	}()
+	if names := browserBackendNames(); !slices.Equal(names, []string{"bidi", "chrome", "fake"}) {
+		t.Errorf("browserBackendNames() = %v", names)
+	}
+	backend, ok := lookupBrowserBackend("fake")
+	if !ok || backend(nil) != fake {
+		t.Errorf("lookupBrowserBackend(fake) = %v, %t", backend, ok)
+	}
+	if _, ok := lookupBrowserBackend("firefox"); ok {
+		t.Error("lookupBrowserBackend(firefox) found a backend")
+	}
+}
+
+func TestNonCDPBackendTools(t *testing.T) {
+	fake := &fakeBrowser{}
+	s := &CDPBrowserServer{browser: fake, stats: newToolStats(), policy: &navigationPolicy{}}
+	server := newMCPServer(s)
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	for _, call := range []mcp.CallToolParams{
+		{Name: "navigate", Arguments: map[string]any{"url": "https://example.com/"}},
+		{Name: "refresh_page"},
+		{Name: "screenshot"},
+		{Name: "get_page_metadata"},
+		{Name: "detect_captcha"},
+	} {
+		res, err := cs.CallTool(ctx, &call)
+		if err != nil || res.IsError {
+			t.Fatalf("CallTool(%s) = %+v, %v", call.Name, res, err)
+		}
+		if call.Name == "navigate" || call.Name == "detect_captcha" {
+			if text := res.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, "CAPTCHA (hCaptcha)") {
+				t.Errorf("%s result = %q, want a CAPTCHA warning", call.Name, text)
+			}
+		}
+	}
+	want := []string{"navigate https://example.com/", "evaluate", "reload", "screenshot", "evaluate", "evaluate"}
+	if !slices.Equal(fake.calls, want) {
+		t.Errorf("backend calls = %q, want %q", fake.calls, want)
+	}
+
+	res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "set_window_size", Arguments: map[string]any{"width": 800, "height": 600}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := res.Content[0].(*mcp.TextContent).Text; !res.IsError || !strings.Contains(text, "requires the chrome backend, not fake") {
+		t.Errorf("set_window_size with the fake backend = %q, want unavailable", text)
+	}
+}
+
+func TestChromeBrowserCloseKeepOpen(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep command to stand in for Chrome")
+	}
+	for _, keepOpen := range []bool{true, false} {
+		cmd := exec.Command(sleep, "30")
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		s := &CDPBrowserServer{ctx: ctx, cancel: cancel, chromeCmd: cmd, keepChromeOpen: keepOpen}
+		newChromeBrowser(s).Close()
+
+		if ctx.Err() == nil {
+			t.Errorf("keepChromeOpen=%t: Close left the CDP connection open", keepOpen)
+		}
+		if s.chromeCmd != nil {
+			t.Errorf("keepChromeOpen=%t: Close left chromeCmd set", keepOpen)
+		}
+		// Give a killed process time to be reaped.
+		time.Sleep(50 * time.Millisecond)
+		running := cmd.Process.Signal(syscall.Signal(0)) == nil
+		if running != keepOpen {
+			t.Errorf("keepChromeOpen=%t: Chrome running after Close = %t", keepOpen, running)
+		}
+		cmd.Process.Kill()
+	}
+}
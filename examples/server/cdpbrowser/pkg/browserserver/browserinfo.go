@@ -0,0 +1,155 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// browserProbeTimeout bounds the health probe of the browser.
+const browserProbeTimeout = 10 * time.Second
+
+// BrowserInfo is the structured result of browser_info.
+type BrowserInfo struct {
+	Product         string  `json:"product" jsonschema:"Browser name and version, e.g. HeadlessChrome/126.0.6478.126"`
+	ProtocolVersion string  `json:"protocol_version" jsonschema:"CDP version of the browser"`
+	UserAgent       string  `json:"user_agent"`
+	JSVersion       string  `json:"js_version" jsonschema:"V8 version"`
+	Headless        bool    `json:"headless"`
+	UserDataDir     string  `json:"user_data_dir,omitempty" jsonschema:"Profile directory of the browser, if known"`
+	Pages           int     `json:"pages" jsonschema:"Number of open page targets"`
+	Targets         int     `json:"targets" jsonschema:"Number of open targets of any type, including workers and extensions"`
+	PID             int     `json:"pid,omitempty" jsonschema:"Process id of the browser, if the server launched it"`
+	DevToolsURL     string  `json:"devtools_url,omitempty" jsonschema:"WebSocket URL the server connected to, if it launched the browser"`
+	LatencyMS       float64 `json:"latency_ms" jsonschema:"Round-trip time of the probe"`
+}
+
+// String formats the info for the text result of browser_info.
+func (i BrowserInfo) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "BROWSER: %s (protocol %s)\n", i.Product, i.ProtocolVersion)
+	mode := "headful"
+	if i.Headless {
+		mode = "headless"
+	}
+	fmt.Fprintf(&b, "MODE: %s\n", mode)
+	fmt.Fprintf(&b, "USER AGENT: %s\n", i.UserAgent)
+	fmt.Fprintf(&b, "V8: %s\n", i.JSVersion)
+	if i.UserDataDir != "" {
+		fmt.Fprintf(&b, "USER DATA DIR: %s\n", i.UserDataDir)
+	}
+	fmt.Fprintf(&b, "OPEN TARGETS: %d (%d pages)\n", i.Targets, i.Pages)
+	if i.PID != 0 {
+		fmt.Fprintf(&b, "PID: %d\n", i.PID)
+	}
+	if i.DevToolsURL != "" {
+		fmt.Fprintf(&b, "DEVTOOLS: %s\n", i.DevToolsURL)
+	}
+	fmt.Fprintf(&b, "PROBE LATENCY: %.1fms\n", i.LatencyMS)
+	return b.String()
+}
+
+// applyCommandLine fills in the headless mode and user data directory from
+// the command line of the browser.
+func (i *BrowserInfo) applyCommandLine(args []string) {
+	for _, arg := range args {
+		switch {
+		case arg == "--headless" || strings.HasPrefix(arg, "--headless="):
+			i.Headless = true
+		case strings.HasPrefix(arg, "--user-data-dir="):
+			i.UserDataDir = strings.TrimPrefix(arg, "--user-data-dir=")
+		}
+	}
+}
+
+// probeBrowser checks that the browser answers CDP commands and reports
+// what it is.
+func (s *CDPBrowserServer) probeBrowser() (BrowserInfo, error) {
+	var info BrowserInfo
+	ctx, cancel := context.WithTimeout(s.ctx, browserProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	var cmdline []string
+	var targets []*target.Info
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		info.ProtocolVersion, info.Product, _, info.UserAgent, info.JSVersion, err = browser.GetVersion().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get browser version: %v", err)
+		}
+		info.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+		if targets, err = target.GetTargets().Do(ctx); err != nil {
+			return fmt.Errorf("failed to list targets: %v", err)
+		}
+		// Chrome only reveals its command line when started with
+		// --enable-automation.
+		cmdline, _ = browser.GetBrowserCommandLine().Do(ctx)
+		return nil
+	}))
+	if err != nil {
+		return info, err
+	}
+
+	info.Targets = len(targets)
+	for _, t := range targets {
+		if t.Type == "page" {
+			info.Pages++
+		}
+	}
+	if s.chromeCmd != nil && s.chromeCmd.Process != nil {
+		info.PID = s.chromeCmd.Process.Pid
+		if cmdline == nil {
+			cmdline = s.chromeCmd.Args
+		}
+	}
+	info.applyCommandLine(cmdline)
+	info.Headless = info.Headless || strings.Contains(info.Product, "Headless")
+	info.DevToolsURL = s.wsURL
+	return info, nil
+}
+
+// logBrowserInfo probes the browser at startup and logs its report, so that
+// operators can diagnose a mismatched environment from the server log.
+func (s *CDPBrowserServer) logBrowserInfo() error {
+	info, err := s.probeBrowser()
+	if err != nil {
+		return fmt.Errorf("browser failed its health probe: %v", err)
+	}
+	mode := "headful"
+	if info.Headless {
+		mode = "headless"
+	}
+	log.Printf("Browser %s (protocol %s, %s), user data dir %q, %d open pages, probe took %.1fms",
+		info.Product, info.ProtocolVersion, mode, info.UserDataDir, info.Pages, info.LatencyMS)
+	return nil
+}
+
+// BrowserInfo tool - probes the browser and reports its version, mode,
+// profile directory, and open targets
+func (s *CDPBrowserServer) BrowserInfo(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[BrowserInfo], error) {
+	info, err := s.probeBrowser()
+	if err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[BrowserInfo]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error probing browser: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResultFor[BrowserInfo]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: info.String()},
+		},
+		StructuredContent: info,
+	}, nil
+}
@@ -0,0 +1,31 @@
+package browserserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBrowserInfoCommandLine(t *testing.T) {
+	var info BrowserInfo
+	info.applyCommandLine([]string{"/usr/bin/chromium", "--headless=new", "--user-data-dir=/tmp/profile", "--no-first-run"})
+	if !info.Headless || info.UserDataDir != "/tmp/profile" {
+		t.Errorf("applyCommandLine() = headless %t, user data dir %q", info.Headless, info.UserDataDir)
+	}
+
+	info = BrowserInfo{}
+	info.applyCommandLine([]string{"chrome", "--remote-debugging-port=9222", "--user-data-dir=/tmp/chrome-remote-profile"})
+	if info.Headless {
+		t.Error("applyCommandLine() without --headless = headless")
+	}
+
+	info = BrowserInfo{Product: "Chrome/126.0.6478.126", ProtocolVersion: "1.3", UserDataDir: "/tmp/chrome-remote-profile", Pages: 1, Targets: 3, PID: 42}
+	text := info.String()
+	for _, want := range []string{"BROWSER: Chrome/126.0.6478.126 (protocol 1.3)\n", "MODE: headful\n", "USER DATA DIR: /tmp/chrome-remote-profile\n", "OPEN TARGETS: 3 (1 pages)\n", "PID: 42\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("String() = %q, want it to contain %q", text, want)
+		}
+	}
+	if strings.Contains(text, "DEVTOOLS") {
+		t.Errorf("String() without a DevTools URL = %q", text)
+	}
+}
@@ -0,0 +1,421 @@
+package browserserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// screenshotsTemplate is the URI template of the screenshots taken by
+	// bulk_screenshot and compare_screenshots.
+	screenshotsTemplate = "browser://screenshots/{id}"
+	// maxBulkScreenshots is both the number of URLs bulk_screenshot captures
+	// per call and the number of screenshots kept; older ones are dropped.
+	maxBulkScreenshots = 100
+	// defaultViewportWidth and defaultViewportHeight are the default viewport
+	// of bulk_screenshot, in CSS pixels.
+	defaultViewportWidth  = 1280
+	defaultViewportHeight = 800
+	// maxViewportSize bounds the viewport width and height.
+	maxViewportSize = 8192
+	// maxDeviceScaleFactor bounds device_scale_factor.
+	maxDeviceScaleFactor = 4
+	// maxSitemapBytes bounds the size of a sitemap, after decompression.
+	maxSitemapBytes = 50 << 20
+	// sitemapTimeout bounds fetching one sitemap.
+	sitemapTimeout = 30 * time.Second
+	// bulkScreenshotTimeout bounds loading and capturing one page.
+	bulkScreenshotTimeout = 30 * time.Second
+)
+
+// screenshotStore keeps the most recent screenshots of bulk_screenshot and
+// compare_screenshots for the browser://screenshots resources.
+type screenshotStore struct {
+	mu     sync.Mutex
+	nextID int
+	pngs   map[int][]byte
+}
+
+// add stores a screenshot and returns its URI, dropping the oldest one when
+// the store is full.
+func (st *screenshotStore) add(png []byte) string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.pngs == nil {
+		st.pngs = make(map[int][]byte)
+	}
+	st.nextID++
+	st.pngs[st.nextID] = png
+	delete(st.pngs, st.nextID-maxBulkScreenshots)
+	return strings.Replace(screenshotsTemplate, "{id}", strconv.Itoa(st.nextID), 1)
+}
+
+// get returns the screenshot stored under uri.
+func (st *screenshotStore) get(uri string) ([]byte, bool) {
+	id, err := strconv.Atoi(strings.TrimPrefix(uri, "browser://screenshots/"))
+	if err != nil {
+		return nil, false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	png, ok := st.pngs[id]
+	return png, ok
+}
+
+// readScreenshot serves the browser://screenshots resources.
+func (s *CDPBrowserServer) readScreenshot(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	png, ok := s.screenshots.get(uri)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: uri, MIMEType: "image/png", Blob: png},
+		},
+	}, nil
+}
+
+// sitemapLocation is a <url> or <sitemap> entry of a sitemap.
+type sitemapLocation struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemap is a sitemap.xml: either a urlset listing pages or a sitemap index
+// listing other sitemaps.
+type sitemap struct {
+	URLs     []sitemapLocation `xml:"url"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+// parseSitemap parses a sitemap, which may be gzipped.
+func parseSitemap(data []byte) (*sitemap, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(io.LimitReader(zr, maxSitemapBytes)); err != nil {
+			return nil, err
+		}
+	}
+	var sm sitemap
+	if err := xml.Unmarshal(data, &sm); err != nil {
+		return nil, fmt.Errorf("invalid sitemap: %v", err)
+	}
+	return &sm, nil
+}
+
+// fetchSitemap downloads and parses the sitemap at url, if the navigation
+// policy allows it.
+func (s *CDPBrowserServer) fetchSitemap(ctx context.Context, url string) (*sitemap, error) {
+	if err := s.policy.check(url); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, sitemapTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, invalidArgumentError{fmt.Errorf("invalid sitemap URL %q", url)}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching sitemap %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSitemapBytes))
+	if err != nil {
+		return nil, fmt.Errorf("fetching sitemap %s: %v", url, err)
+	}
+	return parseSitemap(data)
+}
+
+// sitemapURLs returns the page URLs of the sitemap at url. The sitemaps of a
+// sitemap index are fetched in order until more than limit URLs are known.
+func (s *CDPBrowserServer) sitemapURLs(ctx context.Context, url string, limit int) ([]string, error) {
+	sm, err := s.fetchSitemap(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, u := range sm.URLs {
+		urls = append(urls, strings.TrimSpace(u.Loc))
+	}
+	for _, child := range sm.Sitemaps {
+		if len(urls) > limit {
+			break
+		}
+		childURL := strings.TrimSpace(child.Loc)
+		csm, err := s.fetchSitemap(ctx, childURL)
+		if err != nil {
+			return nil, fmt.Errorf("sitemap %s: %v", childURL, err)
+		}
+		// Sitemap indexes do not nest, so the sitemaps of csm are ignored.
+		for _, u := range csm.URLs {
+			urls = append(urls, strings.TrimSpace(u.Loc))
+		}
+	}
+	return urls, nil
+}
+
+// screenshotCapturer loads url in a worker's tab and captures it as a PNG.
+type screenshotCapturer func(ctx context.Context, url string) ([]byte, error)
+
+// BulkScreenshot is the screenshot of one page taken by bulk_screenshot.
+type BulkScreenshot struct {
+	URL   string `json:"url"`
+	URI   string `json:"uri,omitempty" jsonschema:"browser://screenshots resource holding the PNG"`
+	Error string `json:"error,omitempty"`
+}
+
+// captureScreenshots captures urls with one goroutine per capturer, storing
+// each screenshot with store. done, if set, is called after each page with
+// the number of pages finished.
+func captureScreenshots(ctx context.Context, urls []string, capturers []screenshotCapturer, store func([]byte) string, done func(shot *BulkScreenshot, finished int)) []BulkScreenshot {
+	shots := make([]BulkScreenshot, len(urls))
+	jobs := make(chan int)
+	var mu sync.Mutex
+	finished := 0
+	var wg sync.WaitGroup
+	for _, capture := range capturers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				shot := &shots[i]
+				shot.URL = urls[i]
+				if png, err := capture(ctx, urls[i]); err != nil {
+					shot.Error = err.Error()
+				} else {
+					shot.URI = store(png)
+				}
+				mu.Lock()
+				finished++
+				if done != nil {
+					done(shot, finished)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range urls {
+		if ctx.Err() != nil {
+			shots[i] = BulkScreenshot{URL: urls[i], Error: ctx.Err().Error()}
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return shots
+}
+
+type BulkScreenshotArgs struct {
+	URLs              []string `json:"urls,omitempty" jsonschema:"Pages to capture"`
+	Sitemap           string   `json:"sitemap,omitempty" jsonschema:"URL of a sitemap.xml or sitemap index listing the pages to capture, instead of urls"`
+	Width             int      `json:"width,omitempty" jsonschema:"Viewport width in CSS pixels (default: 1280)"`
+	Height            int      `json:"height,omitempty" jsonschema:"Viewport height in CSS pixels (default: 800)"`
+	DeviceScaleFactor float64  `json:"device_scale_factor,omitempty" jsonschema:"Device pixels per CSS pixel, up to 4 (default: 1)"`
+	Mobile            bool     `json:"mobile,omitempty" jsonschema:"Emulate a mobile device, so that the page's meta viewport applies (default: false)"`
+	FullPage          bool     `json:"full_page,omitempty" jsonschema:"Capture the whole page instead of the viewport (default: false)"`
+	Workers           int      `json:"workers,omitempty" jsonschema:"Number of tabs capturing in parallel, up to 16 (default: 4)"`
+}
+
+// viewport checks the viewport arguments and applies their defaults.
+func (args *BulkScreenshotArgs) viewport() error {
+	if args.Width == 0 {
+		args.Width = defaultViewportWidth
+	}
+	if args.Height == 0 {
+		args.Height = defaultViewportHeight
+	}
+	if args.DeviceScaleFactor == 0 {
+		args.DeviceScaleFactor = 1
+	}
+	if args.Width < 0 || args.Width > maxViewportSize || args.Height < 0 || args.Height > maxViewportSize {
+		return fmt.Errorf("viewport %dx%d is not between 1x1 and %dx%d", args.Width, args.Height, maxViewportSize, maxViewportSize)
+	}
+	if args.DeviceScaleFactor < 0 || args.DeviceScaleFactor > maxDeviceScaleFactor {
+		return fmt.Errorf("device_scale_factor %v is not between 0 and %d", args.DeviceScaleFactor, maxDeviceScaleFactor)
+	}
+	return nil
+}
+
+// BulkScreenshotResult is the structured result of bulk_screenshot.
+type BulkScreenshotResult struct {
+	Viewport    string           `json:"viewport" jsonschema:"Viewport the pages were captured in, e.g. 1280x800@2x mobile"`
+	Captured    int              `json:"captured"`
+	Failed      int              `json:"failed"`
+	Skipped     int              `json:"skipped,omitempty" jsonschema:"Number of URLs beyond the limit of 100 per call that were not captured"`
+	Screenshots []BulkScreenshot `json:"screenshots"`
+}
+
+// String formats the summary and the screenshots for the text result of
+// bulk_screenshot.
+func (r *BulkScreenshotResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Captured %d of %d pages at %s", r.Captured, len(r.Screenshots), r.Viewport)
+	if r.Skipped > 0 {
+		fmt.Fprintf(&b, " (%d more URLs skipped; at most %d per call)", r.Skipped, maxBulkScreenshots)
+	}
+	b.WriteString("\n")
+	for _, shot := range r.Screenshots {
+		if shot.Error != "" {
+			fmt.Fprintf(&b, "- %s FAILED: %s\n", shot.URL, shot.Error)
+		} else {
+			fmt.Fprintf(&b, "- %s: %s\n", shot.URL, shot.URI)
+		}
+	}
+	return b.String()
+}
+
+// bulkScreenshotURLs returns the pages args select, without duplicates, and
+// the number of pages beyond the per-call limit.
+func (s *CDPBrowserServer) bulkScreenshotURLs(ctx context.Context, args *BulkScreenshotArgs) ([]string, int, error) {
+	if (len(args.URLs) == 0) == (args.Sitemap == "") {
+		return nil, 0, invalidArgumentError{errors.New("give either urls or sitemap")}
+	}
+	urls := args.URLs
+	if args.Sitemap != "" {
+		var err error
+		if urls, err = s.sitemapURLs(ctx, args.Sitemap, maxBulkScreenshots); err != nil {
+			return nil, 0, err
+		}
+		if len(urls) == 0 {
+			return nil, 0, fmt.Errorf("sitemap %s lists no pages", args.Sitemap)
+		}
+	}
+	seen := make(map[string]bool)
+	var unique []string
+	for _, u := range urls {
+		if u != "" && !seen[u] {
+			seen[u] = true
+			unique = append(unique, u)
+		}
+	}
+	if len(unique) > maxBulkScreenshots {
+		return unique[:maxBulkScreenshots], len(unique) - maxBulkScreenshots, nil
+	}
+	return unique, 0, nil
+}
+
+// screenshotCapturer returns a screenshotCapturer that loads pages in the
+// tab of tabCtx, which must already emulate the viewport.
+func (s *CDPBrowserServer) screenshotCapturer(tabCtx context.Context, fullPage bool) screenshotCapturer {
+	return func(ctx context.Context, url string) ([]byte, error) {
+		if err := s.policy.check(url); err != nil {
+			return nil, err
+		}
+		if err := s.rateLimiter.step(ctx, "navigate"); err != nil {
+			return nil, err
+		}
+		var png []byte
+		capture := chromedp.CaptureScreenshot(&png)
+		if fullPage {
+			capture = chromedp.FullScreenshot(&png, 100)
+		}
+		if err := runInTab(ctx, tabCtx, bulkScreenshotTimeout, chromedp.Navigate(url), capture); err != nil {
+			return nil, err
+		}
+		return png, nil
+	}
+}
+
+// BulkScreenshot tool - captures screenshots of many pages in parallel tabs
+func (s *CDPBrowserServer) BulkScreenshot(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[BulkScreenshotArgs]]) (*mcp.CallToolResultFor[BulkScreenshotResult], error) {
+	args := req.Params.Arguments
+	fail := func(err error) (*mcp.CallToolResultFor[BulkScreenshotResult], error) {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[BulkScreenshotResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error taking screenshots: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	if err := args.viewport(); err != nil {
+		return fail(invalidArgumentError{err})
+	}
+	urls, skipped, err := s.bulkScreenshotURLs(ctx, &args)
+	if err != nil {
+		return fail(err)
+	}
+
+	tabs, closeTabs, err := s.openWorkerTabs(min(workerTabs(args.Workers), len(urls)))
+	if err != nil {
+		return fail(err)
+	}
+	defer closeTabs()
+	var capturers []screenshotCapturer
+	for _, tab := range tabs {
+		metrics := emulation.SetDeviceMetricsOverride(int64(args.Width), int64(args.Height), args.DeviceScaleFactor, args.Mobile)
+		if err := chromedp.Run(tab, metrics); err != nil {
+			return fail(fmt.Errorf("failed to set the viewport: %v", err))
+		}
+		capturers = append(capturers, s.screenshotCapturer(tab, args.FullPage))
+	}
+
+	var progress func(*BulkScreenshot, int)
+	if token := req.Params.GetProgressToken(); token != nil && req.Session != nil {
+		progress = func(shot *BulkScreenshot, finished int) {
+			err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+				ProgressToken: token,
+				Progress:      float64(finished),
+				Total:         float64(len(urls)),
+				Message:       fmt.Sprintf("Captured %d of %d pages: %s", finished, len(urls), shot.URL),
+			})
+			if err != nil {
+				log.Printf("Failed to report progress: %v", err)
+			}
+		}
+	}
+
+	result := BulkScreenshotResult{
+		Viewport:    fmt.Sprintf("%dx%d", args.Width, args.Height),
+		Skipped:     skipped,
+		Screenshots: captureScreenshots(ctx, urls, capturers, s.screenshots.add, progress),
+	}
+	if args.DeviceScaleFactor != 1 {
+		result.Viewport += fmt.Sprintf("@%vx", args.DeviceScaleFactor)
+	}
+	if args.Mobile {
+		result.Viewport += " mobile"
+	}
+	content := []mcp.Content{nil}
+	for _, shot := range result.Screenshots {
+		if shot.Error != "" {
+			result.Failed++
+			continue
+		}
+		result.Captured++
+		content = append(content, &mcp.ResourceLink{
+			URI:      shot.URI,
+			Name:     shot.URL,
+			MIMEType: "image/png",
+		})
+	}
+	content[0] = &mcp.TextContent{Text: result.String()}
+	return &mcp.CallToolResultFor[BulkScreenshotResult]{
+		Content:           content,
+		StructuredContent: result,
+		IsError:           result.Captured == 0,
+	}, nil
+}
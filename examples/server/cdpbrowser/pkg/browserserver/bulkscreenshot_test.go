@@ -0,0 +1,169 @@
+package browserserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newSitemapServer serves a sitemap index at /sitemap.xml that lists
+// /pages.xml and the gzipped /more.xml.gz.
+func newSitemapServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	fmt.Fprint(zw, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://shop.example/c</loc></url></urlset>`)
+	zw.Close()
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/pages.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/more.xml.gz</loc></sitemap>
+</sitemapindex>`, ts.URL)
+		case "/pages.xml":
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc> https://shop.example/a </loc><lastmod>2026-01-01</lastmod></url>
+  <url><loc>https://shop.example/b</loc></url>
+</urlset>`)
+		case "/more.xml.gz":
+			w.Write(gz.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestSitemapURLs(t *testing.T) {
+	ts := newSitemapServer(t)
+	s := &CDPBrowserServer{policy: &navigationPolicy{}}
+	ctx := context.Background()
+
+	urls, err := s.sitemapURLs(ctx, ts.URL+"/sitemap.xml", maxBulkScreenshots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://shop.example/a", "https://shop.example/b", "https://shop.example/c"}; !slices.Equal(urls, want) {
+		t.Errorf("sitemap URLs = %q, want %q", urls, want)
+	}
+	// Once the limit is exceeded, the remaining sitemaps are not fetched.
+	if urls, err := s.sitemapURLs(ctx, ts.URL+"/sitemap.xml", 1); err != nil || len(urls) != 2 {
+		t.Errorf("limited sitemap URLs = %q, %v", urls, err)
+	}
+
+	if _, err := s.sitemapURLs(ctx, ts.URL+"/missing.xml", maxBulkScreenshots); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("missing sitemap error = %v", err)
+	}
+	s.policy = &navigationPolicy{DenyDomains: []string{"127.0.0.1"}}
+	if _, err := s.sitemapURLs(ctx, ts.URL+"/sitemap.xml", maxBulkScreenshots); err == nil {
+		t.Error("sitemap fetched from a blocked domain")
+	}
+}
+
+func TestBulkScreenshotURLs(t *testing.T) {
+	s := &CDPBrowserServer{policy: &navigationPolicy{}}
+	ctx := context.Background()
+	urls := []string{"https://shop.example/a", "https://shop.example/b", "https://shop.example/a"}
+	for i := range maxBulkScreenshots {
+		urls = append(urls, fmt.Sprintf("https://shop.example/p/%d", i))
+	}
+	got, skipped, err := s.bulkScreenshotURLs(ctx, &BulkScreenshotArgs{URLs: urls})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != maxBulkScreenshots || skipped != 2 || got[1] != "https://shop.example/b" || got[2] != "https://shop.example/p/0" {
+		t.Errorf("URLs = %d, skipped %d, first %q", len(got), skipped, got[:3])
+	}
+
+	for _, args := range []BulkScreenshotArgs{
+		{},
+		{URLs: []string{"https://shop.example/"}, Sitemap: "https://shop.example/sitemap.xml"},
+	} {
+		if _, _, err := s.bulkScreenshotURLs(ctx, &args); err == nil {
+			t.Errorf("bulkScreenshotURLs(%+v) succeeded", args)
+		}
+	}
+}
+
+func TestBulkScreenshotViewport(t *testing.T) {
+	args := BulkScreenshotArgs{}
+	if err := args.viewport(); err != nil || args.Width != 1280 || args.Height != 800 || args.DeviceScaleFactor != 1 {
+		t.Errorf("default viewport = %dx%d@%v, %v", args.Width, args.Height, args.DeviceScaleFactor, err)
+	}
+	for _, args := range []BulkScreenshotArgs{
+		{Width: -1},
+		{Height: maxViewportSize + 1},
+		{DeviceScaleFactor: 5},
+	} {
+		if err := args.viewport(); err == nil {
+			t.Errorf("viewport(%+v) succeeded", args)
+		}
+	}
+}
+
+func TestCaptureScreenshots(t *testing.T) {
+	s := &CDPBrowserServer{}
+	var capturers []screenshotCapturer
+	for i := range 3 {
+		capturers = append(capturers, func(ctx context.Context, url string) ([]byte, error) {
+			if strings.HasSuffix(url, "/broken") {
+				return nil, errors.New("net::ERR_CONNECTION_REFUSED")
+			}
+			return []byte(fmt.Sprintf("png of %s by %d", url, i)), nil
+		})
+	}
+	urls := []string{"https://shop.example/a", "https://shop.example/broken", "https://shop.example/b", "https://shop.example/c"}
+	var finished []int
+	shots := captureScreenshots(context.Background(), urls, capturers, s.screenshots.add, func(shot *BulkScreenshot, n int) {
+		finished = append(finished, n)
+	})
+	if !slices.Equal(finished, []int{1, 2, 3, 4}) {
+		t.Errorf("progress = %v", finished)
+	}
+	for i, shot := range shots {
+		if shot.URL != urls[i] {
+			t.Errorf("screenshot %d is of %s, want %s", i, shot.URL, urls[i])
+		}
+		if (shot.Error != "") != (i == 1) {
+			t.Errorf("screenshot %d = %+v", i, shot)
+		}
+	}
+
+	res, err := s.readScreenshot(context.Background(), &mcp.ServerRequest[*mcp.ReadResourceParams]{Params: &mcp.ReadResourceParams{URI: shots[3].URI}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(res.Contents[0].Blob), "png of https://shop.example/c") || res.Contents[0].MIMEType != "image/png" {
+		t.Errorf("screenshot resource = %+v", res.Contents[0])
+	}
+	if _, err := s.readScreenshot(context.Background(), &mcp.ServerRequest[*mcp.ReadResourceParams]{Params: &mcp.ReadResourceParams{URI: "browser://screenshots/99"}}); err == nil {
+		t.Error("unknown screenshot read")
+	}
+
+	result := BulkScreenshotResult{Viewport: "390x844@3x mobile", Captured: 3, Failed: 1, Skipped: 5, Screenshots: shots}
+	text := result.String()
+	for _, line := range []string{
+		"Captured 3 of 4 pages at 390x844@3x mobile (5 more URLs skipped; at most 100 per call)\n",
+		"- https://shop.example/a: browser://screenshots/",
+		"- https://shop.example/broken FAILED: net::ERR_CONNECTION_REFUSED\n",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("report lacks %q:\n%s", line, text)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/chromedp/cdproto"
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// cdpMethodNotFound is the JSON-RPC error code Chrome returns for commands
+// it does not implement.
+const cdpMethodNotFound = -32601
+
+// toolDomains lists the CDP domains each tool depends on. Tools that are not
+// listed only need the domains every target supports.
+var toolDomains = map[string][]string{
+	"navigate":                 {"Page"},
+	"click":                    {"DOM", "Input"},
+	"screenshot":               {"Page"},
+	"aria_snapshot":            {"Accessibility", "DOMSnapshot"},
+	"aria_diff":                {"Accessibility", "DOMSnapshot"},
+	"find_text":                {"Runtime"},
+	"find_element":             {"Accessibility", "DOMSnapshot"},
+	"get_page_metadata":        {"Runtime"},
+	"save_page_state":          {"Runtime"},
+	"compare_page_state":       {"Runtime"},
+	"assert_url_matches":       {"Runtime"},
+	"wait_for_route_change":    {"Page", "Runtime"},
+	"assert_text_present":      {"Runtime"},
+	"assert_element_visible":   {"Runtime"},
+	"assert_element_count":     {"Runtime"},
+	"detect_captcha":           {"Runtime"},
+	"annotated_screenshot":     {"Page", "Runtime"},
+	"capture_long_page":        {"DOM", "Page", "Runtime"},
+	"click_element_by_id":      {"DOM", "Input"},
+	"click_at":                 {"DOM", "Input", "Page"},
+	"move_mouse":               {"DOM", "Input", "Page"},
+	"swipe":                    {"DOM", "Input", "Page"},
+	"type_into_element_by_id":  {"DOM", "Input"},
+	"screenshot_element_by_id": {"DOM", "Page"},
+	"type_text":                {"DOM", "Input"},
+	"click_button":             {"DOM", "Input"},
+	"click_link":               {"DOM", "Input"},
+	"select_dropdown":          {"DOM"},
+	"choose_option":            {"DOM"},
+	"refresh_page":             {"Page"},
+	"set_window_size":          {"Browser"},
+	"maximize":                 {"Browser"},
+	"minimize":                 {"Browser"},
+	"bring_to_front":           {"Browser", "Page"},
+	"emulate_media":            {"Emulation", "Runtime"},
+	"set_zoom":                 {"Emulation", "Page", "Runtime"},
+	"set_blocking_preset":      {"Network"},
+	"highlight_element":        {"DOM", "Runtime"},
+	"login_with_credentials":   {"DOM", "Input", "Runtime"},
+	"browser_info":             {"Browser"},
+	"crawl":                    {"Page", "Runtime"},
+	"bulk_screenshot":          {"Page", "Emulation"},
+	"compare_screenshots":      {"Page", "Runtime"},
+	"ocr_screenshot":           {"Page", "Runtime"},
+	"start_coverage":           {"Profiler", "CSS"},
+	"stop_coverage":            {"Profiler", "CSS"},
+	"get_page_errors":          {"Runtime"},
+	"list_websockets":          {"Network"},
+	"start_websocket_capture":  {"Network"},
+	"get_websocket_frames":     {"Network"},
+	"stop_websocket_capture":   {"Network"},
+}
+
+// domainProbes are side-effect free commands used to detect support for a
+// domain when the browser does not implement Schema.getDomains.
+var domainProbes = map[string]string{
+	"Page":          "Page.getFrameTree",
+	"DOM":           "DOM.getDocument",
+	"Runtime":       "Runtime.getIsolateId",
+	"Accessibility": "Accessibility.getRootAXNode",
+	"DOMSnapshot":   "DOMSnapshot.disable",
+	"Browser":       "Browser.getVersion",
+	"Emulation":     "Emulation.canEmulate",
+}
+
+// browserCapabilities describes what the connected browser supports.
+type browserCapabilities struct {
+	Product         string   `json:"product"`
+	ProtocolVersion string   `json:"protocolVersion"`
+	UserAgent       string   `json:"userAgent"`
+	JSVersion       string   `json:"jsVersion"`
+	Domains         []string `json:"domains,omitempty"`
+	MissingDomains  []string `json:"missingDomains,omitempty"`
+	DisabledTools   []string `json:"disabledTools,omitempty"`
+
+	supported map[string]bool
+}
+
+// missingDomainsFor returns the domains required by the named tool that the
+// browser does not support.
+func (c *browserCapabilities) missingDomainsFor(tool string) []string {
+	if c == nil || c.supported == nil {
+		return nil
+	}
+	var missing []string
+	for _, d := range toolDomains[tool] {
+		if !c.supported[d] {
+			missing = append(missing, d)
+		}
+	}
+	return missing
+}
+
+// isMethodNotFound reports whether err is Chrome's "method not found" error.
+func isMethodNotFound(err error) bool {
+	var cdpErr *cdproto.Error
+	return errors.As(err, &cdpErr) && cdpErr.Code == cdpMethodNotFound
+}
+
+// probeCapabilities detects the browser version and the CDP domains it
+// implements, so that tools depending on missing domains can be disabled
+// up front instead of failing cryptically at call time.
+func (s *CDPBrowserServer) probeCapabilities() error {
+	caps := &browserCapabilities{supported: make(map[string]bool)}
+
+	err := chromedp.Run(s.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		caps.ProtocolVersion, caps.Product, _, caps.UserAgent, caps.JSVersion, err = browser.GetVersion().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get browser version: %v", err)
+		}
+
+		var res struct {
+			Domains []struct {
+				Name string `json:"name"`
+			} `json:"domains"`
+		}
+		err = cdp.Execute(ctx, "Schema.getDomains", nil, &res)
+		if err == nil {
+			for _, d := range res.Domains {
+				caps.supported[d.Name] = true
+			}
+			return nil
+		}
+		if !isMethodNotFound(err) {
+			return fmt.Errorf("failed to list CDP domains: %v", err)
+		}
+
+		// Schema.getDomains is deprecated; fall back to probing each domain.
+		log.Println("Schema.getDomains unsupported, probing CDP domains individually")
+		for domain, method := range domainProbes {
+			if err := cdp.Execute(ctx, method, nil, nil); isMethodNotFound(err) {
+				continue
+			}
+			caps.supported[domain] = true
+		}
+		// Input has no side-effect free command to probe; assume it is present.
+		caps.supported["Input"] = true
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+
+	required := make(map[string]bool)
+	for _, domains := range toolDomains {
+		for _, d := range domains {
+			required[d] = true
+		}
+	}
+	for d := range caps.supported {
+		caps.Domains = append(caps.Domains, d)
+	}
+	for d := range required {
+		if !caps.supported[d] {
+			caps.MissingDomains = append(caps.MissingDomains, d)
+		}
+	}
+	for tool := range toolDomains {
+		if len(caps.missingDomainsFor(tool)) > 0 {
+			caps.DisabledTools = append(caps.DisabledTools, tool)
+		}
+	}
+	sort.Strings(caps.Domains)
+	sort.Strings(caps.MissingDomains)
+	sort.Strings(caps.DisabledTools)
+
+	if len(caps.MissingDomains) > 0 {
+		log.Printf("Browser %s lacks CDP domains %v; disabling tools %v", caps.Product, caps.MissingDomains, caps.DisabledTools)
+	} else {
+		log.Printf("Browser %s supports all required CDP domains", caps.Product)
+	}
+
+	s.capabilities = caps
+	return nil
+}
+
+// unavailableReason explains why the named tool cannot run with the
+// browser s drives, or returns "" if it can.
+func (s *CDPBrowserServer) unavailableReason(tool string) string {
+	if !s.usesCDP() && !backendTools[tool] {
+		return fmt.Sprintf("unavailable: requires the chrome backend, not %s", s.backend().Name())
+	}
+	if missing := s.capabilities.missingDomainsFor(tool); len(missing) > 0 {
+		return fmt.Sprintf("unavailable: browser does not support CDP domain(s) %s", strings.Join(missing, ", "))
+	}
+	return ""
+}
+
+// addTool defines a tool, registering it with the MCP server while the
+// server's tool set exposes it. If the tool cannot run with the browser,
+// because the backend does not speak CDP or the browser lacks a CDP domain
+// the tool depends on, the tool is still listed so that clients can see why
+// it is unavailable, but its handler reports the degradation instead of
+// calling into the browser. Idempotent tools accept an idempotency key. The
+// handler is also recorded in s.tools so that it can be invoked server-side;
+// see hasTool.
+func addTool[In, Out any](srv *mcp.Server, s *CDPBrowserServer, t *mcp.Tool, h mcp.ToolHandlerFor[In, Out]) {
+	if idempotentTools[t.Name] && t.InputSchema == nil {
+		withIdempotencyKey[In](t)
+	}
+	reason := s.unavailableReason(t.Name)
+	if reason != "" {
+		t.Description = fmt.Sprintf("%s (%s)", t.Description, reason)
+		if t.Annotations == nil {
+			t.Annotations = &mcp.ToolAnnotations{}
+		}
+		t.Annotations.Title = fmt.Sprintf("%s [%s]", t.Name, reason)
+		h = func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[In]]) (*mcp.CallToolResultFor[Out], error) {
+			noteToolError(ctx, nil, CodeToolUnavailable)
+			return &mcp.CallToolResultFor[Out]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Tool %s is %s", t.Name, reason)},
+				},
+				IsError: true,
+			}, nil
+		}
+	}
+	s.toolset.define(t.Name, func(on bool) {
+		switch {
+		case !on:
+			srv.RemoveTools(t.Name)
+			log.Printf("Removed tool: %s", t.Name)
+		case reason != "":
+			mcp.AddTool(srv, t, h)
+			log.Printf("Registered tool: %s (%s)", t.Name, reason)
+		default:
+			mcp.AddTool(srv, t, h)
+			log.Printf("Registered tool: %s", t.Name)
+		}
+	})
+
+	if s.tools == nil {
+		s.tools = make(map[string]toolInvoker)
+	}
+	s.tools[t.Name] = func(ctx context.Context, args json.RawMessage) (*mcp.CallToolResult, error) {
+		var in In
+		if len(args) > 0 {
+			if err := json.Unmarshal(args, &in); err != nil {
+				return nil, invalidArgumentError{fmt.Errorf("invalid arguments for %s: %v", t.Name, err)}
+			}
+		}
+		res, err := h(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[In]]{
+			Params: &mcp.CallToolParamsFor[In]{Name: t.Name, Arguments: in},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.CallToolResult{
+			Meta:              res.Meta,
+			Content:           res.Content,
+			StructuredContent: res.StructuredContent,
+			IsError:           res.IsError,
+		}, nil
+	}
+}
+
+// hasTool reports whether the named tool is defined and exposed, which keeps
+// hidden tools out of batches, macros, and replays too.
+func (s *CDPBrowserServer) hasTool(name string) bool {
+	_, ok := s.tools[name]
+	return ok && s.toolset.exposes(name)
+}
+
+// toolInvoker calls a registered tool handler directly with JSON arguments,
+// bypassing the MCP session. It is used to re-run tools server-side, for
+// example when replaying a recording.
+type toolInvoker func(ctx context.Context, args json.RawMessage) (*mcp.CallToolResult, error)
+
+// GetEnvironment tool - reports the browser version and CDP capabilities
+func (s *CDPBrowserServer) GetEnvironment(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[browserCapabilities], error) {
+	caps := s.capabilities
+	if caps == nil {
+		return &mcp.CallToolResultFor[browserCapabilities]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Browser capabilities have not been probed"},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("BROWSER: %s (protocol %s)\n", caps.Product, caps.ProtocolVersion))
+	output.WriteString(fmt.Sprintf("USER AGENT: %s\n", caps.UserAgent))
+	output.WriteString(fmt.Sprintf("V8: %s\n", caps.JSVersion))
+	output.WriteString(fmt.Sprintf("CDP DOMAINS: %s\n", strings.Join(caps.Domains, ", ")))
+	if len(caps.MissingDomains) > 0 {
+		output.WriteString(fmt.Sprintf("MISSING DOMAINS: %s\n", strings.Join(caps.MissingDomains, ", ")))
+		output.WriteString(fmt.Sprintf("DISABLED TOOLS: %s\n", strings.Join(caps.DisabledTools, ", ")))
+	} else {
+		output.WriteString("All tools are available\n")
+	}
+
+	return &mcp.CallToolResultFor[browserCapabilities]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: output.String()},
+		},
+		StructuredContent: *caps,
+	}, nil
+}
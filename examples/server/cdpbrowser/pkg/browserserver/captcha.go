@@ -0,0 +1,173 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// captchaJS defines detectCaptchas, which looks for the widgets, frames,
+// and scripts of common CAPTCHA providers and reports one entry per
+// provider found. An entry is visible if the operator would have to solve
+// something: reCAPTCHA v3 and other invisible widgets only score the
+// visitor and do not block the page.
+const captchaJS = `
+function detectCaptchas() {
+	const providers = [
+		{
+			name: 'reCAPTCHA',
+			frames: /\/recaptcha\/(api2|enterprise)\/(anchor|bframe)/,
+			scripts: /\/recaptcha\/(api|enterprise)\.js/,
+			elements: '.g-recaptcha'
+		},
+		{
+			name: 'hCaptcha',
+			frames: /^https:\/\/([a-z0-9-]+\.)*hcaptcha\.com\//,
+			scripts: /hcaptcha\.com\/1\/api\.js/,
+			elements: '.h-captcha'
+		},
+		{
+			name: 'Turnstile',
+			frames: /^https:\/\/challenges\.cloudflare\.com\//,
+			scripts: /challenges\.cloudflare\.com\/turnstile\/|\/cdn-cgi\/challenge-platform\//,
+			elements: '.cf-turnstile, #challenge-stage, #challenge-form'
+		}
+	];
+	const shown = el => {
+		const rect = el.getBoundingClientRect();
+		const style = getComputedStyle(el);
+		return rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+	};
+	const found = [];
+	for (const p of providers) {
+		let hit = null;
+		for (const frame of document.querySelectorAll('iframe[src]')) {
+			if (!p.frames.test(frame.src)) {
+				continue;
+			}
+			const visible = shown(frame) && !/[?&]size=invisible/.test(frame.src);
+			if (!hit || (visible && !hit.visible)) {
+				hit = { provider: p.name, evidence: 'iframe ' + frame.src.split('?')[0], visible: visible };
+			}
+		}
+		if (!hit) {
+			const el = document.querySelector(p.elements);
+			if (el) {
+				const id = el.id ? '#' + el.id : '.' + el.classList[0];
+				hit = {
+					provider: p.name,
+					evidence: el.tagName.toLowerCase() + id,
+					visible: shown(el) && el.getAttribute('data-size') !== 'invisible'
+				};
+			}
+		}
+		if (!hit) {
+			for (const script of document.querySelectorAll('script[src]')) {
+				if (p.scripts.test(script.src)) {
+					hit = { provider: p.name, evidence: 'script ' + script.src.split('?')[0], visible: false };
+					break;
+				}
+			}
+		}
+		if (hit) {
+			found.push(hit);
+		}
+	}
+	return found;
+}
+`
+
+// detectCaptchasJS evaluates to the result of detectCaptchas.
+const detectCaptchasJS = `(function() {` + captchaJS + `
+	return detectCaptchas();
+})()`
+
+// Captcha is a CAPTCHA found on the page.
+type Captcha struct {
+	Provider string `json:"provider" jsonschema:"reCAPTCHA, hCaptcha, or Turnstile"`
+	Evidence string `json:"evidence" jsonschema:"The frame, element, or script that identified the provider"`
+	Visible  bool   `json:"visible" jsonschema:"Whether the challenge is shown to the user; invisible ones only score the visitor"`
+}
+
+// blockingCaptchas returns the visible CAPTCHAs in captchas.
+func blockingCaptchas(captchas []Captcha) []Captcha {
+	var blocking []Captcha
+	for _, c := range captchas {
+		if c.Visible {
+			blocking = append(blocking, c)
+		}
+	}
+	return blocking
+}
+
+// captchaWarning returns the warning added to tool results for captchas, or
+// "" if none of them is visible.
+func captchaWarning(captchas []Captcha) string {
+	var providers []string
+	for _, c := range blockingCaptchas(captchas) {
+		providers = append(providers, c.Provider)
+	}
+	if len(providers) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Warning: the page shows a CAPTCHA (%s). It cannot be solved automatically; "+
+		"ask the user with request_human_input (kind confirm) or try another route instead of interacting with it.",
+		strings.Join(providers, ", "))
+}
+
+// detectCaptchas reports the CAPTCHAs on the current page.
+func (s *CDPBrowserServer) detectCaptchas(ctx context.Context) ([]Captcha, error) {
+	var captchas []Captcha
+	if err := s.backend().Evaluate(ctx, detectCaptchasJS, &captchas); err != nil {
+		return nil, err
+	}
+	return captchas, nil
+}
+
+// CaptchaReport is the structured result of detect_captcha.
+type CaptchaReport struct {
+	Captchas []Captcha `json:"captchas,omitempty"`
+	Blocking bool      `json:"blocking" jsonschema:"Whether a visible CAPTCHA blocks the page"`
+}
+
+// String formats r for the text result of detect_captcha.
+func (r *CaptchaReport) String() string {
+	if len(r.Captchas) == 0 {
+		return "No CAPTCHA found on the page"
+	}
+	var lines []string
+	for _, c := range r.Captchas {
+		state := "visible"
+		if !c.Visible {
+			state = "invisible"
+		}
+		lines = append(lines, fmt.Sprintf("%s (%s): %s", c.Provider, state, c.Evidence))
+	}
+	if w := captchaWarning(r.Captchas); w != "" {
+		lines = append(lines, w)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DetectCaptcha tool - reports CAPTCHA challenges on the current page
+func (s *CDPBrowserServer) DetectCaptcha(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[CaptchaReport], error) {
+	captchas, err := s.detectCaptchas(ctx)
+	if err != nil {
+		return &mcp.CallToolResultFor[CaptchaReport]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error detecting CAPTCHAs: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	r := &CaptchaReport{Captchas: captchas, Blocking: len(blockingCaptchas(captchas)) > 0}
+	return &mcp.CallToolResultFor[CaptchaReport]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: r.String()},
+		},
+		StructuredContent: *r,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package browserserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaptchaWarning(t *testing.T) {
+	invisible := Captcha{Provider: "reCAPTCHA", Evidence: "script https://www.google.com/recaptcha/api.js"}
+	if w := captchaWarning([]Captcha{invisible}); w != "" {
+		t.Errorf("warning for an invisible CAPTCHA = %q, want none", w)
+	}
+	captchas := []Captcha{
+		invisible,
+		{Provider: "hCaptcha", Evidence: "div.h-captcha", Visible: true},
+		{Provider: "Turnstile", Evidence: "iframe https://challenges.cloudflare.com/cdn-cgi/challenge-platform/", Visible: true},
+	}
+	if w := captchaWarning(captchas); !strings.Contains(w, "CAPTCHA (hCaptcha, Turnstile)") || !strings.Contains(w, "request_human_input") {
+		t.Errorf("warning = %q", w)
+	}
+
+	r := &CaptchaReport{Captchas: captchas, Blocking: true}
+	want := []string{
+		"reCAPTCHA (invisible): script https://www.google.com/recaptcha/api.js",
+		"hCaptcha (visible): div.h-captcha",
+		"Turnstile (visible): iframe https://challenges.cloudflare.com/cdn-cgi/challenge-platform/",
+		captchaWarning(captchas),
+	}
+	if got := r.String(); got != strings.Join(want, "\n") {
+		t.Errorf("report:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+	if got := (&CaptchaReport{}).String(); got != "No CAPTCHA found on the page" {
+		t.Errorf("empty report = %q", got)
+	}
+
+	e := &ActionEffects{URL: "https://example.com/", Captchas: captchas[1:2]}
+	if got := e.String(); got != captchaWarning(captchas[1:2]) {
+		t.Errorf("effects = %q, want only the warning", got)
+	}
+}
@@ -0,0 +1,297 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultConfirmationPolicy is the policy enabled by -confirm-destructive:
+// form submissions, and clicks on controls labelled like a destructive or
+// purchasing action or on pages and links under checkout-like paths.
+var defaultConfirmationPolicy = confirmationPolicy{
+	Submit: true,
+	Text: []string{
+		`(?i)\b(delete|remove|destroy|erase|purchase|buy|pay|place order|check ?out|transfer|send|publish|unsubscribe|deactivate|close account|cancel (my )?(account|subscription|order))\b`,
+	},
+	URLs: []string{
+		`(?i)/(checkout|payment|purchase|billing)\b`,
+	},
+}
+
+// confirmationPolicy decides which clicks are destructive and need the
+// operator's confirmation before they run.
+type confirmationPolicy struct {
+	// Submit makes every click that submits a form destructive.
+	Submit bool `json:"submit"`
+	// Text lists regular expressions matched against the label of the
+	// clicked control: its aria-label, text, value, or title.
+	Text []string `json:"text"`
+	// URLs lists regular expressions matched against the page URL and the
+	// URL the control leads to: a link's href or a form's action.
+	URLs []string `json:"urls"`
+
+	text, urls []*regexp.Regexp
+}
+
+// loadConfirmationPolicy reads a JSON policy file.
+func loadConfirmationPolicy(path string) (*confirmationPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read confirmation policy file: %v", err)
+	}
+	var p confirmationPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse confirmation policy file %s: %v", path, err)
+	}
+	return &p, nil
+}
+
+// compile compiles the policy's patterns. It must be called before match.
+func (p *confirmationPolicy) compile() error {
+	compile := func(patterns []string) ([]*regexp.Regexp, error) {
+		var res []*regexp.Regexp
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid confirmation pattern %q: %v", pattern, err)
+			}
+			res = append(res, re)
+		}
+		return res, nil
+	}
+	var err error
+	if p.text, err = compile(p.Text); err != nil {
+		return err
+	}
+	p.urls, err = compile(p.URLs)
+	return err
+}
+
+// clickTargetJS is a function called with the element about to be clicked
+// as this. It describes the control the click lands on: the element itself
+// or the link or button around it.
+const clickTargetJS = `function() {` + pageStateJS + `
+	const el = this.closest('a, button, input, [role="button"], [role="link"]') || this;
+	const label = el.getAttribute('aria-label') || el.innerText || el.value || el.title || '';
+	const type = (el.getAttribute('type') || '').toLowerCase();
+	const form = el.form || null;
+	const submit = !!form && ((el.tagName === 'BUTTON' && (type === '' || type === 'submit')) ||
+		(el.tagName === 'INPUT' && (type === 'submit' || type === 'image')));
+	return {
+		element: describeElement(el),
+		label: label.replace(/\s+/g, ' ').trim().slice(0, 200),
+		href: el.href && typeof el.href === 'string' ? el.href : '',
+		submit: submit,
+		action: submit ? form.action : '',
+		page: location.href
+	};
+}`
+
+// enterTargetJS is a function called with the field about to receive an
+// Enter key as this. It describes what Enter triggers: in a text input of a
+// form it submits the form, which counts as a click on the form's default
+// button.
+const enterTargetJS = `function() {` + pageStateJS + `
+	const form = this.tagName === 'INPUT' ? this.form : null;
+	const button = form && form.querySelector('button:not([type]), button[type="submit"], input[type="submit"], input[type="image"]');
+	const label = button ? button.getAttribute('aria-label') || button.innerText || button.value || button.title || '' : '';
+	return {
+		element: describeElement(this),
+		label: label.replace(/\s+/g, ' ').trim().slice(0, 200),
+		href: '',
+		submit: !!form,
+		action: form ? form.action : '',
+		page: location.href,
+		key: 'Enter'
+	};
+}`
+
+// clickTarget is what clickTargetJS and enterTargetJS return.
+type clickTarget struct {
+	Element string `json:"element"`
+	Label   string `json:"label"`
+	Href    string `json:"href"`
+	Submit  bool   `json:"submit"`
+	Action  string `json:"action"`
+	Page    string `json:"page"`
+	// Key is set when the action is pressing that key in Element rather
+	// than clicking it.
+	Key string `json:"key,omitempty"`
+}
+
+// action describes the action on t, such as `clicking button "Delete"`.
+func (t *clickTarget) action() string {
+	if t.Key != "" {
+		return fmt.Sprintf("pressing %s in %s", t.Key, t.Element)
+	}
+	return "clicking " + t.Element
+}
+
+// pressesEnter reports whether typing text presses Enter.
+func pressesEnter(text string) bool {
+	return strings.ContainsAny(text, "\r\n")
+}
+
+// match returns why clicking t needs confirmation, or "" if it does not.
+func (p *confirmationPolicy) match(t *clickTarget) string {
+	if p.Submit && t.Submit {
+		return "it submits a form to " + t.Action
+	}
+	for _, re := range p.text {
+		if t.Label != "" && re.MatchString(t.Label) {
+			return fmt.Sprintf("its label %q matches %q", t.Label, re)
+		}
+	}
+	for _, re := range p.urls {
+		for _, u := range []string{t.Page, t.Href, t.Action} {
+			if u != "" && re.MatchString(u) {
+				return fmt.Sprintf("%s matches %q", u, re)
+			}
+		}
+	}
+	return ""
+}
+
+// unconfirmedActionError is returned when a destructive click was neither
+// confirmed by the caller nor approved by the operator.
+type unconfirmedActionError struct {
+	target *clickTarget
+	reason string
+	// declined is the operator's answer if they were asked, or "".
+	declined string
+}
+
+func (e *unconfirmedActionError) Error() string {
+	if e.declined != "" {
+		return fmt.Sprintf("the operator chose to %s %s (%s)", e.declined, e.target.action(), e.reason)
+	}
+	return fmt.Sprintf("%s needs confirmation because %s; ask the user, then call again with confirm: true", e.target.action(), e.reason)
+}
+
+// isUnconfirmed reports whether err is an *unconfirmedActionError, which
+// must not be retried with another selector.
+func isUnconfirmed(err error) bool {
+	var e *unconfirmedActionError
+	return errors.As(err, &e)
+}
+
+// clickConfirmation checks the clicks of one tool call, and the Enter keys
+// it types, against the confirmation policy. The zero value allows
+// everything.
+type clickConfirmation struct {
+	policy    *confirmationPolicy
+	session   *mcp.ServerSession
+	ask       bool
+	confirmed bool
+}
+
+// confirmation returns the clickConfirmation for a call in session, where
+// confirm is the call's confirm argument.
+func (s *CDPBrowserServer) confirmation(session *mcp.ServerSession, confirm bool) *clickConfirmation {
+	return &clickConfirmation{
+		policy:    s.confirmPolicy,
+		session:   session,
+		ask:       session != nil && s.elicitation.supported(session),
+		confirmed: confirm,
+	}
+}
+
+// check returns an *unconfirmedActionError if clicking the element with
+// backend node ID b needs confirmation that was not given. If the client
+// supports elicitation, the operator is asked instead of failing.
+func (c *clickConfirmation) check(ctx context.Context, b cdp.BackendNodeID) error {
+	return c.checkTarget(ctx, b, clickTargetJS)
+}
+
+// checkEnter is check for pressing Enter in the field with backend node ID
+// b.
+func (c *clickConfirmation) checkEnter(ctx context.Context, b cdp.BackendNodeID) error {
+	return c.checkTarget(ctx, b, enterTargetJS)
+}
+
+// checkTarget applies the policy to the action that targetJS, clickTargetJS
+// or enterTargetJS, describes for the element with backend node ID b.
+func (c *clickConfirmation) checkTarget(ctx context.Context, b cdp.BackendNodeID, targetJS string) error {
+	if c == nil || c.policy == nil || c.confirmed {
+		return nil
+	}
+	var t clickTarget
+	if err := callOnElement(ctx, b, targetJS, &t); err != nil {
+		return err
+	}
+	return c.confirm(ctx, &t)
+}
+
+// checkInPage is checkTarget for the first element matched by loc, for
+// backends without CDP.
+func (c *clickConfirmation) checkInPage(ctx context.Context, s *CDPBrowserServer, loc SelectorLocator, targetJS string) error {
+	if c == nil || c.policy == nil || c.confirmed {
+		return nil
+	}
+	var t clickTarget
+	if err := s.evaluateOnMatch(ctx, loc, targetJS, &t); err != nil {
+		return err
+	}
+	return c.confirm(ctx, &t)
+}
+
+// confirm applies the policy to a click on t, asking the operator if the
+// policy requires confirmation and the client supports elicitation.
+func (c *clickConfirmation) confirm(ctx context.Context, t *clickTarget) error {
+	reason := c.policy.match(t)
+	if reason == "" {
+		return nil
+	}
+	err := &unconfirmedActionError{target: t, reason: reason}
+	if !c.ask {
+		log.Printf("Confirmation policy: blocked %s: %s", t.action(), reason)
+		return err
+	}
+
+	log.Printf("Confirmation policy: asking the operator about %s: %s", t.Element, reason)
+	askCtx, cancel := context.WithTimeout(ctx, defaultHumanInputTimeout)
+	defer cancel()
+	res, elicitErr := c.session.Elicit(askCtx, &mcp.ElicitParams{
+		Message:         fmt.Sprintf("The agent wants to go ahead with %s on %s, which may be irreversible: %s.\n\nAccept to allow it.", t.action(), t.Page, reason),
+		RequestedSchema: humanInputSchema(humanInputConfirm),
+	})
+	if elicitErr != nil {
+		return fmt.Errorf("asking the operator to confirm: %v", elicitErr)
+	}
+	if res.Action != "accept" {
+		err.declined = res.Action
+		return err
+	}
+	// The operator approved this call; later actions in it need not ask
+	// again.
+	c.confirmed = true
+	return nil
+}
+
+// checkAction returns an action that runs checkTarget with targetJS on the
+// first element matched by loc.
+func (c *clickConfirmation) checkAction(loc SelectorLocator, targetJS string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if c == nil || c.policy == nil || c.confirmed {
+			return nil
+		}
+		var nodes []*cdp.Node
+		if err := chromedp.Nodes(loc.Query, &nodes, loc.by(), chromedp.AtLeast(0)).Do(ctx); err != nil {
+			return err
+		}
+		if len(nodes) == 0 {
+			return nil
+		}
+		return c.checkTarget(ctx, nodes[0].BackendNodeID, targetJS)
+	})
+}
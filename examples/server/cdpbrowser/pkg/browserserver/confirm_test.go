@@ -0,0 +1,98 @@
+package browserserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfirmationPolicy(t *testing.T) {
+	p := defaultConfirmationPolicy
+	if err := p.compile(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		target clickTarget
+		want   string
+	}{
+		{clickTarget{Label: "Search", Submit: true, Action: "https://example.com/search", Page: "https://example.com/"}, "submits a form to https://example.com/search"},
+		{clickTarget{Label: "Delete account", Page: "https://example.com/settings"}, `label "Delete account" matches`},
+		{clickTarget{Label: "Place order", Page: "https://shop.example.com/cart"}, `label "Place order" matches`},
+		{clickTarget{Label: "Buy now", Page: "https://shop.example.com/item/1"}, `label "Buy now" matches`},
+		{clickTarget{Label: "Continue", Href: "https://shop.example.com/checkout/step2", Page: "https://shop.example.com/cart"}, "https://shop.example.com/checkout/step2 matches"},
+		{clickTarget{Label: "Next", Page: "https://shop.example.com/checkout"}, "https://shop.example.com/checkout matches"},
+		{clickTarget{Label: "Read more", Href: "https://example.com/blog/1", Page: "https://example.com/"}, ""},
+		{clickTarget{Label: "Buyer's guide", Page: "https://example.com/"}, ""},
+		{clickTarget{Label: "Checkoutside", Page: "https://example.com/paymentsdocs"}, ""},
+	} {
+		got := p.match(&tc.target)
+		if tc.want == "" && got != "" || !strings.Contains(got, tc.want) {
+			t.Errorf("match(%+v) = %q, want %q", tc.target, got, tc.want)
+		}
+	}
+
+	// Without submit, only the patterns count.
+	custom := confirmationPolicy{Text: []string{`^Send$`}}
+	if err := custom.compile(); err != nil {
+		t.Fatal(err)
+	}
+	if got := custom.match(&clickTarget{Label: "Search", Submit: true}); got != "" {
+		t.Errorf("form submission with submit off = %q, want none", got)
+	}
+	if got := custom.match(&clickTarget{Label: "Send"}); got == "" {
+		t.Errorf("Send did not match ^Send$")
+	}
+}
+
+func TestLoadConfirmationPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "confirm.json")
+	if err := os.WriteFile(path, []byte(`{"submit": true, "text": ["(?i)refund"], "urls": ["/admin/"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := loadConfirmationPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.compile(); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.match(&clickTarget{Label: "Issue refund"}); got == "" {
+		t.Errorf("Issue refund did not match")
+	}
+	if got := p.match(&clickTarget{Label: "Save", Page: "https://example.com/admin/users"}); got == "" {
+		t.Errorf("admin page did not match")
+	}
+
+	bad := &confirmationPolicy{Text: []string{"("}}
+	if err := bad.compile(); err == nil || !strings.Contains(err.Error(), `invalid confirmation pattern "("`) {
+		t.Errorf("compile with an invalid pattern = %v", err)
+	}
+}
+
+func TestUnconfirmedActionError(t *testing.T) {
+	err := &unconfirmedActionError{target: &clickTarget{Element: `button "Delete"`}, reason: "it submits a form to https://example.com/delete"}
+	if got := err.Error(); !strings.Contains(got, "needs confirmation") || !strings.Contains(got, "confirm: true") {
+		t.Errorf("error = %q", got)
+	}
+	if !isUnconfirmed(err) {
+		t.Errorf("isUnconfirmed = false")
+	}
+	err.declined = "decline"
+	if got := err.Error(); !strings.HasPrefix(got, `the operator chose to decline clicking button "Delete"`) {
+		t.Errorf("declined error = %q", got)
+	}
+	enter := &unconfirmedActionError{target: &clickTarget{Element: "input#card", Key: "Enter"}, reason: "it submits a form to https://example.com/pay"}
+	if got := enter.Error(); !strings.HasPrefix(got, "pressing Enter in input#card needs confirmation") {
+		t.Errorf("Enter key error = %q", got)
+	}
+	for text, want := range map[string]bool{"hello": false, "hello\n": true, "4111\r": true} {
+		if got := pressesEnter(text); got != want {
+			t.Errorf("pressesEnter(%q) = %t, want %t", text, got, want)
+		}
+	}
+	if c := (*clickConfirmation)(nil); c.check(context.Background(), 0) != nil {
+		t.Errorf("nil confirmation blocked a click")
+	}
+}
@@ -0,0 +1,426 @@
+package browserserver
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/css"
+	"github.com/chromedp/cdproto/profiler"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Kinds of code coverage.
+const (
+	coverageJS  = "js"
+	coverageCSS = "css"
+)
+
+// defaultCoverageLimit is the number of files stop_coverage lists in its
+// text result by default.
+const defaultCoverageLimit = 20
+
+// coverageRange is a range of a script or stylesheet and how often it ran,
+// or whether it was used.
+type coverageRange struct {
+	start, end int64
+	count      int64
+}
+
+// usedRanges returns the disjoint ranges covered by ranges with a non-zero
+// count. Ranges may nest, as the blocks of a function nest in it; the
+// innermost range decides whether an offset was used.
+func usedRanges(ranges []coverageRange) [][2]int64 {
+	type point struct {
+		offset int64
+		end    bool
+		r      coverageRange
+	}
+	points := make([]point, 0, 2*len(ranges))
+	for _, r := range ranges {
+		points = append(points, point{r.start, false, r}, point{r.end, true, r})
+	}
+	slices.SortStableFunc(points, func(a, b point) int {
+		if a.offset != b.offset {
+			return cmp.Compare(a.offset, b.offset)
+		}
+		// Ranges ending here close before those starting here open.
+		if a.end != b.end {
+			if a.end {
+				return -1
+			}
+			return 1
+		}
+		// Outer ranges open before and close after the ranges they contain.
+		la, lb := a.r.end-a.r.start, b.r.end-b.r.start
+		if a.end {
+			return cmp.Compare(la, lb)
+		}
+		return cmp.Compare(lb, la)
+	})
+
+	var used [][2]int64
+	var counts []int64
+	var last int64
+	for _, p := range points {
+		if len(counts) > 0 && last < p.offset && counts[len(counts)-1] > 0 {
+			if n := len(used); n > 0 && used[n-1][1] == last {
+				used[n-1][1] = p.offset
+			} else {
+				used = append(used, [2]int64{last, p.offset})
+			}
+		}
+		last = p.offset
+		if p.end {
+			counts = counts[:len(counts)-1]
+		} else {
+			counts = append(counts, p.r.count)
+		}
+	}
+	return used
+}
+
+// usedSize returns the total length of the used ranges of ranges.
+func usedSize(ranges []coverageRange) int64 {
+	var n int64
+	for _, r := range usedRanges(ranges) {
+		n += r[1] - r[0]
+	}
+	return n
+}
+
+// FileCoverage is how much of a script or stylesheet a page used. Sizes
+// are in characters of source, which is bytes for ASCII code.
+type FileCoverage struct {
+	URL           string  `json:"url"`
+	Type          string  `json:"type" jsonschema:"js or css"`
+	TotalBytes    int64   `json:"total_bytes"`
+	UsedBytes     int64   `json:"used_bytes"`
+	UnusedBytes   int64   `json:"unused_bytes"`
+	UnusedPercent float64 `json:"unused_percent"`
+}
+
+// CoverageTotals sums the FileCoverage of one type.
+type CoverageTotals struct {
+	Files         int     `json:"files"`
+	TotalBytes    int64   `json:"total_bytes"`
+	UsedBytes     int64   `json:"used_bytes"`
+	UnusedBytes   int64   `json:"unused_bytes"`
+	UnusedPercent float64 `json:"unused_percent"`
+}
+
+// add counts f in t.
+func (t *CoverageTotals) add(f *FileCoverage) {
+	t.Files++
+	t.TotalBytes += f.TotalBytes
+	t.UsedBytes += f.UsedBytes
+	t.UnusedBytes = t.TotalBytes - t.UsedBytes
+	t.UnusedPercent = unusedPercent(t.TotalBytes, t.UsedBytes)
+}
+
+func unusedPercent(total, used int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(total-used) * 100 / float64(total)
+}
+
+// CoverageReport is the structured result of stop_coverage.
+type CoverageReport struct {
+	Duration string          `json:"duration"`
+	JS       *CoverageTotals `json:"js,omitempty"`
+	CSS      *CoverageTotals `json:"css,omitempty"`
+	// Files lists the scripts and stylesheets, most unused bytes first.
+	Files []FileCoverage `json:"files"`
+	// limit is the number of files String lists.
+	limit int
+}
+
+// String formats r for tool results.
+func (r *CoverageReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Coverage collected over %s\n", r.Duration)
+	for _, t := range []struct {
+		name   string
+		totals *CoverageTotals
+	}{{"JS", r.JS}, {"CSS", r.CSS}} {
+		if t.totals != nil {
+			fmt.Fprintf(&b, "%s: %d of %d bytes unused (%.1f%%) in %d files\n", t.name, t.totals.UnusedBytes, t.totals.TotalBytes, t.totals.UnusedPercent, t.totals.Files)
+		}
+	}
+	for i, f := range r.Files {
+		if r.limit > 0 && i == r.limit {
+			fmt.Fprintf(&b, "... and %d more files in the structured result\n", len(r.Files)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- [%s] %s: %d of %d bytes unused (%.1f%%)\n", f.Type, f.URL, f.UnusedBytes, f.TotalBytes, f.UnusedPercent)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// coverageFiles sums the ranges of each file, keyed by URL, into a
+// FileCoverage. totals holds the sizes of the files.
+func coverageFiles(kind string, ranges map[string][]coverageRange, totals map[string]int64) ([]FileCoverage, *CoverageTotals) {
+	sum := &CoverageTotals{}
+	var files []FileCoverage
+	for url, total := range totals {
+		used := min(usedSize(ranges[url]), total)
+		f := FileCoverage{URL: url, Type: kind, TotalBytes: total, UsedBytes: used, UnusedBytes: total - used, UnusedPercent: unusedPercent(total, used)}
+		sum.add(&f)
+		files = append(files, f)
+	}
+	return files, sum
+}
+
+// jsCoverage turns the precise coverage of V8 into FileCoverage. Scripts
+// without a URL, which include the scripts the tools evaluate, are left out.
+// Inline scripts are counted together under the URL of their page.
+func jsCoverage(scripts []*profiler.ScriptCoverage) ([]FileCoverage, *CoverageTotals) {
+	ranges := make(map[string][]coverageRange)
+	totals := make(map[string]int64)
+	for _, script := range scripts {
+		if script.URL == "" {
+			continue
+		}
+		// Inline scripts sharing a URL are laid end to end, so that their
+		// offsets do not mix.
+		var rs []coverageRange
+		var size int64
+		for _, fn := range script.Functions {
+			for _, r := range fn.Ranges {
+				rs = append(rs, coverageRange{r.StartOffset, r.EndOffset, r.Count})
+				size = max(size, r.EndOffset)
+			}
+		}
+		offset := totals[script.URL]
+		for i := range rs {
+			rs[i].start += offset
+			rs[i].end += offset
+		}
+		ranges[script.URL] = append(ranges[script.URL], rs...)
+		totals[script.URL] = offset + size
+	}
+	return coverageFiles(coverageJS, ranges, totals)
+}
+
+// cssCoverage turns CSS rule usage into FileCoverage, given the headers of
+// the stylesheets. Stylesheets without a URL, such as constructed ones, are
+// left out; inline ones are counted under the URL of their page.
+func cssCoverage(rules []*css.RuleUsage, sheets map[css.StyleSheetID]*css.StyleSheetHeader) ([]FileCoverage, *CoverageTotals) {
+	ranges := make(map[string][]coverageRange)
+	totals := make(map[string]int64)
+	offsets := make(map[css.StyleSheetID]int64)
+	for id, h := range sheets {
+		if h.SourceURL == "" {
+			continue
+		}
+		offsets[id] = totals[h.SourceURL]
+		totals[h.SourceURL] += int64(h.Length)
+	}
+	for _, rule := range rules {
+		h, ok := sheets[rule.StyleSheetID]
+		if !ok || h.SourceURL == "" {
+			continue
+		}
+		var count int64
+		if rule.Used {
+			count = 1
+		}
+		offset := offsets[rule.StyleSheetID]
+		ranges[h.SourceURL] = append(ranges[h.SourceURL], coverageRange{offset + int64(rule.StartOffset), offset + int64(rule.EndOffset), count})
+	}
+	return coverageFiles(coverageCSS, ranges, totals)
+}
+
+// coverageSession is the coverage collection started by start_coverage.
+type coverageSession struct {
+	mu      sync.Mutex
+	active  bool
+	js, css bool
+	started time.Time
+	// sheets holds the stylesheets of the page, reported by the CSS domain.
+	sheets map[css.StyleSheetID]*css.StyleSheetHeader
+	// stopListening stops recording stylesheets.
+	stopListening context.CancelFunc
+}
+
+// begin marks a collection of the given kinds as started.
+func (c *coverageSession) begin(js, css bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active {
+		return fmt.Errorf("coverage is already being collected; call stop_coverage first")
+	}
+	c.active, c.js, c.css, c.started = true, js, css, time.Now()
+	c.sheets = nil
+	c.stopListening = func() {}
+	return nil
+}
+
+// end marks the collection as stopped and stops recording stylesheets.
+func (c *coverageSession) end() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active = false
+	c.stopListening()
+}
+
+// listen records the stylesheets of the page of tabCtx until end.
+func (c *coverageSession) listen(tabCtx context.Context) {
+	lctx, cancel := context.WithCancel(tabCtx)
+	c.mu.Lock()
+	c.sheets = make(map[css.StyleSheetID]*css.StyleSheetHeader)
+	c.stopListening = cancel
+	c.mu.Unlock()
+	chromedp.ListenTarget(lctx, func(ev any) {
+		if ev, ok := ev.(*css.EventStyleSheetAdded); ok {
+			c.mu.Lock()
+			c.sheets[ev.Header.StyleSheetID] = ev.Header
+			c.mu.Unlock()
+		}
+	})
+}
+
+type StartCoverageArgs struct {
+	Types []string `json:"types,omitempty" jsonschema:"What to collect coverage of: js and/or css (default: both)"`
+}
+
+type StopCoverageArgs struct {
+	Limit int `json:"limit,omitempty" jsonschema:"Number of files to list in the text result, most unused bytes first; the structured result lists all (default: 20)"`
+}
+
+// StartCoverage tool - starts collecting JavaScript and CSS coverage for
+// stop_coverage
+func (s *CDPBrowserServer) StartCoverage(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[StartCoverageArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+	fail := func(err error) (*mcp.CallToolResultFor[struct{}], error) {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error starting coverage: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	js, cssCov := len(req.Params.Arguments.Types) == 0, len(req.Params.Arguments.Types) == 0
+	for _, t := range req.Params.Arguments.Types {
+		switch t {
+		case coverageJS:
+			js = true
+		case coverageCSS:
+			cssCov = true
+		default:
+			return fail(invalidArgumentError{fmt.Errorf("unknown coverage type %q (want %s or %s)", t, coverageJS, coverageCSS)})
+		}
+	}
+	if err := s.coverage.begin(js, cssCov); err != nil {
+		return fail(err)
+	}
+
+	var actions []chromedp.Action
+	var kinds []string
+	if js {
+		actions = append(actions, profiler.Enable(), chromedp.ActionFunc(func(ctx context.Context) error {
+			_, err := profiler.StartPreciseCoverage().WithCallCount(false).WithDetailed(true).Do(ctx)
+			return err
+		}))
+		kinds = append(kinds, "JavaScript")
+	}
+	if cssCov {
+		s.coverage.listen(s.ctx)
+		// Enabling the CSS domain again reports the stylesheets already
+		// in the page.
+		actions = append(actions, css.Disable(), css.Enable(), css.StartRuleUsageTracking())
+		kinds = append(kinds, "CSS")
+	}
+	if err := s.run(ctx, actions...); err != nil {
+		s.coverage.end()
+		return fail(err)
+	}
+	log.Printf("Coverage started: %v", kinds)
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Collecting %s coverage. Load and use the page, then call stop_coverage for the report.", strings.Join(kinds, " and "))},
+		},
+	}, nil
+}
+
+// StopCoverage tool - stops collecting coverage and reports the used and
+// unused bytes of each script and stylesheet
+func (s *CDPBrowserServer) StopCoverage(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[StopCoverageArgs]]) (*mcp.CallToolResultFor[CoverageReport], error) {
+	fail := func(err error) (*mcp.CallToolResultFor[CoverageReport], error) {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[CoverageReport]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error stopping coverage: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	c := &s.coverage
+	c.mu.Lock()
+	active, js, cssCov, started := c.active, c.js, c.css, c.started
+	c.mu.Unlock()
+	if !active {
+		return fail(fmt.Errorf("coverage is not being collected; call start_coverage first"))
+	}
+	defer c.end()
+
+	var scripts []*profiler.ScriptCoverage
+	var rules []*css.RuleUsage
+	var actions []chromedp.Action
+	if js {
+		actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			if scripts, _, err = profiler.TakePreciseCoverage().Do(ctx); err != nil {
+				return err
+			}
+			if err := profiler.StopPreciseCoverage().Do(ctx); err != nil {
+				return err
+			}
+			return profiler.Disable().Do(ctx)
+		}))
+	}
+	if cssCov {
+		actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			rules, err = css.StopRuleUsageTracking().Do(ctx)
+			return err
+		}))
+	}
+	if err := s.run(ctx, actions...); err != nil {
+		return fail(err)
+	}
+
+	report := CoverageReport{Duration: time.Since(started).Round(100 * time.Millisecond).String(), Files: []FileCoverage{}, limit: req.Params.Arguments.Limit}
+	if report.limit <= 0 {
+		report.limit = defaultCoverageLimit
+	}
+	if js {
+		var files []FileCoverage
+		files, report.JS = jsCoverage(scripts)
+		report.Files = append(report.Files, files...)
+	}
+	if cssCov {
+		c.mu.Lock()
+		files, totals := cssCoverage(rules, c.sheets)
+		c.mu.Unlock()
+		report.CSS = totals
+		report.Files = append(report.Files, files...)
+	}
+	slices.SortFunc(report.Files, func(a, b FileCoverage) int {
+		return cmp.Or(cmp.Compare(b.UnusedBytes, a.UnusedBytes), strings.Compare(a.URL, b.URL), strings.Compare(a.Type, b.Type))
+	})
+	log.Printf("Coverage stopped: %d files", len(report.Files))
+	return &mcp.CallToolResultFor[CoverageReport]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: report.String()},
+		},
+		StructuredContent: report,
+	}, nil
+}
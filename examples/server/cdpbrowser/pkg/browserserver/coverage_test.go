@@ -0,0 +1,125 @@
+package browserserver
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/css"
+	"github.com/chromedp/cdproto/profiler"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestUsedRanges(t *testing.T) {
+	// A script whose top-level code ran, with a function that ran except for
+	// one branch, and a function that never ran.
+	ranges := []coverageRange{
+		{0, 100, 1},
+		{10, 50, 1},
+		{20, 40, 0},
+		{25, 30, 1},
+		{60, 90, 0},
+	}
+	want := [][2]int64{{0, 20}, {25, 30}, {40, 60}, {90, 100}}
+	if got := usedRanges(ranges); !slices.Equal(got, want) {
+		t.Errorf("usedRanges = %v, want %v", got, want)
+	}
+	if got := usedSize(ranges); got != 20+5+20+10 {
+		t.Errorf("usedSize = %d", got)
+	}
+	// Adjacent used rules merge; the gaps between them are unused.
+	if got := usedRanges([]coverageRange{{0, 10, 1}, {10, 20, 1}, {25, 30, 0}, {30, 35, 1}}); !slices.Equal(got, [][2]int64{{0, 20}, {30, 35}}) {
+		t.Errorf("usedRanges of rules = %v", got)
+	}
+}
+
+func TestJSCoverage(t *testing.T) {
+	script := func(url string, ranges ...*profiler.CoverageRange) *profiler.ScriptCoverage {
+		return &profiler.ScriptCoverage{URL: url, Functions: []*profiler.FunctionCoverage{{Ranges: ranges}}}
+	}
+	files, totals := jsCoverage([]*profiler.ScriptCoverage{
+		script("https://shop.example/app.js", &profiler.CoverageRange{StartOffset: 0, EndOffset: 100, Count: 1}, &profiler.CoverageRange{StartOffset: 50, EndOffset: 100, Count: 0}),
+		// Two inline scripts of the page.
+		script("https://shop.example/", &profiler.CoverageRange{StartOffset: 0, EndOffset: 10, Count: 1}),
+		script("https://shop.example/", &profiler.CoverageRange{StartOffset: 0, EndOffset: 30, Count: 0}),
+		// Evaluated by a tool.
+		script("", &profiler.CoverageRange{StartOffset: 0, EndOffset: 1000, Count: 1}),
+	})
+	slices.SortFunc(files, func(a, b FileCoverage) int { return strings.Compare(a.URL, b.URL) })
+	want := []FileCoverage{
+		{URL: "https://shop.example/", Type: coverageJS, TotalBytes: 40, UsedBytes: 10, UnusedBytes: 30, UnusedPercent: 75},
+		{URL: "https://shop.example/app.js", Type: coverageJS, TotalBytes: 100, UsedBytes: 50, UnusedBytes: 50, UnusedPercent: 50},
+	}
+	if !slices.Equal(files, want) {
+		t.Errorf("files = %+v, want %+v", files, want)
+	}
+	if *totals != (CoverageTotals{Files: 2, TotalBytes: 140, UsedBytes: 60, UnusedBytes: 80, UnusedPercent: 80.0 * 100 / 140}) {
+		t.Errorf("totals = %+v", totals)
+	}
+}
+
+func TestCSSCoverage(t *testing.T) {
+	sheets := map[css.StyleSheetID]*css.StyleSheetHeader{
+		"1": {StyleSheetID: "1", SourceURL: "https://shop.example/app.css", Length: 200},
+		"2": {StyleSheetID: "2", Length: 50, IsConstructed: true},
+	}
+	files, totals := cssCoverage([]*css.RuleUsage{
+		{StyleSheetID: "1", StartOffset: 0, EndOffset: 40, Used: true},
+		{StyleSheetID: "1", StartOffset: 41, EndOffset: 200, Used: false},
+		{StyleSheetID: "2", StartOffset: 0, EndOffset: 50, Used: true},
+		{StyleSheetID: "9", StartOffset: 0, EndOffset: 10, Used: true},
+	}, sheets)
+	if len(files) != 1 || files[0].URL != "https://shop.example/app.css" || files[0].UsedBytes != 40 || files[0].UnusedBytes != 160 || totals.Files != 1 {
+		t.Errorf("files = %+v, totals %+v", files, totals)
+	}
+}
+
+func TestCoverageReportString(t *testing.T) {
+	r := CoverageReport{
+		Duration: "2.5s",
+		JS:       &CoverageTotals{Files: 2, TotalBytes: 140, UsedBytes: 60, UnusedBytes: 80, UnusedPercent: 57.14},
+		Files: []FileCoverage{
+			{URL: "https://shop.example/app.js", Type: coverageJS, TotalBytes: 100, UsedBytes: 50, UnusedBytes: 50, UnusedPercent: 50},
+			{URL: "https://shop.example/", Type: coverageJS, TotalBytes: 40, UsedBytes: 10, UnusedBytes: 30, UnusedPercent: 75},
+		},
+		limit: 1,
+	}
+	want := "Coverage collected over 2.5s\n" +
+		"JS: 80 of 140 bytes unused (57.1%) in 2 files\n" +
+		"- [js] https://shop.example/app.js: 50 of 100 bytes unused (50.0%)\n" +
+		"... and 1 more files in the structured result"
+	if got := r.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestCoverageSession(t *testing.T) {
+	s := &CDPBrowserServer{}
+	ctx := context.Background()
+	stop, err := s.StopCoverage(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[StopCoverageArgs]]{Params: &mcp.CallToolParamsFor[StopCoverageArgs]{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := stop.Content[0].(*mcp.TextContent).Text; !stop.IsError || !strings.Contains(text, "call start_coverage first") {
+		t.Errorf("stop_coverage without start = %q", text)
+	}
+	start, err := s.StartCoverage(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[StartCoverageArgs]]{Params: &mcp.CallToolParamsFor[StartCoverageArgs]{Arguments: StartCoverageArgs{Types: []string{"html"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := start.Content[0].(*mcp.TextContent).Text; !start.IsError || !strings.Contains(text, `unknown coverage type "html"`) {
+		t.Errorf("start_coverage of html = %q", text)
+	}
+
+	if err := s.coverage.begin(true, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.coverage.begin(true, true); err == nil {
+		t.Error("coverage started twice")
+	}
+	s.coverage.end()
+	if err := s.coverage.begin(false, true); err != nil {
+		t.Errorf("coverage not restartable: %v", err)
+	}
+}
@@ -0,0 +1,498 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// crawlsTemplate is the URI template of the results of crawl.
+	crawlsTemplate = "browser://crawls/{id}"
+	// maxCrawls is the number of crawl results kept; older ones are dropped.
+	maxCrawls = 10
+	// defaultCrawlDepth and defaultCrawlPages are the defaults of the crawl
+	// arguments.
+	defaultCrawlDepth = 1
+	defaultCrawlPages = 100
+	// maxCrawlPages bounds max_pages.
+	maxCrawlPages = 1000
+	// maxCrawlFieldValues bounds the values extracted for one field of a
+	// page.
+	maxCrawlFieldValues = 50
+	// crawlPageTimeout bounds loading and extracting one page.
+	crawlPageTimeout = 30 * time.Second
+	// crawlSummaryPages is the number of pages listed in the text result;
+	// the resource has all of them.
+	crawlSummaryPages = 20
+)
+
+// crawlExtractJS collects the title, the link targets, and the requested
+// fields of the page. fields maps field names to {selector, xpath,
+// attribute}.
+const crawlExtractJS = `function(fields, max) {
+	const out = {title: document.title, links: [], fields: {}};
+	for (const a of document.querySelectorAll('a[href]')) {
+		out.links.push(a.href);
+	}
+	const queryAll = f => {
+		if (!f.xpath) {
+			return Array.from(document.querySelectorAll(f.selector));
+		}
+		const res = document.evaluate(f.selector, document, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+		return Array.from({length: res.snapshotLength}, (_, i) => res.snapshotItem(i));
+	};
+	for (const [name, f] of Object.entries(fields)) {
+		out.fields[name] = queryAll(f).slice(0, max).map(el =>
+			f.attribute ? (el.getAttribute(f.attribute) || '') : (el.innerText || el.textContent || '').trim());
+	}
+	return out;
+}`
+
+// crawlField is a field to extract from every crawled page.
+type crawlField struct {
+	Selector  string `json:"selector"`
+	XPath     bool   `json:"xpath,omitempty"`
+	Attribute string `json:"attribute,omitempty"`
+}
+
+// crawlAttributePattern matches the @attribute suffix of an extract selector.
+var crawlAttributePattern = regexp.MustCompile(`^(.+?)@([A-Za-z_:][-A-Za-z0-9_:.]*)$`)
+
+// parseCrawlFields parses the extract argument of crawl, where a selector
+// ending in @attribute extracts that attribute instead of the text. The
+// selectors are used as written, so a trailing /@attribute step stays part
+// of an XPath expression.
+func parseCrawlFields(extract map[string]string) (map[string]crawlField, error) {
+	fields := make(map[string]crawlField, len(extract))
+	for name, sel := range extract {
+		sel = strings.TrimSpace(sel)
+		if sel == "" {
+			return nil, fmt.Errorf("field %q has no selector", name)
+		}
+		var attr string
+		if m := crawlAttributePattern.FindStringSubmatch(sel); m != nil && !strings.HasSuffix(m[1], "/") {
+			sel, attr = strings.TrimSpace(m[1]), m[2]
+		}
+		loc := literalLocator(sel)
+		fields[name] = crawlField{Selector: loc.Query, XPath: loc.XPath, Attribute: attr}
+	}
+	return fields, nil
+}
+
+// CrawledPage is what crawl extracted from one page.
+type CrawledPage struct {
+	URL    string              `json:"url"`
+	Depth  int                 `json:"depth" jsonschema:"Number of links followed from the start page"`
+	Title  string              `json:"title,omitempty"`
+	Fields map[string][]string `json:"fields,omitempty" jsonschema:"Values of the extracted fields"`
+	Links  int                 `json:"links" jsonschema:"Number of links on the page matching the link pattern"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// crawlJob is a page for a crawl worker to visit.
+type crawlJob struct {
+	url   string
+	depth int
+}
+
+// crawlVisit is the outcome of a crawlJob.
+type crawlVisit struct {
+	job   crawlJob
+	page  *CrawledPage
+	links []string
+}
+
+// pageVisitor loads url in a worker's tab and returns what it extracted,
+// along with the absolute URLs the page links to.
+type pageVisitor func(ctx context.Context, url string) (*CrawledPage, []string)
+
+// crawler schedules a breadth-first crawl over parallel pageVisitors.
+type crawler struct {
+	maxDepth int
+	maxPages int
+	// follow reports whether links to url are followed.
+	follow func(url string) bool
+	// allowed returns an error if the navigation policy blocks url.
+	allowed func(url string) error
+	// progress, if set, is called after each visited page.
+	progress func(page *CrawledPage, visited, queued int)
+}
+
+// crawlOutcome is the result of crawler.run.
+type crawlOutcome struct {
+	pages []*CrawledPage
+	// unvisited is the number of followed links left when the crawl stopped
+	// at maxPages or was cancelled.
+	unvisited int
+	// blocked is the number of followed links the navigation policy blocks.
+	blocked int
+}
+
+// normalizeCrawlURL returns link without its fragment, or "" if it is not an
+// http or https URL.
+func normalizeCrawlURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// run crawls from start, handing pages to one goroutine per visitor. Each
+// page is visited at most once, and pages closer to start are visited
+// first.
+func (c *crawler) run(ctx context.Context, start string, visitors []pageVisitor) crawlOutcome {
+	jobs := make(chan crawlJob)
+	visits := make(chan crawlVisit)
+	var wg sync.WaitGroup
+	for _, visit := range visitors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				page, links := visit(ctx, job.url)
+				select {
+				case visits <- crawlVisit{job, page, links}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(jobs)
+
+	var out crawlOutcome
+	seen := map[string]bool{start: true, normalizeCrawlURL(start): true}
+	pending := []crawlJob{{url: start}}
+	inflight := 0
+	for ctx.Err() == nil {
+		var next chan crawlJob
+		if len(pending) > 0 && len(out.pages)+inflight < c.maxPages {
+			next = jobs
+		}
+		if next == nil && inflight == 0 {
+			break
+		}
+		var job crawlJob
+		if next != nil {
+			job = pending[0]
+		}
+		select {
+		case next <- job:
+			pending = pending[1:]
+			inflight++
+		case v := <-visits:
+			inflight--
+			v.page.URL, v.page.Depth = v.job.url, v.job.depth
+			for _, link := range v.links {
+				if link = normalizeCrawlURL(link); link == "" || !c.follow(link) {
+					continue
+				}
+				v.page.Links++
+				if seen[link] || v.job.depth >= c.maxDepth {
+					continue
+				}
+				seen[link] = true
+				if err := c.allowed(link); err != nil {
+					out.blocked++
+					continue
+				}
+				pending = append(pending, crawlJob{url: link, depth: v.job.depth + 1})
+			}
+			out.pages = append(out.pages, v.page)
+			if c.progress != nil {
+				c.progress(v.page, len(out.pages), len(pending)+inflight)
+			}
+		case <-ctx.Done():
+		}
+	}
+	out.unvisited = len(pending) + inflight
+	return out
+}
+
+// CrawlResult is the structured result of crawl.
+type CrawlResult struct {
+	URI       string `json:"uri" jsonschema:"browser://crawls resource with the pages and their extracted fields"`
+	StartURL  string `json:"start_url"`
+	Visited   int    `json:"visited" jsonschema:"Number of pages visited"`
+	Failed    int    `json:"failed" jsonschema:"Number of visited pages that failed to load or extract"`
+	Unvisited int    `json:"unvisited" jsonschema:"Number of matching links not visited because max_pages was reached or the crawl was cancelled"`
+	Blocked   int    `json:"blocked,omitempty" jsonschema:"Number of matching links the navigation policy blocks"`
+	Workers   int    `json:"workers"`
+	Elapsed   string `json:"elapsed"`
+}
+
+// crawlReport is the content of a browser://crawls resource.
+type crawlReport struct {
+	Crawl CrawlResult    `json:"crawl"`
+	Pages []*CrawledPage `json:"pages"`
+}
+
+// String formats the summary and the first pages of a crawl for the text
+// result of crawl.
+func (r *crawlReport) String() string {
+	var b strings.Builder
+	c := r.Crawl
+	fmt.Fprintf(&b, "Crawled %d pages from %s in %s with %d tabs", c.Visited, c.StartURL, c.Elapsed, c.Workers)
+	var notes []string
+	if c.Failed > 0 {
+		notes = append(notes, fmt.Sprintf("%d failed", c.Failed))
+	}
+	if c.Unvisited > 0 {
+		notes = append(notes, fmt.Sprintf("%d links not visited", c.Unvisited))
+	}
+	if c.Blocked > 0 {
+		notes = append(notes, fmt.Sprintf("%d links blocked by the navigation policy", c.Blocked))
+	}
+	if len(notes) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(notes, ", "))
+	}
+	fmt.Fprintf(&b, "\nRESULTS: %s\n", c.URI)
+	for i, p := range r.Pages {
+		if i == crawlSummaryPages {
+			fmt.Fprintf(&b, "... %d more pages in %s\n", len(r.Pages)-i, c.URI)
+			break
+		}
+		if p.Error != "" {
+			fmt.Fprintf(&b, "- [depth %d] %s FAILED: %s\n", p.Depth, p.URL, p.Error)
+			continue
+		}
+		fmt.Fprintf(&b, "- [depth %d] %s %q", p.Depth, p.URL, p.Title)
+		names := make([]string, 0, len(p.Fields))
+		for name := range p.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, " %s=%d", name, len(p.Fields[name]))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// crawlStore keeps the most recent crawl reports for the browser://crawls
+// resources.
+type crawlStore struct {
+	mu      sync.Mutex
+	nextID  int
+	reports map[int][]byte
+}
+
+// add stores the JSON of a report and returns its URI, dropping the oldest
+// one when the store is full. The URI is set in the report before it is
+// encoded.
+func (st *crawlStore) add(r *crawlReport) (string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.reports == nil {
+		st.reports = make(map[int][]byte)
+	}
+	id := st.nextID + 1
+	r.Crawl.URI = strings.Replace(crawlsTemplate, "{id}", strconv.Itoa(id), 1)
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	st.nextID = id
+	st.reports[id] = data
+	delete(st.reports, id-maxCrawls)
+	return r.Crawl.URI, nil
+}
+
+// get returns the report stored under uri.
+func (st *crawlStore) get(uri string) ([]byte, bool) {
+	id, err := strconv.Atoi(strings.TrimPrefix(uri, "browser://crawls/"))
+	if err != nil {
+		return nil, false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	data, ok := st.reports[id]
+	return data, ok
+}
+
+// readCrawl serves the browser://crawls resources.
+func (s *CDPBrowserServer) readCrawl(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	data, ok := s.crawls.get(uri)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: uri, MIMEType: "application/json", Text: string(data)},
+		},
+	}, nil
+}
+
+// crawlVisitor returns a pageVisitor that loads pages in the tab of tabCtx
+// and extracts fields from them.
+func (s *CDPBrowserServer) crawlVisitor(tabCtx context.Context, fields map[string]crawlField) pageVisitor {
+	return func(ctx context.Context, url string) (*CrawledPage, []string) {
+		page := &CrawledPage{URL: url}
+		if err := s.rateLimiter.step(ctx, "navigate"); err != nil {
+			page.Error = err.Error()
+			return page, nil
+		}
+		var out struct {
+			Title  string              `json:"title"`
+			Links  []string            `json:"links"`
+			Fields map[string][]string `json:"fields"`
+		}
+		err := runInTab(ctx, tabCtx, crawlPageTimeout,
+			chromedp.Navigate(url),
+			chromedp.Evaluate(callJS(crawlExtractJS, fields, maxCrawlFieldValues), &out),
+		)
+		if err != nil {
+			page.Error = err.Error()
+			return page, nil
+		}
+		page.Title = out.Title
+		if len(out.Fields) > 0 {
+			page.Fields = out.Fields
+		}
+		return page, out.Links
+	}
+}
+
+type CrawlArgs struct {
+	URL         string            `json:"url" jsonschema:"Page to start crawling from"`
+	LinkPattern string            `json:"link_pattern,omitempty" jsonschema:"Regular expression the URLs of followed links must match (default: links to the start page's host)"`
+	MaxDepth    *int              `json:"max_depth,omitempty" jsonschema:"How many links away from the start page to follow; 0 visits only the start page (default: 1)"`
+	MaxPages    int               `json:"max_pages,omitempty" jsonschema:"Maximum number of pages to visit, up to 1000 (default: 100)"`
+	Workers     int               `json:"workers,omitempty" jsonschema:"Number of tabs crawling in parallel, up to 16 (default: 4)"`
+	Extract     map[string]string `json:"extract,omitempty" jsonschema:"Fields to extract from every page, as field name to CSS selector, XPath expression starting with /, or xpath= or text= prefixed selector. A field holds the text of the matching elements, or their values of an attribute if the selector ends in @attribute, e.g. img.product@src"`
+}
+
+// newCrawler checks args and returns the crawler and the fields they
+// describe.
+func (s *CDPBrowserServer) newCrawler(args *CrawlArgs) (*crawler, map[string]crawlField, error) {
+	start, err := url.Parse(args.URL)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") {
+		return nil, nil, invalidArgumentError{fmt.Errorf("invalid start URL %q", args.URL)}
+	}
+	if err := s.policy.check(args.URL); err != nil {
+		return nil, nil, err
+	}
+	c := &crawler{
+		maxDepth: defaultCrawlDepth,
+		maxPages: defaultCrawlPages,
+		allowed:  s.policy.check,
+	}
+	if args.MaxDepth != nil {
+		c.maxDepth = max(*args.MaxDepth, 0)
+	}
+	if args.MaxPages > 0 {
+		c.maxPages = min(args.MaxPages, maxCrawlPages)
+	}
+	if args.LinkPattern != "" {
+		re, err := regexp.Compile(args.LinkPattern)
+		if err != nil {
+			return nil, nil, invalidArgumentError{fmt.Errorf("invalid link pattern: %v", err)}
+		}
+		c.follow = re.MatchString
+	} else {
+		c.follow = func(link string) bool {
+			u, err := url.Parse(link)
+			return err == nil && u.Host == start.Host
+		}
+	}
+	fields, err := parseCrawlFields(args.Extract)
+	if err != nil {
+		return nil, nil, invalidArgumentError{err}
+	}
+	return c, fields, nil
+}
+
+// Crawl tool - crawls a site from a start URL in parallel tabs, extracting
+// fields from every page
+func (s *CDPBrowserServer) Crawl(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[CrawlArgs]]) (*mcp.CallToolResultFor[CrawlResult], error) {
+	args := req.Params.Arguments
+	fail := func(err error, code ErrorCode) (*mcp.CallToolResultFor[CrawlResult], error) {
+		noteToolError(ctx, err, code)
+		return &mcp.CallToolResultFor[CrawlResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error crawling %s: %v", args.URL, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	c, fields, err := s.newCrawler(&args)
+	if err != nil {
+		return fail(err, "")
+	}
+	tabs, closeTabs, err := s.openWorkerTabs(min(workerTabs(args.Workers), c.maxPages))
+	if err != nil {
+		return fail(err, "")
+	}
+	defer closeTabs()
+	var visitors []pageVisitor
+	for _, tab := range tabs {
+		visitors = append(visitors, s.crawlVisitor(tab, fields))
+	}
+
+	if token := req.Params.GetProgressToken(); token != nil && req.Session != nil {
+		c.progress = func(page *CrawledPage, visited, queued int) {
+			err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+				ProgressToken: token,
+				Progress:      float64(visited),
+				Total:         float64(min(visited+queued, c.maxPages)),
+				Message:       fmt.Sprintf("Crawled %d pages, %d queued: %s", visited, queued, page.URL),
+			})
+			if err != nil {
+				log.Printf("Failed to report progress: %v", err)
+			}
+		}
+	}
+
+	began := time.Now()
+	out := c.run(ctx, args.URL, visitors)
+	report := &crawlReport{
+		Crawl: CrawlResult{
+			StartURL:  args.URL,
+			Visited:   len(out.pages),
+			Unvisited: out.unvisited,
+			Blocked:   out.blocked,
+			Workers:   len(visitors),
+			Elapsed:   time.Since(began).Round(time.Millisecond).String(),
+		},
+		Pages: out.pages,
+	}
+	for _, p := range out.pages {
+		if p.Error != "" {
+			report.Crawl.Failed++
+		}
+	}
+	uri, err := s.crawls.add(report)
+	if err != nil {
+		return fail(err, "")
+	}
+	return &mcp.CallToolResultFor[CrawlResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: report.String()},
+			&mcp.ResourceLink{
+				URI:         uri,
+				Name:        "crawl results",
+				Description: "Pages visited by the crawl and their extracted fields",
+				MIMEType:    "application/json",
+			},
+		},
+		StructuredContent: report.Crawl,
+	}, nil
+}
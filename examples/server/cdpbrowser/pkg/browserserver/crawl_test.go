@@ -0,0 +1,220 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// testSite maps the pages of a site to the links on them.
+var testSite = map[string][]string{
+	"https://shop.example/":         {"https://shop.example/a#top", "https://shop.example/b", "mailto:sales@shop.example", "https://ads.example/"},
+	"https://shop.example/a":        {"https://shop.example/", "https://shop.example/a/1", "https://shop.example/a/2"},
+	"https://shop.example/b":        {"https://shop.example/a", "https://shop.example/b/1"},
+	"https://shop.example/a/1":      {"https://shop.example/a/1/deep"},
+	"https://shop.example/a/2":      nil,
+	"https://shop.example/b/1":      nil,
+	"https://shop.example/a/1/deep": nil,
+}
+
+// siteVisitors returns n visitors of testSite that record the pages they
+// visit in *visited.
+func siteVisitors(n int, visited *[]string) []pageVisitor {
+	var mu sync.Mutex
+	visitors := make([]pageVisitor, n)
+	for i := range visitors {
+		visitors[i] = func(ctx context.Context, url string) (*CrawledPage, []string) {
+			mu.Lock()
+			*visited = append(*visited, url)
+			mu.Unlock()
+			links, ok := testSite[url]
+			if !ok {
+				return &CrawledPage{Error: "404"}, nil
+			}
+			return &CrawledPage{Title: url}, links
+		}
+	}
+	return visitors
+}
+
+func TestCrawler(t *testing.T) {
+	c := &crawler{
+		maxDepth: 2,
+		maxPages: 100,
+		follow:   func(url string) bool { return strings.HasPrefix(url, "https://shop.example/") },
+		allowed:  func(string) error { return nil },
+	}
+	var progress []int
+	c.progress = func(page *CrawledPage, visited, queued int) { progress = append(progress, visited) }
+
+	var visited []string
+	out := c.run(context.Background(), "https://shop.example/", siteVisitors(3, &visited))
+	want := []string{
+		"https://shop.example/",
+		"https://shop.example/a",
+		"https://shop.example/a/1",
+		"https://shop.example/a/2",
+		"https://shop.example/b",
+		"https://shop.example/b/1",
+	}
+	slices.Sort(visited)
+	if !slices.Equal(visited, want) {
+		t.Errorf("visited %q, want %q", visited, want)
+	}
+	if len(out.pages) != len(want) || out.unvisited != 0 || !slices.Equal(progress, []int{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("crawl = %+v, progress %v", out, progress)
+	}
+	for _, p := range out.pages {
+		if p.URL == "https://shop.example/" && (p.Depth != 0 || p.Links != 2) {
+			t.Errorf("start page = %+v, want depth 0 and 2 matching links", p)
+		}
+		if p.URL == "https://shop.example/a/1" && (p.Depth != 2 || p.Links != 1) {
+			t.Errorf("page at the maximum depth = %+v, want depth 2 and 1 matching link", p)
+		}
+	}
+
+	// Pages beyond max_pages are counted as unvisited, and blocked links are
+	// not visited.
+	c.maxPages = 3
+	c.allowed = func(url string) error {
+		if strings.HasSuffix(url, "/b") {
+			return errors.New("blocked")
+		}
+		return nil
+	}
+	visited = nil
+	out = c.run(context.Background(), "https://shop.example/", siteVisitors(1, &visited))
+	if len(out.pages) != 3 || out.blocked != 1 || out.unvisited != 1 {
+		t.Errorf("limited crawl = %d pages, %d blocked, %d unvisited; visited %q", len(out.pages), out.blocked, out.unvisited, visited)
+	}
+	if slices.Contains(visited, "https://shop.example/b") {
+		t.Error("blocked page visited")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	out = c.run(ctx, "https://shop.example/", siteVisitors(2, &visited))
+	if len(out.pages) != 0 || out.unvisited != 1 {
+		t.Errorf("cancelled crawl = %+v", out)
+	}
+}
+
+func TestParseCrawlFields(t *testing.T) {
+	fields, err := parseCrawlFields(map[string]string{
+		"price": " .price ",
+		"image": "img.product@src",
+		"email": `a[href^="mailto:"]@data-user`,
+		"links": "xpath=//a/@href",
+		"alt":   "xpath=//img[@alt]@src",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]crawlField{
+		"price": {Selector: ".price"},
+		"image": {Selector: "img.product", Attribute: "src"},
+		"email": {Selector: `a[href^="mailto:"]`, Attribute: "data-user"},
+		"links": {Selector: "//a/@href", XPath: true},
+		"alt":   {Selector: "//img[@alt]", XPath: true, Attribute: "src"},
+	}
+	for name, f := range want {
+		if fields[name] != f {
+			t.Errorf("field %s = %+v, want %+v", name, fields[name], f)
+		}
+	}
+	if _, err := parseCrawlFields(map[string]string{"price": " "}); err == nil {
+		t.Error("empty selector accepted")
+	}
+}
+
+func TestCrawlArgs(t *testing.T) {
+	s := &CDPBrowserServer{policy: &navigationPolicy{DenyDomains: []string{"ads.example"}}}
+	zero := 0
+	c, _, err := s.newCrawler(&CrawlArgs{URL: "https://shop.example/", MaxDepth: &zero, MaxPages: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.maxDepth != 0 || c.maxPages != maxCrawlPages {
+		t.Errorf("crawler = depth %d, pages %d", c.maxDepth, c.maxPages)
+	}
+	if !c.follow("https://shop.example/a") || c.follow("https://blog.example/") {
+		t.Error("default link pattern does not follow the start host only")
+	}
+	if c.allowed("https://ads.example/") == nil {
+		t.Error("crawler ignores the navigation policy")
+	}
+
+	for _, args := range []CrawlArgs{
+		{URL: "file:///etc/passwd"},
+		{URL: "https://ads.example/"},
+		{URL: "https://shop.example/", LinkPattern: "("},
+		{URL: "https://shop.example/", Extract: map[string]string{"x": ""}},
+	} {
+		if _, _, err := s.newCrawler(&args); err == nil {
+			t.Errorf("newCrawler(%+v) succeeded", args)
+		}
+	}
+}
+
+func TestCrawlResource(t *testing.T) {
+	s := &CDPBrowserServer{}
+	var uris []string
+	for i := range maxCrawls + 1 {
+		uri, err := s.crawls.add(&crawlReport{
+			Crawl: CrawlResult{StartURL: "https://shop.example/", Visited: i},
+			Pages: []*CrawledPage{{URL: "https://shop.example/", Title: "Shop", Fields: map[string][]string{"price": {"$1"}}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		uris = append(uris, uri)
+	}
+	if uris[0] != "browser://crawls/1" {
+		t.Errorf("first URI = %s", uris[0])
+	}
+
+	read := func(uri string) (*mcp.ReadResourceResult, error) {
+		return s.readCrawl(context.Background(), &mcp.ServerRequest[*mcp.ReadResourceParams]{Params: &mcp.ReadResourceParams{URI: uri}})
+	}
+	if _, err := read(uris[0]); err == nil {
+		t.Error("oldest crawl not dropped")
+	}
+	res, err := read(uris[maxCrawls])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report crawlReport
+	if err := json.Unmarshal([]byte(res.Contents[0].Text), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Crawl.URI != uris[maxCrawls] || report.Crawl.Visited != maxCrawls || report.Pages[0].Fields["price"][0] != "$1" {
+		t.Errorf("crawl resource = %+v", report)
+	}
+}
+
+func TestCrawlReportString(t *testing.T) {
+	r := &crawlReport{
+		Crawl: CrawlResult{URI: "browser://crawls/3", StartURL: "https://shop.example/", Visited: 2, Failed: 1, Unvisited: 4, Workers: 2, Elapsed: "1.5s"},
+		Pages: []*CrawledPage{
+			{URL: "https://shop.example/", Title: "Shop", Fields: map[string][]string{"price": {"$1", "$2"}, "name": {"A"}}},
+			{URL: "https://shop.example/gone", Depth: 1, Error: "net::ERR_NAME_NOT_RESOLVED"},
+		},
+	}
+	text := r.String()
+	for _, line := range []string{
+		"Crawled 2 pages from https://shop.example/ in 1.5s with 2 tabs (1 failed, 4 links not visited)\n",
+		"RESULTS: browser://crawls/3\n",
+		`- [depth 0] https://shop.example/ "Shop" name=1 price=2` + "\n",
+		"- [depth 1] https://shop.example/gone FAILED: net::ERR_NAME_NOT_RESOLVED\n",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("report lacks %q:\n%s", line, text)
+		}
+	}
+}
@@ -0,0 +1,1280 @@
+package browserserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// The tests in this file drive every tool through an in-memory MCP client
+// session against a headless Chrome and a local fixture server. They are
+// skipped in -short mode and when no Chrome binary can be found; set
+// CHROME_PATH to point them at a specific browser.
+
+// findTestChrome returns the path of a Chrome binary to use for end-to-end
+// tests, or "" if none is installed.
+func findTestChrome() string {
+	if path := os.Getenv("CHROME_PATH"); path != "" {
+		return path
+	}
+	for _, name := range []string{
+		"google-chrome-stable",
+		"google-chrome",
+		"chromium",
+		"chromium-browser",
+		"headless-shell",
+	} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// newTestSession launches headless Chrome, wraps it in a CDPBrowserServer,
+// and returns the server along with a client session connected to it.
+func newTestSession(t *testing.T) (*CDPBrowserServer, *mcp.ClientSession) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping end-to-end test in short mode")
+	}
+	execPath := findTestChrome()
+	if execPath == "" {
+		t.Skip("Chrome not found; set CHROME_PATH to run end-to-end tests")
+	}
+
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.ExecPath(execPath),
+		chromedp.NoSandbox,
+	)
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	s := &CDPBrowserServer{
+		ctx:            ctx,
+		cancel:         cancel,
+		allocCtx:       allocCtx,
+		allocCancel:    allocCancel,
+		keepChromeOpen: true,
+	}
+	t.Cleanup(s.Close)
+
+	// Running no actions starts the browser.
+	if err := chromedp.Run(ctx); err != nil {
+		t.Fatalf("starting Chrome: %v", err)
+	}
+	if err := s.probeCapabilities(); err != nil {
+		t.Fatalf("probeCapabilities() error = %v", err)
+	}
+	if err := s.state.start(ctx, s.stateChanged); err != nil {
+		t.Fatalf("starting the state tracker: %v", err)
+	}
+	s.pageErrors.start(ctx)
+	s.websockets.start(ctx)
+	s.elementCache.start(ctx)
+	if err := s.routes.start(ctx); err != nil {
+		t.Fatalf("starting the route tracker: %v", err)
+	}
+
+	s.allowRawCDP = true
+	s.vault = &credentialVault{credentials: map[string]*Credential{
+		"local":  {Username: "alice@example.com", Password: "correct-horse", Domains: []string{"127.0.0.1"}},
+		"remote": {Username: "bob", Password: "battery-staple", Domains: []string{"example.com"}},
+	}}
+	s.secrets.add(s.vault.secrets()...)
+	server := newMCPServer(s)
+	s.mcpServer = server
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "e2e"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		clientSession.Close()
+		serverSession.Wait()
+	})
+	return s, clientSession
+}
+
+// newFixtureServer serves the HTML fixtures in testdata/fixtures, and a
+// WebSocket at /echo that answers each text message with "echo: " and the
+// message.
+func newFixtureServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir("testdata/fixtures")))
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _, err := ws.UpgradeHTTP(r, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			msg, err := wsutil.ReadClientText(conn)
+			if err != nil {
+				return
+			}
+			if err := wsutil.WriteServerText(conn, append([]byte("echo: "), msg...)); err != nil {
+				return
+			}
+		}
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// callTool calls the named tool and fails the test if the call errors or the
+// tool reports an error.
+func callTool(t *testing.T, cs *mcp.ClientSession, name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("CallTool(%s) error = %v", name, err)
+	}
+	if res.IsError {
+		t.Fatalf("CallTool(%s) returned a tool error: %s", name, resultText(res))
+	}
+	return res
+}
+
+// callToolError calls the named tool and returns the text of the tool error
+// it reports, failing the test if the call succeeds.
+func callToolError(t *testing.T, cs *mcp.ClientSession, name string, args map[string]any) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("CallTool(%s) error = %v", name, err)
+	}
+	if !res.IsError {
+		t.Fatalf("CallTool(%s) succeeded, want a tool error: %s", name, resultText(res))
+	}
+	return resultText(res)
+}
+
+// resultText concatenates the text content of a tool result.
+func resultText(res *mcp.CallToolResult) string {
+	var texts []string
+	for _, c := range res.Content {
+		if tc, ok := c.(*mcp.TextContent); ok {
+			texts = append(texts, tc.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// evalString evaluates a JavaScript expression in the page and returns its
+// string result.
+func evalString(t *testing.T, s *CDPBrowserServer, expr string) string {
+	t.Helper()
+	var out string
+	if err := chromedp.Run(s.ctx, chromedp.Evaluate(expr, &out)); err != nil {
+		t.Fatalf("evaluating %q: %v", expr, err)
+	}
+	return out
+}
+
+func TestEndToEndTools(t *testing.T) {
+	s, cs := newTestSession(t)
+	s.actionTimeout = 2 * time.Second
+	s.errorArtifacts = errorArtifactsInline
+	fixtures := newFixtureServer(t)
+
+	res, err := cs.ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := make(map[string]bool)
+	for _, tool := range res.Tools {
+		listed[tool.Name] = true
+	}
+	for tool := range toolDomains {
+		if !listed[tool] {
+			t.Errorf("tool %s is not listed", tool)
+		}
+	}
+
+	t.Run("navigate", func(t *testing.T) {
+		text := resultText(callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/form.html"}))
+		if !strings.Contains(text, "Navigated to") {
+			t.Errorf("navigate result = %q", text)
+		}
+		if got := evalString(t, s, "document.title"); got != "Form Fixture" {
+			t.Errorf("document.title = %q, want %q", got, "Form Fixture")
+		}
+	})
+
+	t.Run("aria_snapshot", func(t *testing.T) {
+		text := resultText(callTool(t, cs, "aria_snapshot", map[string]any{"format": "llm-text", "focus": "all"}))
+		for _, want := range []string{"PAGE: Form Fixture", "INTERACTIVE ELEMENTS:", "Submit form", "Username", "HEADINGS:"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("aria_snapshot text missing %q:\n%s", want, text)
+			}
+		}
+
+		res := callTool(t, cs, "aria_snapshot", map[string]any{"format": "json", "focus": "interactive"})
+		if res.StructuredContent == nil {
+			t.Error("aria_snapshot returned no structured content")
+		}
+
+		text = resultText(callTool(t, cs, "aria_snapshot", map[string]any{"format": "compact", "max_elements": 1}))
+		if !strings.Contains(text, "[showing elements 1-1 of") || !strings.Contains(text, "call aria_snapshot again with offset 1") {
+			t.Errorf("aria_snapshot with max_elements has no truncation footer:\n%s", text)
+		}
+
+		text = resultText(callTool(t, cs, "aria_snapshot", map[string]any{"format": "compact", "focus": "region", "region": "#signup"}))
+		if !strings.Contains(text, "#username") {
+			t.Errorf("region snapshot missing #username:\n%s", text)
+		}
+	})
+
+	t.Run("annotated_screenshot", func(t *testing.T) {
+		res := callTool(t, cs, "annotated_screenshot", nil)
+		if img, ok := res.Content[0].(*mcp.ImageContent); !ok || len(img.Data) == 0 {
+			t.Fatalf("annotated_screenshot content[0] = %T, want non-empty image", res.Content[0])
+		}
+		text := resultText(res)
+		if !strings.Contains(text, "#username") {
+			t.Errorf("annotated_screenshot marks missing #username:\n%s", text)
+		}
+		if got := evalString(t, s, "String(document.getElementById('"+annotationOverlayID+"'))"); got != "null" {
+			t.Errorf("overlay was not removed: %s", got)
+		}
+	})
+
+	t.Run("capture_long_page", func(t *testing.T) {
+		evalString(t, s, "document.body.style.minHeight = '10000px', ''")
+		defer evalString(t, s, "document.body.style.minHeight = '', window.scrollTo(0, 0), ''")
+
+		res := callTool(t, cs, "capture_long_page", map[string]any{"max_tiles": 2})
+		var capture LongPageCapture
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &capture); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if len(capture.Tiles) != 2 || !capture.Truncated || capture.Tiles[1].Y != capture.Tiles[0].Height-defaultTileOverlap {
+			t.Fatalf("capture_long_page = %+v, want 2 overlapping tiles of a truncated page", capture)
+		}
+		if images := len(res.Content) - 1; images != 2 {
+			t.Errorf("capture_long_page returned %d images, want 2", images)
+		}
+		if !strings.Contains(capture.Tiles[0].String(), "#username") {
+			t.Errorf("first tile elements = %s, want #username", capture.Tiles[0].String())
+		}
+		if got := evalString(t, s, "String(window.scrollY)"); got != "0" {
+			t.Errorf("scroll position after the capture = %s, want it restored", got)
+		}
+
+		res = callTool(t, cs, "capture_long_page", map[string]any{"max_tiles": 2, "stitch": true})
+		link, ok := res.Content[1].(*mcp.ResourceLink)
+		if !ok {
+			t.Fatalf("stitched capture content[1] = %T, want a resource link", res.Content[1])
+		}
+		png, ok := s.screenshots.get(link.URI)
+		if !ok {
+			t.Fatalf("stitched image %s not stored", link.URI)
+		}
+		img, err := decodeScreenshot(png, "stitched")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Dy() <= capture.Tiles[0].Height {
+			t.Errorf("stitched image is %v, want it taller than one tile", img.Bounds())
+		}
+	})
+
+	t.Run("reader resources", func(t *testing.T) {
+		read := func(uri string) string {
+			res, err := cs.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: uri})
+			if err != nil {
+				t.Fatalf("ReadResource(%s): %v", uri, err)
+			}
+			return res.Contents[0].Text
+		}
+		markdown := read(pageMarkdownURI)
+		for _, want := range []string{"# Form Fixture\n", "Username", "[Open dialog page](" + fixtures.URL + "/dialog.html)"} {
+			if !strings.Contains(markdown, want) {
+				t.Errorf("%s = %q, want it to contain %q", pageMarkdownURI, markdown, want)
+			}
+		}
+		// The site header and the form controls are left out.
+		if strings.Contains(markdown, "Sign up") || strings.Contains(markdown, "Click me") {
+			t.Errorf("%s = %q, want only the main content", pageMarkdownURI, markdown)
+		}
+		if text := read(pageTextURI); !strings.Contains(text, "Open dialog page") || strings.Contains(text, "](") {
+			t.Errorf("%s = %q, want the plain text of the page", pageTextURI, text)
+		}
+	})
+
+	t.Run("element_ids", func(t *testing.T) {
+		snapshotIDs := func() map[string]int {
+			res := callTool(t, cs, "aria_snapshot", map[string]any{"format": "json", "focus": "interactive"})
+			data, err := json.Marshal(res.StructuredContent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var snapshot ARIASnapshotResult
+			if err := json.Unmarshal(data, &snapshot); err != nil {
+				t.Fatal(err)
+			}
+			ids := make(map[string]int)
+			for _, el := range snapshot.Interactive {
+				ids[el.Selector] = el.ID
+			}
+			return ids
+		}
+		first, second := snapshotIDs(), snapshotIDs()
+		id := first["#username"]
+		if id == 0 {
+			t.Fatalf("aria_snapshot has no id for #username: %v", first)
+		}
+		if second["#username"] != id {
+			t.Errorf("#username id changed between snapshots: %d, then %d", id, second["#username"])
+		}
+
+		callTool(t, cs, "type_into_element_by_id", map[string]any{"id": id, "text": "bob", "clear": true})
+		if got := evalString(t, s, "document.getElementById('username').value"); got != "bob" {
+			t.Errorf("username value = %q, want %q", got, "bob")
+		}
+		callTool(t, cs, "click_element_by_id", map[string]any{"id": id})
+		if got := evalString(t, s, "document.activeElement.id"); got != "username" {
+			t.Errorf("focused element = %q, want %q", got, "username")
+		}
+		res := callTool(t, cs, "screenshot_element_by_id", map[string]any{"id": id})
+		if img, ok := res.Content[0].(*mcp.ImageContent); !ok || len(img.Data) == 0 {
+			t.Errorf("screenshot_element_by_id content = %T, want non-empty image", res.Content[0])
+		}
+	})
+
+	t.Run("find_text", func(t *testing.T) {
+		res := callTool(t, cs, "find_text", map[string]any{"query": "open DIALOG"})
+		data, err := json.Marshal(res.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found FindTextResult
+		if err := json.Unmarshal(data, &found); err != nil {
+			t.Fatal(err)
+		}
+		if found.Total != 1 || len(found.Matches) != 1 {
+			t.Fatalf("find_text found %d matches, want 1:\n%s", found.Total, resultText(res))
+		}
+		m := found.Matches[0]
+		if m.Text != "Open dialog" || m.After != " page" || m.ClickableTag != "a" || m.ClickableID == 0 {
+			t.Errorf("find_text match = %+v, want the dialog link", m)
+		}
+
+		text := resultText(callTool(t, cs, "find_text", map[string]any{"query": "^(Plan|Red)$", "regex": true, "case_sensitive": true}))
+		if !strings.Contains(text, "2 matches") || !strings.Contains(text, "<label>") {
+			t.Errorf("find_text regex result:\n%s", text)
+		}
+	})
+
+	t.Run("find_element", func(t *testing.T) {
+		res := callTool(t, cs, "find_element", map[string]any{"description": "the submit button in the main area"})
+		data, err := json.Marshal(res.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found FindElementResult
+		if err := json.Unmarshal(data, &found); err != nil {
+			t.Fatal(err)
+		}
+		if len(found.Candidates) == 0 || found.Candidates[0].Selector != `[aria-label="Submit form"]` {
+			t.Errorf("find_element best candidate is not the submit button:\n%s", resultText(res))
+		}
+	})
+
+	t.Run("type_text", func(t *testing.T) {
+		// Resolved through the placeholder strategy of the smart selector.
+		res := callTool(t, cs, "type_text", map[string]any{"selector": "Your name", "text": "alice", "clear": true})
+		if got := evalString(t, s, "document.getElementById('username').value"); got != "alice" {
+			t.Errorf("username value = %q, want %q", got, "alice")
+		}
+		if text := resultText(res); !strings.Contains(text, "strategy: placeholder") {
+			t.Errorf("type_text result = %q, want the placeholder strategy", text)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		strict, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "type_text", Arguments: map[string]any{"selector": "Your name", "text": "x", "strict": true}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strict.IsError {
+			t.Errorf("strict type_text with a placeholder succeeded: %s", resultText(strict))
+		}
+	})
+
+	t.Run("select_dropdown", func(t *testing.T) {
+		res := callTool(t, cs, "select_dropdown", map[string]any{"selector": "plan", "value": "pro"})
+		if text := resultText(res); !strings.Contains(text, "strategy: id") {
+			t.Errorf("select_dropdown result = %q, want the id strategy", text)
+		}
+	})
+
+	t.Run("choose_option", func(t *testing.T) {
+		// checked defaults to true, and the change handler must run.
+		callTool(t, cs, "choose_option", map[string]any{"selector": "#terms"})
+		if got := evalString(t, s, "String(document.getElementById('terms').checked) + ' ' + document.getElementById('terms').dataset.changes"); got != "true 1" {
+			t.Errorf("terms checked and change events = %q, want %q", got, "true 1")
+		}
+		res := callTool(t, cs, "choose_option", map[string]any{"selector": "terms", "checked": true})
+		var result ChooseOptionResult
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if !result.Checked || result.Changed || result.Strategy != "id" {
+			t.Errorf("choose_option on a checked box = %+v, want checked and unchanged via the id strategy", result)
+		}
+		callTool(t, cs, "choose_option", map[string]any{"selector": "#terms", "checked": false})
+		if got := evalString(t, s, "String(document.getElementById('terms').checked)"); got != "false" {
+			t.Errorf("terms checked after unchecking = %q, want %q", got, "false")
+		}
+	})
+
+	t.Run("click_button", func(t *testing.T) {
+		// Resolved through the aria-label strategy of the smart selector.
+		res := callTool(t, cs, "click_button", map[string]any{"selector": "Submit form"})
+		if got := evalString(t, s, "document.getElementById('status').textContent"); got != "Submitted alice" {
+			t.Errorf("status = %q, want %q", got, "Submitted alice")
+		}
+		var match SelectorMatch
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &match); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if match.Strategy != "aria-label" || match.Selector != `[aria-label="Submit form"]` {
+			t.Errorf("click_button matched %+v, want the aria-label strategy", match)
+		}
+
+		// Typed selectors are used as their prefix says.
+		evalString(t, s, "document.getElementById('status').textContent = ''")
+		res = callTool(t, cs, "click_button", map[string]any{"selector": "text=Submit"})
+		if text := resultText(res); !strings.Contains(text, "strategy: text=") {
+			t.Errorf("click_button with text= = %q", text)
+		}
+		if got := evalString(t, s, "document.getElementById('status').textContent"); got != "Submitted alice" {
+			t.Errorf("status after text=Submit = %q, want %q", got, "Submitted alice")
+		}
+		res = callTool(t, cs, "assert_element_count", map[string]any{"selector": "xpath=id('signup')//button", "count": 1})
+		if text := resultText(res); !strings.Contains(text, "PASS") {
+			t.Errorf("assert_element_count with xpath= = %q", text)
+		}
+	})
+
+	t.Run("element cache", func(t *testing.T) {
+		callTool(t, cs, "click", map[string]any{"selector": "#counter", "strict": true})
+		// A framework re-rendering the button replaces its node.
+		evalString(t, s, "(() => { const b = document.getElementById('counter'); b.textContent = 'Click me'; b.replaceWith(b.cloneNode(true)); return ''; })()")
+		res := callTool(t, cs, "click", map[string]any{"selector": "#counter", "strict": true})
+		var match SelectorMatch
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &match); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if !match.Reresolved || !strings.Contains(resultText(res), "re-resolved") {
+			t.Errorf("click on a re-rendered element = %s, want it re-resolved", resultText(res))
+		}
+		if got := evalString(t, s, "document.getElementById('counter').textContent"); got != "Clicked" {
+			t.Errorf("re-rendered button text = %q, want %q", got, "Clicked")
+		}
+		res = callTool(t, cs, "click", map[string]any{"selector": "#counter", "strict": true})
+		if strings.Contains(resultText(res), "re-resolved") {
+			t.Errorf("click on a cached element = %s, want no re-resolution", resultText(res))
+		}
+	})
+
+	t.Run("wait_for_route_change", func(t *testing.T) {
+		// A single-page app changes the route without loading a document.
+		evalString(t, s, "setTimeout(() => history.pushState({}, '', '?step=2'), 50), ''")
+		res := callTool(t, cs, "wait_for_route_change", map[string]any{"url_pattern": `step=2$`})
+		var change WaitForRouteChangeResult
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &change); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if change.Kind != routePushState || !strings.HasSuffix(change.URL, "/form.html?step=2") {
+			t.Errorf("route change = %+v, want a pushState to ?step=2", change)
+		}
+		if got := s.routes.current(); got != change.URL {
+			t.Errorf("current URL = %q, want %q", got, change.URL)
+		}
+		if text := resultText(callTool(t, cs, "wait_for_route_change", map[string]any{"url_pattern": `step=2$`})); !strings.Contains(text, "already matches") {
+			t.Errorf("wait for the current route = %q", text)
+		}
+
+		evalString(t, s, "history.replaceState({}, '', location.pathname), ''")
+		res = callTool(t, cs, "wait_for_route_change", map[string]any{"since": change.Seq})
+		if text := resultText(res); !strings.Contains(text, "by replaceState") || !strings.Contains(text, "/form.html ") {
+			t.Errorf("route change after replaceState = %q", text)
+		}
+		if text := callToolError(t, cs, "wait_for_route_change", map[string]any{"timeout_seconds": 0}); !strings.Contains(text, "no route change") {
+			t.Errorf("wait without a change = %q", text)
+		}
+	})
+
+	t.Run("confirmation_policy", func(t *testing.T) {
+		p := defaultConfirmationPolicy
+		if err := p.compile(); err != nil {
+			t.Fatal(err)
+		}
+		s.confirmPolicy = &p
+		defer func() { s.confirmPolicy = nil }()
+
+		evalString(t, s, "document.getElementById('status').textContent = ''")
+		text := callToolError(t, cs, "click_button", map[string]any{"selector": "Submit form"})
+		if !strings.Contains(text, `clicking button[Submit form] "Submit" needs confirmation because it submits a form`) {
+			t.Errorf("unconfirmed submit = %q", text)
+		}
+		if got := evalString(t, s, "document.getElementById('status').textContent"); got != "" {
+			t.Errorf("status after a blocked submit = %q, want it unchanged", got)
+		}
+		callTool(t, cs, "click_button", map[string]any{"selector": "Submit form", "confirm": true})
+		if got := evalString(t, s, "document.getElementById('status').textContent"); got != "Submitted alice" {
+			t.Errorf("status after a confirmed submit = %q, want %q", got, "Submitted alice")
+		}
+		// Clicks that neither submit nor match a pattern are not gated.
+		callTool(t, cs, "click", map[string]any{"selector": "#counter"})
+		evalString(t, s, "document.getElementById('counter').textContent = 'Click me'")
+	})
+
+	t.Run("highlight_element", func(t *testing.T) {
+		res := callTool(t, cs, "highlight_element", map[string]any{"selector": "#counter", "label": "About to click"})
+		if text := resultText(res); !strings.HasPrefix(text, `Highlighted button#counter "Click me" for 5s`) {
+			t.Errorf("highlight_element result = %q", text)
+		}
+		if got := evalString(t, s, "document.getElementById('"+highlightOverlayID+"').textContent"); got != "About to click" {
+			t.Errorf("highlight label = %q", got)
+		}
+		callToolError(t, cs, "highlight_element", map[string]any{"selector": "#missing", "strict": true})
+	})
+
+	t.Run("click", func(t *testing.T) {
+		res := callTool(t, cs, "click", map[string]any{"selector": "#counter"})
+		if got := evalString(t, s, "document.getElementById('counter').textContent"); got != "Clicked" {
+			t.Errorf("counter text = %q, want %q", got, "Clicked")
+		}
+		var match SelectorMatch
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &match); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if match.Effects == nil || match.Effects.DOM.empty() || match.Effects.PreviousURL != "" {
+			t.Errorf("click effects = %+v, want a DOM change and no navigation", match.Effects)
+		}
+		if text := resultText(res); !strings.Contains(text, `~button#counter "Clicked" text`) {
+			t.Errorf("click result does not report the text change:\n%s", text)
+		}
+	})
+
+	t.Run("aria_diff", func(t *testing.T) {
+		callTool(t, cs, "aria_snapshot", map[string]any{"focus": "interactive", "save_as": "before"})
+		evalString(t, s, `(() => {
+			const b = document.createElement('button');
+			b.id = 'added';
+			b.textContent = 'Added later';
+			document.body.appendChild(b);
+			return 'ok';
+		})()`)
+		text := resultText(callTool(t, cs, "aria_diff", nil))
+		if !strings.Contains(text, "ADDED (1):") || !strings.Contains(text, "Added later") {
+			t.Errorf("aria_diff did not report the added button:\n%s", text)
+		}
+		if text := resultText(callTool(t, cs, "aria_diff", nil)); !strings.Contains(text, "No changes.") {
+			t.Errorf("second aria_diff reported changes:\n%s", text)
+		}
+		if text := resultText(callTool(t, cs, "aria_diff", map[string]any{"against": "before"})); !strings.Contains(text, "Added later") {
+			t.Errorf("aria_diff against saved snapshot missed the added button:\n%s", text)
+		}
+	})
+
+	t.Run("screenshot", func(t *testing.T) {
+		res := callTool(t, cs, "screenshot", nil)
+		if len(res.Content) != 1 {
+			t.Fatalf("screenshot returned %d content items, want 1", len(res.Content))
+		}
+		img, ok := res.Content[0].(*mcp.ImageContent)
+		if !ok || len(img.Data) == 0 {
+			t.Errorf("screenshot content = %T, want non-empty image", res.Content[0])
+		}
+	})
+
+	t.Run("click_link", func(t *testing.T) {
+		// Resolved through the text XPath strategy of the smart selector.
+		text := resultText(callTool(t, cs, "click_link", map[string]any{"selector": "Open dialog page"}))
+		if !strings.Contains(text, "URL changed: ") || !strings.Contains(text, "Navigated to "+fixtures.URL+"/dialog.html") {
+			t.Errorf("click_link result does not report the navigation:\n%s", text)
+		}
+		if err := chromedp.Run(s.ctx, chromedp.WaitVisible("#open", chromedp.ByQuery)); err != nil {
+			t.Fatalf("dialog page did not load: %v", err)
+		}
+		res := callTool(t, cs, "click_button", map[string]any{"selector": `role=button[name="open dialog"]`})
+		if got := evalString(t, s, "String(document.getElementById('dlg').open)"); got != "true" {
+			t.Errorf("dialog open = %q, want %q", got, "true")
+		}
+		var match SelectorMatch
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &match); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if match.Strategy != "role" {
+			t.Errorf("role selector matched %+v, want the role strategy", match)
+		}
+
+		res = callTool(t, cs, "click_button", map[string]any{"selector": "close-dialog"})
+		if got := evalString(t, s, "String(document.getElementById('dlg').open)"); got != "false" {
+			t.Errorf("dialog open after close = %q, want %q", got, "false")
+		}
+		data, _ = json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &match); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if match.Strategy != "data-testid" {
+			t.Errorf("test id matched %+v, want the data-testid strategy", match)
+		}
+	})
+
+	t.Run("refresh_page", func(t *testing.T) {
+		callTool(t, cs, "refresh_page", nil)
+	})
+
+	t.Run("window", func(t *testing.T) {
+		res := callTool(t, cs, "set_window_size", map[string]any{"width": 800, "height": 600})
+		var b WindowBounds
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &b); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if b.Width != 800 || b.Height != 600 || b.State != "normal" {
+			t.Errorf("set_window_size = %+v, want 800x600", b)
+		}
+		callTool(t, cs, "maximize", nil)
+		callTool(t, cs, "minimize", nil)
+		callTool(t, cs, "bring_to_front", nil)
+		callTool(t, cs, "set_window_size", map[string]any{"width": 1280, "height": 900})
+	})
+
+	t.Run("emulate_media", func(t *testing.T) {
+		res := callTool(t, cs, "emulate_media", map[string]any{"media": "print", "color_scheme": "dark", "reduced_motion": "reduce"})
+		if text := resultText(res); text != "Page now matches media print, prefers-color-scheme: dark, prefers-reduced-motion: reduce" {
+			t.Errorf("emulate_media result = %q", text)
+		}
+		if got := evalString(t, s, "matchMedia('(prefers-color-scheme: dark)').matches ? 'dark' : 'light'"); got != "dark" {
+			t.Errorf("color scheme after emulation = %q, want dark", got)
+		}
+		if text := resultText(callTool(t, cs, "emulate_media", nil)); !strings.Contains(text, "media screen") {
+			t.Errorf("emulate_media reset = %q", text)
+		}
+		if text := callToolError(t, cs, "emulate_media", map[string]any{"color_scheme": "sepia"}); !strings.Contains(text, "must be light or dark") {
+			t.Errorf("emulate_media with an invalid color scheme = %q", text)
+		}
+	})
+
+	t.Run("error_codes", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/form.html"})
+		res, err := cs.CallTool(context.Background(), &mcp.CallToolParams{Name: "click_button", Arguments: map[string]any{"selector": "No such button"}})
+		if err != nil || !res.IsError {
+			t.Fatalf("click_button on a missing button = %v, %v", res, err)
+		}
+		var te ToolError
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &te); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if te.Code != CodeElementNotFound || te.Selector != "No such button" || !te.Retryable || !slices.Contains(te.Strategies, "fallback") {
+			t.Errorf("click_button error = %+v", te)
+		}
+		if text := callToolError(t, cs, "navigate", map[string]any{"url": "http://127.0.0.1:1/"}); !strings.Contains(text, "Error code: NAVIGATION_FAILED") {
+			t.Errorf("navigate to a closed port = %q", text)
+		}
+	})
+
+	t.Run("assertions", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/form.html"})
+		callTool(t, cs, "assert_url_matches", map[string]any{"pattern": `/form\.html$`})
+		callTool(t, cs, "assert_element_visible", map[string]any{"selector": "#counter"})
+		callTool(t, cs, "assert_element_count", map[string]any{"selector": "//form//input", "count": 3})
+		callTool(t, cs, "assert_text_present", map[string]any{"text": "Clicked", "absent": true, "timeout_seconds": 0})
+		callTool(t, cs, "click", map[string]any{"selector": "#counter"})
+		callTool(t, cs, "assert_text_present", map[string]any{"text": "Clicked", "selector": "#counter"})
+
+		text := callToolError(t, cs, "assert_text_present", map[string]any{"text": "sign UP", "timeout_seconds": 0})
+		if !strings.Contains(text, `found with different case: "Sign up"`) {
+			t.Errorf("assert_text_present with the wrong case = %q", text)
+		}
+		text = callToolError(t, cs, "assert_element_count", map[string]any{"selector": "option", "min": 3, "timeout_seconds": 0})
+		if !strings.Contains(text, "Actual: 2 elements") {
+			t.Errorf("assert_element_count = %q", text)
+		}
+		text = callToolError(t, cs, "assert_element_visible", map[string]any{"selector": "#status", "timeout_seconds": 0})
+		if !strings.Contains(text, "p#status is hidden: empty box") {
+			t.Errorf("assert_element_visible on an empty paragraph = %q", text)
+		}
+	})
+
+	t.Run("page_state", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/form.html"})
+		callTool(t, cs, "save_page_state", map[string]any{"name": "start"})
+		callTool(t, cs, "type_text", map[string]any{"selector": "#username", "text": "carol"})
+		res := callTool(t, cs, "compare_page_state", map[string]any{"name": "start"})
+		var cmp PageStateComparison
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &cmp); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if cmp.Matches || !slices.Contains(cmp.Differences, `field #username changed: "" -> "carol"`) {
+			t.Errorf("compare_page_state after typing = %+v", cmp)
+		}
+		if slices.ContainsFunc(cmp.Differences, func(d string) bool { return strings.HasPrefix(d, "DOM changed") }) {
+			t.Errorf("typing changed the DOM hash: %q", cmp.Differences)
+		}
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/form.html"})
+		if text := resultText(callTool(t, cs, "compare_page_state", nil)); text != `Page matches saved state "start"` {
+			t.Errorf("compare_page_state after reloading = %q", text)
+		}
+	})
+
+	t.Run("iframe", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/iframe.html"})
+		text := resultText(callTool(t, cs, "aria_snapshot", nil))
+		if !strings.Contains(text, "Embedded form") {
+			t.Errorf("aria_snapshot of iframe page missing heading:\n%s", text)
+		}
+	})
+
+	t.Run("get_page_metadata", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/metadata.html"})
+		res := callTool(t, cs, "get_page_metadata", nil)
+		var m PageMetadata
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if m.Canonical != fixtures.URL+"/metadata.html" || m.Lang != "en" || m.Robots["robots"] != "index, follow" {
+			t.Errorf("canonical, lang, robots = %q, %q, %q", m.Canonical, m.Lang, m.Robots["robots"])
+		}
+		if len(m.OpenGraph) != 2 || len(m.Twitter) != 1 || len(m.Hreflang) != 2 || len(m.StructuredData) != 1 {
+			t.Errorf("metadata = %+v", m)
+		}
+		if text := resultText(res); !strings.Contains(text, "Structured data (1 JSON-LD blocks): WebPage") || !strings.Contains(text, "Warning: JSON-LD block 2:") {
+			t.Errorf("get_page_metadata result:\n%s", text)
+		}
+	})
+
+	t.Run("detect_captcha", func(t *testing.T) {
+		text := resultText(callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/captcha.html"}))
+		if !strings.Contains(text, "shows a CAPTCHA (hCaptcha)") {
+			t.Errorf("navigate to a CAPTCHA page = %q, want a warning", text)
+		}
+		res := callTool(t, cs, "detect_captcha", nil)
+		var r CaptchaReport
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &r); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		want := []Captcha{
+			{Provider: "reCAPTCHA", Evidence: "script " + fixtures.URL + "/recaptcha/api.js"},
+			{Provider: "hCaptcha", Evidence: "div.h-captcha", Visible: true},
+			{Provider: "Turnstile", Evidence: "div.cf-turnstile"},
+		}
+		if !r.Blocking || !slices.Equal(r.Captchas, want) {
+			t.Errorf("detect_captcha = %+v, want %+v", r, want)
+		}
+		text = resultText(callTool(t, cs, "click", map[string]any{"selector": "#reveal"}))
+		if !strings.Contains(text, "shows a CAPTCHA (hCaptcha, Turnstile)") {
+			t.Errorf("click revealing a CAPTCHA = %q, want a warning", text)
+		}
+	})
+
+	t.Run("login_with_credentials", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/login.html"})
+		if text := callToolError(t, cs, "login_with_credentials", map[string]any{"alias": "remote"}); !strings.Contains(text, "restricted to example.com") {
+			t.Errorf("login on a page outside the credential's domains = %q", text)
+		}
+		res := callTool(t, cs, "login_with_credentials", map[string]any{"alias": "local"})
+		want := "Welcome alice@example.com, your password is correct-horse"
+		if got := evalString(t, s, "document.getElementById('status').textContent"); got != want {
+			t.Errorf("status = %q, want %q", got, want)
+		}
+		data, _ := json.Marshal(res.StructuredContent)
+		text := resultText(res)
+		if strings.Contains(text, "correct-horse") || strings.Contains(string(data), "correct-horse") {
+			t.Errorf("result exposes the password:\n%s\n%s", text, data)
+		}
+		if !strings.Contains(text, `Entered username "alice@example.com" and password of credential local and submitted the form`) || !strings.Contains(text, secretMask) {
+			t.Errorf("login_with_credentials result:\n%s", text)
+		}
+		if got := evalString(t, s, "document.getElementById('search').value"); got != "" {
+			t.Errorf("search field = %q, want it left alone", got)
+		}
+	})
+
+	t.Run("actionability", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/actionability.html"})
+		// Disabled and behind an overlay until the page's timer runs.
+		callTool(t, cs, "click", map[string]any{"selector": "#late"})
+		if got := evalString(t, s, "document.getElementById('late').textContent"); got != "Clicked" {
+			t.Errorf("late button text = %q, want %q", got, "Clicked")
+		}
+		// Hidden until the same timer.
+		callTool(t, cs, "type_text", map[string]any{"selector": "#later", "text": "shown", "strict": true})
+
+		text := callToolError(t, cs, "click", map[string]any{"selector": "#blocked"})
+		if !strings.Contains(text, "covered by div#blocker") || !strings.Contains(text, "Error code: ELEMENT_NOT_ACTIONABLE") {
+			t.Errorf("click on a covered button = %q", text)
+		}
+		if !strings.Contains(text, "Page at the time of the error:\nActionability Fixture |") || !strings.Contains(text, `button "Blocked"`) {
+			t.Errorf("click error does not include the page excerpt:\n%s", text)
+		}
+		if got := evalString(t, s, "document.getElementById('blocked').textContent"); got != "Blocked" {
+			t.Errorf("covered button was clicked")
+		}
+		if text := callToolError(t, cs, "type_text", map[string]any{"selector": "#fixed", "text": "x"}); !strings.Contains(text, "read-only") {
+			t.Errorf("type_text into a read-only input = %q", text)
+		}
+	})
+
+	t.Run("recording", func(t *testing.T) {
+		callTool(t, cs, "start_recording", map[string]any{"name": "form"})
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/form.html"})
+		callTool(t, cs, "type_text", map[string]any{"selector": "#username", "text": "bob", "clear": true})
+		callTool(t, cs, "click_button", map[string]any{"selector": "Submit form"})
+		script := strings.TrimPrefix(resultText(callTool(t, cs, "stop_recording", nil)), "Recorded 3 steps:\n")
+
+		callTool(t, cs, "navigate", map[string]any{"url": "about:blank"})
+		callTool(t, cs, "replay_recording", map[string]any{"script": script})
+		if got := evalString(t, s, "document.getElementById('status').textContent"); got != "Submitted bob" {
+			t.Errorf("status after replay = %q, want %q", got, "Submitted bob")
+		}
+	})
+
+	t.Run("events", func(t *testing.T) {
+		callTool(t, cs, "subscribe_events", map[string]any{"domains": []string{"Runtime", "page"}})
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/form.html"})
+		if err := chromedp.Run(s.ctx, chromedp.Evaluate(`console.error("boom")`, nil)); err != nil {
+			t.Fatal(err)
+		}
+		text := resultText(callTool(t, cs, "poll_events", map[string]any{"methods": []string{"Runtime.consoleAPICalled", "Page.frameNavigated"}}))
+		for _, want := range []string{"Page.frameNavigated", "Runtime.consoleAPICalled", "boom"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("poll_events result missing %q:\n%s", want, text)
+			}
+		}
+		callTool(t, cs, "subscribe_events", map[string]any{"domains": []string{}})
+	})
+
+	t.Run("execute_cdp", func(t *testing.T) {
+		res := callTool(t, cs, "execute_cdp", map[string]any{
+			"method": "Runtime.evaluate",
+			"params": map[string]any{"expression": "1 + 2", "returnByValue": true},
+		})
+		if text := resultText(res); !strings.Contains(text, `"value": 3`) {
+			t.Errorf("execute_cdp result = %s", text)
+		}
+	})
+
+	t.Run("crawl", func(t *testing.T) {
+		res := callTool(t, cs, "crawl", map[string]any{
+			"url":          fixtures.URL + "/crawl/index.html",
+			"link_pattern": "/crawl/products/",
+			"workers":      2,
+			"extract":      map[string]any{"price": ".price", "image": "img@src"},
+		})
+		if text := resultText(res); !strings.Contains(text, "Crawled 3 pages from "+fixtures.URL+"/crawl/index.html") {
+			t.Fatalf("crawl result = %q", text)
+		}
+		link, ok := res.Content[1].(*mcp.ResourceLink)
+		if !ok {
+			t.Fatalf("crawl result content = %v, want a resource link", res.Content)
+		}
+		rr, err := cs.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: link.URI})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report crawlReport
+		if err := json.Unmarshal([]byte(rr.Contents[0].Text), &report); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range report.Pages {
+			if p.URL == fixtures.URL+"/crawl/products/2.html" {
+				if p.Depth != 1 || !slices.Equal(p.Fields["price"], []string{"$2.99"}) || !slices.Equal(p.Fields["image"], []string{"/images/product-2.png"}) {
+					t.Errorf("crawled product page = %+v", p)
+				}
+				return
+			}
+		}
+		t.Errorf("crawl did not visit product 2: %+v", report.Pages)
+	})
+
+	t.Run("bulk_screenshot", func(t *testing.T) {
+		res := callTool(t, cs, "bulk_screenshot", map[string]any{
+			"urls":   []string{fixtures.URL + "/crawl/index.html", fixtures.URL + "/crawl/products/1.html"},
+			"width":  390,
+			"height": 844,
+		})
+		if text := resultText(res); !strings.Contains(text, "Captured 2 of 2 pages at 390x844") {
+			t.Fatalf("bulk_screenshot result = %q", text)
+		}
+		link, ok := res.Content[1].(*mcp.ResourceLink)
+		if !ok {
+			t.Fatalf("bulk_screenshot result content = %v, want resource links", res.Content)
+		}
+		rr, err := cs.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: link.URI})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if blob := rr.Contents[0].Blob; !bytes.HasPrefix(blob, []byte("\x89PNG")) {
+			t.Errorf("screenshot resource is not a PNG: %q", blob[:min(len(blob), 8)])
+		}
+	})
+
+	t.Run("compare_screenshots", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/crawl/products/1.html"})
+		res := callTool(t, cs, "compare_screenshots", nil)
+		baseline := res.Content[1].(*mcp.ResourceLink).URI
+		res = callTool(t, cs, "compare_screenshots", map[string]any{"baseline": baseline})
+		if text := resultText(res); !strings.Contains(text, "VISUAL DIFF: 0.00% of pixels changed") {
+			t.Errorf("unchanged page = %q", text)
+		}
+
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/crawl/products/2.html"})
+		res = callTool(t, cs, "compare_screenshots", map[string]any{"baseline": baseline})
+		data, err := json.Marshal(res.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d VisualDiff
+		if err := json.Unmarshal(data, &d); err != nil {
+			t.Fatal(err)
+		}
+		if d.ChangedPixels == 0 || len(d.Regions) == 0 || d.Regions[0].Selector == "" {
+			t.Errorf("changed page = %s", resultText(res))
+		}
+	})
+
+	t.Run("coordinate input", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/canvas.html"})
+		// The fixture logs the pointer events on its canvas in #log.
+		logged := func(text string) {
+			t.Helper()
+			callTool(t, cs, "assert_text_present", map[string]any{"text": text, "selector": "#log"})
+		}
+		if text := resultText(callTool(t, cs, "click_at", map[string]any{"x": 50, "y": 40})); !strings.Contains(text, "Clicked at (50, 40) on canvas#board") {
+			t.Errorf("click_at = %q", text)
+		}
+		logged("click 50,40")
+		callTool(t, cs, "click_at", map[string]any{"x": 60, "y": 40, "click_count": 2})
+		logged("dblclick 60,40")
+		callTool(t, cs, "move_mouse", map[string]any{"x": 100, "y": 100, "steps": 5})
+		logged("hover 100,100")
+		callTool(t, cs, "swipe", map[string]any{"from_x": 10, "from_y": 10, "to_x": 200, "to_y": 150, "duration_ms": 50})
+		logged("drag 10,10 to 200,150")
+
+		if text := callToolError(t, cs, "click_at", map[string]any{"x": 5000, "y": 10}); !strings.Contains(text, "outside the") {
+			t.Errorf("click outside the viewport = %q", text)
+		}
+	})
+
+	t.Run("set_zoom", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/form.html"})
+		if text := resultText(callTool(t, cs, "set_zoom", map[string]any{"zoom": 0.5})); !strings.HasPrefix(text, "Zoom is 50% (css); the page is") {
+			t.Errorf("set_zoom 0.5 = %q", text)
+		}
+		if text := resultText(callTool(t, cs, "set_zoom", map[string]any{"zoom": 2, "method": "pinch"})); !strings.HasPrefix(text, "Zoom is 200% (pinch)") {
+			t.Errorf("set_zoom pinch 2 = %q", text)
+		}
+		callTool(t, cs, "set_zoom", map[string]any{"zoom": 1})
+	})
+
+	t.Run("browser state", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/form.html"})
+		if err := cs.Subscribe(context.Background(), &mcp.SubscribeParams{URI: stateURI}); err != nil {
+			t.Fatalf("subscribing to %s: %v", stateURI, err)
+		}
+		rr, err := cs.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: stateURI})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var state BrowserState
+		if err := json.Unmarshal([]byte(rr.Contents[0].Text), &state); err != nil {
+			t.Fatal(err)
+		}
+		if state.URL != fixtures.URL+"/form.html" || state.Dialog != nil || !slices.ContainsFunc(state.Tabs, func(tab TabState) bool { return tab.Current }) {
+			t.Errorf("browser state = %s", rr.Contents[0].Text)
+		}
+	})
+
+	t.Run("define_macro", func(t *testing.T) {
+		text := resultText(callTool(t, cs, "define_macro", map[string]any{
+			"name":   "fill_name",
+			"params": []string{"name"},
+			"steps": []map[string]any{
+				{"tool": "type_text", "arguments": map[string]any{"selector": "#username", "text": "{{name}}", "clear": true}},
+			},
+		}))
+		if text != "Defined macro fill_name(name) with 1 steps" {
+			t.Errorf("define_macro result = %q", text)
+		}
+		res := callTool(t, cs, "run_macro", map[string]any{"name": "fill_name", "arguments": map[string]any{"name": "carol"}})
+		var result MacroResult
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		if !result.Passed || len(result.Steps) != 1 {
+			t.Errorf("run_macro = %s", resultText(res))
+		}
+		if got := evalString(t, s, "document.getElementById('username').value"); got != "carol" {
+			t.Errorf("username value after the macro = %q, want %q", got, "carol")
+		}
+		if text := callToolError(t, cs, "define_macro", map[string]any{"name": "broken", "steps": []map[string]any{{"tool": "no_such_tool"}}}); !strings.Contains(text, "no_such_tool") {
+			t.Errorf("define_macro with an unknown tool = %q", text)
+		}
+	})
+
+	t.Run("ocr_screenshot", func(t *testing.T) {
+		RegisterOCREngine("e2e", &fakeOCREngine{})
+		defer func() {
+			ocrEnginesMu.Lock()
+			delete(ocrEngines, "e2e")
+			ocrEnginesMu.Unlock()
+		}()
+		s.ocrEngine = "e2e"
+		defer func() { s.ocrEngine = "" }()
+
+		res := callTool(t, cs, "ocr_screenshot", map[string]any{"selector": "#username"})
+		var result OCRResult
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		// The fake engine reports the cropped image as one word covering it.
+		if result.Engine != "e2e" || len(result.Words) == 0 || result.Words[0].Width <= 0 {
+			t.Errorf("ocr_screenshot = %s", resultText(res))
+		}
+		if text := callToolError(t, cs, "ocr_screenshot", map[string]any{"selector": "#no-such-element"}); !strings.Contains(text, "#no-such-element") {
+			t.Errorf("ocr_screenshot of a missing element = %q", text)
+		}
+	})
+
+	t.Run("profiles", func(t *testing.T) {
+		s.profilesDir = t.TempDir()
+		defer func() { s.profilesDir = "" }()
+		if text := resultText(callTool(t, cs, "list_profiles", nil)); text != "No profiles saved in "+s.profilesDir {
+			t.Errorf("list_profiles = %q", text)
+		}
+		// The test browser is owned by chromedp rather than launched by the
+		// server, so its user data directory is unknown.
+		for _, tool := range []string{"save_profile", "load_profile"} {
+			if text := callToolError(t, cs, tool, map[string]any{"name": "work"}); !strings.Contains(text, "launched by the server") {
+				t.Errorf("%s = %q", tool, text)
+			}
+		}
+	})
+
+	t.Run("coverage", func(t *testing.T) {
+		callTool(t, cs, "start_coverage", nil)
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/coverage/index.html"})
+		res := callTool(t, cs, "stop_coverage", nil)
+		data, err := json.Marshal(res.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report CoverageReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"app.js", "app.css"} {
+			i := slices.IndexFunc(report.Files, func(f FileCoverage) bool { return strings.HasSuffix(f.URL, "/coverage/"+name) })
+			if i < 0 {
+				t.Errorf("no coverage of %s: %s", name, resultText(res))
+				continue
+			}
+			if f := report.Files[i]; f.UsedBytes == 0 || f.UnusedBytes == 0 {
+				t.Errorf("coverage of %s = %+v, want used and unused bytes", name, f)
+			}
+		}
+		if text := callToolError(t, cs, "stop_coverage", nil); !strings.Contains(text, "not being collected") {
+			t.Errorf("second stop_coverage = %q", text)
+		}
+	})
+
+	t.Run("page errors", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/errors.html"})
+		latest := s.pageErrors.latest()
+		s.failOnPageError = true
+		defer func() { s.failOnPageError = false }()
+
+		callTool(t, cs, "click", map[string]any{"selector": "#ok"})
+		text := callToolError(t, cs, "click", map[string]any{"selector": "#broken"})
+		for _, want := range []string{"Cannot read properties of undefined", "at renderCart (", "PAGE_ERROR"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("click #broken = %q, want %q", text, want)
+			}
+		}
+
+		res := callTool(t, cs, "get_page_errors", map[string]any{"since": latest})
+		data, err := json.Marshal(res.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var errs PageErrors
+		if err := json.Unmarshal(data, &errs); err != nil {
+			t.Fatal(err)
+		}
+		if len(errs.Errors) != 1 || !strings.HasSuffix(errs.Errors[0].URL, "/errors.html") || len(errs.Errors[0].Stack) < 2 {
+			t.Errorf("get_page_errors = %s", resultText(res))
+		}
+	})
+
+	t.Run("websockets", func(t *testing.T) {
+		callTool(t, cs, "start_websocket_capture", map[string]any{"url": "/echo", "max_payload_bytes": 12})
+		defer callTool(t, cs, "stop_websocket_capture", nil)
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/websocket.html"})
+		callTool(t, cs, "assert_text_present", map[string]any{"text": "echo: hello", "selector": "#log"})
+		callTool(t, cs, "click", map[string]any{"selector": "#send"})
+		callTool(t, cs, "assert_text_present", map[string]any{"text": "echo: a longer message", "selector": "#log"})
+
+		if text := resultText(callTool(t, cs, "list_websockets", nil)); !strings.Contains(text, "/echo (open): 2 frames sent") {
+			t.Errorf("list_websockets = %q", text)
+		}
+		res := callTool(t, cs, "get_websocket_frames", map[string]any{"contains": "echo"})
+		data, err := json.Marshal(res.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var frames WebSocketFrames
+		if err := json.Unmarshal(data, &frames); err != nil {
+			t.Fatal(err)
+		}
+		if len(frames.Frames) != 2 || frames.Frames[0].Payload != "echo: hello" || frames.Frames[0].Direction != "received" {
+			t.Fatalf("get_websocket_frames = %s", resultText(res))
+		}
+		if f := frames.Frames[1]; f.Payload != "echo: a long" || !f.Truncated || f.Size != len("echo: a longer message") {
+			t.Errorf("truncated frame = %+v", f)
+		}
+	})
+
+	t.Run("set_blocking_preset", func(t *testing.T) {
+		res := callTool(t, cs, "set_blocking_preset", map[string]any{"presets": []string{"images", "ads"}})
+		if text := resultText(res); !strings.HasPrefix(text, "Blocking images, ads (") {
+			t.Errorf("set_blocking_preset = %q", text)
+		}
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/blocking.html"})
+		if got := evalString(t, s, `String(document.getElementById('pixel').naturalWidth)`); got != "0" {
+			t.Errorf("blocked image width = %s", got)
+		}
+		callTool(t, cs, "set_blocking_preset", map[string]any{"presets": []string{}})
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/blocking.html"})
+		if got := evalString(t, s, `String(document.getElementById('pixel').naturalWidth)`); got != "1" {
+			t.Errorf("unblocked image width = %s", got)
+		}
+		if text := callToolError(t, cs, "set_blocking_preset", map[string]any{"presets": []string{"popups"}}); !strings.Contains(text, "unknown preset") {
+			t.Errorf("unknown preset = %q", text)
+		}
+	})
+
+	t.Run("i18n selectors", func(t *testing.T) {
+		callTool(t, cs, "navigate", map[string]any{"url": fixtures.URL + "/i18n.html"})
+		res := callTool(t, cs, "click", map[string]any{"selector": "hilfe & kontakt"})
+		if text := resultText(res); !strings.Contains(text, "strategy: text-normalized") {
+			t.Errorf("click hilfe & kontakt = %q", text)
+		}
+		callTool(t, cs, "assert_text_present", map[string]any{"text": "clicked help", "selector": "#log"})
+
+		s.selectorConfig = &selectorConfig{Translations: [][]string{{"Log in", "Anmelden"}}}
+		defer func() { s.selectorConfig = nil }()
+		res = callTool(t, cs, "click", map[string]any{"selector": "Log in"})
+		if text := resultText(res); !strings.Contains(text, "translation: Anmelden") {
+			t.Errorf("click Log in = %q", text)
+		}
+		callTool(t, cs, "assert_text_present", map[string]any{"text": "clicked login", "selector": "#log"})
+	})
+
+	t.Run("get_environment", func(t *testing.T) {
+		text := resultText(callTool(t, cs, "get_environment", nil))
+		if !strings.Contains(text, "BROWSER:") {
+			t.Errorf("get_environment result = %q", text)
+		}
+	})
+
+	t.Run("browser_info", func(t *testing.T) {
+		res := callTool(t, cs, "browser_info", nil)
+		data, err := json.Marshal(res.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var info BrowserInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(info.Product, "Chrome") || info.ProtocolVersion == "" || info.Pages < 1 || info.LatencyMS <= 0 {
+			t.Errorf("browser_info = %+v", info)
+		}
+		// The test browser runs with chromedp's default --headless flag.
+		if !info.Headless {
+			t.Errorf("browser_info reports a headful browser: %+v", info)
+		}
+		if text := resultText(res); !strings.Contains(text, "MODE: headless") {
+			t.Errorf("browser_info text = %q", text)
+		}
+	})
+
+	t.Run("set_chrome_lifecycle", func(t *testing.T) {
+		callTool(t, cs, "set_chrome_lifecycle", map[string]any{"keep_open": true})
+		if !s.keepChromeOpen {
+			t.Error("keepChromeOpen = false, want true")
+		}
+	})
+
+	t.Run("close_browser", func(t *testing.T) {
+		// The test browser is owned by chromedp rather than a launched
+		// process, so there is nothing for the tool to close.
+		text := resultText(callTool(t, cs, "close_browser", nil))
+		if !strings.Contains(text, "No Chrome process") {
+			t.Errorf("close_browser result = %q", text)
+		}
+	})
+
+	t.Run("shutdown_server", func(t *testing.T) {
+		// The test server is not run by serve, so there is nothing to shut
+		// down; TestServeShutdown covers the graceful path.
+		if text := callToolError(t, cs, "shutdown_server", nil); !strings.Contains(text, "not available") {
+			t.Errorf("shutdown_server result = %q", text)
+		}
+	})
+
+	t.Run("detach_browser", func(t *testing.T) {
+		// This ends the session's connection to Chrome, so it runs last.
+		text := resultText(callTool(t, cs, "detach_browser", nil))
+		if !strings.HasPrefix(text, "Detached from Chrome") {
+			t.Errorf("detach_browser result = %q", text)
+		}
+		if s.ctx.Err() == nil {
+			t.Error("the browser context is still live after detaching")
+		}
+	})
+}
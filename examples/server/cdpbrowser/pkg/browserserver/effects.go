@@ -0,0 +1,397 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// actionSettleTime is how long to watch the page after an action for
+	// effects that follow it, such as a navigation started from a click
+	// handler.
+	actionSettleTime = 250 * time.Millisecond
+
+	// actionLoadTimeout bounds the wait for a navigation started by an
+	// action to finish loading.
+	actionLoadTimeout = 5 * time.Second
+
+	// maxConsoleErrors is the number of console errors reported per action.
+	maxConsoleErrors = 5
+)
+
+// pageStateJS defines describeElement, which summarises an element in a
+// line such as `button#save.primary "Save"`, and pageState, which reports
+// the URL, title and focused element of the page.
+const pageStateJS = `
+function describeElement(el) {
+	if (!el || el.nodeType !== 1) {
+		return '';
+	}
+	let s = el.tagName.toLowerCase();
+	if (el.id) {
+		s += '#' + el.id;
+	}
+	if (typeof el.className === 'string' && el.className.trim()) {
+		s += '.' + el.className.trim().split(/\s+/)[0];
+	}
+	const name = el.getAttribute('aria-label') || el.getAttribute('name');
+	if (name) {
+		s += '[' + name + ']';
+	}
+	const text = (el.innerText || el.textContent || '').replace(/\s+/g, ' ').trim();
+	if (text) {
+		s += ' "' + (text.length > 40 ? text.slice(0, 40) + '…' : text) + '"';
+	}
+	return s;
+}
+
+function pageState() {
+	const focused = document.activeElement;
+	return {
+		url: location.href,
+		title: document.title,
+		focused: focused && focused !== document.body && focused !== document.documentElement ? describeElement(focused) : ''
+	};
+}
+`
+
+// observeMutationsJS starts recording a summary of DOM changes in
+// window.__cdpbrowserDelta and returns the page state. Attribute changes
+// are limited to those that usually mean something to a user.
+const observeMutationsJS = `(function() {` + pageStateJS + `
+	if (window.__cdpbrowserObserver) {
+		window.__cdpbrowserObserver.disconnect();
+	}
+	const limit = 10;
+	const seen = new Set();
+	const delta = { added: [], removed: [], changed: [], more: 0 };
+	const push = (list, s) => {
+		if (!s || seen.has(s)) {
+			return;
+		}
+		seen.add(s);
+		if (list.length < limit) {
+			list.push(s);
+		} else {
+			delta.more++;
+		}
+	};
+	// Overlays drawn by the server itself, such as highlights, are not
+	// effects of the action.
+	const ours = n => n.nodeType === 1 && n.id.startsWith('__cdpbrowser');
+	const record = records => {
+		for (const r of records) {
+			if (r.type === 'childList') {
+				for (const n of r.addedNodes) {
+					if (ours(n)) {
+						continue;
+					}
+					n.nodeType === 1 ? push(delta.added, describeElement(n)) : push(delta.changed, describeElement(r.target) + ' text');
+				}
+				for (const n of r.removedNodes) {
+					if (ours(n)) {
+						continue;
+					}
+					n.nodeType === 1 ? push(delta.removed, describeElement(n)) : push(delta.changed, describeElement(r.target) + ' text');
+				}
+			} else if (r.type === 'attributes') {
+				const v = r.target.getAttribute(r.attributeName);
+				push(delta.changed, describeElement(r.target) + ' ' + r.attributeName + (v === null ? ' removed' : '=' + JSON.stringify(v)));
+			} else {
+				push(delta.changed, describeElement(r.target.parentElement) + ' text');
+			}
+		}
+	};
+	const observer = new MutationObserver(record);
+	observer.observe(document.documentElement, {
+		childList: true, subtree: true, characterData: true, attributes: true,
+		attributeFilter: ['class', 'hidden', 'open', 'disabled', 'value', 'checked', 'selected',
+			'aria-expanded', 'aria-hidden', 'aria-selected', 'aria-checked', 'aria-pressed', 'aria-invalid']
+	});
+	observer.record = record;
+	window.__cdpbrowserObserver = observer;
+	window.__cdpbrowserDelta = delta;
+	return pageState();
+})()`
+
+// collectMutationsJS stops the observer started by observeMutationsJS and
+// returns the page state with the recorded DOM delta and the visible
+// CAPTCHAs. The delta is null if the document was replaced.
+const collectMutationsJS = `(function() {` + pageStateJS + captchaJS + `
+	const observer = window.__cdpbrowserObserver;
+	const state = pageState();
+	state.dom = window.__cdpbrowserDelta || null;
+	state.captchas = detectCaptchas().filter(c => c.visible);
+	if (observer) {
+		observer.record(observer.takeRecords());
+		observer.disconnect();
+	}
+	delete window.__cdpbrowserObserver;
+	delete window.__cdpbrowserDelta;
+	return state;
+})()`
+
+// DOMDelta summarises the elements an action added, removed or changed.
+type DOMDelta struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	More    int      `json:"more,omitempty" jsonschema:"Number of further changes left out"`
+}
+
+// empty reports whether d records no changes.
+func (d *DOMDelta) empty() bool {
+	return d == nil || len(d.Added)+len(d.Removed)+len(d.Changed)+d.More == 0
+}
+
+// ActionEffects describes what an interaction did to the page, so that an
+// agent can tell whether it worked without taking another snapshot.
+type ActionEffects struct {
+	URL           string    `json:"url" jsonschema:"Page URL after the action"`
+	PreviousURL   string    `json:"previous_url,omitempty" jsonschema:"Page URL before the action, if it changed"`
+	Title         string    `json:"title,omitempty"`
+	Navigations   []string  `json:"navigations,omitempty" jsonschema:"URLs the main frame navigated to, including same-document navigations"`
+	Focused       string    `json:"focused,omitempty" jsonschema:"The element focused after the action"`
+	ConsoleErrors []string  `json:"console_errors,omitempty" jsonschema:"Console errors and uncaught exceptions logged during the action"`
+	DOM           *DOMDelta `json:"dom,omitempty" jsonschema:"Summary of DOM changes; omitted if the document was replaced"`
+	Captchas      []Captcha `json:"captchas,omitempty" jsonschema:"Visible CAPTCHAs on the page after the action, which cannot be solved automatically"`
+}
+
+// String renders e as a few lines of text for tool results.
+func (e *ActionEffects) String() string {
+	var lines []string
+	if e.PreviousURL != "" {
+		lines = append(lines, fmt.Sprintf("URL changed: %s -> %s", e.PreviousURL, e.URL))
+	}
+	for _, nav := range e.Navigations {
+		lines = append(lines, "Navigated to "+nav)
+	}
+	if e.Focused != "" {
+		lines = append(lines, "Focused: "+e.Focused)
+	}
+	for _, msg := range e.ConsoleErrors {
+		lines = append(lines, "Console error: "+msg)
+	}
+	if d := e.DOM; !d.empty() {
+		var parts []string
+		for _, a := range d.Added {
+			parts = append(parts, "+"+a)
+		}
+		for _, r := range d.Removed {
+			parts = append(parts, "-"+r)
+		}
+		for _, c := range d.Changed {
+			parts = append(parts, "~"+c)
+		}
+		if d.More > 0 {
+			parts = append(parts, fmt.Sprintf("and %d more", d.More))
+		}
+		lines = append(lines, "DOM: "+strings.Join(parts, "; "))
+	}
+	if w := captchaWarning(e.Captchas); w != "" {
+		lines = append(lines, w)
+	}
+	if len(lines) == 0 {
+		return "No visible effect on the page"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// actionResult returns the result of an action tool without a selector,
+// with effects appended to text. effects may be nil.
+func actionResult(text string, effects *ActionEffects) *mcp.CallToolResultFor[ActionEffects] {
+	res := &mcp.CallToolResultFor[ActionEffects]{}
+	if effects != nil {
+		text += "\n" + effects.String()
+		res.StructuredContent = *effects
+	}
+	res.Content = []mcp.Content{&mcp.TextContent{Text: text}}
+	return res
+}
+
+// pageSnapshot is the page state reported by pageStateJS.
+type pageSnapshot struct {
+	URL      string    `json:"url"`
+	Title    string    `json:"title"`
+	Focused  string    `json:"focused"`
+	DOM      *DOMDelta `json:"dom"`
+	Captchas []Captcha `json:"captchas"`
+}
+
+// actionObserver watches the page while an interaction runs. The zero
+// observer, returned when observation could not start, reports nothing.
+type actionObserver struct {
+	s         *CDPBrowserServer
+	before    pageSnapshot
+	mainFrame cdp.FrameID
+	cancel    context.CancelFunc
+
+	mu            sync.Mutex
+	navigations   []string
+	consoleErrors []string
+	loading       bool
+	loaded        chan struct{}
+}
+
+// observeAction starts watching the page for the effects of an action.
+// Call finish after the action succeeds; call stop in any case. Effects are
+// only observed over CDP; with other backends finish returns nil.
+func (s *CDPBrowserServer) observeAction(ctx context.Context) *actionObserver {
+	if !s.usesCDP() {
+		return &actionObserver{}
+	}
+	o := &actionObserver{s: s, loaded: make(chan struct{}, 1)}
+	err := s.run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		tree, err := page.GetFrameTree().Do(ctx)
+		if err != nil {
+			return err
+		}
+		o.mainFrame = tree.Frame.ID
+		return chromedp.Evaluate(observeMutationsJS, &o.before).Do(ctx)
+	}))
+	if err != nil {
+		log.Printf("Failed to observe action effects: %v", err)
+		return &actionObserver{}
+	}
+	var lctx context.Context
+	lctx, o.cancel = context.WithCancel(s.ctx)
+	chromedp.ListenTarget(lctx, o.event)
+	return o
+}
+
+// event records the CDP events that matter for action effects. It runs on
+// chromedp's event loop, so it must not block.
+func (o *actionObserver) event(ev any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch ev := ev.(type) {
+	case *page.EventFrameStartedLoading:
+		if ev.FrameID == o.mainFrame {
+			o.loading = true
+		}
+	case *page.EventFrameNavigated:
+		if ev.Frame.ParentID == "" {
+			o.navigations = append(o.navigations, ev.Frame.URL)
+		}
+	case *page.EventNavigatedWithinDocument:
+		if ev.FrameID == o.mainFrame {
+			o.navigations = append(o.navigations, ev.URL)
+		}
+	case *page.EventLoadEventFired:
+		if o.loading {
+			o.loading = false
+			select {
+			case o.loaded <- struct{}{}:
+			default:
+			}
+		}
+	case *runtime.EventConsoleAPICalled:
+		if ev.Type == runtime.APITypeError {
+			var args []string
+			for _, arg := range ev.Args {
+				args = append(args, remoteObjectText(arg))
+			}
+			o.addConsoleError(strings.Join(args, " "))
+		}
+	case *runtime.EventExceptionThrown:
+		d := ev.ExceptionDetails
+		msg := d.Text
+		if d.Exception != nil && d.Exception.Description != "" {
+			msg = strings.SplitN(d.Exception.Description, "\n", 2)[0]
+		}
+		o.addConsoleError(msg)
+	}
+}
+
+// addConsoleError records msg, keeping at most maxConsoleErrors. o.mu must
+// be held.
+func (o *actionObserver) addConsoleError(msg string) {
+	if len(o.consoleErrors) < maxConsoleErrors {
+		o.consoleErrors = append(o.consoleErrors, msg)
+	}
+}
+
+// remoteObjectText renders a console argument as text.
+func remoteObjectText(obj *runtime.RemoteObject) string {
+	if obj == nil {
+		return ""
+	}
+	if len(obj.Value) > 0 {
+		var str string
+		if err := json.Unmarshal(obj.Value, &str); err == nil {
+			return str
+		}
+		return string(obj.Value)
+	}
+	if obj.Description != "" {
+		return obj.Description
+	}
+	return string(obj.Type)
+}
+
+// stop stops watching the page. It may be called more than once.
+func (o *actionObserver) stop() {
+	if o.cancel != nil {
+		o.cancel()
+	}
+}
+
+// finish waits for the page to settle after an action and returns its
+// effects, or nil if the page could not be observed.
+func (o *actionObserver) finish(ctx context.Context) *ActionEffects {
+	if o.s == nil {
+		return nil
+	}
+	select {
+	case <-time.After(actionSettleTime):
+	case <-ctx.Done():
+	}
+	o.mu.Lock()
+	loading := o.loading
+	o.mu.Unlock()
+	if loading {
+		select {
+		case <-o.loaded:
+		case <-time.After(actionLoadTimeout):
+			log.Printf("Page still loading %v after action", actionLoadTimeout)
+		case <-ctx.Done():
+		}
+	}
+	o.stop()
+
+	var after pageSnapshot
+	if err := o.s.run(ctx, chromedp.Evaluate(collectMutationsJS, &after)); err != nil {
+		log.Printf("Failed to collect action effects: %v", err)
+		after.URL = o.before.URL
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	effects := &ActionEffects{
+		URL:           after.URL,
+		Title:         after.Title,
+		Navigations:   o.navigations,
+		Focused:       after.Focused,
+		ConsoleErrors: o.consoleErrors,
+		DOM:           after.DOM,
+		Captchas:      after.Captchas,
+	}
+	if after.URL != o.before.URL {
+		effects.PreviousURL = o.before.URL
+	}
+	if effects.DOM.empty() {
+		effects.DOM = nil
+	}
+	return effects
+}
@@ -0,0 +1,90 @@
+package browserserver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestActionObserverEvents(t *testing.T) {
+	o := &actionObserver{mainFrame: "main", loaded: make(chan struct{}, 1)}
+	o.event(&page.EventFrameStartedLoading{FrameID: "child"})
+	if o.loading {
+		t.Error("child frame load marked the page as loading")
+	}
+	o.event(&page.EventFrameStartedLoading{FrameID: "main"})
+	o.event(&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "child", ParentID: "main", URL: "https://ads.test/"}})
+	o.event(&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "main", URL: "https://example.com/next"}})
+	o.event(&page.EventNavigatedWithinDocument{FrameID: "main", URL: "https://example.com/next#step2"})
+	o.event(&page.EventLoadEventFired{})
+	select {
+	case <-o.loaded:
+	default:
+		t.Error("load event did not signal loaded")
+	}
+	if o.loading {
+		t.Error("page still loading after the load event")
+	}
+	if got, want := strings.Join(o.navigations, " "), "https://example.com/next https://example.com/next#step2"; got != want {
+		t.Errorf("navigations = %q, want %q", got, want)
+	}
+
+	o.event(&runtime.EventConsoleAPICalled{Type: runtime.APITypeLog, Args: []*runtime.RemoteObject{{Value: []byte(`"ignored"`)}}})
+	o.event(&runtime.EventConsoleAPICalled{Type: runtime.APITypeError, Args: []*runtime.RemoteObject{
+		{Type: runtime.TypeString, Value: []byte(`"save failed:"`)},
+		{Type: runtime.TypeNumber, Value: []byte(`500`)},
+		{Type: runtime.TypeObject, Description: "Error: boom"},
+	}})
+	o.event(&runtime.EventExceptionThrown{ExceptionDetails: &runtime.ExceptionDetails{
+		Text:      "Uncaught",
+		Exception: &runtime.RemoteObject{Description: "TypeError: x is undefined\n    at onclick"},
+	}})
+	want := []string{"save failed: 500 Error: boom", "TypeError: x is undefined"}
+	if len(o.consoleErrors) != len(want) || o.consoleErrors[0] != want[0] || o.consoleErrors[1] != want[1] {
+		t.Errorf("console errors = %q, want %q", o.consoleErrors, want)
+	}
+	for range maxConsoleErrors + 3 {
+		o.event(&runtime.EventExceptionThrown{ExceptionDetails: &runtime.ExceptionDetails{Text: "again"}})
+	}
+	if len(o.consoleErrors) != maxConsoleErrors {
+		t.Errorf("kept %d console errors, want %d", len(o.consoleErrors), maxConsoleErrors)
+	}
+}
+
+func TestActionEffectsString(t *testing.T) {
+	e := &ActionEffects{
+		URL:           "https://example.com/b",
+		PreviousURL:   "https://example.com/a",
+		Navigations:   []string{"https://example.com/b"},
+		Focused:       `input#email[email]`,
+		ConsoleErrors: []string{"boom"},
+		DOM:           &DOMDelta{Added: []string{`div.toast "Saved"`}, Removed: []string{"p#hint"}, Changed: []string{`button#save disabled=""`}, More: 2},
+	}
+	want := `URL changed: https://example.com/a -> https://example.com/b
+Navigated to https://example.com/b
+Focused: input#email[email]
+Console error: boom
+DOM: +div.toast "Saved"; -p#hint; ~button#save disabled=""; and 2 more`
+	if got := e.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+	if got := (&ActionEffects{URL: "https://example.com/"}).String(); got != "No visible effect on the page" {
+		t.Errorf("String() of no effects = %q", got)
+	}
+}
+
+func TestZeroActionObserver(t *testing.T) {
+	o := &actionObserver{}
+	o.stop()
+	if e := o.finish(t.Context()); e != nil {
+		t.Errorf("finish() of an observer that never started = %+v, want nil", e)
+	}
+	res := actionResult("Clicked element 3", nil)
+	if text := res.Content[0].(*mcp.TextContent).Text; text != "Clicked element 3" {
+		t.Errorf("actionResult() without effects text = %q", text)
+	}
+}
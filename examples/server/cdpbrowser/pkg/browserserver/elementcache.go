@@ -0,0 +1,144 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// elementCache remembers the backend node each selector query resolved to
+// in the current document, so that repeated actions on a selector reach the
+// same element. Single-page apps re-render constantly, replacing the nodes
+// a selector matched; actOnElement tells such stale nodes apart from
+// elements that never existed and resolves the selector again. The cache is
+// cleared whenever the main frame navigates to a new document.
+type elementCache struct {
+	mu    sync.Mutex
+	nodes map[SelectorLocator]cdp.BackendNodeID
+}
+
+// start clears the cache and follows the navigations of the tab.
+func (c *elementCache) start(tabCtx context.Context) {
+	c.reset()
+	chromedp.ListenTarget(tabCtx, c.event)
+}
+
+// event clears the cache when the main frame loads a new document. Nodes
+// survive same-document navigations, so those keep the cache.
+func (c *elementCache) event(ev any) {
+	if ev, ok := ev.(*page.EventFrameNavigated); ok && ev.Frame.ParentID == "" {
+		c.reset()
+	}
+}
+
+// get returns the node loc last resolved to.
+func (c *elementCache) get(loc SelectorLocator) (cdp.BackendNodeID, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.nodes[loc]
+	return b, ok
+}
+
+// put records that loc resolved to the node b.
+func (c *elementCache) put(loc SelectorLocator, b cdp.BackendNodeID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodes == nil {
+		c.nodes = make(map[SelectorLocator]cdp.BackendNodeID)
+	}
+	c.nodes[loc] = b
+}
+
+// forget removes loc from the cache.
+func (c *elementCache) forget(loc SelectorLocator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.nodes, loc)
+}
+
+// reset empties the cache.
+func (c *elementCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes = nil
+}
+
+// isFirstMatchJS is called on a cached element with its query, and reports
+// whether the element is still the first one the query matches. A node
+// that left the document, or that a re-render moved, is not.
+const isFirstMatchJS = `function(query, xpath) { return (` + findInPageJS + `)(query, xpath) === this; }`
+
+// errStaleElement is returned by actions on a cached node that no longer is
+// the element its selector selects.
+var errStaleElement = notFoundError{errors.New("element was replaced by a re-render")}
+
+// isStale reports whether err means the node an action was given has left
+// the document.
+func isStale(err error) bool {
+	var nf notFoundError
+	return errors.As(err, &nf)
+}
+
+// actOnElement calls act on the backend node of the element loc selects,
+// once it is actionable. The node is taken from the element cache if the
+// selector was resolved in this document before and still selects it. If
+// the node turns out to be stale, because the page re-rendered the element,
+// loc is resolved again and act retried once; reresolved reports whether
+// that happened.
+func (s *CDPBrowserServer) actOnElement(ctx context.Context, loc SelectorLocator, checks actionChecks, act func(context.Context, cdp.BackendNodeID) error) (reresolved bool, err error) {
+	err = s.run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		if b, ok := s.elementCache.get(loc); ok {
+			err := s.actOnCachedElement(ctx, loc, b, checks, act)
+			if !isStale(err) {
+				return err
+			}
+			log.Printf("Element cache: %s is stale (%v), resolving it again", loc.Query, err)
+			s.elementCache.forget(loc)
+			reresolved = true
+		}
+		for {
+			b, err := s.resolveActionable(ctx, loc, checks)
+			if err != nil {
+				return err
+			}
+			err = act(ctx, b)
+			if err == nil {
+				s.elementCache.put(loc, b)
+				return nil
+			}
+			if !isStale(err) || reresolved {
+				return err
+			}
+			log.Printf("Element cache: %s went stale during the action (%v), resolving it again", loc.Query, err)
+			reresolved = true
+		}
+	}))
+	return reresolved, err
+}
+
+// actOnCachedElement calls act on the cached node b of loc. It returns a
+// stale error if b is no longer the element loc selects.
+func (s *CDPBrowserServer) actOnCachedElement(ctx context.Context, loc SelectorLocator, b cdp.BackendNodeID, checks actionChecks, act func(context.Context, cdp.BackendNodeID) error) error {
+	args, err := json.Marshal([]any{loc.Query, loc.XPath})
+	if err != nil {
+		return err
+	}
+	var first bool
+	if err := callOnElement(ctx, b, fmt.Sprintf("function() { return (%s).apply(this, %s); }", isFirstMatchJS, args), &first); err != nil {
+		return err
+	}
+	if !first {
+		return errStaleElement
+	}
+	if err := s.waitElementActionable(b, checks).Do(ctx); err != nil {
+		return err
+	}
+	return act(ctx, b)
+}
@@ -0,0 +1,48 @@
+package browserserver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+)
+
+func TestElementCache(t *testing.T) {
+	var c elementCache
+	css := SelectorLocator{Query: "#save"}
+	xpath := SelectorLocator{Query: "#save", XPath: true}
+	c.put(css, 7)
+	if b, ok := c.get(css); !ok || b != 7 {
+		t.Errorf("get(%v) = %d, %t, want 7", css, b, ok)
+	}
+	if _, ok := c.get(xpath); ok {
+		t.Error("an XPath query shares the entry of the CSS query with the same text")
+	}
+
+	// Navigations within the document and in frames keep the nodes.
+	c.event(&page.EventNavigatedWithinDocument{URL: "https://example.com/#next"})
+	c.event(&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "child", ParentID: "main"}})
+	if _, ok := c.get(css); !ok {
+		t.Error("cache cleared by a same-document or frame navigation")
+	}
+	c.event(&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "main"}})
+	if _, ok := c.get(css); ok {
+		t.Error("cache kept after the main frame navigated")
+	}
+
+	c.put(css, 8)
+	c.forget(css)
+	if _, ok := c.get(css); ok {
+		t.Error("forgotten entry still cached")
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	if !isStale(errStaleElement) || !isStale(notFoundError{errors.New("element no longer exists")}) {
+		t.Error("not-found errors are not stale")
+	}
+	if isStale(&actionabilityError{reason: "element is not visible"}) || isStale(nil) {
+		t.Error("an unactionable element is stale")
+	}
+}
@@ -0,0 +1,331 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// elementObjectGroup is the Runtime object group used while resolving
+// element IDs, released once the backend node IDs are known.
+const elementObjectGroup = "cdpbrowser-elements"
+
+// elementRegistry assigns stable numeric IDs to DOM elements, keyed by their
+// CDP backend node ID. An element keeps its ID across snapshots for as long
+// as it stays in the document, so the *_by_id tools do not depend on the
+// model reproducing a selector exactly. The registry is cleared whenever the
+// main frame navigates to a new document: backend node IDs count up per
+// renderer process, and after a cross-site navigation swaps the process an
+// old one can name an unrelated node of the new page.
+type elementRegistry struct {
+	mu     sync.Mutex
+	nextID int
+	ids    map[cdp.BackendNodeID]int
+	nodes  map[int]cdp.BackendNodeID
+}
+
+// idFor returns the ID of the element with backend node ID b, assigning a
+// new one if the element has not been seen before.
+func (r *elementRegistry) idFor(b cdp.BackendNodeID) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id, ok := r.ids[b]; ok {
+		return id
+	}
+	if r.ids == nil {
+		r.ids = make(map[cdp.BackendNodeID]int)
+		r.nodes = make(map[int]cdp.BackendNodeID)
+	}
+	r.nextID++
+	r.ids[b] = r.nextID
+	r.nodes[r.nextID] = b
+	return r.nextID
+}
+
+// lookup returns the backend node ID of the element with the given ID.
+func (r *elementRegistry) lookup(id int) (cdp.BackendNodeID, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.nodes[id]
+	return b, ok
+}
+
+// start clears the registry and follows the navigations of the tab.
+func (r *elementRegistry) start(tabCtx context.Context) {
+	r.reset()
+	chromedp.ListenTarget(tabCtx, r.event)
+}
+
+// event clears the registry when the main frame loads a new document.
+func (r *elementRegistry) event(ev any) {
+	if ev, ok := ev.(*page.EventFrameNavigated); ok && ev.Frame.ParentID == "" {
+		r.reset()
+	}
+}
+
+// reset forgets all elements, for when the page navigates or the browser is
+// replaced. IDs are not reused, so an ID from before the reset is unknown
+// rather than another element.
+func (r *elementRegistry) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = nil
+	r.nodes = nil
+}
+
+// interactiveElementIDs returns the stable IDs of the elements most recently
+// listed by collectInteractiveElements in the page, in the same order.
+func (s *CDPBrowserServer) interactiveElementIDs(ctx context.Context) ([]int, error) {
+	var ids []int
+	err := s.run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		defer runtime.ReleaseObjectGroup(elementObjectGroup).Do(ctx)
+
+		list, exc, err := runtime.Evaluate("window.__cdpbrowserElements || []").WithObjectGroup(elementObjectGroup).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if exc != nil {
+			return exc
+		}
+		props, _, _, exc, err := runtime.GetProperties(list.ObjectID).WithOwnProperties(true).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if exc != nil {
+			return exc
+		}
+
+		byIndex := make(map[int]cdp.BackendNodeID)
+		for _, p := range props {
+			i, err := strconv.Atoi(p.Name)
+			if err != nil || p.Value == nil || p.Value.ObjectID == "" {
+				continue // length and other non-element properties
+			}
+			node, err := dom.DescribeNode().WithObjectID(p.Value.ObjectID).Do(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to describe element %d: %v", i, err)
+			}
+			byIndex[i] = node.BackendNodeID
+		}
+		ids = make([]int, len(byIndex))
+		for i := range ids {
+			if b, ok := byIndex[i]; ok {
+				ids[i] = s.elements.idFor(b)
+			}
+		}
+		return nil
+	}))
+	return ids, err
+}
+
+// elementNode returns the backend node ID of the element with the given ID.
+func (s *CDPBrowserServer) elementNode(id int) (cdp.BackendNodeID, error) {
+	b, ok := s.elements.lookup(id)
+	if !ok {
+		return 0, notFoundError{fmt.Errorf("unknown element id %d; take an aria_snapshot or annotated_screenshot to get element ids", id)}
+	}
+	return b, nil
+}
+
+// callOnElement calls the JavaScript function fn with the element with
+// backend node ID b as this, and decodes its JSON result into res. If fn
+// returns a promise, its resolved value is decoded.
+func callOnElement(ctx context.Context, b cdp.BackendNodeID, fn string, res any) error {
+	obj, err := dom.ResolveNode().WithBackendNodeID(b).WithObjectGroup(elementObjectGroup).Do(ctx)
+	if err != nil {
+		return notFoundError{fmt.Errorf("element no longer exists: %v", err)}
+	}
+	defer runtime.ReleaseObjectGroup(elementObjectGroup).Do(ctx)
+
+	v, exc, err := runtime.CallFunctionOn(fn).WithObjectID(obj.ObjectID).WithReturnByValue(true).WithAwaitPromise(true).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if exc != nil {
+		return exc
+	}
+	if res == nil || len(v.Value) == 0 {
+		return nil
+	}
+	return json.Unmarshal(v.Value, res)
+}
+
+type ElementIDArgs struct {
+	ID int `json:"id" jsonschema:"Element id from aria_snapshot or annotated_screenshot"`
+}
+
+type ClickElementArgs struct {
+	ID      int  `json:"id" jsonschema:"Element id from aria_snapshot or annotated_screenshot"`
+	Confirm bool `json:"confirm,omitempty" jsonschema:"Confirm a click the server's confirmation policy treats as destructive, after checking with the user (default: false)"`
+}
+
+type TypeIntoElementArgs struct {
+	ID      int    `json:"id" jsonschema:"Element id from aria_snapshot or annotated_screenshot"`
+	Text    string `json:"text" jsonschema:"Text to type into the element"`
+	Clear   bool   `json:"clear,omitempty" jsonschema:"Whether to clear existing text before typing (default: false)"`
+	Confirm bool   `json:"confirm,omitempty" jsonschema:"Confirm a form submission by an Enter key in text that the server's confirmation policy treats as destructive, after checking with the user (default: false)"`
+}
+
+// elementError returns a tool error result for an action on element id.
+func elementError[Out any](ctx context.Context, action string, id int, err error) *mcp.CallToolResultFor[Out] {
+	noteToolError(ctx, err, "")
+	return &mcp.CallToolResultFor[Out]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Error %s element %d: %v", action, id, err)},
+		},
+		IsError: true,
+	}
+}
+
+// ClickElementByID tool - clicks an element by its snapshot id
+func (s *CDPBrowserServer) ClickElementByID(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickElementArgs]]) (*mcp.CallToolResultFor[ActionEffects], error) {
+	id := req.Params.Arguments.ID
+	b, err := s.elementNode(id)
+	if err != nil {
+		return elementError[ActionEffects](ctx, "clicking", id, err), nil
+	}
+
+	log.Printf("ClickElementByID: id=%d backendNodeId=%d", id, b)
+	o := s.observeAction(ctx)
+	defer o.stop()
+	c := s.confirmation(req.Session, req.Params.Arguments.Confirm)
+	err = s.run(ctx, s.waitElementActionable(b, actionChecks{pointer: true}), chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := c.check(ctx, b); err != nil {
+			return err
+		}
+		return clickNode(ctx, b)
+	}))
+	if err != nil {
+		return elementError[ActionEffects](ctx, "clicking", id, err), nil
+	}
+	return actionResult(fmt.Sprintf("Clicked element %d", id), o.finish(ctx)), nil
+}
+
+// TypeIntoElementByID tool - types text into an element by its snapshot id
+func (s *CDPBrowserServer) TypeIntoElementByID(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[TypeIntoElementArgs]]) (*mcp.CallToolResultFor[ActionEffects], error) {
+	args := req.Params.Arguments
+	b, err := s.elementNode(args.ID)
+	if err != nil {
+		return elementError[ActionEffects](ctx, "typing into", args.ID, err), nil
+	}
+
+	log.Printf("TypeIntoElementByID: id=%d backendNodeId=%d", args.ID, b)
+	o := s.observeAction(ctx)
+	defer o.stop()
+	c := s.confirmation(req.Session, args.Confirm)
+	err = s.run(ctx, s.waitElementActionable(b, actionChecks{editable: true}), chromedp.ActionFunc(func(ctx context.Context) error {
+		if pressesEnter(args.Text) {
+			if err := c.checkEnter(ctx, b); err != nil {
+				return err
+			}
+		}
+		return typeIntoNode(ctx, b, args.Text, args.Clear)
+	}))
+	if err != nil {
+		return elementError[ActionEffects](ctx, "typing into", args.ID, err), nil
+	}
+	return actionResult(fmt.Sprintf("Typed %q into element %d", args.Text, args.ID), o.finish(ctx)), nil
+}
+
+// clickNode clicks the center of the element with backend node ID b with
+// the mouse, scrolling it into view first.
+func clickNode(ctx context.Context, b cdp.BackendNodeID) error {
+	if err := dom.ScrollIntoViewIfNeeded().WithBackendNodeID(b).Do(ctx); err != nil {
+		return notFoundError{fmt.Errorf("element no longer exists: %v", err)}
+	}
+	quads, err := dom.GetContentQuads().WithBackendNodeID(b).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if len(quads) == 0 || len(quads[0]) < 8 {
+		return fmt.Errorf("element is not rendered")
+	}
+	var x, y float64
+	for i := 0; i < len(quads[0]); i += 2 {
+		x += quads[0][i]
+		y += quads[0][i+1]
+	}
+	n := float64(len(quads[0]) / 2)
+	return chromedp.MouseClickXY(x/n, y/n).Do(ctx)
+}
+
+// clearFieldJS empties a form field or editable element.
+const clearFieldJS = `function() {
+	if ('value' in this) { this.value = ''; } else if (this.isContentEditable) { this.textContent = ''; }
+	this.dispatchEvent(new Event('input', { bubbles: true }));
+}`
+
+// typeIntoNode focuses the element with backend node ID b and types text
+// into it with key events, clearing it first if clear is set.
+func typeIntoNode(ctx context.Context, b cdp.BackendNodeID, text string, clear bool) error {
+	if err := dom.ScrollIntoViewIfNeeded().WithBackendNodeID(b).Do(ctx); err != nil {
+		return notFoundError{fmt.Errorf("element no longer exists: %v", err)}
+	}
+	if err := dom.Focus().WithBackendNodeID(b).Do(ctx); err != nil {
+		return fmt.Errorf("element cannot be focused: %v", err)
+	}
+	if clear {
+		if err := callOnElement(ctx, b, clearFieldJS, nil); err != nil {
+			return err
+		}
+	}
+	return chromedp.KeyEvent(text).Do(ctx)
+}
+
+// ScreenshotElementByID tool - captures a single element by its snapshot id
+func (s *CDPBrowserServer) ScreenshotElementByID(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ElementIDArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+	id := req.Params.Arguments.ID
+	b, err := s.elementNode(id)
+	if err != nil {
+		return elementError[struct{}](ctx, "capturing", id, err), nil
+	}
+
+	var buf []byte
+	err = s.run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := dom.ScrollIntoViewIfNeeded().WithBackendNodeID(b).Do(ctx); err != nil {
+			return notFoundError{fmt.Errorf("element no longer exists: %v", err)}
+		}
+		var clip page.Viewport
+		rectJS := `function() {
+			const r = this.getBoundingClientRect();
+			return { x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height };
+		}`
+		if err := callOnElement(ctx, b, rectJS, &clip); err != nil {
+			return err
+		}
+		if clip.Width == 0 || clip.Height == 0 {
+			return fmt.Errorf("element is not rendered")
+		}
+		x, y := math.Round(clip.X), math.Round(clip.Y)
+		clip.Width, clip.Height = math.Round(clip.Width+clip.X-x), math.Round(clip.Height+clip.Y-y)
+		clip.X, clip.Y, clip.Scale = x, y, 1
+
+		var err error
+		buf, err = page.CaptureScreenshot().
+			WithFormat(page.CaptureScreenshotFormatPng).
+			WithCaptureBeyondViewport(true).
+			WithFromSurface(true).
+			WithClip(&clip).
+			Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return elementError[struct{}](ctx, "capturing", id, err), nil
+	}
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.ImageContent{Data: buf, MIMEType: "image/png"},
+		},
+	}, nil
+}
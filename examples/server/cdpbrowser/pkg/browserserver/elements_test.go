@@ -0,0 +1,43 @@
+package browserserver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+)
+
+func TestElementRegistry(t *testing.T) {
+	var r elementRegistry
+	a, b := r.idFor(101), r.idFor(205)
+	if a == b || a == 0 || b == 0 {
+		t.Fatalf("idFor assigned ids %d and %d, want distinct non-zero ids", a, b)
+	}
+	if got := r.idFor(101); got != a {
+		t.Errorf("idFor(101) = %d on second call, want %d", got, a)
+	}
+	if node, ok := r.lookup(b); !ok || node != 205 {
+		t.Errorf("lookup(%d) = %d, %v, want 205, true", b, node, ok)
+	}
+
+	// Frames and same-document navigations keep the ids; a new document
+	// clears them without reusing any
+	r.event(&page.EventNavigatedWithinDocument{URL: "https://example.com/#next"})
+	r.event(&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "child", ParentID: "main"}})
+	if _, ok := r.lookup(a); !ok {
+		t.Error("ids cleared by a same-document or frame navigation")
+	}
+	r.event(&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "main"}})
+	if _, ok := r.lookup(a); ok {
+		t.Error("ids kept after the main frame navigated")
+	}
+	if got := r.idFor(101); got == a || got == b {
+		t.Errorf("idFor(101) after navigation = %d, want a new id", got)
+	}
+
+	s := &CDPBrowserServer{}
+	if _, err := s.elementNode(42); err == nil || !strings.Contains(err.Error(), "unknown element id 42") {
+		t.Errorf("elementNode(42) error = %v, want unknown element id", err)
+	}
+}
@@ -0,0 +1,227 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// eventDomains maps the cdproto package of each subscribable domain to its
+// CDP domain name. chromedp enables all of these domains on every target, so
+// subscribing only has to start buffering.
+var eventDomains = map[string]string{
+	"network": "Network",
+	"page":    "Page",
+	"runtime": "Runtime",
+}
+
+// defaultEventBufferSize is how many events are kept before the oldest are
+// dropped.
+const defaultEventBufferSize = 1000
+
+// BufferedEvent is a CDP event captured for a subscribed domain.
+type BufferedEvent struct {
+	Seq    int64           `json:"seq"`
+	Time   time.Time       `json:"time"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// eventBuffer holds the events of the subscribed domains until they are
+// polled.
+type eventBuffer struct {
+	mu        sync.Mutex
+	listenCtx context.Context // browser context the listener is attached to
+	domains   map[string]bool
+	size      int
+	events    []BufferedEvent
+	nextSeq   int64
+	dropped   int
+}
+
+// eventMethod returns the CDP method name of a cdproto event, such as
+// "Network.requestWillBeSent", and whether its domain can be subscribed to.
+func eventMethod(ev any) (method, domain string, ok bool) {
+	t := reflect.TypeOf(ev)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return "", "", false
+	}
+	t = t.Elem()
+	domain, ok = eventDomains[path.Base(t.PkgPath())]
+	name, isEvent := strings.CutPrefix(t.Name(), "Event")
+	if !ok || !isEvent || name == "" {
+		return "", "", false
+	}
+	r, n := utf8.DecodeRuneInString(name)
+	return domain + "." + string(unicode.ToLower(r)) + name[n:], domain, true
+}
+
+// eventDomainName returns the canonical name of a subscribable domain,
+// matched case-insensitively.
+func eventDomainName(name string) (string, bool) {
+	for _, domain := range eventDomains {
+		if strings.EqualFold(domain, name) {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+// add buffers ev if its domain is subscribed.
+func (b *eventBuffer) add(ev any) {
+	method, domain, ok := eventMethod(ev)
+	if !ok {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.domains[domain] {
+		return
+	}
+	params, err := json.Marshal(ev)
+	if err != nil {
+		params, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	b.nextSeq++
+	b.events = append(b.events, BufferedEvent{Seq: b.nextSeq, Time: time.Now(), Method: method, Params: params})
+	if over := len(b.events) - b.size; over > 0 {
+		b.events = slices.Delete(b.events, 0, over)
+		b.dropped += over
+	}
+}
+
+// subscribe replaces the set of subscribed domains and attaches the event
+// listener to ctx if it is not already listening there.
+func (b *eventBuffer) subscribe(ctx context.Context, domains []string, size int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.domains = make(map[string]bool)
+	for _, d := range domains {
+		b.domains[d] = true
+	}
+	b.size = size
+	if b.listenCtx != ctx {
+		b.listenCtx = ctx
+		chromedp.ListenTarget(ctx, b.add)
+	}
+}
+
+// poll removes and returns up to limit buffered events whose method starts
+// with one of the given prefixes (all events if there are none). It also
+// reports how many events remain buffered and how many were dropped since
+// the last poll.
+func (b *eventBuffer) poll(limit int, prefixes []string) (events []BufferedEvent, remaining, dropped int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events = []BufferedEvent{}
+	kept := b.events[:0]
+	for _, ev := range b.events {
+		match := len(prefixes) == 0
+		for _, p := range prefixes {
+			if strings.HasPrefix(ev.Method, p) {
+				match = true
+				break
+			}
+		}
+		if match && len(events) < limit {
+			events = append(events, ev)
+		} else {
+			kept = append(kept, ev)
+		}
+	}
+	clear(b.events[len(kept):])
+	b.events = kept
+	dropped, b.dropped = b.dropped, 0
+	return events, len(b.events), dropped
+}
+
+type SubscribeEventsArgs struct {
+	Domains    []string `json:"domains" jsonschema:"CDP event domains to buffer: Network, Page, and/or Runtime. Replaces the current subscription; an empty list unsubscribes from all"`
+	BufferSize int      `json:"buffer_size,omitempty" jsonschema:"Maximum number of buffered events before the oldest are dropped (default: 1000)"`
+}
+
+type PollEventsArgs struct {
+	Limit   int      `json:"limit,omitempty" jsonschema:"Maximum number of events to return (default: 100)"`
+	Methods []string `json:"methods,omitempty" jsonschema:"Only return events whose method starts with one of these prefixes, e.g. Runtime.consoleAPICalled or Network.response"`
+}
+
+// PollEventsResult is the structured result of poll_events.
+type PollEventsResult struct {
+	Events    []BufferedEvent `json:"events"`
+	Remaining int             `json:"remaining"`
+	Dropped   int             `json:"dropped,omitempty"`
+}
+
+// SubscribeEvents tool - starts buffering CDP events from the chosen domains
+func (s *CDPBrowserServer) SubscribeEvents(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[SubscribeEventsArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+	args := req.Params.Arguments
+	var domains []string
+	for _, d := range args.Domains {
+		domain, ok := eventDomainName(d)
+		if !ok {
+			return &mcp.CallToolResultFor[struct{}]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Unsupported event domain %q (supported: Network, Page, Runtime)", d)},
+				},
+				IsError: true,
+			}, nil
+		}
+		domains = append(domains, domain)
+	}
+	size := args.BufferSize
+	if size <= 0 {
+		size = defaultEventBufferSize
+	}
+
+	s.events.subscribe(s.ctx, domains, size)
+	log.Printf("Subscribed to CDP events: %v", domains)
+
+	text := "Unsubscribed from all CDP events"
+	if len(domains) > 0 {
+		text = fmt.Sprintf("Subscribed to %s events (buffer size %d). Use poll_events to retrieve them.", strings.Join(domains, ", "), size)
+	}
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}, nil
+}
+
+// PollEvents tool - returns and removes buffered CDP events
+func (s *CDPBrowserServer) PollEvents(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[PollEventsArgs]]) (*mcp.CallToolResultFor[PollEventsResult], error) {
+	args := req.Params.Arguments
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	events, remaining, dropped := s.events.poll(limit, args.Methods)
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%d events (%d remaining", len(events), remaining))
+	if dropped > 0 {
+		output.WriteString(fmt.Sprintf(", %d dropped because the buffer was full", dropped))
+	}
+	output.WriteString(")\n")
+	for _, ev := range events {
+		output.WriteString(fmt.Sprintf("#%d %s %s %s\n", ev.Seq, ev.Time.Format("15:04:05.000"), ev.Method, ev.Params))
+	}
+
+	return &mcp.CallToolResultFor[PollEventsResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: output.String()},
+		},
+		StructuredContent: PollEventsResult{Events: events, Remaining: remaining, Dropped: dropped},
+	}, nil
+}
@@ -0,0 +1,53 @@
+package browserserver
+
+import (
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+)
+
+func TestEventMethod(t *testing.T) {
+	for _, tc := range []struct {
+		ev     any
+		method string
+		ok     bool
+	}{
+		{&network.EventRequestWillBeSent{}, "Network.requestWillBeSent", true},
+		{&page.EventFrameNavigated{}, "Page.frameNavigated", true},
+		{&runtime.EventConsoleAPICalled{}, "Runtime.consoleAPICalled", true},
+		{&network.Request{}, "", false},
+		{"not an event", "", false},
+	} {
+		method, _, ok := eventMethod(tc.ev)
+		if method != tc.method || ok != tc.ok {
+			t.Errorf("eventMethod(%T) = %q, %v, want %q, %v", tc.ev, method, ok, tc.method, tc.ok)
+		}
+	}
+}
+
+func TestEventBuffer(t *testing.T) {
+	b := &eventBuffer{domains: map[string]bool{"Network": true, "Runtime": true}, size: 3}
+	b.add(&page.EventLoadEventFired{}) // not subscribed
+	b.add(&network.EventRequestWillBeSent{RequestID: "1"})
+	b.add(&runtime.EventConsoleAPICalled{Type: runtime.APITypeError})
+	b.add(&network.EventResponseReceived{RequestID: "1"})
+	b.add(&network.EventLoadingFinished{RequestID: "1"}) // drops the oldest
+
+	events, remaining, dropped := b.poll(10, []string{"Runtime."})
+	if len(events) != 1 || events[0].Method != "Runtime.consoleAPICalled" {
+		t.Errorf("poll(Runtime.) = %+v, want the console event", events)
+	}
+	if remaining != 2 || dropped != 1 {
+		t.Errorf("remaining, dropped = %d, %d, want 2, 1", remaining, dropped)
+	}
+
+	events, remaining, dropped = b.poll(1, nil)
+	if len(events) != 1 || events[0].Method != "Network.responseReceived" || events[0].Seq != 3 {
+		t.Errorf("poll(limit 1) = %+v, want Network.responseReceived #3", events)
+	}
+	if remaining != 1 || dropped != 0 {
+		t.Errorf("remaining, dropped = %d, %d, want 1, 0", remaining, dropped)
+	}
+}
@@ -0,0 +1,540 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/chromedp/cdproto/accessibility"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// find_element ranks the interactive elements of the page against a natural
+// description such as "the blue Continue button in the checkout panel". The
+// description is split into aspects, and each aspect the description
+// mentions contributes to a candidate's confidence with the weight below.
+const (
+	nameWeight      = 0.5
+	roleWeight      = 0.2
+	containerWeight = 0.15
+	colorWeight     = 0.1
+	positionWeight  = 0.1
+)
+
+// roleWords maps words that name a kind of control to the roles they
+// describe. Words marked as role-only do not also count towards the name.
+var roleWords = map[string]struct {
+	roles    []string
+	roleOnly bool
+}{
+	"button":   {[]string{"button"}, true},
+	"btn":      {[]string{"button"}, true},
+	"link":     {[]string{"link"}, true},
+	"field":    {[]string{"textbox", "searchbox", "combobox", "spinbutton"}, true},
+	"input":    {[]string{"textbox", "searchbox", "combobox", "spinbutton", "checkbox", "radio"}, true},
+	"textbox":  {[]string{"textbox", "searchbox"}, true},
+	"box":      {[]string{"textbox", "searchbox", "checkbox", "combobox"}, true},
+	"checkbox": {[]string{"checkbox"}, true},
+	"dropdown": {[]string{"combobox", "listbox"}, true},
+	"combobox": {[]string{"combobox"}, true},
+	"radio":    {[]string{"radio"}, true},
+	"slider":   {[]string{"slider"}, true},
+	"toggle":   {[]string{"switch", "checkbox", "button"}, true},
+	"switch":   {[]string{"switch"}, false},
+	"tab":      {[]string{"tab"}, false},
+	"menu":     {[]string{"menuitem", "menuitemcheckbox", "menuitemradio", "button"}, false},
+	"search":   {[]string{"searchbox"}, false},
+	"select":   {[]string{"combobox", "listbox"}, false},
+	"option":   {[]string{"option", "radio", "menuitemradio"}, false},
+}
+
+// colorWords maps color names to a representative RGB value.
+var colorWords = map[string][3]float64{
+	"red":    {220, 40, 40},
+	"orange": {245, 140, 30},
+	"yellow": {240, 210, 40},
+	"green":  {40, 160, 70},
+	"blue":   {40, 100, 220},
+	"purple": {130, 60, 180},
+	"pink":   {235, 100, 170},
+	"gray":   {128, 128, 128},
+	"grey":   {128, 128, 128},
+	"black":  {0, 0, 0},
+	"white":  {255, 255, 255},
+}
+
+// positionWords are words that describe where an element is, either on the
+// page or in document order.
+var positionWords = map[string]bool{
+	"top": true, "upper": true, "bottom": true, "lower": true, "left": true, "right": true,
+	"first": true, "second": true, "third": true, "last": true,
+}
+
+// stopWords carry no meaning for matching.
+var stopWords = map[string]bool{
+	"the": true, "a": true, "an": true, "to": true, "of": true, "on": true, "for": true,
+	"with": true, "and": true, "that": true, "which": true, "says": true, "labeled": true,
+	"labelled": true, "called": true, "named": true, "element": true, "one": true,
+}
+
+// contextPattern splits "the Save button in the settings panel" into the
+// element description and the description of its container.
+var contextPattern = regexp.MustCompile(`(?i)\s+(?:inside|within|in|under)\s+(?:the|a|an)\s+(.+)$`)
+
+// containerWords are generic words for a part of a page, which say nothing
+// about which container is meant. Words for dialogs are mapped to the role.
+var containerWords = map[string]string{
+	"panel": "", "pane": "", "section": "", "area": "", "part": "", "block": "",
+	"card": "", "container": "", "page": "", "modal": "dialog", "popup": "dialog",
+}
+
+// containerRoles are the roles whose name describes the elements inside
+// them, for matching "in the checkout panel".
+var containerRoles = map[string]bool{
+	"banner": true, "navigation": true, "main": true, "contentinfo": true, "complementary": true,
+	"region": true, "search": true, "form": true, "dialog": true, "alertdialog": true,
+	"article": true, "group": true, "toolbar": true, "menu": true, "menubar": true, "tablist": true,
+}
+
+// elementQuery is a parsed element description.
+type elementQuery struct {
+	nameTerms      []string
+	roles          map[string]bool
+	containerTerms []string
+	color          string
+	position       []string
+}
+
+// words splits s into lower-case words.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// parseElementQuery splits a natural description of an element into the
+// aspects it mentions.
+func parseElementQuery(description string) elementQuery {
+	q := elementQuery{roles: make(map[string]bool)}
+	if m := contextPattern.FindStringSubmatchIndex(description); m != nil {
+		for _, w := range words(description[m[2]:m[3]]) {
+			if cw, ok := containerWords[w]; ok {
+				w = cw
+			}
+			if w != "" && !stopWords[w] {
+				q.containerTerms = append(q.containerTerms, w)
+			}
+		}
+		description = description[:m[0]]
+	}
+	for _, w := range words(description) {
+		_, isColor := colorWords[w]
+		switch {
+		case stopWords[w]:
+		case positionWords[w]:
+			q.position = append(q.position, w)
+		case isColor:
+			q.color = w
+		default:
+			if rw, ok := roleWords[w]; ok {
+				for _, r := range rw.roles {
+					q.roles[r] = true
+				}
+				if rw.roleOnly {
+					continue
+				}
+			}
+			q.nameTerms = append(q.nameTerms, w)
+		}
+	}
+	return q
+}
+
+// termSimilarity scores how well a query word matches a word of an element,
+// from 0 to 1, tolerating prefixes and small typos.
+func termSimilarity(term, word string) float64 {
+	switch {
+	case term == word:
+		return 1
+	case len(term) >= 3 && strings.HasPrefix(word, term), len(word) >= 3 && strings.HasPrefix(term, word):
+		return 0.8
+	}
+	longest := max(len(term), len(word))
+	if longest < 4 {
+		return 0
+	}
+	sim := 1 - float64(levenshtein(term, word))/float64(longest)
+	if sim < 0.7 {
+		return 0
+	}
+	return sim * 0.9
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// textSimilarity scores how well terms match text, from 0 to 1: the average
+// over terms of the best match among the words of text, reduced when text has
+// many words the terms do not mention.
+func textSimilarity(terms []string, text string) float64 {
+	textWords := words(text)
+	if len(terms) == 0 || len(textWords) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, t := range terms {
+		best := 0.0
+		for _, w := range textWords {
+			best = max(best, termSimilarity(t, w))
+		}
+		total += best
+	}
+	score := total / float64(len(terms))
+	if extra := len(textWords) - len(terms); extra > 0 {
+		score *= 1 - 0.3*float64(extra)/float64(len(textWords))
+	}
+	return score
+}
+
+// cssColor parses a computed color such as "rgb(0, 0, 255)" or
+// "rgba(0, 0, 0, 0)". It reports false for transparent colors.
+func cssColor(s string) ([3]float64, bool) {
+	inner, ok := strings.CutSuffix(s, ")")
+	if i := strings.Index(inner, "("); ok && i >= 0 {
+		parts := strings.Split(inner[i+1:], ",")
+		if len(parts) < 3 {
+			return [3]float64{}, false
+		}
+		var rgb [3]float64
+		for k := range rgb {
+			v, err := strconv.ParseFloat(strings.TrimSpace(parts[k]), 64)
+			if err != nil {
+				return [3]float64{}, false
+			}
+			rgb[k] = v
+		}
+		if len(parts) == 4 {
+			if a, err := strconv.ParseFloat(strings.TrimSpace(parts[3]), 64); err == nil && a == 0 {
+				return [3]float64{}, false
+			}
+		}
+		return rgb, true
+	}
+	return [3]float64{}, false
+}
+
+// nearestColorWord returns the color word closest to rgb.
+func nearestColorWord(rgb [3]float64) string {
+	best, bestDist := "", math.Inf(1)
+	for name, ref := range colorWords {
+		if name == "grey" {
+			continue
+		}
+		d := 0.0
+		for k := range rgb {
+			d += (rgb[k] - ref[k]) * (rgb[k] - ref[k])
+		}
+		if d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// sameColorWord reports whether two color words name the same color.
+func sameColorWord(a, b string) bool {
+	norm := func(s string) string {
+		if s == "grey" {
+			return "gray"
+		}
+		return s
+	}
+	return norm(a) == norm(b)
+}
+
+// ElementCandidate is an element ranked by find_element.
+type ElementCandidate struct {
+	ID         int      `json:"id" jsonschema:"Element id, for the *_by_id tools"`
+	Role       string   `json:"role"`
+	Name       string   `json:"name,omitempty"`
+	Selector   string   `json:"selector"`
+	Selectors  []string `json:"selectors,omitempty"`
+	Container  string   `json:"container,omitempty" jsonschema:"Nearest landmark, dialog, or group containing the element"`
+	Confidence float64  `json:"confidence" jsonschema:"How well the element matches the description, from 0 to 1"`
+	Reasons    []string `json:"reasons,omitempty" jsonschema:"Which parts of the description the element matched"`
+}
+
+// elementCandidate is an interactive element with the page context used to
+// score it.
+type elementCandidate struct {
+	ElementCandidate
+	order         int // document order among candidates
+	dom           *domElement
+	containerName string
+}
+
+// nearestContainer returns the role and accessible name of the nearest
+// container of n.
+func nearestContainer(n *accessibility.Node, byID map[accessibility.NodeID]*accessibility.Node) (role, name string) {
+	for p := byID[n.ParentID]; p != nil; p = byID[p.ParentID] {
+		if role := axString(p.Role); !p.Ignored && containerRoles[role] {
+			return role, axString(p.Name)
+		}
+	}
+	return "", ""
+}
+
+// elementCandidates returns the interactive elements of page in document
+// order.
+func elementCandidates(page *axPage, idFor func(cdp.BackendNodeID) int) []*elementCandidate {
+	byID := make(map[accessibility.NodeID]*accessibility.Node, len(page.nodes))
+	for _, n := range page.nodes {
+		byID[n.NodeID] = n
+	}
+	var candidates []*elementCandidate
+	for _, n := range axDocumentOrder(page.nodes) {
+		if n.Ignored || n.BackendDOMNodeID == 0 || !axInteractive(n) {
+			continue
+		}
+		e, ok := page.dom.element(n.BackendDOMNodeID)
+		if !ok {
+			continue
+		}
+		el := interactiveElement(n, e, idFor)
+		containerRole, containerName := nearestContainer(n, byID)
+		container := containerRole
+		if containerName != "" {
+			container = fmt.Sprintf("%s %q", containerRole, containerName)
+		}
+		candidates = append(candidates, &elementCandidate{
+			ElementCandidate: ElementCandidate{
+				ID:        el.ID,
+				Role:      el.Role,
+				Name:      el.Name,
+				Selector:  el.Selector,
+				Selectors: el.Selectors,
+				Container: container,
+			},
+			order:         len(candidates),
+			dom:           e,
+			containerName: containerName,
+		})
+	}
+	return candidates
+}
+
+// rankCandidates scores candidates against q and returns those with a
+// non-zero confidence, best first.
+func rankCandidates(q elementQuery, candidates []*elementCandidate) []ElementCandidate {
+	// Extents of the rendered candidates, for top/bottom/left/right.
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, c := range candidates {
+		if b := c.dom.bounds; b != nil {
+			minX, minY = min(minX, b[0]), min(minY, b[1])
+			maxX, maxY = max(maxX, b[0]), max(maxY, b[1])
+		}
+	}
+	relative := func(v, lo, hi float64) float64 {
+		if hi <= lo {
+			return 1
+		}
+		return (v - lo) / (hi - lo)
+	}
+
+	// Ordinals count among the candidates of the described role, if any.
+	var ofRole []*elementCandidate
+	for _, c := range candidates {
+		if len(q.roles) == 0 || q.roles[c.Role] {
+			ofRole = append(ofRole, c)
+		}
+	}
+	ordinal := func(c *elementCandidate, word string) bool {
+		i, ok := map[string]int{"first": 0, "second": 1, "third": 2, "last": len(ofRole) - 1}[word]
+		return ok && i >= 0 && i < len(ofRole) && ofRole[i] == c
+	}
+
+	var ranked []*elementCandidate
+	for _, c := range candidates {
+		var score, weight float64
+		c.Reasons = nil
+		if len(q.nameTerms) > 0 {
+			weight += nameWeight
+			text := c.Name + " " + c.dom.attrs["aria-label"] + " " + c.dom.attrs["id"] + " " + c.dom.attrs["name"] + " " + c.dom.attrs["placeholder"]
+			s := max(textSimilarity(q.nameTerms, c.Name), textSimilarity(q.nameTerms, text)*0.9)
+			if s > 0 {
+				score += nameWeight * s
+				c.Reasons = append(c.Reasons, fmt.Sprintf("name %q matches %q (%.2f)", c.Name, strings.Join(q.nameTerms, " "), s))
+			}
+		}
+		if len(q.roles) > 0 {
+			weight += roleWeight
+			if q.roles[c.Role] {
+				score += roleWeight
+				c.Reasons = append(c.Reasons, "role "+c.Role)
+			}
+		}
+		if len(q.containerTerms) > 0 {
+			weight += containerWeight
+			// Match "the checkout panel" against both `region "Checkout"` and
+			// just "Checkout", so the role word does not count against it.
+			s := max(textSimilarity(q.containerTerms, c.Container), textSimilarity(q.containerTerms, c.containerName))
+			if s > 0 {
+				score += containerWeight * s
+				c.Reasons = append(c.Reasons, "in "+c.Container)
+			}
+		}
+		if q.color != "" {
+			weight += colorWeight
+			if rgb, ok := cssColor(c.dom.background); ok && sameColorWord(nearestColorWord(rgb), q.color) {
+				score += colorWeight
+				c.Reasons = append(c.Reasons, q.color+" background")
+			} else if rgb, ok := cssColor(c.dom.color); ok && sameColorWord(nearestColorWord(rgb), q.color) {
+				score += colorWeight * 0.7
+				c.Reasons = append(c.Reasons, q.color+" text")
+			}
+		}
+		for _, p := range q.position {
+			weight += positionWeight
+			var s float64
+			if b := c.dom.bounds; b != nil {
+				switch p {
+				case "top", "upper":
+					s = 1 - relative(b[1], minY, maxY)
+				case "bottom", "lower":
+					s = relative(b[1], minY, maxY)
+				case "left":
+					s = 1 - relative(b[0], minX, maxX)
+				case "right":
+					s = relative(b[0], minX, maxX)
+				}
+			}
+			if ordinal(c, p) {
+				s = 1
+			}
+			if s > 0 {
+				score += positionWeight * s
+				if s > 0.5 {
+					c.Reasons = append(c.Reasons, p)
+				}
+			}
+		}
+		if weight == 0 || score == 0 {
+			continue
+		}
+		c.Confidence = math.Round(score/weight*100) / 100
+		ranked = append(ranked, c)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Confidence > ranked[j].Confidence
+	})
+	result := make([]ElementCandidate, len(ranked))
+	for i, c := range ranked {
+		result[i] = c.ElementCandidate
+	}
+	return result
+}
+
+type FindElementArgs struct {
+	Description string `json:"description" jsonschema:"Natural description of the element, e.g. 'the blue Continue button in the checkout panel'"`
+	Limit       int    `json:"limit,omitempty" jsonschema:"Maximum number of candidates to return (default: 5)"`
+}
+
+// FindElementResult is the structured result of find_element.
+type FindElementResult struct {
+	Candidates []ElementCandidate `json:"candidates"`
+}
+
+// FindElement tool - ranks the interactive elements of the page against a
+// natural description
+func (s *CDPBrowserServer) FindElement(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[FindElementArgs]]) (*mcp.CallToolResultFor[FindElementResult], error) {
+	args := req.Params.Arguments
+	q := parseElementQuery(args.Description)
+	if len(q.nameTerms) == 0 && len(q.roles) == 0 && len(q.containerTerms) == 0 && q.color == "" && len(q.position) == 0 {
+		return &mcp.CallToolResultFor[FindElementResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "description is required"},
+			},
+			IsError: true,
+		}, nil
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+
+	var candidates []*elementCandidate
+	err := s.run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		page, err := captureAXPage(ctx)
+		if err != nil {
+			return err
+		}
+		candidates = elementCandidates(page, s.elements.idFor)
+		return nil
+	}))
+	if err != nil {
+		return &mcp.CallToolResultFor[FindElementResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error finding element: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	ranked := rankCandidates(q, candidates)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	result := FindElementResult{Candidates: ranked}
+	if result.Candidates == nil {
+		result.Candidates = []ElementCandidate{}
+	}
+
+	var output strings.Builder
+	if len(ranked) == 0 {
+		output.WriteString(fmt.Sprintf("No element matches %q\n", args.Description))
+	} else {
+		output.WriteString(fmt.Sprintf("Candidates for %q:\n", args.Description))
+	}
+	for i, c := range ranked {
+		output.WriteString(fmt.Sprintf("%d. #%d %s %q %s (confidence %.2f)\n", i+1, c.ID, c.Role, c.Name, c.Selector, c.Confidence))
+		if c.Container != "" {
+			output.WriteString(fmt.Sprintf("   in %s\n", c.Container))
+		}
+		if len(c.Reasons) > 0 {
+			output.WriteString(fmt.Sprintf("   matched: %s\n", strings.Join(c.Reasons, "; ")))
+		}
+	}
+
+	return &mcp.CallToolResultFor[FindElementResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: output.String()},
+		},
+		StructuredContent: result,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package browserserver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseElementQuery(t *testing.T) {
+	q := parseElementQuery("the blue Continue button in the checkout panel")
+	if !reflect.DeepEqual(q.nameTerms, []string{"continue"}) {
+		t.Errorf("nameTerms = %q, want [continue]", q.nameTerms)
+	}
+	if !q.roles["button"] || len(q.roles) != 1 {
+		t.Errorf("roles = %v, want button", q.roles)
+	}
+	if !reflect.DeepEqual(q.containerTerms, []string{"checkout"}) {
+		t.Errorf("containerTerms = %q, want [checkout]", q.containerTerms)
+	}
+	if q.color != "blue" {
+		t.Errorf("color = %q, want blue", q.color)
+	}
+
+	// "in" only starts a container description when followed by an article.
+	q = parseElementQuery("Sign in link at the top")
+	if !reflect.DeepEqual(q.nameTerms, []string{"sign", "in", "at"}) || !q.roles["link"] || len(q.containerTerms) != 0 {
+		t.Errorf("parseElementQuery(Sign in link at the top) = %+v", q)
+	}
+	if !reflect.DeepEqual(q.position, []string{"top"}) {
+		t.Errorf("position = %q, want [top]", q.position)
+	}
+
+	// Ambiguous role words also count towards the name.
+	q = parseElementQuery("search")
+	if !reflect.DeepEqual(q.nameTerms, []string{"search"}) || !q.roles["searchbox"] {
+		t.Errorf("parseElementQuery(search) = %+v", q)
+	}
+}
+
+func TestTermSimilarity(t *testing.T) {
+	for _, tc := range []struct {
+		term, word string
+		min, max   float64
+	}{
+		{"continue", "continue", 1, 1},
+		{"cont", "continue", 0.8, 0.8},
+		{"contnue", "continue", 0.7, 0.9},
+		{"save", "cancel", 0, 0},
+		{"ok", "on", 0, 0},
+	} {
+		if got := termSimilarity(tc.term, tc.word); got < tc.min || got > tc.max {
+			t.Errorf("termSimilarity(%q, %q) = %v, want between %v and %v", tc.term, tc.word, got, tc.min, tc.max)
+		}
+	}
+}
+
+func TestCSSColor(t *testing.T) {
+	if rgb, ok := cssColor("rgb(30, 90, 230)"); !ok || nearestColorWord(rgb) != "blue" {
+		t.Errorf("cssColor(rgb(30, 90, 230)) = %v, %v, want blue", rgb, ok)
+	}
+	if _, ok := cssColor("rgba(0, 0, 0, 0)"); ok {
+		t.Error("cssColor of a transparent color succeeded")
+	}
+	if _, ok := cssColor("transparent"); ok {
+		t.Error("cssColor(transparent) succeeded")
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	candidate := func(order int, role, name, container, background string, y float64) *elementCandidate {
+		return &elementCandidate{
+			ElementCandidate: ElementCandidate{ID: order + 1, Role: role, Name: name, Selector: "#c" + name, Container: "region " + container},
+			order:            order,
+			dom:              &domElement{attrs: map[string]string{}, bounds: []float64{0, y, 100, 20}, background: background},
+			containerName:    container,
+		}
+	}
+	candidates := []*elementCandidate{
+		candidate(0, "link", "Continue shopping", "Main", "rgba(0, 0, 0, 0)", 10),
+		candidate(1, "button", "Continue", "Cart", "rgb(128, 128, 128)", 300),
+		candidate(2, "button", "Continue", "Checkout", "rgb(30, 90, 230)", 600),
+		candidate(3, "button", "Cancel", "Checkout", "rgb(220, 40, 40)", 600),
+	}
+
+	ranked := rankCandidates(parseElementQuery("the blue Continue button in the checkout panel"), candidates)
+	if len(ranked) == 0 || ranked[0].ID != 3 {
+		t.Fatalf("best candidate = %+v, want element 3", ranked)
+	}
+	if ranked[0].Confidence != 1 {
+		t.Errorf("confidence = %v, want 1 for a full match", ranked[0].Confidence)
+	}
+	if len(ranked) < 2 || ranked[1].Confidence >= ranked[0].Confidence {
+		t.Errorf("runner-up = %+v, want a lower confidence", ranked)
+	}
+
+	ranked = rankCandidates(parseElementQuery("first button"), candidates)
+	if len(ranked) == 0 || ranked[0].ID != 2 {
+		t.Errorf("first button = %+v, want element 2", ranked)
+	}
+	ranked = rankCandidates(parseElementQuery("contnue link"), candidates)
+	if len(ranked) == 0 || ranked[0].ID != 1 {
+		t.Errorf("contnue link = %+v, want element 1", ranked)
+	}
+}
@@ -0,0 +1,179 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// findTextJS is a function that searches the visible text nodes of the page.
+// It returns up to limit matches and the total number found. The nearest
+// clickable ancestors of the returned matches are left in
+// window.__cdpbrowserElements so that the server can give them element IDs;
+// clickable holds the index of each match's ancestor in that list, or -1.
+// Matches cannot span more than one text node.
+const findTextJS = `
+function(query, regex, caseSensitive, limit, contextChars) {
+	const source = regex ? query : query.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+	const pattern = new RegExp(source, caseSensitive ? 'g' : 'gi');
+	const clickableSelector = 'a[href], button, input, select, textarea, label, summary, ' +
+		'[role="button"], [role="link"], [role="menuitem"], [role="tab"], [role="checkbox"], [role="radio"], ' +
+		'[onclick], [tabindex]:not([tabindex="-1"])';
+	const skip = new Set(['SCRIPT', 'STYLE', 'NOSCRIPT', 'TEMPLATE']);
+
+	const clickables = [];
+	const matches = [];
+	const clickableIndex = [];
+	let total = 0;
+	const walker = document.createTreeWalker(document.body || document.documentElement, NodeFilter.SHOW_TEXT);
+	for (let node = walker.nextNode(); node; node = walker.nextNode()) {
+		const el = node.parentElement;
+		if (!el || skip.has(el.tagName) || !el.getClientRects().length ||
+			getComputedStyle(el).visibility === 'hidden') {
+			continue;
+		}
+		const text = node.textContent;
+		pattern.lastIndex = 0;
+		let m;
+		while ((m = pattern.exec(text)) !== null) {
+			if (m[0] === '') {
+				pattern.lastIndex++;
+				continue;
+			}
+			total++;
+			if (matches.length >= limit) {
+				continue;
+			}
+			const match = {
+				text: m[0],
+				before: text.slice(Math.max(0, m.index - contextChars), m.index).replace(/\s+/g, ' ').trimStart(),
+				after: text.slice(m.index + m[0].length, m.index + m[0].length + contextChars).replace(/\s+/g, ' ').trimEnd(),
+				selector: getSelector(el),
+				tag: el.tagName.toLowerCase()
+			};
+			const clickable = el.closest(clickableSelector);
+			if (clickable) {
+				let i = clickables.indexOf(clickable);
+				if (i < 0) {
+					i = clickables.push(clickable) - 1;
+				}
+				match.clickable_selector = getSelector(clickable);
+				match.clickable_tag = clickable.tagName.toLowerCase();
+				clickableIndex.push(i);
+			} else {
+				clickableIndex.push(-1);
+			}
+			matches.push(match);
+		}
+	}
+	window.__cdpbrowserElements = clickables;
+	return { matches: matches, total: total, clickable: clickableIndex };
+}
+`
+
+type FindTextArgs struct {
+	Query         string `json:"query" jsonschema:"Text to search for in the rendered page"`
+	Regex         bool   `json:"regex,omitempty" jsonschema:"Treat query as a JavaScript regular expression (default: false)"`
+	CaseSensitive bool   `json:"case_sensitive,omitempty" jsonschema:"Match case exactly (default: false)"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"Maximum number of matches to return (default: 20)"`
+	Context       int    `json:"context,omitempty" jsonschema:"Characters of surrounding text to include on each side of a match (default: 40)"`
+}
+
+// TextMatch is an occurrence of the searched text in the page.
+type TextMatch struct {
+	Text              string `json:"text" jsonschema:"The matched text"`
+	Before            string `json:"before,omitempty" jsonschema:"Text preceding the match"`
+	After             string `json:"after,omitempty" jsonschema:"Text following the match"`
+	Selector          string `json:"selector" jsonschema:"Selector of the element containing the match"`
+	Tag               string `json:"tag"`
+	ClickableSelector string `json:"clickable_selector,omitempty" jsonschema:"Selector of the nearest clickable element containing the match"`
+	ClickableTag      string `json:"clickable_tag,omitempty"`
+	ClickableID       int    `json:"clickable_id,omitempty" jsonschema:"Element id of the nearest clickable element, for the *_by_id tools"`
+}
+
+// FindTextResult is the structured result of find_text.
+type FindTextResult struct {
+	Matches []TextMatch `json:"matches"`
+	Total   int         `json:"total" jsonschema:"Number of matches in the page, including those beyond the limit"`
+}
+
+// FindText tool - searches the rendered page text and returns matches with
+// context and the nearest clickable element
+func (s *CDPBrowserServer) FindText(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[FindTextArgs]]) (*mcp.CallToolResultFor[FindTextResult], error) {
+	args := req.Params.Arguments
+	if args.Query == "" {
+		return &mcp.CallToolResultFor[FindTextResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "query is required"},
+			},
+			IsError: true,
+		}, nil
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	contextChars := args.Context
+	if contextChars <= 0 {
+		contextChars = 40
+	}
+
+	params, _ := json.Marshal([]any{args.Query, args.Regex, args.CaseSensitive, limit, contextChars})
+	js := fmt.Sprintf("(function() {%s return (%s)(...%s); })()", ariaHelpersJS, findTextJS, params)
+	var found struct {
+		FindTextResult
+		Clickable []int `json:"clickable"`
+	}
+	err := s.run(ctx, chromedp.Evaluate(js, &found))
+	if err != nil {
+		return &mcp.CallToolResultFor[FindTextResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error searching page text: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	result := found.FindTextResult
+	if result.Matches == nil {
+		result.Matches = []TextMatch{}
+	}
+
+	ids, err := s.interactiveElementIDs(ctx)
+	if err != nil {
+		log.Printf("FindText: failed to assign element ids: %v", err)
+	}
+	for i, c := range found.Clickable {
+		if i < len(result.Matches) && c >= 0 && c < len(ids) {
+			result.Matches[i].ClickableID = ids[c]
+		}
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%d matches for %q", result.Total, args.Query))
+	if result.Total > len(result.Matches) {
+		output.WriteString(fmt.Sprintf(" (showing the first %d)", len(result.Matches)))
+	}
+	output.WriteString("\n")
+	for i, m := range result.Matches {
+		output.WriteString(fmt.Sprintf("%d. %q in %s\n", i+1, m.Before+"["+m.Text+"]"+m.After, m.Selector))
+		if m.ClickableSelector != "" {
+			id := ""
+			if m.ClickableID != 0 {
+				id = fmt.Sprintf("#%d ", m.ClickableID)
+			}
+			output.WriteString(fmt.Sprintf("   clickable: %s<%s> %s\n", id, m.ClickableTag, m.ClickableSelector))
+		}
+	}
+
+	return &mcp.CallToolResultFor[FindTextResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: output.String()},
+		},
+		StructuredContent: result,
+	}, nil
+}
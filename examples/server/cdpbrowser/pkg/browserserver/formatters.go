@@ -0,0 +1,292 @@
+package browserserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A SnapshotFormatter renders an ARIA snapshot as text for a model.
+//
+// Formatters are selected per call with the aria_snapshot format argument.
+// Programs embedding the server can add their own with
+// [RegisterSnapshotFormatter] to match their model's preferred representation.
+type SnapshotFormatter interface {
+	Format(snapshot *ARIASnapshotResult) string
+}
+
+// SnapshotFormatterFunc adapts an ordinary function to a SnapshotFormatter.
+type SnapshotFormatterFunc func(snapshot *ARIASnapshotResult) string
+
+// Format calls f(snapshot).
+func (f SnapshotFormatterFunc) Format(snapshot *ARIASnapshotResult) string {
+	return f(snapshot)
+}
+
+var (
+	snapshotFormattersMu sync.RWMutex
+	snapshotFormatters   = map[string]SnapshotFormatter{
+		"llm-text": SnapshotFormatterFunc(formatVerbose),
+		"verbose":  SnapshotFormatterFunc(formatVerbose),
+		"compact":  SnapshotFormatterFunc(formatCompact),
+		"markdown": SnapshotFormatterFunc(formatMarkdown),
+		"yaml":     SnapshotFormatterFunc(formatYAML),
+		"json":     SnapshotFormatterFunc(formatJSON),
+		"debug":    SnapshotFormatterFunc(formatDebug),
+	}
+)
+
+// RegisterSnapshotFormatter makes a formatter available under name,
+// replacing any formatter previously registered with that name.
+func RegisterSnapshotFormatter(name string, f SnapshotFormatter) {
+	snapshotFormattersMu.Lock()
+	defer snapshotFormattersMu.Unlock()
+	snapshotFormatters[name] = f
+}
+
+// lookupSnapshotFormatter returns the formatter registered under name.
+func lookupSnapshotFormatter(name string) (SnapshotFormatter, bool) {
+	snapshotFormattersMu.RLock()
+	defer snapshotFormattersMu.RUnlock()
+	f, ok := snapshotFormatters[name]
+	return f, ok
+}
+
+// snapshotFormatterNames returns the sorted names of all registered formatters.
+func snapshotFormatterNames() []string {
+	snapshotFormattersMu.RLock()
+	defer snapshotFormattersMu.RUnlock()
+	names := make([]string, 0, len(snapshotFormatters))
+	for name := range snapshotFormatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// idPrefix returns "#n " for an interactive element with an ID, or "".
+func idPrefix(e ARIAElement) string {
+	if e.ID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("#%d ", e.ID)
+}
+
+// elementName returns the accessible name of e, or its tag if it has none.
+func elementName(e ARIAElement) string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return fmt.Sprintf("<%s>", e.Tag)
+}
+
+// formatVerbose is the original llm-text rendering: every section, with the
+// primary and alternative selectors for each interactive element.
+func formatVerbose(data *ARIASnapshotResult) string {
+	var output strings.Builder
+
+	// Page information
+	output.WriteString(fmt.Sprintf("PAGE: %s (%s)\n\n", data.Page.Title, data.Page.URL))
+
+	// Landmarks
+	if len(data.Landmarks) > 0 {
+		output.WriteString("LANDMARKS:\n")
+		for _, landmark := range data.Landmarks {
+			name := landmark.Name
+			if name == "" {
+				name = fmt.Sprintf("<%s>", landmark.Tag)
+			}
+			output.WriteString(fmt.Sprintf("• [%s] %s\n", landmark.Role, name))
+		}
+		output.WriteString("\n")
+	}
+
+	// Interactive elements
+	if len(data.Interactive) > 0 {
+		output.WriteString("INTERACTIVE ELEMENTS:\n")
+		for _, elem := range data.Interactive {
+			name := elem.Name
+			if name == "" {
+				name = fmt.Sprintf("<%s>", elem.Tag)
+			}
+
+			// Add href or value info if relevant
+			extra := ""
+			if elem.Href != "" {
+				extra = fmt.Sprintf(" -> %s", elem.Href)
+			} else if elem.Value != "" {
+				extra = fmt.Sprintf(" value=\"%s\"", elem.Value)
+			}
+
+			// Format with aria-label if available
+			if elem.AriaLabel != "" {
+				output.WriteString(fmt.Sprintf("• %s[%s] \"%s\" (aria-label: \"%s\")%s\n",
+					idPrefix(elem), elem.Role, name, elem.AriaLabel, extra))
+				output.WriteString(fmt.Sprintf("  - Primary selector: %s\n", elem.Selector))
+
+				// Show alternative selectors if available (the first one is the primary)
+				if len(elem.Selectors) > 1 {
+					output.WriteString("  - Alternative selectors: ")
+					output.WriteString(strings.Join(elem.Selectors[1:], ", ") + "\n")
+				}
+			} else {
+				output.WriteString(fmt.Sprintf("• %s[%s] \"%s\"%s (selector: %s)\n",
+					idPrefix(elem), elem.Role, name, extra, elem.Selector))
+			}
+		}
+		output.WriteString("\n")
+	}
+
+	// Headings
+	if len(data.Headings) > 0 {
+		output.WriteString("HEADINGS:\n")
+		for _, heading := range data.Headings {
+			indent := strings.Repeat("  ", max(heading.Level-1, 0))
+			output.WriteString(fmt.Sprintf("%s• [h%d] \"%s\"\n", indent, heading.Level, heading.Text))
+		}
+		output.WriteString("\n")
+	}
+
+	// Content structure
+	if len(data.Content) > 0 {
+		output.WriteString("CONTENT STRUCTURE:\n")
+		for _, section := range data.Content {
+			name := section.Name
+			if name == "" {
+				name = fmt.Sprintf("<%s>", section.Tag)
+			}
+			output.WriteString(fmt.Sprintf("• [%s] %s\n", section.Role, name))
+		}
+		output.WriteString("\n")
+	}
+
+	return output.String()
+}
+
+// formatCompact renders one short line per element, omitting alternative
+// selectors and landmark/content sections, to minimize tokens.
+func formatCompact(data *ARIASnapshotResult) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%s | %s\n", data.Page.Title, data.Page.URL))
+	for _, heading := range data.Headings {
+		output.WriteString(fmt.Sprintf("h%d %s\n", heading.Level, heading.Text))
+	}
+	for _, elem := range data.Interactive {
+		output.WriteString(fmt.Sprintf("%s%s %q %s\n", idPrefix(elem), elem.Role, elementName(elem), elem.Selector))
+	}
+	return output.String()
+}
+
+// formatMarkdown renders the snapshot as Markdown sections and tables.
+func formatMarkdown(data *ARIASnapshotResult) string {
+	var output strings.Builder
+	cell := func(s string) string {
+		return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+	}
+
+	output.WriteString(fmt.Sprintf("# %s\n\n%s\n\n", cell(data.Page.Title), data.Page.URL))
+
+	if len(data.Landmarks) > 0 {
+		output.WriteString("## Landmarks\n\n")
+		for _, landmark := range data.Landmarks {
+			output.WriteString(fmt.Sprintf("- **%s** %s\n", landmark.Role, cell(elementName(landmark))))
+		}
+		output.WriteString("\n")
+	}
+
+	if len(data.Interactive) > 0 {
+		output.WriteString("## Interactive elements\n\n")
+		output.WriteString("| Role | Name | Selector | Target |\n")
+		output.WriteString("| --- | --- | --- | --- |\n")
+		for _, elem := range data.Interactive {
+			target := elem.Href
+			if target == "" {
+				target = elem.Value
+			}
+			output.WriteString(fmt.Sprintf("| %s%s | %s | `%s` | %s |\n",
+				idPrefix(elem), elem.Role, cell(elementName(elem)), cell(elem.Selector), cell(target)))
+		}
+		output.WriteString("\n")
+	}
+
+	if len(data.Headings) > 0 {
+		output.WriteString("## Headings\n\n")
+		for _, heading := range data.Headings {
+			indent := strings.Repeat("  ", max(heading.Level-1, 0))
+			output.WriteString(fmt.Sprintf("%s- h%d %s\n", indent, heading.Level, cell(heading.Text)))
+		}
+		output.WriteString("\n")
+	}
+
+	if len(data.Content) > 0 {
+		output.WriteString("## Content\n\n")
+		for _, section := range data.Content {
+			output.WriteString(fmt.Sprintf("- **%s** %s\n", section.Role, cell(elementName(section))))
+		}
+		output.WriteString("\n")
+	}
+
+	return output.String()
+}
+
+// formatYAML renders the snapshot as YAML. Strings are emitted as
+// double-quoted scalars, whose escapes are a superset of Go's.
+func formatYAML(data *ARIASnapshotResult) string {
+	var output strings.Builder
+	output.WriteString("page:\n")
+	output.WriteString(fmt.Sprintf("  title: %s\n", strconv.Quote(data.Page.Title)))
+	output.WriteString(fmt.Sprintf("  url: %s\n", strconv.Quote(data.Page.URL)))
+
+	writeElements := func(key string, elems []ARIAElement) {
+		if len(elems) == 0 {
+			return
+		}
+		output.WriteString(key + ":\n")
+		for _, e := range elems {
+			prefix := "  - "
+			if e.ID > 0 {
+				output.WriteString(fmt.Sprintf("%sid: %d\n", prefix, e.ID))
+				prefix = "    "
+			}
+			field := func(name, value string) {
+				if value == "" {
+					return
+				}
+				output.WriteString(fmt.Sprintf("%s%s: %s\n", prefix, name, strconv.Quote(value)))
+				prefix = "    "
+			}
+			field("role", e.Role)
+			field("name", e.Name)
+			field("text", e.Text)
+			if e.Level > 0 {
+				output.WriteString(fmt.Sprintf("%slevel: %d\n", prefix, e.Level))
+				prefix = "    "
+			}
+			field("selector", e.Selector)
+			field("href", e.Href)
+			field("value", e.Value)
+		}
+	}
+	writeElements("landmarks", data.Landmarks)
+	writeElements("interactive", data.Interactive)
+	writeElements("headings", data.Headings)
+	writeElements("content", data.Content)
+	return output.String()
+}
+
+// formatJSON renders the snapshot as indented JSON.
+func formatJSON(data *ARIASnapshotResult) string {
+	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("Error formatting JSON: %v", err)
+	}
+	return string(jsonBytes)
+}
+
+// formatDebug dumps the snapshot with Go syntax for troubleshooting.
+func formatDebug(data *ARIASnapshotResult) string {
+	return fmt.Sprintf("ARIA Snapshot Debug:\n%+v", *data)
+}
@@ -0,0 +1,92 @@
+package browserserver
+
+import (
+	"strings"
+	"testing"
+)
+
+var testSnapshot = &ARIASnapshotResult{
+	Page: ARIAPageInfo{Title: "Checkout", URL: "https://shop.test/checkout"},
+	Landmarks: []ARIAElement{
+		{Role: "main", Selector: "main", Tag: "main"},
+	},
+	Interactive: []ARIAElement{
+		{Role: "button", Name: "Pay | now", Selector: "#pay", Selectors: []string{"#pay", "button.primary"}, AriaLabel: "Pay now", Tag: "button"},
+		{Role: "link", Name: "Back", Selector: `a[href="/cart"]`, Tag: "a", Href: "https://shop.test/cart"},
+	},
+	Headings: []ARIAElement{
+		{Level: 1, Text: `Review "order"`, Selector: "h1", Tag: "h1"},
+	},
+}
+
+func TestSnapshotFormatters(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"llm-text", []string{"PAGE: Checkout (https://shop.test/checkout)", `(aria-label: "Pay now")`, "Alternative selectors: button.primary", `• [h1] "Review "order""`}},
+		{"compact", []string{"Checkout | https://shop.test/checkout", `button "Pay | now" #pay`, `h1 Review "order"`}},
+		{"markdown", []string{"# Checkout", "| button | Pay \\| now | `#pay` |  |", "| link | Back | `a[href=\"/cart\"]` | https://shop.test/cart |", "- h1 Review \"order\""}},
+		{"yaml", []string{"page:\n  title: \"Checkout\"", "interactive:\n  - role: \"button\"\n    name: \"Pay | now\"\n    selector: \"#pay\"", `text: "Review \"order\""`, "level: 1"}},
+		{"json", []string{`"title": "Checkout"`, `"selector": "#pay"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			f, ok := lookupSnapshotFormatter(tt.format)
+			if !ok {
+				t.Fatalf("formatter %q not registered", tt.format)
+			}
+			got := f.Format(testSnapshot)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("%s output missing %q:\n%s", tt.format, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterSnapshotFormatter(t *testing.T) {
+	RegisterSnapshotFormatter("titles-only", SnapshotFormatterFunc(func(s *ARIASnapshotResult) string {
+		return s.Page.Title
+	}))
+	defer func() {
+		snapshotFormattersMu.Lock()
+		delete(snapshotFormatters, "titles-only")
+		snapshotFormattersMu.Unlock()
+	}()
+
+	f, ok := lookupSnapshotFormatter("titles-only")
+	if !ok {
+		t.Fatal("custom formatter not registered")
+	}
+	if got := f.Format(testSnapshot); got != "Checkout" {
+		t.Errorf("Format() = %q, want %q", got, "Checkout")
+	}
+	found := false
+	for _, name := range snapshotFormatterNames() {
+		found = found || name == "titles-only"
+	}
+	if !found {
+		t.Errorf("snapshotFormatterNames() = %v, missing custom formatter", snapshotFormatterNames())
+	}
+}
+
+func TestSnapshotFormattersShowID(t *testing.T) {
+	snapshot := &ARIASnapshotResult{
+		Interactive: []ARIAElement{
+			{ID: 7, Role: "button", Name: "Pay", Selector: "#pay", Tag: "button"},
+		},
+	}
+	for format, want := range map[string]string{
+		"llm-text": `• #7 [button] "Pay"`,
+		"compact":  `#7 button "Pay" #pay`,
+		"markdown": "| #7 button | Pay |",
+		"yaml":     "  - id: 7\n    role: \"button\"",
+	} {
+		f, _ := lookupSnapshotFormatter(format)
+		if got := f.Format(snapshot); !strings.Contains(got, want) {
+			t.Errorf("%s output missing %q:\n%s", format, want, got)
+		}
+	}
+}
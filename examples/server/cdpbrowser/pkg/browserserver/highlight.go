@@ -0,0 +1,147 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// highlightOverlayID is the id of the outline drawn by
+	// highlight_element. Like the other overlays, its id starts with
+	// __cdpbrowser so that action effects leave it out.
+	highlightOverlayID = "__cdpbrowser_highlight"
+
+	defaultHighlightDuration = 5 * time.Second
+	maxHighlightDuration     = 5 * time.Minute
+	defaultHighlightColor    = "#ff00ff"
+)
+
+// highlightJS is a function called with an element as this that outlines
+// it, replacing any earlier highlight, and returns a description of the
+// element and its position. The outline follows the element as the page
+// scrolls, ignores the pointer so it cannot intercept clicks, and removes
+// itself after ms milliseconds.
+const highlightJS = `function(label, color, ms) {` + pageStateJS + `
+	const old = document.getElementById('` + highlightOverlayID + `');
+	if (old) {
+		old.remove();
+	}
+	const el = this;
+	const box = document.createElement('div');
+	box.id = '` + highlightOverlayID + `';
+	box.setAttribute('aria-hidden', 'true');
+	box.style.cssText = 'position:fixed;box-sizing:border-box;pointer-events:none;z-index:2147483647;' +
+		'border:3px solid ' + color + ';border-radius:3px;';
+	if (label) {
+		const tag = document.createElement('span');
+		tag.textContent = label;
+		tag.style.cssText = 'position:absolute;left:-3px;top:-3px;transform:translateY(-100%);white-space:nowrap;' +
+			'background:' + color + ';color:#fff;font:bold 13px/16px sans-serif;padding:1px 4px;';
+		box.appendChild(tag);
+	}
+	const place = () => {
+		if (!box.isConnected) {
+			return;
+		}
+		const r = el.getBoundingClientRect();
+		box.style.left = (r.left - 3) + 'px';
+		box.style.top = (r.top - 3) + 'px';
+		box.style.width = (r.width + 6) + 'px';
+		box.style.height = (r.height + 6) + 'px';
+		box.style.display = el.isConnected ? '' : 'none';
+		requestAnimationFrame(place);
+	};
+	document.documentElement.appendChild(box);
+	place();
+	setTimeout(() => box.remove(), ms);
+	const r = el.getBoundingClientRect();
+	return {
+		element: describeElement(el),
+		x: Math.round(r.left),
+		y: Math.round(r.top),
+		width: Math.round(r.width),
+		height: Math.round(r.height)
+	};
+}`
+
+type HighlightElementArgs struct {
+	Selector        string `json:"selector" jsonschema:"CSS selector, DOM ID, or ARIA label for the element to highlight, or xpath= or text= followed by an XPath expression or the text it shows"`
+	Strict          bool   `json:"strict,omitempty" jsonschema:"Use the selector as written, as CSS or XPath, without smart resolution (default: false)"`
+	Label           string `json:"label,omitempty" jsonschema:"Text shown above the outline, e.g. 'About to click: Delete account'"`
+	Color           string `json:"color,omitempty" jsonschema:"CSS color of the outline (default: #ff00ff)"`
+	DurationSeconds int    `json:"duration_seconds,omitempty" jsonschema:"How long the highlight stays, up to 300 (default: 5)"`
+}
+
+// HighlightResult is the structured result of highlight_element.
+type HighlightResult struct {
+	Selector string `json:"selector" jsonschema:"The query that matched the element"`
+	XPath    bool   `json:"xpath,omitempty" jsonschema:"Whether selector is an XPath expression rather than CSS"`
+	Strategy string `json:"strategy" jsonschema:"Name of the selector strategy that matched the element, fallback if none did, or strict"`
+	Element  string `json:"element" jsonschema:"Summary of the highlighted element"`
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+// HighlightElement tool - outlines an element in the live browser so a human
+// watching can confirm the target before the agent acts on it
+func (s *CDPBrowserServer) HighlightElement(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[HighlightElementArgs]]) (*mcp.CallToolResultFor[HighlightResult], error) {
+	args := req.Params.Arguments
+	duration := defaultHighlightDuration
+	if args.DurationSeconds > 0 {
+		duration = min(time.Duration(args.DurationSeconds)*time.Second, maxHighlightDuration)
+	}
+	color := args.Color
+	if color == "" {
+		color = defaultHighlightColor
+	}
+
+	match := s.resolveSelector(ctx, args.Selector, args.Strict)
+	loc := match.locator()
+	result := HighlightResult{Selector: match.Selector, XPath: match.XPath, Strategy: match.Strategy}
+	callArgs, _ := json.Marshal([]any{args.Label, color, duration.Milliseconds()})
+	var nodes []*cdp.Node
+	err := s.run(ctx,
+		chromedp.Nodes(loc.Query, &nodes, loc.by(), chromedp.AtLeast(0)),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if len(nodes) == 0 {
+				return notFoundError{errors.New(noElementReason)}
+			}
+			b := nodes[0].BackendNodeID
+			// Elements without a layout box cannot be scrolled to; the
+			// outline then has no size, which the result shows.
+			dom.ScrollIntoViewIfNeeded().WithBackendNodeID(b).Do(ctx)
+			fn := fmt.Sprintf("function() { return (%s).apply(this, %s); }", highlightJS, callArgs)
+			return callOnElement(ctx, b, fn, &result)
+		}),
+	)
+	if err != nil {
+		noteSelectorError(ctx, err, args.Selector, match)
+		return &mcp.CallToolResultFor[HighlightResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error highlighting element %s: %v", match.Selector, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	text := fmt.Sprintf("Highlighted %s for %v: %s (strategy: %s)", result.Element, duration, match.Selector, match.Strategy)
+	if result.Width == 0 || result.Height == 0 {
+		text += "\nWarning: the element has no size, so the highlight is not visible"
+	}
+	return &mcp.CallToolResultFor[HighlightResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+		StructuredContent: result,
+	}, nil
+}
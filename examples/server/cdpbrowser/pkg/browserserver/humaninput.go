@@ -0,0 +1,274 @@
+package browserserver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// defaultHumanInputTimeout bounds how long request_human_input waits for
+	// the operator when the call does not set timeout_seconds.
+	defaultHumanInputTimeout = 5 * time.Minute
+	// humanInputProgressInterval is how often request_human_input reports
+	// progress while it waits, so that clients do not time out the call.
+	humanInputProgressInterval = 10 * time.Second
+)
+
+// Kinds of human input.
+const (
+	// humanInputText asks the operator for a value, such as a one-time code.
+	humanInputText = "text"
+	// humanInputConfirm asks the operator to do something in the browser,
+	// such as solving a CAPTCHA, and confirm when done.
+	humanInputConfirm = "confirm"
+)
+
+// elicitingSessions records the sessions whose client declared the
+// elicitation capability.
+type elicitingSessions struct {
+	sessions sync.Map // *mcp.ServerSession -> bool
+}
+
+// middleware records the elicitation capability of each client as it
+// initializes its session, and forgets the session when it ends.
+func (e *elicitingSessions) middleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		p, ok := req.GetParams().(*mcp.InitializeParams)
+		ss, isServer := req.GetSession().(*mcp.ServerSession)
+		if ok && isServer && p.Capabilities != nil && p.Capabilities.Elicitation != nil {
+			if _, loaded := e.sessions.LoadOrStore(ss, true); !loaded {
+				go func() {
+					ss.Wait()
+					e.sessions.Delete(ss)
+				}()
+			}
+		}
+		return next(ctx, method, req)
+	}
+}
+
+// supported reports whether the client of ss can answer elicitation requests.
+func (e *elicitingSessions) supported(ss *mcp.ServerSession) bool {
+	_, ok := e.sessions.Load(ss)
+	return ok
+}
+
+type RequestHumanInputArgs struct {
+	Prompt         string `json:"prompt" jsonschema:"What to ask the operator, e.g. 'Enter the 6-digit code sent to your phone' or 'Solve the CAPTCHA in the browser window'"`
+	Kind           string `json:"kind,omitempty" jsonschema:"text (default) to ask for a value, or confirm to ask the operator to act in the browser and confirm when done"`
+	Selector       string `json:"selector,omitempty" jsonschema:"Type the operator's answer into this field instead of returning it, e.g. for one-time codes (text only)"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty" jsonschema:"How long to wait for the operator (default: 300)"`
+}
+
+// HumanInputResult is the structured result of request_human_input.
+type HumanInputResult struct {
+	Action  string         `json:"action" jsonschema:"The operator's response: accept, decline, or cancel"`
+	Value   string         `json:"value,omitempty" jsonschema:"The operator's answer, unless it was typed into selector"`
+	Entered bool           `json:"entered,omitempty" jsonschema:"Whether the answer was typed into selector"`
+	Channel string         `json:"channel" jsonschema:"How the operator was asked: elicitation (through the MCP client) or terminal"`
+	Effects *ActionEffects `json:"effects,omitempty" jsonschema:"What typing the answer did to the page"`
+}
+
+// humanInputSchema returns the schema of the answer requested for kind.
+func humanInputSchema(kind string) *jsonschema.Schema {
+	schema := &jsonschema.Schema{Type: "object", Properties: map[string]*jsonschema.Schema{}}
+	if kind == humanInputText {
+		schema.Properties["value"] = &jsonschema.Schema{Type: "string", Title: "Answer"}
+		schema.Required = []string{"value"}
+	}
+	return schema
+}
+
+// askTerminal asks the operator on the terminal the server runs in. It is
+// used when the client cannot elicit input. The read cannot be interrupted,
+// so on timeout it is abandoned and its answer, if any, discarded.
+func askTerminal(ctx context.Context, kind, prompt string) (action, value string, err error) {
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONIN$"
+	}
+	tty, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return "", "", fmt.Errorf("the client does not support elicitation and the server has no terminal to ask the operator on: %v", err)
+	}
+	if kind == humanInputConfirm {
+		fmt.Fprintf(tty, "\n%s\nPress Enter when done, or type 'no' to decline: ", prompt)
+	} else {
+		fmt.Fprintf(tty, "\n%s\nAnswer (empty to decline): ", prompt)
+	}
+
+	type answer struct {
+		line string
+		err  error
+	}
+	answers := make(chan answer, 1)
+	go func() {
+		defer tty.Close()
+		line, err := bufio.NewReader(tty).ReadString('\n')
+		answers <- answer{strings.TrimRight(line, "\r\n"), err}
+	}()
+	select {
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	case a := <-answers:
+		if a.err != nil {
+			return "", "", fmt.Errorf("reading the answer from the terminal: %v", a.err)
+		}
+		switch {
+		case kind == humanInputConfirm && strings.EqualFold(strings.TrimSpace(a.line), "no"):
+			return "decline", "", nil
+		case kind == humanInputText && a.line == "":
+			return "decline", "", nil
+		}
+		return "accept", a.line, nil
+	}
+}
+
+// reportWaiting sends progress notifications for req until ctx is done, if
+// the client asked for progress.
+func reportWaiting(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[RequestHumanInputArgs]], prompt string) {
+	token := req.Params.GetProgressToken()
+	if token == nil {
+		return
+	}
+	start := time.Now()
+	ticker := time.NewTicker(humanInputProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+				ProgressToken: token,
+				Progress:      time.Since(start).Seconds(),
+				Message:       fmt.Sprintf("Waiting for the operator (%s): %s", time.Since(start).Round(time.Second), prompt),
+			})
+			if err != nil {
+				log.Printf("Failed to report progress: %v", err)
+			}
+		}
+	}
+}
+
+// RequestHumanInput tool - pauses the flow to ask the human operator for a
+// value, such as a one-time code, or to act in the browser, such as solving
+// a CAPTCHA
+func (s *CDPBrowserServer) RequestHumanInput(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[RequestHumanInputArgs]]) (*mcp.CallToolResultFor[HumanInputResult], error) {
+	args := req.Params.Arguments
+	fail := func(format string, a ...any) (*mcp.CallToolResultFor[HumanInputResult], error) {
+		return &mcp.CallToolResultFor[HumanInputResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf(format, a...)},
+			},
+			IsError: true,
+		}, nil
+	}
+	kind := args.Kind
+	if kind == "" {
+		kind = humanInputText
+	}
+	if kind != humanInputText && kind != humanInputConfirm {
+		return fail("Unknown kind %q (want %s or %s)", kind, humanInputText, humanInputConfirm)
+	}
+	if args.Prompt == "" {
+		return fail("prompt is required")
+	}
+	if args.Selector != "" && kind != humanInputText {
+		return fail("selector can only be used with kind %s", humanInputText)
+	}
+	timeout := defaultHumanInputTimeout
+	if args.TimeoutSeconds > 0 {
+		timeout = time.Duration(args.TimeoutSeconds) * time.Second
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	go reportWaiting(waitCtx, req, args.Prompt)
+
+	log.Printf("Asking the operator (%s): %s", kind, args.Prompt)
+	result := HumanInputResult{Channel: "terminal"}
+	var err error
+	if s.elicitation.supported(req.Session) {
+		result.Channel = "elicitation"
+		message := args.Prompt
+		if kind == humanInputConfirm {
+			message += "\n\nAccept when done."
+		}
+		var res *mcp.ElicitResult
+		res, err = req.Session.Elicit(waitCtx, &mcp.ElicitParams{Message: message, RequestedSchema: humanInputSchema(kind)})
+		if err == nil {
+			result.Action = res.Action
+			if v, ok := res.Content["value"].(string); ok {
+				result.Value = v
+			}
+		}
+	} else {
+		result.Action, result.Value, err = askTerminal(waitCtx, kind, args.Prompt)
+	}
+	if waitCtx.Err() == context.DeadlineExceeded {
+		return fail("The operator did not answer within %v", timeout)
+	}
+	if err != nil {
+		return fail("Error asking the operator: %v", err)
+	}
+	log.Printf("Operator responded: %s", result.Action)
+	if result.Action != "accept" {
+		return fail("The operator chose to %s the request: %s", result.Action, args.Prompt)
+	}
+
+	if args.Selector == "" {
+		text := "The operator confirmed: " + args.Prompt
+		if kind == humanInputText {
+			text = "The operator answered: " + result.Value
+		}
+		return &mcp.CallToolResultFor[HumanInputResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+			StructuredContent: result,
+		}, nil
+	}
+
+	// The answer goes to the page only; keep it out of the results and logs
+	// of this call. A code masked for longer would garble unrelated text.
+	defer s.secrets.addForCall(ctx, result.Value)()
+	match := s.resolveSelector(ctx, args.Selector, false)
+	loc := match.locator()
+	timeoutCtx, cancelType := context.WithTimeout(s.ctx, s.actionTimeoutOrDefault()+5*time.Second)
+	defer cancelType()
+	o := s.observeAction(ctx)
+	defer o.stop()
+	err = chromedp.Run(timeoutCtx,
+		s.waitActionable(loc, actionChecks{editable: true}),
+		chromedp.Clear(loc.Query, loc.by()),
+		chromedp.SendKeys(loc.Query, result.Value, loc.by()),
+	)
+	if err != nil {
+		return fail("Error typing the operator's answer into %s: %v", match.Selector, err)
+	}
+	result.Value = ""
+	result.Entered = true
+	result.Effects = o.finish(ctx)
+	text := "Typed the operator's answer into " + match.Selector
+	if result.Effects != nil {
+		text += "\n" + result.Effects.String()
+	}
+	return &mcp.CallToolResultFor[HumanInputResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+		StructuredContent: result,
+	}, nil
+}
@@ -0,0 +1,123 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRequestHumanInputElicitation(t *testing.T) {
+	s := &CDPBrowserServer{}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "request_human_input"}, s.RequestHumanInput)
+	server.AddReceivingMiddleware(s.elicitation.middleware)
+
+	var asked []*mcp.ElicitParams
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, &mcp.ClientOptions{
+		ElicitationHandler: func(ctx context.Context, req *mcp.ClientRequest[*mcp.ElicitParams]) (*mcp.ElicitResult, error) {
+			asked = append(asked, req.Params)
+			if strings.Contains(req.Params.Message, "CAPTCHA") {
+				return &mcp.ElicitResult{Action: "decline"}, nil
+			}
+			return &mcp.ElicitResult{Action: "accept", Content: map[string]any{"value": "123456"}}, nil
+		},
+	})
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	cs, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	call := func(args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "request_human_input", Arguments: args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := call(map[string]any{"prompt": "Enter the code sent to your phone"})
+	if res.IsError || resultText(res) != "The operator answered: 123456" {
+		t.Errorf("text input result = %q (error %t)", resultText(res), res.IsError)
+	}
+	var got HumanInputResult
+	data, _ := json.Marshal(res.StructuredContent)
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Action != "accept" || got.Value != "123456" || got.Channel != "elicitation" {
+		t.Errorf("structured result = %+v", got)
+	}
+	if len(asked) != 1 || asked[0].RequestedSchema.Properties["value"] == nil {
+		t.Fatalf("elicitation requests = %+v, want one asking for a value", asked)
+	}
+
+	res = call(map[string]any{"prompt": "Solve the CAPTCHA", "kind": "confirm"})
+	if !res.IsError || !strings.Contains(resultText(res), "chose to decline") {
+		t.Errorf("declined confirmation result = %q (error %t)", resultText(res), res.IsError)
+	}
+	if len(asked) != 2 || len(asked[1].RequestedSchema.Properties) != 0 || !strings.Contains(asked[1].Message, "Accept when done") {
+		t.Errorf("confirmation request = %+v", asked[len(asked)-1])
+	}
+
+	for _, args := range []map[string]any{
+		{"prompt": "x", "kind": "vote"},
+		{"prompt": ""},
+		{"prompt": "x", "kind": "confirm", "selector": "#otp"},
+	} {
+		if res := call(args); !res.IsError {
+			t.Errorf("request_human_input(%v) succeeded, want an error", args)
+		}
+	}
+	if len(asked) != 2 {
+		t.Errorf("invalid requests reached the operator: %d requests", len(asked))
+	}
+}
+
+func TestElicitingSessionsForgetClosedSessions(t *testing.T) {
+	var e elicitingSessions
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	server.AddReceivingMiddleware(e.middleware)
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, &mcp.ClientOptions{
+		ElicitationHandler: func(context.Context, *mcp.ClientRequest[*mcp.ElicitParams]) (*mcp.ElicitResult, error) {
+			return &mcp.ElicitResult{Action: "cancel"}, nil
+		},
+	})
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.supported(serverSession) {
+		t.Fatal("session of an eliciting client not recorded")
+	}
+
+	cs.Close()
+	serverSession.Wait()
+	deadline := time.Now().Add(5 * time.Second)
+	for e.supported(serverSession) {
+		if time.Now().After(deadline) {
+			t.Fatal("closed session still recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,215 @@
+package browserserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// idempotencyKeyArg is the argument that carries the idempotency key of a
+// call.
+const idempotencyKeyArg = "idempotency_key"
+
+// idempotencyTTL is how long the result of a call is kept for repeated calls
+// with the same key.
+const idempotencyTTL = 10 * time.Minute
+
+// idempotentTools are the tools that accept an idempotency key: the tools
+// whose repetition changes the page or the site behind it.
+var idempotentTools = map[string]bool{
+	"navigate":                true,
+	"click":                   true,
+	"type_text":               true,
+	"click_button":            true,
+	"click_link":              true,
+	"select_dropdown":         true,
+	"choose_option":           true,
+	"refresh_page":            true,
+	"click_element_by_id":     true,
+	"type_into_element_by_id": true,
+	"click_at":                true,
+	"swipe":                   true,
+	"login_with_credentials":  true,
+	"batch":                   true,
+	"run_macro":               true,
+	"replay_recording":        true,
+}
+
+// withIdempotencyKey sets the input schema of t to the schema inferred from
+// In with an idempotency_key property added.
+func withIdempotencyKey[In any](t *mcp.Tool) {
+	schema, err := jsonschema.For[In](nil)
+	if err != nil {
+		panic(fmt.Sprintf("inferring the input schema of tool %q: %v", t.Name, err))
+	}
+	if schema.Properties == nil {
+		schema.Properties = make(map[string]*jsonschema.Schema)
+	}
+	schema.Properties[idempotencyKeyArg] = &jsonschema.Schema{
+		Type:        "string",
+		Description: "Unique key for this action. A repeated call with the same key, such as a retry after a timeout, returns the first call's result instead of acting again (default: none)",
+	}
+	t.InputSchema = schema
+}
+
+// idempotentCall is a call made with an idempotency key.
+type idempotentCall struct {
+	tool string
+	// args are the arguments of the call without the key, as canonical JSON.
+	args    []byte
+	started time.Time
+	// done is closed when the call completes. result is then set if the
+	// call succeeded, and nil if it failed.
+	done   chan struct{}
+	result *mcp.CallToolResult
+}
+
+// idempotencyStore holds the calls made with idempotency keys, per session.
+type idempotencyStore struct {
+	mu    sync.Mutex
+	calls map[*mcp.ServerSession]map[string]*idempotentCall
+}
+
+// start returns the call with key in session if there is one, or records c
+// under key and returns nil. Calls older than idempotencyTTL are dropped
+// first.
+func (st *idempotencyStore) start(session *mcp.ServerSession, key string, c *idempotentCall) *idempotentCall {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for sess, calls := range st.calls {
+		for k, old := range calls {
+			if time.Since(old.started) > idempotencyTTL {
+				delete(calls, k)
+			}
+		}
+		if len(calls) == 0 {
+			delete(st.calls, sess)
+		}
+	}
+	if prev, ok := st.calls[session][key]; ok {
+		return prev
+	}
+	if st.calls == nil {
+		st.calls = make(map[*mcp.ServerSession]map[string]*idempotentCall)
+	}
+	if st.calls[session] == nil {
+		st.calls[session] = make(map[string]*idempotentCall)
+	}
+	st.calls[session][key] = c
+	return nil
+}
+
+// release forgets the call with key in session, so that it can be retried.
+func (st *idempotencyStore) release(session *mcp.ServerSession, key string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.calls[session], key)
+}
+
+// splitIdempotencyKey returns the idempotency key in args and the remaining
+// arguments as canonical JSON. ok is false if args has no key.
+func splitIdempotencyKey(args json.RawMessage) (key string, rest []byte, ok bool) {
+	var m map[string]any
+	if json.Unmarshal(args, &m) != nil {
+		return "", nil, false
+	}
+	v, ok := m[idempotencyKeyArg]
+	if !ok {
+		return "", nil, false
+	}
+	key, _ = v.(string)
+	delete(m, idempotencyKeyArg)
+	// Maps are marshalled with sorted keys.
+	rest, err := json.Marshal(m)
+	return key, rest, err == nil
+}
+
+// idempotencyMiddleware deduplicates calls to idempotent tools that repeat
+// an idempotency key within a session. A repeated call returns the result of
+// the first one, waiting for it if it is still running. Only successful
+// results are kept: a failed call releases its key, so the action can be
+// retried with it. The key is removed from the arguments before the tool
+// sees them, so recordings leave it out.
+func (s *CDPBrowserServer) idempotencyMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		params, ok := req.GetParams().(*mcp.CallToolParamsFor[json.RawMessage])
+		if !ok || !idempotentTools[params.Name] {
+			return next(ctx, method, req)
+		}
+		key, args, ok := splitIdempotencyKey(params.Arguments)
+		if !ok {
+			return next(ctx, method, req)
+		}
+		params.Arguments = args
+		session, _ := req.GetSession().(*mcp.ServerSession)
+		if key == "" || session == nil {
+			return next(ctx, method, req)
+		}
+
+		c := &idempotentCall{tool: params.Name, args: args, started: time.Now(), done: make(chan struct{})}
+		if prev := s.idempotency.start(session, key, c); prev != nil {
+			return s.repeatedCall(ctx, key, prev, c)
+		}
+
+		// Release the key and wake the repeated calls even if the tool
+		// panics, or they would wait for as long as their contexts last.
+		defer func() {
+			if c.result == nil {
+				s.idempotency.release(session, key)
+			}
+			close(c.done)
+		}()
+		result, err := next(ctx, method, req)
+		if res, _ := result.(*mcp.CallToolResult); err == nil && res != nil && !res.IsError {
+			c.result = res
+		}
+		return result, err
+	}
+}
+
+// repeatedCall returns the result of call c, which repeats the idempotency
+// key of the earlier call prev.
+func (s *CDPBrowserServer) repeatedCall(ctx context.Context, key string, prev, c *idempotentCall) (mcp.Result, error) {
+	if prev.tool != c.tool || !bytes.Equal(prev.args, c.args) {
+		res := &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Idempotency key %q was already used for a %s call with different arguments", key, prev.tool)},
+			},
+			IsError: true,
+		}
+		s.describeFailure(res, &toolFailure{err: invalidArgumentError{fmt.Errorf("idempotency key reused")}})
+		return res, nil
+	}
+	select {
+	case <-prev.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if prev.result == nil {
+		// The failed call released the key, so a retry runs the action.
+		res := &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("The first %s call with idempotency key %q failed, so this one did not run; retry it with the same key", prev.tool, key)},
+			},
+			IsError: true,
+		}
+		te := s.describeFailure(res, &toolFailure{err: fmt.Errorf("first call with idempotency key failed")})
+		te.Retryable = true
+		return res, nil
+	}
+	log.Printf("Idempotency: %s call with key %q repeats the call at %s; returning its result", c.tool, key, prev.started.Format(time.TimeOnly))
+
+	res := *prev.result
+	res.Meta = mcp.Meta{"idempotentReplay": true}
+	res.Content = append([]mcp.Content{
+		&mcp.TextContent{Text: fmt.Sprintf("Not repeated: a call with idempotency key %q already ran at %s. Its result follows.", key, prev.started.Format(time.TimeOnly))},
+	}, prev.result.Content...)
+	return &res, nil
+}
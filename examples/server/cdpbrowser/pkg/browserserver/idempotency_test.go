@@ -0,0 +1,185 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	s := &CDPBrowserServer{}
+	var mu sync.Mutex
+	clicks := make(map[string]int)
+	release := make(chan struct{})
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	addTool(server, s, &mcp.Tool{Name: "click"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+		sel := req.Params.Arguments.Selector
+		if sel == "#slow" {
+			<-release
+		}
+		mu.Lock()
+		clicks[sel]++
+		mu.Unlock()
+		if sel == "#bad" {
+			return &mcp.CallToolResultFor[SelectorMatch]{
+				Content: []mcp.Content{&mcp.TextContent{Text: "Error clicking element #bad"}},
+				IsError: true,
+			}, nil
+		}
+		return &mcp.CallToolResultFor[SelectorMatch]{
+			Content:           []mcp.Content{&mcp.TextContent{Text: "Clicked element: " + sel}},
+			StructuredContent: SelectorMatch{Selector: sel, Strategy: "strict"},
+		}, nil
+	})
+	server.AddReceivingMiddleware(s.idempotencyMiddleware, s.toolErrorMiddleware)
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	tools, err := cs.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props := tools.Tools[0].InputSchema.Properties; props["idempotency_key"] == nil || props["selector"] == nil {
+		t.Errorf("click input schema properties = %v, want selector and idempotency_key", props)
+	}
+
+	call := func(args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "click", Arguments: args})
+		if err != nil {
+			t.Fatalf("CallTool(click, %v) error = %v", args, err)
+		}
+		return res
+	}
+
+	call(map[string]any{"selector": "#order", "idempotency_key": "order-1"})
+	res := call(map[string]any{"selector": "#order", "idempotency_key": "order-1"})
+	if clicks["#order"] != 1 {
+		t.Errorf("#order clicked %d times, want 1", clicks["#order"])
+	}
+	if text := resultText(res); res.IsError || !strings.Contains(text, `Not repeated: a call with idempotency key "order-1" already ran`) || !strings.Contains(text, "Clicked element: #order") {
+		t.Errorf("repeated call result = %q", text)
+	}
+	if res.Meta["idempotentReplay"] != true {
+		t.Errorf("repeated call meta = %v", res.Meta)
+	}
+
+	res = call(map[string]any{"selector": "#cancel", "idempotency_key": "order-1"})
+	if text := resultText(res); !res.IsError || !strings.Contains(text, "already used for a click call with different arguments") || !strings.Contains(text, "Error code: INVALID_ARGUMENT") {
+		t.Errorf("key reused with other arguments = %q", text)
+	}
+	if clicks["#cancel"] != 0 {
+		t.Error("call reusing a key with other arguments ran")
+	}
+
+	// A failed call can be retried with the same key.
+	call(map[string]any{"selector": "#bad", "idempotency_key": "retry"})
+	call(map[string]any{"selector": "#bad", "idempotency_key": "retry"})
+	if clicks["#bad"] != 2 {
+		t.Errorf("failed call ran %d times with the same key, want 2", clicks["#bad"])
+	}
+
+	// Calls without a key are not deduplicated.
+	call(map[string]any{"selector": "#next"})
+	call(map[string]any{"selector": "#next"})
+	if clicks["#next"] != 2 {
+		t.Errorf("#next clicked %d times without a key, want 2", clicks["#next"])
+	}
+
+	// A retry while the first call is running waits for its result.
+	var wg sync.WaitGroup
+	results := make([]*mcp.CallToolResult, 2)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = cs.CallTool(ctx, &mcp.CallToolParams{Name: "click", Arguments: map[string]any{"selector": "#slow", "idempotency_key": "slow"}})
+		}()
+	}
+	close(release)
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	if clicks["#slow"] != 1 {
+		t.Errorf("#slow clicked %d times by concurrent calls with one key, want 1", clicks["#slow"])
+	}
+	for i, res := range results {
+		if res == nil || res.IsError {
+			t.Errorf("concurrent call %d = %v", i, res)
+		}
+	}
+}
+
+func TestIdempotencyRepeatOfFailedCall(t *testing.T) {
+	s := &CDPBrowserServer{}
+	prev := &idempotentCall{tool: "click", args: []byte(`{"selector":"#pay"}`), done: make(chan struct{})}
+	close(prev.done) // failed: no result
+	c := &idempotentCall{tool: "click", args: []byte(`{"selector":"#pay"}`)}
+
+	result, err := s.repeatedCall(context.Background(), "pay-1", prev, c)
+	if err != nil {
+		t.Fatalf("repeatedCall() error = %v, want a tool error result", err)
+	}
+	res := result.(*mcp.CallToolResult)
+	if text := resultText(res); !res.IsError || !strings.Contains(text, "retry it with the same key") {
+		t.Errorf("repeat of a failed call = %q (error %t)", text, res.IsError)
+	}
+	if te, ok := res.StructuredContent.(*ToolError); !ok || !te.Retryable {
+		t.Errorf("repeat of a failed call structured content = %+v, want a retryable ToolError", res.StructuredContent)
+	}
+}
+
+func TestIdempotencyKeyReleasedOnPanic(t *testing.T) {
+	s := &CDPBrowserServer{}
+	panicking := true
+	handler := s.idempotencyMiddleware(func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if panicking {
+			panic("tool crashed")
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Clicked"}}}, nil
+	})
+	session := &mcp.ServerSession{}
+	request := func() mcp.Request {
+		return &mcp.ServerRequest[*mcp.CallToolParamsFor[json.RawMessage]]{
+			Session: session,
+			Params:  &mcp.CallToolParamsFor[json.RawMessage]{Name: "click", Arguments: json.RawMessage(`{"selector":"#pay","idempotency_key":"pay-1"}`)},
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("the tool's panic was swallowed")
+			}
+		}()
+		handler(context.Background(), "tools/call", request())
+	}()
+
+	// The retry must run the tool again, not wait for the crashed call
+	panicking = false
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := handler(ctx, "tools/call", request())
+	if err != nil {
+		t.Fatalf("retry after a panic: %v", err)
+	}
+	if text := resultText(res.(*mcp.CallToolResult)); text != "Clicked" {
+		t.Errorf("retry result = %q, want the tool to run again", text)
+	}
+}
@@ -0,0 +1,333 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// maxInputSteps limits the intermediate events of move_mouse and swipe.
+	maxInputSteps = 100
+	// defaultSwipeSteps and defaultSwipeDuration shape a swipe without
+	// steps or duration_ms.
+	defaultSwipeSteps    = 10
+	defaultSwipeDuration = 300 * time.Millisecond
+	// maxSwipeDuration bounds duration_ms.
+	maxSwipeDuration = 10 * time.Second
+)
+
+// mousePosition is where the coordinate tools last left the mouse, the
+// starting point of move_mouse. Chrome does not report it.
+type mousePosition struct {
+	mu   sync.Mutex
+	x, y float64
+}
+
+func (m *mousePosition) get() (x, y float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.x, m.y
+}
+
+func (m *mousePosition) set(x, y float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.x, m.y = x, y
+}
+
+// mouseButtons maps the button argument of click_at to the button and the
+// bit it sets in the buttons of mouse events.
+var mouseButtons = map[string]struct {
+	button input.MouseButton
+	bit    int64
+}{
+	"left":   {input.Left, 1},
+	"right":  {input.Right, 2},
+	"middle": {input.Middle, 4},
+}
+
+// describeElementJS is a function that describes the element it is called
+// on, like the elements in action effects.
+const describeElementJS = `function() {` + pageStateJS + ` return describeElement(this); }`
+
+// checkPoints returns an action that fails with an invalidArgumentError if
+// any of points, given as x, y pairs, is outside the viewport.
+func checkPoints(points ...float64) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		_, _, _, viewport, _, _, err := page.GetLayoutMetrics().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("getting the viewport size: %w", err)
+		}
+		w, h := float64(viewport.ClientWidth), float64(viewport.ClientHeight)
+		for i := 0; i+1 < len(points); i += 2 {
+			if x, y := points[i], points[i+1]; x < 0 || y < 0 || x >= w || y >= h {
+				return invalidArgumentError{fmt.Errorf("(%g, %g) is outside the %gx%g viewport", x, y, w, h)}
+			}
+		}
+		return nil
+	})
+}
+
+// elementAt returns the backend node ID and description of the element at
+// x, y in the viewport, or 0 and "" if there is none.
+func elementAt(ctx context.Context, x, y float64) (cdp.BackendNodeID, string) {
+	b, _, _, err := dom.GetNodeForLocation(int64(x), int64(y)).Do(ctx)
+	if err != nil || b == 0 {
+		return 0, ""
+	}
+	var desc string
+	if err := callOnElement(ctx, b, describeElementJS, &desc); err != nil {
+		return b, ""
+	}
+	return b, desc
+}
+
+// pointText formats x, y and the element there for tool results.
+func pointText(x, y float64, element string) string {
+	if element == "" {
+		return fmt.Sprintf("(%g, %g)", x, y)
+	}
+	return fmt.Sprintf("(%g, %g) on %s", x, y, element)
+}
+
+// inputPath returns the points of a straight path of steps moves from
+// (x0, y0) to (x1, y1), excluding the start.
+func inputPath(x0, y0, x1, y1 float64, steps int) [][2]float64 {
+	path := make([][2]float64, steps)
+	for i := range path {
+		f := float64(i+1) / float64(steps)
+		path[i] = [2]float64{x0 + (x1-x0)*f, y0 + (y1-y0)*f}
+	}
+	return path
+}
+
+// inputSteps validates a steps argument, returning def if it is 0.
+func inputSteps(steps, def int) (int, error) {
+	if steps == 0 {
+		return def, nil
+	}
+	if steps < 0 || steps > maxInputSteps {
+		return 0, fmt.Errorf("steps must be between 1 and %d, got %d", maxInputSteps, steps)
+	}
+	return steps, nil
+}
+
+// inputError returns a tool error result for a coordinate tool.
+func inputError(ctx context.Context, action string, err error) *mcp.CallToolResultFor[ActionEffects] {
+	noteToolError(ctx, err, "")
+	return &mcp.CallToolResultFor[ActionEffects]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Error %s: %v", action, err)},
+		},
+		IsError: true,
+	}
+}
+
+type ClickAtArgs struct {
+	X          float64 `json:"x" jsonschema:"Horizontal position in the viewport, in CSS pixels, as in the boxes of ocr_screenshot and annotated_screenshot"`
+	Y          float64 `json:"y" jsonschema:"Vertical position in the viewport, in CSS pixels"`
+	Button     string  `json:"button,omitempty" jsonschema:"left, right, or middle (default: left)"`
+	ClickCount int     `json:"click_count,omitempty" jsonschema:"1 for a click, 2 for a double click, 3 for a triple click (default: 1)"`
+	Confirm    bool    `json:"confirm,omitempty" jsonschema:"Confirm a click the server's confirmation policy treats as destructive, after checking with the user (default: false)"`
+}
+
+// ClickAt tool - clicks a point in the viewport, for canvas apps, maps, and
+// games whose targets are not elements
+func (s *CDPBrowserServer) ClickAt(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickAtArgs]]) (*mcp.CallToolResultFor[ActionEffects], error) {
+	args := req.Params.Arguments
+	if args.Button == "" {
+		args.Button = "left"
+	}
+	button, ok := mouseButtons[args.Button]
+	if !ok {
+		return inputError(ctx, "clicking", invalidArgumentError{fmt.Errorf("unknown button %q (want left, right, or middle)", args.Button)}), nil
+	}
+	if args.ClickCount == 0 {
+		args.ClickCount = 1
+	}
+	if args.ClickCount < 1 || args.ClickCount > 3 {
+		return inputError(ctx, "clicking", invalidArgumentError{fmt.Errorf("click_count must be 1, 2, or 3, got %d", args.ClickCount)}), nil
+	}
+
+	o := s.observeAction(ctx)
+	defer o.stop()
+	c := s.confirmation(req.Session, args.Confirm)
+	var target string
+	err := s.run(ctx, checkPoints(args.X, args.Y), chromedp.ActionFunc(func(ctx context.Context) error {
+		var b cdp.BackendNodeID
+		b, target = elementAt(ctx, args.X, args.Y)
+		if b != 0 {
+			if err := c.check(ctx, b); err != nil {
+				return err
+			}
+		}
+		if err := input.DispatchMouseEvent(input.MouseMoved, args.X, args.Y).Do(ctx); err != nil {
+			return err
+		}
+		for n := int64(1); n <= int64(args.ClickCount); n++ {
+			if err := input.DispatchMouseEvent(input.MousePressed, args.X, args.Y).
+				WithButton(button.button).WithButtons(button.bit).WithClickCount(n).Do(ctx); err != nil {
+				return err
+			}
+			if err := input.DispatchMouseEvent(input.MouseReleased, args.X, args.Y).
+				WithButton(button.button).WithClickCount(n).Do(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	if err != nil {
+		return inputError(ctx, "clicking at "+pointText(args.X, args.Y, target), err), nil
+	}
+	s.mouse.set(args.X, args.Y)
+
+	verb := "Clicked"
+	switch args.ClickCount {
+	case 2:
+		verb = "Double-clicked"
+	case 3:
+		verb = "Triple-clicked"
+	}
+	if args.Button != "left" {
+		verb = fmt.Sprintf("%s (%s button)", verb, args.Button)
+	}
+	return actionResult(fmt.Sprintf("%s at %s", verb, pointText(args.X, args.Y, target)), o.finish(ctx)), nil
+}
+
+type MoveMouseArgs struct {
+	X     float64 `json:"x" jsonschema:"Horizontal position in the viewport, in CSS pixels"`
+	Y     float64 `json:"y" jsonschema:"Vertical position in the viewport, in CSS pixels"`
+	Steps int     `json:"steps,omitempty" jsonschema:"Number of mouse move events on a straight line from the last position, for pages that track the pointer (default: 1)"`
+}
+
+// MoveMouse tool - moves the mouse to a point in the viewport, hovering
+// whatever is there
+func (s *CDPBrowserServer) MoveMouse(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[MoveMouseArgs]]) (*mcp.CallToolResultFor[ActionEffects], error) {
+	args := req.Params.Arguments
+	steps, err := inputSteps(args.Steps, 1)
+	if err != nil {
+		return inputError(ctx, "moving the mouse", invalidArgumentError{err}), nil
+	}
+
+	o := s.observeAction(ctx)
+	defer o.stop()
+	x0, y0 := s.mouse.get()
+	var target string
+	err = s.run(ctx, checkPoints(args.X, args.Y), chromedp.ActionFunc(func(ctx context.Context) error {
+		for _, p := range inputPath(x0, y0, args.X, args.Y, steps) {
+			if err := input.DispatchMouseEvent(input.MouseMoved, p[0], p[1]).Do(ctx); err != nil {
+				return err
+			}
+		}
+		_, target = elementAt(ctx, args.X, args.Y)
+		return nil
+	}))
+	if err != nil {
+		return inputError(ctx, "moving the mouse", err), nil
+	}
+	s.mouse.set(args.X, args.Y)
+	return actionResult("Moved the mouse to "+pointText(args.X, args.Y, target), o.finish(ctx)), nil
+}
+
+type SwipeArgs struct {
+	FromX      float64 `json:"from_x" jsonschema:"Horizontal start position in the viewport, in CSS pixels"`
+	FromY      float64 `json:"from_y" jsonschema:"Vertical start position in the viewport, in CSS pixels"`
+	ToX        float64 `json:"to_x" jsonschema:"Horizontal end position in the viewport, in CSS pixels"`
+	ToY        float64 `json:"to_y" jsonschema:"Vertical end position in the viewport, in CSS pixels"`
+	Pointer    string  `json:"pointer,omitempty" jsonschema:"mouse to drag with the left button held, or touch for a one-finger swipe (default: mouse)"`
+	DurationMS int     `json:"duration_ms,omitempty" jsonschema:"How long the gesture takes, in milliseconds (default: 300)"`
+	Steps      int     `json:"steps,omitempty" jsonschema:"Number of move events between the start and the end (default: 10)"`
+}
+
+// Swipe tool - drags the mouse or swipes a finger between two points, for
+// panning maps, sliders, and gesture-driven canvases
+func (s *CDPBrowserServer) Swipe(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[SwipeArgs]]) (*mcp.CallToolResultFor[ActionEffects], error) {
+	args := req.Params.Arguments
+	fail := func(err error) (*mcp.CallToolResultFor[ActionEffects], error) {
+		return inputError(ctx, "swiping", invalidArgumentError{err}), nil
+	}
+	if args.Pointer == "" {
+		args.Pointer = "mouse"
+	}
+	if args.Pointer != "mouse" && args.Pointer != "touch" {
+		return fail(fmt.Errorf("unknown pointer %q (want mouse or touch)", args.Pointer))
+	}
+	steps, err := inputSteps(args.Steps, defaultSwipeSteps)
+	if err != nil {
+		return fail(err)
+	}
+	duration := defaultSwipeDuration
+	if args.DurationMS != 0 {
+		duration = time.Duration(args.DurationMS) * time.Millisecond
+	}
+	if duration < 0 || duration > maxSwipeDuration {
+		return fail(fmt.Errorf("duration_ms must be between 0 and %d, got %d", maxSwipeDuration.Milliseconds(), args.DurationMS))
+	}
+
+	var start, move, end func(x, y float64) chromedp.Action
+	if args.Pointer == "touch" {
+		touch := func(t input.TouchType) func(x, y float64) chromedp.Action {
+			return func(x, y float64) chromedp.Action {
+				var points []*input.TouchPoint
+				if t != input.TouchEnd {
+					points = []*input.TouchPoint{{X: x, Y: y}}
+				}
+				return input.DispatchTouchEvent(t, points)
+			}
+		}
+		start, move, end = touch(input.TouchStart), touch(input.TouchMove), touch(input.TouchEnd)
+	} else {
+		left := mouseButtons["left"]
+		start = func(x, y float64) chromedp.Action {
+			return chromedp.Tasks{
+				input.DispatchMouseEvent(input.MouseMoved, x, y),
+				input.DispatchMouseEvent(input.MousePressed, x, y).WithButton(left.button).WithButtons(left.bit).WithClickCount(1),
+			}
+		}
+		move = func(x, y float64) chromedp.Action {
+			return input.DispatchMouseEvent(input.MouseMoved, x, y).WithButton(left.button).WithButtons(left.bit)
+		}
+		end = func(x, y float64) chromedp.Action {
+			return input.DispatchMouseEvent(input.MouseReleased, x, y).WithButton(left.button).WithClickCount(1)
+		}
+	}
+
+	o := s.observeAction(ctx)
+	defer o.stop()
+	var target string
+	err = s.run(ctx, checkPoints(args.FromX, args.FromY, args.ToX, args.ToY), chromedp.ActionFunc(func(ctx context.Context) error {
+		_, target = elementAt(ctx, args.FromX, args.FromY)
+		if err := start(args.FromX, args.FromY).Do(ctx); err != nil {
+			return err
+		}
+		interval := duration / time.Duration(steps)
+		for _, p := range inputPath(args.FromX, args.FromY, args.ToX, args.ToY, steps) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+			if err := move(p[0], p[1]).Do(ctx); err != nil {
+				return err
+			}
+		}
+		return end(args.ToX, args.ToY).Do(ctx)
+	}))
+	if err != nil {
+		return inputError(ctx, "swiping", err), nil
+	}
+	if args.Pointer == "mouse" {
+		s.mouse.set(args.ToX, args.ToY)
+	}
+	text := fmt.Sprintf("Swiped (%s) from %s to (%g, %g) in %v", args.Pointer, pointText(args.FromX, args.FromY, target), args.ToX, args.ToY, duration)
+	return actionResult(text, o.finish(ctx)), nil
+}
@@ -0,0 +1,73 @@
+package browserserver
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestInputPath(t *testing.T) {
+	got := inputPath(0, 100, 40, 60, 4)
+	want := [][2]float64{{10, 90}, {20, 80}, {30, 70}, {40, 60}}
+	if !slices.Equal(got, want) {
+		t.Errorf("inputPath = %v, want %v", got, want)
+	}
+	if got := inputPath(5, 5, 5, 5, 1); !slices.Equal(got, [][2]float64{{5, 5}}) {
+		t.Errorf("single step path = %v", got)
+	}
+
+	if n, err := inputSteps(0, defaultSwipeSteps); n != defaultSwipeSteps || err != nil {
+		t.Errorf("default steps = %d, %v", n, err)
+	}
+	for _, n := range []int{-1, maxInputSteps + 1} {
+		if _, err := inputSteps(n, 1); err == nil {
+			t.Errorf("inputSteps(%d) succeeded", n)
+		}
+	}
+}
+
+func TestPointText(t *testing.T) {
+	if got := pointText(12.5, 40, ""); got != "(12.5, 40)" {
+		t.Errorf("pointText without element = %q", got)
+	}
+	if got := pointText(3, 4, `canvas#map`); got != "(3, 4) on canvas#map" {
+		t.Errorf("pointText = %q", got)
+	}
+}
+
+func TestCoordinateInputArgs(t *testing.T) {
+	s := &CDPBrowserServer{}
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name string
+		call func() (*mcp.CallToolResultFor[ActionEffects], error)
+		want string
+	}{
+		{"button", func() (*mcp.CallToolResultFor[ActionEffects], error) {
+			return s.ClickAt(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[ClickAtArgs]]{Params: &mcp.CallToolParamsFor[ClickAtArgs]{Arguments: ClickAtArgs{Button: "back"}}})
+		}, `unknown button "back"`},
+		{"click count", func() (*mcp.CallToolResultFor[ActionEffects], error) {
+			return s.ClickAt(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[ClickAtArgs]]{Params: &mcp.CallToolParamsFor[ClickAtArgs]{Arguments: ClickAtArgs{ClickCount: 4}}})
+		}, "click_count must be 1, 2, or 3"},
+		{"move steps", func() (*mcp.CallToolResultFor[ActionEffects], error) {
+			return s.MoveMouse(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[MoveMouseArgs]]{Params: &mcp.CallToolParamsFor[MoveMouseArgs]{Arguments: MoveMouseArgs{Steps: 1000}}})
+		}, "steps must be between 1 and 100"},
+		{"pointer", func() (*mcp.CallToolResultFor[ActionEffects], error) {
+			return s.Swipe(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[SwipeArgs]]{Params: &mcp.CallToolParamsFor[SwipeArgs]{Arguments: SwipeArgs{Pointer: "pen"}}})
+		}, `unknown pointer "pen"`},
+		{"duration", func() (*mcp.CallToolResultFor[ActionEffects], error) {
+			return s.Swipe(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[SwipeArgs]]{Params: &mcp.CallToolParamsFor[SwipeArgs]{Arguments: SwipeArgs{DurationMS: 60000}}})
+		}, "duration_ms must be between 0 and 10000"},
+	} {
+		res, err := tc.call()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := res.Content[0].(*mcp.TextContent).Text; !res.IsError || !strings.Contains(text, tc.want) {
+			t.Errorf("%s: result = %q, want an error containing %q", tc.name, text, tc.want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package browserserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// defaultLongPageTiles and maxLongPageTiles bound the tiles
+	// capture_long_page takes.
+	defaultLongPageTiles = 10
+	maxLongPageTiles     = 30
+	// defaultTileOverlap is the height, in CSS pixels, that consecutive
+	// tiles share, so that a line cut by a tile edge is whole in one of them.
+	defaultTileOverlap = 64
+	// tileSettleDelay lets lazily loaded content and scroll handlers catch
+	// up before a tile is captured.
+	tileSettleDelay = 150 * time.Millisecond
+	// maxStitchedHeight bounds the height of a stitched capture in device
+	// pixels; tiles below it are left out.
+	maxStitchedHeight = 32768
+)
+
+// scrollToJS scrolls the page to a vertical offset and returns the previous
+// one.
+const scrollToJS = `function(y) {
+	const previous = window.scrollY;
+	window.scrollTo(window.scrollX, y);
+	return previous;
+}`
+
+// pageTileJS describes the viewport after a scroll: its offset in the page,
+// the sizes of viewport and page, and the interactive elements it shows. It
+// is called with the stable element IDs of the elements listed by
+// collectInteractiveElements, as annotateJS is.
+const pageTileJS = `function(ids) {
+	const elements = [];
+	(window.__cdpbrowserElements || []).forEach((el, i) => {
+		const rect = el.getBoundingClientRect();
+		if (!ids[i] || !el.isConnected || rect.width === 0 || rect.height === 0 ||
+			rect.bottom <= 0 || rect.right <= 0 ||
+			rect.top >= window.innerHeight || rect.left >= window.innerWidth) {
+			return;
+		}
+		elements.push({
+			id: ids[i],
+			selector: getSelector(el),
+			name: getAccessibleName(el),
+			tag: el.tagName.toLowerCase(),
+			x: Math.round(rect.left),
+			y: Math.round(rect.top),
+			width: Math.round(rect.width),
+			height: Math.round(rect.height)
+		});
+	});
+	return {
+		y: Math.round(window.scrollY),
+		viewportWidth: window.innerWidth,
+		viewportHeight: window.innerHeight,
+		pageHeight: Math.max(document.documentElement.scrollHeight, document.body ? document.body.scrollHeight : 0),
+		elements: elements
+	};
+}`
+
+// PageTile is one viewport-sized capture of capture_long_page.
+type PageTile struct {
+	Index    int           `json:"index" jsonschema:"Position of the tile, from 1 at the top of the page"`
+	Y        int           `json:"y" jsonschema:"Offset of the tile's top edge from the top of the page, in CSS pixels"`
+	Height   int           `json:"height" jsonschema:"Height of the tile in CSS pixels"`
+	Elements []ElementMark `json:"elements" jsonschema:"Interactive elements visible in the tile, with their boxes relative to the tile"`
+}
+
+// pageTileInfo is the result of pageTileJS.
+type pageTileInfo struct {
+	Y              int           `json:"y"`
+	ViewportWidth  int           `json:"viewportWidth"`
+	ViewportHeight int           `json:"viewportHeight"`
+	PageHeight     int           `json:"pageHeight"`
+	Elements       []ElementMark `json:"elements"`
+}
+
+type CaptureLongPageArgs struct {
+	MaxTiles int  `json:"max_tiles,omitempty" jsonschema:"Maximum number of tiles to capture, up to 30 (default: 10)"`
+	Overlap  *int `json:"overlap,omitempty" jsonschema:"Height in CSS pixels that consecutive tiles share, less than half the viewport (default: 64)"`
+	Stitch   bool `json:"stitch,omitempty" jsonschema:"Return one stitched image as a browser://screenshots resource instead of the tiles (default: false)"`
+}
+
+// LongPageCapture is the structured result of capture_long_page.
+type LongPageCapture struct {
+	Viewport    string     `json:"viewport" jsonschema:"Viewport size in CSS pixels, e.g. 1280x800"`
+	PageHeight  int        `json:"page_height" jsonschema:"Height of the page in CSS pixels when the last tile was taken"`
+	Tiles       []PageTile `json:"tiles"`
+	Truncated   bool       `json:"truncated,omitempty" jsonschema:"The page continues below the last tile; raise max_tiles or scroll and capture again"`
+	StitchedURI string     `json:"stitched_uri,omitempty" jsonschema:"browser://screenshots resource holding the tiles stitched into one PNG"`
+}
+
+// String formats the tiles and their elements for the text result of
+// capture_long_page.
+func (c *LongPageCapture) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Captured %d tiles of a %dpx page at %s", len(c.Tiles), c.PageHeight, c.Viewport)
+	if c.Truncated && len(c.Tiles) > 0 {
+		last := c.Tiles[len(c.Tiles)-1]
+		fmt.Fprintf(&b, "; the page continues below %dpx", last.Y+last.Height)
+	}
+	if c.StitchedURI != "" {
+		fmt.Fprintf(&b, "\nStitched image: %s", c.StitchedURI)
+	}
+	b.WriteString("\n")
+	for _, tile := range c.Tiles {
+		b.WriteString(tile.String())
+	}
+	return b.String()
+}
+
+// String formats the element index of t, one element per line.
+func (t *PageTile) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Tile %d (y %d-%d): %d elements\n", t.Index, t.Y, t.Y+t.Height, len(t.Elements))
+	for _, m := range t.Elements {
+		name := m.Name
+		if name == "" {
+			name = fmt.Sprintf("<%s>", m.Tag)
+		}
+		fmt.Fprintf(&b, "  %d: %s %q at %d,%d\n", m.ID, m.Selector, name, m.X, m.Y)
+	}
+	return b.String()
+}
+
+// nextTileOffset returns the scroll offset to request for the tile after
+// one at y, or false if the tile at y reaches the bottom of the page.
+func nextTileOffset(y, viewportHeight, pageHeight, overlap int) (int, bool) {
+	if y+viewportHeight >= pageHeight {
+		return 0, false
+	}
+	return y + viewportHeight - overlap, true
+}
+
+// stitchTiles draws the tile images one below the other at their offsets,
+// in CSS pixels, scaled by the device pixels per CSS pixel. Later tiles are
+// drawn over the overlap of earlier ones. Tiles that would make the image
+// taller than maxStitchedHeight are left out; stitchTiles returns how many
+// were drawn.
+func stitchTiles(tiles []image.Image, offsets []int, scale float64) (*image.RGBA, int) {
+	var width, height, n int
+	for i, tile := range tiles {
+		bottom := int(math.Round(float64(offsets[i]-offsets[0])*scale)) + tile.Bounds().Dy()
+		if bottom > maxStitchedHeight && n > 0 {
+			break
+		}
+		width, height, n = max(width, tile.Bounds().Dx()), max(height, bottom), i+1
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i, tile := range tiles[:n] {
+		top := int(math.Round(float64(offsets[i]-offsets[0]) * scale))
+		r := image.Rect(0, top, tile.Bounds().Dx(), top+tile.Bounds().Dy())
+		draw.Draw(img, r, tile, tile.Bounds().Min, draw.Src)
+	}
+	return img, n
+}
+
+// CaptureLongPage tool - scrolls through the page one viewport at a time,
+// capturing each as a tile with the interactive elements it shows, for
+// pages too tall for one full-page screenshot to be legible
+func (s *CDPBrowserServer) CaptureLongPage(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[CaptureLongPageArgs]]) (*mcp.CallToolResultFor[LongPageCapture], error) {
+	args := req.Params.Arguments
+	fail := func(err error) (*mcp.CallToolResultFor[LongPageCapture], error) {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[LongPageCapture]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error capturing the page: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	maxTiles := defaultLongPageTiles
+	if args.MaxTiles > 0 {
+		maxTiles = min(args.MaxTiles, maxLongPageTiles)
+	}
+	overlap := defaultTileOverlap
+	if args.Overlap != nil {
+		if overlap = *args.Overlap; overlap < 0 {
+			return fail(invalidArgumentError{fmt.Errorf("overlap %d is negative", overlap)})
+		}
+	}
+
+	collectJS := "(function() {" + ariaHelpersJS + "return collectInteractiveElements().length; })()"
+	if err := s.run(ctx, chromedp.Evaluate(collectJS, nil)); err != nil {
+		return fail(err)
+	}
+	ids, err := s.interactiveElementIDs(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	idsJSON, _ := json.Marshal(ids)
+	tileJS := fmt.Sprintf("(function() {%s return (%s)(%s); })()", ariaHelpersJS, pageTileJS, idsJSON)
+
+	var result LongPageCapture
+	var pngs [][]byte
+	startY, y, more := 0, 0, true
+	for more && len(result.Tiles) < maxTiles {
+		var previous int
+		var info pageTileInfo
+		var buf []byte
+		err := s.run(ctx,
+			chromedp.Evaluate(fmt.Sprintf("(%s)(%d)", scrollToJS, y), &previous),
+			chromedp.Sleep(tileSettleDelay),
+			chromedp.Evaluate(tileJS, &info),
+			chromedp.CaptureScreenshot(&buf),
+		)
+		if err != nil {
+			return fail(err)
+		}
+		if n := len(result.Tiles); n == 0 {
+			startY = previous
+			if overlap >= info.ViewportHeight/2 {
+				overlap = max(info.ViewportHeight/2-1, 0)
+			}
+			result.Viewport = fmt.Sprintf("%dx%d", info.ViewportWidth, info.ViewportHeight)
+		} else if info.Y <= result.Tiles[n-1].Y {
+			// The page does not scroll any further.
+			break
+		}
+		if info.Elements == nil {
+			info.Elements = []ElementMark{}
+		}
+		result.Tiles = append(result.Tiles, PageTile{Index: len(result.Tiles) + 1, Y: info.Y, Height: info.ViewportHeight, Elements: info.Elements})
+		result.PageHeight = info.PageHeight
+		pngs = append(pngs, buf)
+		y, more = nextTileOffset(info.Y, info.ViewportHeight, info.PageHeight, overlap)
+	}
+	result.Truncated = more
+	if err := s.run(ctx, chromedp.Evaluate(fmt.Sprintf("(%s)(%d)", scrollToJS, startY), nil)); err != nil {
+		log.Printf("CaptureLongPage: failed to restore the scroll position: %v", err)
+	}
+
+	if !args.Stitch {
+		content := []mcp.Content{&mcp.TextContent{Text: result.String()}}
+		for _, buf := range pngs {
+			content = append(content, &mcp.ImageContent{Data: buf, MIMEType: "image/png"})
+		}
+		return &mcp.CallToolResultFor[LongPageCapture]{
+			Content:           content,
+			StructuredContent: result,
+		}, nil
+	}
+
+	images := make([]image.Image, len(pngs))
+	offsets := make([]int, len(pngs))
+	for i, buf := range pngs {
+		if images[i], err = png.Decode(bytes.NewReader(buf)); err != nil {
+			return fail(fmt.Errorf("tile %d is not a PNG: %v", i+1, err))
+		}
+		offsets[i] = result.Tiles[i].Y
+	}
+	if len(images) == 0 {
+		return fail(errors.New("no tiles captured"))
+	}
+	scale := float64(images[0].Bounds().Dy()) / float64(max(result.Tiles[0].Height, 1))
+	stitched, n := stitchTiles(images, offsets, scale)
+	if n < len(images) {
+		result.Tiles, result.Truncated = result.Tiles[:n], true
+	}
+	var out bytes.Buffer
+	if err := png.Encode(&out, stitched); err != nil {
+		return fail(err)
+	}
+	result.StitchedURI = s.screenshots.add(out.Bytes())
+	return &mcp.CallToolResultFor[LongPageCapture]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: result.String()},
+			&mcp.ResourceLink{
+				URI:         result.StitchedURI,
+				Name:        "long page",
+				Description: fmt.Sprintf("%d tiles stitched top to bottom", len(result.Tiles)),
+				MIMEType:    "image/png",
+			},
+		},
+		StructuredContent: result,
+	}, nil
+}
@@ -0,0 +1,78 @@
+package browserserver
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestNextTileOffset(t *testing.T) {
+	tests := []struct {
+		y, viewport, page, overlap int
+		want                       int
+		more                       bool
+	}{
+		{0, 800, 3000, 64, 736, true},
+		{2200, 800, 3000, 64, 0, false},
+		{0, 800, 800, 64, 0, false},
+		{0, 800, 500, 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, more := nextTileOffset(tt.y, tt.viewport, tt.page, tt.overlap)
+		if got != tt.want || more != tt.more {
+			t.Errorf("nextTileOffset(%d, %d, %d, %d) = %d, %v, want %d, %v", tt.y, tt.viewport, tt.page, tt.overlap, got, more, tt.want, tt.more)
+		}
+	}
+}
+
+func TestStitchTiles(t *testing.T) {
+	tile := func(c color.Color, w, h int) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := range h {
+			for x := range w {
+				img.Set(x, y, c)
+			}
+		}
+		return img
+	}
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	// Device pixels are twice CSS pixels; the tiles overlap by 2 CSS pixels.
+	img, n := stitchTiles([]image.Image{tile(red, 4, 10), tile(blue, 4, 10)}, []int{100, 104}, 2)
+	if n != 2 || img.Bounds().Dx() != 4 || img.Bounds().Dy() != 18 {
+		t.Fatalf("stitchTiles = %v, %d tiles, want 4x18 from 2 tiles", img.Bounds(), n)
+	}
+	if got := img.RGBAAt(0, 7); got != red {
+		t.Errorf("pixel above the overlap = %v, want red", got)
+	}
+	if got := img.RGBAAt(0, 8); got != blue {
+		t.Errorf("pixel in the overlap = %v, want blue from the later tile", got)
+	}
+
+	_, n = stitchTiles([]image.Image{tile(red, 1, maxStitchedHeight), tile(blue, 1, 10)}, []int{0, maxStitchedHeight}, 1)
+	if n != 1 {
+		t.Errorf("stitchTiles past maxStitchedHeight drew %d tiles, want 1", n)
+	}
+}
+
+func TestLongPageCaptureString(t *testing.T) {
+	c := LongPageCapture{
+		Viewport:   "1280x800",
+		PageHeight: 5000,
+		Truncated:  true,
+		Tiles: []PageTile{
+			{Index: 1, Y: 0, Height: 800, Elements: []ElementMark{{ID: 3, Selector: "#login", Name: "Log in", Tag: "button", X: 10, Y: 20}}},
+			{Index: 2, Y: 736, Height: 800, Elements: []ElementMark{{ID: 9, Selector: "a.more", Tag: "a", X: 0, Y: 700}}},
+		},
+	}
+	text := c.String()
+	for _, want := range []string{
+		"Captured 2 tiles of a 5000px page at 1280x800; the page continues below 1536px",
+		"Tile 1 (y 0-800): 1 elements\n  3: #login \"Log in\" at 10,20\n",
+		"Tile 2 (y 736-1536): 1 elements\n  9: a.more \"<a>\" at 0,700\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("String() = %q, want it to contain %q", text, want)
+		}
+	}
+}
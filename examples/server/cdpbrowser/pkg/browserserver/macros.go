@@ -0,0 +1,248 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// macroTools cannot be used as macro or workflow steps, which rules out
+// recursion.
+var macroTools = map[string]bool{
+	"define_macro": true,
+	"run_macro":    true,
+	"batch":        true,
+	"run_workflow": true,
+}
+
+// placeholderPattern matches a "{{param}}" placeholder in a macro step
+// argument.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// MacroStep is one tool call in a macro. String values in Arguments may
+// contain "{{param}}" placeholders.
+type MacroStep struct {
+	Tool      string         `json:"tool" jsonschema:"Name of the tool to call"`
+	Arguments map[string]any `json:"arguments,omitempty" jsonschema:"Tool arguments; string values may contain {{param}} placeholders"`
+}
+
+// macro is a named, parameterized sequence of tool calls.
+type macro struct {
+	Name        string
+	Description string
+	Params      []string
+	Steps       []MacroStep
+}
+
+// signature returns the macro's call signature, such as "login(username, password)".
+func (m *macro) signature() string {
+	return fmt.Sprintf("%s(%s)", m.Name, strings.Join(m.Params, ", "))
+}
+
+// macroRegistry holds the macros defined in this server.
+type macroRegistry struct {
+	mu     sync.Mutex
+	macros map[string]*macro
+}
+
+func (r *macroRegistry) set(m *macro) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.macros == nil {
+		r.macros = make(map[string]*macro)
+	}
+	r.macros[m.Name] = m
+}
+
+func (r *macroRegistry) get(name string) (*macro, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.macros[name]
+	return m, ok
+}
+
+// signatures returns the signatures of all defined macros, sorted.
+func (r *macroRegistry) signatures() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sigs []string
+	for _, m := range r.macros {
+		sigs = append(sigs, m.signature())
+	}
+	slices.Sort(sigs)
+	return sigs
+}
+
+// validateMacro checks that every step calls a known, non-macro tool and that
+// every placeholder names a declared parameter.
+func (s *CDPBrowserServer) validateMacro(m *macro) error {
+	if m.Name == "" {
+		return fmt.Errorf("macro name is required")
+	}
+	if len(m.Steps) == 0 {
+		return fmt.Errorf("macro %s has no steps", m.Name)
+	}
+	for i, step := range m.Steps {
+		if macroTools[step.Tool] {
+			return fmt.Errorf("step %d: %s cannot be called from a macro", i+1, step.Tool)
+		}
+		if !s.hasTool(step.Tool) {
+			return fmt.Errorf("step %d: unknown tool %s", i+1, step.Tool)
+		}
+		var err error
+		walkStrings(step.Arguments, func(v string) any {
+			for _, match := range placeholderPattern.FindAllStringSubmatch(v, -1) {
+				if !slices.Contains(m.Params, match[1]) && err == nil {
+					err = fmt.Errorf("step %d: placeholder {{%s}} is not a declared parameter", i+1, match[1])
+				}
+			}
+			return v
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkStrings returns a copy of v with every string value replaced by f(s).
+func walkStrings(v any, f func(string) any) any {
+	switch v := v.(type) {
+	case string:
+		return f(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = walkStrings(e, f)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = walkStrings(e, f)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// expandArguments substitutes params into a step's argument placeholders. A
+// string that is exactly one placeholder takes the parameter value as is, so
+// non-string parameters such as booleans keep their type.
+func expandArguments(args map[string]any, params map[string]any) (json.RawMessage, error) {
+	expanded := walkStrings(args, func(v string) any {
+		if m := placeholderPattern.FindStringSubmatch(v); m != nil && m[0] == v {
+			return params[m[1]]
+		}
+		return placeholderPattern.ReplaceAllStringFunc(v, func(p string) string {
+			return fmt.Sprint(params[placeholderPattern.FindStringSubmatch(p)[1]])
+		})
+	})
+	return json.Marshal(expanded)
+}
+
+// MacroResult is the structured result of run_macro.
+type MacroResult struct {
+	Macro  string       `json:"macro"`
+	Passed bool         `json:"passed"`
+	Steps  []StepResult `json:"steps"`
+}
+
+type DefineMacroArgs struct {
+	Name        string      `json:"name" jsonschema:"Name of the macro"`
+	Description string      `json:"description,omitempty" jsonschema:"What the macro does"`
+	Params      []string    `json:"params,omitempty" jsonschema:"Parameter names, referenced in step arguments as {{name}}"`
+	Steps       []MacroStep `json:"steps" jsonschema:"Ordered tool calls the macro performs"`
+}
+
+type RunMacroArgs struct {
+	Name      string         `json:"name" jsonschema:"Name of the macro to run"`
+	Arguments map[string]any `json:"arguments,omitempty" jsonschema:"Values for the macro's parameters"`
+}
+
+// DefineMacro tool - registers a named sequence of tool calls
+func (s *CDPBrowserServer) DefineMacro(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[DefineMacroArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+	args := req.Params.Arguments
+	m := &macro{Name: args.Name, Description: args.Description, Params: args.Params, Steps: args.Steps}
+	if err := s.validateMacro(m); err != nil {
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error defining macro: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	s.macros.set(m)
+	log.Printf("Defined macro %s with %d steps", m.signature(), len(m.Steps))
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Defined macro %s with %d steps", m.signature(), len(m.Steps))},
+		},
+	}, nil
+}
+
+// RunMacro tool - runs a defined macro server-side, stopping at the first
+// failing step
+func (s *CDPBrowserServer) RunMacro(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[RunMacroArgs]]) (*mcp.CallToolResultFor[MacroResult], error) {
+	args := req.Params.Arguments
+	m, ok := s.macros.get(args.Name)
+	if !ok {
+		defined := "none"
+		if sigs := s.macros.signatures(); len(sigs) > 0 {
+			defined = strings.Join(sigs, ", ")
+		}
+		return &mcp.CallToolResultFor[MacroResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown macro %s (defined macros: %s)", args.Name, defined)},
+			},
+			IsError: true,
+		}, nil
+	}
+	for _, p := range m.Params {
+		if _, ok := args.Arguments[p]; !ok {
+			return &mcp.CallToolResultFor[MacroResult]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Missing argument %s for macro %s", p, m.signature())},
+				},
+				IsError: true,
+			}, nil
+		}
+	}
+
+	result := MacroResult{Macro: m.Name, Passed: true, Steps: []StepResult{}}
+	var output strings.Builder
+	for i, step := range m.Steps {
+		var sr StepResult
+		if stepArgs, err := expandArguments(step.Arguments, args.Arguments); err != nil {
+			sr = StepResult{Step: i + 1, Tool: step.Tool, Message: fmt.Sprintf("invalid arguments: %v", err)}
+		} else {
+			sr = s.invokeStep(ctx, i+1, step.Tool, stepArgs)
+		}
+		result.Steps = append(result.Steps, sr)
+		output.WriteString(fmt.Sprintf("%d. %s: %s\n", sr.Step, sr.Tool, sr.Message))
+		if !sr.OK {
+			result.Passed = false
+			break
+		}
+	}
+
+	status := "completed"
+	if !result.Passed {
+		status = fmt.Sprintf("failed at step %d of %d", len(result.Steps), len(m.Steps))
+	}
+	return &mcp.CallToolResultFor[MacroResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Macro %s %s\n%s", m.signature(), status, output.String())},
+		},
+		StructuredContent: result,
+		IsError:           !result.Passed,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestExpandArguments(t *testing.T) {
+	args := map[string]any{
+		"selector": "#user-{{ id }}",
+		"text":     "{{username}}",
+		"checked":  "{{remember}}",
+		"nested":   []any{"{{username}}!", 3},
+	}
+	params := map[string]any{"id": 7, "username": "alice", "remember": true}
+	got, err := expandArguments(args, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"checked":true,"nested":["alice!",3],"selector":"#user-7","text":"alice"}`
+	if string(got) != want {
+		t.Errorf("expandArguments() = %s, want %s", got, want)
+	}
+}
+
+// newMacroTestServer returns a server whose type_text and click_button tools
+// record their calls in *calls.
+func newMacroTestServer(t *testing.T, calls *[]string) *CDPBrowserServer {
+	t.Helper()
+	s := &CDPBrowserServer{}
+	srv := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	addTool(srv, s, &mcp.Tool{Name: "type_text"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[TypeTextArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+		*calls = append(*calls, req.Params.Arguments.Selector+"="+req.Params.Arguments.Text)
+		return &mcp.CallToolResultFor[struct{}]{}, nil
+	})
+	addTool(srv, s, &mcp.Tool{Name: "click_button"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickButtonArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+		*calls = append(*calls, "click "+req.Params.Arguments.Selector)
+		return &mcp.CallToolResultFor[struct{}]{IsError: req.Params.Arguments.Selector == "#broken"}, nil
+	})
+	addTool(srv, s, &mcp.Tool{Name: "run_macro"}, s.RunMacro)
+	return s
+}
+
+func defineMacro(t *testing.T, s *CDPBrowserServer, args DefineMacroArgs) *mcp.CallToolResultFor[struct{}] {
+	t.Helper()
+	res, err := s.DefineMacro(context.Background(), &mcp.ServerRequest[*mcp.CallToolParamsFor[DefineMacroArgs]]{
+		Params: &mcp.CallToolParamsFor[DefineMacroArgs]{Arguments: args},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func runMacro(t *testing.T, s *CDPBrowserServer, name string, args map[string]any) *mcp.CallToolResultFor[MacroResult] {
+	t.Helper()
+	res, err := s.RunMacro(context.Background(), &mcp.ServerRequest[*mcp.CallToolParamsFor[RunMacroArgs]]{
+		Params: &mcp.CallToolParamsFor[RunMacroArgs]{Arguments: RunMacroArgs{Name: name, Arguments: args}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestMacros(t *testing.T) {
+	var calls []string
+	s := newMacroTestServer(t, &calls)
+
+	login := DefineMacroArgs{
+		Name:   "login",
+		Params: []string{"username", "password"},
+		Steps: []MacroStep{
+			{Tool: "type_text", Arguments: map[string]any{"selector": "#user", "text": "{{username}}"}},
+			{Tool: "type_text", Arguments: map[string]any{"selector": "#pass", "text": "{{password}}"}},
+			{Tool: "click_button", Arguments: map[string]any{"selector": "Sign in"}},
+		},
+	}
+	if res := defineMacro(t, s, login); res.IsError {
+		t.Fatalf("define_macro failed: %v", res.Content)
+	}
+
+	res := runMacro(t, s, "login", map[string]any{"username": "alice", "password": "secret"})
+	if res.IsError || !res.StructuredContent.Passed {
+		t.Fatalf("run_macro failed: %+v", res.StructuredContent)
+	}
+	if got, want := strings.Join(calls, ";"), "#user=alice;#pass=secret;click Sign in"; got != want {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+
+	if res := runMacro(t, s, "login", map[string]any{"username": "alice"}); !res.IsError {
+		t.Error("run_macro with a missing argument succeeded")
+	}
+	if res := runMacro(t, s, "logout", nil); !res.IsError {
+		t.Error("run_macro of an undefined macro succeeded")
+	}
+}
+
+func TestMacroStopsAtFailure(t *testing.T) {
+	var calls []string
+	s := newMacroTestServer(t, &calls)
+	defineMacro(t, s, DefineMacroArgs{
+		Name: "broken",
+		Steps: []MacroStep{
+			{Tool: "click_button", Arguments: map[string]any{"selector": "#broken"}},
+			{Tool: "click_button", Arguments: map[string]any{"selector": "#never"}},
+		},
+	})
+	res := runMacro(t, s, "broken", nil)
+	if !res.IsError || len(res.StructuredContent.Steps) != 1 || len(calls) != 1 {
+		data, _ := json.Marshal(res.StructuredContent)
+		t.Errorf("run_macro = %s after calls %q, want failure at step 1", data, calls)
+	}
+}
+
+func TestDefineMacroValidation(t *testing.T) {
+	var calls []string
+	s := newMacroTestServer(t, &calls)
+	for _, tc := range []struct {
+		name string
+		args DefineMacroArgs
+		want string
+	}{
+		{"no steps", DefineMacroArgs{Name: "m"}, "no steps"},
+		{"unknown tool", DefineMacroArgs{Name: "m", Steps: []MacroStep{{Tool: "fly"}}}, "unknown tool"},
+		{"recursive", DefineMacroArgs{Name: "m", Steps: []MacroStep{{Tool: "run_macro"}}}, "cannot be called"},
+		{"undeclared placeholder", DefineMacroArgs{
+			Name:  "m",
+			Steps: []MacroStep{{Tool: "type_text", Arguments: map[string]any{"text": "{{who}}"}}},
+		}, "{{who}}"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res := defineMacro(t, s, tc.args)
+			if !res.IsError {
+				t.Fatal("define_macro succeeded, want error")
+			}
+			if text := res.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, tc.want) {
+				t.Errorf("error = %q, want it to contain %q", text, tc.want)
+			}
+		})
+	}
+}
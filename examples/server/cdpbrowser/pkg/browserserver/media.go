@@ -0,0 +1,99 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Values accepted by emulate_media. An empty value removes the override.
+var (
+	emulatedMediaTypes    = []string{"screen", "print"}
+	emulatedColorSchemes  = []string{"light", "dark"}
+	emulatedReducedMotion = []string{"reduce", "no-preference"}
+)
+
+// mediaStateJS reports which media the page currently matches.
+const mediaStateJS = `({
+	media: matchMedia('print').matches ? 'print' : 'screen',
+	colorScheme: matchMedia('(prefers-color-scheme: dark)').matches ? 'dark' : 'light',
+	reducedMotion: matchMedia('(prefers-reduced-motion: reduce)').matches ? 'reduce' : 'no-preference'
+})`
+
+type EmulateMediaArgs struct {
+	Media         string `json:"media,omitempty" jsonschema:"CSS media type: screen or print (default: no override)"`
+	ColorScheme   string `json:"color_scheme,omitempty" jsonschema:"prefers-color-scheme: light or dark (default: no override)"`
+	ReducedMotion string `json:"reduced_motion,omitempty" jsonschema:"prefers-reduced-motion: reduce or no-preference (default: no override)"`
+}
+
+// MediaState is the structured result of emulate_media: the media the page
+// matches after the call.
+type MediaState struct {
+	Media         string `json:"media"`
+	ColorScheme   string `json:"color_scheme"`
+	ReducedMotion string `json:"reduced_motion"`
+}
+
+// checkMediaValue returns an error if value is neither empty nor one of
+// allowed.
+func checkMediaValue(name, value string, allowed []string) error {
+	if value == "" || slices.Contains(allowed, value) {
+		return nil
+	}
+	return invalidArgumentError{fmt.Errorf("invalid %s %q: must be %s", name, value, strings.Join(allowed, " or "))}
+}
+
+// features returns the media features to emulate for args.
+func (args *EmulateMediaArgs) features() []*emulation.MediaFeature {
+	var features []*emulation.MediaFeature
+	if args.ColorScheme != "" {
+		features = append(features, &emulation.MediaFeature{Name: "prefers-color-scheme", Value: args.ColorScheme})
+	}
+	if args.ReducedMotion != "" {
+		features = append(features, &emulation.MediaFeature{Name: "prefers-reduced-motion", Value: args.ReducedMotion})
+	}
+	return features
+}
+
+// EmulateMedia tool - emulates a media type and user preference media
+// features, replacing any earlier emulation
+func (s *CDPBrowserServer) EmulateMedia(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[EmulateMediaArgs]]) (*mcp.CallToolResultFor[MediaState], error) {
+	args := req.Params.Arguments
+	err := checkMediaValue("media", args.Media, emulatedMediaTypes)
+	if err == nil {
+		err = checkMediaValue("color_scheme", args.ColorScheme, emulatedColorSchemes)
+	}
+	if err == nil {
+		err = checkMediaValue("reduced_motion", args.ReducedMotion, emulatedReducedMotion)
+	}
+	var state MediaState
+	if err == nil {
+		// Each call replaces the whole emulation, so features left out are
+		// reset to the browser's own values.
+		err = s.run(ctx,
+			emulation.SetEmulatedMedia().WithMedia(args.Media).WithFeatures(args.features()),
+			chromedp.Evaluate(mediaStateJS, &state),
+		)
+	}
+	if err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[MediaState]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error emulating media: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResultFor[MediaState]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Page now matches media %s, prefers-color-scheme: %s, prefers-reduced-motion: %s", state.Media, state.ColorScheme, state.ReducedMotion)},
+		},
+		StructuredContent: state,
+	}, nil
+}
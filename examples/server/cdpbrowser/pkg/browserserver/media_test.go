@@ -0,0 +1,26 @@
+package browserserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmulateMediaArgs(t *testing.T) {
+	for _, value := range []string{"", "dark", "light"} {
+		if err := checkMediaValue("color_scheme", value, emulatedColorSchemes); err != nil {
+			t.Errorf("checkMediaValue(%q) = %v", value, err)
+		}
+	}
+	err := checkMediaValue("color_scheme", "Dark", emulatedColorSchemes)
+	if err == nil || !strings.Contains(err.Error(), `invalid color_scheme "Dark": must be light or dark`) {
+		t.Errorf("checkMediaValue(Dark) = %v", err)
+	}
+
+	if f := (&EmulateMediaArgs{Media: "print"}).features(); f != nil {
+		t.Errorf("features without preferences = %v, want none", f)
+	}
+	f := (&EmulateMediaArgs{ColorScheme: "dark", ReducedMotion: "reduce"}).features()
+	if len(f) != 2 || f[0].Name != "prefers-color-scheme" || f[0].Value != "dark" || f[1].Name != "prefers-reduced-motion" || f[1].Value != "reduce" {
+		t.Errorf("features = %+v", f)
+	}
+}
@@ -0,0 +1,234 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// pageMetadataJS collects the metadata tags of the page. Link URLs are
+// resolved against the document. JSON-LD blocks that do not parse are
+// returned as errors rather than failing the whole call.
+const pageMetadataJS = `
+(function() {
+	const meta = [];
+	for (const el of document.querySelectorAll('meta[content]')) {
+		const name = el.getAttribute('property') || el.getAttribute('name');
+		if (name) {
+			meta.push({ name: name, content: el.getAttribute('content') });
+		}
+	}
+	const links = (rel) => Array.from(document.querySelectorAll('link[href]'))
+		.filter(l => l.rel.split(/\s+/).some(r => r.toLowerCase() === rel));
+	const structured = [];
+	const structuredErrors = [];
+	document.querySelectorAll('script[type="application/ld+json"]').forEach((script, i) => {
+		try {
+			structured.push(JSON.parse(script.textContent));
+		} catch (e) {
+			structuredErrors.push('JSON-LD block ' + (i + 1) + ': ' + e.message);
+		}
+	});
+	return {
+		url: location.href,
+		title: document.title,
+		lang: document.documentElement.lang || '',
+		meta: meta,
+		canonicals: links('canonical').map(l => l.href),
+		alternates: links('alternate').filter(l => l.hreflang).map(l => ({ hreflang: l.hreflang, url: l.href })),
+		structured: structured,
+		structuredErrors: structuredErrors
+	};
+})()
+`
+
+// MetaTag is a name/content pair from a <meta> tag.
+type MetaTag struct {
+	Name    string `json:"name" jsonschema:"Property or name attribute, e.g. og:title or twitter:card"`
+	Content string `json:"content"`
+}
+
+// HreflangLink is an alternate language version of the page.
+type HreflangLink struct {
+	Hreflang string `json:"hreflang" jsonschema:"Language code, or x-default"`
+	URL      string `json:"url"`
+}
+
+// PageMetadata is the structured result of get_page_metadata.
+type PageMetadata struct {
+	URL            string            `json:"url"`
+	Title          string            `json:"title"`
+	Description    string            `json:"description,omitempty" jsonschema:"Content of the description meta tag"`
+	Canonical      string            `json:"canonical,omitempty" jsonschema:"Canonical URL, resolved against the page URL"`
+	Lang           string            `json:"lang,omitempty" jsonschema:"Language declared on the html element"`
+	Robots         map[string]string `json:"robots,omitempty" jsonschema:"Robots directives by meta name: robots and crawler-specific names such as googlebot"`
+	OpenGraph      []MetaTag         `json:"open_graph,omitempty" jsonschema:"Open Graph (og:*) tags in document order"`
+	Twitter        []MetaTag         `json:"twitter,omitempty" jsonschema:"Twitter card (twitter:*) tags in document order"`
+	Hreflang       []HreflangLink    `json:"hreflang,omitempty" jsonschema:"Alternate language versions of the page"`
+	StructuredData []any             `json:"structured_data,omitempty" jsonschema:"Parsed JSON-LD blocks"`
+	Warnings       []string          `json:"warnings,omitempty" jsonschema:"Common SEO problems found in the metadata"`
+}
+
+// robotsMetaNames are the meta names that carry robots directives, in the
+// order they are reported.
+var robotsMetaNames = []string{"robots", "googlebot", "googlebot-news", "bingbot"}
+
+// rawPageMetadata is what pageMetadataJS returns.
+type rawPageMetadata struct {
+	URL              string         `json:"url"`
+	Title            string         `json:"title"`
+	Lang             string         `json:"lang"`
+	Meta             []MetaTag      `json:"meta"`
+	Canonicals       []string       `json:"canonicals"`
+	Alternates       []HreflangLink `json:"alternates"`
+	Structured       []any          `json:"structured"`
+	StructuredErrors []string       `json:"structuredErrors"`
+}
+
+// pageMetadata sorts the collected tags into a PageMetadata and notes
+// missing, duplicate, and invalid metadata.
+func (raw *rawPageMetadata) pageMetadata() *PageMetadata {
+	m := &PageMetadata{
+		URL:            raw.URL,
+		Title:          strings.TrimSpace(raw.Title),
+		Lang:           raw.Lang,
+		Hreflang:       raw.Alternates,
+		StructuredData: raw.Structured,
+	}
+	descriptions := 0
+	for _, tag := range raw.Meta {
+		name := strings.ToLower(tag.Name)
+		switch {
+		case name == "description":
+			if descriptions == 0 {
+				m.Description = strings.TrimSpace(tag.Content)
+			}
+			descriptions++
+		case slices.Contains(robotsMetaNames, name):
+			if m.Robots == nil {
+				m.Robots = make(map[string]string)
+			}
+			if prev := m.Robots[name]; prev != "" {
+				tag.Content = prev + ", " + tag.Content
+			}
+			m.Robots[name] = tag.Content
+		case strings.HasPrefix(name, "og:"):
+			m.OpenGraph = append(m.OpenGraph, tag)
+		case strings.HasPrefix(name, "twitter:"):
+			m.Twitter = append(m.Twitter, tag)
+		}
+	}
+	if len(raw.Canonicals) > 0 {
+		m.Canonical = raw.Canonicals[0]
+	}
+
+	if m.Title == "" {
+		m.Warnings = append(m.Warnings, "page has no title")
+	}
+	if m.Description == "" {
+		m.Warnings = append(m.Warnings, "page has no meta description")
+	}
+	if descriptions > 1 {
+		m.Warnings = append(m.Warnings, fmt.Sprintf("page has %d meta descriptions", descriptions))
+	}
+	if len(raw.Canonicals) > 1 {
+		m.Warnings = append(m.Warnings, fmt.Sprintf("page has %d canonical links", len(raw.Canonicals)))
+	}
+	for _, directive := range strings.Split(strings.ToLower(m.Robots["robots"]), ",") {
+		if d := strings.TrimSpace(directive); d == "noindex" || d == "none" {
+			m.Warnings = append(m.Warnings, "page is excluded from indexing by its robots meta tag")
+			break
+		}
+	}
+	m.Warnings = append(m.Warnings, raw.StructuredErrors...)
+	return m
+}
+
+// structuredDataTypes returns the @type of each JSON-LD block, looking into
+// @graph arrays.
+func structuredDataTypes(data []any) []string {
+	var types []string
+	var visit func(v any)
+	visit = func(v any) {
+		switch v := v.(type) {
+		case []any:
+			for _, e := range v {
+				visit(e)
+			}
+		case map[string]any:
+			switch t := v["@type"].(type) {
+			case string:
+				types = append(types, t)
+			case []any:
+				for _, e := range t {
+					if s, ok := e.(string); ok {
+						types = append(types, s)
+					}
+				}
+			}
+			if graph, ok := v["@graph"]; ok {
+				visit(graph)
+			}
+		}
+	}
+	visit(data)
+	return types
+}
+
+// String formats m for the text result of get_page_metadata.
+func (m *PageMetadata) String() string {
+	var output strings.Builder
+	line := func(label, value string) {
+		if value != "" {
+			output.WriteString(fmt.Sprintf("%s: %s\n", label, value))
+		}
+	}
+	line("URL", m.URL)
+	line("Title", m.Title)
+	line("Description", m.Description)
+	line("Canonical", m.Canonical)
+	line("Language", m.Lang)
+	for _, name := range robotsMetaNames {
+		line("Robots ("+name+")", m.Robots[name])
+	}
+	for _, tag := range m.OpenGraph {
+		line(tag.Name, tag.Content)
+	}
+	for _, tag := range m.Twitter {
+		line(tag.Name, tag.Content)
+	}
+	for _, alt := range m.Hreflang {
+		line("hreflang "+alt.Hreflang, alt.URL)
+	}
+	if len(m.StructuredData) > 0 {
+		line(fmt.Sprintf("Structured data (%d JSON-LD blocks)", len(m.StructuredData)), strings.Join(structuredDataTypes(m.StructuredData), ", "))
+	}
+	for _, w := range m.Warnings {
+		line("Warning", w)
+	}
+	return output.String()
+}
+
+// GetPageMetadata tool - reports the SEO-relevant metadata of the page
+func (s *CDPBrowserServer) GetPageMetadata(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[PageMetadata], error) {
+	var raw rawPageMetadata
+	if err := s.backend().Evaluate(ctx, pageMetadataJS, &raw); err != nil {
+		return &mcp.CallToolResultFor[PageMetadata]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error reading page metadata: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	m := raw.pageMetadata()
+	return &mcp.CallToolResultFor[PageMetadata]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: m.String()},
+		},
+		StructuredContent: *m,
+	}, nil
+}
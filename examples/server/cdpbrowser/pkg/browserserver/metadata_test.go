@@ -0,0 +1,65 @@
+package browserserver
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPageMetadata(t *testing.T) {
+	raw := rawPageMetadata{
+		URL:   "https://example.com/shoes",
+		Title: " Shoes ",
+		Meta: []MetaTag{
+			{Name: "description", Content: "All the shoes"},
+			{Name: "Description", Content: "Again"},
+			{Name: "robots", Content: "index"},
+			{Name: "robots", Content: "NoIndex"},
+			{Name: "og:title", Content: "Shoes"},
+			{Name: "og:image", Content: "https://example.com/a.png"},
+			{Name: "og:image", Content: "https://example.com/b.png"},
+			{Name: "twitter:card", Content: "summary"},
+			{Name: "viewport", Content: "width=device-width"},
+		},
+		Canonicals:       []string{"https://example.com/shoes", "https://example.com/shoes?x"},
+		Alternates:       []HreflangLink{{Hreflang: "de", URL: "https://example.de/schuhe"}},
+		StructuredErrors: []string{"JSON-LD block 2: Unexpected token"},
+	}
+	m := raw.pageMetadata()
+	if m.Title != "Shoes" || m.Description != "All the shoes" || m.Canonical != "https://example.com/shoes" {
+		t.Errorf("title, description, canonical = %q, %q, %q", m.Title, m.Description, m.Canonical)
+	}
+	if got := m.Robots["robots"]; got != "index, NoIndex" {
+		t.Errorf("robots = %q", got)
+	}
+	if len(m.OpenGraph) != 3 || len(m.Twitter) != 1 {
+		t.Errorf("open graph, twitter = %v, %v", m.OpenGraph, m.Twitter)
+	}
+	want := []string{
+		"page has 2 meta descriptions",
+		"page has 2 canonical links",
+		"page is excluded from indexing by its robots meta tag",
+		"JSON-LD block 2: Unexpected token",
+	}
+	if !slices.Equal(m.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", m.Warnings, want)
+	}
+
+	empty := (&rawPageMetadata{URL: "about:blank"}).pageMetadata()
+	if !slices.Equal(empty.Warnings, []string{"page has no title", "page has no meta description"}) {
+		t.Errorf("warnings of an empty page = %q", empty.Warnings)
+	}
+}
+
+func TestStructuredDataTypes(t *testing.T) {
+	var data []any
+	blocks := `[{"@type": "Product"}, {"@graph": [{"@type": ["Organization", "Brand"]}, {"@type": "WebSite"}]}, [{"@type": "BreadcrumbList"}]]`
+	if err := json.Unmarshal([]byte(blocks), &data); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(structuredDataTypes(data), ",")
+	if want := "Product,Organization,Brand,WebSite,BreadcrumbList"; got != want {
+		t.Errorf("structuredDataTypes() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,352 @@
+package browserserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// An OCREngine recognizes the text in images for ocr_screenshot, selected
+// with -ocr-engine. Programs embedding the server can add engines, for
+// example one built on gosseract or a cloud vision API, with
+// [RegisterOCREngine].
+type OCREngine interface {
+	// Recognize returns the words in a PNG image, with their bounding boxes
+	// in image pixels. languages are the languages to recognize, as
+	// Tesseract language codes such as eng, or nil for the engine's default.
+	Recognize(ctx context.Context, img []byte, languages []string) ([]OCRWord, error)
+}
+
+// OCRWord is a word recognized by an OCREngine.
+type OCRWord struct {
+	Text       string  `json:"text"`
+	X          int     `json:"x"`
+	Y          int     `json:"y"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Confidence float64 `json:"confidence" jsonschema:"How sure the engine is of the word, from 0 to 100"`
+	// Line identifies the line of the word: words with the same Line are on
+	// the same line. Lines are numbered in reading order.
+	Line int `json:"line"`
+}
+
+// defaultOCREngine is the engine used when -ocr-engine is not given.
+const defaultOCREngine = "tesseract"
+
+var (
+	ocrEnginesMu sync.RWMutex
+	ocrEngines   = map[string]OCREngine{
+		"tesseract": tesseractEngine{command: "tesseract"},
+	}
+)
+
+// RegisterOCREngine makes an OCR engine available under name, replacing any
+// engine previously registered with that name.
+func RegisterOCREngine(name string, e OCREngine) {
+	ocrEnginesMu.Lock()
+	defer ocrEnginesMu.Unlock()
+	ocrEngines[name] = e
+}
+
+// lookupOCREngine returns the engine registered under name.
+func lookupOCREngine(name string) (OCREngine, bool) {
+	ocrEnginesMu.RLock()
+	defer ocrEnginesMu.RUnlock()
+	e, ok := ocrEngines[name]
+	return e, ok
+}
+
+// ocrEngineNames returns the sorted names of all registered engines.
+func ocrEngineNames() []string {
+	ocrEnginesMu.RLock()
+	defer ocrEnginesMu.RUnlock()
+	names := make([]string, 0, len(ocrEngines))
+	for name := range ocrEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tesseractEngine runs the Tesseract command-line program.
+type tesseractEngine struct {
+	command string
+}
+
+func (e tesseractEngine) Recognize(ctx context.Context, img []byte, languages []string) ([]OCRWord, error) {
+	args := []string{"stdin", "stdout"}
+	if len(languages) > 0 {
+		args = append(args, "-l", strings.Join(languages, "+"))
+	}
+	cmd := exec.CommandContext(ctx, e.command, append(args, "tsv")...)
+	cmd.Stdin = bytes.NewReader(img)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("the tesseract OCR engine needs the %s command; install Tesseract or select another engine with -ocr-engine", e.command)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", e.command, err, strings.TrimSpace(stderr.String()))
+	}
+	return parseTesseractTSV(out)
+}
+
+// parseTesseractTSV parses the words out of Tesseract's TSV output.
+func parseTesseractTSV(tsv []byte) ([]OCRWord, error) {
+	// The columns are level, page_num, block_num, par_num, line_num,
+	// word_num, left, top, width, height, conf, and text. Words are level 5.
+	const wordLevel = "5"
+	var words []OCRWord
+	lines := make(map[string]int)
+	sc := bufio.NewScanner(bytes.NewReader(tsv))
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.SplitN(sc.Text(), "\t", 12)
+		if n == 1 || len(fields) < 12 || fields[0] != wordLevel {
+			continue
+		}
+		text := strings.TrimSpace(fields[11])
+		if text == "" {
+			continue
+		}
+		var box [4]int
+		for i := range box {
+			v, err := strconv.Atoi(fields[6+i])
+			if err != nil {
+				return nil, fmt.Errorf("line %d of the Tesseract output: invalid box %q", n, fields[6+i])
+			}
+			box[i] = v
+		}
+		conf, err := strconv.ParseFloat(fields[10], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d of the Tesseract output: invalid confidence %q", n, fields[10])
+		}
+		key := strings.Join(fields[1:5], "/")
+		line, ok := lines[key]
+		if !ok {
+			line = len(lines)
+			lines[key] = line
+		}
+		words = append(words, OCRWord{
+			Text:       text,
+			X:          box[0],
+			Y:          box[1],
+			Width:      box[2],
+			Height:     box[3],
+			Confidence: conf,
+			Line:       line,
+		})
+	}
+	return words, sc.Err()
+}
+
+// OCRLine is a line of text recognized by ocr_screenshot.
+type OCRLine struct {
+	Text       string  `json:"text"`
+	X          int     `json:"x"`
+	Y          int     `json:"y"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Confidence float64 `json:"confidence" jsonschema:"Lowest confidence of the words of the line, from 0 to 100"`
+}
+
+// ocrLines joins words into lines, in the order of their Line.
+func ocrLines(words []OCRWord) []OCRLine {
+	byLine := make(map[int]*OCRLine)
+	var ids []int
+	for _, w := range words {
+		l, ok := byLine[w.Line]
+		if !ok {
+			byLine[w.Line] = &OCRLine{Text: w.Text, X: w.X, Y: w.Y, Width: w.Width, Height: w.Height, Confidence: w.Confidence}
+			ids = append(ids, w.Line)
+			continue
+		}
+		right, bottom := max(l.X+l.Width, w.X+w.Width), max(l.Y+l.Height, w.Y+w.Height)
+		l.X, l.Y = min(l.X, w.X), min(l.Y, w.Y)
+		l.Width, l.Height = right-l.X, bottom-l.Y
+		l.Text += " " + w.Text
+		l.Confidence = min(l.Confidence, w.Confidence)
+	}
+	sort.Ints(ids)
+	lines := make([]OCRLine, len(ids))
+	for i, id := range ids {
+		lines[i] = *byLine[id]
+	}
+	return lines
+}
+
+// ocrRegionJS returns the device pixel ratio and, given a CSS or XPath
+// query, the viewport rectangle of the element it selects after scrolling it
+// into view. found is false if the query matches nothing.
+const ocrRegionJS = `function(query, xpath) {
+	const out = {dpr: window.devicePixelRatio || 1, found: true};
+	if (!query) {
+		return out;
+	}
+	const el = (` + findInPageJS + `)(query, xpath);
+	if (!el) {
+		out.found = false;
+		return out;
+	}
+	el.scrollIntoView({block: 'nearest', inline: 'nearest'});
+	const r = el.getBoundingClientRect();
+	return Object.assign(out, {x: r.left, y: r.top, width: r.width, height: r.height});
+}`
+
+// ocrRegion is the result of ocrRegionJS.
+type ocrRegion struct {
+	DPR    float64 `json:"dpr"`
+	Found  bool    `json:"found"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// cropPNG returns the part of a PNG image inside r, in image pixels, and the
+// rectangle cropped, which is r clipped to the image.
+func cropPNG(data []byte, r image.Rectangle) ([]byte, image.Rectangle, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, r, err
+	}
+	b := img.Bounds()
+	r = r.Add(b.Min).Intersect(b).Sub(b.Min)
+	if r.Empty() {
+		return nil, r, errors.New("element is outside the viewport")
+	}
+	sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, r, fmt.Errorf("cannot crop %T images", img)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sub.SubImage(r.Add(b.Min))); err != nil {
+		return nil, r, err
+	}
+	return buf.Bytes(), r, nil
+}
+
+type OCRScreenshotArgs struct {
+	Selector      string   `json:"selector,omitempty" jsonschema:"CSS selector, XPath expression starting with /, or xpath= or text= prefixed selector of the element to read, such as a canvas or chart (default: the whole viewport)"`
+	Languages     []string `json:"languages,omitempty" jsonschema:"Languages to recognize, as Tesseract language codes such as eng or deu (default: the engine's default)"`
+	MinConfidence float64  `json:"min_confidence,omitempty" jsonschema:"Drop words recognized with a confidence below this, from 0 to 100 (default: 0)"`
+}
+
+// OCRResult is the structured result of ocr_screenshot. Bounding boxes are
+// in CSS pixels relative to the viewport.
+type OCRResult struct {
+	Engine string    `json:"engine"`
+	Text   string    `json:"text" jsonschema:"Recognized text, one line per line"`
+	Lines  []OCRLine `json:"lines"`
+	Words  []OCRWord `json:"words"`
+}
+
+// String formats r for the text result of ocr_screenshot.
+func (r *OCRResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "OCR (%s): %d lines, %d words\n", r.Engine, len(r.Lines), len(r.Words))
+	for _, l := range r.Lines {
+		fmt.Fprintf(&b, "[%d,%d %dx%d] %s\n", l.X, l.Y, l.Width, l.Height, l.Text)
+	}
+	return b.String()
+}
+
+// toViewport converts a box in pixels of an image captured at dpr, whose
+// top left corner is at (x, y) in the viewport, to CSS pixels of the
+// viewport.
+func toViewport(box *[4]int, dpr, x, y float64) {
+	box[0] = int(math.Round(float64(box[0])/dpr + x))
+	box[1] = int(math.Round(float64(box[1])/dpr + y))
+	box[2] = int(math.Round(float64(box[2]) / dpr))
+	box[3] = int(math.Round(float64(box[3]) / dpr))
+}
+
+// OCRScreenshot tool - recognizes the text in the viewport or an element
+func (s *CDPBrowserServer) OCRScreenshot(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[OCRScreenshotArgs]]) (*mcp.CallToolResultFor[OCRResult], error) {
+	args := req.Params.Arguments
+	fail := func(err error, code ErrorCode) (*mcp.CallToolResultFor[OCRResult], error) {
+		noteToolError(ctx, err, code)
+		return &mcp.CallToolResultFor[OCRResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error running OCR: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	name := s.ocrEngine
+	if name == "" {
+		name = defaultOCREngine
+	}
+	engine, ok := lookupOCREngine(name)
+	if !ok {
+		return fail(fmt.Errorf("unknown OCR engine %q", name), CodeToolUnavailable)
+	}
+
+	var region ocrRegion
+	loc := literalLocator(args.Selector)
+	if err := s.backend().Evaluate(ctx, callJS(ocrRegionJS, loc.Query, loc.XPath), &region); err != nil {
+		return fail(err, "")
+	}
+	if !region.Found {
+		return fail(notFoundError{fmt.Errorf("no element matches %s", args.Selector)}, "")
+	}
+	if region.DPR <= 0 {
+		region.DPR = 1
+	}
+	shot, err := s.backend().Screenshot(ctx)
+	if err != nil {
+		return fail(err, "")
+	}
+	if args.Selector != "" {
+		r := image.Rect(
+			int(math.Floor(region.X*region.DPR)), int(math.Floor(region.Y*region.DPR)),
+			int(math.Ceil((region.X+region.Width)*region.DPR)), int(math.Ceil((region.Y+region.Height)*region.DPR)),
+		)
+		if shot, r, err = cropPNG(shot, r); err != nil {
+			return fail(fmt.Errorf("capturing %s: %v", args.Selector, err), "")
+		}
+		// Word boxes are relative to the crop.
+		region.X, region.Y = float64(r.Min.X)/region.DPR, float64(r.Min.Y)/region.DPR
+	}
+
+	words, err := engine.Recognize(ctx, shot, args.Languages)
+	if err != nil {
+		return fail(err, "")
+	}
+	result := OCRResult{Engine: name, Words: []OCRWord{}}
+	for _, w := range words {
+		if w.Confidence < args.MinConfidence {
+			continue
+		}
+		box := [4]int{w.X, w.Y, w.Width, w.Height}
+		toViewport(&box, region.DPR, region.X, region.Y)
+		w.X, w.Y, w.Width, w.Height = box[0], box[1], box[2], box[3]
+		result.Words = append(result.Words, w)
+	}
+	result.Lines = ocrLines(result.Words)
+	var text []string
+	for _, l := range result.Lines {
+		text = append(text, l.Text)
+	}
+	result.Text = strings.Join(text, "\n")
+	return &mcp.CallToolResultFor[OCRResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: result.String()},
+		},
+		StructuredContent: result,
+	}, nil
+}
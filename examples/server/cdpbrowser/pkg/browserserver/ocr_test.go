@@ -0,0 +1,160 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const tesseractTSV = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+	"1\t1\t0\t0\t0\t0\t0\t0\t200\t100\t-1\t\n" +
+	"4\t1\t1\t1\t1\t0\t10\t10\t120\t20\t-1\t\n" +
+	"5\t1\t1\t1\t1\t1\t10\t10\t50\t20\t96.5\tRevenue\n" +
+	"5\t1\t1\t1\t1\t2\t70\t12\t60\t18\t91\t$1,200\n" +
+	"5\t1\t1\t1\t2\t1\t10\t40\t40\t20\t42\tQ3\n" +
+	"5\t1\t1\t1\t2\t2\t60\t40\t10\t20\t95\t \n"
+
+func TestParseTesseractTSV(t *testing.T) {
+	words, err := parseTesseractTSV([]byte(tesseractTSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []OCRWord{
+		{Text: "Revenue", X: 10, Y: 10, Width: 50, Height: 20, Confidence: 96.5, Line: 0},
+		{Text: "$1,200", X: 70, Y: 12, Width: 60, Height: 18, Confidence: 91, Line: 0},
+		{Text: "Q3", X: 10, Y: 40, Width: 40, Height: 20, Confidence: 42, Line: 1},
+	}
+	if !slices.Equal(words, want) {
+		t.Errorf("words = %+v, want %+v", words, want)
+	}
+	lines := ocrLines(words)
+	if len(lines) != 2 || lines[0] != (OCRLine{Text: "Revenue $1,200", X: 10, Y: 10, Width: 120, Height: 20, Confidence: 91}) || lines[1].Text != "Q3" {
+		t.Errorf("lines = %+v", lines)
+	}
+
+	if _, err := parseTesseractTSV([]byte("header\n5\t1\t1\t1\t1\t1\tx\t0\t0\t0\t90\tword\n")); err == nil {
+		t.Error("invalid box accepted")
+	}
+}
+
+// ocrBrowser is a backend whose page is a PNG, for ocr_screenshot.
+type ocrBrowser struct {
+	fakeBrowser
+	region string
+	png    []byte
+}
+
+func (b *ocrBrowser) Evaluate(ctx context.Context, expression string, res any) error {
+	return json.Unmarshal([]byte(b.region), res)
+}
+
+func (b *ocrBrowser) Screenshot(ctx context.Context) ([]byte, error) {
+	return b.png, nil
+}
+
+// fakeOCREngine reports the size of each image it is given as one word.
+type fakeOCREngine struct {
+	languages []string
+}
+
+func (e *fakeOCREngine) Recognize(ctx context.Context, img []byte, languages []string) ([]OCRWord, error) {
+	e.languages = languages
+	cfg, err := decodeScreenshot(img, "OCR")
+	if err != nil {
+		return nil, err
+	}
+	b := cfg.Bounds()
+	return []OCRWord{
+		{Text: "whole", X: 0, Y: 0, Width: b.Dx(), Height: b.Dy(), Confidence: 90},
+		{Text: "image", X: 2, Y: 4, Width: 2, Height: 2, Confidence: 50, Line: 1},
+	}, nil
+}
+
+func ocrScreenshot(t *testing.T, s *CDPBrowserServer, args OCRScreenshotArgs) (*mcp.CallToolResultFor[OCRResult], string) {
+	t.Helper()
+	res, err := s.OCRScreenshot(context.Background(), &mcp.ServerRequest[*mcp.CallToolParamsFor[OCRScreenshotArgs]]{
+		Params: &mcp.CallToolParamsFor[OCRScreenshotArgs]{Arguments: args},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, res.Content[0].(*mcp.TextContent).Text
+}
+
+func TestOCRScreenshot(t *testing.T) {
+	engine := &fakeOCREngine{}
+	RegisterOCREngine("fake", engine)
+	defer func() {
+		ocrEnginesMu.Lock()
+		delete(ocrEngines, "fake")
+		ocrEnginesMu.Unlock()
+	}()
+	if names := ocrEngineNames(); !slices.Equal(names, []string{"fake", "tesseract"}) {
+		t.Errorf("ocrEngineNames() = %v", names)
+	}
+
+	// A 200x100 screenshot of a 100x50 viewport at 2x.
+	b := &ocrBrowser{region: `{"dpr": 2, "found": true}`, png: encodePNG(t, testImage(200, 100, color.White))}
+	s := &CDPBrowserServer{browser: b, ocrEngine: "fake"}
+	res, text := ocrScreenshot(t, s, OCRScreenshotArgs{Languages: []string{"eng", "deu"}, MinConfidence: 60})
+	if res.IsError {
+		t.Fatalf("ocr_screenshot failed: %s", text)
+	}
+	if want := (OCRWord{Text: "whole", Width: 100, Height: 50, Confidence: 90}); len(res.StructuredContent.Words) != 1 || res.StructuredContent.Words[0] != want {
+		t.Errorf("words = %+v, want only %+v in CSS pixels", res.StructuredContent.Words, want)
+	}
+	if !strings.Contains(text, "OCR (fake): 1 lines, 1 words\n[0,0 100x50] whole\n") || !slices.Equal(engine.languages, []string{"eng", "deu"}) {
+		t.Errorf("ocr_screenshot = %q, languages %v", text, engine.languages)
+	}
+
+	// The element is cropped from the screenshot, and the boxes are
+	// translated to the viewport.
+	b.region = `{"dpr": 2, "found": true, "x": 10.25, "y": 20, "width": 30, "height": 40}`
+	res, text = ocrScreenshot(t, s, OCRScreenshotArgs{Selector: "canvas"})
+	if res.IsError {
+		t.Fatalf("ocr_screenshot of an element failed: %s", text)
+	}
+	words := res.StructuredContent.Words
+	if len(words) != 2 || words[0] != (OCRWord{Text: "whole", X: 10, Y: 20, Width: 31, Height: 30, Confidence: 90}) || words[1].X != 11 || words[1].Y != 22 {
+		t.Errorf("element words = %+v", words)
+	}
+	if res.StructuredContent.Text != "whole\nimage" {
+		t.Errorf("element text = %q", res.StructuredContent.Text)
+	}
+
+	b.region = `{"dpr": 1, "found": false}`
+	if res, text := ocrScreenshot(t, s, OCRScreenshotArgs{Selector: "#chart"}); !res.IsError || !strings.Contains(text, "no element matches #chart") {
+		t.Errorf("missing element = %q", text)
+	}
+	b.region = `{"dpr": 1, "found": true, "x": 500, "y": 0, "width": 10, "height": 10}`
+	if res, text := ocrScreenshot(t, s, OCRScreenshotArgs{Selector: "#offscreen"}); !res.IsError || !strings.Contains(text, "outside the viewport") {
+		t.Errorf("element outside the viewport = %q", text)
+	}
+}
+
+func TestCropPNG(t *testing.T) {
+	data := encodePNG(t, testImage(10, 10, color.Black, image.Rect(5, 5, 10, 10)))
+	cropped, r, err := cropPNG(data, image.Rect(4, 4, 20, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := decodeScreenshot(cropped, "cropped")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != image.Rect(4, 4, 10, 10) || img.Bounds().Dx() != 6 {
+		t.Errorf("crop = %v, %v", r, img.Bounds())
+	}
+	if _, _, err := cropPNG([]byte("png"), r); err == nil {
+		t.Error("cropped an invalid PNG")
+	}
+	if _, _, err := cropPNG(data, image.Rect(20, 20, 30, 30)); err == nil {
+		t.Error("cropped outside the image")
+	}
+}
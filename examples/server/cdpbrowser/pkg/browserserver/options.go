@@ -0,0 +1,236 @@
+package browserserver
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Options configure a server created with [New]. The zero value launches
+// Chrome on a random debugging port, closes it with the server, and exposes
+// the tools of the standard profile without further restrictions.
+type Options struct {
+	// Browser is the name of the browser backend to drive (default: chrome).
+	Browser string
+	// BiDiURL is the WebDriver BiDi WebSocket the bidi backend connects to,
+	// or "" to launch Firefox.
+	BiDiURL string
+	// ChromePort is the remote debugging port the browser is launched with,
+	// or 0 for a random port between 9222 and 9321.
+	ChromePort int
+	// ChromeUserDataDir is the user data directory Chrome is launched with
+	// (default: chrome-remote-profile in the temporary directory).
+	ChromeUserDataDir string
+	// KeepBrowserOpen leaves the launched browser running after Close.
+	KeepBrowserOpen bool
+
+	// AllowDomains and DenyDomains restrict the domains the browser may
+	// load, in addition to the lists in PolicyFile.
+	AllowDomains []string
+	DenyDomains  []string
+	// PolicyFile is a JSON navigation policy with allow_domains and
+	// deny_domains lists.
+	PolicyFile string
+	// ConfirmDestructive gates form submissions and delete/purchase-like
+	// clicks behind a confirmation.
+	ConfirmDestructive bool
+	// ConfirmPolicyFile is a JSON confirmation policy with submit, text, and
+	// urls patterns. It implies ConfirmDestructive.
+	ConfirmPolicyFile string
+	// AllowRawCDP exposes the execute_cdp tool.
+	AllowRawCDP bool
+	// ToolProfile is the tool profile: readonly, standard, or admin
+	// (default: standard).
+	ToolProfile string
+	// ToolConfigFile is a JSON tool config overriding ToolProfile.
+	ToolConfigFile string
+	// MaxConcurrentCalls, MaxCallsPerMinute, and MaxNavigationsPerMinute
+	// rate limit tool calls; 0 means no limit.
+	MaxConcurrentCalls      int
+	MaxCallsPerMinute       int
+	MaxNavigationsPerMinute int
+
+	// SelectorConfigFile enables, disables, reorders or adds smart selector
+	// strategies.
+	SelectorConfigFile string
+	// ActionTimeout is how long interactions wait for an actionable element
+	// (default: 10s).
+	ActionTimeout time.Duration
+	// ErrorArtifacts is what failed interactions attach: off, inline, or
+	// resource (default: inline).
+	ErrorArtifacts string
+	// ProfilesDir is where save_profile keeps browser profiles (default:
+	// the user config directory).
+	ProfilesDir string
+	// RecordingsDir is where stop_recording saves and replay_recording
+	// loads named recordings (default: the user config directory).
+	RecordingsDir string
+	// WorkflowsDir is where run_workflow finds stored workflows, or "" to
+	// only accept inline workflows.
+	WorkflowsDir string
+	// OCREngine is the name of the engine ocr_screenshot uses (default:
+	// tesseract). Add engines with RegisterOCREngine.
+	OCREngine string
+	// FailOnPageError fails interaction tools during which the page threw
+	// an uncaught JavaScript exception.
+	FailOnPageError bool
+	// VaultPath is the encrypted credential vault for
+	// login_with_credentials, opened with VaultPassphrase.
+	VaultPath       string
+	VaultPassphrase string
+}
+
+// New creates a server from opts, which may be nil, loading and checking the
+// files they name. It does not launch the browser; see Start.
+func New(opts *Options) (*CDPBrowserServer, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	s := &CDPBrowserServer{
+		keepChromeOpen:  opts.KeepBrowserOpen,
+		chromePort:      opts.ChromePort,
+		stats:           newToolStats(),
+		allowRawCDP:     opts.AllowRawCDP,
+		bidiURL:         opts.BiDiURL,
+		actionTimeout:   opts.ActionTimeout,
+		errorArtifacts:  opts.ErrorArtifacts,
+		profilesDir:     opts.ProfilesDir,
+		recordingsDir:   opts.RecordingsDir,
+		workflowsDir:    opts.WorkflowsDir,
+		ocrEngine:       opts.OCREngine,
+		failOnPageError: opts.FailOnPageError,
+	}
+	s.chromeUserDataDir = opts.ChromeUserDataDir
+	if s.chromePort == 0 {
+		s.chromePort = 9222 + rand.Intn(100)
+	}
+	if s.actionTimeout < 0 {
+		return nil, fmt.Errorf("action timeout must be positive, got %v", s.actionTimeout)
+	}
+	if s.errorArtifacts == "" {
+		s.errorArtifacts = errorArtifactsInline
+	}
+	if err := checkErrorArtifactsMode(s.errorArtifacts); err != nil {
+		return nil, err
+	}
+	if s.profilesDir == "" {
+		s.profilesDir = defaultProfilesDir()
+	}
+	if s.recordingsDir == "" {
+		s.recordingsDir = defaultRecordingsDir()
+	}
+
+	name := opts.Browser
+	if name == "" {
+		name = defaultBrowserBackend
+	}
+	backend, ok := lookupBrowserBackend(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown browser backend %q (available: %s)", name, strings.Join(browserBackendNames(), ", "))
+	}
+	s.browser = backend(s)
+
+	if s.ocrEngine == "" {
+		s.ocrEngine = defaultOCREngine
+	}
+	if _, ok := lookupOCREngine(s.ocrEngine); !ok {
+		return nil, fmt.Errorf("unknown OCR engine %q (available: %s)", s.ocrEngine, strings.Join(ocrEngineNames(), ", "))
+	}
+
+	var err error
+	s.policy = &navigationPolicy{}
+	if opts.PolicyFile != "" {
+		if s.policy, err = loadNavigationPolicy(opts.PolicyFile); err != nil {
+			return nil, fmt.Errorf("failed to load navigation policy: %v", err)
+		}
+	}
+	s.policy.AllowDomains = append(s.policy.AllowDomains, opts.AllowDomains...)
+	s.policy.DenyDomains = append(s.policy.DenyDomains, opts.DenyDomains...)
+	if opts.ConfirmPolicyFile != "" {
+		if s.confirmPolicy, err = loadConfirmationPolicy(opts.ConfirmPolicyFile); err != nil {
+			return nil, fmt.Errorf("failed to load confirmation policy: %v", err)
+		}
+	} else if opts.ConfirmDestructive {
+		p := defaultConfirmationPolicy
+		s.confirmPolicy = &p
+	}
+	if s.confirmPolicy != nil {
+		if err := s.confirmPolicy.compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.toolset.config = toolConfig{Profile: opts.ToolProfile}
+	if s.toolset.config.Profile == "" {
+		s.toolset.config.Profile = defaultToolProfile
+	}
+	if err := checkToolProfile(s.toolset.config.Profile); err != nil {
+		return nil, err
+	}
+	if opts.ToolConfigFile != "" {
+		if s.toolset.config, err = loadToolConfig(opts.ToolConfigFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.MaxConcurrentCalls < 0 || opts.MaxCallsPerMinute < 0 || opts.MaxNavigationsPerMinute < 0 {
+		return nil, fmt.Errorf("rate limits must not be negative")
+	}
+	s.rateLimiter.limits = rateLimits{
+		Concurrent:           opts.MaxConcurrentCalls,
+		CallsPerMinute:       opts.MaxCallsPerMinute,
+		NavigationsPerMinute: opts.MaxNavigationsPerMinute,
+	}
+	if opts.SelectorConfigFile != "" {
+		if s.selectorConfig, err = loadSelectorConfig(opts.SelectorConfigFile); err != nil {
+			return nil, fmt.Errorf("failed to load selector config: %v", err)
+		}
+	}
+	if opts.VaultPath != "" {
+		if s.vault, err = loadVault(opts.VaultPath, opts.VaultPassphrase); err != nil {
+			return nil, fmt.Errorf("failed to open credential vault: %v", err)
+		}
+		s.secrets.add(s.vault.secrets()...)
+		log.Printf("Loaded %d credentials from %s: %s", len(s.vault.credentials), opts.VaultPath, strings.Join(s.vault.aliases(), ", "))
+	}
+	return s, nil
+}
+
+// Start launches the browser and registers the tools, after which the
+// server can serve MCP sessions.
+func (s *CDPBrowserServer) Start() error {
+	if err := s.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize browser: %v", err)
+	}
+	mcpServer := newMCPServer(s)
+	// Check the tool names in the config, now that the tools are defined
+	if _, err := s.toolset.setConfig(s.toolset.config); err != nil {
+		s.Close()
+		return fmt.Errorf("invalid tool config: %v", err)
+	}
+	s.mcpServer = mcpServer
+	return nil
+}
+
+// MCPServer returns the MCP server of s, or nil before Start. Programs can
+// connect it to transports of their own.
+func (s *CDPBrowserServer) MCPServer() *mcp.Server {
+	return s.mcpServer
+}
+
+// Handler returns an HTTP handler serving the tools over the streamable HTTP
+// transport, one MCP session per client. Call it after Start.
+func (s *CDPBrowserServer) Handler() http.Handler {
+	return mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return s.mcpServer }, nil)
+}
+
+// Close disconnects from the browser, stopping it unless KeepBrowserOpen was
+// set or set_chrome_lifecycle asked to keep it open.
+func (s *CDPBrowserServer) Close() {
+	s.backend().Close()
+}
@@ -0,0 +1,111 @@
+package browserserver
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestNew(t *testing.T) {
+	s, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.chromePort < 9222 || s.chromePort > 9321 {
+		t.Errorf("random Chrome port = %d", s.chromePort)
+	}
+	if s.Backend().Name() != "chrome" || s.errorArtifacts != errorArtifactsInline || s.toolset.config.Profile != toolProfileStandard || s.profilesDir == "" {
+		t.Errorf("New(nil) = backend %s, error artifacts %q, profile %q, profiles dir %q",
+			s.Backend().Name(), s.errorArtifacts, s.toolset.config.Profile, s.profilesDir)
+	}
+	if s.keepChromeOpen || s.confirmPolicy != nil || s.policy.enabled() {
+		t.Error("New(nil) keeps Chrome open or enables a policy")
+	}
+
+	dir := t.TempDir()
+	policyFile := filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(policyFile, []byte(`{"allow_domains": ["example.com"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err = New(&Options{
+		Browser:            "bidi",
+		ChromePort:         9500,
+		KeepBrowserOpen:    true,
+		AllowDomains:       []string{"example.org"},
+		DenyDomains:        []string{"ads.example.com"},
+		PolicyFile:         policyFile,
+		ConfirmDestructive: true,
+		ToolProfile:        toolProfileReadonly,
+		MaxCallsPerMinute:  30,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Backend().Name() != "bidi" || s.chromePort != 9500 || !s.keepChromeOpen || s.confirmPolicy == nil || s.rateLimiter.limits.CallsPerMinute != 30 {
+		t.Errorf("New with options = %+v", s)
+	}
+	if !slices.Equal(s.policy.AllowDomains, []string{"example.com", "example.org"}) {
+		t.Errorf("allowed domains = %v", s.policy.AllowDomains)
+	}
+	if s.CheckURL("https://ads.example.com/") == nil || s.CheckURL("https://example.org/") != nil {
+		t.Error("navigation policy not applied")
+	}
+
+	for _, opts := range []*Options{
+		{Browser: "netscape"},
+		{ToolProfile: "guest"},
+		{ErrorArtifacts: "email"},
+		{OCREngine: "eyeball"},
+		{MaxConcurrentCalls: -1},
+		{PolicyFile: filepath.Join(dir, "missing.json")},
+		{VaultPath: filepath.Join(dir, "missing.vault")},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v) succeeded", opts)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s, err := New(&Options{ToolProfile: toolProfileReadonly})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.MCPServer() != nil {
+		t.Error("MCP server created before Start")
+	}
+	if err := s.Serve(context.Background(), &mcp.StdioTransport{}); err == nil {
+		t.Error("Serve succeeded before Start")
+	}
+	// Register the tools without launching the browser, as Start would.
+	s.mcpServer = newMCPServer(s)
+
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	ctx := context.Background()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, mcp.NewStreamableClientTransport(ts.URL, nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	res, err := cs.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range res.Tools {
+		names = append(names, tool.Name)
+	}
+	if !slices.Contains(names, "get_tool_stats") || slices.Contains(names, "click") {
+		t.Errorf("tools over HTTP = %v", names)
+	}
+	if text := resultText(callTool(t, cs, "get_tool_stats", nil)); !strings.HasPrefix(text, "TOOL STATS") {
+		t.Errorf("get_tool_stats over HTTP = %q", text)
+	}
+}
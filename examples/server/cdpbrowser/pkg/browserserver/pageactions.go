@@ -0,0 +1,257 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// The functions in this file find and act on elements with scripts run
+// through Browser.Evaluate, so that the core interaction and snapshot tools
+// work with backends that do not speak CDP. The events they fire are
+// synthetic: pages that check isTrusted can tell them from a user's.
+
+// findInPageJS returns the first element matching a CSS or XPath query.
+const findInPageJS = `function(query, xpath) {
+	if (xpath) {
+		return document.evaluate(query, document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue;
+	}
+	return document.querySelector(query);
+}`
+
+// countInPageJS returns the number of elements matching a CSS or XPath
+// query.
+const countInPageJS = `function(query, xpath) {
+	if (xpath) {
+		return document.evaluate(query, document, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null).snapshotLength;
+	}
+	return document.querySelectorAll(query).length;
+}`
+
+// pageClickJS is called on the element to click. It fires the pointer and
+// mouse events of a click at the element's center, then clicks it, which
+// runs the default action such as following a link or submitting a form.
+const pageClickJS = `function() {
+	const rect = this.getBoundingClientRect();
+	const init = {bubbles: true, cancelable: true, composed: true, view: window, button: 0,
+		clientX: rect.left + rect.width / 2, clientY: rect.top + rect.height / 2};
+	this.dispatchEvent(new PointerEvent('pointerdown', init));
+	this.dispatchEvent(new MouseEvent('mousedown', init));
+	if (typeof this.focus === 'function') {
+		this.focus();
+	}
+	this.dispatchEvent(new PointerEvent('pointerup', init));
+	this.dispatchEvent(new MouseEvent('mouseup', init));
+	this.click();
+}`
+
+// pageTypeJS is called on the field to type into with the text and whether
+// to clear the field first. It types one character at a time with key and
+// input events. Values are set through the prototype's setter, so that
+// frameworks tracking the value property see the change. A line break is an
+// Enter key, which submits the form of a text input.
+const pageTypeJS = `function(text, clear) {
+	const el = this;
+	el.focus();
+	const proto = el instanceof HTMLTextAreaElement ? HTMLTextAreaElement.prototype : HTMLInputElement.prototype;
+	const setter = Object.getOwnPropertyDescriptor(proto, 'value').set;
+	const editable = !(el instanceof HTMLInputElement || el instanceof HTMLTextAreaElement);
+	if (clear) {
+		if (editable) {
+			el.textContent = '';
+		} else {
+			setter.call(el, '');
+		}
+		el.dispatchEvent(new InputEvent('input', {bubbles: true, inputType: 'deleteContentBackward'}));
+	}
+	for (const ch of text.replace(/\r\n?/g, '\n')) {
+		const enter = ch === '\n';
+		const key = {key: enter ? 'Enter' : ch, bubbles: true, cancelable: true, composed: true};
+		if (!el.dispatchEvent(new KeyboardEvent('keydown', key))) {
+			continue;
+		}
+		el.dispatchEvent(new KeyboardEvent('keypress', key));
+		if (enter && el instanceof HTMLInputElement) {
+			if (el.form) {
+				el.form.requestSubmit();
+			}
+		} else if (editable) {
+			document.execCommand('insertText', false, ch);
+		} else {
+			setter.call(el, el.value + ch);
+			el.dispatchEvent(new InputEvent('input', {bubbles: true, data: ch, inputType: 'insertText'}));
+		}
+		el.dispatchEvent(new KeyboardEvent('keyup', key));
+	}
+	el.dispatchEvent(new Event('change', {bubbles: true}));
+}`
+
+// pageSelectJS is called on a select element with the value or visible
+// text of the option to select.
+const pageSelectJS = `function(want) {
+	if (!(this instanceof HTMLSelectElement)) {
+		throw new Error('element is not a select element');
+	}
+	const option = Array.from(this.options).find(o => o.value === want) ||
+		Array.from(this.options).find(o => o.text.trim() === want.trim());
+	if (!option) {
+		throw new Error('no option with value or text ' + JSON.stringify(want));
+	}
+	this.value = option.value;
+	this.dispatchEvent(new Event('input', {bubbles: true}));
+	this.dispatchEvent(new Event('change', {bubbles: true}));
+}`
+
+// pageSnapshotJS builds an ARIA snapshot from the DOM, for the given focus
+// and the CSS or XPath query of the region. Roles and names are approximated from tags and
+// attributes, since the accessibility tree is only available over CDP. It
+// returns null if no element matches the region selector.
+const pageSnapshotJS = `function(focus, region, xpath) {` + ariaHelpersJS + `
+	const root = region ? (` + findInPageJS + `)(region, xpath) : document.body;
+	if (!root) {
+		return null;
+	}
+	const implicitRoles = {A: 'link', BUTTON: 'button', SELECT: 'combobox', TEXTAREA: 'textbox',
+		NAV: 'navigation', MAIN: 'main', HEADER: 'banner', FOOTER: 'contentinfo', ASIDE: 'complementary', FORM: 'form'};
+	const inputRoles = {checkbox: 'checkbox', radio: 'radio', button: 'button', submit: 'button', reset: 'button', range: 'slider', search: 'searchbox'};
+	const roleOf = el => el.getAttribute('role') ||
+		(el.tagName === 'INPUT' ? inputRoles[el.type] || 'textbox' : implicitRoles[el.tagName] || '');
+	const base = el => ({
+		role: roleOf(el),
+		name: getAccessibleName(el).replace(/\s+/g, ' ').trim(),
+		selector: getSelector(el),
+		ariaLabel: el.getAttribute('aria-label') || '',
+		tag: el.tagName.toLowerCase()
+	});
+	const everything = focus === 'all' || focus === 'region';
+	const snapshot = {page: {title: document.title, url: location.href}, landmarks: [], interactive: [], headings: []};
+	if (everything || focus === 'landmarks') {
+		root.querySelectorAll('nav, main, header, footer, aside, form, [role="navigation"], [role="main"], [role="banner"], [role="contentinfo"], [role="complementary"], [role="search"], [role="region"]').forEach(el => {
+			snapshot.landmarks.push(base(el));
+		});
+	}
+	if (everything || focus === 'interactive') {
+		collectInteractiveElements().filter(el => root.contains(el)).forEach(el => {
+			const e = base(el);
+			if (el.href) {
+				e.href = el.href;
+			}
+			if ('value' in el && el.value) {
+				e.value = String(el.value);
+			}
+			snapshot.interactive.push(e);
+		});
+	}
+	if (everything || focus === 'headings') {
+		root.querySelectorAll('h1, h2, h3, h4, h5, h6').forEach(el => {
+			const text = el.textContent.replace(/\s+/g, ' ').trim();
+			if (text) {
+				snapshot.headings.push({level: Number(el.tagName[1]), text: text, selector: getSelector(el), tag: el.tagName.toLowerCase()});
+			}
+		});
+	}
+	return snapshot;
+}`
+
+// inPageMatch is the result of a function called on a matched element.
+type inPageMatch struct {
+	Found bool            `json:"found"`
+	Value json.RawMessage `json:"value"`
+}
+
+// evaluateOnMatch calls the JavaScript function fn with the first element
+// loc matches as this and args as its arguments, and decodes its result into
+// res, which may be nil. It returns a notFoundError if no element matches.
+func (s *CDPBrowserServer) evaluateOnMatch(ctx context.Context, loc SelectorLocator, fn string, res any, args ...any) error {
+	params, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	query, _ := json.Marshal(loc.Query)
+	js := fmt.Sprintf("(async () => { const el = (%s)(%s, %t); return el ? {found: true, value: await (%s).apply(el, %s)} : {found: false}; })()",
+		findInPageJS, query, loc.XPath, fn, params)
+	var m inPageMatch
+	if err := s.backend().Evaluate(ctx, js, &m); err != nil {
+		return err
+	}
+	if !m.Found {
+		return notFoundError{fmt.Errorf("no element matches %s", loc.Query)}
+	}
+	if res == nil || len(m.Value) == 0 || string(m.Value) == "null" {
+		return nil
+	}
+	return json.Unmarshal(m.Value, res)
+}
+
+// countInPage returns the number of elements loc matches.
+func (s *CDPBrowserServer) countInPage(ctx context.Context, loc SelectorLocator) (int, error) {
+	var n int
+	err := s.backend().Evaluate(ctx, callJS(countInPageJS, loc.Query, loc.XPath), &n)
+	return n, err
+}
+
+// waitActionableInPage is waitActionable for backends without CDP. It
+// scrolls the element into view before each check.
+func (s *CDPBrowserServer) waitActionableInPage(ctx context.Context, loc SelectorLocator, checks actionChecks) error {
+	fn := fmt.Sprintf("async function() { this.scrollIntoView({block: 'center', inline: 'center'}); return await (%s).call(this, %t, %t); }", actionabilityJS, checks.pointer, checks.editable)
+	return pollActionable(ctx, s.actionTimeoutOrDefault(), func(ctx context.Context) (string, error) {
+		var reason string
+		err := s.evaluateOnMatch(ctx, loc, fn, &reason)
+		if _, ok := err.(notFoundError); ok {
+			return noElementReason, nil
+		}
+		return reason, err
+	})
+}
+
+// clickInPage clicks the first element loc matches once it is actionable
+// and c allows it.
+func (s *CDPBrowserServer) clickInPage(ctx context.Context, loc SelectorLocator, c *clickConfirmation) error {
+	if err := s.waitActionableInPage(ctx, loc, actionChecks{pointer: true}); err != nil {
+		return err
+	}
+	if err := c.checkInPage(ctx, s, loc, clickTargetJS); err != nil {
+		return err
+	}
+	return s.evaluateOnMatch(ctx, loc, pageClickJS, nil)
+}
+
+// typeInPage types text into the first element loc matches once it is
+// actionable, clearing it first if clear is set. If text presses Enter, c
+// must allow the form submission it causes.
+func (s *CDPBrowserServer) typeInPage(ctx context.Context, loc SelectorLocator, text string, clear bool, c *clickConfirmation) error {
+	if err := s.waitActionableInPage(ctx, loc, actionChecks{editable: true}); err != nil {
+		return err
+	}
+	if pressesEnter(text) {
+		if err := c.checkInPage(ctx, s, loc, enterTargetJS); err != nil {
+			return err
+		}
+	}
+	return s.evaluateOnMatch(ctx, loc, pageTypeJS, nil, text, clear)
+}
+
+// selectInPage selects the option with the given value or text in the
+// select element loc matches.
+func (s *CDPBrowserServer) selectInPage(ctx context.Context, loc SelectorLocator, value string) error {
+	if err := s.waitActionableInPage(ctx, loc, actionChecks{}); err != nil {
+		return err
+	}
+	return s.evaluateOnMatch(ctx, loc, pageSelectJS, nil, value)
+}
+
+// ariaSnapshotInPage builds an ARIA snapshot from the DOM. Its interactive
+// elements have no IDs, since the *_by_id tools need CDP.
+func (s *CDPBrowserServer) ariaSnapshotInPage(ctx context.Context, focus, region string) (*ARIASnapshotResult, error) {
+	var snapshot *ARIASnapshotResult
+	loc := literalLocator(region)
+	if err := s.backend().Evaluate(ctx, callJS(pageSnapshotJS, focus, loc.Query, loc.XPath), &snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, notFoundError{fmt.Errorf("no element matches region selector %s", region)}
+	}
+	snapshot.Page.Timestamp = time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	return snapshot, nil
+}
@@ -0,0 +1,104 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// fakePage answers the page scripts of pageactions.go for a page whose only
+// element is the one with the given id selector, such as "#go", recording
+// the actions taken on it.
+type fakePage struct {
+	selector string
+	actions  []string
+}
+
+func (p *fakePage) eval(expression string, res any) error {
+	found := strings.Contains(expression, strconv.Quote(p.selector))
+	var answer any
+	switch {
+	case strings.Contains(expression, countInPageJS):
+		answer = 0
+		if found {
+			answer = 1
+		}
+	case strings.Contains(expression, pageSnapshotJS):
+		answer = map[string]any{
+			"page":        map[string]any{"title": "Fake", "url": "https://example.com/"},
+			"interactive": []map[string]any{{"role": "button", "name": "Go", "selector": p.selector, "tag": "button"}},
+		}
+	case !found:
+		answer = map[string]any{"found": false}
+	case strings.Contains(expression, pageClickJS):
+		p.actions = append(p.actions, "click")
+		answer = map[string]any{"found": true}
+	case strings.Contains(expression, enterTargetJS):
+		answer = map[string]any{"found": true, "value": map[string]any{
+			"element": "input#go", "submit": true, "action": "https://example.com/pay", "page": "https://example.com/", "key": "Enter",
+		}}
+	case strings.Contains(expression, pageTypeJS):
+		p.actions = append(p.actions, "type")
+		answer = map[string]any{"found": true}
+	default:
+		// actionabilityJS: the element is ready.
+		answer = map[string]any{"found": true, "value": ""}
+	}
+	data, err := json.Marshal(answer)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, res)
+}
+
+func TestPageActionsWithoutCDP(t *testing.T) {
+	page := &fakePage{selector: "#go"}
+	s := &CDPBrowserServer{browser: &fakeBrowser{eval: page.eval}, stats: newToolStats(), policy: &navigationPolicy{}, actionTimeout: 100 * time.Millisecond}
+	server := newMCPServer(s)
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	callTool(t, cs, "click", map[string]any{"selector": "#go"})
+	callTool(t, cs, "type_text", map[string]any{"selector": "#go", "text": "hello"})
+	if want := []string{"click", "type"}; strings.Join(page.actions, ",") != strings.Join(want, ",") {
+		t.Errorf("page actions = %q, want %q", page.actions, want)
+	}
+
+	if text := callToolError(t, cs, "click", map[string]any{"selector": "#missing", "strict": true}); !strings.Contains(text, "#missing") {
+		t.Errorf("click on a missing element = %q, want it to name the selector", text)
+	}
+
+	// An Enter key that submits a form is subject to the confirmation
+	// policy, like a click on the submit button.
+	s.confirmPolicy = &confirmationPolicy{Submit: true}
+	page.actions = nil
+	if text := callToolError(t, cs, "type_text", map[string]any{"selector": "#go", "text": "4111\n"}); !strings.Contains(text, "pressing Enter in input#go needs confirmation") {
+		t.Errorf("type_text with Enter under a submit policy = %q, want a confirmation error", text)
+	}
+	callTool(t, cs, "type_text", map[string]any{"selector": "#go", "text": "4111\n", "confirm": true})
+	callTool(t, cs, "type_text", map[string]any{"selector": "#go", "text": "4111"})
+	if want := []string{"type", "type"}; strings.Join(page.actions, ",") != strings.Join(want, ",") {
+		t.Errorf("page actions under a submit policy = %q, want %q", page.actions, want)
+	}
+	s.confirmPolicy = nil
+
+	res := callTool(t, cs, "aria_snapshot", map[string]any{"focus": "interactive"})
+	if text := resultText(res); !strings.Contains(text, "Go") || !strings.Contains(text, "#go") {
+		t.Errorf("aria_snapshot = %q, want the Go button", text)
+	}
+}
@@ -0,0 +1,295 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxPageErrors bounds the uncaught exceptions kept for get_page_errors.
+const maxPageErrors = 100
+
+// maxStackFrames bounds the stack frames kept for each exception.
+const maxStackFrames = 10
+
+// PageError is an uncaught JavaScript exception thrown by the page.
+type PageError struct {
+	Seq     int          `json:"seq" jsonschema:"Sequence number of the error, increasing over the life of the browser"`
+	Time    time.Time    `json:"time"`
+	Message string       `json:"message"`
+	URL     string       `json:"url,omitempty" jsonschema:"Script or page that threw the exception"`
+	Line    int          `json:"line,omitempty"`
+	Column  int          `json:"column,omitempty"`
+	Stack   []StackFrame `json:"stack,omitempty" jsonschema:"Call stack where the exception was thrown, innermost first"`
+
+	// exceptionID is the CDP identifier of the exception, which
+	// Runtime.exceptionRevoked refers to.
+	exceptionID int64
+}
+
+// StackFrame is a frame of the stack of a PageError.
+type StackFrame struct {
+	Function string `json:"function,omitempty"`
+	URL      string `json:"url"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+// String formats e with its stack, one frame per line.
+func (e *PageError) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d %s", e.Seq, e.Message)
+	if len(e.Stack) == 0 && e.URL != "" {
+		fmt.Fprintf(&b, " (%s:%d:%d)", e.URL, e.Line, e.Column)
+	}
+	for _, f := range e.Stack {
+		function := f.Function
+		if function == "" {
+			function = "<anonymous>"
+		}
+		fmt.Fprintf(&b, "\n    at %s (%s:%d:%d)", function, f.URL, f.Line, f.Column)
+	}
+	return b.String()
+}
+
+// newPageError converts the details of Runtime.exceptionThrown, with
+// 1-based line and column numbers.
+func newPageError(d *runtime.ExceptionDetails) PageError {
+	e := PageError{
+		Message:     d.Text,
+		URL:         d.URL,
+		Line:        int(d.LineNumber) + 1,
+		Column:      int(d.ColumnNumber) + 1,
+		exceptionID: d.ExceptionID,
+	}
+	// The description of the exception is its message and stack; the text
+	// is only "Uncaught" or "Uncaught (in promise)".
+	if d.Exception != nil && d.Exception.Description != "" {
+		message, _, _ := strings.Cut(d.Exception.Description, "\n")
+		if strings.Contains(d.Text, "(in promise)") {
+			message = "(in promise) " + message
+		}
+		e.Message = message
+	}
+	if d.StackTrace != nil {
+		for _, f := range d.StackTrace.CallFrames {
+			if len(e.Stack) == maxStackFrames {
+				break
+			}
+			e.Stack = append(e.Stack, StackFrame{
+				Function: f.FunctionName,
+				URL:      f.URL,
+				Line:     int(f.LineNumber) + 1,
+				Column:   int(f.ColumnNumber) + 1,
+			})
+		}
+		if e.URL == "" && len(e.Stack) > 0 {
+			e.URL = e.Stack[0].URL
+		}
+	}
+	return e
+}
+
+// pageErrorLog collects the uncaught exceptions of the tab the tools drive.
+type pageErrorLog struct {
+	mu sync.Mutex
+	// errors are the most recent exceptions, oldest first.
+	errors []PageError
+	// seq is the sequence number of the latest exception.
+	seq int
+	// dropped counts the exceptions removed to stay within maxPageErrors.
+	dropped int
+}
+
+// start collects the exceptions of the tab of tabCtx. The runtime domain,
+// which reports them, is enabled by chromedp when it attaches to the tab.
+func (l *pageErrorLog) start(tabCtx context.Context) {
+	l.mu.Lock()
+	l.errors, l.dropped = nil, 0
+	l.mu.Unlock()
+	chromedp.ListenTarget(tabCtx, l.event)
+}
+
+// event records exceptions and forgets revoked ones, such as promise
+// rejections handled after they were reported.
+func (l *pageErrorLog) event(ev any) {
+	switch ev := ev.(type) {
+	case *runtime.EventExceptionThrown:
+		t := time.Now()
+		if ev.Timestamp != nil {
+			t = ev.Timestamp.Time()
+		}
+		l.add(newPageError(ev.ExceptionDetails), t)
+	case *runtime.EventExceptionRevoked:
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, e := range l.errors {
+			if e.exceptionID == ev.ExceptionID {
+				l.errors = append(l.errors[:i], l.errors[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// add records e, thrown at t, and returns its sequence number.
+func (l *pageErrorLog) add(e PageError, t time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	e.Seq, e.Time = l.seq, t
+	l.errors = append(l.errors, e)
+	if over := len(l.errors) - maxPageErrors; over > 0 {
+		l.errors = append([]PageError(nil), l.errors[over:]...)
+		l.dropped += over
+	}
+	return e.Seq
+}
+
+// latest returns the sequence number of the latest exception.
+func (l *pageErrorLog) latest() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq
+}
+
+// since returns the recorded exceptions after sequence number seq, oldest
+// first, and how many exceptions were dropped.
+func (l *pageErrorLog) since(seq int) ([]PageError, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var errs []PageError
+	for _, e := range l.errors {
+		if e.Seq > seq {
+			errs = append(errs, e)
+		}
+	}
+	return errs, l.dropped
+}
+
+// clear forgets the recorded exceptions; sequence numbers keep increasing.
+func (l *pageErrorLog) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors, l.dropped = nil, 0
+}
+
+type GetPageErrorsArgs struct {
+	Since int  `json:"since,omitempty" jsonschema:"Only return errors with a greater seq, such as the latest of a previous call"`
+	Limit int  `json:"limit,omitempty" jsonschema:"Maximum number of errors to return, the most recent (default: 20)"`
+	Clear bool `json:"clear,omitempty" jsonschema:"Forget the recorded errors after returning them"`
+}
+
+// PageErrors is the structured result of get_page_errors.
+type PageErrors struct {
+	Errors []PageError `json:"errors"`
+	// Latest is the seq of the latest error, to pass as since.
+	Latest int `json:"latest" jsonschema:"Seq of the latest error; pass it as since to only get later errors"`
+	// Omitted counts the errors after since left out by limit.
+	Omitted int `json:"omitted,omitempty"`
+	// Dropped counts the errors no longer kept, as only the most recent
+	// are.
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// String formats p for tool results.
+func (p *PageErrors) String() string {
+	if len(p.Errors) == 0 {
+		return fmt.Sprintf("No uncaught exceptions (latest seq %d)", p.Latest)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d uncaught exceptions (latest seq %d)", len(p.Errors)+p.Omitted, p.Latest)
+	if p.Omitted > 0 {
+		fmt.Fprintf(&b, ", showing the last %d", len(p.Errors))
+	}
+	b.WriteString(":")
+	for _, e := range p.Errors {
+		b.WriteString("\n")
+		b.WriteString(e.String())
+	}
+	return b.String()
+}
+
+// GetPageErrors tool - returns the uncaught JavaScript exceptions thrown by
+// the page, with their stacks, so an agent can tell a broken page from a
+// slow one.
+func (s *CDPBrowserServer) GetPageErrors(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[GetPageErrorsArgs]]) (*mcp.CallToolResultFor[PageErrors], error) {
+	args := req.Params.Arguments
+	if args.Limit <= 0 {
+		args.Limit = 20
+	}
+
+	errs, dropped := s.pageErrors.since(args.Since)
+	result := PageErrors{Latest: s.pageErrors.latest(), Dropped: dropped}
+	if over := len(errs) - args.Limit; over > 0 {
+		errs, result.Omitted = errs[over:], over
+	}
+	result.Errors = errs
+	if result.Errors == nil {
+		result.Errors = []PageError{}
+	}
+	if args.Clear {
+		s.pageErrors.clear()
+	}
+
+	return &mcp.CallToolResultFor[PageErrors]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: result.String()},
+		},
+		StructuredContent: result,
+	}, nil
+}
+
+// pageExceptionError is the failure of an interaction during which the page
+// threw uncaught exceptions.
+type pageExceptionError struct {
+	errors []PageError
+}
+
+func (e *pageExceptionError) Error() string {
+	if len(e.errors) == 1 {
+		return "the page threw an uncaught exception during the action: " + e.errors[0].Message
+	}
+	return fmt.Sprintf("the page threw %d uncaught exceptions during the action, first: %s", len(e.errors), e.errors[0].Message)
+}
+
+// pageErrorsMiddleware fails interaction tools that succeeded while the
+// page threw uncaught exceptions, if enabled with -fail-on-page-error. It
+// runs inside toolErrorMiddleware, which adds the PAGE_ERROR code.
+func (s *CDPBrowserServer) pageErrorsMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		params, ok := req.GetParams().(*mcp.CallToolParamsFor[json.RawMessage])
+		if !ok || !interactionTools[params.Name] || !s.failOnPageError {
+			return next(ctx, method, req)
+		}
+
+		before := s.pageErrors.latest()
+		result, err := next(ctx, method, req)
+		res, ok := result.(*mcp.CallToolResult)
+		if !ok || err != nil || res.IsError {
+			return result, err
+		}
+		// Exceptions thrown while the action settled are reported before the
+		// tool returns.
+		errs, _ := s.pageErrors.since(before)
+		if len(errs) == 0 {
+			return result, err
+		}
+		failure := &pageExceptionError{errs}
+		noteToolError(ctx, failure, CodePageError)
+		lines := []string{"Error: " + failure.Error()}
+		for _, e := range errs {
+			lines = append(lines, e.String())
+		}
+		res.Content = append([]mcp.Content{&mcp.TextContent{Text: strings.Join(lines, "\n")}}, res.Content...)
+		res.IsError = true
+		return result, err
+	}
+}
@@ -0,0 +1,168 @@
+package browserserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestNewPageError(t *testing.T) {
+	e := newPageError(&runtime.ExceptionDetails{
+		ExceptionID:  7,
+		Text:         "Uncaught",
+		LineNumber:   11,
+		ColumnNumber: 4,
+		Exception:    &runtime.RemoteObject{Description: "TypeError: x is undefined\n    at render (http://127.0.0.1/app.js:12:5)"},
+		StackTrace: &runtime.StackTrace{CallFrames: []*runtime.CallFrame{
+			{FunctionName: "render", URL: "http://127.0.0.1/app.js", LineNumber: 11, ColumnNumber: 4},
+			{URL: "http://127.0.0.1/app.js", LineNumber: 30, ColumnNumber: 0},
+		}},
+	})
+	if e.Message != "TypeError: x is undefined" || e.URL != "http://127.0.0.1/app.js" || e.Line != 12 || e.Column != 5 || e.exceptionID != 7 {
+		t.Errorf("newPageError = %+v", e)
+	}
+	e.Seq = 3
+	want := "#3 TypeError: x is undefined\n    at render (http://127.0.0.1/app.js:12:5)\n    at <anonymous> (http://127.0.0.1/app.js:31:1)"
+	if got := e.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	e = newPageError(&runtime.ExceptionDetails{
+		Text:      "Uncaught (in promise)",
+		Exception: &runtime.RemoteObject{Description: "Error: nope"},
+	})
+	if e.Message != "(in promise) Error: nope" {
+		t.Errorf("promise rejection message = %q", e.Message)
+	}
+}
+
+func TestPageErrorLog(t *testing.T) {
+	var l pageErrorLog
+	for i := range maxPageErrors + 2 {
+		l.event(&runtime.EventExceptionThrown{ExceptionDetails: &runtime.ExceptionDetails{ExceptionID: int64(i + 1), Text: "Uncaught"}})
+	}
+	errs, dropped := l.since(0)
+	if len(errs) != maxPageErrors || dropped != 2 || errs[0].Seq != 3 || l.latest() != maxPageErrors+2 {
+		t.Fatalf("since(0) = %d errors from seq %d, %d dropped", len(errs), errs[0].Seq, dropped)
+	}
+
+	// A handled promise rejection is revoked.
+	l.event(&runtime.EventExceptionRevoked{ExceptionID: maxPageErrors + 2})
+	if errs, _ := l.since(maxPageErrors); len(errs) != 1 || errs[0].Seq != maxPageErrors+1 {
+		t.Errorf("after revocation, since(%d) = %+v", maxPageErrors, errs)
+	}
+
+	l.clear()
+	if errs, dropped := l.since(0); len(errs) != 0 || dropped != 0 {
+		t.Errorf("after clear, since(0) = %d errors, %d dropped", len(errs), dropped)
+	}
+	if seq := l.add(PageError{Message: "again"}, time.Now()); seq != maxPageErrors+3 {
+		t.Errorf("seq after clear = %d", seq)
+	}
+}
+
+func TestGetPageErrors(t *testing.T) {
+	s := &CDPBrowserServer{}
+	for _, msg := range []string{"first", "second", "third"} {
+		s.pageErrors.add(PageError{Message: msg}, time.Now())
+	}
+	get := func(args GetPageErrorsArgs) PageErrors {
+		t.Helper()
+		res, err := s.GetPageErrors(context.Background(), &mcp.ServerRequest[*mcp.CallToolParamsFor[GetPageErrorsArgs]]{
+			Params: &mcp.CallToolParamsFor[GetPageErrorsArgs]{Arguments: args},
+		})
+		if err != nil || res.IsError {
+			t.Fatalf("GetPageErrors(%+v) = %v, %v", args, res, err)
+		}
+		return res.StructuredContent
+	}
+
+	got := get(GetPageErrorsArgs{Since: 1, Limit: 1})
+	if len(got.Errors) != 1 || got.Errors[0].Message != "third" || got.Omitted != 1 || got.Latest != 3 {
+		t.Errorf("since 1, limit 1 = %+v", got)
+	}
+	if text := got.String(); !strings.HasPrefix(text, "2 uncaught exceptions (latest seq 3), showing the last 1:\n#3 third") {
+		t.Errorf("text = %q", text)
+	}
+	if got := get(GetPageErrorsArgs{Clear: true}); len(got.Errors) != 3 {
+		t.Errorf("clear returned %d errors", len(got.Errors))
+	}
+	if got := get(GetPageErrorsArgs{}); len(got.Errors) != 0 || got.Latest != 3 {
+		t.Errorf("after clear = %+v", got)
+	}
+}
+
+func TestPageErrorsMiddleware(t *testing.T) {
+	s := &CDPBrowserServer{failOnPageError: true}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	clicked := func(throw bool) func(context.Context, *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+		return func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+			if throw {
+				s.pageErrors.add(PageError{Message: "TypeError: x is undefined", Stack: []StackFrame{{Function: "onclick", URL: "http://127.0.0.1/", Line: 3, Column: 9}}}, time.Now())
+			}
+			return &mcp.CallToolResultFor[SelectorMatch]{
+				Content:           []mcp.Content{&mcp.TextContent{Text: "Clicked element #go"}},
+				StructuredContent: SelectorMatch{Selector: "#go", Strategy: "css"},
+			}, nil
+		}
+	}
+	addTool(server, s, &mcp.Tool{Name: "click"}, clicked(true))
+	addTool(server, s, &mcp.Tool{Name: "click_button"}, clicked(false))
+	addTool(server, s, &mcp.Tool{Name: "get_page_errors"}, s.GetPageErrors)
+	server.AddReceivingMiddleware(s.toolErrorMiddleware, s.pageErrorsMiddleware)
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("CallTool(%s) error = %v", name, err)
+		}
+		return res
+	}
+
+	res := call("click", map[string]any{"selector": "#go"})
+	if !res.IsError {
+		t.Fatal("click that threw succeeded")
+	}
+	text := res.Content[0].(*mcp.TextContent).Text
+	for _, want := range []string{"uncaught exception during the action: TypeError: x is undefined", "at onclick (http://127.0.0.1/:3:9)", "Error code: PAGE_ERROR"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("click text %q does not contain %q", text, want)
+		}
+	}
+	if got := res.Content[1].(*mcp.TextContent).Text; got != "Clicked element #go" {
+		t.Errorf("click kept text %q", got)
+	}
+
+	// Errors thrown before the call do not fail it, nor do they fail tools
+	// other than interactions.
+	if res := call("click_button", map[string]any{"selector": "#go"}); res.IsError {
+		t.Errorf("click_button failed: %v", res.Content)
+	}
+	s.pageErrors.add(PageError{Message: "later"}, time.Now())
+	if res := call("get_page_errors", map[string]any{}); res.IsError {
+		t.Errorf("get_page_errors failed: %v", res.Content)
+	}
+
+	s.failOnPageError = false
+	if res := call("click", map[string]any{"selector": "#go"}); res.IsError {
+		t.Errorf("click failed without -fail-on-page-error: %v", res.Content)
+	}
+}
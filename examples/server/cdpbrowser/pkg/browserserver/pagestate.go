@@ -0,0 +1,319 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// pageStateCaptureJS captures the page state compared by
+// compare_page_state. The DOM is summarised by two 53-bit hashes (cyrb53):
+// one over elements, attributes, and text, and one over the text alone, so
+// that a comparison can tell a text change from a markup change. Overlays
+// drawn by the server are skipped. Hidden inputs are left out because they
+// often hold per-load tokens, and password values are reduced to their
+// length.
+const pageStateCaptureJS = `(function() {
+	const hash = (str) => {
+		let h1 = 0xdeadbeef, h2 = 0x41c6ce57;
+		for (let i = 0; i < str.length; i++) {
+			const ch = str.charCodeAt(i);
+			h1 = Math.imul(h1 ^ ch, 2654435761);
+			h2 = Math.imul(h2 ^ ch, 1597334677);
+		}
+		h1 = Math.imul(h1 ^ (h1 >>> 16), 2246822507) ^ Math.imul(h2 ^ (h2 >>> 13), 3266489909);
+		h2 = Math.imul(h2 ^ (h2 >>> 16), 2246822507) ^ Math.imul(h1 ^ (h1 >>> 13), 3266489909);
+		return (4294967296 * (2097151 & h2) + (h1 >>> 0)).toString(16).padStart(14, '0');
+	};
+	const ours = el => el.id.startsWith('__cdpbrowser');
+	const markup = [];
+	const text = [];
+	let elements = 0;
+	const walk = node => {
+		for (let n = node.firstChild; n; n = n.nextSibling) {
+			if (n.nodeType === 1) {
+				if (ours(n)) {
+					continue;
+				}
+				elements++;
+				let open = '<' + n.tagName;
+				for (const a of n.attributes) {
+					open += ' ' + a.name + '="' + a.value + '"';
+				}
+				markup.push(open + '>');
+				walk(n);
+				if (n.shadowRoot) {
+					walk(n.shadowRoot);
+				}
+				markup.push('</' + n.tagName + '>');
+			} else if (n.nodeType === 3) {
+				const t = n.data.replace(/\s+/g, ' ').trim();
+				if (t) {
+					markup.push(t);
+					text.push(t);
+				}
+			}
+		}
+	};
+	walk(document);
+
+	const fields = [];
+	const skipped = ['hidden', 'submit', 'button', 'reset', 'image'];
+	document.querySelectorAll('input, select, textarea').forEach((el, i) => {
+		const type = (el.type || '').toLowerCase();
+		if (ours(el) || skipped.includes(type)) {
+			return;
+		}
+		const tag = el.tagName.toLowerCase();
+		let field = el.id ? '#' + el.id : el.name ? tag + '[name="' + el.name + '"]' : tag + ' #' + (i + 1);
+		let value;
+		if (type === 'checkbox' || type === 'radio') {
+			if (!el.id && el.name) {
+				field += '[value="' + el.value + '"]';
+			}
+			value = el.checked ? 'checked' : 'unchecked';
+		} else if (tag === 'select') {
+			value = Array.from(el.selectedOptions).map(o => o.value).join(', ');
+		} else if (type === 'password') {
+			value = el.value ? '(' + el.value.length + ' characters)' : '';
+		} else if (type === 'file') {
+			value = Array.from(el.files || []).map(f => f.name).join(', ');
+		} else {
+			value = el.value;
+		}
+		fields.push({ field: field, value: value });
+	});
+
+	return {
+		url: location.href,
+		title: document.title,
+		scroll_x: Math.round(window.scrollX),
+		scroll_y: Math.round(window.scrollY),
+		fields: fields,
+		elements: elements,
+		dom_hash: hash(markup.join('\n')),
+		text_hash: hash(text.join('\n'))
+	};
+})()`
+
+// Page state aspects that compare_page_state can ignore.
+const (
+	pageAspectURL    = "url"
+	pageAspectTitle  = "title"
+	pageAspectScroll = "scroll"
+	pageAspectFields = "fields"
+	pageAspectDOM    = "dom"
+)
+
+var pageAspects = []string{pageAspectURL, pageAspectTitle, pageAspectScroll, pageAspectFields, pageAspectDOM}
+
+// FormFieldValue is the value of a form field in a page state.
+type FormFieldValue struct {
+	Field string `json:"field" jsonschema:"The field's #id, name, or position"`
+	Value string `json:"value" jsonschema:"The value; checked or unchecked for checkboxes and radio buttons, and only the length for passwords"`
+}
+
+// PageState is a checkpoint of the page taken by save_page_state.
+type PageState struct {
+	Name     string           `json:"name,omitempty"`
+	SavedAt  string           `json:"saved_at,omitempty"`
+	URL      string           `json:"url"`
+	Title    string           `json:"title"`
+	ScrollX  int              `json:"scroll_x"`
+	ScrollY  int              `json:"scroll_y"`
+	Fields   []FormFieldValue `json:"fields,omitempty" jsonschema:"Values of the visible form fields, in document order"`
+	Elements int              `json:"elements" jsonschema:"Number of elements in the document"`
+	DOMHash  string           `json:"dom_hash" jsonschema:"Hash of the document's elements, attributes, and text"`
+	TextHash string           `json:"text_hash" jsonschema:"Hash of the document's text"`
+}
+
+// String summarises p in a line.
+func (p *PageState) String() string {
+	return fmt.Sprintf("%s (%q), scrolled to (%d, %d), %d form fields, %d elements, DOM hash %s",
+		p.URL, p.Title, p.ScrollX, p.ScrollY, len(p.Fields), p.Elements, p.DOMHash)
+}
+
+// comparePageStates describes how current differs from saved, leaving out
+// the aspects in ignore.
+func comparePageStates(saved, current *PageState, ignore []string) []string {
+	var diffs []string
+	check := func(aspect string) bool { return !slices.Contains(ignore, aspect) }
+	if check(pageAspectURL) && saved.URL != current.URL {
+		diffs = append(diffs, fmt.Sprintf("URL changed: %s -> %s", saved.URL, current.URL))
+	}
+	if check(pageAspectTitle) && saved.Title != current.Title {
+		diffs = append(diffs, fmt.Sprintf("title changed: %q -> %q", saved.Title, current.Title))
+	}
+	if check(pageAspectScroll) && (saved.ScrollX != current.ScrollX || saved.ScrollY != current.ScrollY) {
+		diffs = append(diffs, fmt.Sprintf("scroll position changed: (%d, %d) -> (%d, %d)", saved.ScrollX, saved.ScrollY, current.ScrollX, current.ScrollY))
+	}
+	if check(pageAspectFields) {
+		before := make(map[string]string)
+		for _, f := range saved.Fields {
+			before[f.Field] = f.Value
+		}
+		now := make(map[string]bool)
+		for _, f := range current.Fields {
+			now[f.Field] = true
+			old, ok := before[f.Field]
+			switch {
+			case !ok:
+				diffs = append(diffs, fmt.Sprintf("field %s added: %q", f.Field, f.Value))
+			case old != f.Value:
+				diffs = append(diffs, fmt.Sprintf("field %s changed: %q -> %q", f.Field, old, f.Value))
+			}
+		}
+		for _, f := range saved.Fields {
+			if !now[f.Field] {
+				diffs = append(diffs, fmt.Sprintf("field %s removed", f.Field))
+			}
+		}
+	}
+	if check(pageAspectDOM) && saved.DOMHash != current.DOMHash {
+		what := "markup changed, text unchanged"
+		if saved.TextHash != current.TextHash {
+			what = "text changed"
+		}
+		if saved.Elements != current.Elements {
+			what += fmt.Sprintf(", %d -> %d elements", saved.Elements, current.Elements)
+		}
+		diffs = append(diffs, "DOM changed: "+what)
+	}
+	return diffs
+}
+
+// pageStateStore holds the page states saved by save_page_state.
+type pageStateStore struct {
+	mu    sync.Mutex
+	last  *PageState
+	named map[string]*PageState
+}
+
+// save stores state under its name and makes it the most recent one.
+func (st *pageStateStore) save(state *PageState) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.named == nil {
+		st.named = make(map[string]*PageState)
+	}
+	st.named[state.Name] = state
+	st.last = state
+}
+
+// get returns the state saved under name, or the most recent one if name is
+// empty.
+func (st *pageStateStore) get(name string) (*PageState, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if name == "" {
+		if st.last == nil {
+			return nil, fmt.Errorf("no saved page state; call save_page_state first")
+		}
+		return st.last, nil
+	}
+	state, ok := st.named[name]
+	if !ok {
+		return nil, fmt.Errorf("no page state saved as %q", name)
+	}
+	return state, nil
+}
+
+// capturePageState captures the current page state.
+func (s *CDPBrowserServer) capturePageState(ctx context.Context) (*PageState, error) {
+	var state PageState
+	if err := s.backend().Evaluate(ctx, pageStateCaptureJS, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+type SavePageStateArgs struct {
+	Name string `json:"name,omitempty" jsonschema:"Name to save the state under, replacing any earlier state with that name (default: default)"`
+}
+
+// SavePageState tool - checkpoints the URL, scroll position, form values,
+// and DOM of the page
+func (s *CDPBrowserServer) SavePageState(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[SavePageStateArgs]]) (*mcp.CallToolResultFor[PageState], error) {
+	state, err := s.capturePageState(ctx)
+	if err != nil {
+		return &mcp.CallToolResultFor[PageState]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error capturing page state: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	state.Name = req.Params.Arguments.Name
+	if state.Name == "" {
+		state.Name = "default"
+	}
+	state.SavedAt = time.Now().UTC().Format(time.RFC3339)
+	s.pageStates.save(state)
+
+	return &mcp.CallToolResultFor[PageState]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Saved page state %q: %s", state.Name, state)},
+		},
+		StructuredContent: *state,
+	}, nil
+}
+
+type ComparePageStateArgs struct {
+	Name   string   `json:"name,omitempty" jsonschema:"Name of the state to compare with (default: the most recently saved)"`
+	Ignore []string `json:"ignore,omitempty" jsonschema:"Aspects to leave out of the comparison: url, title, scroll, fields, dom"`
+}
+
+// PageStateComparison is the structured result of compare_page_state.
+type PageStateComparison struct {
+	Against     string     `json:"against" jsonschema:"Name of the saved state"`
+	Matches     bool       `json:"matches" jsonschema:"Whether the page is in the saved state, apart from ignored aspects"`
+	Differences []string   `json:"differences,omitempty"`
+	Current     *PageState `json:"current"`
+}
+
+// ComparePageState tool - reports how the page differs from a state saved
+// with save_page_state
+func (s *CDPBrowserServer) ComparePageState(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ComparePageStateArgs]]) (*mcp.CallToolResultFor[PageStateComparison], error) {
+	args := req.Params.Arguments
+	fail := func(err error) (*mcp.CallToolResultFor[PageStateComparison], error) {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[PageStateComparison]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error comparing page state: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	for _, aspect := range args.Ignore {
+		if !slices.Contains(pageAspects, aspect) {
+			return fail(invalidArgumentError{fmt.Errorf("unknown aspect %q in ignore (want %s)", aspect, strings.Join(pageAspects, ", "))})
+		}
+	}
+	saved, err := s.pageStates.get(args.Name)
+	if err != nil {
+		return fail(err)
+	}
+	current, err := s.capturePageState(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	result := PageStateComparison{Against: saved.Name, Current: current}
+	result.Differences = comparePageStates(saved, current, args.Ignore)
+	result.Matches = len(result.Differences) == 0
+	text := fmt.Sprintf("Page matches saved state %q", saved.Name)
+	if !result.Matches {
+		text = fmt.Sprintf("Page differs from saved state %q:\n%s", saved.Name, strings.Join(result.Differences, "\n"))
+	}
+	return &mcp.CallToolResultFor[PageStateComparison]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+		StructuredContent: result,
+	}, nil
+}
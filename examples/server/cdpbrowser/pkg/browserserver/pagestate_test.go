@@ -0,0 +1,70 @@
+package browserserver
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestComparePageStates(t *testing.T) {
+	saved := &PageState{
+		URL: "https://example.com/form", Title: "Form", ScrollY: 0,
+		Fields: []FormFieldValue{
+			{Field: "#username", Value: "alice"},
+			{Field: "#terms", Value: "unchecked"},
+			{Field: `input[name="color"][value="red"]`, Value: "unchecked"},
+		},
+		Elements: 20, DOMHash: "00000000000001", TextHash: "00000000000002",
+	}
+	same := *saved
+	if diffs := comparePageStates(saved, &same, nil); len(diffs) != 0 {
+		t.Errorf("identical states differ: %q", diffs)
+	}
+
+	current := &PageState{
+		URL: "https://example.com/form?sent=1", Title: "Form", ScrollY: 640,
+		Fields: []FormFieldValue{
+			{Field: "#username", Value: "bob"},
+			{Field: `input[name="color"][value="red"]`, Value: "unchecked"},
+			{Field: "#email", Value: ""},
+		},
+		Elements: 22, DOMHash: "00000000000003", TextHash: "00000000000002",
+	}
+	want := []string{
+		"URL changed: https://example.com/form -> https://example.com/form?sent=1",
+		"scroll position changed: (0, 0) -> (0, 640)",
+		`field #username changed: "alice" -> "bob"`,
+		`field #email added: ""`,
+		"field #terms removed",
+		"DOM changed: markup changed, text unchanged, 20 -> 22 elements",
+	}
+	if diffs := comparePageStates(saved, current, nil); !slices.Equal(diffs, want) {
+		t.Errorf("differences:\n%q\nwant:\n%q", diffs, want)
+	}
+	if diffs := comparePageStates(saved, current, []string{"url", "scroll", "fields"}); !slices.Equal(diffs, want[5:]) {
+		t.Errorf("differences ignoring url, scroll, and fields = %q", diffs)
+	}
+
+	current.TextHash = "00000000000004"
+	current.Elements = 20
+	if diffs := comparePageStates(saved, current, []string{"url", "scroll", "fields"}); !slices.Equal(diffs, []string{"DOM changed: text changed"}) {
+		t.Errorf("differences after a text change = %q", diffs)
+	}
+}
+
+func TestPageStateStore(t *testing.T) {
+	var st pageStateStore
+	if _, err := st.get(""); err == nil {
+		t.Error("get on an empty store succeeded")
+	}
+	st.save(&PageState{Name: "start", URL: "a"})
+	st.save(&PageState{Name: "cart", URL: "b"})
+	if got, err := st.get(""); err != nil || got.Name != "cart" {
+		t.Errorf("get(\"\") = %v, %v; want the most recent state", got, err)
+	}
+	if got, err := st.get("start"); err != nil || got.URL != "a" {
+		t.Errorf("get(start) = %v, %v", got, err)
+	}
+	if _, err := st.get("missing"); err == nil {
+		t.Error("get(missing) succeeded")
+	}
+}
@@ -0,0 +1,204 @@
+package browserserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultPluginTimeout bounds a subprocess tool that sets no timeout.
+const defaultPluginTimeout = time.Minute
+
+// customTool is a tool added with RegisterTool.
+type customTool struct {
+	name string
+	// add defines the tool in an MCP server of s.
+	add func(srv *mcp.Server, s *CDPBrowserServer)
+}
+
+var (
+	customToolsMu sync.RWMutex
+	customTools   []customTool
+)
+
+// RegisterTool adds a site-specific tool to the servers created afterwards,
+// such as the one [Main] runs, replacing any custom tool previously
+// registered with the same name. A custom tool with the name of a built-in
+// tool replaces the built-in tool.
+//
+// The server calls newHandler once per MCP server to bind the tool to the
+// browser. Custom tools go through the same middleware as the built-in ones,
+// so they are counted in get_tool_stats, recorded, rate limited, and usable
+// in batches and macros. They belong to the standard and admin tool
+// profiles, and need the chrome backend: handlers drive the page through
+// [CDPBrowserServer.BrowserContext].
+func RegisterTool[In, Out any](t *mcp.Tool, newHandler func(s *CDPBrowserServer) mcp.ToolHandlerFor[In, Out]) {
+	if t.Name == "" {
+		panic("browserserver: RegisterTool with an unnamed tool")
+	}
+	ct := customTool{
+		name: t.Name,
+		add: func(srv *mcp.Server, s *CDPBrowserServer) {
+			// addTool annotates the tool, so each server needs its own copy.
+			tool := *t
+			addTool(srv, s, &tool, newHandler(s))
+		},
+	}
+	customToolsMu.Lock()
+	defer customToolsMu.Unlock()
+	for i, old := range customTools {
+		if old.name == t.Name {
+			customTools[i] = ct
+			return
+		}
+	}
+	customTools = append(customTools, ct)
+}
+
+// addCustomTools defines the tools added with RegisterTool in srv.
+func (s *CDPBrowserServer) addCustomTools(srv *mcp.Server) {
+	customToolsMu.RLock()
+	defer customToolsMu.RUnlock()
+	for _, ct := range customTools {
+		if _, ok := s.tools[ct.name]; ok {
+			log.Printf("Custom tool %s replaces the built-in tool", ct.name)
+		}
+		ct.add(srv, s)
+	}
+}
+
+// BrowserContext returns the chromedp context of the page the server
+// drives, for custom tools to run chromedp actions in. It is nil until the
+// browser is launched, and with backends other than chrome.
+func (s *CDPBrowserServer) BrowserContext() context.Context {
+	if !s.usesCDP() {
+		return nil
+	}
+	return s.ctx
+}
+
+// Backend returns the browser backend the server drives.
+func (s *CDPBrowserServer) Backend() Browser {
+	return s.backend()
+}
+
+// CheckURL returns an error if the navigation policy blocks url. Custom
+// tools that navigate should check their URLs first.
+func (s *CDPBrowserServer) CheckURL(url string) error {
+	return s.policy.check(url)
+}
+
+// subprocessTool is a custom tool implemented by an external command, as
+// listed in a -tool-plugins file.
+type subprocessTool struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	InputSchema *jsonschema.Schema `json:"input_schema,omitempty"`
+	// Command is the program and its arguments.
+	Command []string `json:"command"`
+	// Timeout is how long the command may run, as a Go duration (default: 1m).
+	Timeout string `json:"timeout,omitempty"`
+
+	timeout time.Duration
+}
+
+// loadSubprocessTools reads a -tool-plugins file.
+func loadSubprocessTools(path string) ([]*subprocessTool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool plugins: %v", err)
+	}
+	var file struct {
+		Tools []*subprocessTool `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tool plugins %s: %v", path, err)
+	}
+	for _, t := range file.Tools {
+		if t.Name == "" || len(t.Command) == 0 {
+			return nil, fmt.Errorf("tool plugin %q in %s needs a name and a command", t.Name, path)
+		}
+		t.timeout = defaultPluginTimeout
+		if t.Timeout != "" {
+			if t.timeout, err = time.ParseDuration(t.Timeout); err != nil || t.timeout <= 0 {
+				return nil, fmt.Errorf("tool plugin %s has an invalid timeout %q", t.Name, t.Timeout)
+			}
+		}
+		if t.InputSchema == nil {
+			t.InputSchema = &jsonschema.Schema{Type: "object"}
+		}
+	}
+	return file.Tools, nil
+}
+
+// register adds t with RegisterTool.
+func (t *subprocessTool) register() {
+	RegisterTool(&mcp.Tool{Name: t.Name, Description: t.Description, InputSchema: t.InputSchema},
+		func(s *CDPBrowserServer) mcp.ToolHandlerFor[map[string]any, any] {
+			return func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) (*mcp.CallToolResultFor[any], error) {
+				out, err := t.run(ctx, s, req.Params.Arguments)
+				if err != nil {
+					noteToolError(ctx, err, "")
+					return &mcp.CallToolResultFor[any]{
+						Content: []mcp.Content{
+							&mcp.TextContent{Text: fmt.Sprintf("Error running %s: %v", t.Name, err)},
+						},
+						IsError: true,
+					}, nil
+				}
+				return &mcp.CallToolResultFor[any]{
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: out},
+					},
+				}, nil
+			}
+		})
+}
+
+// run runs the command with the JSON arguments on its standard input and
+// returns its standard output. The command finds the browser through the
+// CDPBROWSER_DEVTOOLS_URL environment variable, and the page the server
+// last navigated to in CDPBROWSER_PAGE_URL.
+func (t *subprocessTool) run(ctx context.Context, s *CDPBrowserServer, args map[string]any) (string, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	input, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, t.Command[0], t.Command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"CDPBROWSER_TOOL="+t.Name,
+		"CDPBROWSER_DEVTOOLS_URL="+s.wsURL,
+		"CDPBROWSER_PAGE_URL="+s.routes.current(),
+	)
+	cmd.Stdin = bytes.NewReader(input)
+	// Don't wait for children of the command that keep its output open.
+	cmd.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("timed out after %v", t.timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,149 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// unregisterTool removes a tool added with RegisterTool.
+func unregisterTool(name string) {
+	customToolsMu.Lock()
+	defer customToolsMu.Unlock()
+	customTools = slices.DeleteFunc(customTools, func(ct customTool) bool { return ct.name == name })
+}
+
+// connectCustomTools serves the tools of s, including the custom ones, to a
+// new client session.
+func connectCustomTools(t *testing.T, s *CDPBrowserServer) *mcp.ClientSession {
+	t.Helper()
+	server := newMCPServer(s)
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cs.Close()
+		serverSession.Wait()
+	})
+	return cs
+}
+
+type openTicketArgs struct {
+	Project string `json:"project"`
+}
+
+func TestRegisterTool(t *testing.T) {
+	RegisterTool(&mcp.Tool{Name: "open_ticket", Description: "Open a ticket"}, func(s *CDPBrowserServer) mcp.ToolHandlerFor[openTicketArgs, struct{}] {
+		return func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[openTicketArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+			url := "https://" + req.Params.Arguments.Project + ".example.com/new"
+			if err := s.CheckURL(url); err != nil {
+				return nil, err
+			}
+			return &mcp.CallToolResultFor[struct{}]{
+				Content: []mcp.Content{&mcp.TextContent{Text: "Opened " + url}},
+			}, nil
+		}
+	})
+	defer unregisterTool("open_ticket")
+
+	s := &CDPBrowserServer{stats: newToolStats(), policy: &navigationPolicy{DenyDomains: []string{"secret.example.com"}}}
+	cs := connectCustomTools(t, s)
+	if text := resultText(callTool(t, cs, "open_ticket", map[string]any{"project": "web"})); text != "Opened https://web.example.com/new" {
+		t.Errorf("open_ticket result = %q", text)
+	}
+	if text := callToolError(t, cs, "open_ticket", map[string]any{"project": "secret"}); !strings.Contains(text, "policy violation") {
+		t.Errorf("open_ticket on a blocked domain = %q", text)
+	}
+	if !s.hasTool("open_ticket") {
+		t.Error("custom tool cannot be used in batches and macros")
+	}
+	s.stats.mu.Lock()
+	calls := s.stats.byTool["open_ticket"].calls
+	s.stats.mu.Unlock()
+	if calls != 2 {
+		t.Errorf("open_ticket calls counted = %d, want 2", calls)
+	}
+
+	// Custom tools are not part of the readonly profile.
+	s = &CDPBrowserServer{stats: newToolStats(), policy: &navigationPolicy{}}
+	s.toolset.config.Profile = toolProfileReadonly
+	connectCustomTools(t, s)
+	if s.toolset.exposes("open_ticket") {
+		t.Error("readonly profile exposes a custom tool")
+	}
+}
+
+func TestSubprocessTools(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+	dir := t.TempDir()
+	script := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	echo := script("echo.sh", `echo "$CDPBROWSER_TOOL at $CDPBROWSER_PAGE_URL: $(cat)"`)
+	fail := script("fail.sh", `echo "no such project" >&2; exit 3`)
+	slow := script("slow.sh", `sleep 5`)
+	plugins := filepath.Join(dir, "plugins.json")
+	if err := os.WriteFile(plugins, []byte(fmt.Sprintf(`{"tools": [
+		{"name": "echo_args", "description": "Echo the arguments", "command": [%q]},
+		{"name": "failing_tool", "command": [%q]},
+		{"name": "slow_tool", "command": [%q], "timeout": "100ms"}
+	]}`, echo, fail, slow)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tools, err := loadSubprocessTools(plugins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range tools {
+		tool.register()
+		defer unregisterTool(tool.Name)
+	}
+	s := &CDPBrowserServer{stats: newToolStats(), policy: &navigationPolicy{}}
+	s.routes.navigated("https://example.com/")
+	cs := connectCustomTools(t, s)
+
+	res := callTool(t, cs, "echo_args", map[string]any{"project": "web"})
+	if text := resultText(res); text != "echo_args at https://example.com/: {\"project\":\"web\"}\n" {
+		t.Errorf("echo_args result = %q", text)
+	}
+	if text := callToolError(t, cs, "failing_tool", nil); !strings.Contains(text, "exit status 3: no such project") {
+		t.Errorf("failing_tool result = %q", text)
+	}
+	if text := callToolError(t, cs, "slow_tool", nil); !strings.Contains(text, "timed out after 100ms") {
+		t.Errorf("slow_tool result = %q", text)
+	}
+
+	for _, bad := range []string{
+		`{"tools": [{"name": "no_command"}]}`,
+		`{"tools": [{"name": "bad_timeout", "command": ["true"], "timeout": "soon"}]}`,
+		`{"tools": `,
+	} {
+		if err := os.WriteFile(plugins, []byte(bad), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadSubprocessTools(plugins); err == nil {
+			t.Errorf("loadSubprocessTools(%s) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// navigationPolicy restricts which domains the browser may load pages and
+// sub-resources from.
+//
+// A pattern such as "example.com" matches that domain and all of its
+// subdomains; "*.example.com" matches only the subdomains. Deny patterns take
+// precedence over allow patterns. If any allow patterns are configured, hosts
+// that match none of them are blocked.
+type navigationPolicy struct {
+	AllowDomains []string `json:"allow_domains"`
+	DenyDomains  []string `json:"deny_domains"`
+}
+
+// loadNavigationPolicy reads a JSON policy file.
+func loadNavigationPolicy(path string) (*navigationPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %v", err)
+	}
+	var p navigationPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %v", path, err)
+	}
+	return &p, nil
+}
+
+// enabled reports whether the policy restricts anything.
+func (p *navigationPolicy) enabled() bool {
+	return p != nil && (len(p.AllowDomains) > 0 || len(p.DenyDomains) > 0)
+}
+
+// policyViolationError is returned when a URL is blocked by the navigation
+// policy.
+type policyViolationError struct {
+	URL    string
+	Reason string
+}
+
+func (e *policyViolationError) Error() string {
+	return fmt.Sprintf("policy violation: %s is blocked: %s", e.URL, e.Reason)
+}
+
+// check returns a *policyViolationError if rawURL may not be loaded.
+// Only http(s) and ws(s) URLs on allowed hosts pass, along with
+// about:blank, data: URLs, and blob: URLs whose origin passes; file:,
+// chrome:, javascript:, and other schemes would escape the host checks.
+func (p *navigationPolicy) check(rawURL string) error {
+	if !p.enabled() {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return &policyViolationError{URL: rawURL, Reason: fmt.Sprintf("invalid URL: %v", err)}
+	}
+	switch scheme := strings.ToLower(u.Scheme); scheme {
+	case "http", "https", "ws", "wss":
+	case "about":
+		if u.Opaque == "blank" {
+			return nil
+		}
+		return &policyViolationError{URL: rawURL, Reason: "only about:blank is allowed"}
+	case "data":
+		return nil
+	case "blob":
+		// blob:https://example.com/uuid belongs to the origin it names
+		if err := p.check(u.Opaque); err != nil {
+			return &policyViolationError{URL: rawURL, Reason: err.(*policyViolationError).Reason}
+		}
+		return nil
+	default:
+		return &policyViolationError{URL: rawURL, Reason: fmt.Sprintf("scheme %q is not allowed", scheme)}
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return &policyViolationError{URL: rawURL, Reason: "the URL has no host"}
+	}
+	for _, pattern := range p.DenyDomains {
+		if domainMatches(pattern, host) {
+			return &policyViolationError{URL: rawURL, Reason: fmt.Sprintf("domain %s matches denylist entry %q", host, pattern)}
+		}
+	}
+	if len(p.AllowDomains) == 0 {
+		return nil
+	}
+	for _, pattern := range p.AllowDomains {
+		if domainMatches(pattern, host) {
+			return nil
+		}
+	}
+	return &policyViolationError{URL: rawURL, Reason: fmt.Sprintf("domain %s is not in the allowlist", host)}
+}
+
+// domainMatches reports whether host matches a policy pattern.
+func domainMatches(pattern, host string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return false
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// splitDomains parses a comma-separated domain list flag.
+func splitDomains(list string) []string {
+	var domains []string
+	for _, d := range strings.Split(list, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// enablePolicyInterception pauses every request the page makes through the
+// Fetch domain and fails those the navigation policy blocks, so that links,
+// redirects, and sub-resource or fetch() requests cannot escape the policy.
+// It applies to the tab of tabCtx, a chromedp context such as s.ctx.
+func (s *CDPBrowserServer) enablePolicyInterception(tabCtx context.Context) error {
+	if !s.policy.enabled() {
+		return nil
+	}
+
+	chromedp.ListenTarget(tabCtx, func(ev interface{}) {
+		paused, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+		// The listener must not block, so resolve the request in a goroutine.
+		go func() {
+			c := chromedp.FromContext(tabCtx)
+			ctx := cdp.WithExecutor(tabCtx, c.Target)
+			var err error
+			if violation := s.policy.check(paused.Request.URL); violation != nil {
+				log.Printf("Navigation policy: %v", violation)
+				err = fetch.FailRequest(paused.RequestID, network.ErrorReasonBlockedByClient).Do(ctx)
+			} else {
+				err = fetch.ContinueRequest(paused.RequestID).Do(ctx)
+			}
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Navigation policy: failed to resolve paused request %s: %v", paused.Request.URL, err)
+			}
+		}()
+	})
+
+	if err := chromedp.Run(tabCtx, fetch.Enable()); err != nil {
+		return fmt.Errorf("failed to enable request interception: %v", err)
+	}
+	return nil
+}
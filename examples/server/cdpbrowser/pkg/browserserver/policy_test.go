@@ -0,0 +1,65 @@
+package browserserver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNavigationPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *navigationPolicy
+		url     string
+		blocked bool
+	}{
+		{"nil policy", nil, "https://anything.test/", false},
+		{"empty policy", &navigationPolicy{}, "https://anything.test/", false},
+		{"allowed exact", &navigationPolicy{AllowDomains: []string{"example.com"}}, "https://example.com/a", false},
+		{"allowed subdomain", &navigationPolicy{AllowDomains: []string{"example.com"}}, "https://www.example.com/", false},
+		{"not allowed", &navigationPolicy{AllowDomains: []string{"example.com"}}, "https://evil.test/", true},
+		{"suffix is not a subdomain", &navigationPolicy{AllowDomains: []string{"example.com"}}, "https://notexample.com/", true},
+		{"wildcard excludes apex", &navigationPolicy{AllowDomains: []string{"*.example.com"}}, "https://example.com/", true},
+		{"wildcard matches subdomain", &navigationPolicy{AllowDomains: []string{"*.example.com"}}, "https://a.b.example.com/", false},
+		{"denied", &navigationPolicy{DenyDomains: []string{"ads.test"}}, "https://cdn.ads.test/x.js", true},
+		{"deny beats allow", &navigationPolicy{AllowDomains: []string{"example.com"}, DenyDomains: []string{"admin.example.com"}}, "https://admin.example.com/", true},
+		{"case insensitive", &navigationPolicy{AllowDomains: []string{"Example.COM"}}, "https://EXAMPLE.com/", false},
+		{"port ignored", &navigationPolicy{AllowDomains: []string{"localhost"}}, "http://localhost:8080/", false},
+		{"hostless allowed", &navigationPolicy{AllowDomains: []string{"example.com"}}, "about:blank", false},
+		{"data allowed", &navigationPolicy{AllowDomains: []string{"example.com"}}, "data:text/html,hi", false},
+		{"blob of allowed origin", &navigationPolicy{AllowDomains: []string{"example.com"}}, "blob:https://example.com/0b7c", false},
+		{"blob of other origin", &navigationPolicy{AllowDomains: []string{"example.com"}}, "blob:https://evil.test/0b7c", true},
+		{"file blocked", &navigationPolicy{AllowDomains: []string{"example.com"}}, "file:///etc/passwd", true},
+		{"file blocked by denylist policy", &navigationPolicy{DenyDomains: []string{"ads.test"}}, "file:///etc/passwd", true},
+		{"chrome blocked", &navigationPolicy{AllowDomains: []string{"example.com"}}, "chrome://settings", true},
+		{"javascript blocked", &navigationPolicy{AllowDomains: []string{"example.com"}}, "javascript:alert(1)", true},
+		{"view-source blocked", &navigationPolicy{AllowDomains: []string{"example.com"}}, "view-source:file:///etc/passwd", true},
+		{"other about page blocked", &navigationPolicy{AllowDomains: []string{"example.com"}}, "about:config", true},
+		{"hostless http blocked", &navigationPolicy{AllowDomains: []string{"example.com"}}, "http:///path", true},
+		{"websocket allowed", &navigationPolicy{AllowDomains: []string{"example.com"}}, "wss://ws.example.com/feed", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.check(tt.url)
+			if got := err != nil; got != tt.blocked {
+				t.Fatalf("check(%q) = %v, want blocked=%t", tt.url, err, tt.blocked)
+			}
+			var violation *policyViolationError
+			if err != nil && !errors.As(err, &violation) {
+				t.Errorf("check(%q) error type = %T, want *policyViolationError", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestSplitDomains(t *testing.T) {
+	got := splitDomains(" example.com, ,*.test.org,")
+	want := []string{"example.com", "*.test.org"}
+	if len(got) != len(want) {
+		t.Fatalf("splitDomains() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("splitDomains()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
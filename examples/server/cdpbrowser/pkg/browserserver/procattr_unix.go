@@ -0,0 +1,12 @@
+//go:build unix
+
+package browserserver
+
+import "syscall"
+
+// detachedProcAttr starts a process in its own process group, so that
+// signals sent to the server's group, such as a terminal's Ctrl-C, do not
+// reach it.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,442 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// profileManifest is the file describing a saved profile.
+	profileManifest = "profile.json"
+	// profileCookies holds the cookies of a saved profile as reported by
+	// CDP, including session cookies, which Chrome does not write to disk.
+	profileCookies = "cookies.json"
+	// profileUserData is the copy of the user data directory.
+	profileUserData = "user-data"
+)
+
+// profileNamePattern restricts profile names to ones that are safe to use as
+// directory names.
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// profileSkipped are the user data directory entries left out of a saved
+// profile: the locks of the running browser and caches that only cost space.
+var profileSkipped = map[string]bool{
+	"SingletonLock":     true,
+	"SingletonSocket":   true,
+	"SingletonCookie":   true,
+	"lockfile":          true,
+	"Cache":             true,
+	"Code Cache":        true,
+	"GPUCache":          true,
+	"GrShaderCache":     true,
+	"GraphiteDawnCache": true,
+	"ShaderCache":       true,
+	"DawnCache":         true,
+	"Crashpad":          true,
+}
+
+// chromeUserDataDir returns the default user data directory of the Chrome
+// instance the server launches.
+func chromeUserDataDir() string {
+	if runtime.GOOS == "windows" {
+		return "C:\\temp\\chrome-remote-profile"
+	}
+	return "/tmp/chrome-remote-profile"
+}
+
+// defaultProfilesDir returns the directory profiles are saved in when the
+// server was not given -profiles-dir.
+func defaultProfilesDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "cdpbrowser", "profiles")
+}
+
+// checkProfileName reports whether name can be used for a profile.
+func checkProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-', starting with a letter or digit", name)
+	}
+	return nil
+}
+
+// ProfileInfo describes a saved browser profile.
+type ProfileInfo struct {
+	Name    string `json:"name"`
+	SavedAt string `json:"saved_at" jsonschema:"When the profile was saved, in RFC 3339 format"`
+	URL     string `json:"url,omitempty" jsonschema:"Page that was open when the profile was saved"`
+	Cookies int    `json:"cookies" jsonschema:"Number of cookies in the profile"`
+}
+
+// String formats p for the text results of the profile tools.
+func (p *ProfileInfo) String() string {
+	s := fmt.Sprintf("%s (saved %s, %d cookies", p.Name, p.SavedAt, p.Cookies)
+	if p.URL != "" {
+		s += ", at " + p.URL
+	}
+	return s + ")"
+}
+
+// copyUserData copies the user data directory src to dst, leaving out the
+// entries in profileSkipped. Files that disappear while being copied, as
+// temporary files of the running browser do, are ignored.
+func copyUserData(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path != src {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if profileSkipped[d.Name()] && path != src {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0o700)
+		case d.Type().IsRegular():
+			if err := copyFile(path, target); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		// Symlinks and other special files are not part of the browser state.
+		return nil
+	})
+}
+
+// copyFile copies the regular file src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeProfile saves the user data directory userData and cookies under
+// dir/info.Name. The profile is assembled next to its final location and
+// then moved into place, so that a failed save leaves any previous profile
+// with that name intact.
+func writeProfile(dir, userData string, info *ProfileInfo, cookies []*network.Cookie) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(dir, "."+info.Name+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := copyUserData(userData, filepath.Join(tmp, profileUserData)); err != nil {
+		return fmt.Errorf("copying %s: %v", userData, err)
+	}
+	if err := writeJSONFile(filepath.Join(tmp, profileCookies), cookies); err != nil {
+		return err
+	}
+	if err := writeJSONFile(filepath.Join(tmp, profileManifest), info); err != nil {
+		return err
+	}
+
+	final := filepath.Join(dir, info.Name)
+	if err := os.RemoveAll(final); err != nil {
+		return err
+	}
+	return os.Rename(tmp, final)
+}
+
+// writeJSONFile writes v to path as indented JSON.
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// readProfile returns the manifest and cookies of the profile name in dir.
+func readProfile(dir, name string) (*ProfileInfo, []*network.Cookie, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name, profileManifest))
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("no profile named %s", name)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var info ProfileInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, nil, fmt.Errorf("profile %s: %v", name, err)
+	}
+	var cookies []*network.Cookie
+	data, err = os.ReadFile(filepath.Join(dir, name, profileCookies))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return nil, nil, fmt.Errorf("profile %s cookies: %v", name, err)
+	}
+	return &info, cookies, nil
+}
+
+// listProfiles returns the profiles saved in dir, sorted by name. Entries
+// without a readable manifest, such as saves in progress, are skipped.
+func listProfiles(dir string) ([]ProfileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var profiles []ProfileInfo
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name(), profileManifest))
+		if err != nil {
+			continue
+		}
+		var info ProfileInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			log.Printf("Skipping profile %s: %v", e.Name(), err)
+			continue
+		}
+		profiles = append(profiles, info)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+// cookieParams converts saved cookies into the parameters that restore them.
+func cookieParams(cookies []*network.Cookie) []*network.CookieParam {
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		p := &network.CookieParam{
+			Name:         c.Name,
+			Value:        c.Value,
+			Domain:       c.Domain,
+			Path:         c.Path,
+			Secure:       c.Secure,
+			HTTPOnly:     c.HTTPOnly,
+			SameSite:     c.SameSite,
+			Priority:     c.Priority,
+			SourceScheme: c.SourceScheme,
+			SourcePort:   c.SourcePort,
+			PartitionKey: c.PartitionKey,
+		}
+		if !c.Session {
+			expires := cdp.TimeSinceEpoch(time.Unix(0, int64(c.Expires*float64(time.Second))))
+			p.Expires = &expires
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// profilesDirOrDefault returns the directory profiles are saved in.
+func (s *CDPBrowserServer) profilesDirOrDefault() string {
+	if s.profilesDir != "" {
+		return s.profilesDir
+	}
+	return defaultProfilesDir()
+}
+
+// userDataDir returns the user data directory Chrome is launched with.
+func (s *CDPBrowserServer) userDataDir() string {
+	if s.chromeUserDataDir != "" {
+		return s.chromeUserDataDir
+	}
+	return chromeUserDataDir()
+}
+
+// checkOwnsUserData reports an error unless the server launched Chrome
+// itself, and therefore knows its user data directory.
+func (s *CDPBrowserServer) checkOwnsUserData() error {
+	if s.wsURL == "" {
+		return fmt.Errorf("profiles need a Chrome instance launched by the server")
+	}
+	return nil
+}
+
+type SaveProfileArgs struct {
+	Name string `json:"name" jsonschema:"Name to save the profile under; an existing profile with this name is replaced"`
+}
+
+type LoadProfileArgs struct {
+	Name string `json:"name" jsonschema:"Name of the profile to load"`
+	URL  string `json:"url,omitempty" jsonschema:"URL to open after loading (default: the page that was open when the profile was saved)"`
+}
+
+// ProfileList is the structured result of list_profiles.
+type ProfileList struct {
+	Directory string        `json:"directory" jsonschema:"Directory the profiles are saved in"`
+	Profiles  []ProfileInfo `json:"profiles,omitempty"`
+}
+
+// profileError returns an error result for the profile tools.
+func profileError[Out any](format string, args ...any) *mcp.CallToolResultFor[Out] {
+	return &mcp.CallToolResultFor[Out]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf(format, args...)},
+		},
+		IsError: true,
+	}
+}
+
+// SaveProfile tool - snapshots the browser's user data directory and cookies
+// to a named profile
+func (s *CDPBrowserServer) SaveProfile(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[SaveProfileArgs]]) (*mcp.CallToolResultFor[ProfileInfo], error) {
+	name := req.Params.Arguments.Name
+	if err := checkProfileName(name); err != nil {
+		return profileError[ProfileInfo]("Error saving profile: %v", err), nil
+	}
+	if err := s.checkOwnsUserData(); err != nil {
+		return profileError[ProfileInfo]("Error saving profile: %v", err), nil
+	}
+
+	var cookies []*network.Cookie
+	var url string
+	err := s.run(ctx,
+		chromedp.Location(&url),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			cookies, err = storage.GetCookies().Do(ctx)
+			return err
+		}),
+	)
+	if err != nil {
+		return profileError[ProfileInfo]("Error reading cookies for profile %s: %v", name, err), nil
+	}
+
+	info := &ProfileInfo{Name: name, SavedAt: time.Now().Format(time.RFC3339), URL: url, Cookies: len(cookies)}
+	if err := writeProfile(s.profilesDirOrDefault(), s.userDataDir(), info, cookies); err != nil {
+		return profileError[ProfileInfo]("Error saving profile %s: %v", name, err), nil
+	}
+	log.Printf("Saved profile %s", info)
+	return &mcp.CallToolResultFor[ProfileInfo]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: "Saved profile " + info.String()},
+		},
+		StructuredContent: *info,
+	}, nil
+}
+
+// LoadProfile tool - restarts Chrome on a copy of a saved profile and
+// restores its cookies
+func (s *CDPBrowserServer) LoadProfile(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[LoadProfileArgs]]) (*mcp.CallToolResultFor[ProfileInfo], error) {
+	args := req.Params.Arguments
+	if err := checkProfileName(args.Name); err != nil {
+		return profileError[ProfileInfo]("Error loading profile: %v", err), nil
+	}
+	if err := s.checkOwnsUserData(); err != nil {
+		return profileError[ProfileInfo]("Error loading profile: %v", err), nil
+	}
+	dir := s.profilesDirOrDefault()
+	info, cookies, err := readProfile(dir, args.Name)
+	if err != nil {
+		return profileError[ProfileInfo]("Error loading profile: %v", err), nil
+	}
+
+	// Chrome must not run while its user data directory is replaced. The
+	// copy keeps the saved profile unchanged by the session that follows.
+	log.Printf("Restarting Chrome with profile %s", info)
+	s.closeChrome()
+	userData := s.userDataDir()
+	if err := os.RemoveAll(userData); err != nil {
+		return profileError[ProfileInfo]("Error clearing %s: %v", userData, err), nil
+	}
+	if err := copyUserData(filepath.Join(dir, args.Name, profileUserData), userData); err != nil {
+		return profileError[ProfileInfo]("Error copying profile %s: %v", args.Name, err), nil
+	}
+	if err := s.backend().Launch(); err != nil {
+		return profileError[ProfileInfo]("Error restarting Chrome with profile %s: %v", args.Name, err), nil
+	}
+	// Element IDs refer to nodes of the previous browser.
+	s.elements.reset()
+
+	url := args.URL
+	if url == "" {
+		url = info.URL
+	}
+	actions := []chromedp.Action{storage.SetCookies(cookieParams(cookies))}
+	if url != "" && url != "about:blank" {
+		actions = append(actions, chromedp.Navigate(url))
+	}
+	if err := s.run(ctx, actions...); err != nil {
+		return profileError[ProfileInfo]("Error restoring profile %s: %v", args.Name, err), nil
+	}
+	s.routes.navigated(url)
+
+	text := "Loaded profile " + info.String()
+	if url != "" {
+		text += "\nOpened " + url
+	}
+	return &mcp.CallToolResultFor[ProfileInfo]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+		StructuredContent: *info,
+	}, nil
+}
+
+// ListProfiles tool - lists the saved profiles
+func (s *CDPBrowserServer) ListProfiles(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[ProfileList], error) {
+	dir := s.profilesDirOrDefault()
+	profiles, err := listProfiles(dir)
+	if err != nil {
+		return profileError[ProfileList]("Error listing profiles in %s: %v", dir, err), nil
+	}
+
+	var output strings.Builder
+	if len(profiles) == 0 {
+		output.WriteString(fmt.Sprintf("No profiles saved in %s", dir))
+	} else {
+		output.WriteString(fmt.Sprintf("%d profiles in %s:", len(profiles), dir))
+		for _, p := range profiles {
+			output.WriteString("\n- " + p.String())
+		}
+	}
+	return &mcp.CallToolResultFor[ProfileList]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: output.String()},
+		},
+		StructuredContent: ProfileList{Directory: dir, Profiles: profiles},
+	}, nil
+}
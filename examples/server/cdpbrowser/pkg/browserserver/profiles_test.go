@@ -0,0 +1,134 @@
+package browserserver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestCheckProfileName(t *testing.T) {
+	for _, name := range []string{"work", "shop-admin", "user_1.v2"} {
+		if err := checkProfileName(name); err != nil {
+			t.Errorf("checkProfileName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".hidden", "../etc", "a/b", "with space"} {
+		if err := checkProfileName(name); err == nil {
+			t.Errorf("checkProfileName(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteProfile(t *testing.T) {
+	userData := t.TempDir()
+	writeTestFile(t, filepath.Join(userData, "Local State"), "state")
+	writeTestFile(t, filepath.Join(userData, "Default", "Cookies"), "cookies")
+	writeTestFile(t, filepath.Join(userData, "Default", "Local Storage", "leveldb", "000003.log"), "storage")
+	writeTestFile(t, filepath.Join(userData, "Default", "Cache", "data_0"), "cache")
+	writeTestFile(t, filepath.Join(userData, "SingletonCookie"), "lock")
+
+	dir := filepath.Join(t.TempDir(), "profiles")
+	cookies := []*network.Cookie{{Name: "session", Value: "abc", Domain: "example.com", Path: "/", Session: true, Priority: network.CookiePriorityMedium, SourceScheme: network.CookieSourceSchemeSecure}}
+	info := &ProfileInfo{Name: "work", SavedAt: "2026-01-02T03:04:05Z", URL: "https://example.com/", Cookies: 1}
+	if err := writeProfile(dir, userData, info, cookies); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := filepath.Join(dir, "work", profileUserData)
+	for _, rel := range []string{"Local State", "Default/Cookies", "Default/Local Storage/leveldb/000003.log"} {
+		if _, err := os.Stat(filepath.Join(saved, rel)); err != nil {
+			t.Errorf("saved profile is missing %s: %v", rel, err)
+		}
+	}
+	for _, rel := range []string{"Default/Cache", "SingletonCookie"} {
+		if _, err := os.Stat(filepath.Join(saved, rel)); !os.IsNotExist(err) {
+			t.Errorf("saved profile contains %s", rel)
+		}
+	}
+
+	gotInfo, gotCookies, err := readProfile(dir, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *gotInfo != *info {
+		t.Errorf("readProfile info = %+v, want %+v", gotInfo, info)
+	}
+	if len(gotCookies) != 1 || gotCookies[0].Value != "abc" || !gotCookies[0].Session {
+		t.Errorf("readProfile cookies = %+v", gotCookies)
+	}
+	if _, _, err := readProfile(dir, "home"); err == nil || !strings.Contains(err.Error(), "no profile named home") {
+		t.Errorf("readProfile(home) error = %v", err)
+	}
+
+	// Saving again under the same name replaces the profile.
+	os.Remove(filepath.Join(userData, "Local State"))
+	if err := writeProfile(dir, userData, &ProfileInfo{Name: "work", SavedAt: "2026-02-01T00:00:00Z"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(saved, "Local State")); !os.IsNotExist(err) {
+		t.Errorf("replaced profile still contains Local State")
+	}
+	if err := writeProfile(dir, userData, &ProfileInfo{Name: "home", SavedAt: "2026-03-01T00:00:00Z"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// An interrupted save leaves a hidden directory, which is not listed.
+	if err := os.Mkdir(filepath.Join(dir, ".work-123"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := listProfiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+	if got := strings.Join(names, ","); got != "home,work" {
+		t.Errorf("listProfiles = %s, want home,work", got)
+	}
+	if profiles[1].SavedAt != "2026-02-01T00:00:00Z" {
+		t.Errorf("work saved at %s, want the second save", profiles[1].SavedAt)
+	}
+
+	if profiles, err := listProfiles(filepath.Join(dir, "missing")); err != nil || len(profiles) != 0 {
+		t.Errorf("listProfiles(missing) = %v, %v; want none", profiles, err)
+	}
+}
+
+func TestCookieParams(t *testing.T) {
+	params := cookieParams([]*network.Cookie{
+		{Name: "session", Value: "a", Domain: "example.com", Path: "/", Session: true, HTTPOnly: true},
+		{Name: "remember", Value: "b", Domain: ".example.com", Path: "/", Expires: 1767225600.5, Secure: true, SameSite: network.CookieSameSiteLax},
+	})
+	if len(params) != 2 {
+		t.Fatalf("got %d params, want 2", len(params))
+	}
+	if p := params[0]; p.Expires != nil || !p.HTTPOnly || p.Domain != "example.com" {
+		t.Errorf("session cookie param = %+v", p)
+	}
+	p := params[1]
+	if p.Expires == nil {
+		t.Fatalf("persistent cookie param has no expiry")
+	}
+	if got, want := time.Time(*p.Expires), time.Unix(1767225600, 5e8); !got.Equal(want) {
+		t.Errorf("expires = %v, want %v", got, want)
+	}
+	if !p.Secure || p.SameSite != network.CookieSameSiteLax {
+		t.Errorf("persistent cookie param = %+v", p)
+	}
+}
@@ -0,0 +1,180 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// rateWindow is the window of the per-minute limits.
+const rateWindow = time.Minute
+
+// navigationTools are the tools counted against the navigation limit.
+var navigationTools = map[string]bool{
+	"navigate":     true,
+	"refresh_page": true,
+}
+
+// rateLimits are the limits on tool calls. Zero means no limit.
+type rateLimits struct {
+	// Concurrent is the number of tool calls that may run at once. It
+	// applies across sessions, since they share one browser.
+	Concurrent int
+	// CallsPerMinute is the number of tool calls, including the steps of
+	// batches, macros and replays, a session may start per minute.
+	CallsPerMinute int
+	// NavigationsPerMinute is the number of navigate and refresh_page calls
+	// a session may start per minute.
+	NavigationsPerMinute int
+}
+
+func (l rateLimits) enabled() bool {
+	return l.Concurrent > 0 || l.CallsPerMinute > 0 || l.NavigationsPerMinute > 0
+}
+
+// rateLimitError is the error of a call rejected by a rate limit.
+type rateLimitError struct {
+	// limit describes the limit exceeded, e.g. "60 calls per minute per session".
+	limit string
+	// retryAfter is when the call would have been allowed.
+	retryAfter time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded: at most %s; retry in %s", e.limit, e.retryAfter.Round(time.Second))
+}
+
+// sessionUsage is the usage of one session that the limits apply to.
+type sessionUsage struct {
+	// active is the number of calls of the session running.
+	active int
+	// calls and navigations are the start times of the calls in the last
+	// rateWindow, oldest first.
+	calls       []time.Time
+	navigations []time.Time
+}
+
+// prune drops the start times older than rateWindow before now.
+func (u *sessionUsage) prune(now time.Time) {
+	drop := func(ts []time.Time) []time.Time {
+		i := 0
+		for i < len(ts) && now.Sub(ts[i]) >= rateWindow {
+			i++
+		}
+		return ts[i:]
+	}
+	u.calls = drop(u.calls)
+	u.navigations = drop(u.navigations)
+}
+
+// rateLimiter enforces rateLimits.
+type rateLimiter struct {
+	limits   rateLimits
+	mu       sync.Mutex
+	active   int
+	sessions map[*mcp.ServerSession]*sessionUsage
+}
+
+// sessionUsageKey is the context key for the usage of the session making the
+// current call.
+type sessionUsageKey struct{}
+
+// start counts the start of a call of tool at now against u, or returns a
+// *rateLimitError if a per-minute limit forbids it. l.mu must be held.
+func (l *rateLimiter) start(u *sessionUsage, tool string, now time.Time) error {
+	u.prune(now)
+	if n := l.limits.CallsPerMinute; n > 0 && len(u.calls) >= n {
+		return &rateLimitError{
+			limit:      fmt.Sprintf("%d calls per minute per session", n),
+			retryAfter: u.calls[len(u.calls)-n].Add(rateWindow).Sub(now),
+		}
+	}
+	if n := l.limits.NavigationsPerMinute; n > 0 && navigationTools[tool] && len(u.navigations) >= n {
+		return &rateLimitError{
+			limit:      fmt.Sprintf("%d navigations per minute per session", n),
+			retryAfter: u.navigations[len(u.navigations)-n].Add(rateWindow).Sub(now),
+		}
+	}
+	u.calls = append(u.calls, now)
+	if navigationTools[tool] {
+		u.navigations = append(u.navigations, now)
+	}
+	return nil
+}
+
+// acquire starts a call of tool in session, returning the session's usage,
+// or a *rateLimitError if a limit forbids the call. Each successful acquire
+// must be followed by a release.
+func (l *rateLimiter) acquire(session *mcp.ServerSession, tool string, now time.Time) (*sessionUsage, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for sess, u := range l.sessions {
+		if u.prune(now); u.active == 0 && len(u.calls) == 0 && len(u.navigations) == 0 {
+			delete(l.sessions, sess)
+		}
+	}
+	if n := l.limits.Concurrent; n > 0 && l.active >= n {
+		return nil, &rateLimitError{limit: fmt.Sprintf("%d concurrent calls", n), retryAfter: time.Second}
+	}
+	u := l.sessions[session]
+	if u == nil {
+		u = &sessionUsage{}
+		if l.sessions == nil {
+			l.sessions = make(map[*mcp.ServerSession]*sessionUsage)
+		}
+		l.sessions[session] = u
+	}
+	if err := l.start(u, tool, now); err != nil {
+		return nil, err
+	}
+	l.active++
+	u.active++
+	return u, nil
+}
+
+// release ends a call started with acquire.
+func (l *rateLimiter) release(u *sessionUsage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	u.active--
+}
+
+// step counts a step of tool, run by a batch, macro or replay, against the
+// per-minute limits of the session making the call in ctx.
+func (l *rateLimiter) step(ctx context.Context, tool string) error {
+	u, ok := ctx.Value(sessionUsageKey{}).(*sessionUsage)
+	if !ok {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.start(u, tool, time.Now())
+}
+
+// rateLimitMiddleware rejects tool calls that exceed the rate limits with a
+// RATE_LIMITED error.
+func (s *CDPBrowserServer) rateLimitMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		params, ok := req.GetParams().(*mcp.CallToolParamsFor[json.RawMessage])
+		session, _ := req.GetSession().(*mcp.ServerSession)
+		if !ok || session == nil || !s.rateLimiter.limits.enabled() {
+			return next(ctx, method, req)
+		}
+		u, err := s.rateLimiter.acquire(session, params.Name, time.Now())
+		if err != nil {
+			res := &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Not running %s: %v", params.Name, err)}},
+				IsError: true,
+			}
+			s.describeFailure(res, &toolFailure{err: err})
+			return res, nil
+		}
+		defer s.rateLimiter.release(u)
+		return next(context.WithValue(ctx, sessionUsageKey{}, u), method, req)
+	}
+}
@@ -0,0 +1,167 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{limits: rateLimits{Concurrent: 2, CallsPerMinute: 4, NavigationsPerMinute: 2}}
+	session := new(mcp.ServerSession)
+	now := time.Now()
+
+	acquire := func(tool string, at time.Time) (*sessionUsage, error) {
+		t.Helper()
+		u, err := l.acquire(session, tool, at)
+		if u != nil {
+			l.release(u)
+		}
+		return u, err
+	}
+	var limited *rateLimitError
+
+	if _, err := acquire("navigate", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acquire("navigate", now.Add(10*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	_, err := acquire("refresh_page", now.Add(20*time.Second))
+	if !errors.As(err, &limited) || limited.limit != "2 navigations per minute per session" || limited.retryAfter != 40*time.Second {
+		t.Errorf("third navigation error = %v, want the navigation limit with 40s to wait", err)
+	}
+	if _, err := acquire("click", now.Add(20*time.Second)); err != nil {
+		t.Errorf("click after the navigation limit: %v", err)
+	}
+	if _, err := acquire("click", now.Add(30*time.Second)); err != nil {
+		t.Errorf("fourth call: %v", err)
+	}
+	_, err = acquire("click", now.Add(30*time.Second))
+	if !errors.As(err, &limited) || limited.limit != "4 calls per minute per session" || limited.retryAfter != 30*time.Second {
+		t.Errorf("fifth call error = %v, want the call limit with 30s to wait", err)
+	}
+	// The first call leaves the window.
+	if _, err := acquire("navigate", now.Add(time.Minute)); err != nil {
+		t.Errorf("navigation a minute later: %v", err)
+	}
+
+	// Per-minute limits apply to each session separately.
+	if _, err := acquire("click", now.Add(time.Minute)); !errors.As(err, &limited) {
+		t.Errorf("call over the call limit error = %v", err)
+	}
+	if _, err := l.acquire(new(mcp.ServerSession), "click", now.Add(time.Minute)); err != nil {
+		t.Errorf("call in another session: %v", err)
+	}
+
+	// The concurrency limit applies across sessions.
+	later := now.Add(5 * time.Minute)
+	u1, err := l.acquire(session, "click", later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.acquire(new(mcp.ServerSession), "click", later); !errors.As(err, &limited) || limited.limit != "2 concurrent calls" {
+		t.Errorf("third concurrent call error = %v, want the concurrency limit", err)
+	}
+	l.release(u1)
+
+	// Steps of batches count against the call limit of their session: the
+	// batch and three steps make four calls.
+	ctx := context.WithValue(context.Background(), sessionUsageKey{}, u1)
+	for i := range 3 {
+		if err := l.step(ctx, "click"); err != nil {
+			t.Errorf("step %d: %v", i+1, err)
+		}
+	}
+	if err := l.step(ctx, "click"); !errors.As(err, &limited) {
+		t.Errorf("step over the call limit error = %v", err)
+	}
+	if err := l.step(context.Background(), "click"); err != nil {
+		t.Errorf("step outside a limited call: %v", err)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	s := &CDPBrowserServer{}
+	s.rateLimiter.limits = rateLimits{Concurrent: 1, CallsPerMinute: 3}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	addTool(server, s, &mcp.Tool{Name: "click"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+		if req.Params.Arguments.Selector == "#slow" {
+			close(started)
+			<-release
+		}
+		return &mcp.CallToolResultFor[SelectorMatch]{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Clicked element: " + req.Params.Arguments.Selector}},
+		}, nil
+	})
+	server.AddReceivingMiddleware(s.rateLimitMiddleware, s.toolErrorMiddleware)
+
+	ctx := context.Background()
+	connect := func() *mcp.ClientSession {
+		t.Helper()
+		clientTransport, serverTransport := mcp.NewInMemoryTransports()
+		serverSession, err := server.Connect(ctx, serverTransport, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { serverSession.Close() })
+		cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { cs.Close() })
+		return cs
+	}
+	cs, other := connect(), connect()
+
+	call := func(cs *mcp.ClientSession, selector string) (*mcp.CallToolResult, *ToolError) {
+		t.Helper()
+		res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "click", Arguments: map[string]any{"selector": selector}})
+		if err != nil {
+			t.Fatalf("CallTool(click) error = %v", err)
+		}
+		if !res.IsError {
+			return res, nil
+		}
+		var te ToolError
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &te); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		return res, &te
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		call(other, "#slow")
+	}()
+	<-started
+	res, te := call(cs, "#busy")
+	if te == nil || te.Code != CodeRateLimited || !te.Retryable || te.RetryAfterSeconds != 1 {
+		t.Errorf("call while another runs = %+v", te)
+	} else if text := resultText(res); !strings.Contains(text, "Not running click: rate limit exceeded: at most 1 concurrent calls; retry in 1s") ||
+		!strings.Contains(text, "Error code: RATE_LIMITED") {
+		t.Errorf("call while another runs text = %q", text)
+	}
+	close(release)
+	wg.Wait()
+
+	for _, sel := range []string{"#one", "#two", "#three"} {
+		if _, te := call(cs, sel); te != nil {
+			t.Errorf("call %s = %+v", sel, te)
+		}
+	}
+	if _, te := call(cs, "#four"); te == nil || te.Code != CodeRateLimited || te.RetryAfterSeconds < 59 || te.RetryAfterSeconds > 60 {
+		t.Errorf("call over the per-minute limit = %+v", te)
+	}
+}
@@ -0,0 +1,93 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ExecuteCDPArgs struct {
+	Method string         `json:"method" jsonschema:"CDP method in Domain.method form, e.g. Network.getCookies"`
+	Params map[string]any `json:"params,omitempty" jsonschema:"Parameters for the method, as documented in the Chrome DevTools Protocol"`
+}
+
+// ExecuteCDPResult is the structured result of execute_cdp.
+type ExecuteCDPResult struct {
+	Method string         `json:"method"`
+	Result map[string]any `json:"result"`
+}
+
+// ExecuteCDP tool - sends an arbitrary CDP command to the current page target.
+// It is only registered when the server runs with -allow-raw-cdp.
+func (s *CDPBrowserServer) ExecuteCDP(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ExecuteCDPArgs]]) (*mcp.CallToolResultFor[ExecuteCDPResult], error) {
+	args := req.Params.Arguments
+	domain, name, ok := strings.Cut(args.Method, ".")
+	if !ok || domain == "" || name == "" {
+		return &mcp.CallToolResultFor[ExecuteCDPResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid CDP method %q: expected Domain.method", args.Method)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	if err := s.checkRawCDPPolicy(domain, args.Params); err != nil {
+		return &mcp.CallToolResultFor[ExecuteCDPResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error executing %s: %v", args.Method, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	log.Printf("ExecuteCDP: %s", args.Method)
+	var params any
+	if args.Params != nil {
+		params = args.Params
+	}
+	result := make(map[string]any)
+	err := s.run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		return cdp.Execute(ctx, args.Method, params, &result)
+	}))
+	if err != nil {
+		return &mcp.CallToolResultFor[ExecuteCDPResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error executing %s: %v", args.Method, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.CallToolResultFor[ExecuteCDPResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(data)},
+		},
+		StructuredContent: ExecuteCDPResult{Method: args.Method, Result: result},
+	}, nil
+}
+
+// checkRawCDPPolicy keeps raw commands from escaping the navigation policy:
+// while a policy is enforced, the Fetch domain that implements it is off
+// limits and any "url" parameter must be allowed.
+func (s *CDPBrowserServer) checkRawCDPPolicy(domain string, params map[string]any) error {
+	if !s.policy.enabled() {
+		return nil
+	}
+	if domain == "Fetch" {
+		return fmt.Errorf("the Fetch domain is reserved for the navigation policy")
+	}
+	if u, ok := params["url"].(string); ok {
+		return s.policy.check(u)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package browserserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestExecuteCDPRejectsBeforeCallingChrome(t *testing.T) {
+	s := &CDPBrowserServer{policy: &navigationPolicy{AllowDomains: []string{"example.com"}}}
+	for _, tc := range []struct {
+		method string
+		params map[string]any
+		want   string
+	}{
+		{"getVersion", nil, "expected Domain.method"},
+		{"Fetch.disable", nil, "reserved for the navigation policy"},
+		{"Page.navigate", map[string]any{"url": "https://evil.test/"}, "policy violation"},
+	} {
+		res, err := s.ExecuteCDP(context.Background(), &mcp.ServerRequest[*mcp.CallToolParamsFor[ExecuteCDPArgs]]{
+			Params: &mcp.CallToolParamsFor[ExecuteCDPArgs]{Arguments: ExecuteCDPArgs{Method: tc.method, Params: tc.params}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.IsError {
+			t.Errorf("ExecuteCDP(%s) succeeded, want error", tc.method)
+			continue
+		}
+		if text := res.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, tc.want) {
+			t.Errorf("ExecuteCDP(%s) error = %q, want it to contain %q", tc.method, text, tc.want)
+		}
+	}
+}
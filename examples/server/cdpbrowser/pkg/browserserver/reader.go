@@ -0,0 +1,240 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// pageMarkdownURI and pageTextURI are the resources holding the
+	// readable content of the current page.
+	pageMarkdownURI = "page://current/markdown"
+	pageTextURI     = "page://current/text"
+	// maxReaderBytes bounds the content of the reader resources.
+	maxReaderBytes = 512 << 10
+)
+
+// readerJS extracts the readable content of the page, as "markdown" or
+// "text": the main article, or the body without its navigation, leaving out
+// hidden elements and form controls. It returns the title, URL, and content.
+const readerJS = `function(format) {
+	const page = {title: document.title, url: location.href, content: ''};
+	const root = document.querySelector('article, main, [role="main"]') || document.body;
+	if (!root) {
+		return page;
+	}
+	if (format === 'text') {
+		page.content = root.innerText.replace(/[ \t]+\n/g, '\n').replace(/\n{3,}/g, '\n\n').trim();
+		return page;
+	}
+	const skipped = new Set(['SCRIPT', 'STYLE', 'NOSCRIPT', 'TEMPLATE', 'SVG', 'CANVAS', 'IFRAME', 'NAV', 'ASIDE', 'DIALOG',
+		'BUTTON', 'INPUT', 'SELECT', 'TEXTAREA']);
+	const blockTags = new Set(['ADDRESS', 'ARTICLE', 'ASIDE', 'BLOCKQUOTE', 'DD', 'DETAILS', 'DIV', 'DL', 'DT', 'FIELDSET',
+		'FIGCAPTION', 'FIGURE', 'FOOTER', 'FORM', 'H1', 'H2', 'H3', 'H4', 'H5', 'H6', 'HEADER', 'HGROUP', 'HR', 'LI', 'MAIN',
+		'NAV', 'OL', 'P', 'PRE', 'SECTION', 'SUMMARY', 'TABLE', 'UL']);
+	const skip = el => {
+		const tag = el.tagName.toUpperCase();
+		if (skipped.has(tag) || el.hidden || el.getAttribute('aria-hidden') === 'true') {
+			return true;
+		}
+		// Site headers and footers are chrome; those of an article are not.
+		if ((tag === 'HEADER' || tag === 'FOOTER') && !el.closest('article, main, [role="main"]')) {
+			return true;
+		}
+		const style = getComputedStyle(el);
+		return style.display === 'none' || style.visibility === 'hidden';
+	};
+	const isBlock = node => node.nodeType === Node.ELEMENT_NODE && blockTags.has(node.tagName);
+	const clean = text => text.replace(/[ \t]*\n[ \t]*/g, '\n').replace(/ {2,}/g, ' ').trim();
+	const inline = node => {
+		if (node.nodeType === Node.TEXT_NODE) {
+			return node.textContent.replace(/\s+/g, ' ');
+		}
+		if (node.nodeType !== Node.ELEMENT_NODE || skip(node)) {
+			return '';
+		}
+		const inner = () => Array.from(node.childNodes, inline).join('');
+		const wrap = mark => {
+			const text = inner().trim();
+			return text ? mark + text + mark : '';
+		};
+		switch (node.tagName) {
+		case 'BR':
+			return '\n';
+		case 'IMG':
+			return node.alt ? '![' + node.alt + '](' + node.src + ')' : '';
+		case 'A': {
+			const text = inner().trim();
+			const href = node.getAttribute('href') || '';
+			return text && href && !href.startsWith('javascript:') ? '[' + text + '](' + node.href + ')' : text;
+		}
+		case 'STRONG':
+		case 'B':
+			return wrap('**');
+		case 'EM':
+		case 'I':
+			return wrap('*');
+		case 'CODE':
+			return node.textContent ? '` + "`" + `' + node.textContent + '` + "`" + `' : '';
+		default:
+			return inner();
+		}
+	};
+	let block;
+	const container = (el, out) => {
+		let paragraph = '';
+		const flush = () => {
+			const text = clean(paragraph);
+			if (text) {
+				out.push(text);
+			}
+			paragraph = '';
+		};
+		for (const child of el.childNodes) {
+			if (isBlock(child)) {
+				flush();
+				if (!skip(child)) {
+					block(child, out);
+				}
+			} else {
+				paragraph += inline(child);
+			}
+		}
+		flush();
+	};
+	const list = (el, depth) => {
+		const lines = [];
+		let n = 1;
+		for (const li of el.children) {
+			if (li.tagName !== 'LI' || skip(li)) {
+				continue;
+			}
+			const marker = el.tagName === 'OL' ? (n++) + '. ' : '- ';
+			let text = '';
+			const nested = [];
+			for (const child of li.childNodes) {
+				if (child.nodeType === Node.ELEMENT_NODE && (child.tagName === 'UL' || child.tagName === 'OL')) {
+					nested.push(list(child, depth + 1));
+				} else if (isBlock(child)) {
+					const blocks = [];
+					if (!skip(child)) {
+						block(child, blocks);
+					}
+					text += ' ' + blocks.join(' ');
+				} else {
+					text += inline(child);
+				}
+			}
+			lines.push('  '.repeat(depth) + marker + clean(text).replace(/\n/g, ' '), ...nested.filter(Boolean));
+		}
+		return lines.join('\n');
+	};
+	block = (el, out) => {
+		const tag = el.tagName;
+		if (/^H[1-6]$/.test(tag)) {
+			const text = clean(inline(el)).replace(/\n/g, ' ');
+			if (text) {
+				out.push('#'.repeat(Number(tag[1])) + ' ' + text);
+			}
+			return;
+		}
+		switch (tag) {
+		case 'UL':
+		case 'OL': {
+			const text = list(el, 0);
+			if (text) {
+				out.push(text);
+			}
+			return;
+		}
+		case 'PRE':
+			out.push('` + "```" + `\n' + el.textContent.replace(/\n$/, '') + '\n` + "```" + `');
+			return;
+		case 'HR':
+			out.push('---');
+			return;
+		case 'BLOCKQUOTE': {
+			const blocks = [];
+			container(el, blocks);
+			if (blocks.length) {
+				out.push(blocks.join('\n\n').replace(/^/gm, '> '));
+			}
+			return;
+		}
+		case 'TABLE': {
+			const rows = Array.from(el.rows, row => '| ' + Array.from(row.cells, cell =>
+				clean(inline(cell)).replace(/\n/g, ' ').replace(/\|/g, '\\|')).join(' | ') + ' |');
+			if (rows.length) {
+				rows.splice(1, 0, '|' + ' --- |'.repeat(el.rows[0].cells.length));
+				out.push(rows.join('\n'));
+			}
+			return;
+		}
+		default:
+			container(el, out);
+		}
+	};
+	const out = [];
+	container(root, out);
+	page.content = out.join('\n\n');
+	return page;
+}`
+
+// readerPage is the readable content of a page.
+type readerPage struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Content string `json:"content"`
+}
+
+// format formats p as the reader resource of the given format: markdown
+// gets the title as a heading and a link to the page, text the title and
+// URL on their own lines.
+func (p *readerPage) format(format string) string {
+	var b strings.Builder
+	if format == "markdown" {
+		if p.Title != "" {
+			fmt.Fprintf(&b, "# %s\n\n", p.Title)
+		}
+		fmt.Fprintf(&b, "Source: <%s>\n\n", p.URL)
+	} else {
+		if p.Title != "" {
+			fmt.Fprintf(&b, "%s\n", p.Title)
+		}
+		fmt.Fprintf(&b, "%s\n\n", p.URL)
+	}
+	content := p.Content
+	if len(content) > maxReaderBytes {
+		n := maxReaderBytes
+		for n > 0 && !utf8.RuneStart(content[n]) {
+			n--
+		}
+		content = content[:n] + fmt.Sprintf("\n\n[content truncated at %d KiB]", maxReaderBytes>>10)
+	}
+	b.WriteString(content)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// readPage serves page://current/markdown and page://current/text, reading
+// the content of the page at the time of the request.
+func (s *CDPBrowserServer) readPage(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	format, mimeType := "markdown", "text/markdown"
+	if uri == pageTextURI {
+		format, mimeType = "text", "text/plain"
+	}
+	var page readerPage
+	if err := s.backend().Evaluate(ctx, callJS(readerJS, format), &page); err != nil {
+		return nil, fmt.Errorf("reading the page: %w", err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: uri, MIMEType: mimeType, Text: page.format(format)},
+		},
+	}, nil
+}
@@ -0,0 +1,63 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestReaderPageFormat(t *testing.T) {
+	p := readerPage{Title: "Release notes", URL: "https://example.com/notes", Content: "## v2\n\n- Faster"}
+	if got, want := p.format("markdown"), "# Release notes\n\nSource: <https://example.com/notes>\n\n## v2\n\n- Faster\n"; got != want {
+		t.Errorf("markdown = %q, want %q", got, want)
+	}
+	p.Content = "v2\nFaster"
+	if got, want := p.format("text"), "Release notes\nhttps://example.com/notes\n\nv2\nFaster\n"; got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+
+	// Long content is cut at a rune boundary.
+	p.Content = strings.Repeat("é", maxReaderBytes)
+	got := p.format("text")
+	body, note, ok := strings.Cut(strings.TrimPrefix(got, "Release notes\nhttps://example.com/notes\n\n"), "\n\n[content truncated")
+	if !ok || len(body) != maxReaderBytes || strings.ContainsRune(body, '�') || !strings.HasPrefix(note, " at 512 KiB]") {
+		t.Errorf("truncated text is %d bytes with note %q", len(body), note)
+	}
+}
+
+func TestReadPage(t *testing.T) {
+	var formats []string
+	eval := func(expression string, res any) error {
+		if !strings.Contains(expression, readerJS) {
+			t.Errorf("unexpected expression %q", expression)
+		}
+		format := "markdown"
+		if strings.HasSuffix(expression, `["text"])`) {
+			format = "text"
+		}
+		formats = append(formats, format)
+		data, _ := json.Marshal(readerPage{Title: "Fake", URL: "https://example.com/", Content: format + " content"})
+		return json.Unmarshal(data, res)
+	}
+	s := &CDPBrowserServer{browser: &fakeBrowser{eval: eval}, stats: newToolStats(), policy: &navigationPolicy{}}
+	cs := connectCustomTools(t, s)
+
+	for _, tt := range []struct{ uri, mimeType, content string }{
+		{pageMarkdownURI, "text/markdown", "# Fake\n\nSource: <https://example.com/>\n\nmarkdown content\n"},
+		{pageTextURI, "text/plain", "Fake\nhttps://example.com/\n\ntext content\n"},
+	} {
+		res, err := cs.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: tt.uri})
+		if err != nil {
+			t.Fatalf("ReadResource(%s): %v", tt.uri, err)
+		}
+		if c := res.Contents[0]; c.MIMEType != tt.mimeType || c.Text != tt.content {
+			t.Errorf("ReadResource(%s) = %s %q, want %s %q", tt.uri, c.MIMEType, c.Text, tt.mimeType, tt.content)
+		}
+	}
+	if strings.Join(formats, ",") != "markdown,text" {
+		t.Errorf("formats read = %q", formats)
+	}
+}
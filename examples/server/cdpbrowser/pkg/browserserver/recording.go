@@ -0,0 +1,388 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// recordableTools are the tools captured by a recording: the tools that
+// change page state, and the assertions, which make a replay fail where the
+// page no longer behaves as recorded. Other read-only and lifecycle tools are
+// not replayed.
+var recordableTools = map[string]bool{
+	"navigate":            true,
+	"click":               true,
+	"type_text":           true,
+	"click_button":        true,
+	"click_link":          true,
+	"select_dropdown":     true,
+	"choose_option":       true,
+	"click_at":            true,
+	"move_mouse":          true,
+	"swipe":               true,
+	"refresh_page":        true,
+	"set_window_size":     true,
+	"maximize":            true,
+	"emulate_media":       true,
+	"set_zoom":            true,
+	"set_blocking_preset": true,
+
+	"assert_url_matches":     true,
+	"assert_text_present":    true,
+	"assert_element_visible": true,
+	"assert_element_count":   true,
+}
+
+// RecordedStep is one successful tool call in a recording.
+type RecordedStep struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	// ResolvedSelector is the selector the smart selector settled on, if it
+	// differs from the one the caller passed.
+	ResolvedSelector string `json:"resolved_selector,omitempty"`
+	// OffsetMS is when the call started, relative to the start of the recording.
+	OffsetMS   int64 `json:"offset_ms"`
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// Recording is a replayable script of the actions performed in a session.
+type Recording struct {
+	Name      string         `json:"name,omitempty"`
+	StartedAt time.Time      `json:"started_at"`
+	Steps     []RecordedStep `json:"steps"`
+}
+
+// defaultRecordingsDir returns the directory recordings are saved in when
+// the server was not given -recordings-dir.
+func defaultRecordingsDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "cdpbrowser", "recordings")
+}
+
+// recordingPath returns the file the recording name is kept in in dir.
+// Names are restricted like profile names, so that clients cannot read or
+// write files outside dir.
+func recordingPath(dir, name string) (string, error) {
+	if !profileNamePattern.MatchString(name) {
+		return "", invalidArgumentError{fmt.Errorf("invalid recording name %q: use letters, digits, '.', '_' and '-', starting with a letter or digit", name)}
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// saveRecording writes the recording script data to dir under name.
+func saveRecording(dir, name string, data []byte) error {
+	path, err := recordingPath(dir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create recordings directory: %v", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// loadStoredRecording reads the recording saved under name in dir.
+func loadStoredRecording(dir, name string) (*Recording, error) {
+	path, err := recordingPath(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	return loadRecording(path)
+}
+
+// loadRecording reads a recording script saved by stop_recording. path is
+// trusted: it comes from the command line or from recordingPath.
+func loadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %v", err)
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %v", path, err)
+	}
+	return &rec, nil
+}
+
+// recorder holds the recording in progress, if any.
+type recorder struct {
+	mu     sync.Mutex
+	active *Recording
+}
+
+func (r *recorder) start(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active != nil {
+		return fmt.Errorf("recording %q is already in progress", r.active.Name)
+	}
+	r.active = &Recording{Name: name, StartedAt: time.Now(), Steps: []RecordedStep{}}
+	return nil
+}
+
+func (r *recorder) stop() (*Recording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active == nil {
+		return nil, fmt.Errorf("no recording is in progress")
+	}
+	rec := r.active
+	r.active = nil
+	return rec, nil
+}
+
+func (r *recorder) recording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active != nil
+}
+
+// add appends step, which started at start, to the active recording.
+func (r *recorder) add(step *RecordedStep, start time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active == nil {
+		return
+	}
+	step.OffsetMS = start.Sub(r.active.StartedAt).Milliseconds()
+	r.active.Steps = append(r.active.Steps, *step)
+}
+
+// recordedStepKey is the context key for the step being recorded.
+type recordedStepKey struct{}
+
+// noteResolvedSelector records the selector a tool actually used for the
+// call in ctx, if that call is being recorded.
+func noteResolvedSelector(ctx context.Context, selector string) {
+	if step, ok := ctx.Value(recordedStepKey{}).(*RecordedStep); ok {
+		step.ResolvedSelector = selector
+	}
+}
+
+// recordingMiddleware captures successful calls to recordable tools while a
+// recording is in progress.
+func (s *CDPBrowserServer) recordingMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		params, ok := req.GetParams().(*mcp.CallToolParamsFor[json.RawMessage])
+		if !ok || !recordableTools[params.Name] || !s.recorder.recording() {
+			return next(ctx, method, req)
+		}
+
+		step := &RecordedStep{Tool: params.Name, Arguments: params.Arguments}
+		start := time.Now()
+		result, err := next(context.WithValue(ctx, recordedStepKey{}, step), method, req)
+		if err != nil {
+			return result, err
+		}
+		if res, ok := result.(*mcp.CallToolResult); ok && res.IsError {
+			return result, err
+		}
+		step.DurationMS = time.Since(start).Milliseconds()
+		s.recorder.add(step, start)
+		return result, err
+	}
+}
+
+// ReplayResult is the structured result of replay_recording.
+type ReplayResult struct {
+	Name   string       `json:"name,omitempty"`
+	Passed bool         `json:"passed"`
+	Steps  []StepResult `json:"steps"`
+}
+
+// replayArguments returns the arguments to replay step with. When the smart
+// selector resolved the selector, the resolved CSS or XPath selector is used
+// instead of the original one, with strict set, so that the replay targets
+// exactly the same element. replay falls back to the original arguments if
+// no element matches the resolved selector.
+func replayArguments(step RecordedStep) (json.RawMessage, error) {
+	sel := step.ResolvedSelector
+	if sel == "" {
+		return step.Arguments, nil
+	}
+	args := make(map[string]any)
+	if len(step.Arguments) > 0 {
+		if err := json.Unmarshal(step.Arguments, &args); err != nil {
+			return nil, err
+		}
+	}
+	args["selector"] = sel
+	args["strict"] = true
+	return json.Marshal(args)
+}
+
+// replay re-executes the steps of rec in order. With keepTiming, it waits
+// between steps as long as the recorded session did. Unless continueOnError
+// is set, replay stops at the first failing step.
+func (s *CDPBrowserServer) replay(ctx context.Context, rec *Recording, keepTiming, continueOnError bool) ReplayResult {
+	result := ReplayResult{Name: rec.Name, Passed: true, Steps: []StepResult{}}
+	for i, step := range rec.Steps {
+		if keepTiming && i > 0 {
+			prev := rec.Steps[i-1]
+			if wait := time.Duration(step.OffsetMS-prev.OffsetMS-prev.DurationMS) * time.Millisecond; wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					result.Passed = false
+					result.Steps = append(result.Steps, StepResult{Step: i + 1, Tool: step.Tool, Message: ctx.Err().Error()})
+					return result
+				}
+			}
+		}
+
+		var sr StepResult
+		if !recordableTools[step.Tool] {
+			sr = StepResult{Step: i + 1, Tool: step.Tool, Message: fmt.Sprintf("tool %s cannot be replayed", step.Tool), Code: CodeInvalidArgument}
+		} else if args, err := replayArguments(step); err != nil {
+			sr = StepResult{Step: i + 1, Tool: step.Tool, Message: fmt.Sprintf("invalid arguments: %v", err), Code: CodeInvalidArgument}
+		} else {
+			sr = s.invokeStep(ctx, i+1, step.Tool, args)
+			// The resolved selector may name the element by text of the
+			// recorded locale; the selector as recorded goes through the
+			// smart selector again, translations included.
+			if !sr.OK && sr.Code == CodeElementNotFound && step.ResolvedSelector != "" {
+				log.Printf("Replay: step %d resolved selector not found, retrying with the recorded selector", i+1)
+				retry := s.invokeStep(ctx, i+1, step.Tool, step.Arguments)
+				retry.DurationMS += sr.DurationMS
+				sr = retry
+			}
+		}
+		result.Steps = append(result.Steps, sr)
+		log.Printf("Replay: step %d/%d %s ok=%v %s", sr.Step, len(rec.Steps), sr.Tool, sr.OK, sr.Message)
+
+		if !sr.OK {
+			result.Passed = false
+			if !continueOnError {
+				break
+			}
+		}
+	}
+	return result
+}
+
+// String summarizes the replay, one line per step.
+func (r ReplayResult) String() string {
+	var output strings.Builder
+	status := "PASSED"
+	if !r.Passed {
+		status = "FAILED"
+	}
+	output.WriteString(fmt.Sprintf("REPLAY %s: %s (%d steps run)\n", r.Name, status, len(r.Steps)))
+	for _, st := range r.Steps {
+		mark := "ok"
+		if !st.OK {
+			mark = "FAIL"
+		}
+		output.WriteString(fmt.Sprintf("%d. [%s] %s (%dms): %s\n", st.Step, mark, st.Tool, st.DurationMS, st.Message))
+	}
+	return output.String()
+}
+
+type StartRecordingArgs struct {
+	Name string `json:"name,omitempty" jsonschema:"Optional name for the recording"`
+}
+
+type StopRecordingArgs struct {
+	SaveAs string `json:"save_as,omitempty" jsonschema:"Optional name to save the recording script under in the server's recordings directory"`
+}
+
+type ReplayRecordingArgs struct {
+	Name            string `json:"name,omitempty" jsonschema:"Name a recording script was saved under with stop_recording"`
+	Script          string `json:"script,omitempty" jsonschema:"Recording script JSON, as returned by stop_recording (used if name is empty)"`
+	KeepTiming      bool   `json:"keep_timing,omitempty" jsonschema:"Wait between steps as long as the recorded session did (default: false)"`
+	ContinueOnError bool   `json:"continue_on_error,omitempty" jsonschema:"Keep replaying after a step fails (default: false)"`
+}
+
+// StartRecording tool - starts capturing performed actions
+func (s *CDPBrowserServer) StartRecording(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[StartRecordingArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+	name := req.Params.Arguments.Name
+	if err := s.recorder.start(name); err != nil {
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error starting recording: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	log.Printf("Recording started: %q", name)
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: "Recording started. Actions performed from now on will be captured until stop_recording is called."},
+		},
+	}, nil
+}
+
+// StopRecording tool - stops capturing and returns the replayable script
+func (s *CDPBrowserServer) StopRecording(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[StopRecordingArgs]]) (*mcp.CallToolResultFor[Recording], error) {
+	rec, err := s.recorder.stop()
+	if err == nil {
+		var data []byte
+		if data, err = json.MarshalIndent(rec, "", "  "); err == nil {
+			if name := req.Params.Arguments.SaveAs; name != "" {
+				err = saveRecording(s.recordingsDir, name, data)
+			}
+		}
+		if err == nil {
+			log.Printf("Recording stopped: %q with %d steps", rec.Name, len(rec.Steps))
+			return &mcp.CallToolResultFor[Recording]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Recorded %d steps:\n%s", len(rec.Steps), data)},
+				},
+				StructuredContent: *rec,
+			}, nil
+		}
+	}
+	return &mcp.CallToolResultFor[Recording]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Error stopping recording: %v", err)},
+		},
+		IsError: true,
+	}, nil
+}
+
+// ReplayRecording tool - re-executes a recorded script
+func (s *CDPBrowserServer) ReplayRecording(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ReplayRecordingArgs]]) (*mcp.CallToolResultFor[ReplayResult], error) {
+	args := req.Params.Arguments
+	var rec *Recording
+	var err error
+	switch {
+	case args.Name != "":
+		rec, err = loadStoredRecording(s.recordingsDir, args.Name)
+	case args.Script != "":
+		rec = &Recording{}
+		if err = json.Unmarshal([]byte(args.Script), rec); err != nil {
+			err = fmt.Errorf("failed to parse script: %v", err)
+		}
+	default:
+		err = fmt.Errorf("either name or script is required")
+	}
+	if err != nil {
+		return &mcp.CallToolResultFor[ReplayResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error loading recording: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	result := s.replay(ctx, rec, args.KeepTiming, args.ContinueOnError)
+	return &mcp.CallToolResultFor[ReplayResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: result.String()},
+		},
+		StructuredContent: result,
+		IsError:           !result.Passed,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	s := &CDPBrowserServer{recordingsDir: t.TempDir()}
+	var clicked []string
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	addTool(server, s, &mcp.Tool{Name: "click_button"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickButtonArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+		sel := req.Params.Arguments.Selector
+		if req.Params.Arguments.Strict {
+			clicked = append(clicked, sel+" (strict)")
+		} else {
+			clicked = append(clicked, sel)
+		}
+		if sel == "missing" {
+			return &mcp.CallToolResultFor[struct{}]{IsError: true}, nil
+		}
+		if sel == "Submit" {
+			noteResolvedSelector(ctx, `[aria-label="Submit"]`)
+		}
+		return &mcp.CallToolResultFor[struct{}]{}, nil
+	})
+	addTool(server, s, &mcp.Tool{Name: "get_tool_stats"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[struct{}], error) {
+		return &mcp.CallToolResultFor[struct{}]{}, nil
+	})
+	addTool(server, s, &mcp.Tool{Name: "start_recording"}, s.StartRecording)
+	addTool(server, s, &mcp.Tool{Name: "stop_recording"}, s.StopRecording)
+	addTool(server, s, &mcp.Tool{Name: "replay_recording"}, s.ReplayRecording)
+	server.AddReceivingMiddleware(s.recordingMiddleware)
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("CallTool(%s) error = %v", name, err)
+		}
+		return res
+	}
+
+	call("click_button", map[string]any{"selector": "before"}) // not recorded
+	call("start_recording", map[string]any{"name": "checkout"})
+	if res := call("start_recording", nil); !res.IsError {
+		t.Error("second start_recording succeeded, want error")
+	}
+	call("click_button", map[string]any{"selector": "Submit"})
+	call("click_button", map[string]any{"selector": "missing"}) // failed, not recorded
+	call("get_tool_stats", nil)                                 // read-only, not recorded
+	call("click_button", map[string]any{"selector": "#next"})
+	res := call("stop_recording", map[string]any{"save_as": "checkout"})
+	if res.IsError {
+		t.Fatalf("stop_recording returned an error")
+	}
+
+	data, err := json.Marshal(res.StructuredContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Name != "checkout" || len(rec.Steps) != 2 {
+		t.Fatalf("recording = %+v, want 2 steps named checkout", rec)
+	}
+	if got, want := rec.Steps[0].ResolvedSelector, `[aria-label="Submit"]`; got != want {
+		t.Errorf("step 1 resolved selector = %q, want %q", got, want)
+	}
+
+	clicked = nil
+	res = call("replay_recording", map[string]any{"script": string(data)})
+	if res.IsError {
+		t.Fatalf("replay_recording failed")
+	}
+	// The replay uses the resolved selector rather than re-resolving "Submit".
+	if want := []string{`[aria-label="Submit"] (strict)`, "#next"}; len(clicked) != 2 || clicked[0] != want[0] || clicked[1] != want[1] {
+		t.Errorf("replayed clicks = %q, want %q", clicked, want)
+	}
+
+	clicked = nil
+	if res := call("replay_recording", map[string]any{"name": "checkout"}); res.IsError || len(clicked) != 2 {
+		t.Errorf("replay_recording of the saved recording clicked %q, want 2 clicks", clicked)
+	}
+	for _, name := range []string{"../checkout", "/etc/passwd", ".hidden"} {
+		if res := call("replay_recording", map[string]any{"name": name}); !res.IsError {
+			t.Errorf("replay_recording(name: %q) succeeded, want error", name)
+		}
+		call("start_recording", nil)
+		if res := call("stop_recording", map[string]any{"save_as": name}); !res.IsError {
+			t.Errorf("stop_recording(save_as: %q) succeeded, want error", name)
+		}
+	}
+
+	if res := call("stop_recording", nil); !res.IsError {
+		t.Error("stop_recording without a recording succeeded, want error")
+	}
+}
+
+func TestReplayStopsOnError(t *testing.T) {
+	s := &CDPBrowserServer{}
+	calls := 0
+	addTool(mcp.NewServer(&mcp.Implementation{Name: "test"}, nil), s, &mcp.Tool{Name: "click"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+		calls++
+		return &mcp.CallToolResultFor[struct{}]{IsError: req.Params.Arguments.Selector == "#bad"}, nil
+	})
+	rec := &Recording{Steps: []RecordedStep{
+		{Tool: "click", Arguments: json.RawMessage(`{"selector":"#bad"}`)},
+		{Tool: "click", Arguments: json.RawMessage(`{"selector":"#ok"}`)},
+	}}
+
+	if r := s.replay(context.Background(), rec, false, false); r.Passed || len(r.Steps) != 1 || calls != 1 {
+		t.Errorf("replay = %+v after %d calls, want failure after 1 step", r, calls)
+	}
+	calls = 0
+	if r := s.replay(context.Background(), rec, false, true); r.Passed || len(r.Steps) != 2 || calls != 2 {
+		t.Errorf("replay with continueOnError = %+v after %d calls, want failure after 2 steps", r, calls)
+	}
+}
+
+func TestReplayFallsBackToRecordedSelector(t *testing.T) {
+	s := &CDPBrowserServer{}
+	var clicked []string
+	addTool(mcp.NewServer(&mcp.Implementation{Name: "test"}, nil), s, &mcp.Tool{Name: "click"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+		args := req.Params.Arguments
+		clicked = append(clicked, args.Selector)
+		if args.Strict {
+			// The page is now in another language.
+			noteToolError(ctx, notFoundError{errors.New("no element matches")}, "")
+			return &mcp.CallToolResultFor[struct{}]{IsError: true}, nil
+		}
+		return &mcp.CallToolResultFor[struct{}]{}, nil
+	})
+	rec := &Recording{Steps: []RecordedStep{
+		{Tool: "click", Arguments: json.RawMessage(`{"selector":"Log in"}`), ResolvedSelector: `//button[text()="Log in"]`},
+	}}
+
+	if r := s.replay(context.Background(), rec, false, false); !r.Passed {
+		t.Errorf("replay = %+v, want success", r)
+	}
+	if want := []string{`//button[text()="Log in"]`, "Log in"}; !slices.Equal(clicked, want) {
+		t.Errorf("clicked = %q, want %q", clicked, want)
+	}
+}
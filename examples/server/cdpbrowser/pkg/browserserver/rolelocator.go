@@ -0,0 +1,271 @@
+package browserserver
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Role locators use the Playwright syntax
+//
+//	role=button[name="Submit"]
+//	role=checkbox[name="Remember me" s][checked]
+//	role=heading[level=2]
+//
+// and are translated to XPath so that they run like any other strategy. The
+// translation covers explicit roles and the implicit roles of native
+// elements, and computes accessible names from aria-label, aria-labelledby
+// (single id), label elements, alt, title, placeholder, value and text
+// content. It does not reproduce the full accessible name computation.
+
+// implicitRoles are XPath tests for native elements with an implicit role.
+// Elements with an explicit role attribute only match that role.
+var implicitRoles = map[string]string{
+	"button":      `self::button or self::summary or self::input[@type="button" or @type="submit" or @type="reset" or @type="image"]`,
+	"link":        `self::a[@href] or self::area[@href]`,
+	"textbox":     `self::textarea or self::input[not(@type) or @type="text" or @type="email" or @type="tel" or @type="url"]`,
+	"searchbox":   `self::input[@type="search"]`,
+	"checkbox":    `self::input[@type="checkbox"]`,
+	"radio":       `self::input[@type="radio"]`,
+	"combobox":    `self::select[not(@multiple)]`,
+	"listbox":     `self::select[@multiple] or self::datalist`,
+	"option":      `self::option`,
+	"slider":      `self::input[@type="range"]`,
+	"spinbutton":  `self::input[@type="number"]`,
+	"heading":     `self::h1 or self::h2 or self::h3 or self::h4 or self::h5 or self::h6`,
+	"img":         `self::img[not(@alt="")]`,
+	"list":        `self::ul or self::ol`,
+	"listitem":    `self::li`,
+	"table":       `self::table`,
+	"row":         `self::tr`,
+	"cell":        `self::td`,
+	"dialog":      `self::dialog`,
+	"navigation":  `self::nav`,
+	"main":        `self::main`,
+	"banner":      `self::header`,
+	"contentinfo": `self::footer`,
+	"form":        `self::form`,
+	"article":     `self::article`,
+}
+
+// roleStates are the boolean state attributes a role locator can filter on,
+// as XPath tests for the true state.
+var roleStates = map[string]string{
+	"checked":  `@checked or @aria-checked="true"`,
+	"disabled": `@disabled or @aria-disabled="true"`,
+	"expanded": `@aria-expanded="true" or @open`,
+	"pressed":  `@aria-pressed="true"`,
+	"selected": `@selected or @aria-selected="true"`,
+}
+
+// roleLocator is a parsed role=... selector.
+type roleLocator struct {
+	role          string
+	name          string
+	hasName       bool
+	exactName     bool // match the whole name, case-sensitively
+	level         int
+	states        map[string]bool
+	includeHidden bool
+}
+
+// roleAttribute is one [attr] or [attr=value] filter of a role locator.
+type roleAttribute struct {
+	name   string
+	value  string
+	quoted bool
+	flag   string // "i" or "s" after a quoted value
+}
+
+// parseRoleLocator parses a role=... selector.
+func parseRoleLocator(selector string) (*roleLocator, error) {
+	rest, ok := strings.CutPrefix(selector, "role=")
+	if !ok {
+		return nil, fmt.Errorf("not a role selector")
+	}
+	end := strings.IndexByte(rest, '[')
+	if end < 0 {
+		end = len(rest)
+	}
+	loc := &roleLocator{role: strings.TrimSpace(rest[:end]), states: make(map[string]bool)}
+	if loc.role == "" || strings.ContainsAny(loc.role, " \t") {
+		return nil, fmt.Errorf("invalid role %q", loc.role)
+	}
+	rest = rest[end:]
+	for rest != "" {
+		if rest[0] != '[' {
+			return nil, fmt.Errorf("unexpected %q after role attribute", rest)
+		}
+		attr, n, err := parseRoleAttribute(rest[1:])
+		if err != nil {
+			return nil, err
+		}
+		rest = strings.TrimSpace(rest[1+n:])
+		if err := loc.set(attr); err != nil {
+			return nil, err
+		}
+	}
+	return loc, nil
+}
+
+// parseRoleAttribute parses the inside of one [attr] or [attr=value] filter
+// and returns it with the number of bytes consumed, including the closing
+// bracket.
+func parseRoleAttribute(s string) (roleAttribute, int, error) {
+	var attr roleAttribute
+	i := strings.IndexAny(s, "=]")
+	if i < 0 {
+		return attr, 0, fmt.Errorf("unterminated role attribute %q", s)
+	}
+	attr.name = strings.TrimSpace(s[:i])
+	if s[i] == ']' {
+		return attr, i + 1, nil
+	}
+	pos := i + 1
+	for pos < len(s) && s[pos] == ' ' {
+		pos++
+	}
+	if pos < len(s) && (s[pos] == '"' || s[pos] == '\'') {
+		// A quoted string with backslash escapes, optionally followed by
+		// an i or s flag.
+		quote := s[pos]
+		var value strings.Builder
+		pos++
+		for ; pos < len(s) && s[pos] != quote; pos++ {
+			if s[pos] == '\\' && pos+1 < len(s) {
+				pos++
+			}
+			value.WriteByte(s[pos])
+		}
+		if pos >= len(s) {
+			return attr, 0, fmt.Errorf("unterminated string in role attribute %q", s)
+		}
+		attr.value, attr.quoted = value.String(), true
+		pos++
+	}
+	close := strings.IndexByte(s[pos:], ']')
+	if close < 0 {
+		return attr, 0, fmt.Errorf("unterminated role attribute %q", s)
+	}
+	rest := strings.TrimSpace(s[pos : pos+close])
+	if attr.quoted {
+		if rest != "" && rest != "i" && rest != "s" {
+			return attr, 0, fmt.Errorf("unknown flag %q in role attribute %q", rest, s)
+		}
+		attr.flag = rest
+	} else {
+		attr.value = rest
+	}
+	return attr, pos + close + 1, nil
+}
+
+// set applies one attribute filter to l.
+func (l *roleLocator) set(attr roleAttribute) error {
+	switch {
+	case attr.name == "name":
+		if !attr.quoted {
+			return fmt.Errorf("role name must be a quoted string")
+		}
+		l.name, l.hasName, l.exactName = attr.value, true, attr.flag == "s"
+	case attr.name == "level":
+		level, err := strconv.Atoi(attr.value)
+		if err != nil || level < 1 {
+			return fmt.Errorf("invalid heading level %q", attr.value)
+		}
+		l.level = level
+	case attr.name == "include-hidden":
+		l.includeHidden = attr.value != "false"
+	case roleStates[attr.name] != "":
+		switch attr.value {
+		case "", "true":
+			l.states[attr.name] = true
+		case "false":
+			l.states[attr.name] = false
+		default:
+			return fmt.Errorf("invalid value %q for %s", attr.value, attr.name)
+		}
+	default:
+		return fmt.Errorf("unsupported role attribute %q", attr.name)
+	}
+	return nil
+}
+
+// xpathLower lowercases the ASCII letters of the XPath expression expr.
+func xpathLower(expr string) string {
+	return fmt.Sprintf(`translate(%s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ", "abcdefghijklmnopqrstuvwxyz")`, expr)
+}
+
+// nameTest returns an XPath test comparing the string expression expr with
+// the locator's name.
+func (l *roleLocator) nameTest(expr string) string {
+	if l.exactName {
+		return fmt.Sprintf("normalize-space(%s)=%s", expr, xpathString(l.name))
+	}
+	return fmt.Sprintf("contains(%s, %s)", xpathLower("normalize-space("+expr+")"), xpathString(strings.ToLower(l.name)))
+}
+
+// xpath translates l to an XPath expression.
+func (l *roleLocator) xpath() string {
+	roleTest := fmt.Sprintf("@role=%s", xpathString(l.role))
+	if implicit, ok := implicitRoles[l.role]; ok {
+		roleTest = fmt.Sprintf("%s or (not(@role) and (%s))", roleTest, implicit)
+	}
+	tests := []string{roleTest}
+
+	if l.level > 0 {
+		tests = append(tests, fmt.Sprintf(`@aria-level="%d" or (not(@aria-level) and self::h%d)`, l.level, l.level))
+	}
+	states := make([]string, 0, len(l.states))
+	for state := range l.states {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		if l.states[state] {
+			tests = append(tests, roleStates[state])
+		} else {
+			tests = append(tests, "not("+roleStates[state]+")")
+		}
+	}
+	if !l.includeHidden {
+		tests = append(tests, `not(ancestor-or-self::*[@hidden or @aria-hidden="true"])`)
+	}
+	if l.hasName {
+		names := []string{
+			l.nameTest("@aria-label"),
+			fmt.Sprintf("@aria-labelledby=//*[%s]/@id", l.nameTest(".")),
+			fmt.Sprintf("(not(@aria-label) and not(@aria-labelledby) and (%s))", strings.Join([]string{
+				fmt.Sprintf("@id=//label[%s]/@for", l.nameTest(".")),
+				fmt.Sprintf("ancestor::label[%s]", l.nameTest(".")),
+				l.nameTest("@alt"),
+				l.nameTest("@value"),
+				l.nameTest("@placeholder"),
+				l.nameTest("@title"),
+				l.nameTest("."),
+			}, " or ")),
+		}
+		tests = append(tests, strings.Join(names, " or "))
+	}
+
+	var b strings.Builder
+	b.WriteString("//*")
+	for _, t := range tests {
+		b.WriteString("[" + t + "]")
+	}
+	return b.String()
+}
+
+// locateRole is the role strategy: it applies to role=... selectors only.
+func locateRole(selector string) (SelectorLocator, bool) {
+	if !strings.HasPrefix(selector, "role=") {
+		return SelectorLocator{}, false
+	}
+	loc, err := parseRoleLocator(selector)
+	if err != nil {
+		log.Printf("Smart selector: invalid role selector %q: %v", selector, err)
+		return SelectorLocator{}, false
+	}
+	return SelectorLocator{Query: loc.xpath(), XPath: true}, true
+}
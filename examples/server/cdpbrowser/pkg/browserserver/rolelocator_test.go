@@ -0,0 +1,96 @@
+package browserserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRoleLocator(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     roleLocator
+	}{
+		{`role=button`, roleLocator{role: "button"}},
+		{`role=button[name="Submit"]`, roleLocator{role: "button", name: "Submit", hasName: true}},
+		{`role=button[name='Say "hi"' s]`, roleLocator{role: "button", name: `Say "hi"`, hasName: true, exactName: true}},
+		{`role=link[name="a \"b\" ] c" i]`, roleLocator{role: "link", name: `a "b" ] c`, hasName: true}},
+		{`role=heading[level=2]`, roleLocator{role: "heading", level: 2}},
+		{`role=checkbox[checked][disabled=false]`, roleLocator{role: "checkbox", states: map[string]bool{"checked": true, "disabled": false}}},
+		{`role=dialog [include-hidden]`, roleLocator{role: "dialog", includeHidden: true}},
+	}
+	for _, tt := range tests {
+		got, err := parseRoleLocator(tt.selector)
+		if err != nil {
+			t.Errorf("parseRoleLocator(%q) = %v", tt.selector, err)
+			continue
+		}
+		if got.role != tt.want.role || got.name != tt.want.name || got.hasName != tt.want.hasName ||
+			got.exactName != tt.want.exactName || got.level != tt.want.level || got.includeHidden != tt.want.includeHidden {
+			t.Errorf("parseRoleLocator(%q) = %+v, want %+v", tt.selector, *got, tt.want)
+		}
+		for state, on := range tt.want.states {
+			if v, ok := got.states[state]; !ok || v != on {
+				t.Errorf("parseRoleLocator(%q) state %s = %t, %t, want %t", tt.selector, state, v, ok, on)
+			}
+		}
+	}
+}
+
+func TestParseRoleLocatorErrors(t *testing.T) {
+	for _, selector := range []string{
+		`button[name="x"]`,
+		`role=`,
+		`role=button[name=Submit]`,
+		`role=button[name="x"`,
+		`role=button[name="x" q]`,
+		`role=button[name="x]`,
+		`role=heading[level=two]`,
+		`role=button[color="red"]`,
+		`role=checkbox[checked=maybe]`,
+		`role=button x`,
+	} {
+		if _, err := parseRoleLocator(selector); err == nil {
+			t.Errorf("parseRoleLocator(%q) succeeded, want error", selector)
+		}
+	}
+}
+
+func TestRoleLocatorXPath(t *testing.T) {
+	tests := []struct {
+		selector string
+		contains []string
+		excludes []string
+	}{
+		{`role=button[name="Submit"]`,
+			[]string{`@role="button" or (not(@role) and (self::button`, `"submit"`, `@aria-labelledby=`, `@hidden`},
+			[]string{`="Submit"`}},
+		{`role=button[name="Submit" s]`,
+			[]string{`normalize-space(@aria-label)="Submit"`, `normalize-space(.)="Submit"`},
+			[]string{"translate("}},
+		{`role=tab`,
+			[]string{`//*[@role="tab"]`},
+			[]string{"not(@role)"}},
+		{`role=heading[level=3][include-hidden]`,
+			[]string{`[@aria-level="3" or (not(@aria-level) and self::h3)]`},
+			[]string{"@hidden"}},
+		{`role=checkbox[checked=false]`,
+			[]string{`[not(@checked or @aria-checked="true")]`},
+			nil},
+	}
+	for _, tt := range tests {
+		loc, ok := locateRole(tt.selector)
+		if !ok || !loc.XPath {
+			t.Fatalf("locateRole(%q) = %+v, %t, want an XPath", tt.selector, loc, ok)
+		}
+		for _, want := range tt.contains {
+			if !strings.Contains(loc.Query, want) {
+				t.Errorf("locateRole(%q) = %s\nmissing %s", tt.selector, loc.Query, want)
+			}
+		}
+		for _, unwanted := range tt.excludes {
+			if strings.Contains(loc.Query, unwanted) {
+				t.Errorf("locateRole(%q) = %s\nunexpectedly contains %s", tt.selector, loc.Query, unwanted)
+			}
+		}
+	}
+}
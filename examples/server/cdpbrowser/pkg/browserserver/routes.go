@@ -0,0 +1,320 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxRouteChanges bounds the route changes kept for wait_for_route_change.
+const maxRouteChanges = 100
+
+// Kinds of route changes. Same-document navigations the history hook did
+// not report are routeHistory or routeHashChange.
+const (
+	routeNavigation   = "navigation"
+	routePushState    = "pushState"
+	routeReplaceState = "replaceState"
+	routePopState     = "popstate"
+	routeHashChange   = "hashchange"
+	routeHistory      = "history"
+)
+
+// routeBinding is the name of the binding the history hook reports through.
+const routeBinding = "cdpbrowserRouteChanged"
+
+// routeHookJS wraps the History API of the top-level document and listens
+// for popstate and hashchange, reporting each change with its kind and the
+// new URL through routeBinding. Page.navigatedWithinDocument only tells
+// that the History API was used, not how.
+const routeHookJS = `(() => {
+	if (window !== window.top || typeof window.` + routeBinding + ` !== 'function' || history.__cdpbrowserRoutes) {
+		return;
+	}
+	const report = kind => window.` + routeBinding + `(JSON.stringify({kind: kind, url: location.href}));
+	for (const kind of ['pushState', 'replaceState']) {
+		const original = history[kind];
+		history[kind] = function(...args) {
+			const result = original.apply(this, args);
+			report(kind);
+			return result;
+		};
+	}
+	Object.defineProperty(history, '__cdpbrowserRoutes', {value: true});
+	window.addEventListener('popstate', () => report('popstate'));
+	window.addEventListener('hashchange', () => report('hashchange'));
+})()`
+
+// RouteChange is a change of the URL of the page: a navigation to a new
+// document, or a same-document change made by a single-page app.
+type RouteChange struct {
+	Seq  int       `json:"seq" jsonschema:"Sequence number of the change, increasing over the life of the browser"`
+	Time time.Time `json:"time"`
+	URL  string    `json:"url"`
+	Kind string    `json:"kind" jsonschema:"navigation for a new document; pushState, replaceState, popstate, or hashchange for a same-document change; history if the page changed the URL in another way"`
+}
+
+// routeTracker follows the URL of the tab the tools drive. Single-page apps
+// change it with the History API and fragments without loading a new
+// document, so navigating is not the only way the current URL changes.
+type routeTracker struct {
+	mu sync.Mutex
+	// listening is set once the tracker follows the events of a tab, which
+	// report the URL after redirects.
+	listening bool
+	mainFrame cdp.FrameID
+	url       string
+	// changes are the most recent route changes, oldest first.
+	changes []RouteChange
+	// seq is the sequence number of the latest change.
+	seq int
+	// hint is the change the history hook reported before the CDP event
+	// for it arrived; settled is set once the latest change got its kind
+	// from the hook.
+	hint    *RouteChange
+	settled bool
+	// changed is closed and replaced when a change is recorded.
+	changed chan struct{}
+}
+
+// start follows the route changes of the tab of tabCtx: navigations from
+// the Page domain, and how same-document changes were made from a hook in
+// every top-level document.
+func (t *routeTracker) start(tabCtx context.Context) error {
+	c := chromedp.FromContext(tabCtx)
+	if c == nil || c.Target == nil {
+		return fmt.Errorf("no tab to track")
+	}
+	t.mu.Lock()
+	t.listening = true
+	// The main frame of a tab has the ID of its target.
+	t.mainFrame = cdp.FrameID(c.Target.TargetID)
+	t.changes, t.hint, t.settled = nil, nil, false
+	t.mu.Unlock()
+	chromedp.ListenTarget(tabCtx, t.event)
+
+	return chromedp.Run(tabCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := runtime.AddBinding(routeBinding).Do(ctx); err != nil {
+			return err
+		}
+		if _, err := page.AddScriptToEvaluateOnNewDocument(routeHookJS).Do(ctx); err != nil {
+			return err
+		}
+		var url string
+		if err := chromedp.Evaluate(routeHookJS+`, location.href`, &url).Do(ctx); err != nil {
+			return err
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.url == "" {
+			t.url = url
+		}
+		return nil
+	}))
+}
+
+// event records the route changes of the main frame. It runs on chromedp's
+// event loop, so it must not block.
+func (t *routeTracker) event(ev any) {
+	switch ev := ev.(type) {
+	case *page.EventFrameNavigated:
+		if ev.Frame.ParentID != "" {
+			return
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.mainFrame = ev.Frame.ID
+		t.hint = nil
+		t.record(ev.Frame.URL+ev.Frame.URLFragment, routeNavigation)
+	case *page.EventNavigatedWithinDocument:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if ev.FrameID != t.mainFrame {
+			return
+		}
+		if t.hint != nil && t.hint.URL == ev.URL {
+			t.record(ev.URL, t.hint.Kind)
+			t.hint, t.settled = nil, true
+			return
+		}
+		kind := routeHistory
+		if ev.NavigationType == page.NavigatedWithinDocumentNavigationTypeFragment {
+			kind = routeHashChange
+		}
+		t.record(ev.URL, kind)
+	case *runtime.EventBindingCalled:
+		if ev.Name != routeBinding {
+			return
+		}
+		var hint RouteChange
+		if err := json.Unmarshal([]byte(ev.Payload), &hint); err != nil {
+			return
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.hinted(hint)
+	}
+}
+
+// hinted takes the kind of a same-document change from the history hook.
+// The hook and the CDP event for a change arrive in either order: a hint
+// after the event gives the change it recorded its kind, while a hint
+// before the event is kept for it.
+func (t *routeTracker) hinted(hint RouteChange) {
+	if n := len(t.changes); n > 0 && !t.settled {
+		last := &t.changes[n-1]
+		if last.URL == hint.URL && (last.Kind == routeHistory || last.Kind == hint.Kind) {
+			last.Kind = hint.Kind
+			t.settled = true
+			return
+		}
+	}
+	t.hint = &hint
+}
+
+// record adds a change to url of the given kind and wakes up the waiters.
+// t.mu must be held.
+func (t *routeTracker) record(url, kind string) {
+	t.seq++
+	t.url = url
+	t.settled = false
+	t.changes = append(t.changes, RouteChange{Seq: t.seq, Time: time.Now(), URL: url, Kind: kind})
+	if over := len(t.changes) - maxRouteChanges; over > 0 {
+		t.changes = append([]RouteChange(nil), t.changes[over:]...)
+	}
+	if t.changed != nil {
+		close(t.changed)
+		t.changed = nil
+	}
+}
+
+// navigated sets the current URL after the navigate tool loaded url, for
+// backends whose events the tracker does not follow.
+func (t *routeTracker) navigated(url string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.listening {
+		t.url = url
+	}
+}
+
+// current returns the URL of the page.
+func (t *routeTracker) current() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.url
+}
+
+// latest returns the sequence number of the latest change.
+func (t *routeTracker) latest() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.seq
+}
+
+// wait returns the first change after sequence number since whose URL
+// matches re, which may be nil to match any, waiting for it until ctx is
+// done.
+func (t *routeTracker) wait(ctx context.Context, since int, re *regexp.Regexp) (RouteChange, error) {
+	for {
+		t.mu.Lock()
+		for _, c := range t.changes {
+			if c.Seq > since && (re == nil || re.MatchString(c.URL)) {
+				t.mu.Unlock()
+				return c, nil
+			}
+		}
+		if t.changed == nil {
+			t.changed = make(chan struct{})
+		}
+		changed := t.changed
+		t.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return RouteChange{}, ctx.Err()
+		}
+	}
+}
+
+type WaitForRouteChangeArgs struct {
+	URLPattern     string `json:"url_pattern,omitempty" jsonschema:"Regular expression (RE2 syntax) the new URL must match somewhere; if the current URL already matches, the tool returns at once"`
+	Since          int    `json:"since,omitempty" jsonschema:"Return the first change with a greater seq, even one made before the call, such as the seq of a previous result; by default only changes after the call count"`
+	TimeoutSeconds *int   `json:"timeout_seconds,omitempty" jsonschema:"How long to wait for the change, up to 120 (default: 5)"`
+}
+
+// WaitForRouteChangeResult is the structured result of
+// wait_for_route_change.
+type WaitForRouteChangeResult struct {
+	RouteChange
+	// AlreadyMatched is set if the current URL matched url_pattern when
+	// the call was made, so there was no change to wait for.
+	AlreadyMatched bool `json:"already_matched,omitempty"`
+}
+
+// WaitForRouteChange tool - waits until the URL of the page changes, by a
+// navigation or by a single-page app updating the route without loading a
+// new document.
+func (s *CDPBrowserServer) WaitForRouteChange(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[WaitForRouteChangeArgs]]) (*mcp.CallToolResultFor[WaitForRouteChangeResult], error) {
+	args := req.Params.Arguments
+	var re *regexp.Regexp
+	if args.URLPattern != "" {
+		var err error
+		if re, err = regexp.Compile(args.URLPattern); err != nil {
+			err = invalidArgumentError{fmt.Errorf("invalid url_pattern %q: %v", args.URLPattern, err)}
+			noteToolError(ctx, err, "")
+			return &mcp.CallToolResultFor[WaitForRouteChangeResult]{
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error waiting for a route change: %v", err)}},
+				IsError: true,
+			}, nil
+		}
+	}
+
+	since := args.Since
+	if since <= 0 {
+		since = s.routes.latest()
+		if url := s.routes.current(); re != nil && re.MatchString(url) {
+			result := WaitForRouteChangeResult{RouteChange: RouteChange{Seq: since, Time: time.Now(), URL: url}, AlreadyMatched: true}
+			return &mcp.CallToolResultFor[WaitForRouteChangeResult]{
+				Content:           []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("The URL %s already matches %s", url, args.URLPattern)}},
+				StructuredContent: result,
+			}, nil
+		}
+	}
+
+	timeout := assertTimeout(args.TimeoutSeconds)
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	change, err := s.routes.wait(waitCtx, since, re)
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("no route change within %v; the URL is still %s", timeout, s.routes.current())
+			if re != nil {
+				err = fmt.Errorf("no route change to a URL matching %s within %v; the URL is %s", args.URLPattern, timeout, s.routes.current())
+			}
+		}
+		noteToolError(ctx, err, CodeTimeout)
+		return &mcp.CallToolResultFor[WaitForRouteChangeResult]{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error waiting for a route change: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResultFor[WaitForRouteChangeResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Route changed to %s by %s (seq %d, after %v)", change.URL, change.Kind, change.Seq, time.Since(start).Round(time.Millisecond))},
+		},
+		StructuredContent: WaitForRouteChangeResult{RouteChange: change},
+	}, nil
+}
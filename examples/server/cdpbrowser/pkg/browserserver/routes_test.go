@@ -0,0 +1,108 @@
+package browserserver
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+)
+
+func TestRouteTracker(t *testing.T) {
+	tr := routeTracker{listening: true, mainFrame: "main"}
+	hook := func(kind, url string) {
+		tr.event(&runtime.EventBindingCalled{Name: routeBinding, Payload: `{"kind":"` + kind + `","url":"` + url + `"}`})
+	}
+	within := func(url string, typ page.NavigatedWithinDocumentNavigationType) {
+		tr.event(&page.EventNavigatedWithinDocument{FrameID: "main", URL: url, NavigationType: typ})
+	}
+
+	tr.event(&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "main", URL: "https://app.example/", URLFragment: "#top"}})
+	// Navigations of iframes are not route changes.
+	tr.event(&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "ad", ParentID: "main", URL: "https://ads.example/"}})
+	tr.event(&page.EventNavigatedWithinDocument{FrameID: "ad", URL: "https://ads.example/#2"})
+	// The hook reports a change after its event...
+	within("https://app.example/inbox", page.NavigatedWithinDocumentNavigationTypeHistoryAPI)
+	hook(routePushState, "https://app.example/inbox")
+	// ...or before it.
+	hook(routeReplaceState, "https://app.example/inbox?page=2")
+	within("https://app.example/inbox?page=2", page.NavigatedWithinDocumentNavigationTypeHistoryAPI)
+	// Without the hook, the kind is guessed from the event.
+	within("https://app.example/inbox?page=2#msg", page.NavigatedWithinDocumentNavigationTypeFragment)
+	within("https://app.example/", page.NavigatedWithinDocumentNavigationTypeOther)
+	// Unrelated binding calls are ignored.
+	tr.event(&runtime.EventBindingCalled{Name: "other", Payload: "{}"})
+
+	want := []RouteChange{
+		{Seq: 1, URL: "https://app.example/#top", Kind: routeNavigation},
+		{Seq: 2, URL: "https://app.example/inbox", Kind: routePushState},
+		{Seq: 3, URL: "https://app.example/inbox?page=2", Kind: routeReplaceState},
+		{Seq: 4, URL: "https://app.example/inbox?page=2#msg", Kind: routeHashChange},
+		{Seq: 5, URL: "https://app.example/", Kind: routeHistory},
+	}
+	if len(tr.changes) != len(want) {
+		t.Fatalf("changes = %+v, want %d", tr.changes, len(want))
+	}
+	for i, c := range tr.changes {
+		if c.Seq != want[i].Seq || c.URL != want[i].URL || c.Kind != want[i].Kind {
+			t.Errorf("change %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+	if got := tr.current(); got != "https://app.example/" {
+		t.Errorf("current() = %q", got)
+	}
+
+	// Events set the URL, not the navigate tool.
+	tr.navigated("https://app.example/requested")
+	if got := tr.current(); got != "https://app.example/" {
+		t.Errorf("current() after navigated = %q", got)
+	}
+}
+
+func TestRouteTrackerWait(t *testing.T) {
+	tr := routeTracker{listening: true, mainFrame: "main"}
+	tr.event(&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "main", URL: "https://app.example/"}})
+	ctx := context.Background()
+
+	// Recorded changes after since are returned at once.
+	if c, err := tr.wait(ctx, 0, nil); err != nil || c.Seq != 1 {
+		t.Errorf("wait(0) = %+v, %v", c, err)
+	}
+
+	done := make(chan RouteChange)
+	go func() {
+		c, err := tr.wait(ctx, tr.latest(), regexp.MustCompile(`/settings$`))
+		if err != nil {
+			t.Error(err)
+		}
+		done <- c
+	}()
+	time.Sleep(10 * time.Millisecond)
+	tr.event(&page.EventNavigatedWithinDocument{FrameID: "main", URL: "https://app.example/inbox", NavigationType: page.NavigatedWithinDocumentNavigationTypeHistoryAPI})
+	tr.event(&page.EventNavigatedWithinDocument{FrameID: "main", URL: "https://app.example/settings", NavigationType: page.NavigatedWithinDocumentNavigationTypeHistoryAPI})
+	select {
+	case c := <-done:
+		if c.Seq != 3 || c.URL != "https://app.example/settings" {
+			t.Errorf("wait for /settings = %+v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return after a matching change")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := tr.wait(ctx, tr.latest(), nil); err != context.DeadlineExceeded {
+		t.Errorf("wait without a change = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRouteTrackerWithoutEvents(t *testing.T) {
+	var tr routeTracker
+	tr.navigated("https://example.com/")
+	if got := tr.current(); got != "https://example.com/" {
+		t.Errorf("current() = %q", got)
+	}
+}
@@ -0,0 +1,490 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// A SelectorLocator is a query for an element, as CSS or XPath.
+type SelectorLocator struct {
+	Query string
+	XPath bool
+}
+
+// by returns the chromedp query option for l.
+func (l SelectorLocator) by() chromedp.QueryOption {
+	if l.XPath {
+		return chromedp.BySearch
+	}
+	return chromedp.ByQuery
+}
+
+// A SelectorStrategy turns the selector argument of a tool into a query for
+// the element the caller most likely meant.
+//
+// Smart-targeting tools try each enabled strategy in priority order and use
+// the first query that matches an element. Programs embedding the server can
+// add their own with [RegisterSelectorStrategy].
+type SelectorStrategy interface {
+	// Locate returns the query to try for selector, or false if the
+	// strategy does not apply to it.
+	Locate(selector string) (SelectorLocator, bool)
+}
+
+// SelectorStrategyFunc adapts an ordinary function to a SelectorStrategy.
+type SelectorStrategyFunc func(selector string) (SelectorLocator, bool)
+
+// Locate calls f(selector).
+func (f SelectorStrategyFunc) Locate(selector string) (SelectorLocator, bool) {
+	return f(selector)
+}
+
+// registeredStrategy is a named strategy with its default settings.
+type registeredStrategy struct {
+	name     string
+	priority int
+	enabled  bool
+	strategy SelectorStrategy
+}
+
+var (
+	selectorStrategiesMu sync.RWMutex
+	selectorStrategies   = map[string]registeredStrategy{}
+)
+
+func init() {
+	builtin := []struct {
+		name     string
+		priority int
+		enabled  bool
+		locate   SelectorStrategyFunc
+	}{
+		{"role", 5, true, locateRole},
+		{"aria-label", 10, true, attributeStrategy("aria-label", "=")},
+		{"xpath", 15, true, locateXPath},
+		{"css", 20, true, locateCSS},
+		{"id", 30, true, locateID},
+		{"data-testid", 32, true, testIDStrategy("data-testid")},
+		{"data-test", 34, true, testIDStrategy("data-test")},
+		{"data-cy", 36, true, testIDStrategy("data-cy")},
+		{"aria-label-partial", 40, true, attributeStrategy("aria-label", "*=")},
+		{"name", 50, true, attributeStrategy("name", "=")},
+		{"placeholder", 60, true, attributeStrategy("placeholder", "=")},
+		{"text", 70, true, locateText},
+		{"text-normalized", 75, true, foldedTextStrategy(caseFold)},
+		{"text-transliterated", 78, false, foldedTextStrategy(diacriticFold)},
+		{"text-partial", 80, true, locatePartialText},
+	}
+	for _, b := range builtin {
+		selectorStrategies[b.name] = registeredStrategy{b.name, b.priority, b.enabled, b.locate}
+	}
+}
+
+// RegisterSelectorStrategy makes a strategy available under name, replacing
+// any strategy previously registered with that name. Strategies run in
+// ascending priority order; the built-in ones range from 5 (role) to 80
+// (text-partial).
+func RegisterSelectorStrategy(name string, priority int, s SelectorStrategy) {
+	selectorStrategiesMu.Lock()
+	defer selectorStrategiesMu.Unlock()
+	selectorStrategies[name] = registeredStrategy{name, priority, true, s}
+}
+
+// cssString quotes s as a CSS string.
+func cssString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\a `).Replace(s) + `"`
+}
+
+// xpathString quotes s as an XPath string literal. XPath has no escapes, so
+// a string holding both kinds of quote is built with concat().
+func xpathString(s string) string {
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	parts := strings.Split(s, `"`)
+	for i, p := range parts {
+		parts[i] = `"` + p + `"`
+	}
+	return "concat(" + strings.Join(parts, `, '"', `) + ")"
+}
+
+// attributeStrategy matches elements whose attr matches the selector using
+// the CSS attribute operator op.
+func attributeStrategy(attr, op string) SelectorStrategyFunc {
+	return func(selector string) (SelectorLocator, bool) {
+		return SelectorLocator{Query: fmt.Sprintf("[%s%s%s]", attr, op, cssString(selector))}, true
+	}
+}
+
+// testIDAttributes are the attributes test frameworks use to tag elements.
+var testIDAttributes = map[string]bool{"data-testid": true, "data-test": true, "data-cy": true}
+
+// testIDStrategy matches elements whose test id attribute attr equals the
+// selector. A selector naming the attribute, such as data-cy=login, is only
+// tried against that attribute.
+func testIDStrategy(attr string) SelectorStrategyFunc {
+	return func(selector string) (SelectorLocator, bool) {
+		if name, value, ok := strings.Cut(selector, "="); ok && testIDAttributes[name] {
+			if name != attr {
+				return SelectorLocator{}, false
+			}
+			selector = value
+		}
+		return attributeStrategy(attr, "=")(selector)
+	}
+}
+
+// isXPath reports whether selector looks like an XPath expression.
+func isXPath(selector string) bool {
+	return strings.HasPrefix(selector, "/") || strings.HasPrefix(selector, "(/")
+}
+
+// typedSelector parses a selector argument whose prefix says how it is
+// meant, instead of leaving the server to guess: xpath= is followed by an
+// XPath expression, and text= by the text the element shows. It returns the
+// query and the prefix, or false if selector has neither prefix.
+func typedSelector(selector string) (loc SelectorLocator, prefix string, ok bool) {
+	prefix, rest, ok := strings.Cut(selector, "=")
+	if !ok || rest == "" {
+		return SelectorLocator{}, "", false
+	}
+	switch prefix {
+	case "xpath":
+		return SelectorLocator{Query: rest, XPath: true}, prefix, true
+	case "text":
+		// The innermost element whose text, with runs of whitespace
+		// collapsed, is the given text, or an input button with that label.
+		q := xpathString(strings.Join(strings.Fields(rest), " "))
+		return SelectorLocator{Query: fmt.Sprintf(`//body//*[normalize-space(.)=%s][not(.//*[normalize-space(.)=%s])] | //input[@type="button" or @type="submit" or @type="reset"][@value=%s]`, q, q, q), XPath: true}, prefix, true
+	}
+	return SelectorLocator{}, "", false
+}
+
+// literalLocator returns the query for selector used as written: as its
+// xpath= or text= prefix says, as XPath if it starts with /, and as CSS
+// otherwise.
+func literalLocator(selector string) SelectorLocator {
+	if loc, _, ok := typedSelector(selector); ok {
+		return loc
+	}
+	return SelectorLocator{Query: selector, XPath: isXPath(selector)}
+}
+
+// locateXPath uses XPath expressions as they are.
+func locateXPath(selector string) (SelectorLocator, bool) {
+	return SelectorLocator{Query: selector, XPath: true}, isXPath(selector)
+}
+
+// locateCSS uses the selector as a CSS selector.
+func locateCSS(selector string) (SelectorLocator, bool) {
+	return SelectorLocator{Query: selector}, !isXPath(selector)
+}
+
+// locateID treats a bare word as an element id.
+func locateID(selector string) (SelectorLocator, bool) {
+	if strings.ContainsAny(selector, "#.[ /") {
+		return SelectorLocator{}, false
+	}
+	return SelectorLocator{Query: "#" + selector}, true
+}
+
+// locateText matches buttons, links and input buttons by their exact text.
+func locateText(selector string) (SelectorLocator, bool) {
+	q := xpathString(selector)
+	return SelectorLocator{Query: fmt.Sprintf(`//button[text()=%s] | //a[text()=%s] | //input[@value=%s]`, q, q, q), XPath: true}, true
+}
+
+// locatePartialText matches buttons, links and input buttons whose text
+// contains the selector.
+func locatePartialText(selector string) (SelectorLocator, bool) {
+	q := xpathString(selector)
+	return SelectorLocator{Query: fmt.Sprintf(`//button[contains(text(), %s)] | //a[contains(text(), %s)] | //input[contains(@value, %s)]`, q, q, q), XPath: true}, true
+}
+
+// selectorConfig adjusts the smart selector pipeline. It is loaded from the
+// JSON file given with -selector-config, for example:
+//
+//	{
+//	  "strategies": {"aria-label-partial": {"enabled": false}, "data-testid": {"priority": 5}},
+//	  "custom": [{"name": "data-qa", "css": "[data-qa={selector}]", "priority": 25}],
+//	  "translations": [["Log in", "Anmelden", "Se connecter"]]
+//	}
+type selectorConfig struct {
+	Strategies map[string]strategySettings `json:"strategies"`
+	Custom     []customStrategy            `json:"custom"`
+	// Translations are groups of labels that mean the same in different
+	// locales. When no strategy matches a selector in a group, the others
+	// are tried in turn.
+	Translations [][]string `json:"translations"`
+}
+
+// strategySettings overrides the defaults of a registered strategy.
+type strategySettings struct {
+	Enabled  *bool `json:"enabled"`
+	Priority *int  `json:"priority"`
+}
+
+// customStrategy is a strategy defined in the selector config by a CSS or
+// XPath template. {selector} in the template is replaced by the selector
+// argument, quoted as a string literal.
+type customStrategy struct {
+	Name     string `json:"name"`
+	CSS      string `json:"css"`
+	XPath    string `json:"xpath"`
+	Priority int    `json:"priority"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Locate fills in the template.
+func (c customStrategy) Locate(selector string) (SelectorLocator, bool) {
+	if c.XPath != "" {
+		return SelectorLocator{Query: strings.ReplaceAll(c.XPath, "{selector}", xpathString(selector)), XPath: true}, true
+	}
+	return SelectorLocator{Query: strings.ReplaceAll(c.CSS, "{selector}", cssString(selector))}, true
+}
+
+// loadSelectorConfig reads a JSON selector config file.
+func loadSelectorConfig(path string) (*selectorConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read selector config: %v", err)
+	}
+	var c selectorConfig
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse selector config %s: %v", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid selector config %s: %v", path, err)
+	}
+	return &c, nil
+}
+
+// validate checks that c only refers to known strategies and that custom
+// strategies are well formed.
+func (c *selectorConfig) validate() error {
+	selectorStrategiesMu.RLock()
+	defer selectorStrategiesMu.RUnlock()
+	custom := make(map[string]bool)
+	for _, cs := range c.Custom {
+		switch {
+		case cs.Name == "":
+			return fmt.Errorf("custom strategy without a name")
+		case custom[cs.Name] || selectorStrategies[cs.Name].strategy != nil:
+			return fmt.Errorf("duplicate strategy %q", cs.Name)
+		case (cs.CSS == "") == (cs.XPath == ""):
+			return fmt.Errorf("custom strategy %q needs exactly one of css and xpath", cs.Name)
+		}
+		custom[cs.Name] = true
+	}
+	for name := range c.Strategies {
+		if selectorStrategies[name].strategy == nil && !custom[name] {
+			return fmt.Errorf("unknown strategy %q", name)
+		}
+	}
+	for _, group := range c.Translations {
+		if len(group) < 2 || slices.Contains(group, "") {
+			return fmt.Errorf("translation group %q needs at least two labels, none empty", group)
+		}
+	}
+	return nil
+}
+
+// selectorPipeline returns the enabled strategies in the order they are
+// tried: registered and custom strategies, adjusted by config, sorted by
+// priority and then name. config may be nil.
+func selectorPipeline(config *selectorConfig) []registeredStrategy {
+	selectorStrategiesMu.RLock()
+	all := make([]registeredStrategy, 0, len(selectorStrategies))
+	for _, rs := range selectorStrategies {
+		all = append(all, rs)
+	}
+	selectorStrategiesMu.RUnlock()
+
+	if config != nil {
+		for _, cs := range config.Custom {
+			all = append(all, registeredStrategy{cs.Name, cs.Priority, !cs.Disabled, cs})
+		}
+	}
+	var pipeline []registeredStrategy
+	for _, rs := range all {
+		if config != nil {
+			if settings, ok := config.Strategies[rs.name]; ok {
+				if settings.Enabled != nil {
+					rs.enabled = *settings.Enabled
+				}
+				if settings.Priority != nil {
+					rs.priority = *settings.Priority
+				}
+			}
+		}
+		if rs.enabled {
+			pipeline = append(pipeline, rs)
+		}
+	}
+	sort.Slice(pipeline, func(i, j int) bool {
+		if pipeline[i].priority != pipeline[j].priority {
+			return pipeline[i].priority < pipeline[j].priority
+		}
+		return pipeline[i].name < pipeline[j].name
+	})
+	return pipeline
+}
+
+// countMatches returns the number of elements loc matches in the page.
+func (s *CDPBrowserServer) countMatches(ctx context.Context, loc SelectorLocator) (int, error) {
+	if !s.usesCDP() {
+		return s.countInPage(ctx, loc)
+	}
+	var nodes []*cdp.Node
+	err := s.run(ctx, chromedp.Nodes(loc.Query, &nodes, loc.by(), chromedp.AtLeast(0)))
+	return len(nodes), err
+}
+
+// SelectorMatch reports how a tool resolved its selector argument and, for
+// tools that act on the element, what the action did to the page.
+type SelectorMatch struct {
+	Selector string `json:"selector" jsonschema:"The query that matched the element"`
+	XPath    bool   `json:"xpath,omitempty" jsonschema:"Whether selector is an XPath expression rather than CSS"`
+	Strategy string `json:"strategy" jsonschema:"Name of the selector strategy that matched the element, fallback if none did, strict, or the xpath= or text= prefix of the selector"`
+	// Translation is the label the strategy matched instead of the
+	// selector, from the translations of the selector config.
+	Translation string `json:"translation,omitempty" jsonschema:"The translation of the selector that matched, if the selector itself did not"`
+	// Reresolved is set when the element the selector first resolved to
+	// left the document, as single-page apps do when they re-render, and
+	// the action was retried on the element found again.
+	Reresolved bool           `json:"reresolved,omitempty" jsonschema:"Whether the element was replaced by a re-render and found again by its selector"`
+	Effects    *ActionEffects `json:"effects,omitempty" jsonschema:"What the action did to the page"`
+
+	// tried lists the strategies that were tried and did not match before
+	// Strategy, for error reports.
+	tried []string
+}
+
+// locator returns the query of m.
+func (m SelectorMatch) locator() SelectorLocator {
+	return SelectorLocator{Query: m.Selector, XPath: m.XPath}
+}
+
+// literal returns the query of m as a selector argument that is used as
+// written, for replays.
+func (m SelectorMatch) literal() string {
+	if m.XPath && !isXPath(m.Selector) {
+		return "xpath=" + m.Selector
+	}
+	return m.Selector
+}
+
+// resolveSelector returns the query a selector-taking tool should use. A
+// selector with an xpath= or text= prefix is used as the prefix says. With
+// strict, the selector is used as written: as XPath if it starts with /, and
+// as CSS otherwise. Without strict, it is resolved with the smart selector
+// pipeline, falling back to the selector as written so that the tool can
+// still wait for an element that has not appeared yet.
+func (s *CDPBrowserServer) resolveSelector(ctx context.Context, selector string, strict bool) SelectorMatch {
+	if loc, prefix, ok := typedSelector(selector); ok {
+		return SelectorMatch{Selector: loc.Query, XPath: loc.XPath, Strategy: prefix + "="}
+	}
+	literal := SelectorMatch{Selector: selector, XPath: isXPath(selector), Strategy: "strict"}
+	if strict {
+		return literal
+	}
+	match, err := s.findElementWithSmartSelector(ctx, selector)
+	if err != nil {
+		literal.Strategy = "fallback"
+		literal.tried = match.tried
+		return literal
+	}
+	return match
+}
+
+// selectorResult returns the result of a tool that acted on the element
+// selected by match, and notes the resolved selector for recording.
+func selectorResult(ctx context.Context, text string, match SelectorMatch) *mcp.CallToolResultFor[SelectorMatch] {
+	noteResolvedSelector(ctx, match.literal())
+	if match.Translation != "" {
+		text = fmt.Sprintf("%s (strategy: %s, translation: %s%s)", text, match.Strategy, match.Translation, reresolvedNote(match.Reresolved))
+	} else {
+		text = fmt.Sprintf("%s (strategy: %s%s)", text, match.Strategy, reresolvedNote(match.Reresolved))
+	}
+	if match.Effects != nil {
+		text += "\n" + match.Effects.String()
+	}
+	return &mcp.CallToolResultFor[SelectorMatch]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+		StructuredContent: match,
+	}
+}
+
+// reresolvedNote returns the note added to the strategy of a result when
+// the element had to be found again after a re-render.
+func reresolvedNote(reresolved bool) string {
+	if reresolved {
+		return ", re-resolved after a re-render"
+	}
+	return ""
+}
+
+// findElementWithSmartSelector tries each strategy of the selector pipeline
+// in turn and returns the first query that matches an element in the page.
+// If none matches, the translations of the selector in the selector config
+// are tried the same way. If nothing matches, the returned match lists the
+// strategies tried.
+func (s *CDPBrowserServer) findElementWithSmartSelector(ctx context.Context, selector string) (SelectorMatch, error) {
+	log.Printf("Smart selector: Trying to find element with selector '%s'", selector)
+
+	pipeline := selectorPipeline(s.selectorConfig)
+	match, tried, ok := s.tryStrategies(ctx, pipeline, selector)
+	if ok {
+		return match, nil
+	}
+	translations := s.selectorConfig.translations(selector)
+	for _, label := range translations {
+		log.Printf("Smart selector: Trying translation '%s' of '%s'", label, selector)
+		if match, _, ok := s.tryStrategies(ctx, pipeline, label); ok {
+			match.Translation = label
+			match.tried = tried
+			return match, nil
+		}
+	}
+	if len(translations) > 0 {
+		tried = append(tried, "translations")
+	}
+
+	log.Printf("Smart selector: All strategies failed for '%s'", selector)
+	return SelectorMatch{tried: tried}, notFoundError{fmt.Errorf("element not found with any targeting strategy: %s", selector)}
+}
+
+// tryStrategies returns the query of the first strategy of pipeline that
+// matches an element for selector, and the strategies tried before it.
+func (s *CDPBrowserServer) tryStrategies(ctx context.Context, pipeline []registeredStrategy, selector string) (match SelectorMatch, tried []string, ok bool) {
+	for _, rs := range pipeline {
+		loc, ok := rs.strategy.Locate(selector)
+		if !ok {
+			continue
+		}
+		n, err := s.countMatches(ctx, loc)
+		if err == nil && n > 0 {
+			log.Printf("Smart selector: Found element using %s strategy: %s", rs.name, loc.Query)
+			return SelectorMatch{Selector: loc.Query, XPath: loc.XPath, Strategy: rs.name, tried: tried}, tried, true
+		}
+		log.Printf("Smart selector: %s strategy failed for '%s'", rs.name, loc.Query)
+		tried = append(tried, rs.name)
+	}
+	return SelectorMatch{}, tried, false
+}
@@ -0,0 +1,189 @@
+package browserserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func pipelineNames(pipeline []registeredStrategy) []string {
+	var names []string
+	for _, rs := range pipeline {
+		names = append(names, rs.name)
+	}
+	return names
+}
+
+func TestSelectorPipelineDefaults(t *testing.T) {
+	got := strings.Join(pipelineNames(selectorPipeline(nil)), ",")
+	want := "role,aria-label,xpath,css,id,data-testid,data-test,data-cy,aria-label-partial,name,placeholder,text,text-normalized,text-partial"
+	if got != want {
+		t.Errorf("default pipeline = %s, want %s", got, want)
+	}
+}
+
+func TestSelectorPipelineConfig(t *testing.T) {
+	disabled, enabled, first := false, true, 1
+	config := &selectorConfig{
+		Strategies: map[string]strategySettings{
+			"aria-label":          {Enabled: &disabled},
+			"data-testid":         {Priority: &first},
+			"data-qa":             {Priority: &first},
+			"text-transliterated": {Enabled: &enabled},
+		},
+		Custom: []customStrategy{
+			{Name: "data-qa", CSS: "[data-qa={selector}]", Priority: 25},
+			{Name: "off", CSS: "x", Disabled: true},
+		},
+	}
+	if err := config.validate(); err != nil {
+		t.Fatalf("validate() = %v", err)
+	}
+	got := strings.Join(pipelineNames(selectorPipeline(config)), ",")
+	want := "data-qa,data-testid,role,xpath,css,id,data-test,data-cy,aria-label-partial,name,placeholder,text,text-normalized,text-transliterated,text-partial"
+	if got != want {
+		t.Errorf("configured pipeline = %s, want %s", got, want)
+	}
+}
+
+func TestSelectorConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config selectorConfig
+		want   string
+	}{
+		{"unknown strategy", selectorConfig{Strategies: map[string]strategySettings{"nope": {}}}, `unknown strategy "nope"`},
+		{"unnamed custom", selectorConfig{Custom: []customStrategy{{CSS: "x"}}}, "without a name"},
+		{"builtin name", selectorConfig{Custom: []customStrategy{{Name: "css", CSS: "x"}}}, `duplicate strategy "css"`},
+		{"no template", selectorConfig{Custom: []customStrategy{{Name: "a"}}}, "exactly one of css and xpath"},
+		{"two templates", selectorConfig{Custom: []customStrategy{{Name: "a", CSS: "x", XPath: "//x"}}}, "exactly one of css and xpath"},
+		{"lone translation", selectorConfig{Translations: [][]string{{"Log in"}}}, "at least two labels"},
+		{"empty translation", selectorConfig{Translations: [][]string{{"Log in", ""}}}, "at least two labels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("validate() = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSelectorConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selectors.json")
+	data := `{"strategies": {"text-partial": {"enabled": false}}, "custom": [{"name": "title", "xpath": "//*[@title={selector}]"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := loadSelectorConfig(path)
+	if err != nil {
+		t.Fatalf("loadSelectorConfig() = %v", err)
+	}
+	loc, _ := config.Custom[0].Locate(`Say "hi"`)
+	if want := `//*[@title='Say "hi"']`; loc.Query != want || !loc.XPath {
+		t.Errorf("custom Locate() = %+v, want XPath %s", loc, want)
+	}
+	if err := os.WriteFile(path, []byte(`{"strategies": {"bogus": {}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSelectorConfig(path); err == nil {
+		t.Error("loadSelectorConfig() with an unknown strategy succeeded")
+	}
+}
+
+func TestBuiltinSelectorStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		selector string
+		want     string // "" if the strategy does not apply
+	}{
+		{"aria-label", `Say "hi"`, `[aria-label="Say \"hi\""]`},
+		{"aria-label-partial", "Sign", `[aria-label*="Sign"]`},
+		{"xpath", "//button", "//button"},
+		{"xpath", "#submit", ""},
+		{"css", "#submit", "#submit"},
+		{"css", "//button", ""},
+		{"id", "submit", "#submit"},
+		{"id", "sign in", ""},
+		{"id", ".primary", ""},
+		{"data-testid", "login", `[data-testid="login"]`},
+		{"data-testid", "data-testid=login", `[data-testid="login"]`},
+		{"data-test", "data-cy=login", ""},
+		{"data-cy", "data-cy=login", `[data-cy="login"]`},
+		{"role", "Submit", ""},
+		{"role", "role=bogus[", ""},
+		{"text", "OK", `//button[text()="OK"] | //a[text()="OK"] | //input[@value="OK"]`},
+		{"text-partial", `it's "x"`, `//button[contains(text(), concat("it's ", '"', "x", '"', ""))] | //a[contains(text(), concat("it's ", '"', "x", '"', ""))] | //input[contains(@value, concat("it's ", '"', "x", '"', ""))]`},
+	}
+	for _, tt := range tests {
+		loc, ok := selectorStrategies[tt.strategy].strategy.Locate(tt.selector)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("%s.Locate(%q) = %q, want not applicable", tt.strategy, tt.selector, loc.Query)
+			}
+			continue
+		}
+		if !ok || loc.Query != tt.want {
+			t.Errorf("%s.Locate(%q) = %q, %t, want %q", tt.strategy, tt.selector, loc.Query, ok, tt.want)
+		}
+	}
+}
+
+func TestRegisterSelectorStrategy(t *testing.T) {
+	RegisterSelectorStrategy("test-title", 31, SelectorStrategyFunc(func(selector string) (SelectorLocator, bool) {
+		return SelectorLocator{Query: "[title=" + cssString(selector) + "]"}, true
+	}))
+	defer func() {
+		selectorStrategiesMu.Lock()
+		delete(selectorStrategies, "test-title")
+		selectorStrategiesMu.Unlock()
+	}()
+	names := pipelineNames(selectorPipeline(nil))
+	if names[5] != "test-title" {
+		t.Errorf("pipeline = %v, want test-title after id", names)
+	}
+}
+
+func TestTypedSelectors(t *testing.T) {
+	tests := []struct {
+		selector string
+		prefix   string // "" if the selector is not typed
+		want     SelectorLocator
+	}{
+		{"xpath=//form//button", "xpath", SelectorLocator{Query: "//form//button", XPath: true}},
+		{"xpath=id('main')", "xpath", SelectorLocator{Query: "id('main')", XPath: true}},
+		{"text=Sign  in", "text", SelectorLocator{Query: `//body//*[normalize-space(.)="Sign in"][not(.//*[normalize-space(.)="Sign in"])] | //input[@type="button" or @type="submit" or @type="reset"][@value="Sign in"]`, XPath: true}},
+		{"text=", "", SelectorLocator{Query: "text="}},
+		{"data-cy=login", "", SelectorLocator{Query: "data-cy=login"}},
+		{`input[name="text=x"]`, "", SelectorLocator{Query: `input[name="text=x"]`}},
+		{"//button", "", SelectorLocator{Query: "//button", XPath: true}},
+	}
+	for _, tt := range tests {
+		loc, prefix, ok := typedSelector(tt.selector)
+		if prefix != tt.prefix || ok != (tt.prefix != "") || (ok && loc != tt.want) {
+			t.Errorf("typedSelector(%q) = %+v, %q, %t, want %+v, %q", tt.selector, loc, prefix, ok, tt.want, tt.prefix)
+		}
+		if got := literalLocator(tt.selector); got != tt.want {
+			t.Errorf("literalLocator(%q) = %+v, want %+v", tt.selector, got, tt.want)
+		}
+	}
+
+	// Typed selectors bypass the smart selector pipeline, strict or not.
+	s := &CDPBrowserServer{}
+	for _, strict := range []bool{false, true} {
+		match := s.resolveSelector(context.Background(), "xpath=//main//a", strict)
+		if match.Selector != "//main//a" || !match.XPath || match.Strategy != "xpath=" {
+			t.Errorf("resolveSelector(xpath=//main//a, strict %t) = %+v", strict, match)
+		}
+	}
+
+	// Replays use the resolved query as written.
+	for _, m := range []SelectorMatch{{Selector: "#go"}, {Selector: "//a", XPath: true}, {Selector: "id('main')", XPath: true}} {
+		if got := literalLocator(m.literal()); got != m.locator() {
+			t.Errorf("literalLocator(%q) = %+v, want %+v", m.literal(), got, m.locator())
+		}
+	}
+}
@@ -0,0 +1,1353 @@
+// Package browserserver implements an MCP server that drives a browser over
+// the Chrome DevTools Protocol.
+//
+// Programs embed the server by creating it with [New], launching the browser
+// with [CDPBrowserServer.Start], and serving MCP sessions with
+// [CDPBrowserServer.Serve] or [CDPBrowserServer.Handler]. The cdpbrowser
+// command is a thin wrapper around [Main]. Programs that only need
+// site-specific tools can add them with [RegisterTool] and then call [Main],
+// instead of forking the command.
+package browserserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	serverName    = "cdpbrowser"
+	serverVersion = "1.0.0"
+)
+
+type CDPBrowserServer struct {
+	ctx            context.Context
+	cancel         context.CancelFunc
+	allocCtx       context.Context
+	allocCancel    context.CancelFunc
+	chromeCmd      *exec.Cmd
+	wsURL          string
+	chromePort     int  // Remote debugging port of the launched browser
+	keepChromeOpen bool // Flag to control Chrome lifecycle
+	capabilities   *browserCapabilities
+	policy         *navigationPolicy
+	stats          *toolStats
+	tools          map[string]toolInvoker // registered tool handlers, by name
+	recorder       recorder
+	macros         macroRegistry
+	allowRawCDP    bool // register the execute_cdp tool
+	events         eventBuffer
+	elements       elementRegistry
+	elementCache   elementCache
+	routes         routeTracker
+	snapshots      snapshotStore
+	pageStates     pageStateStore
+	selectorConfig *selectorConfig // smart selector strategy settings, or nil for the defaults
+	actionTimeout  time.Duration   // how long interactions wait for an actionable element, or 0 for the default
+	errorArtifacts string          // what failed interactions attach: errorArtifactsOff, errorArtifactsInline or errorArtifactsResource
+	// errorScreenshots holds failure screenshots in errorArtifactsResource mode
+	errorScreenshots errorArtifactStore
+	// crawls holds the results of crawl for the browser://crawls resources
+	crawls crawlStore
+	// screenshots holds the captures of bulk_screenshot and
+	// compare_screenshots for the browser://screenshots resources
+	screenshots screenshotStore
+	// browser is the automation backend selected with -browser, or nil for
+	// Chrome over CDP
+	browser Browser
+	// bidiURL is the WebDriver BiDi WebSocket the bidi backend connects to,
+	// or "" to launch Firefox
+	bidiURL string
+	// chromeUserDataDir is the profile directory Chrome is launched with,
+	// or "" for the default
+	chromeUserDataDir string
+	// profilesDir is where save_profile stores profiles, or "" for the
+	// default
+	profilesDir string
+	// recordingsDir is where stop_recording saves named recordings and
+	// replay_recording loads them
+	recordingsDir string
+	// workflowsDir is where run_workflow finds stored workflows, or "" if
+	// workflows can only be passed inline
+	workflowsDir string
+	// ocrEngine is the name of the engine ocr_screenshot uses, or "" for
+	// defaultOCREngine
+	ocrEngine string
+	// mouse is where the coordinate input tools left the mouse
+	mouse mousePosition
+	// zoom is the CSS zoom set_zoom applies to new documents
+	zoom pageZoom
+	// state follows the tabs, downloads, and dialogs for browser://state
+	state stateTracker
+	// coverage is the code coverage collection of start_coverage
+	coverage coverageSession
+	// pageErrors collects the uncaught exceptions of the page for
+	// get_page_errors
+	pageErrors pageErrorLog
+	// failOnPageError fails interaction tools during which the page threw
+	// an uncaught exception
+	failOnPageError bool
+	// websockets follows the WebSocket connections of the page and captures
+	// their frames for get_websocket_frames
+	websockets webSocketTracker
+	// vault holds the credentials for login_with_credentials, or nil if no
+	// vault was given
+	vault *credentialVault
+	// secrets masks the vault's passwords in tool results and logs
+	secrets secretMasker
+	// elicitation records which clients can answer request_human_input
+	// through MCP elicitation
+	elicitation elicitingSessions
+	// confirmPolicy decides which clicks need confirmation, or is nil if
+	// clicks are never gated
+	confirmPolicy *confirmationPolicy
+	// idempotency holds the calls made with idempotency keys
+	idempotency idempotencyStore
+	// rateLimiter enforces the per-session limits set with the -max-* flags
+	rateLimiter rateLimiter
+	// mcpServer serves the tools, once Start has registered them
+	mcpServer *mcp.Server
+	// shutdown stops Serve, or is nil if the server is not serving MCP
+	shutdownMu sync.Mutex
+	shutdown   context.CancelFunc
+	// toolset decides which tools are registered, from the tool profile
+	// and the -tool-config file
+	toolset toolSet
+}
+
+// getChromeCommand returns the appropriate Chrome command for the current OS,
+// with remote debugging on port and the profile in userDataDir
+func getChromeCommand(port int, userDataDir string) (string, []string) {
+	// Check for mock Chrome path (for testing)
+	if mockPath := os.Getenv("MOCK_CHROME_PATH"); mockPath != "" {
+		if _, err := os.Stat(mockPath); err == nil {
+			return mockPath, []string{} // Mock doesn't need args
+		}
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		// Try different Chrome paths on Linux
+		chromePaths := []string{
+			"/usr/bin/google-chrome-stable",
+			"/usr/bin/google-chrome",
+			"/usr/bin/chromium-browser",
+			"/usr/bin/chromium",
+		}
+		for _, path := range chromePaths {
+			if _, err := os.Stat(path); err == nil {
+				return path, []string{
+					fmt.Sprintf("--remote-debugging-port=%d", port),
+					"--no-first-run",
+					"--no-default-browser-check",
+					"--user-data-dir=" + userDataDir,
+					"--disable-background-timer-throttling",
+					"--disable-backgrounding-occluded-windows",
+					"--disable-renderer-backgrounding",
+					"--disable-features=TranslateUI",
+					"--disable-extensions",
+					"--no-sandbox",
+				}
+			}
+		}
+	case "darwin":
+		return "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome", []string{
+			fmt.Sprintf("--remote-debugging-port=%d", port),
+			"--no-first-run",
+			"--no-default-browser-check",
+			"--user-data-dir=" + userDataDir,
+			"--disable-background-timer-throttling",
+			"--disable-backgrounding-occluded-windows",
+			"--disable-renderer-backgrounding",
+		}
+	case "windows":
+		// Try different Windows Chrome paths
+		chromePaths := []string{
+			"C:\\Program Files\\Google\\Chrome\\Application\\chrome.exe",
+			"C:\\Program Files (x86)\\Google\\Chrome\\Application\\chrome.exe",
+		}
+		for _, path := range chromePaths {
+			if _, err := os.Stat(path); err == nil {
+				return path, []string{
+					fmt.Sprintf("--remote-debugging-port=%d", port),
+					"--no-first-run",
+					"--no-default-browser-check",
+					"--user-data-dir=" + userDataDir,
+					"--disable-background-timer-throttling",
+					"--disable-backgrounding-occluded-windows",
+					"--disable-renderer-backgrounding",
+				}
+			}
+		}
+	}
+
+	// Fallback to 'chrome' command in PATH
+	return "chrome", []string{
+		fmt.Sprintf("--remote-debugging-port=%d", port),
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--user-data-dir=" + userDataDir,
+	}
+} // launchChromeAndGetWebSocketURL launches Chrome and extracts the WebSocket URL from output
+func (s *CDPBrowserServer) launchChromeAndGetWebSocketURL() error {
+	chromePath, args := getChromeCommand(s.chromePort, s.userDataDir())
+
+	log.Printf("Launching Chrome: %s %s", chromePath, strings.Join(args, " "))
+
+	cmd := exec.Command(chromePath, args...)
+	// Keep Chrome out of the server's process group, so that a Ctrl-C meant
+	// for the server does not stop a browser it was asked to keep open
+	cmd.SysProcAttr = detachedProcAttr()
+
+	// Create pipes to capture stderr (where Chrome outputs the DevTools URL)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stderr pipe: %v", err)
+	}
+
+	// Start Chrome
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start Chrome: %v", err)
+	}
+
+	s.chromeCmd = cmd
+
+	// Read stderr to find the WebSocket URL
+	wsURLChan := make(chan string, 1)
+	errChan := make(chan error, 1)
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		// Regex to match WebSocket URL pattern
+		wsPattern := regexp.MustCompile(`DevTools listening on (ws://[^\s]+)`)
+
+		for scanner.Scan() {
+			line := scanner.Text()
+			log.Printf("Chrome output: %s", line)
+
+			if matches := wsPattern.FindStringSubmatch(line); len(matches) > 1 {
+				wsURLChan <- matches[1]
+				// Keep draining stderr, so Chrome never blocks writing to it
+				io.Copy(io.Discard, stderr)
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			errChan <- fmt.Errorf("error reading Chrome output: %v", err)
+		} else {
+			errChan <- fmt.Errorf("chrome started but no WebSocket URL found")
+		}
+	}()
+
+	// Wait for WebSocket URL or timeout
+	select {
+	case wsURL := <-wsURLChan:
+		s.wsURL = wsURL
+		log.Printf("Found Chrome WebSocket URL: %s", wsURL)
+		return nil
+	case err := <-errChan:
+		cmd.Process.Kill()
+		return err
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		return fmt.Errorf("timeout waiting for Chrome WebSocket URL")
+	}
+}
+
+// connectToChromeWebSocket connects to Chrome using the extracted WebSocket URL
+func (s *CDPBrowserServer) connectToChromeWebSocket() error {
+	log.Printf("Attempting to connect to Chrome WebSocket: %s", s.wsURL)
+
+	if s.wsURL == "" {
+		return fmt.Errorf("no WebSocket URL available")
+	}
+
+	log.Println("Creating remote allocator with WebSocket URL...")
+	// Create remote allocator with the WebSocket URL
+	allocCtx, allocCancel := chromedp.NewRemoteAllocator(context.Background(), s.wsURL)
+	s.allocCtx = allocCtx
+	s.allocCancel = allocCancel
+
+	log.Println("Creating Chrome context...")
+	// Create context
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	s.ctx = ctx
+	s.cancel = cancel
+
+	log.Println("Testing Chrome connection by getting page title...")
+	// Test the connection
+	var title string
+	err := chromedp.Run(ctx, chromedp.Title(&title))
+	if err != nil {
+		log.Printf("Failed to get page title, cleaning up: %v", err)
+		s.closeChrome()
+		return fmt.Errorf("failed to connect to Chrome WebSocket: %v", err)
+	}
+
+	log.Printf("Successfully connected to Chrome via WebSocket - page title: '%s'", title)
+	return nil
+}
+
+func (s *CDPBrowserServer) connectToExistingChrome(port int) error {
+	allocCtx, allocCancel := chromedp.NewRemoteAllocator(context.Background(),
+		fmt.Sprintf("ws://localhost:%d/", port))
+	s.allocCtx = allocCtx
+	s.allocCancel = allocCancel
+
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	s.ctx = ctx
+	s.cancel = cancel
+
+	var title string
+	err := chromedp.Run(ctx, chromedp.Title(&title))
+	if err != nil {
+		s.closeChrome()
+		return fmt.Errorf("failed to connect to Chrome on port %d: %v", port, err)
+	}
+
+	log.Printf("Connected to existing Chrome instance on port %d", port)
+	return nil
+}
+
+func (s *CDPBrowserServer) launchNewChrome() error {
+	// Launch Chrome and get WebSocket URL
+	if err := s.launchChromeAndGetWebSocketURL(); err != nil {
+		return fmt.Errorf("failed to launch Chrome: %v", err)
+	}
+
+	// Connect to Chrome using the WebSocket URL
+	if err := s.connectToChromeWebSocket(); err != nil {
+		return fmt.Errorf("failed to connect to Chrome: %v", err)
+	}
+
+	log.Println("Launched new Chrome instance and connected successfully")
+	return nil
+}
+
+// killExistingChromeProcesses kills any existing Chrome processes to avoid conflicts
+func (s *CDPBrowserServer) killExistingChromeProcesses() {
+	log.Println("Killing any existing Chrome processes to avoid conflicts...")
+
+	// Try to kill Chrome processes on the default debugging port
+	exec.Command("pkill", "-f", "chrome.*remote-debugging-port").Run()
+	exec.Command("pkill", "-f", "google-chrome.*remote-debugging").Run()
+
+	// Wait a moment for processes to terminate
+	time.Sleep(1 * time.Second)
+}
+
+// closeChrome closes the CDP connection and stops the Chrome process the
+// server launched.
+func (s *CDPBrowserServer) closeChrome() {
+	// Close CDP connection
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if s.allocCancel != nil {
+		s.allocCancel()
+	}
+
+	// Always terminate Chrome for testing to avoid conflicts
+	if s.chromeCmd != nil && s.chromeCmd.Process != nil {
+		log.Println("Terminating Chrome process to avoid conflicts...")
+		s.chromeCmd.Process.Kill()
+		s.chromeCmd.Wait()
+		s.chromeCmd = nil
+	}
+}
+
+// detachChrome closes the CDP connection but leaves the Chrome process the
+// server launched running, for the user to keep using.
+func (s *CDPBrowserServer) detachChrome() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if s.allocCancel != nil {
+		s.allocCancel()
+	}
+	if cmd := s.chromeCmd; cmd != nil && cmd.Process != nil {
+		log.Printf("Leaving Chrome (pid %d) running, DevTools at %s", cmd.Process.Pid, s.wsURL)
+		// Reap the process if it exits while the server still runs
+		go cmd.Wait()
+		s.chromeCmd = nil
+	}
+}
+
+// Initialize launches the browser backend.
+func (s *CDPBrowserServer) Initialize() error {
+	log.Printf("Launching new %s instance...", s.backend().Name())
+	return s.backend().Launch()
+}
+
+type NavigateArgs struct {
+	URL string `json:"url" jsonschema:"The URL to navigate to"`
+}
+
+// NavigateResult is the structured result of navigate.
+type NavigateResult struct {
+	URL      string    `json:"url"`
+	Captchas []Captcha `json:"captchas,omitempty" jsonschema:"Visible CAPTCHAs on the loaded page, which cannot be solved automatically"`
+}
+
+func (s *CDPBrowserServer) Navigate(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[NavigateArgs]]) (*mcp.CallToolResultFor[NavigateResult], error) {
+	url := req.Params.Arguments.URL
+	if err := s.policy.check(url); err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[NavigateResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error navigating to %s: %v", url, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	err := s.backend().Navigate(ctx, url)
+	if err != nil {
+		noteToolError(ctx, err, CodeNavigationFailed)
+		return &mcp.CallToolResultFor[NavigateResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error navigating to %s: %v", url, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	s.routes.navigated(url)
+	result := NavigateResult{URL: url}
+	text := fmt.Sprintf("Navigated to %s", url)
+	if captchas, err := s.detectCaptchas(ctx); err != nil {
+		log.Printf("Failed to detect CAPTCHAs after navigating to %s: %v", url, err)
+	} else if w := captchaWarning(captchas); w != "" {
+		result.Captchas = blockingCaptchas(captchas)
+		text += "\n" + w
+	}
+	return &mcp.CallToolResultFor[NavigateResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+		StructuredContent: result,
+	}, nil
+}
+
+type ClickArgs struct {
+	Selector string `json:"selector" jsonschema:"CSS selector, DOM ID, or ARIA label for the element to click, or xpath= or text= followed by an XPath expression or the text it shows"`
+	Strict   bool   `json:"strict,omitempty" jsonschema:"Use the selector as written, as CSS or XPath, without smart resolution (default: false)"`
+	Confirm  bool   `json:"confirm,omitempty" jsonschema:"Confirm a click the server's confirmation policy treats as destructive, after checking with the user (default: false)"`
+}
+
+func (s *CDPBrowserServer) Click(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+	match := s.resolveSelector(ctx, req.Params.Arguments.Selector, req.Params.Arguments.Strict)
+	o := s.observeAction(ctx)
+	defer o.stop()
+	err := s.clickElement(ctx, &match, s.confirmation(req.Session, req.Params.Arguments.Confirm))
+	if err != nil {
+		noteSelectorError(ctx, err, req.Params.Arguments.Selector, match)
+		return &mcp.CallToolResultFor[SelectorMatch]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error clicking element %s: %v", match.Selector, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	match.Effects = o.finish(ctx)
+	return selectorResult(ctx, fmt.Sprintf("Clicked element: %s", match.Selector), match), nil
+}
+
+// clickElement clicks the first element matched by m once it is actionable
+// and c allows the click, noting in m whether the element had to be
+// resolved again after a re-render.
+func (s *CDPBrowserServer) clickElement(ctx context.Context, m *SelectorMatch, c *clickConfirmation) error {
+	if !s.usesCDP() {
+		return s.clickInPage(ctx, m.locator(), c)
+	}
+	var err error
+	m.Reresolved, err = s.actOnElement(ctx, m.locator(), actionChecks{pointer: true}, func(ctx context.Context, b cdp.BackendNodeID) error {
+		if err := c.check(ctx, b); err != nil {
+			return err
+		}
+		return clickNode(ctx, b)
+	})
+	return err
+}
+
+func (s *CDPBrowserServer) Screenshot(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[struct{}], error) {
+	buf, err := s.backend().Screenshot(ctx)
+	if err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error taking screenshot: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.ImageContent{Data: buf, MIMEType: "image/png"},
+		},
+	}, nil
+}
+
+// CloseBrowser tool - allows user to close Chrome
+func (s *CDPBrowserServer) CloseBrowser(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[struct{}], error) {
+	if s.chromeCmd != nil && s.chromeCmd.Process != nil {
+		log.Println("User requested to close Chrome browser...")
+		s.chromeCmd.Process.Kill()
+		s.chromeCmd.Wait()
+		s.chromeCmd = nil
+
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Chrome browser closed successfully"},
+			},
+		}, nil
+	}
+
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: "No Chrome process to close"},
+		},
+	}, nil
+}
+
+// DetachBrowser tool - releases the CDP connection without closing Chrome
+func (s *CDPBrowserServer) DetachBrowser(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[struct{}], error) {
+	if s.ctx == nil || s.ctx.Err() != nil {
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to Chrome"},
+			},
+			IsError: true,
+		}, nil
+	}
+	log.Println("User requested to detach from Chrome browser...")
+	wsURL := s.wsURL
+	s.detachChrome()
+
+	text := "Detached from Chrome, which keeps running. Browser tools are unavailable until the server restarts"
+	if wsURL != "" {
+		text += fmt.Sprintf("; its DevTools endpoint is %s", wsURL)
+	}
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}, nil
+}
+
+type ChromeControlArgs struct {
+	KeepOpen bool `json:"keep_open" jsonschema:"Whether to keep Chrome open when MCP server exits"`
+}
+
+type TypeTextArgs struct {
+	Selector string `json:"selector" jsonschema:"CSS selector, DOM ID, or ARIA label for the text input element, or xpath= or text= followed by an XPath expression or the text it shows"`
+	Text     string `json:"text" jsonschema:"Text to type into the element"`
+	Clear    bool   `json:"clear,omitempty" jsonschema:"Whether to clear existing text before typing (default: false)"`
+	Strict   bool   `json:"strict,omitempty" jsonschema:"Use the selector as written, as CSS or XPath, without smart resolution (default: false)"`
+	Confirm  bool   `json:"confirm,omitempty" jsonschema:"Confirm a form submission by an Enter key in text that the server's confirmation policy treats as destructive, after checking with the user (default: false)"`
+}
+
+type ClickButtonArgs struct {
+	Selector string `json:"selector" jsonschema:"CSS selector, DOM ID, or ARIA label for the button element, or xpath= or text= followed by an XPath expression or the text it shows"`
+	Strict   bool   `json:"strict,omitempty" jsonschema:"Use the selector as written, as CSS or XPath, without smart resolution (default: false)"`
+	Confirm  bool   `json:"confirm,omitempty" jsonschema:"Confirm a click the server's confirmation policy treats as destructive, after checking with the user (default: false)"`
+}
+
+type ClickLinkArgs struct {
+	Selector string `json:"selector" jsonschema:"CSS selector, DOM ID, or ARIA label for the link element, or xpath= or text= followed by an XPath expression or the text it shows"`
+	Strict   bool   `json:"strict,omitempty" jsonschema:"Use the selector as written, as CSS or XPath, without smart resolution (default: false)"`
+	Confirm  bool   `json:"confirm,omitempty" jsonschema:"Confirm a click the server's confirmation policy treats as destructive, after checking with the user (default: false)"`
+}
+
+type SelectDropdownArgs struct {
+	Selector string `json:"selector" jsonschema:"CSS selector, DOM ID, or ARIA label for the select element, or xpath= or text= followed by an XPath expression or the text it shows"`
+	Value    string `json:"value" jsonschema:"Value or visible text of the option to select"`
+	Strict   bool   `json:"strict,omitempty" jsonschema:"Use the selector as written, as CSS or XPath, without smart resolution (default: false)"`
+}
+
+type ChooseOptionArgs struct {
+	Selector string `json:"selector" jsonschema:"CSS selector, DOM ID, or ARIA label for the radio button or checkbox, or xpath= or text= followed by an XPath expression or the text it shows"`
+	Checked  *bool  `json:"checked,omitempty" jsonschema:"Whether to check or uncheck the option (default: true)"`
+	Strict   bool   `json:"strict,omitempty" jsonschema:"Use the selector as written, as CSS or XPath, without smart resolution (default: false)"`
+}
+
+// SetChromeLifecycle tool - allows user to control Chrome lifecycle
+func (s *CDPBrowserServer) SetChromeLifecycle(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ChromeControlArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+	s.keepChromeOpen = req.Params.Arguments.KeepOpen
+
+	status := "will be closed"
+	if s.keepChromeOpen {
+		status = "will remain open"
+	}
+
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Chrome lifecycle updated: browser %s when MCP server exits", status)},
+		},
+	}, nil
+}
+
+type ARIASnapshotArgs struct {
+	Format      string `json:"format" jsonschema:"Output format: llm-text (default), verbose, compact, markdown, yaml, json, debug, or a registered custom formatter"`
+	Focus       string `json:"focus" jsonschema:"Focus area: all, interactive, landmarks, headings, or region (every section, within the element matched by region)"`
+	Region      string `json:"region,omitempty" jsonschema:"CSS selector, XPath expression starting with /, or xpath= or text= prefixed selector of the element to scope the snapshot to; required when focus is region"`
+	MaxElements int    `json:"max_elements,omitempty" jsonschema:"Maximum number of elements to return (default: no limit)"`
+	Offset      int    `json:"offset,omitempty" jsonschema:"Number of elements to skip, to page through a truncated snapshot"`
+	MaxTokens   int    `json:"max_tokens,omitempty" jsonschema:"Approximate token budget for the text output; elements that do not fit are left out (default: no limit)"`
+	SaveAs      string `json:"save_as,omitempty" jsonschema:"Optional name to save the snapshot under, for later comparison with aria_diff"`
+}
+
+// ARIAPageInfo identifies the page an ARIA snapshot was taken from.
+type ARIAPageInfo struct {
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	Timestamp string `json:"timestamp"`
+}
+
+// ARIAElement is a landmark, interactive element, heading, or content region
+// captured in an ARIA snapshot. Fields that do not apply to the element's
+// category are left empty.
+type ARIAElement struct {
+	ID        int      `json:"id,omitempty" jsonschema:"Stable ID of an interactive element, for the *_by_id tools and annotated_screenshot labels"`
+	Role      string   `json:"role,omitempty" jsonschema:"ARIA role, explicit or implied by the tag"`
+	Name      string   `json:"name,omitempty" jsonschema:"Accessible name"`
+	Selector  string   `json:"selector" jsonschema:"Preferred CSS selector for the element"`
+	Selectors []string `json:"selectors,omitempty" jsonschema:"All candidate selectors, primary first"`
+	AriaLabel string   `json:"ariaLabel,omitempty"`
+	Tag       string   `json:"tag"`
+	Href      string   `json:"href,omitempty"`
+	Value     string   `json:"value,omitempty"`
+	Level     int      `json:"level,omitempty" jsonschema:"Heading level"`
+	Text      string   `json:"text,omitempty" jsonschema:"Heading text"`
+}
+
+// ARIASnapshotResult is the structured content returned by aria_snapshot.
+type ARIASnapshotResult struct {
+	Page        ARIAPageInfo  `json:"page"`
+	Landmarks   []ARIAElement `json:"landmarks,omitempty"`
+	Interactive []ARIAElement `json:"interactive,omitempty"`
+	Headings    []ARIAElement `json:"headings,omitempty"`
+	Content     []ARIAElement `json:"content,omitempty"`
+	// Pagination is set when only part of the snapshot was returned.
+	Pagination *ARIASnapshotPagination `json:"pagination,omitempty"`
+}
+
+// ARIASnapshot tool - captures page accessibility structure for LLM consumption
+func (s *CDPBrowserServer) ARIASnapshot(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ARIASnapshotArgs]]) (*mcp.CallToolResultFor[ARIASnapshotResult], error) {
+	args := req.Params.Arguments
+	format := args.Format
+	focus := args.Focus
+
+	// Default values
+	if format == "" {
+		format = "llm-text"
+	}
+	if focus == "" {
+		focus = "all"
+	}
+
+	formatter, ok := lookupSnapshotFormatter(format)
+	if !ok {
+		noteToolError(ctx, nil, CodeInvalidArgument)
+		return &mcp.CallToolResultFor[ARIASnapshotResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown snapshot format %q (available: %s)", format, strings.Join(snapshotFormatterNames(), ", "))},
+			},
+			IsError: true,
+		}, nil
+	}
+	if focus == "region" && args.Region == "" {
+		noteToolError(ctx, nil, CodeInvalidArgument)
+		return &mcp.CallToolResultFor[ARIASnapshotResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "focus region requires a region selector"},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	snapshot, err := s.takeARIASnapshot(ctx, focus, args.Region)
+	if err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[ARIASnapshotResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error extracting ARIA snapshot: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	// The full snapshot is saved so that aria_diff compares whole pages.
+	s.snapshots.save(args.SaveAs, focus, args.Region, snapshot)
+
+	output, page := paginateSnapshot(snapshot, formatter, args.Offset, args.MaxElements, args.MaxTokens)
+	return &mcp.CallToolResultFor[ARIASnapshotResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: output},
+		},
+		StructuredContent: *page,
+	}, nil
+}
+
+// ShutdownServer tool - allows graceful server shutdown. The server finishes
+// the in-flight tool calls, sends this result, and closes the connection
+// before cleaning up.
+func (s *CDPBrowserServer) ShutdownServer(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[struct{}], error) {
+	if !s.Shutdown() {
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Server shutdown is not available in this mode"},
+			},
+			IsError: true,
+		}, nil
+	}
+	log.Println("Shutdown requested via MCP tool")
+
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: "Server shutdown initiated"},
+		},
+	}, nil
+}
+
+// TypeText tool - types text into an input element
+func (s *CDPBrowserServer) TypeText(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[TypeTextArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+	match := s.resolveSelector(ctx, req.Params.Arguments.Selector, req.Params.Arguments.Strict)
+	selector := match.Selector
+	text := req.Params.Arguments.Text
+	clear := req.Params.Arguments.Clear
+	c := s.confirmation(req.Session, req.Params.Arguments.Confirm)
+
+	// The text is not logged: it may be a password or other personal data.
+	log.Printf("TypeText called: selector='%s' (%s strategy), %d characters, clear=%t", selector, match.Strategy, len([]rune(text)), clear)
+
+	o := s.observeAction(ctx)
+	defer o.stop()
+	var err error
+	if s.usesCDP() {
+		match.Reresolved, err = s.actOnElement(ctx, match.locator(), actionChecks{editable: true}, func(ctx context.Context, b cdp.BackendNodeID) error {
+			// An Enter key submits the field's form like a click on its
+			// default button, so it is subject to the confirmation policy
+			// too.
+			if pressesEnter(text) {
+				if err := c.checkEnter(ctx, b); err != nil {
+					return err
+				}
+			}
+			return typeIntoNode(ctx, b, text, clear)
+		})
+	} else {
+		err = s.typeInPage(ctx, match.locator(), text, clear, c)
+	}
+	if err != nil {
+		log.Printf("TypeText: FAILED - %v", err)
+		noteSelectorError(ctx, err, req.Params.Arguments.Selector, match)
+		return &mcp.CallToolResultFor[SelectorMatch]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error typing into element %s: %v", selector, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	log.Printf("TypeText: Typed into '%s'", selector)
+	match.Effects = o.finish(ctx)
+	return selectorResult(ctx, fmt.Sprintf("Typed \"%s\" into element: %s", text, selector), match), nil
+}
+
+// ClickButton tool - clicks a button element
+func (s *CDPBrowserServer) ClickButton(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickButtonArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+	selector := req.Params.Arguments.Selector
+	log.Printf("ClickButton called: selector='%s'", selector)
+
+	textXPath := fmt.Sprintf(`//button[text()=%s] | //input[@value=%s]`, xpathString(selector), xpathString(selector))
+	return s.smartClick(ctx, "button", selector, req.Params.Arguments.Strict, textXPath, s.confirmation(req.Session, req.Params.Arguments.Confirm))
+}
+
+// ClickLink tool - clicks a link element
+func (s *CDPBrowserServer) ClickLink(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickLinkArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+	selector := req.Params.Arguments.Selector
+	log.Printf("ClickLink called: selector='%s'", selector)
+
+	textXPath := fmt.Sprintf(`//a[text()=%s]`, xpathString(selector))
+	return s.smartClick(ctx, "link", selector, req.Params.Arguments.Strict, textXPath, s.confirmation(req.Session, req.Params.Arguments.Confirm))
+}
+
+// smartClick clicks the element selected by the smart selector pipeline.
+// If no strategy matches, or the match cannot be clicked, it falls back to
+// waiting for selector as CSS and then for textXPath, reporting the
+// "fallback" strategy. With strict, or an xpath= or text= prefix on
+// selector, only selector as written is tried. kind
+// names the element in messages. A click that c blocks is not retried.
+func (s *CDPBrowserServer) smartClick(ctx context.Context, kind, selector string, strict bool, textXPath string, c *clickConfirmation) (*mcp.CallToolResultFor[SelectorMatch], error) {
+	click := func(m *SelectorMatch) error {
+		return s.clickElement(ctx, m, c)
+	}
+
+	if _, _, ok := typedSelector(selector); ok {
+		strict = true
+	}
+	o := s.observeAction(ctx)
+	defer o.stop()
+	var match SelectorMatch
+	var err error
+	var tried []string
+	if strict {
+		match = s.resolveSelector(ctx, selector, true)
+		err = click(&match)
+	} else if match, err = s.findElementWithSmartSelector(ctx, selector); err == nil {
+		if err = click(&match); err != nil {
+			log.Printf("smartClick: %s strategy selector '%s' failed: %v", match.Strategy, match.Selector, err)
+			tried = append(match.tried, match.Strategy)
+		}
+	} else {
+		log.Printf("smartClick: Smart selector failed: %v", err)
+		tried = match.tried
+	}
+	if err != nil && !strict && !isUnconfirmed(err) {
+		log.Printf("smartClick: Trying fallback with original selector: '%s'", selector)
+		match = SelectorMatch{Selector: selector, XPath: isXPath(selector), Strategy: "fallback", tried: tried}
+		if err = click(&match); err != nil && !isUnconfirmed(err) {
+			log.Printf("smartClick: Trying XPath fallback: '%s'", textXPath)
+			match = SelectorMatch{Selector: textXPath, XPath: true, Strategy: "fallback", tried: tried}
+			err = click(&match)
+		}
+	}
+	if err != nil {
+		noteSelectorError(ctx, err, selector, match)
+		return &mcp.CallToolResultFor[SelectorMatch]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error clicking %s %s: %v", kind, selector, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	log.Printf("smartClick: Clicked %s '%s' using %s strategy", kind, match.Selector, match.Strategy)
+	match.Effects = o.finish(ctx)
+	return selectorResult(ctx, fmt.Sprintf("Clicked %s: %s", kind, match.Selector), match), nil
+}
+
+// SelectDropdown tool - selects an option from a dropdown
+func (s *CDPBrowserServer) SelectDropdown(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[SelectDropdownArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+	match := s.resolveSelector(ctx, req.Params.Arguments.Selector, req.Params.Arguments.Strict)
+	selector := match.Selector
+	value := req.Params.Arguments.Value
+
+	o := s.observeAction(ctx)
+	defer o.stop()
+	var err error
+	if s.usesCDP() {
+		want, _ := json.Marshal(value)
+		selectJS := fmt.Sprintf("function() { return (%s).call(this, %s); }", pageSelectJS, want)
+		match.Reresolved, err = s.actOnElement(ctx, match.locator(), actionChecks{}, func(ctx context.Context, b cdp.BackendNodeID) error {
+			return callOnElement(ctx, b, selectJS, nil)
+		})
+	} else {
+		err = s.selectInPage(ctx, match.locator(), value)
+	}
+
+	if err != nil {
+		noteSelectorError(ctx, err, req.Params.Arguments.Selector, match)
+		return &mcp.CallToolResultFor[SelectorMatch]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error selecting option \"%s\" from dropdown %s: %v", value, selector, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	match.Effects = o.finish(ctx)
+	return selectorResult(ctx, fmt.Sprintf("Selected option \"%s\" from dropdown: %s", value, selector), match), nil
+}
+
+// toggleOptionJS is called on the element selected by choose_option with
+// the wanted checked state. A label or wrapper element stands for the
+// checkbox or radio button it contains or labels. The control is clicked,
+// so page handlers run as they would for a user; if the click does not
+// reach the wanted state, the checked property is set and input and change
+// events are fired. Elements with an ARIA checkbox, radio or switch role
+// are clicked and report aria-checked.
+const toggleOptionJS = `
+function(want) {
+	let el = this;
+	if (!(el instanceof HTMLInputElement)) {
+		el = (el instanceof HTMLLabelElement && el.control) ||
+			el.querySelector('input[type="checkbox"], input[type="radio"]') || el;
+	}
+	if (!(el instanceof HTMLInputElement)) {
+		const role = el.getAttribute('role');
+		if (!['checkbox', 'radio', 'switch', 'menuitemcheckbox', 'menuitemradio'].includes(role)) {
+			throw new Error('element is not a checkbox or radio button');
+		}
+		const before = el.getAttribute('aria-checked') === 'true';
+		if (before !== want) {
+			el.click();
+		}
+		const after = el.getAttribute('aria-checked') === 'true';
+		return { checked: after, changed: before !== after };
+	}
+	if (el.type !== 'checkbox' && el.type !== 'radio') {
+		throw new Error('element is an input of type ' + el.type + ', not a checkbox or radio button');
+	}
+	if (el.disabled) {
+		throw new Error('element is disabled');
+	}
+	if (el.type === 'radio' && !want && el.checked) {
+		throw new Error('a radio button cannot be unchecked; choose another option in its group');
+	}
+	const before = el.checked;
+	if (before !== want) {
+		el.click();
+		if (el.checked !== want) {
+			el.checked = want;
+			el.dispatchEvent(new Event('input', { bubbles: true }));
+			el.dispatchEvent(new Event('change', { bubbles: true }));
+		}
+	}
+	return { checked: el.checked, changed: before !== el.checked };
+}
+`
+
+// ChooseOptionResult is the structured result of choose_option.
+type ChooseOptionResult struct {
+	Selector string `json:"selector" jsonschema:"The query that matched the element"`
+	XPath    bool   `json:"xpath,omitempty" jsonschema:"Whether selector is an XPath expression rather than CSS"`
+	Strategy string `json:"strategy" jsonschema:"Name of the selector strategy that matched the element, fallback if none did, or strict"`
+	Checked  bool   `json:"checked" jsonschema:"Whether the option is checked after the call"`
+	Changed  bool   `json:"changed" jsonschema:"Whether the call changed the checked state"`
+	// Reresolved is as in SelectorMatch.
+	Reresolved bool           `json:"reresolved,omitempty" jsonschema:"Whether the element was replaced by a re-render and found again by its selector"`
+	Effects    *ActionEffects `json:"effects,omitempty" jsonschema:"What the action did to the page"`
+}
+
+// ChooseOption tool - checks/unchecks a radio button or checkbox
+func (s *CDPBrowserServer) ChooseOption(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ChooseOptionArgs]]) (*mcp.CallToolResultFor[ChooseOptionResult], error) {
+	match := s.resolveSelector(ctx, req.Params.Arguments.Selector, req.Params.Arguments.Strict)
+	loc := match.locator()
+	checked := true // default to checking the option
+	if req.Params.Arguments.Checked != nil {
+		checked = *req.Params.Arguments.Checked
+	}
+
+	result := ChooseOptionResult{Selector: match.Selector, XPath: match.XPath, Strategy: match.Strategy}
+	o := s.observeAction(ctx)
+	defer o.stop()
+	var err error
+	if s.usesCDP() {
+		toggleJS := fmt.Sprintf("function() { return (%s).call(this, %t); }", toggleOptionJS, checked)
+		// toggleOptionJS handles hidden inputs behind custom controls.
+		result.Reresolved, err = s.actOnElement(ctx, loc, actionChecks{attachedOnly: true}, func(ctx context.Context, b cdp.BackendNodeID) error {
+			return callOnElement(ctx, b, toggleJS, &result)
+		})
+	} else {
+		err = s.evaluateOnMatch(ctx, loc, toggleOptionJS, &result, checked)
+	}
+	if err != nil {
+		noteSelectorError(ctx, err, req.Params.Arguments.Selector, match)
+		return &mcp.CallToolResultFor[ChooseOptionResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error setting option %s to %t: %v", match.Selector, checked, err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	action := "checked"
+	if !result.Checked {
+		action = "unchecked"
+	}
+	if !result.Changed {
+		action = "already " + action
+	}
+	result.Effects = o.finish(ctx)
+	noteResolvedSelector(ctx, match.literal())
+	text := fmt.Sprintf("Option %s: %s (strategy: %s%s)", action, match.Selector, match.Strategy, reresolvedNote(result.Reresolved))
+	if result.Effects != nil {
+		text += "\n" + result.Effects.String()
+	}
+	return &mcp.CallToolResultFor[ChooseOptionResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+		StructuredContent: result,
+	}, nil
+}
+
+// RefreshPage tool - refreshes the current page
+func (s *CDPBrowserServer) RefreshPage(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[struct{}], error) {
+	err := s.backend().Reload(ctx)
+	if err != nil {
+		noteToolError(ctx, err, CodeNavigationFailed)
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error refreshing page: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: "Page refreshed successfully"},
+		},
+	}, nil
+}
+
+// newMCPServer creates the MCP server and registers the browser tools.
+func newMCPServer(server *CDPBrowserServer) *mcp.Server {
+	if server.stats == nil {
+		server.stats = newToolStats()
+	}
+	mcpServer := mcp.NewServer(&mcp.Implementation{
+		Name:    serverName,
+		Version: serverVersion,
+	}, &mcp.ServerOptions{
+		SubscribeHandler:   server.subscribeResource,
+		UnsubscribeHandler: server.unsubscribeResource,
+	})
+
+	log.Println("Registering MCP tools...")
+	addTool(mcpServer, server, &mcp.Tool{Name: "navigate", Description: "Navigate to a URL"}, server.Navigate)
+	addTool(mcpServer, server, &mcp.Tool{Name: "wait_for_route_change", Description: "Wait until the page URL changes, including single-page app route changes made with pushState, replaceState, back/forward, or the fragment, which load no new document; optionally until it matches a regular expression"}, server.WaitForRouteChange)
+	addTool(mcpServer, server, &mcp.Tool{Name: "click", Description: "Click on an element"}, server.Click)
+	addTool(mcpServer, server, &mcp.Tool{Name: "screenshot", Description: "Take a screenshot"}, server.Screenshot)
+	addTool(mcpServer, server, &mcp.Tool{Name: "annotated_screenshot", Description: "Take a screenshot with numbered boxes over interactive elements labelled with the element ids used by aria_snapshot and return the id to selector mapping"}, server.AnnotatedScreenshot)
+	addTool(mcpServer, server, &mcp.Tool{Name: "capture_long_page", Description: "Scroll through a page too tall for one legible screenshot, capturing it as ordered viewport tiles (or one stitched browser://screenshots image) with the interactive elements visible in each tile"}, server.CaptureLongPage)
+	addTool(mcpServer, server, &mcp.Tool{Name: "aria_snapshot", Description: "Capture ARIA accessibility structure for LLM analysis"}, server.ARIASnapshot)
+	addTool(mcpServer, server, &mcp.Tool{Name: "aria_diff", Description: "Compare the page with the previous ARIA snapshot (or one saved with save_as) and return only the added, removed, and changed elements"}, server.ARIADiff)
+	addTool(mcpServer, server, &mcp.Tool{Name: "save_page_state", Description: "Checkpoint the page's URL, scroll position, form values, and a hash of its DOM under a name, for compare_page_state"}, server.SavePageState)
+	addTool(mcpServer, server, &mcp.Tool{Name: "compare_page_state", Description: "Compare the page with a state saved by save_page_state, to detect unintended changes or verify that a retried flow returned to a known state"}, server.ComparePageState)
+	addTool(mcpServer, server, &mcp.Tool{Name: "assert_url_matches", Description: "Assert that the page URL matches a regular expression, retrying until it does or the timeout expires. Fails with diagnostics, so recordings that include assertions replay as regression tests"}, server.AssertURLMatches)
+	addTool(mcpServer, server, &mcp.Tool{Name: "assert_text_present", Description: "Assert that text is shown on the page or in the elements matching a selector (or, with absent, that it is not), retrying until it is or the timeout expires"}, server.AssertTextPresent)
+	addTool(mcpServer, server, &mcp.Tool{Name: "assert_element_visible", Description: "Assert that an element matching a CSS or XPath selector is visible (or, with hidden, that none is), retrying until it is or the timeout expires"}, server.AssertElementVisible)
+	addTool(mcpServer, server, &mcp.Tool{Name: "assert_element_count", Description: "Assert that the number of elements matching a CSS or XPath selector is exactly count or between min and max, retrying until it is or the timeout expires"}, server.AssertElementCount)
+	addTool(mcpServer, server, &mcp.Tool{Name: "find_text", Description: "Search the visible page text for a string or regular expression and return matches with surrounding context and the nearest clickable element"}, server.FindText)
+	addTool(mcpServer, server, &mcp.Tool{Name: "find_element", Description: "Find an element from a natural description such as 'the blue Continue button in the checkout panel', ranking candidates by role, accessible name, text similarity, container, color, and position"}, server.FindElement)
+	addTool(mcpServer, server, &mcp.Tool{Name: "get_page_metadata", Description: "Report the page's SEO metadata: title, meta description, canonical URL, Open Graph and Twitter tags, JSON-LD structured data, hreflang links, and robots directives"}, server.GetPageMetadata)
+	addTool(mcpServer, server, &mcp.Tool{Name: "detect_captcha", Description: "Detect reCAPTCHA, hCaptcha, and Cloudflare Turnstile challenges on the page. navigate and interaction tools also warn when a visible CAPTCHA appears; ask the user to solve it with request_human_input rather than clicking into it"}, server.DetectCaptcha)
+	addTool(mcpServer, server, &mcp.Tool{Name: "highlight_element", Description: "Outline an element in the live browser for a few seconds, optionally with a label, so a human watching can confirm the target before a destructive action"}, server.HighlightElement)
+	addTool(mcpServer, server, &mcp.Tool{Name: "click_element_by_id", Description: "Click an element by the id shown in aria_snapshot or annotated_screenshot"}, server.ClickElementByID)
+	addTool(mcpServer, server, &mcp.Tool{Name: "click_at", Description: "Click a point in the viewport, given in CSS pixels, for canvas apps, maps, and games whose targets are not elements; coordinates can come from ocr_screenshot or annotated_screenshot"}, server.ClickAt)
+	addTool(mcpServer, server, &mcp.Tool{Name: "move_mouse", Description: "Move the mouse to a point in the viewport, hovering whatever is there"}, server.MoveMouse)
+	addTool(mcpServer, server, &mcp.Tool{Name: "swipe", Description: "Drag the mouse or swipe a finger from one point in the viewport to another, to pan maps, move sliders, or drive gesture-based canvases"}, server.Swipe)
+	addTool(mcpServer, server, &mcp.Tool{Name: "type_into_element_by_id", Description: "Type text into an element by the id shown in aria_snapshot or annotated_screenshot"}, server.TypeIntoElementByID)
+	addTool(mcpServer, server, &mcp.Tool{Name: "screenshot_element_by_id", Description: "Take a screenshot of a single element by the id shown in aria_snapshot or annotated_screenshot"}, server.ScreenshotElementByID)
+	addTool(mcpServer, server, &mcp.Tool{Name: "type_text", Description: "Type text into an input field with smart element targeting"}, server.TypeText)
+	addTool(mcpServer, server, &mcp.Tool{Name: "click_button", Description: "Click a button element with smart targeting"}, server.ClickButton)
+	addTool(mcpServer, server, &mcp.Tool{Name: "click_link", Description: "Click a link element with smart targeting"}, server.ClickLink)
+	addTool(mcpServer, server, &mcp.Tool{Name: "select_dropdown", Description: "Select an option from a dropdown with smart targeting"}, server.SelectDropdown)
+	addTool(mcpServer, server, &mcp.Tool{Name: "choose_option", Description: "Check/uncheck a radio button or checkbox with smart targeting"}, server.ChooseOption)
+	addTool(mcpServer, server, &mcp.Tool{Name: "refresh_page", Description: "Refresh the current page"}, server.RefreshPage)
+	addTool(mcpServer, server, &mcp.Tool{Name: "set_window_size", Description: "Resize (and optionally move) the browser window, e.g. to test responsive breakpoints"}, server.SetWindowSize)
+	addTool(mcpServer, server, &mcp.Tool{Name: "maximize", Description: "Maximize the browser window"}, server.Maximize)
+	addTool(mcpServer, server, &mcp.Tool{Name: "minimize", Description: "Minimize the browser window"}, server.Minimize)
+	addTool(mcpServer, server, &mcp.Tool{Name: "bring_to_front", Description: "Restore the browser window if minimized and bring the current tab to the front, so a human can watch or take over"}, server.BringToFront)
+	addTool(mcpServer, server, &mcp.Tool{Name: "emulate_media", Description: "Emulate print media, dark or light prefers-color-scheme, and prefers-reduced-motion, to check themes and print stylesheets; each call replaces the previous emulation and omitted values reset to the browser default"}, server.EmulateMedia)
+	addTool(mcpServer, server, &mcp.Tool{Name: "set_zoom", Description: "Zoom the page out to fit more of a dense page such as a dashboard into a screenshot, or in to read small text; the zoom lasts across navigations and 1 resets it"}, server.SetZoom)
+	addTool(mcpServer, server, &mcp.Tool{Name: "set_blocking_preset", Description: "Block requests for ads, analytics, images, fonts, and/or media, plus any URL patterns, to load pages faster and with less noise for text extraction; each call replaces the previous blocking and an empty list unblocks everything"}, server.SetBlockingPreset)
+	addTool(mcpServer, server, &mcp.Tool{Name: "close_browser", Description: "Close the Chrome browser"}, server.CloseBrowser)
+	addTool(mcpServer, server, &mcp.Tool{Name: "detach_browser", Description: "Release the connection to Chrome without closing it, handing the browser over to the user"}, server.DetachBrowser)
+	addTool(mcpServer, server, &mcp.Tool{Name: "set_chrome_lifecycle", Description: "Control whether Chrome stays open when MCP server exits"}, server.SetChromeLifecycle)
+	addTool(mcpServer, server, &mcp.Tool{Name: "shutdown_server", Description: "Gracefully shutdown the MCP server"}, server.ShutdownServer)
+	addTool(mcpServer, server, &mcp.Tool{Name: "browser_info", Description: "Probe the browser and report its version, CDP protocol version, headless or headful mode, user data directory, and open targets, to adapt to or diagnose the environment"}, server.BrowserInfo)
+	addTool(mcpServer, server, &mcp.Tool{Name: "get_environment", Description: "Report the browser version, supported CDP domains, and any disabled tools"}, server.GetEnvironment)
+	addTool(mcpServer, server, &mcp.Tool{Name: "start_recording", Description: "Start recording performed actions into a replayable script"}, server.StartRecording)
+	addTool(mcpServer, server, &mcp.Tool{Name: "stop_recording", Description: "Stop recording and return the replayable script, optionally saving it under a name"}, server.StopRecording)
+	addTool(mcpServer, server, &mcp.Tool{Name: "replay_recording", Description: "Replay a script recorded with start_recording/stop_recording"}, server.ReplayRecording)
+	if server.allowRawCDP {
+		addTool(mcpServer, server, &mcp.Tool{Name: "execute_cdp", Description: "Send a raw Chrome DevTools Protocol command to the current page and return the raw result"}, server.ExecuteCDP)
+	}
+	if server.vault != nil {
+		addTool(mcpServer, server, &mcp.Tool{Name: "login_with_credentials", Description: "Fill the login form on the page with a credential from the server's vault, referenced by alias, so the password never appears in the conversation"}, server.LoginWithCredentials)
+	}
+	addTool(mcpServer, server, &mcp.Tool{Name: "request_human_input", Description: "Pause and ask the human operator for input the agent cannot provide, such as a two-factor code (optionally typed straight into a field) or solving a CAPTCHA in the browser; blocks until the operator answers"}, server.RequestHumanInput)
+	addTool(mcpServer, server, &mcp.Tool{Name: "subscribe_events", Description: "Buffer CDP events from the Network, Page, and/or Runtime domains for poll_events"}, server.SubscribeEvents)
+	addTool(mcpServer, server, &mcp.Tool{Name: "poll_events", Description: "Retrieve and remove buffered CDP events, e.g. redirects, XHR completions, and console errors"}, server.PollEvents)
+	addTool(mcpServer, server, &mcp.Tool{Name: "batch", Description: "Run an ordered list of tool calls in one request and return per-step results"}, server.Batch)
+	addTool(mcpServer, server, &mcp.Tool{Name: "define_macro", Description: "Define a named macro: a sequence of tool calls with {{param}} placeholders, run in one call with run_macro"}, server.DefineMacro)
+	addTool(mcpServer, server, &mcp.Tool{Name: "run_macro", Description: "Run a macro defined with define_macro, stopping at the first failing step"}, server.RunMacro)
+	addTool(mcpServer, server, &mcp.Tool{Name: "run_workflow", Description: "Run a declarative YAML or JSON workflow (tool call steps with {{variable}} placeholders, expected results, and retries), stored on the server or passed inline, and return a per-step report"}, server.RunWorkflow)
+	addTool(mcpServer, server, &mcp.Tool{Name: "crawl", Description: "Crawl a site from a start URL in parallel tabs, following links that match a pattern up to a depth and extracting fields by CSS selector from every page; reports progress and returns the results as a browser://crawls resource"}, server.Crawl)
+	addTool(mcpServer, server, &mcp.Tool{Name: "bulk_screenshot", Description: "Capture screenshots of a list of URLs, or of the pages in a sitemap, in parallel tabs at a given viewport size, scale and full-page setting; returns browser://screenshots resource links for visual regression and design review"}, server.BulkScreenshot)
+	addTool(mcpServer, server, &mcp.Tool{Name: "compare_screenshots", Description: "Compare a screenshot with a baseline screenshot resource pixel by pixel, returning the percentage of changed pixels, a diff image, and the changed regions with the selectors of the elements there; by default the current page is captured and compared"}, server.CompareScreenshots)
+	addTool(mcpServer, server, &mcp.Tool{Name: "ocr_screenshot", Description: "Recognize the text in the viewport or an element with OCR and return it with bounding boxes, for canvas-rendered apps, charts and images whose text is not in the DOM"}, server.OCRScreenshot)
+	addTool(mcpServer, server, &mcp.Tool{Name: "start_coverage", Description: "Start collecting JavaScript and CSS code coverage for the current tab; load and use the page, then call stop_coverage"}, server.StartCoverage)
+	addTool(mcpServer, server, &mcp.Tool{Name: "stop_coverage", Description: "Stop collecting code coverage and report the used and unused bytes of each script and stylesheet, to find dead code on a page"}, server.StopCoverage)
+	addTool(mcpServer, server, &mcp.Tool{Name: "get_page_errors", Description: "Get the uncaught JavaScript exceptions the page threw, with their stack traces; pass since to only get those after a previous call"}, server.GetPageErrors)
+	addTool(mcpServer, server, &mcp.Tool{Name: "list_websockets", Description: "List the page's WebSocket connections with their state and the number and size of frames sent and received"}, server.ListWebSockets)
+	addTool(mcpServer, server, &mcp.Tool{Name: "start_websocket_capture", Description: "Start capturing WebSocket frames, optionally only of connections whose URL contains a string or in one direction, with payloads cut to a size limit; replaces any capture in progress"}, server.StartWebSocketCapture)
+	addTool(mcpServer, server, &mcp.Tool{Name: "get_websocket_frames", Description: "Get the captured WebSocket frames, filtered by connection and payload text; pass since to only get frames after a previous call, to debug realtime apps"}, server.GetWebSocketFrames)
+	addTool(mcpServer, server, &mcp.Tool{Name: "stop_websocket_capture", Description: "Stop capturing WebSocket frames; the captured frames stay readable until the next capture"}, server.StopWebSocketCapture)
+	addTool(mcpServer, server, &mcp.Tool{Name: "save_profile", Description: "Save the browser's user data (cookies, localStorage, saved logins) to a named profile, to reuse an authenticated state in later sessions"}, server.SaveProfile)
+	addTool(mcpServer, server, &mcp.Tool{Name: "load_profile", Description: "Restart the browser with a profile saved by save_profile, restoring its cookies, localStorage and logins"}, server.LoadProfile)
+	addTool(mcpServer, server, &mcp.Tool{Name: "list_profiles", Description: "List the profiles saved with save_profile"}, server.ListProfiles)
+	addTool(mcpServer, server, &mcp.Tool{Name: "configure_tools", Description: "Switch the tool profile (readonly, standard, or admin) or expose and hide individual tools at runtime; clients are notified that the tool list changed"}, server.ConfigureTools)
+	addTool(mcpServer, server, &mcp.Tool{Name: "get_tool_stats", Description: "Report per-tool call counts, error rates, and average latency since the server started"}, server.GetToolStats)
+	server.addCustomTools(mcpServer)
+	log.Println("All tools registered successfully")
+
+	mcpServer.AddResource(&mcp.Resource{
+		URI:         statsURI,
+		Name:        "stats",
+		Description: "Per-tool call counts, error rates, and average latency",
+		MIMEType:    "application/json",
+	}, server.readStats)
+
+	mcpServer.AddResource(&mcp.Resource{
+		URI:         stateURI,
+		Name:        "state",
+		Description: "The current URL and title, open tabs, recent downloads, and any open JavaScript dialog; subscribe to be notified when they change",
+		MIMEType:    "application/json",
+	}, server.readState)
+	mcpServer.AddResource(&mcp.Resource{
+		URI:         pageMarkdownURI,
+		Name:        "page markdown",
+		Description: "The readable content of the current page as Markdown: the main article, or the page without its navigation, read when requested",
+		MIMEType:    "text/markdown",
+	}, server.readPage)
+	mcpServer.AddResource(&mcp.Resource{
+		URI:         pageTextURI,
+		Name:        "page text",
+		Description: "The readable content of the current page as plain text, read when requested",
+		MIMEType:    "text/plain",
+	}, server.readPage)
+
+	mcpServer.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: crawlsTemplate,
+		Name:        "crawls",
+		Description: "Pages visited by a crawl and the fields extracted from them",
+		MIMEType:    "application/json",
+	}, server.readCrawl)
+	mcpServer.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: screenshotsTemplate,
+		Name:        "screenshots",
+		Description: "Screenshots and diff images taken by bulk_screenshot and compare_screenshots",
+		MIMEType:    "image/png",
+	}, server.readScreenshot)
+
+	if server.errorArtifacts == errorArtifactsResource {
+		mcpServer.AddResourceTemplate(&mcp.ResourceTemplate{
+			URITemplate: errorArtifactsTemplate,
+			Name:        "error screenshots",
+			Description: "Screenshots of the page taken when an interaction tool failed",
+			MIMEType:    "image/png",
+		}, server.readErrorArtifact)
+	}
+
+	mcpServer.AddReceivingMiddleware(tracingMiddleware, server.statsMiddleware, server.idempotencyMiddleware, server.rateLimitMiddleware, server.recordingMiddleware, server.secretMaskingMiddleware, server.toolErrorMiddleware, server.errorArtifactsMiddleware, server.pageErrorsMiddleware, server.elicitation.middleware)
+	return mcpServer
+}
+
+// Main parses the command-line flags and runs the server, serving MCP on
+// STDIO until the client disconnects or the server is shut down. Tools added
+// with [RegisterTool] before Main is called are served alongside the
+// built-in ones.
+func Main() {
+	allowDomains := flag.String("allow-domains", "", "comma-separated list of domains the browser may load (all others are blocked)")
+	denyDomains := flag.String("deny-domains", "", "comma-separated list of domains the browser may not load")
+	policyFile := flag.String("policy", "", "path to a JSON navigation policy file with allow_domains and deny_domains lists")
+	confirmDestructive := flag.Bool("confirm-destructive", false, "require confirm: true, or the operator's approval through elicitation, for form submissions and delete/purchase-like clicks")
+	confirmPolicyFile := flag.String("confirm-policy", "", "path to a JSON confirmation policy with submit, text, and urls patterns; implies -confirm-destructive")
+	allowRawCDP := flag.Bool("allow-raw-cdp", false, "expose the execute_cdp tool, which sends arbitrary CDP commands to the browser")
+	replayPath := flag.String("replay", "", "replay a recording script saved by stop_recording and exit, instead of serving MCP on STDIO")
+	selectorConfigFile := flag.String("selector-config", "", "path to a JSON file enabling, disabling, reordering or adding smart selector strategies")
+	actionTimeout := flag.Duration("action-timeout", defaultActionTimeout, "how long click and type tools wait for their element to be visible, enabled, stable, and not covered")
+	errorArtifacts := flag.String("error-artifacts", errorArtifactsInline, "what failed interaction tools attach to their error: off, inline (a screenshot and page excerpt), or resource (a page excerpt and a browser://errors link to the screenshot)")
+	toolProfile := flag.String("tool-profile", defaultToolProfile, "tools to expose: readonly (navigation, snapshots, screenshots, extraction, and assertions), standard (adds page interaction), or admin (adds browser, server, raw CDP, profile, and recording control)")
+	toolConfigFile := flag.String("tool-config", "", "path to a JSON tool config with profile, enable, and disable lists, overriding -tool-profile; reloaded on SIGHUP")
+	toolPluginsFile := flag.String("tool-plugins", "", "path to a JSON file of custom tools implemented by external commands, which get the tool arguments on standard input")
+	browserName := flag.String("browser", defaultBrowserBackend, "browser backend to drive: "+strings.Join(browserBackendNames(), ", "))
+	bidiURL := flag.String("bidi-url", "", "WebSocket URL of a WebDriver BiDi endpoint for -browser bidi, e.g. ws://localhost:9222/session or a grid session's webSocketUrl (default: launch Firefox)")
+	workflowsDir := flag.String("workflows-dir", "", "directory run_workflow loads stored workflows from, as NAME.yaml, NAME.yml, or NAME.json (default: inline workflows only)")
+	failOnPageError := flag.Bool("fail-on-page-error", false, "fail click, type, and other interaction tools with PAGE_ERROR if the page throws an uncaught JavaScript exception during the action")
+	ocrEngine := flag.String("ocr-engine", defaultOCREngine, "OCR engine ocr_screenshot uses: "+strings.Join(ocrEngineNames(), ", ")+"; tesseract runs the tesseract command")
+	profilesDir := flag.String("profiles-dir", defaultProfilesDir(), "directory save_profile and load_profile keep browser profiles in")
+	recordingsDir := flag.String("recordings-dir", defaultRecordingsDir(), "directory stop_recording saves named recordings in and replay_recording loads them from")
+	vaultPath := flag.String("vault", "", "encrypted credential vault for login_with_credentials; the passphrase is read from $"+vaultPassphraseEnv)
+	vaultSet := flag.String("vault-set", "", "add or replace the credential with this alias in -vault, reading the password from standard input, and exit")
+	vaultDelete := flag.String("vault-delete", "", "remove the credential with this alias from -vault and exit")
+	vaultUsername := flag.String("vault-username", "", "username of the credential added with -vault-set")
+	vaultDomains := flag.String("vault-domains", "", "comma-separated domains the credential added with -vault-set may be entered on (default: any)")
+	chromePort := flag.Int("chrome-port", 9222, "remote debugging port to launch Chrome with; give each server running at once its own port and -chrome-user-data-dir")
+	chromeUserData := flag.String("chrome-user-data-dir", chromeUserDataDir(), "user data directory to launch Chrome with")
+	maxConcurrentCalls := flag.Int("max-concurrent-calls", 0, "maximum number of tool calls running at once across all sessions, which share one browser (0 means no limit)")
+	maxCallsPerMinute := flag.Int("max-calls-per-minute", 0, "maximum number of tool calls, including batch, macro, and replay steps, a session may make per minute (0 means no limit)")
+	maxNavigationsPerMinute := flag.Int("max-navigations-per-minute", 0, "maximum number of navigate and refresh_page calls a session may make per minute (0 means no limit)")
+	listenPath := flag.String("listen", "", "serve MCP over streamable HTTP on the Unix socket at `path` instead of STDIO, to clients that come and go, until shut down")
+	soak := flag.Bool("soak", false, "run the load/soak harness against local fixtures instead of serving MCP on STDIO")
+	var soakCfg soakConfig
+	flag.IntVar(&soakCfg.Cycles, "soak-cycles", 1000, "number of navigate/snapshot/click cycles to run in soak mode")
+	flag.IntVar(&soakCfg.ReportEvery, "soak-report-every", 100, "sample resource usage and check thresholds every N cycles")
+	flag.Uint64Var(&soakCfg.MaxHeapMB, "soak-max-heap-mb", 512, "fail the soak run if the Go heap exceeds this many MB (0 disables)")
+	flag.IntVar(&soakCfg.MaxGoroutineGrowth, "soak-max-goroutine-growth", 50, "fail the soak run if goroutines grow by more than this (0 disables)")
+	flag.IntVar(&soakCfg.MaxTargets, "soak-max-targets", 1, "fail the soak run if more Chrome page targets are open (0 disables)")
+	flag.IntVar(&soakCfg.MaxErrors, "soak-max-errors", 0, "fail the soak run after more than this many tool errors")
+	flag.Parse()
+
+	log.Printf("Starting %s v%s in long-running mode", serverName, serverVersion)
+
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Printf("Failed to flush traces: %v", err)
+		}
+	}()
+
+	// Read the vault passphrase once and keep it out of the environment the
+	// browser inherits.
+	vaultPassphrase := os.Getenv(vaultPassphraseEnv)
+	os.Unsetenv(vaultPassphraseEnv)
+	if *vaultSet != "" || *vaultDelete != "" {
+		if *vaultPath == "" {
+			log.Fatal("-vault-set and -vault-delete need -vault")
+		}
+		if *vaultSet != "" {
+			err = setVaultCredential(*vaultPath, vaultPassphrase, *vaultSet, *vaultUsername, splitDomains(*vaultDomains), os.Stdin)
+		} else {
+			err = deleteVaultCredential(*vaultPath, vaultPassphrase, *vaultDelete)
+		}
+		if err != nil {
+			log.Fatalf("Failed to update credential vault: %v", err)
+		}
+		log.Printf("Updated credential vault %s", *vaultPath)
+		return
+	}
+
+	// Check environment variable for Chrome lifecycle control
+	keepOpen := true // Default to keeping Chrome open
+	if envVal := os.Getenv("CLOSE_CHROME_ON_EXIT"); envVal == "true" || envVal == "1" {
+		keepOpen = false
+		log.Printf("Environment variable CLOSE_CHROME_ON_EXIT=%s - Chrome will be closed on exit", envVal)
+	} else {
+		log.Printf("Chrome will remain open when MCP server exits (default behavior)")
+	}
+	if *actionTimeout <= 0 {
+		log.Fatalf("-action-timeout must be positive, got %v", *actionTimeout)
+	}
+	if *toolPluginsFile != "" {
+		plugins, err := loadSubprocessTools(*toolPluginsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, p := range plugins {
+			p.register()
+		}
+		log.Printf("Loaded %d tool plugins from %s", len(plugins), *toolPluginsFile)
+	}
+
+	server, err := New(&Options{
+		Browser:                 *browserName,
+		BiDiURL:                 *bidiURL,
+		ChromePort:              *chromePort,
+		ChromeUserDataDir:       *chromeUserData,
+		KeepBrowserOpen:         keepOpen,
+		AllowDomains:            splitDomains(*allowDomains),
+		DenyDomains:             splitDomains(*denyDomains),
+		PolicyFile:              *policyFile,
+		ConfirmDestructive:      *confirmDestructive,
+		ConfirmPolicyFile:       *confirmPolicyFile,
+		AllowRawCDP:             *allowRawCDP,
+		ToolProfile:             *toolProfile,
+		ToolConfigFile:          *toolConfigFile,
+		MaxConcurrentCalls:      *maxConcurrentCalls,
+		MaxCallsPerMinute:       *maxCallsPerMinute,
+		MaxNavigationsPerMinute: *maxNavigationsPerMinute,
+		SelectorConfigFile:      *selectorConfigFile,
+		ActionTimeout:           *actionTimeout,
+		ErrorArtifacts:          *errorArtifacts,
+		ProfilesDir:             *profilesDir,
+		RecordingsDir:           *recordingsDir,
+		WorkflowsDir:            *workflowsDir,
+		OCREngine:               *ocrEngine,
+		FailOnPageError:         *failOnPageError,
+		VaultPath:               *vaultPath,
+		VaultPassphrase:         vaultPassphrase,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.SetOutput(server.LogWriter(os.Stderr))
+
+	if err := server.Start(); err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		log.Println("Shutting down server and cleaning up resources...")
+		server.Close()
+	}()
+
+	log.Println("Browser initialized successfully, ready to accept MCP requests")
+
+	if *soak {
+		err := runSoak(server, soakCfg)
+		server.Close()
+		if err != nil {
+			log.Fatalf("Soak test failed: %v", err)
+		}
+		return
+	}
+
+	if *toolConfigFile != "" {
+		server.reloadToolConfigOnHangup(*toolConfigFile)
+	}
+
+	if *replayPath != "" {
+		rec, err := loadRecording(*replayPath)
+		if err != nil {
+			log.Fatalf("Failed to load recording: %v", err)
+		}
+		result := server.replay(context.Background(), rec, false, false)
+		fmt.Fprint(os.Stderr, result.String())
+		server.Close()
+		if !result.Passed {
+			log.Fatalf("Replay of %s failed", *replayPath)
+		}
+		return
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	if *listenPath != "" {
+		l, err := listenUnix(*listenPath)
+		if err != nil {
+			log.Fatalf("Failed to listen on %s: %v", *listenPath, err)
+		}
+		log.Printf("Server ready - waiting for MCP sessions on %s", *listenPath)
+		if err := server.ServeListener(ctx, l); err != nil {
+			log.Printf("Server stopped with error: %v", err)
+		}
+		log.Println("Server shutdown complete")
+		return
+	}
+
+	transport := &mcp.StdioTransport{}
+
+	log.Println("Server ready - waiting for MCP requests on STDIO")
+	if err := server.Serve(ctx, transport); err != nil {
+		log.Printf("Server stopped with error: %v", err)
+	}
+
+	log.Println("Server shutdown complete")
+}
@@ -0,0 +1,141 @@
+package browserserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// drainTimeout is how long shutdown waits for in-flight tool calls to
+// finish.
+const drainTimeout = 30 * time.Second
+
+// Serve serves the tools of s, which must have been started, on transport
+// until the client disconnects, ctx is done, or Shutdown or shutdown_server
+// is called. On shutdown, the session stops accepting requests and Serve
+// waits up to drainTimeout for the in-flight tool calls to finish and their
+// results to be sent, so that the caller can Close s afterwards.
+func (s *CDPBrowserServer) Serve(ctx context.Context, transport mcp.Transport) error {
+	if s.mcpServer == nil {
+		return errors.New("server not started")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer s.serving(cancel)()
+
+	done := make(chan error, 1)
+	go func() { done <- s.mcpServer.Run(ctx, transport) }()
+	select {
+	case err := <-done:
+		if ctx.Err() == nil {
+			return err
+		}
+	case <-ctx.Done():
+		log.Printf("Shutting down: waiting up to %v for in-flight tool calls", drainTimeout)
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				return err
+			}
+		case <-time.After(drainTimeout):
+			return fmt.Errorf("in-flight tool calls did not finish within %v", drainTimeout)
+		}
+	}
+	return nil
+}
+
+// ServeListener serves the tools of s, which must have been started, over
+// the streamable HTTP transport on l, one MCP session per client, until ctx
+// is done or Shutdown or shutdown_server is called. Clients may connect and
+// disconnect in the meantime. On shutdown, it stops accepting connections
+// and waits up to drainTimeout for the requests in flight to finish.
+func (s *CDPBrowserServer) ServeListener(ctx context.Context, l net.Listener) error {
+	if s.mcpServer == nil {
+		return errors.New("server not started")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer s.serving(cancel)()
+
+	srv := &http.Server{Handler: s.Handler()}
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		log.Printf("Shutting down: waiting up to %v for in-flight tool calls", drainTimeout)
+		drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+		if err := srv.Shutdown(drainCtx); err != nil {
+			return fmt.Errorf("in-flight tool calls did not finish within %v", drainTimeout)
+		}
+	}
+	return nil
+}
+
+// serving makes cancel what Shutdown calls, and returns a function that
+// unsets it once serving is over.
+func (s *CDPBrowserServer) serving(cancel context.CancelFunc) func() {
+	s.shutdownMu.Lock()
+	s.shutdown = cancel
+	s.shutdownMu.Unlock()
+	return func() {
+		s.shutdownMu.Lock()
+		s.shutdown = nil
+		s.shutdownMu.Unlock()
+	}
+}
+
+// Shutdown makes Serve or ServeListener return gracefully, as
+// shutdown_server does. It reports whether either was running.
+func (s *CDPBrowserServer) Shutdown() bool {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+	if s.shutdown == nil {
+		return false
+	}
+	s.shutdown()
+	return true
+}
+
+// listenUnix listens on the Unix socket at path, replacing the socket a
+// server that did not shut down left behind. Any other file at path is
+// left alone, and listening fails.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("a server is already listening on %s", path)
+		}
+		os.Remove(path)
+	}
+	return net.Listen("unix", path)
+}
+
+// signalContext returns a context that is canceled when the process receives
+// SIGINT or SIGTERM. After the first signal, a second one kills the process.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigs:
+			log.Println("Received shutdown signal")
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+		cancel()
+	}()
+	return ctx, cancel
+}
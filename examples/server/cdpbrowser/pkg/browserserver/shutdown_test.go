@@ -0,0 +1,187 @@
+package browserserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestServeShutdown(t *testing.T) {
+	s := &CDPBrowserServer{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	addTool(server, s, &mcp.Tool{Name: "click"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+		close(started)
+		<-release
+		return &mcp.CallToolResultFor[SelectorMatch]{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Clicked element: " + req.Params.Arguments.Selector}},
+		}, nil
+	})
+	addTool(server, s, &mcp.Tool{Name: "shutdown_server"}, s.ShutdownServer)
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	served := make(chan error, 1)
+	s.mcpServer = server
+	go func() { served <- s.Serve(ctx, serverTransport) }()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	// A tool call in flight when shutdown is requested completes.
+	var wg sync.WaitGroup
+	var clickRes *mcp.CallToolResult
+	var clickErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		clickRes, clickErr = cs.CallTool(ctx, &mcp.CallToolParams{Name: "click", Arguments: map[string]any{"selector": "#pay"}})
+	}()
+	<-started
+	if !s.Shutdown() {
+		t.Fatal("Shutdown() = false while serving")
+	}
+
+	select {
+	case err := <-served:
+		t.Fatalf("serve returned with a tool call in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	wg.Wait()
+	if clickErr != nil || resultText(clickRes) != "Clicked element: #pay" {
+		t.Errorf("in-flight click = %v, %v", clickRes, clickErr)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serve error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after the in-flight call finished")
+	}
+}
+
+func TestShutdownServerTool(t *testing.T) {
+	s := &CDPBrowserServer{}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	addTool(server, s, &mcp.Tool{Name: "shutdown_server"}, s.ShutdownServer)
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	served := make(chan error, 1)
+	s.mcpServer = server
+	go func() { served <- s.Serve(ctx, serverTransport) }()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "shutdown_server"})
+	if err != nil {
+		t.Fatalf("shutdown_server error = %v", err)
+	}
+	if res.IsError || resultText(res) != "Server shutdown initiated" {
+		t.Errorf("shutdown_server result = %q", resultText(res))
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serve error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after shutdown_server")
+	}
+	if _, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "shutdown_server"}); err == nil {
+		t.Error("tool call after shutdown succeeded")
+	}
+}
+
+func TestServeListener(t *testing.T) {
+	s := &CDPBrowserServer{}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	addTool(server, s, &mcp.Tool{Name: "shutdown_server"}, s.ShutdownServer)
+	s.mcpServer = server
+
+	// Socket paths are limited to about 100 bytes, which t.TempDir may
+	// exceed.
+	dir, err := os.MkdirTemp("", "cdpbrowser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "server.sock")
+	l, err := listenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.ServeListener(context.Background(), l) }()
+	if _, err := listenUnix(path); err == nil {
+		t.Error("listenUnix on the socket of a running server succeeded")
+	}
+
+	ctx := context.Background()
+	httpClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	connect := func() *mcp.ClientSession {
+		transport := mcp.NewStreamableClientTransport("http://cdpbrowser/", &mcp.StreamableClientTransportOptions{HTTPClient: httpClient})
+		cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, transport, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cs
+	}
+
+	// Sessions come and go while the server keeps running.
+	cs := connect()
+	if err := cs.Ping(ctx, nil); err != nil {
+		t.Errorf("ping of the first session: %v", err)
+	}
+	cs.Close()
+	cs = connect()
+	defer cs.Close()
+	if _, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "shutdown_server"}); err != nil {
+		t.Fatalf("shutdown_server error = %v", err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("ServeListener error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeListener did not return after shutdown_server")
+	}
+
+	// A socket left behind is replaced; another file is not.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	l, err = listenUnix(path)
+	if err != nil {
+		t.Fatalf("listenUnix on a stale socket: %v", err)
+	}
+	l.Close()
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, nil, 0644)
+	if _, err := listenUnix(file); err == nil {
+		t.Error("listenUnix on a regular file succeeded")
+	}
+}
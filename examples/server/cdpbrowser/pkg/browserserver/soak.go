@@ -0,0 +1,191 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// soakFixture is the page the soak harness drives. Each load renders a fresh
+// button so that clicks exercise real DOM work.
+const soakFixture = `<!DOCTYPE html>
+<html>
+<head><title>Soak Fixture</title></head>
+<body>
+<main>
+  <h1>Soak test</h1>
+  <button id="counter" onclick="this.textContent = 'Clicked ' + (++window.clicks)">Click me</button>
+  <a href="/?next=1">Next page</a>
+  <script>window.clicks = 0;</script>
+</main>
+</body>
+</html>`
+
+// soakConfig controls the soak harness and the thresholds that fail a run.
+type soakConfig struct {
+	Cycles             int
+	ReportEvery        int
+	MaxHeapMB          uint64
+	MaxGoroutineGrowth int
+	MaxTargets         int
+	MaxErrors          int
+}
+
+// soakStats is a point-in-time resource sample.
+type soakStats struct {
+	HeapMB     uint64
+	Goroutines int
+	Targets    int
+}
+
+// sampleSoakStats records Go heap, goroutine, and Chrome page target counts.
+// Targets are only counted over CDP.
+func (s *CDPBrowserServer) sampleSoakStats() (soakStats, error) {
+	var mem runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&mem)
+	if !s.usesCDP() {
+		return soakStats{HeapMB: mem.HeapAlloc / (1 << 20), Goroutines: runtime.NumGoroutine()}, nil
+	}
+
+	infos, err := chromedp.Targets(s.ctx)
+	if err != nil {
+		return soakStats{}, fmt.Errorf("failed to list Chrome targets: %v", err)
+	}
+	pages := 0
+	for _, info := range infos {
+		if info.Type == "page" {
+			pages++
+		}
+	}
+	return soakStats{
+		HeapMB:     mem.HeapAlloc / (1 << 20),
+		Goroutines: runtime.NumGoroutine(),
+		Targets:    pages,
+	}, nil
+}
+
+// soakTools are the tools each soak cycle calls.
+var soakTools = []string{"navigate", "aria_snapshot", "click"}
+
+// checkSoakToolset returns an error if a tool the soak cycles call is not
+// exposed or cannot run, or if the navigation policy blocks the fixture
+// server at fixturesURL, so that a misconfigured run fails before it starts
+// instead of counting every call as an error.
+func (s *CDPBrowserServer) checkSoakToolset(fixturesURL string) error {
+	for _, name := range soakTools {
+		if !s.hasTool(name) {
+			return fmt.Errorf("soak needs the %s tool, which the tool profile or tool config does not expose", name)
+		}
+		if reason := s.unavailableReason(name); reason != "" {
+			return fmt.Errorf("soak needs the %s tool, which is %s", name, reason)
+		}
+	}
+	if err := s.policy.check(fixturesURL); err != nil {
+		return fmt.Errorf("soak fixtures are blocked: %v", err)
+	}
+	return nil
+}
+
+// runSoak drives cfg.Cycles navigate/snapshot/click cycles through an
+// in-memory MCP session against a local fixture server, sampling resource
+// usage as it goes. It returns an error if any threshold is exceeded, or
+// before the first cycle if the server cannot run the cycles at all.
+func runSoak(server *CDPBrowserServer, cfg soakConfig) error {
+	fixtures := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, soakFixture)
+	}))
+	defer fixtures.Close()
+	if err := server.checkSoakToolset(fixtures.URL + "/"); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect soak server session: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "soak"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect soak client session: %v", err)
+	}
+	defer session.Close()
+
+	baseline, err := server.sampleSoakStats()
+	if err != nil {
+		return err
+	}
+	log.Printf("Soak: starting %d cycles (baseline heap=%dMB goroutines=%d targets=%d)",
+		cfg.Cycles, baseline.HeapMB, baseline.Goroutines, baseline.Targets)
+
+	calls := []struct {
+		name string
+		args map[string]any
+	}{
+		{"navigate", map[string]any{"url": fixtures.URL + "/"}},
+		{"aria_snapshot", map[string]any{"format": "llm-text", "focus": "all"}},
+		{"click", map[string]any{"selector": "#counter"}},
+	}
+
+	failures := 0
+	start := time.Now()
+	peak := baseline
+	check := func(cycle int) error {
+		stats, err := server.sampleSoakStats()
+		if err != nil {
+			return err
+		}
+		peak.HeapMB = max(peak.HeapMB, stats.HeapMB)
+		peak.Goroutines = max(peak.Goroutines, stats.Goroutines)
+		peak.Targets = max(peak.Targets, stats.Targets)
+		log.Printf("Soak: cycle %d/%d heap=%dMB goroutines=%d targets=%d errors=%d elapsed=%s",
+			cycle, cfg.Cycles, stats.HeapMB, stats.Goroutines, stats.Targets, failures, time.Since(start).Round(time.Second))
+
+		switch {
+		case cfg.MaxHeapMB > 0 && stats.HeapMB > cfg.MaxHeapMB:
+			return fmt.Errorf("heap %dMB exceeds limit %dMB", stats.HeapMB, cfg.MaxHeapMB)
+		case cfg.MaxGoroutineGrowth > 0 && stats.Goroutines-baseline.Goroutines > cfg.MaxGoroutineGrowth:
+			return fmt.Errorf("goroutines grew from %d to %d, exceeding limit of %d", baseline.Goroutines, stats.Goroutines, cfg.MaxGoroutineGrowth)
+		case cfg.MaxTargets > 0 && stats.Targets > cfg.MaxTargets:
+			return fmt.Errorf("%d Chrome page targets open, exceeding limit of %d", stats.Targets, cfg.MaxTargets)
+		case failures > cfg.MaxErrors:
+			return fmt.Errorf("%d tool errors, exceeding limit of %d", failures, cfg.MaxErrors)
+		}
+		return nil
+	}
+
+	for cycle := 1; cycle <= cfg.Cycles; cycle++ {
+		for _, call := range calls {
+			callCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			res, err := session.CallTool(callCtx, &mcp.CallToolParams{Name: call.name, Arguments: call.args})
+			cancel()
+			if err != nil {
+				failures++
+				log.Printf("Soak: cycle %d: %s failed: %v", cycle, call.name, err)
+			} else if res.IsError {
+				failures++
+				log.Printf("Soak: cycle %d: %s returned a tool error", cycle, call.name)
+			}
+		}
+		if (cfg.ReportEvery > 0 && cycle%cfg.ReportEvery == 0) || cycle == cfg.Cycles {
+			if err := check(cycle); err != nil {
+				return fmt.Errorf("soak failed at cycle %d: %v", cycle, err)
+			}
+		}
+	}
+
+	log.Printf("Soak: passed %d cycles in %s (peak heap=%dMB goroutines=%d targets=%d, errors=%d)",
+		cfg.Cycles, time.Since(start).Round(time.Second), peak.HeapMB, peak.Goroutines, peak.Targets, failures)
+	return nil
+}
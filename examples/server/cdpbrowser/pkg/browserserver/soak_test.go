@@ -0,0 +1,56 @@
+package browserserver
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSoakTestServer returns a server driving a fake page with the soak
+// fixture's button.
+func newSoakTestServer(t *testing.T, config toolConfig) (*CDPBrowserServer, *fakePage, *fakeBrowser) {
+	t.Helper()
+	page := &fakePage{selector: "#counter"}
+	fake := &fakeBrowser{eval: page.eval}
+	s := &CDPBrowserServer{browser: fake, stats: newToolStats(), policy: &navigationPolicy{}, actionTimeout: 100 * time.Millisecond}
+	s.toolset.config = config
+	s.mcpServer = newMCPServer(s)
+	return s, page, fake
+}
+
+func TestRunSoak(t *testing.T) {
+	s, page, _ := newSoakTestServer(t, toolConfig{Profile: toolProfileStandard})
+	if err := runSoak(s, soakConfig{Cycles: 3, ReportEvery: 2}); err != nil {
+		t.Fatalf("runSoak() = %v", err)
+	}
+	if got := strings.Join(page.actions, ","); got != "click,click,click" {
+		t.Errorf("page actions = %q, want a click per cycle", got)
+	}
+}
+
+func TestRunSoakChecksToolset(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config toolConfig
+		policy *navigationPolicy
+		want   string
+	}{
+		{"profile", toolConfig{Profile: toolProfileReadonly}, nil, "soak needs the click tool"},
+		{"disabled", toolConfig{Profile: toolProfileStandard, Disable: []string{"navigate"}}, nil, "soak needs the navigate tool"},
+		{"policy", toolConfig{Profile: toolProfileStandard}, &navigationPolicy{AllowDomains: []string{"example.com"}}, "soak fixtures are blocked"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _, fake := newSoakTestServer(t, tc.config)
+			if tc.policy != nil {
+				s.policy = tc.policy
+			}
+			err := runSoak(s, soakConfig{Cycles: 3})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("runSoak() = %v, want %q", err, tc.want)
+			}
+			if len(fake.calls) != 0 {
+				t.Errorf("runSoak called the browser before failing: %q", fake.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// stateURI is the resource that exposes the current page, tabs, downloads,
+// and dialog, so clients can read them without calling tools.
+const stateURI = "browser://state"
+
+// maxTrackedDownloads bounds the downloads listed in browser://state.
+const maxTrackedDownloads = 20
+
+// BrowserState is the content of browser://state.
+type BrowserState struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Tabs lists the open tabs, including those opened by tools such as
+	// crawl, or only the current page if the backend cannot list them.
+	Tabs []TabState `json:"tabs"`
+	// Downloads lists the most recent downloads, oldest first.
+	Downloads        []DownloadState `json:"downloads"`
+	PendingDownloads int             `json:"pending_downloads"`
+	// Dialog is the JavaScript dialog open in the current tab, which blocks
+	// the page until it is handled, or nil.
+	Dialog *DialogState `json:"dialog"`
+}
+
+// TabState describes an open tab in BrowserState.
+type TabState struct {
+	ID      string `json:"id"`
+	URL     string `json:"url"`
+	Title   string `json:"title"`
+	Current bool   `json:"current,omitempty"`
+}
+
+// DownloadState describes a download in BrowserState.
+type DownloadState struct {
+	URL           string  `json:"url"`
+	Filename      string  `json:"filename"`
+	State         string  `json:"state" jsonschema:"inProgress, completed, or canceled"`
+	ReceivedBytes float64 `json:"received_bytes"`
+	TotalBytes    float64 `json:"total_bytes"`
+	Path          string  `json:"path,omitempty"`
+}
+
+// DialogState describes an open JavaScript dialog in BrowserState.
+type DialogState struct {
+	Type          string    `json:"type" jsonschema:"alert, confirm, prompt, or beforeunload"`
+	Message       string    `json:"message"`
+	URL           string    `json:"url"`
+	DefaultPrompt string    `json:"default_prompt,omitempty"`
+	Opened        time.Time `json:"opened"`
+}
+
+// stateTracker follows the tabs, downloads, and dialogs of the browser
+// through CDP events, which keeps browser://state cheap to read and
+// readable while a dialog blocks the page.
+type stateTracker struct {
+	mu sync.Mutex
+	// started is set once the tracker listens to a browser.
+	started bool
+	// current is the ID of the tab the tools drive.
+	current   target.ID
+	tabs      map[target.ID]*TabState
+	tabOrder  []target.ID
+	downloads map[string]*DownloadState
+	// downloadOrder lists the GUIDs of the downloads, oldest first.
+	downloadOrder []string
+	dialog        *DialogState
+	// changed is called, without mu held, after the state changes.
+	changed func()
+}
+
+// start seeds the tracker with the tabs of the browser of tabCtx and
+// follows its events, calling changed after each change. tabCtx is the tab
+// the tools drive; calling start again for a new browser resets the
+// tracker.
+func (t *stateTracker) start(tabCtx context.Context, changed func()) error {
+	c := chromedp.FromContext(tabCtx)
+	if c == nil || c.Target == nil {
+		return fmt.Errorf("no tab to track")
+	}
+	infos, err := chromedp.Targets(tabCtx)
+	if err != nil {
+		return fmt.Errorf("listing tabs: %w", err)
+	}
+
+	t.reset(c.Target.TargetID, infos, changed)
+
+	chromedp.ListenBrowser(tabCtx, t.event)
+	chromedp.ListenTarget(tabCtx, t.event)
+	// Download events are only sent once enabled; the default behavior
+	// keeps downloads working as before.
+	err = chromedp.Run(tabCtx, browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorDefault).WithEventsEnabled(true))
+	if err != nil {
+		log.Printf("Failed to enable download events, browser://state will not list downloads: %v", err)
+	}
+	return nil
+}
+
+// reset starts tracking a browser with the targets infos, where current is
+// the tab the tools drive.
+func (t *stateTracker) reset(current target.ID, infos []*target.Info, changed func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started = true
+	t.changed = changed
+	t.current = current
+	t.tabs = make(map[target.ID]*TabState)
+	t.tabOrder = nil
+	t.downloads = make(map[string]*DownloadState)
+	t.downloadOrder = nil
+	t.dialog = nil
+	for _, info := range infos {
+		t.updateTab(info)
+	}
+}
+
+// updateTab records info if it is a tab. t.mu must be held.
+func (t *stateTracker) updateTab(info *target.Info) bool {
+	if info.Type != "page" {
+		return false
+	}
+	tab, ok := t.tabs[info.TargetID]
+	if !ok {
+		tab = &TabState{ID: string(info.TargetID)}
+		t.tabs[info.TargetID] = tab
+		t.tabOrder = append(t.tabOrder, info.TargetID)
+	} else if tab.URL == info.URL && tab.Title == info.Title {
+		return false
+	}
+	tab.URL, tab.Title = info.URL, info.Title
+	return true
+}
+
+// event updates the state from a browser or tab event. It runs on
+// chromedp's event loops, so it must not block.
+func (t *stateTracker) event(ev any) {
+	t.mu.Lock()
+	changed := false
+	switch ev := ev.(type) {
+	case *target.EventTargetCreated:
+		changed = t.updateTab(ev.TargetInfo)
+	case *target.EventTargetInfoChanged:
+		changed = t.updateTab(ev.TargetInfo)
+	case *target.EventTargetDestroyed:
+		if _, ok := t.tabs[ev.TargetID]; ok {
+			delete(t.tabs, ev.TargetID)
+			t.tabOrder = slices.DeleteFunc(t.tabOrder, func(id target.ID) bool { return id == ev.TargetID })
+			changed = true
+		}
+	case *page.EventJavascriptDialogOpening:
+		t.dialog = &DialogState{Type: string(ev.Type), Message: ev.Message, URL: ev.URL, DefaultPrompt: ev.DefaultPrompt, Opened: time.Now()}
+		changed = true
+	case *page.EventJavascriptDialogClosed:
+		changed = t.dialog != nil
+		t.dialog = nil
+	case *browser.EventDownloadWillBegin:
+		t.downloads[ev.GUID] = &DownloadState{URL: ev.URL, Filename: ev.SuggestedFilename, State: string(browser.DownloadProgressStateInProgress)}
+		t.downloadOrder = append(t.downloadOrder, ev.GUID)
+		if over := len(t.downloadOrder) - maxTrackedDownloads; over > 0 {
+			for _, guid := range t.downloadOrder[:over] {
+				delete(t.downloads, guid)
+			}
+			t.downloadOrder = slices.Delete(t.downloadOrder, 0, over)
+		}
+		changed = true
+	case *browser.EventDownloadProgress:
+		if d, ok := t.downloads[ev.GUID]; ok {
+			d.ReceivedBytes, d.TotalBytes = ev.ReceivedBytes, ev.TotalBytes
+			// Progress alone is not worth a notification.
+			changed = d.State != string(ev.State)
+			d.State = string(ev.State)
+			if ev.FilePath != "" {
+				d.Path = ev.FilePath
+			}
+		}
+	}
+	notify := t.changed
+	t.mu.Unlock()
+	if changed && notify != nil {
+		notify()
+	}
+}
+
+// snapshot returns the tracked state, or nil if the tracker has not
+// started.
+func (t *stateTracker) snapshot() *BrowserState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.started {
+		return nil
+	}
+	state := &BrowserState{Tabs: []TabState{}, Downloads: []DownloadState{}}
+	for _, id := range t.tabOrder {
+		tab := *t.tabs[id]
+		if id == t.current {
+			tab.Current = true
+			state.URL, state.Title = tab.URL, tab.Title
+		}
+		state.Tabs = append(state.Tabs, tab)
+	}
+	for _, guid := range t.downloadOrder {
+		d := *t.downloads[guid]
+		if d.State == string(browser.DownloadProgressStateInProgress) {
+			state.PendingDownloads++
+		}
+		state.Downloads = append(state.Downloads, d)
+	}
+	if t.dialog != nil {
+		dialog := *t.dialog
+		state.Dialog = &dialog
+	}
+	return state
+}
+
+// browserState returns the current state. Backends other than Chrome only
+// report the current page.
+func (s *CDPBrowserServer) browserState(ctx context.Context) (*BrowserState, error) {
+	if state := s.state.snapshot(); state != nil {
+		return state, nil
+	}
+	var current struct {
+		URL   string `json:"url"`
+		Title string `json:"title"`
+	}
+	if err := s.backend().Evaluate(ctx, `({url: location.href, title: document.title})`, &current); err != nil {
+		return nil, err
+	}
+	return &BrowserState{
+		URL:       current.URL,
+		Title:     current.Title,
+		Tabs:      []TabState{{URL: current.URL, Title: current.Title, Current: true}},
+		Downloads: []DownloadState{},
+	}, nil
+}
+
+// stateChanged notifies the clients subscribed to browser://state.
+func (s *CDPBrowserServer) stateChanged() {
+	if s.mcpServer == nil {
+		return
+	}
+	// Notifications are sent to the clients' transports, which must not
+	// hold up chromedp's event loop.
+	go s.mcpServer.ResourceUpdated(context.Background(), &mcp.ResourceUpdatedNotificationParams{URI: stateURI})
+}
+
+func (s *CDPBrowserServer) readState(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+	state, err := s.browserState(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading the browser state: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: stateURI, MIMEType: "application/json", Text: string(data)},
+		},
+	}, nil
+}
+
+// subscribeResource accepts subscriptions to browser://state, the only
+// resource whose updates the server announces.
+func (s *CDPBrowserServer) subscribeResource(ctx context.Context, req *mcp.ServerRequest[*mcp.SubscribeParams]) error {
+	if req.Params.URI != stateURI {
+		return fmt.Errorf("resource %s does not support subscriptions; only %s does", req.Params.URI, stateURI)
+	}
+	return nil
+}
+
+func (s *CDPBrowserServer) unsubscribeResource(ctx context.Context, req *mcp.ServerRequest[*mcp.UnsubscribeParams]) error {
+	return nil
+}
@@ -0,0 +1,98 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/target"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestStateTracker(t *testing.T) {
+	var tr stateTracker
+	if tr.snapshot() != nil {
+		t.Fatal("state reported before the tracker started")
+	}
+	notified := 0
+	tr.reset("A", []*target.Info{
+		{TargetID: "A", Type: "page", URL: "https://shop.example/", Title: "Shop"},
+		{TargetID: "W", Type: "service_worker", URL: "https://shop.example/sw.js"},
+	}, func() { notified++ })
+
+	tr.event(&target.EventTargetCreated{TargetInfo: &target.Info{TargetID: "B", Type: "page", URL: "about:blank"}})
+	tr.event(&target.EventTargetInfoChanged{TargetInfo: &target.Info{TargetID: "B", Type: "page", URL: "https://shop.example/cart", Title: "Cart"}})
+	tr.event(&target.EventTargetInfoChanged{TargetInfo: &target.Info{TargetID: "B", Type: "page", URL: "https://shop.example/cart", Title: "Cart"}})
+	tr.event(&page.EventJavascriptDialogOpening{Type: page.DialogTypeConfirm, Message: "Empty the cart?", URL: "https://shop.example/"})
+	tr.event(&browser.EventDownloadWillBegin{GUID: "d1", URL: "https://shop.example/invoice.pdf", SuggestedFilename: "invoice.pdf"})
+	tr.event(&browser.EventDownloadProgress{GUID: "d1", ReceivedBytes: 10, TotalBytes: 100, State: browser.DownloadProgressStateInProgress})
+	if notified != 4 {
+		t.Errorf("notified %d times, want 4 (new tab, tab change, dialog, download)", notified)
+	}
+
+	state := tr.snapshot()
+	if state.URL != "https://shop.example/" || state.Title != "Shop" || len(state.Tabs) != 2 || !state.Tabs[0].Current || state.Tabs[1].Title != "Cart" {
+		t.Errorf("tabs = %+v", state)
+	}
+	if state.Dialog == nil || state.Dialog.Type != "confirm" || state.Dialog.Message != "Empty the cart?" {
+		t.Errorf("dialog = %+v", state.Dialog)
+	}
+	if state.PendingDownloads != 1 || state.Downloads[0].ReceivedBytes != 10 || state.Downloads[0].Filename != "invoice.pdf" {
+		t.Errorf("downloads = %+v", state.Downloads)
+	}
+
+	tr.event(&page.EventJavascriptDialogClosed{Result: true})
+	tr.event(&browser.EventDownloadProgress{GUID: "d1", ReceivedBytes: 100, TotalBytes: 100, State: browser.DownloadProgressStateCompleted, FilePath: "/tmp/invoice.pdf"})
+	tr.event(&target.EventTargetDestroyed{TargetID: "B"})
+	state = tr.snapshot()
+	if state.Dialog != nil || state.PendingDownloads != 0 || state.Downloads[0].Path != "/tmp/invoice.pdf" || len(state.Tabs) != 1 {
+		t.Errorf("state after changes = %+v", state)
+	}
+	if notified != 7 {
+		t.Errorf("notified %d times, want 7", notified)
+	}
+
+	for i := range maxTrackedDownloads + 5 {
+		tr.event(&browser.EventDownloadWillBegin{GUID: fmt.Sprint("g", i), URL: fmt.Sprint("https://shop.example/", i)})
+	}
+	if state := tr.snapshot(); len(state.Downloads) != maxTrackedDownloads || state.Downloads[0].URL != "https://shop.example/5" {
+		t.Errorf("kept %d downloads, oldest %s", len(state.Downloads), state.Downloads[0].URL)
+	}
+}
+
+func TestStateResource(t *testing.T) {
+	// Backends without CDP report the current page only.
+	s := &CDPBrowserServer{browser: &fakeBrowser{}}
+	read := func() BrowserState {
+		t.Helper()
+		res, err := s.readState(context.Background(), &mcp.ServerRequest[*mcp.ReadResourceParams]{Params: &mcp.ReadResourceParams{URI: stateURI}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var state BrowserState
+		if err := json.Unmarshal([]byte(res.Contents[0].Text), &state); err != nil {
+			t.Fatal(err)
+		}
+		return state
+	}
+	if state := read(); state.URL != "https://example.com/" || len(state.Tabs) != 1 || !state.Tabs[0].Current || state.Downloads == nil {
+		t.Errorf("state from the backend = %+v", state)
+	}
+
+	s.state.reset("A", []*target.Info{{TargetID: "A", Type: "page", URL: "https://shop.example/", Title: "Shop"}}, nil)
+	if state := read(); state.Title != "Shop" || state.Dialog != nil {
+		t.Errorf("tracked state = %+v", state)
+	}
+
+	if err := s.subscribeResource(context.Background(), &mcp.ServerRequest[*mcp.SubscribeParams]{Params: &mcp.SubscribeParams{URI: stateURI}}); err != nil {
+		t.Errorf("subscribing to %s: %v", stateURI, err)
+	}
+	err := s.subscribeResource(context.Background(), &mcp.ServerRequest[*mcp.SubscribeParams]{Params: &mcp.SubscribeParams{URI: statsURI}})
+	if err == nil || !strings.Contains(err.Error(), "does not support subscriptions") {
+		t.Errorf("subscribing to %s = %v", statsURI, err)
+	}
+}
@@ -0,0 +1,167 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// statsURI is the resource that exposes per-tool usage statistics.
+const statsURI = "browser://stats"
+
+// toolStats accumulates per-tool invocation counts, errors, and latency for
+// the lifetime of the server.
+type toolStats struct {
+	mu      sync.Mutex
+	started time.Time
+	byTool  map[string]*toolCounter
+}
+
+type toolCounter struct {
+	calls     int
+	errors    int
+	total     time.Duration
+	lastError string
+}
+
+func newToolStats() *toolStats {
+	return &toolStats{started: time.Now(), byTool: make(map[string]*toolCounter)}
+}
+
+// record adds one call of tool that took d. errMsg is empty for successful
+// calls.
+func (t *toolStats) record(tool string, d time.Duration, errMsg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.byTool[tool]
+	if c == nil {
+		c = &toolCounter{}
+		t.byTool[tool] = c
+	}
+	c.calls++
+	c.total += d
+	if errMsg != "" {
+		c.errors++
+		c.lastError = errMsg
+	}
+}
+
+// ToolStat is the usage summary of a single tool.
+type ToolStat struct {
+	Tool         string  `json:"tool"`
+	Calls        int     `json:"calls"`
+	Errors       int     `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	AvgLatencyMS float64 `json:"avg_latency_ms"`
+	LastError    string  `json:"last_error,omitempty"`
+}
+
+// ToolStatsReport is the structured result of get_tool_stats and the
+// contents of the browser://stats resource.
+type ToolStatsReport struct {
+	Since      time.Time  `json:"since"`
+	TotalCalls int        `json:"total_calls"`
+	Tools      []ToolStat `json:"tools"`
+}
+
+// report returns the current statistics, with the most error-prone tools
+// first.
+func (t *toolStats) report() ToolStatsReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := ToolStatsReport{Since: t.started, Tools: []ToolStat{}}
+	for name, c := range t.byTool {
+		r.TotalCalls += c.calls
+		r.Tools = append(r.Tools, ToolStat{
+			Tool:         name,
+			Calls:        c.calls,
+			Errors:       c.errors,
+			ErrorRate:    float64(c.errors) / float64(c.calls),
+			AvgLatencyMS: float64(c.total.Microseconds()) / float64(c.calls) / 1000,
+			LastError:    c.lastError,
+		})
+	}
+	slices.SortFunc(r.Tools, func(a, b ToolStat) int {
+		if a.ErrorRate != b.ErrorRate {
+			if a.ErrorRate > b.ErrorRate {
+				return -1
+			}
+			return 1
+		}
+		if a.Calls != b.Calls {
+			return b.Calls - a.Calls
+		}
+		return strings.Compare(a.Tool, b.Tool)
+	})
+	return r
+}
+
+// statsMiddleware records every tools/call request in s.stats. Both protocol
+// errors and tool results with IsError set count as failures.
+func (s *CDPBrowserServer) statsMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		params, ok := req.GetParams().(*mcp.CallToolParamsFor[json.RawMessage])
+		if !ok {
+			return next(ctx, method, req)
+		}
+
+		start := time.Now()
+		result, err := next(ctx, method, req)
+		var errMsg string
+		if err != nil {
+			errMsg = err.Error()
+		} else if res, ok := result.(*mcp.CallToolResult); ok && res.IsError {
+			errMsg = "tool returned an error result"
+			for _, c := range res.Content {
+				if tc, ok := c.(*mcp.TextContent); ok {
+					errMsg = tc.Text
+					break
+				}
+			}
+		}
+		s.stats.record(params.Name, time.Since(start), errMsg)
+		return result, err
+	}
+}
+
+// GetToolStats reports per-tool invocation counts, error rates, and average
+// latency since the server started.
+func (s *CDPBrowserServer) GetToolStats(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[ToolStatsReport], error) {
+	report := s.stats.report()
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("TOOL STATS since %s (%d calls)\n", report.Since.Format(time.RFC3339), report.TotalCalls))
+	for _, st := range report.Tools {
+		output.WriteString(fmt.Sprintf("- %s: %d calls, %d errors (%.0f%%), avg %.1fms\n",
+			st.Tool, st.Calls, st.Errors, st.ErrorRate*100, st.AvgLatencyMS))
+		if st.LastError != "" {
+			output.WriteString(fmt.Sprintf("  last error: %s\n", st.LastError))
+		}
+	}
+
+	return &mcp.CallToolResultFor[ToolStatsReport]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: output.String()},
+		},
+		StructuredContent: report,
+	}, nil
+}
+
+// readStats serves the browser://stats resource as JSON.
+func (s *CDPBrowserServer) readStats(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(s.stats.report(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: statsURI, MIMEType: "application/json", Text: string(data)},
+		},
+	}, nil
+}
@@ -0,0 +1,82 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestToolStatsReport(t *testing.T) {
+	stats := newToolStats()
+	stats.record("navigate", 10*time.Millisecond, "")
+	stats.record("navigate", 30*time.Millisecond, "")
+	stats.record("click", 5*time.Millisecond, "")
+	stats.record("click", 5*time.Millisecond, "element not found")
+
+	r := stats.report()
+	if r.TotalCalls != 4 {
+		t.Errorf("TotalCalls = %d, want 4", r.TotalCalls)
+	}
+	if len(r.Tools) != 2 {
+		t.Fatalf("got %d tools, want 2", len(r.Tools))
+	}
+	// The tool that fails most is listed first.
+	click, navigate := r.Tools[0], r.Tools[1]
+	if click.Tool != "click" || click.ErrorRate != 0.5 || click.LastError != "element not found" {
+		t.Errorf("click stat = %+v", click)
+	}
+	if navigate.Tool != "navigate" || navigate.Errors != 0 || navigate.AvgLatencyMS != 20 {
+		t.Errorf("navigate stat = %+v", navigate)
+	}
+}
+
+func TestStatsMiddleware(t *testing.T) {
+	s := &CDPBrowserServer{stats: newToolStats()}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "fail"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[any], error) {
+		return &mcp.CallToolResultFor[any]{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: "boom"}}}, nil
+	})
+	mcp.AddTool(server, &mcp.Tool{Name: "get_tool_stats"}, s.GetToolStats)
+	server.AddResource(&mcp.Resource{URI: statsURI, Name: "stats"}, s.readStats)
+	server.AddReceivingMiddleware(s.statsMiddleware)
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	for range 2 {
+		if _, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "fail"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "get_tool_stats"}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := cs.ReadResource(ctx, &mcp.ReadResourceParams{URI: statsURI})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report ToolStatsReport
+	if err := json.Unmarshal([]byte(res.Contents[0].Text), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalCalls != 3 {
+		t.Errorf("TotalCalls = %d, want 3", report.TotalCalls)
+	}
+	if got := report.Tools[0]; got.Tool != "fail" || got.Calls != 2 || got.Errors != 2 || got.LastError != "boom" {
+		t.Errorf("fail stat = %+v", got)
+	}
+}
@@ -0,0 +1,60 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// StepResult is the outcome of one tool call run server-side as part of a
+// replay, macro, or batch.
+type StepResult struct {
+	Step       int       `json:"step"`
+	Tool       string    `json:"tool"`
+	OK         bool      `json:"ok"`
+	Message    string    `json:"message,omitempty"`
+	Code       ErrorCode `json:"code,omitempty" jsonschema:"Why the step failed, as in the structured errors of failed tool calls"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// invokeStep calls the named tool with args through s.tools and reports the
+// outcome as step number n. The message is the first text content of the
+// tool result, or the error, and a failed step has an error code.
+func (s *CDPBrowserServer) invokeStep(ctx context.Context, n int, tool string, args json.RawMessage) (sr StepResult) {
+	sr = StepResult{Step: n, Tool: tool}
+	start := time.Now()
+	defer func() { sr.DurationMS = time.Since(start).Milliseconds() }()
+
+	invoke, ok := s.tools[tool]
+	if !ok || !s.toolset.exposes(tool) {
+		sr.Message = fmt.Sprintf("unknown tool %s", tool)
+		sr.Code = CodeInvalidArgument
+		return sr
+	}
+	if err := s.rateLimiter.step(ctx, tool); err != nil {
+		sr.Message = err.Error()
+		sr.Code = CodeRateLimited
+		return sr
+	}
+	f := new(toolFailure)
+	res, err := invoke(context.WithValue(ctx, toolFailureKey{}, f), args)
+	if err != nil {
+		sr.Message = err.Error()
+		sr.Code = s.classify(err, "")
+		return sr
+	}
+	sr.OK = !res.IsError
+	if !sr.OK {
+		sr.Code = s.classify(f.err, f.code)
+	}
+	for _, c := range res.Content {
+		if tc, ok := c.(*mcp.TextContent); ok {
+			sr.Message = tc.Text
+			break
+		}
+	}
+	return sr
+}
@@ -0,0 +1,79 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	// defaultWorkerTabs is the default number of tabs of tools that load
+	// pages in parallel.
+	defaultWorkerTabs = 4
+	// maxWorkerTabs bounds the number of tabs of tools that load pages in
+	// parallel.
+	maxWorkerTabs = 16
+)
+
+// workerTabs returns the number of tabs to open for a workers argument.
+func workerTabs(workers int) int {
+	if workers <= 0 {
+		return defaultWorkerTabs
+	}
+	return min(workers, maxWorkerTabs)
+}
+
+// openWorkerTab opens a tab for a tool that loads pages in parallel, such as
+// crawl, with the navigation policy applied. Calling the returned function
+// closes the tab.
+func (s *CDPBrowserServer) openWorkerTab() (context.Context, context.CancelFunc, error) {
+	tabCtx, cancel := chromedp.NewContext(s.ctx)
+	// The first Run creates the tab; it must use tabCtx itself so that the
+	// tab outlives the timeouts of runInTab.
+	if err := chromedp.Run(tabCtx); err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("failed to open a tab: %v", err)
+	}
+	if err := s.enablePolicyInterception(tabCtx); err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return tabCtx, cancel, nil
+}
+
+// openWorkerTabs opens up to n worker tabs. It fails only if no tab can be
+// opened. Calling the returned function closes the tabs.
+func (s *CDPBrowserServer) openWorkerTabs(n int) ([]context.Context, func(), error) {
+	var tabs []context.Context
+	var closers []context.CancelFunc
+	closeTabs := func() {
+		for _, closeTab := range closers {
+			closeTab()
+		}
+	}
+	for range n {
+		tabCtx, closeTab, err := s.openWorkerTab()
+		if err != nil {
+			if len(tabs) == 0 {
+				return nil, nil, err
+			}
+			log.Printf("Continuing with %d of %d tabs: %v", len(tabs), n, err)
+			break
+		}
+		tabs = append(tabs, tabCtx)
+		closers = append(closers, closeTab)
+	}
+	return tabs, closeTabs, nil
+}
+
+// runInTab runs actions in the tab of tabCtx, stopping them after timeout or
+// when ctx, the context of the tool call, is done.
+func runInTab(ctx, tabCtx context.Context, timeout time.Duration, actions ...chromedp.Action) error {
+	runCtx, stop := context.WithTimeout(tabCtx, timeout)
+	defer stop()
+	defer context.AfterFunc(ctx, stop)()
+	return chromedp.Run(runCtx, actions...)
+}
@@ -0,0 +1,11 @@
+function render() {
+	document.querySelector('.title').textContent = 'Rendered';
+}
+
+function neverCalled() {
+	const items = [];
+	for (let i = 0; i < 100; i++) {
+		items.push({id: i, name: 'Item ' + i, price: i * 1.5});
+	}
+	return items.filter(item => item.price > 10).map(item => item.name).join(', ');
+}
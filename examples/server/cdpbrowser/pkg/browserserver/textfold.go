@@ -0,0 +1,151 @@
+package browserserver
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// foldedCaseRanges are the scripts whose capitals text matching folds to
+// lowercase. XPath 1.0 has no lower-case(), so the folding is spelled out
+// for translate(), and the same table folds the selector, which keeps both
+// sides consistent.
+var foldedCaseRanges = [][2]rune{
+	{'A', 'Z'},
+	{0x00C0, 0x00DE}, // Latin-1
+	{0x0100, 0x017F}, // Latin Extended-A
+	{0x0391, 0x03A9}, // Greek
+	{0x0400, 0x042F}, // Cyrillic
+}
+
+// diacriticFolds maps the base letter of each group of accented lowercase
+// letters the transliterated text matching ignores the accents of.
+var diacriticFolds = map[rune]string{
+	'a': "àáâãäåāăą",
+	'c': "çćĉċč",
+	'd': "ďđ",
+	'e': "èéêëēĕėęě",
+	'g': "ĝğġģ",
+	'h': "ĥħ",
+	'i': "ìíîïĩīĭįı",
+	'j': "ĵ",
+	'k': "ķ",
+	'l': "ĺļľŀł",
+	'n': "ñńņň",
+	'o': "òóôõöøōŏő",
+	'r': "ŕŗř",
+	's': "śŝşš",
+	't': "ţťŧ",
+	'u': "ùúûüũūŭůűų",
+	'w': "ŵ",
+	'y': "ýÿŷ",
+	'z': "źżž",
+}
+
+// textFold maps the runes that text matching replaces. It folds the same way
+// in Go and, through translate(), in XPath.
+type textFold map[rune]rune
+
+var (
+	// caseFold folds capitals to lowercase.
+	caseFold = newTextFold(false)
+	// diacriticFold also strips accents from Latin letters.
+	diacriticFold = newTextFold(true)
+)
+
+// newTextFold builds the case fold table, also stripping the accents in
+// diacriticFolds if diacritics is set.
+func newTextFold(diacritics bool) textFold {
+	f := make(textFold)
+	for _, r := range foldedCaseRanges {
+		for c := r[0]; c <= r[1]; c++ {
+			if lower := unicode.ToLower(c); lower != c {
+				f[c] = lower
+			}
+		}
+	}
+	if !diacritics {
+		return f
+	}
+	base := make(map[rune]rune)
+	for b, accented := range diacriticFolds {
+		for _, c := range accented {
+			base[c] = b
+		}
+	}
+	for c, lower := range f {
+		if b, ok := base[lower]; ok {
+			f[c] = b
+		}
+	}
+	for c, b := range base {
+		f[c] = b
+	}
+	return f
+}
+
+// fold returns s with whitespace runs collapsed to single spaces, trimmed,
+// and its runes folded, like the XPath of xpath.
+func (f textFold) fold(s string) string {
+	return strings.Map(func(r rune) rune {
+		if to, ok := f[r]; ok {
+			return to
+		}
+		return r
+	}, strings.Join(strings.Fields(s), " "))
+}
+
+// xpath returns an XPath expression folding the string value of expr.
+func (f textFold) xpath(expr string) string {
+	from := make([]rune, 0, len(f))
+	for c := range f {
+		from = append(from, c)
+	}
+	slices.Sort(from)
+	to := make([]rune, len(from))
+	for i, c := range from {
+		to[i] = f[c]
+	}
+	return fmt.Sprintf("translate(normalize-space(%s), %s, %s)", expr, xpathString(string(from)), xpathString(string(to)))
+}
+
+// foldedTextStrategy matches buttons, links, input buttons, and elements
+// with an aria-label whose text equals the selector once both are folded by
+// f, so that "LOG  IN" matches "Log in".
+func foldedTextStrategy(f textFold) SelectorStrategyFunc {
+	return func(selector string) (SelectorLocator, bool) {
+		folded := f.fold(selector)
+		if folded == "" {
+			return SelectorLocator{}, false
+		}
+		q := xpathString(folded)
+		text, value, label := f.xpath("."), f.xpath("@value"), f.xpath("@aria-label")
+		return SelectorLocator{
+			Query: fmt.Sprintf(`//button[%s=%s] | //a[%s=%s] | //input[%s=%s] | //*[@aria-label][%s=%s]`, text, q, text, q, value, q, label, q),
+			XPath: true,
+		}, true
+	}
+}
+
+// translations returns the labels the selector config lists as
+// translations of selector, matched ignoring case, accents, and spacing.
+// config may be nil.
+func (c *selectorConfig) translations(selector string) []string {
+	if c == nil {
+		return nil
+	}
+	folded := diacriticFold.fold(selector)
+	var labels []string
+	for _, group := range c.Translations {
+		if !slices.ContainsFunc(group, func(label string) bool { return diacriticFold.fold(label) == folded }) {
+			continue
+		}
+		for _, label := range group {
+			if diacriticFold.fold(label) != folded && !slices.Contains(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	return labels
+}
@@ -0,0 +1,84 @@
+package browserserver
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTextFold(t *testing.T) {
+	tests := []struct {
+		fold textFold
+		in   string
+		want string
+	}{
+		{caseFold, "  LOG \n\t IN ", "log in"},
+		{caseFold, "ÜBERSICHT", "übersicht"},
+		{caseFold, "ВОЙТИ", "войти"},
+		{caseFold, "Straße", "straße"},
+		{diacriticFold, "Übersicht", "ubersicht"},
+		{diacriticFold, "Iniciar sesión", "iniciar sesion"},
+		{diacriticFold, "ZAŁÓŻ KONTO", "zaloz konto"},
+		{diacriticFold, "Войти", "войти"},
+	}
+	for _, tt := range tests {
+		if got := tt.fold.fold(tt.in); got != tt.want {
+			t.Errorf("fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTextFoldXPath(t *testing.T) {
+	for _, f := range []textFold{caseFold, diacriticFold} {
+		m := regexp.MustCompile(`^translate\(normalize-space\(@value\), "([^"]*)", "([^"]*)"\)$`).FindStringSubmatch(f.xpath("@value"))
+		if m == nil {
+			t.Fatalf("xpath = %s", f.xpath("@value"))
+		}
+		// translate() maps each rune of the second argument to the rune at
+		// the same position of the third.
+		from, to := []rune(m[1]), []rune(m[2])
+		if len(from) != len(to) || len(from) != len(f) {
+			t.Fatalf("translate() has %d runes from and %d to, for %d folds", len(from), len(to), len(f))
+		}
+		for i, c := range from {
+			if f[c] != to[i] {
+				t.Errorf("translate() maps %q to %q, want %q", c, to[i], f[c])
+			}
+		}
+		if !utf8.ValidString(m[1]) || strings.ContainsAny(m[1], `"'`) {
+			t.Errorf("translate() argument %q needs quoting", m[1])
+		}
+	}
+}
+
+func TestFoldedTextStrategy(t *testing.T) {
+	loc, ok := foldedTextStrategy(caseFold)("  Log IN ")
+	if !ok || !loc.XPath || !strings.Contains(loc.Query, `//button[translate(normalize-space(.), `) || !strings.Contains(loc.Query, `="log in"] | //a[`) {
+		t.Errorf("Locate = %+v, %v", loc, ok)
+	}
+	if _, ok := foldedTextStrategy(caseFold)(" \n"); ok {
+		t.Error("Locate of a blank selector applies")
+	}
+}
+
+func TestSelectorTranslations(t *testing.T) {
+	c := &selectorConfig{Translations: [][]string{
+		{"Log in", "Anmelden", "Iniciar sesión"},
+		{"Sign in", "Anmelden"},
+		{"Search", "Suche"},
+	}}
+	if got, want := c.translations("ANMELDEN"), []string{"Log in", "Iniciar sesión", "Sign in"}; !slices.Equal(got, want) {
+		t.Errorf("translations(ANMELDEN) = %q, want %q", got, want)
+	}
+	if got, want := c.translations("iniciar  sesion"), []string{"Log in", "Anmelden"}; !slices.Equal(got, want) {
+		t.Errorf("translations(iniciar sesion) = %q, want %q", got, want)
+	}
+	if got := c.translations("Log out"); got != nil {
+		t.Errorf("translations(Log out) = %q", got)
+	}
+	if got := (*selectorConfig)(nil).translations("Log in"); got != nil {
+		t.Errorf("translations without a config = %q", got)
+	}
+}
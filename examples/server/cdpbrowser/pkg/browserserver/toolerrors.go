@@ -0,0 +1,246 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ErrorCode classifies why a tool call failed.
+type ErrorCode string
+
+// Error codes of tool failures.
+const (
+	// CodeElementNotFound means no element matches the selector or element
+	// id, or the element has left the page.
+	CodeElementNotFound ErrorCode = "ELEMENT_NOT_FOUND"
+	// CodeElementNotActionable means the element exists but stayed hidden,
+	// disabled, moving, covered, or read-only.
+	CodeElementNotActionable ErrorCode = "ELEMENT_NOT_ACTIONABLE"
+	// CodeTimeout means the browser did not complete the operation in time.
+	CodeTimeout ErrorCode = "TIMEOUT"
+	// CodeNavigationFailed means a page failed to load.
+	CodeNavigationFailed ErrorCode = "NAVIGATION_FAILED"
+	// CodeNavigationBlocked means the navigation policy forbids the URL.
+	CodeNavigationBlocked ErrorCode = "NAVIGATION_BLOCKED"
+	// CodeConfirmationRequired means the confirmation policy blocked a
+	// destructive click.
+	CodeConfirmationRequired ErrorCode = "CONFIRMATION_REQUIRED"
+	// CodeAssertionFailed means an assert_* tool found the page not as
+	// expected.
+	CodeAssertionFailed ErrorCode = "ASSERTION_FAILED"
+	// CodeInvalidArgument means the arguments of the call are invalid.
+	CodeInvalidArgument ErrorCode = "INVALID_ARGUMENT"
+	// CodeToolUnavailable means the browser does not support the tool.
+	CodeToolUnavailable ErrorCode = "TOOL_UNAVAILABLE"
+	// CodeRateLimited means the call exceeds the session's rate limits.
+	CodeRateLimited ErrorCode = "RATE_LIMITED"
+	// CodePageError means the action succeeded but the page threw an
+	// uncaught JavaScript exception meanwhile.
+	CodePageError ErrorCode = "PAGE_ERROR"
+	// CodeCDPDisconnected means the connection to the browser is lost.
+	CodeCDPDisconnected ErrorCode = "CDP_DISCONNECTED"
+	// CodeToolFailed is any other failure.
+	CodeToolFailed ErrorCode = "TOOL_FAILED"
+)
+
+// errorSuggestions tell the caller what to do about each kind of failure.
+var errorSuggestions = map[ErrorCode]string{
+	CodeElementNotFound:      "Take an aria_snapshot or use find_element to see what is on the page, then retry with a selector or element id from it",
+	CodeElementNotActionable: "Wait for the page to settle, close whatever covers the element, or pick an enabled element, then retry",
+	CodeTimeout:              "Retry; if it keeps timing out, take a screenshot to see the state of the page",
+	CodeNavigationFailed:     "Check the URL and that the site is reachable, then retry",
+	CodeNavigationBlocked:    "The server's navigation policy forbids this URL; do not retry it",
+	CodeConfirmationRequired: "Ask the user, then call again with confirm: true",
+	CodeAssertionFailed:      "The page is not in the expected state; see the diagnostics",
+	CodeInvalidArgument:      "Fix the arguments and call again",
+	CodeToolUnavailable:      "Use another tool; this one is not supported by the connected browser",
+	CodeRateLimited:          "Wait retry_after_seconds before calling again, and make fewer calls",
+	CodePageError:            "The page is broken by the action; call get_page_errors for the stack traces instead of retrying",
+	CodeCDPDisconnected:      "The browser connection is lost; restart the server",
+}
+
+// retryableCodes are the failures a retry may overcome without changing the
+// call.
+var retryableCodes = map[ErrorCode]bool{
+	CodeElementNotFound:      true,
+	CodeElementNotActionable: true,
+	CodeTimeout:              true,
+	CodeNavigationFailed:     true,
+	CodeRateLimited:          true,
+}
+
+// ToolError is the structured content of a failed tool call.
+type ToolError struct {
+	Code              ErrorCode `json:"code" jsonschema:"Why the call failed: ELEMENT_NOT_FOUND, ELEMENT_NOT_ACTIONABLE, TIMEOUT, NAVIGATION_FAILED, NAVIGATION_BLOCKED, CONFIRMATION_REQUIRED, ASSERTION_FAILED, INVALID_ARGUMENT, TOOL_UNAVAILABLE, RATE_LIMITED, PAGE_ERROR, CDP_DISCONNECTED, or TOOL_FAILED"`
+	Message           string    `json:"message"`
+	Selector          string    `json:"selector,omitempty" jsonschema:"The selector argument of the call"`
+	Query             string    `json:"query,omitempty" jsonschema:"The last query tried for the selector, if it differs from it"`
+	Strategies        []string  `json:"strategies,omitempty" jsonschema:"Selector strategies attempted, in order"`
+	Suggestion        string    `json:"suggestion,omitempty"`
+	Retryable         bool      `json:"retryable" jsonschema:"Whether retrying the same call may succeed"`
+	RetryAfterSeconds int       `json:"retry_after_seconds,omitempty" jsonschema:"For RATE_LIMITED, how long to wait before the call would be allowed"`
+}
+
+// String returns the lines appended to the text of a failed call.
+func (e *ToolError) String() string {
+	s := "Error code: " + string(e.Code)
+	if e.Suggestion != "" {
+		s += "\nSuggestion: " + e.Suggestion
+	}
+	return s
+}
+
+// notFoundError wraps errors that mean the element to act on is not in the
+// page.
+type notFoundError struct{ error }
+
+func (e notFoundError) Unwrap() error { return e.error }
+
+// invalidArgumentError wraps errors in the arguments of a call.
+type invalidArgumentError struct{ error }
+
+func (e invalidArgumentError) Unwrap() error { return e.error }
+
+// toolFailure is what a tool notes about its failure for toolErrorMiddleware.
+type toolFailure struct {
+	err        error
+	code       ErrorCode
+	selector   string
+	query      string
+	strategies []string
+}
+
+// toolFailureKey is the context key for the failure of the current call.
+type toolFailureKey struct{}
+
+// noteToolError records err as the cause of the failure of the call in ctx.
+// code is used if err does not fall into a more specific class; pass "" to
+// use CodeToolFailed.
+func noteToolError(ctx context.Context, err error, code ErrorCode) {
+	if f, ok := ctx.Value(toolFailureKey{}).(*toolFailure); ok {
+		*f = toolFailure{err: err, code: code}
+	}
+}
+
+// noteSelectorError is noteToolError for a tool that acted on the element
+// selected by selector, which resolved to match.
+func noteSelectorError(ctx context.Context, err error, selector string, match SelectorMatch) {
+	if f, ok := ctx.Value(toolFailureKey{}).(*toolFailure); ok {
+		*f = toolFailure{err: err, selector: selector, strategies: append(slices.Clone(match.tried), match.Strategy)}
+		if match.Selector != selector {
+			f.query = match.Selector
+		}
+	}
+}
+
+// classify returns the error code for err, or fallback if err does not fall
+// into a more specific class.
+func (s *CDPBrowserServer) classify(err error, fallback ErrorCode) ErrorCode {
+	var (
+		policy     *policyViolationError
+		unconfirm  *unconfirmedActionError
+		actionable *actionabilityError
+		notFound   notFoundError
+		invalid    invalidArgumentError
+		limited    *rateLimitError
+	)
+	switch {
+	case err == nil:
+	case errors.As(err, &policy):
+		return CodeNavigationBlocked
+	case errors.As(err, &unconfirm):
+		return CodeConfirmationRequired
+	case errors.As(err, &notFound):
+		return CodeElementNotFound
+	case errors.As(err, &invalid):
+		return CodeInvalidArgument
+	case errors.As(err, &limited):
+		return CodeRateLimited
+	case errors.As(err, &actionable):
+		if actionable.reason == noElementReason {
+			return CodeElementNotFound
+		}
+		return CodeElementNotActionable
+	case (s.ctx != nil && s.ctx.Err() != nil) || errors.Is(err, chromedp.ErrChannelClosed) ||
+		errors.Is(err, chromedp.ErrInvalidContext) || errors.Is(err, chromedp.ErrInvalidTarget):
+		return CodeCDPDisconnected
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	}
+	if fallback == "" {
+		return CodeToolFailed
+	}
+	return fallback
+}
+
+// toolError returns the ToolError for failure f of a call whose result text
+// is text.
+func (s *CDPBrowserServer) toolError(f *toolFailure, text string) *ToolError {
+	e := &ToolError{
+		Code:       s.classify(f.err, f.code),
+		Message:    text,
+		Selector:   f.selector,
+		Query:      f.query,
+		Strategies: f.strategies,
+	}
+	if text == "" && f.err != nil {
+		e.Message = f.err.Error()
+	}
+	e.Suggestion = errorSuggestions[e.Code]
+	e.Retryable = retryableCodes[e.Code]
+	var limited *rateLimitError
+	if errors.As(f.err, &limited) {
+		e.RetryAfterSeconds = int(math.Ceil(limited.retryAfter.Seconds()))
+	}
+	return e
+}
+
+// toolErrorMiddleware describes every failed tool call with a ToolError,
+// classified from the failure the tool noted: it becomes the structured
+// content of the result, unless the tool returned its own, and its code and
+// suggestion are appended to the text.
+func (s *CDPBrowserServer) toolErrorMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if _, ok := req.GetParams().(*mcp.CallToolParamsFor[json.RawMessage]); !ok {
+			return next(ctx, method, req)
+		}
+		f := new(toolFailure)
+		result, err := next(context.WithValue(ctx, toolFailureKey{}, f), method, req)
+		if res, ok := result.(*mcp.CallToolResult); ok && err == nil && res.IsError {
+			s.describeFailure(res, f)
+		}
+		return result, err
+	}
+}
+
+// describeFailure adds the ToolError for f to the failed result res and
+// returns it.
+func (s *CDPBrowserServer) describeFailure(res *mcp.CallToolResult, f *toolFailure) *ToolError {
+	var text *mcp.TextContent
+	for _, c := range res.Content {
+		if t, ok := c.(*mcp.TextContent); ok {
+			text = t
+			break
+		}
+	}
+	var te *ToolError
+	if text == nil {
+		te = s.toolError(f, "")
+		res.Content = append(res.Content, &mcp.TextContent{Text: te.String()})
+	} else {
+		te = s.toolError(f, text.Text)
+		text.Text += "\n" + te.String()
+	}
+	// Typed tools return the zero value of their output type on failure.
+	if res.StructuredContent == nil || reflect.ValueOf(res.StructuredContent).IsZero() {
+		res.StructuredContent = te
+	}
+	return te
+}
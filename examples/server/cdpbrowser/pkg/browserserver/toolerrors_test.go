@@ -0,0 +1,146 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestClassifyError(t *testing.T) {
+	s := &CDPBrowserServer{}
+	tests := []struct {
+		err      error
+		fallback ErrorCode
+		want     ErrorCode
+	}{
+		{&actionabilityError{timeout: time.Second, reason: noElementReason}, "", CodeElementNotFound},
+		{&actionabilityError{timeout: time.Second, reason: "element is disabled"}, "", CodeElementNotActionable},
+		{fmt.Errorf("clicking: %w", notFoundError{errors.New("element no longer exists")}), "", CodeElementNotFound},
+		{&policyViolationError{URL: "https://evil.example", Reason: "denied"}, CodeNavigationFailed, CodeNavigationBlocked},
+		{&unconfirmedActionError{target: &clickTarget{}, reason: "it submits a form"}, "", CodeConfirmationRequired},
+		{invalidArgumentError{errors.New("bad pattern")}, "", CodeInvalidArgument},
+		{chromedp.ErrChannelClosed, "", CodeCDPDisconnected},
+		{fmt.Errorf("waiting: %w", context.DeadlineExceeded), CodeNavigationFailed, CodeTimeout},
+		{errors.New("net::ERR_NAME_NOT_RESOLVED"), CodeNavigationFailed, CodeNavigationFailed},
+		{errors.New("something else"), "", CodeToolFailed},
+		{nil, CodeAssertionFailed, CodeAssertionFailed},
+	}
+	for _, tt := range tests {
+		if got := s.classify(tt.err, tt.fallback); got != tt.want {
+			t.Errorf("classify(%v, %q) = %s, want %s", tt.err, tt.fallback, got, tt.want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ctx = ctx
+	if got := s.classify(context.Canceled, ""); got != CodeCDPDisconnected {
+		t.Errorf("classify after the browser context ended = %s, want %s", got, CodeCDPDisconnected)
+	}
+}
+
+func TestToolErrorMiddleware(t *testing.T) {
+	s := &CDPBrowserServer{}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	addTool(server, s, &mcp.Tool{Name: "click"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+		match := SelectorMatch{Selector: "#go", Strategy: "fallback", tried: []string{"css", "aria-label"}}
+		err := &actionabilityError{timeout: time.Second, reason: noElementReason}
+		noteSelectorError(ctx, err, req.Params.Arguments.Selector, match)
+		return &mcp.CallToolResultFor[SelectorMatch]{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error clicking element #go: " + err.Error()}},
+			IsError: true,
+		}, nil
+	})
+	addTool(server, s, &mcp.Tool{Name: "refresh_page"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[struct{}], error) {
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error refreshing page"}},
+			IsError: true,
+		}, nil
+	})
+	addTool(server, s, &mcp.Tool{Name: "assert_url_matches"}, s.AssertURLMatches)
+	server.AddReceivingMiddleware(s.toolErrorMiddleware)
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	call := func(name string, args map[string]any) (*mcp.CallToolResult, *ToolError) {
+		t.Helper()
+		res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("CallTool(%s) error = %v", name, err)
+		}
+		if !res.IsError {
+			t.Fatalf("CallTool(%s) succeeded", name)
+		}
+		var te ToolError
+		data, _ := json.Marshal(res.StructuredContent)
+		if err := json.Unmarshal(data, &te); err != nil {
+			t.Fatalf("unmarshal structured content: %v", err)
+		}
+		return res, &te
+	}
+
+	res, te := call("click", map[string]any{"selector": "Go"})
+	want := &ToolError{
+		Code:       CodeElementNotFound,
+		Message:    "Error clicking element #go: not actionable after 1s: no element matches",
+		Selector:   "Go",
+		Query:      "#go",
+		Strategies: []string{"css", "aria-label", "fallback"},
+		Suggestion: errorSuggestions[CodeElementNotFound],
+		Retryable:  true,
+	}
+	if te.Code != want.Code || te.Message != want.Message || te.Selector != want.Selector || te.Query != want.Query ||
+		!slices.Equal(te.Strategies, want.Strategies) || te.Suggestion != want.Suggestion || te.Retryable != want.Retryable {
+		t.Errorf("click error = %+v, want %+v", te, want)
+	}
+	if text := resultText(res); !strings.HasSuffix(text, "\nError code: ELEMENT_NOT_FOUND\nSuggestion: "+want.Suggestion) {
+		t.Errorf("click error text = %q", text)
+	}
+
+	// A failure the tool did not classify.
+	if _, te := call("refresh_page", nil); te.Code != CodeToolFailed || te.Message != "Error refreshing page" || te.Retryable {
+		t.Errorf("unclassified error = %+v", te)
+	}
+
+	if _, te := call("assert_url_matches", map[string]any{"pattern": "("}); te.Code != CodeInvalidArgument {
+		t.Errorf("assert_url_matches with an invalid pattern = %+v", te)
+	}
+}
+
+func TestInvokeStepErrorCode(t *testing.T) {
+	s := &CDPBrowserServer{}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	addTool(server, s, &mcp.Tool{Name: "navigate"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[NavigateArgs]]) (*mcp.CallToolResultFor[NavigateResult], error) {
+		noteToolError(ctx, errors.New("net::ERR_CONNECTION_REFUSED"), CodeNavigationFailed)
+		return &mcp.CallToolResultFor[NavigateResult]{IsError: true}, nil
+	})
+
+	if sr := s.invokeStep(context.Background(), 1, "navigate", json.RawMessage(`{"url": "http://localhost:1"}`)); sr.OK || sr.Code != CodeNavigationFailed {
+		t.Errorf("failed navigate step = %+v, want code %s", sr, CodeNavigationFailed)
+	}
+	if sr := s.invokeStep(context.Background(), 2, "navigate", json.RawMessage(`{"url": 1}`)); sr.Code != CodeInvalidArgument {
+		t.Errorf("step with invalid arguments = %+v, want code %s", sr, CodeInvalidArgument)
+	}
+	if sr := s.invokeStep(context.Background(), 3, "missing", nil); sr.Code != CodeInvalidArgument {
+		t.Errorf("step with an unknown tool = %+v, want code %s", sr, CodeInvalidArgument)
+	}
+}
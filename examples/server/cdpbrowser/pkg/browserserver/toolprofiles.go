@@ -0,0 +1,96 @@
+package browserserver
+
+import "fmt"
+
+// Tool profiles, selected with -tool-profile, decide which tools the server
+// registers, so that operators can hand a restricted set of tools to an
+// untrusted agent.
+const (
+	// toolProfileReadonly exposes the tools that observe pages: navigation,
+	// snapshots, screenshots, extraction, and assertions.
+	toolProfileReadonly = "readonly"
+	// toolProfileStandard adds the tools that interact with pages, but not
+	// the adminTools.
+	toolProfileStandard = "standard"
+	// toolProfileAdmin exposes every tool.
+	toolProfileAdmin = "admin"
+
+	// defaultToolProfile is the profile of servers not given one. Tools
+	// that control the server or touch the disk must be asked for.
+	defaultToolProfile = toolProfileStandard
+)
+
+// readonlyTools are the tools of the readonly profile.
+var readonlyTools = map[string]bool{
+	"navigate":                 true,
+	"refresh_page":             true,
+	"screenshot":               true,
+	"annotated_screenshot":     true,
+	"capture_long_page":        true,
+	"screenshot_element_by_id": true,
+	"aria_snapshot":            true,
+	"aria_diff":                true,
+	"save_page_state":          true,
+	"compare_page_state":       true,
+	"find_text":                true,
+	"find_element":             true,
+	"get_page_metadata":        true,
+	"detect_captcha":           true,
+	"crawl":                    true,
+	"bulk_screenshot":          true,
+	"compare_screenshots":      true,
+	"ocr_screenshot":           true,
+	"start_coverage":           true,
+	"stop_coverage":            true,
+	"get_page_errors":          true,
+	"list_websockets":          true,
+	"start_websocket_capture":  true,
+	"get_websocket_frames":     true,
+	"stop_websocket_capture":   true,
+	"assert_url_matches":       true,
+	"wait_for_route_change":    true,
+	"assert_text_present":      true,
+	"assert_element_visible":   true,
+	"assert_element_count":     true,
+	"browser_info":             true,
+	"get_environment":          true,
+	"get_tool_stats":           true,
+}
+
+// adminTools are the tools only the admin profile exposes: those that
+// control the server, the browser process, or the exposed tools, send raw
+// CDP commands, or read and write browser profiles and recordings on disk.
+var adminTools = map[string]bool{
+	"execute_cdp":          true,
+	"close_browser":        true,
+	"detach_browser":       true,
+	"set_chrome_lifecycle": true,
+	"shutdown_server":      true,
+	"save_profile":         true,
+	"load_profile":         true,
+	"list_profiles":        true,
+	"start_recording":      true,
+	"stop_recording":       true,
+	"replay_recording":     true,
+	"configure_tools":      true,
+}
+
+func checkToolProfile(profile string) error {
+	switch profile {
+	case toolProfileReadonly, toolProfileStandard, toolProfileAdmin:
+		return nil
+	}
+	return fmt.Errorf("unknown tool profile %q (want %s, %s, or %s)", profile, toolProfileReadonly, toolProfileStandard, toolProfileAdmin)
+}
+
+// toolInProfile reports whether the tool profile exposes tool. The empty
+// profile is admin.
+func toolInProfile(profile, tool string) bool {
+	switch profile {
+	case toolProfileReadonly:
+		return readonlyTools[tool]
+	case toolProfileStandard:
+		return !adminTools[tool]
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestToolProfiles(t *testing.T) {
+	listTools := func(profile string) (*CDPBrowserServer, []string) {
+		t.Helper()
+		s := &CDPBrowserServer{allowRawCDP: true, stats: newToolStats(), policy: &navigationPolicy{}}
+		s.toolset.config.Profile = profile
+		server := newMCPServer(s)
+		ctx := context.Background()
+		clientTransport, serverTransport := mcp.NewInMemoryTransports()
+		serverSession, err := server.Connect(ctx, serverTransport, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer serverSession.Close()
+		cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil).Connect(ctx, clientTransport, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cs.Close()
+		res, err := cs.ListTools(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, tool := range res.Tools {
+			names = append(names, tool.Name)
+		}
+		return s, names
+	}
+
+	_, all := listTools("")
+	_, admin := listTools(toolProfileAdmin)
+	if !slices.Equal(admin, all) || !slices.Contains(admin, "execute_cdp") || !slices.Contains(admin, "shutdown_server") {
+		t.Errorf("admin tools = %v, want all tools %v", admin, all)
+	}
+
+	_, standard := listTools(toolProfileStandard)
+	for _, name := range all {
+		if slices.Contains(standard, name) == adminTools[name] {
+			t.Errorf("standard profile exposes %s: %t", name, !adminTools[name])
+		}
+	}
+
+	s, readonly := listTools(toolProfileReadonly)
+	var want []string
+	for _, name := range all {
+		if readonlyTools[name] {
+			want = append(want, name)
+		}
+	}
+	if !slices.Equal(readonly, want) || slices.Contains(readonly, "click") || !slices.Contains(readonly, "aria_snapshot") {
+		t.Errorf("readonly tools = %v, want %v", readonly, want)
+	}
+	for name := range readonlyTools {
+		if !slices.Contains(all, name) {
+			t.Errorf("readonly tool %s is not a tool", name)
+		}
+	}
+
+	// Batches cannot reach tools outside the profile.
+	sr := s.invokeStep(context.Background(), 1, "click", json.RawMessage(`{"selector": "#buy"}`))
+	if sr.OK || !strings.Contains(sr.Message, "unknown tool click") {
+		t.Errorf("click step in the readonly profile = %+v", sr)
+	}
+
+	if err := checkToolProfile("guest"); err == nil {
+		t.Error("checkToolProfile(guest) succeeded")
+	}
+}
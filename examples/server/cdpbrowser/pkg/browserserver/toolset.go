@@ -0,0 +1,242 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// toolConfig selects the tools the server exposes: those of a tool profile,
+// plus the Enable tools, minus the Disable tools. It is also the format of
+// the -tool-config file.
+type toolConfig struct {
+	Profile string   `json:"profile,omitempty"`
+	Enable  []string `json:"enable,omitempty"`
+	Disable []string `json:"disable,omitempty"`
+}
+
+// exposes reports whether c exposes tool.
+func (c *toolConfig) exposes(tool string) bool {
+	if slices.Contains(c.Disable, tool) {
+		return false
+	}
+	return toolInProfile(c.Profile, tool) || slices.Contains(c.Enable, tool)
+}
+
+// loadToolConfig reads a JSON tool config file.
+func loadToolConfig(path string) (toolConfig, error) {
+	var c toolConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("failed to read tool config: %v", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("failed to parse tool config %s: %v", path, err)
+	}
+	if c.Profile == "" {
+		c.Profile = defaultToolProfile
+	}
+	return c, nil
+}
+
+// toolSet tracks which of the tools defined with addTool are registered with
+// the MCP server. Changing its config registers and removes tools at
+// runtime; the server then sends notifications/tools/list_changed, so
+// clients refresh their tool lists.
+type toolSet struct {
+	mu     sync.Mutex
+	config toolConfig
+	// names are the defined tools, in definition order.
+	names []string
+	// register adds the named tool to the MCP server, or removes it.
+	register map[string]func(on bool)
+	exposed  map[string]bool
+}
+
+// define adds a tool, registering it if the config exposes it.
+func (ts *toolSet) define(name string, register func(on bool)) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.register == nil {
+		ts.register = make(map[string]func(bool))
+		ts.exposed = make(map[string]bool)
+	}
+	if _, ok := ts.register[name]; !ok {
+		ts.names = append(ts.names, name)
+	}
+	ts.register[name] = register
+	ts.exposed[name] = ts.config.exposes(name)
+	if ts.exposed[name] {
+		register(true)
+	} else {
+		log.Printf("Not registering tool %s: not in the %s tool profile", name, ts.config.Profile)
+	}
+}
+
+// exposes reports whether the named tool is registered. Tools not defined
+// with addTool are always exposed.
+func (ts *toolSet) exposes(name string) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	exposed, ok := ts.exposed[name]
+	return exposed || !ok
+}
+
+// ToolChanges is the structured result of configure_tools.
+type ToolChanges struct {
+	Profile  string   `json:"profile"`
+	Enabled  []string `json:"enabled,omitempty" jsonschema:"Tools exposed in addition to the profile's"`
+	Disabled []string `json:"disabled,omitempty" jsonschema:"Tools hidden although the profile exposes them"`
+	Added    []string `json:"added,omitempty" jsonschema:"Tools this change exposed"`
+	Removed  []string `json:"removed,omitempty" jsonschema:"Tools this change hid"`
+	Tools    []string `json:"tools" jsonschema:"All exposed tools"`
+}
+
+// String formats the changes for the text result of configure_tools.
+func (c ToolChanges) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TOOL PROFILE: %s\n", c.Profile)
+	if len(c.Enabled) > 0 {
+		fmt.Fprintf(&b, "ENABLED: %s\n", strings.Join(c.Enabled, ", "))
+	}
+	if len(c.Disabled) > 0 {
+		fmt.Fprintf(&b, "DISABLED: %s\n", strings.Join(c.Disabled, ", "))
+	}
+	if len(c.Added) == 0 && len(c.Removed) == 0 {
+		b.WriteString("No change to the tool list\n")
+	}
+	if len(c.Added) > 0 {
+		fmt.Fprintf(&b, "ADDED: %s\n", strings.Join(c.Added, ", "))
+	}
+	if len(c.Removed) > 0 {
+		fmt.Fprintf(&b, "REMOVED: %s\n", strings.Join(c.Removed, ", "))
+	}
+	fmt.Fprintf(&b, "%d tools exposed\n", len(c.Tools))
+	return b.String()
+}
+
+// setConfig switches to config c, registering the tools it newly exposes
+// and removing those it hides.
+func (ts *toolSet) setConfig(c toolConfig) (ToolChanges, error) {
+	if err := checkToolProfile(c.Profile); err != nil {
+		return ToolChanges{}, invalidArgumentError{err}
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, name := range slices.Concat(c.Enable, c.Disable) {
+		if _, ok := ts.register[name]; !ok {
+			return ToolChanges{}, invalidArgumentError{fmt.Errorf("unknown tool %s", name)}
+		}
+	}
+	sort.Strings(c.Enable)
+	sort.Strings(c.Disable)
+	c.Enable = slices.Compact(c.Enable)
+	c.Disable = slices.Compact(c.Disable)
+	ts.config = c
+
+	changes := ToolChanges{Profile: c.Profile, Tools: []string{}}
+	for _, name := range ts.names {
+		on := c.exposes(name)
+		switch {
+		case on && !ts.exposed[name]:
+			changes.Added = append(changes.Added, name)
+			ts.register[name](true)
+		case !on && ts.exposed[name]:
+			changes.Removed = append(changes.Removed, name)
+			ts.register[name](false)
+		}
+		ts.exposed[name] = on
+		if on {
+			changes.Tools = append(changes.Tools, name)
+			if !toolInProfile(c.Profile, name) {
+				changes.Enabled = append(changes.Enabled, name)
+			}
+		} else if toolInProfile(c.Profile, name) {
+			changes.Disabled = append(changes.Disabled, name)
+		}
+	}
+	if len(changes.Added) > 0 || len(changes.Removed) > 0 {
+		log.Printf("Tool list changed (profile %s): added %v, removed %v", c.Profile, changes.Added, changes.Removed)
+	}
+	return changes, nil
+}
+
+// ConfigureToolsArgs are the arguments of configure_tools.
+type ConfigureToolsArgs struct {
+	Profile string   `json:"profile,omitempty" jsonschema:"Tool profile to switch to: readonly, standard, or admin (default: keep the current profile)"`
+	Enable  []string `json:"enable,omitempty" jsonschema:"Tools to expose even if the profile does not"`
+	Disable []string `json:"disable,omitempty" jsonschema:"Tools to hide even if the profile exposes them"`
+}
+
+// ConfigureTools tool - changes the exposed tools at runtime
+func (s *CDPBrowserServer) ConfigureTools(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ConfigureToolsArgs]]) (*mcp.CallToolResultFor[ToolChanges], error) {
+	args := req.Params.Arguments
+	s.toolset.mu.Lock()
+	c := toolConfig{
+		Profile: s.toolset.config.Profile,
+		Enable:  slices.Clone(s.toolset.config.Enable),
+		Disable: slices.Clone(s.toolset.config.Disable),
+	}
+	s.toolset.mu.Unlock()
+	if c.Profile == "" {
+		c.Profile = toolProfileAdmin
+	}
+	if args.Profile != "" {
+		c.Profile = args.Profile
+	}
+	for _, name := range args.Enable {
+		c.Disable = slices.DeleteFunc(c.Disable, func(n string) bool { return n == name })
+		c.Enable = append(c.Enable, name)
+	}
+	for _, name := range args.Disable {
+		c.Enable = slices.DeleteFunc(c.Enable, func(n string) bool { return n == name })
+		c.Disable = append(c.Disable, name)
+	}
+
+	changes, err := s.toolset.setConfig(c)
+	if err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[ToolChanges]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error configuring tools: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	return &mcp.CallToolResultFor[ToolChanges]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: changes.String()},
+		},
+		StructuredContent: changes,
+	}, nil
+}
+
+// reloadToolConfigOnHangup reloads the tool config from path whenever the
+// process receives SIGHUP.
+func (s *CDPBrowserServer) reloadToolConfigOnHangup(path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			c, err := loadToolConfig(path)
+			if err == nil {
+				_, err = s.toolset.setConfig(c)
+			}
+			if err != nil {
+				log.Printf("Failed to reload tool config: %v", err)
+				continue
+			}
+			log.Printf("Reloaded tool config from %s", path)
+		}
+	}()
+}
@@ -0,0 +1,127 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestConfigureTools(t *testing.T) {
+	s := &CDPBrowserServer{}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	clicked := func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickArgs]]) (*mcp.CallToolResultFor[SelectorMatch], error) {
+		return &mcp.CallToolResultFor[SelectorMatch]{Content: []mcp.Content{&mcp.TextContent{Text: "Clicked"}}}, nil
+	}
+	addTool(server, s, &mcp.Tool{Name: "click"}, clicked)
+	addTool(server, s, &mcp.Tool{Name: "screenshot"}, s.Screenshot)
+	addTool(server, s, &mcp.Tool{Name: "configure_tools"}, s.ConfigureTools)
+
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverSession.Close()
+	changed := make(chan struct{}, 10)
+	cs, err := mcp.NewClient(&mcp.Implementation{Name: "client"}, &mcp.ClientOptions{
+		ToolListChangedHandler: func(context.Context, *mcp.ClientRequest[*mcp.ToolListChangedParams]) {
+			changed <- struct{}{}
+		},
+	}).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+
+	listTools := func() []string {
+		t.Helper()
+		res, err := cs.ListTools(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, tool := range res.Tools {
+			names = append(names, tool.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+	configure := func(args map[string]any) (*mcp.CallToolResult, ToolChanges) {
+		t.Helper()
+		res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "configure_tools", Arguments: args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var changes ToolChanges
+		data, _ := json.Marshal(res.StructuredContent)
+		json.Unmarshal(data, &changes)
+		return res, changes
+	}
+	waitChanged := func() {
+		t.Helper()
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no tools/list_changed notification")
+		}
+	}
+
+	_, changes := configure(map[string]any{"profile": "readonly", "enable": []string{"configure_tools"}})
+	if !slices.Equal(changes.Removed, []string{"click"}) || !slices.Equal(changes.Enabled, []string{"configure_tools"}) {
+		t.Errorf("switching to readonly = %+v", changes)
+	}
+	waitChanged()
+	if got := listTools(); !slices.Equal(got, []string{"configure_tools", "screenshot"}) {
+		t.Errorf("readonly tools = %v", got)
+	}
+	if sr := s.invokeStep(ctx, 1, "click", json.RawMessage(`{"selector": "#buy"}`)); sr.OK {
+		t.Error("batch step called a hidden tool")
+	}
+
+	res, changes := configure(map[string]any{"enable": []string{"click"}, "disable": []string{"screenshot"}})
+	if !slices.Equal(changes.Added, []string{"click"}) || !slices.Equal(changes.Removed, []string{"screenshot"}) || changes.Profile != "readonly" {
+		t.Errorf("enabling click = %+v", changes)
+	}
+	if text := resultText(res); !strings.Contains(text, "ADDED: click\n") || !strings.Contains(text, "DISABLED: screenshot\n") {
+		t.Errorf("enabling click text = %q", text)
+	}
+	waitChanged()
+	if got := listTools(); !slices.Equal(got, []string{"click", "configure_tools"}) {
+		t.Errorf("tools after enabling click = %v", got)
+	}
+	if res, err := cs.CallTool(ctx, &mcp.CallToolParams{Name: "click", Arguments: map[string]any{"selector": "#go"}}); err != nil || res.IsError {
+		t.Errorf("re-registered click = %v, %v", res, err)
+	}
+
+	if res, _ := configure(map[string]any{"enable": []string{"teleport"}}); !res.IsError || !strings.Contains(resultText(res), "unknown tool teleport") {
+		t.Errorf("enabling an unknown tool = %q", resultText(res))
+	}
+	if res, _ := configure(map[string]any{"profile": "guest"}); !res.IsError {
+		t.Error("switching to an unknown profile succeeded")
+	}
+
+	// A reloaded config replaces the runtime changes.
+	path := filepath.Join(t.TempDir(), "tools.json")
+	if err := os.WriteFile(path, []byte(`{"disable": ["configure_tools"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadToolConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.toolset.setConfig(c); err != nil {
+		t.Fatal(err)
+	}
+	waitChanged()
+	if got := listTools(); !slices.Equal(got, []string{"click", "screenshot"}) {
+		t.Errorf("tools after reloading the config = %v", got)
+	}
+}
@@ -0,0 +1,125 @@
+package browserserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the server's spans. Until setupTracing installs a real
+// provider it is a no-op, so instrumentation costs nothing when tracing is off.
+var tracer = otel.Tracer(serverName)
+
+// setupTracing exports spans over OTLP/HTTP when the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT variable is
+// set. The returned function flushes and stops the exporter.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", serverName),
+			attribute.String("service.version", serverVersion),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	log.Println("OpenTelemetry tracing enabled")
+	return provider.Shutdown, nil
+}
+
+// metaCarrier exposes MCP request _meta as a propagation.TextMapCarrier, so
+// clients can pass W3C trace context ("traceparent", "tracestate") with each
+// tool call.
+type metaCarrier map[string]any
+
+func (c metaCarrier) Get(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c metaCarrier) Set(key, value string) { c[key] = value }
+
+func (c metaCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// tracingMiddleware starts a server span for every tools/call request,
+// continuing the trace whose context the client sent in _meta.
+func tracingMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		params, ok := req.GetParams().(*mcp.CallToolParamsFor[json.RawMessage])
+		if !ok {
+			return next(ctx, method, req)
+		}
+
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metaCarrier(params.GetMeta()))
+		ctx, span := tracer.Start(ctx, "tools/call "+params.Name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("mcp.method", method),
+				attribute.String("mcp.tool", params.Name),
+				attribute.String("mcp.session.id", req.GetSession().ID()),
+			))
+		defer span.End()
+
+		result, err := next(ctx, method, req)
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case result != nil:
+			if res, ok := result.(*mcp.CallToolResult); ok && res.IsError {
+				span.SetStatus(codes.Error, "tool returned an error result")
+			}
+		}
+		return result, err
+	}
+}
+
+// run executes chromedp actions against the browser in a span that is a child
+// of the tool call span carried by ctx. The actions run in the browser
+// context, so that chromedp finds its target, but carry the span and stop
+// when ctx is cancelled.
+func (s *CDPBrowserServer) run(ctx context.Context, actions ...chromedp.Action) error {
+	_, span := tracer.Start(ctx, "chromedp.Run",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int("cdp.actions", len(actions))))
+	defer span.End()
+
+	runCtx, cancel := context.WithCancelCause(trace.ContextWithSpan(s.ctx, span))
+	defer cancel(nil)
+	stop := context.AfterFunc(ctx, func() { cancel(context.Cause(ctx)) })
+	defer stop()
+
+	err := chromedp.Run(runCtx, actions...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
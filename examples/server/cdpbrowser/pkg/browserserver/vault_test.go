@@ -0,0 +1,180 @@
+package browserserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestCredentialVault(t *testing.T) {
+	defer func(n int) { vaultIterations = n }(vaultIterations)
+	vaultIterations = 1000
+
+	path := filepath.Join(t.TempDir(), "vault.json")
+	if err := setVaultCredential(path, "pass", "shop", "alice", []string{"shop.example"}, strings.NewReader("s3cret-pw\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := setVaultCredential(path, "pass", "mail", "bob", nil, strings.NewReader("hunter22")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("s3cret-pw")) || bytes.Contains(data, []byte("alice")) {
+		t.Errorf("vault file is not encrypted:\n%s", data)
+	}
+
+	v, err := loadVault(path, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(v.aliases(), ","); got != "mail,shop" {
+		t.Errorf("aliases = %s, want mail,shop", got)
+	}
+	if c := v.credentials["shop"]; c.Username != "alice" || c.Password != "s3cret-pw" || len(c.Domains) != 1 {
+		t.Errorf("shop credential = %+v", c)
+	}
+
+	if _, err := loadVault(path, "wrong"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("loadVault with the wrong passphrase: %v", err)
+	}
+	if _, err := loadVault(path, ""); err == nil || !strings.Contains(err.Error(), vaultPassphraseEnv) {
+		t.Errorf("loadVault without a passphrase: %v", err)
+	}
+	if err := setVaultCredential(path, "pass", "empty", "carol", nil, strings.NewReader("\n")); err == nil {
+		t.Errorf("setVaultCredential with an empty password succeeded")
+	}
+
+	if err := deleteVaultCredential(path, "pass", "mail"); err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteVaultCredential(path, "pass", "mail"); err == nil {
+		t.Errorf("deleting a missing credential succeeded")
+	}
+	if v, err = loadVault(path, "pass"); err != nil || strings.Join(v.aliases(), ",") != "shop" {
+		t.Errorf("after delete: aliases %v, error %v", v.aliases(), err)
+	}
+}
+
+func TestCredentialAllows(t *testing.T) {
+	c := &Credential{Domains: []string{"example.com", "*.login.test"}}
+	for url, want := range map[string]bool{
+		"https://example.com/login":      true,
+		"https://accounts.example.com/":  true,
+		"https://example.com.evil.test/": false,
+		"https://sso.login.test/":        true,
+		"https://login.test/":            false,
+		"about:blank":                    false,
+	} {
+		if got := c.allows(url); got != want {
+			t.Errorf("allows(%s) = %t, want %t", url, got, want)
+		}
+	}
+	if !(&Credential{}).allows("https://anywhere.test/") {
+		t.Errorf("credential without domains does not allow every page")
+	}
+}
+
+func TestSecretMasker(t *testing.T) {
+	var m secretMasker
+	if got := m.mask("nothing registered"); got != "nothing registered" {
+		t.Errorf("mask without secrets = %q", got)
+	}
+	m.add("pass", "password1", "abc", `quo"te`, "7", "")
+	for in, want := range map[string]string{
+		"my password1 is password1": "my ******** is ********",
+		"pass and abc":              "******** and ********",
+		"pin 7":                     "pin ********",
+		`{"v":"quo\"te"}`:           `{"v":"********"}`,
+	} {
+		if got := m.mask(in); got != want {
+			t.Errorf("mask(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	res := &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: "typed password1"}},
+		StructuredContent: LoginResult{Alias: "x", Username: "password1"},
+	}
+	m.maskResult(res)
+	if got := res.Content[0].(*mcp.TextContent).Text; got != "typed ********" {
+		t.Errorf("masked text = %q", got)
+	}
+	data, _ := json.Marshal(res.StructuredContent)
+	if strings.Contains(string(data), "password1") || !strings.Contains(string(data), `"username":"********"`) {
+		t.Errorf("masked structured content = %s", data)
+	}
+
+	// Secrets in numbers, keys, and escapes must leave valid JSON
+	res = &mcp.CallToolResult{StructuredContent: map[string]any{
+		"count":   17,
+		"pass":    []any{"7 items", 2.7},
+		"escaped": "a\"b <pass>",
+	}}
+	m.maskResult(res)
+	data, err := json.Marshal(res.StructuredContent)
+	if err != nil || !json.Valid(data) {
+		t.Fatalf("masked structured content is not valid JSON: %s, %v", data, err)
+	}
+	var got map[string]any
+	json.Unmarshal(data, &got)
+	want := map[string]any{
+		"count":    17.0,
+		"********": []any{"******** items", 2.7},
+		"escaped":  "a\"b <********>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("masked structured content = %v, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	logger := log.New(&maskingWriter{w: &buf, m: &m}, "", 0)
+	logger.Printf("TypeText called: text='%s'", "password1")
+	if got := buf.String(); got != "TypeText called: text='********'\n" {
+		t.Errorf("masked log = %q", got)
+	}
+}
+
+func TestSecretMaskerCallScope(t *testing.T) {
+	s := &CDPBrowserServer{}
+	s.secrets.add("vault-pw")
+	handler := s.secretMaskingMiddleware(func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		defer s.secrets.addForCall(ctx, "123456")()
+		defer s.secrets.addForCall(ctx, "vault-pw")()
+		if got := s.secrets.mask("code 123456"); got != "code ********" {
+			t.Errorf("mask during the call = %q", got)
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "typed 123456"}}}, nil
+	})
+	req := &mcp.ServerRequest[*mcp.CallToolParamsFor[json.RawMessage]]{Params: &mcp.CallToolParamsFor[json.RawMessage]{Name: "request_human_input"}}
+	res, err := handler(context.Background(), "tools/call", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.(*mcp.CallToolResult).Content[0].(*mcp.TextContent).Text; got != "typed ********" {
+		t.Errorf("masked result = %q", got)
+	}
+	if got := s.secrets.mask("order 123456, vault-pw"); got != "order 123456, ********" {
+		t.Errorf("mask after the call = %q, want the code released and the vault password kept", got)
+	}
+
+	// Outside a tool call, the secret is released by the returned function
+	release := s.secrets.addForCall(context.Background(), "654321")
+	if got := s.secrets.mask("654321"); got != secretMask {
+		t.Errorf("mask before release = %q", got)
+	}
+	release()
+	if got := s.secrets.mask("654321"); got != "654321" {
+		t.Errorf("mask after release = %q", got)
+	}
+}
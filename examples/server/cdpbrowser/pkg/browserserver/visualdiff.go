@@ -0,0 +1,351 @@
+package browserserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// defaultDiffThreshold is how much a color channel may differ before a
+	// pixel counts as changed, so that anti-aliasing and compression noise
+	// are ignored.
+	defaultDiffThreshold = 16
+	// diffCellSize is the size in pixels of the grid cells that changed
+	// pixels are grouped into to find changed regions.
+	diffCellSize = 16
+	// defaultDiffRegions is the default number of changed regions reported.
+	defaultDiffRegions = 10
+	// maxDiffRegions bounds max_regions.
+	maxDiffRegions = 100
+)
+
+// regionSelectorsJS returns the selector of the element at each of points,
+// given in screenshot pixels, or "" where there is none.
+const regionSelectorsJS = `function(points) {
+	const dpr = window.devicePixelRatio || 1;
+	return points.map(([x, y]) => {
+		const el = document.elementFromPoint(x / dpr, y / dpr);
+		return el ? getSelector(el) : '';
+	});
+}`
+
+// ChangedRegion is a rectangle of a screenshot, in pixels, in which pixels
+// changed.
+type ChangedRegion struct {
+	X        int    `json:"x"`
+	Y        int    `json:"y"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Pixels   int    `json:"pixels" jsonschema:"Number of changed pixels in the region"`
+	Selector string `json:"selector,omitempty" jsonschema:"Selector of the element at the center of the region on the current page"`
+}
+
+// String describes r in the text result of compare_screenshots.
+func (r ChangedRegion) String() string {
+	s := fmt.Sprintf("%dx%d at (%d, %d), %d pixels", r.Width, r.Height, r.X, r.Y, r.Pixels)
+	if r.Selector != "" {
+		s += " near " + r.Selector
+	}
+	return s
+}
+
+// imageDiff is the pixel difference of two images.
+type imageDiff struct {
+	changed int
+	total   int
+	// image shows the second image faded, with changed pixels in red.
+	image *image.NRGBA
+	// regions are the changed regions, largest first.
+	regions []ChangedRegion
+}
+
+// diffImages compares a and b pixel by pixel. A pixel changed if a color or
+// alpha channel differs by more than threshold, out of 255. Where the images
+// differ in size, pixels inside only one of them count as changed.
+func diffImages(a, b image.Image, threshold int) *imageDiff {
+	ab, bb := a.Bounds(), b.Bounds()
+	w, h := max(ab.Dx(), bb.Dx()), max(ab.Dy(), bb.Dy())
+	d := &imageDiff{total: w * h, image: image.NewNRGBA(image.Rect(0, 0, w, h))}
+	cols, rows := (w+diffCellSize-1)/diffCellSize, (h+diffCellSize-1)/diffCellSize
+	cells := make([]int, cols*rows)
+	red := color.NRGBA{R: 255, A: 255}
+	for y := range h {
+		for x := range w {
+			inA := x < ab.Dx() && y < ab.Dy()
+			inB := x < bb.Dx() && y < bb.Dy()
+			changed := inA != inB
+			var cb color.Color = color.White
+			if inB {
+				cb = b.At(bb.Min.X+x, bb.Min.Y+y)
+			}
+			if inA && inB {
+				changed = colorsDiffer(a.At(ab.Min.X+x, ab.Min.Y+y), cb, threshold)
+			}
+			if changed {
+				d.changed++
+				cells[(y/diffCellSize)*cols+x/diffCellSize]++
+				d.image.SetNRGBA(x, y, red)
+				continue
+			}
+			gray := color.GrayModel.Convert(cb).(color.Gray).Y
+			faded := 255 - (255-gray)/3
+			d.image.SetNRGBA(x, y, color.NRGBA{R: faded, G: faded, B: faded, A: 255})
+		}
+	}
+	d.regions = changedRegions(cells, cols, rows, w, h)
+	return d
+}
+
+// colorsDiffer reports whether a channel of c1 and c2 differs by more than
+// threshold, out of 255.
+func colorsDiffer(c1, c2 color.Color, threshold int) bool {
+	r1, g1, b1, a1 := c1.RGBA()
+	r2, g2, b2, a2 := c2.RGBA()
+	for _, pair := range [][2]uint32{{r1, r2}, {g1, g2}, {b1, b2}, {a1, a2}} {
+		delta := int(pair[0]>>8) - int(pair[1]>>8)
+		if delta > threshold || -delta > threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// changedRegions groups the grid cells holding changed pixels into
+// connected regions, largest first. cells holds the number of changed
+// pixels of each cell of a cols by rows grid over a w by h image.
+func changedRegions(cells []int, cols, rows, w, h int) []ChangedRegion {
+	var regions []ChangedRegion
+	seen := make([]bool, len(cells))
+	for start := range cells {
+		if cells[start] == 0 || seen[start] {
+			continue
+		}
+		minX, minY, maxX, maxY := cols, rows, -1, -1
+		pixels := 0
+		queue := []int{start}
+		seen[start] = true
+		for len(queue) > 0 {
+			i := queue[0]
+			queue = queue[1:]
+			cx, cy := i%cols, i/cols
+			minX, minY, maxX, maxY = min(minX, cx), min(minY, cy), max(maxX, cx), max(maxY, cy)
+			pixels += cells[i]
+			for _, n := range [][2]int{{cx - 1, cy}, {cx + 1, cy}, {cx, cy - 1}, {cx, cy + 1}} {
+				if n[0] < 0 || n[0] >= cols || n[1] < 0 || n[1] >= rows {
+					continue
+				}
+				if j := n[1]*cols + n[0]; cells[j] > 0 && !seen[j] {
+					seen[j] = true
+					queue = append(queue, j)
+				}
+			}
+		}
+		x, y := minX*diffCellSize, minY*diffCellSize
+		regions = append(regions, ChangedRegion{
+			X:      x,
+			Y:      y,
+			Width:  min((maxX+1)*diffCellSize, w) - x,
+			Height: min((maxY+1)*diffCellSize, h) - y,
+			Pixels: pixels,
+		})
+	}
+	sort.SliceStable(regions, func(i, j int) bool { return regions[i].Pixels > regions[j].Pixels })
+	return regions
+}
+
+// screenshotResource returns the PNG of a browser://screenshots or
+// browser://errors resource.
+func (s *CDPBrowserServer) screenshotResource(uri string) ([]byte, error) {
+	if png, ok := s.screenshots.get(uri); ok {
+		return png, nil
+	}
+	if png, ok := s.errorScreenshots.get(uri); ok {
+		return png, nil
+	}
+	return nil, invalidArgumentError{fmt.Errorf("unknown screenshot %s; use a browser://screenshots or browser://errors resource", uri)}
+}
+
+type CompareScreenshotsArgs struct {
+	Baseline   string `json:"baseline,omitempty" jsonschema:"URI of the baseline screenshot: a browser://screenshots or browser://errors resource. Without it, the current page is captured to serve as the baseline of later calls"`
+	Current    string `json:"current,omitempty" jsonschema:"URI of the screenshot to compare with the baseline (default: a screenshot of the current page, taken now)"`
+	Threshold  *int   `json:"threshold,omitempty" jsonschema:"How much a color channel may differ, from 0 to 255, before a pixel counts as changed (default: 16)"`
+	MaxRegions int    `json:"max_regions,omitempty" jsonschema:"Maximum number of changed regions to report, largest first, up to 100 (default: 10)"`
+}
+
+// VisualDiff is the structured result of compare_screenshots.
+type VisualDiff struct {
+	DiffPercent   float64         `json:"diff_percent" jsonschema:"Percentage of pixels that changed"`
+	ChangedPixels int             `json:"changed_pixels"`
+	TotalPixels   int             `json:"total_pixels"`
+	BaselineSize  string          `json:"baseline_size" jsonschema:"Size of the baseline in pixels, e.g. 1280x800"`
+	CurrentSize   string          `json:"current_size"`
+	CurrentURI    string          `json:"current_uri" jsonschema:"browser://screenshots resource of the current screenshot, usable as a later baseline"`
+	DiffURI       string          `json:"diff_uri" jsonschema:"browser://screenshots resource of the diff image: the current screenshot faded, with changed pixels in red"`
+	Regions       []ChangedRegion `json:"regions"`
+	// MoreRegions is the number of changed regions beyond max_regions.
+	MoreRegions int `json:"more_regions,omitempty"`
+}
+
+// String formats d for the text result of compare_screenshots.
+func (d *VisualDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "VISUAL DIFF: %.2f%% of pixels changed (%d of %d)\n", d.DiffPercent, d.ChangedPixels, d.TotalPixels)
+	if d.BaselineSize != d.CurrentSize {
+		fmt.Fprintf(&b, "SIZE CHANGED: %s -> %s\n", d.BaselineSize, d.CurrentSize)
+	}
+	fmt.Fprintf(&b, "CURRENT: %s\n", d.CurrentURI)
+	fmt.Fprintf(&b, "DIFF IMAGE: %s\n", d.DiffURI)
+	if len(d.Regions) == 0 {
+		return b.String()
+	}
+	b.WriteString("CHANGED REGIONS:\n")
+	for i, r := range d.Regions {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, r)
+	}
+	if d.MoreRegions > 0 {
+		fmt.Fprintf(&b, "... %d smaller regions\n", d.MoreRegions)
+	}
+	return b.String()
+}
+
+// decodeScreenshot decodes the PNG of the screenshot named what.
+func decodeScreenshot(data []byte, what string) (image.Image, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, invalidArgumentError{fmt.Errorf("%s screenshot is not a PNG: %v", what, err)}
+	}
+	return img, nil
+}
+
+// locateRegions sets the selectors of regions to the elements at their
+// centers on the current page.
+func (s *CDPBrowserServer) locateRegions(ctx context.Context, regions []ChangedRegion) error {
+	points := make([][2]int, len(regions))
+	for i, r := range regions {
+		points[i] = [2]int{r.X + r.Width/2, r.Y + r.Height/2}
+	}
+	params, _ := json.Marshal([]any{points})
+	js := fmt.Sprintf("(function() {%s return (%s)(...%s); })()", ariaHelpersJS, regionSelectorsJS, params)
+	var selectors []string
+	if err := s.backend().Evaluate(ctx, js, &selectors); err != nil {
+		return err
+	}
+	for i := range min(len(selectors), len(regions)) {
+		regions[i].Selector = selectors[i]
+	}
+	return nil
+}
+
+// CompareScreenshots tool - compares a screenshot with a baseline pixel by
+// pixel
+func (s *CDPBrowserServer) CompareScreenshots(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[CompareScreenshotsArgs]]) (*mcp.CallToolResultFor[VisualDiff], error) {
+	args := req.Params.Arguments
+	fail := func(err error) (*mcp.CallToolResultFor[VisualDiff], error) {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[VisualDiff]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error comparing screenshots: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	threshold := defaultDiffThreshold
+	if args.Threshold != nil {
+		if threshold = *args.Threshold; threshold < 0 || threshold > 255 {
+			return fail(invalidArgumentError{fmt.Errorf("threshold %d is not between 0 and 255", threshold)})
+		}
+	}
+	maxRegions := defaultDiffRegions
+	if args.MaxRegions > 0 {
+		maxRegions = min(args.MaxRegions, maxDiffRegions)
+	}
+
+	if args.Baseline == "" {
+		if args.Current != "" {
+			return fail(invalidArgumentError{errors.New("current needs a baseline to compare with")})
+		}
+		shot, err := s.backend().Screenshot(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		uri := s.screenshots.add(shot)
+		return &mcp.CallToolResultFor[VisualDiff]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Captured the current page as a baseline: %s\nPass it as baseline to compare the page with it later.", uri)},
+				&mcp.ResourceLink{URI: uri, Name: "baseline", MIMEType: "image/png"},
+			},
+			StructuredContent: VisualDiff{CurrentURI: uri, Regions: []ChangedRegion{}},
+		}, nil
+	}
+
+	baselinePNG, err := s.screenshotResource(args.Baseline)
+	if err != nil {
+		return fail(err)
+	}
+	baseline, err := decodeScreenshot(baselinePNG, "baseline")
+	if err != nil {
+		return fail(err)
+	}
+	result := VisualDiff{CurrentURI: args.Current}
+	var currentPNG []byte
+	if args.Current != "" {
+		currentPNG, err = s.screenshotResource(args.Current)
+	} else if currentPNG, err = s.backend().Screenshot(ctx); err == nil {
+		result.CurrentURI = s.screenshots.add(currentPNG)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	current, err := decodeScreenshot(currentPNG, "current")
+	if err != nil {
+		return fail(err)
+	}
+
+	d := diffImages(baseline, current, threshold)
+	var diffPNG bytes.Buffer
+	if err := png.Encode(&diffPNG, d.image); err != nil {
+		return fail(err)
+	}
+	result.DiffURI = s.screenshots.add(diffPNG.Bytes())
+	result.ChangedPixels, result.TotalPixels = d.changed, d.total
+	result.DiffPercent = 100 * float64(d.changed) / float64(max(d.total, 1))
+	result.BaselineSize = fmt.Sprintf("%dx%d", baseline.Bounds().Dx(), baseline.Bounds().Dy())
+	result.CurrentSize = fmt.Sprintf("%dx%d", current.Bounds().Dx(), current.Bounds().Dy())
+	result.Regions = d.regions
+	if len(result.Regions) > maxRegions {
+		result.Regions, result.MoreRegions = result.Regions[:maxRegions], len(result.Regions)-maxRegions
+	}
+	if result.Regions == nil {
+		result.Regions = []ChangedRegion{}
+	}
+	// Selectors only make sense for a screenshot of the page as it is now.
+	if args.Current == "" && len(result.Regions) > 0 {
+		if err := s.locateRegions(ctx, result.Regions); err != nil {
+			log.Printf("Failed to find the elements of changed regions: %v", err)
+		}
+	}
+
+	return &mcp.CallToolResultFor[VisualDiff]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: result.String()},
+			&mcp.ResourceLink{
+				URI:         result.DiffURI,
+				Name:        "diff image",
+				Description: "The current screenshot faded, with changed pixels in red",
+				MIMEType:    "image/png",
+			},
+		},
+		StructuredContent: result,
+	}, nil
+}
@@ -0,0 +1,141 @@
+package browserserver
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// testImage returns a white w by h image with the given rectangles filled
+// with c.
+func testImage(w, h int, c color.Color, rects ...image.Rectangle) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.White)
+			for _, r := range rects {
+				if image.Pt(x, y).In(r) {
+					img.Set(x, y, c)
+				}
+			}
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDiffImages(t *testing.T) {
+	baseline := testImage(100, 50, color.White)
+	// A faint change below the threshold, a 10x10 square, and a 2x2 square
+	// far from it.
+	current := testImage(100, 50, color.Black, image.Rect(20, 20, 30, 30), image.Rect(90, 2, 92, 4))
+	current.Set(5, 5, color.NRGBA{R: 250, G: 250, B: 250, A: 255})
+
+	d := diffImages(baseline, current, defaultDiffThreshold)
+	if d.changed != 104 || d.total != 5000 {
+		t.Errorf("changed %d of %d pixels, want 104 of 5000", d.changed, d.total)
+	}
+	want := []ChangedRegion{
+		{X: 16, Y: 16, Width: 16, Height: 16, Pixels: 100},
+		{X: 80, Y: 0, Width: 16, Height: 16, Pixels: 4},
+	}
+	if len(d.regions) != len(want) || d.regions[0] != want[0] || d.regions[1] != want[1] {
+		t.Errorf("regions = %+v, want %+v", d.regions, want)
+	}
+	if got := d.image.NRGBAAt(25, 25); got != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("changed pixel in the diff image = %v, want red", got)
+	}
+	if got := d.image.NRGBAAt(5, 5); got.R != got.G || got.R < 200 {
+		t.Errorf("unchanged pixel in the diff image = %v, want faded gray", got)
+	}
+
+	if d := diffImages(baseline, current, 0); d.changed != 105 {
+		t.Errorf("changed %d pixels with threshold 0, want 105", d.changed)
+	}
+
+	// Rows only one image has count as changed.
+	taller := testImage(100, 60, color.White)
+	d = diffImages(baseline, taller, defaultDiffThreshold)
+	if d.changed != 1000 || d.total != 6000 || len(d.regions) != 1 || d.regions[0].Y != 48 || d.regions[0].Height != 12 {
+		t.Errorf("taller image = %d of %d changed, regions %+v", d.changed, d.total, d.regions)
+	}
+}
+
+func compareScreenshots(t *testing.T, s *CDPBrowserServer, args CompareScreenshotsArgs) (*mcp.CallToolResultFor[VisualDiff], string) {
+	t.Helper()
+	res, err := s.CompareScreenshots(context.Background(), &mcp.ServerRequest[*mcp.CallToolParamsFor[CompareScreenshotsArgs]]{
+		Params: &mcp.CallToolParamsFor[CompareScreenshotsArgs]{Arguments: args},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, res.Content[0].(*mcp.TextContent).Text
+}
+
+func TestCompareScreenshots(t *testing.T) {
+	s := &CDPBrowserServer{}
+	baseline := s.screenshots.add(encodePNG(t, testImage(64, 32, color.White)))
+	current := s.screenshots.add(encodePNG(t, testImage(64, 32, color.Black, image.Rect(0, 0, 8, 8))))
+
+	one := 1
+	res, text := compareScreenshots(t, s, CompareScreenshotsArgs{Baseline: baseline, Current: current, Threshold: &one})
+	if res.IsError {
+		t.Fatalf("compare_screenshots failed: %s", text)
+	}
+	d := res.StructuredContent
+	if d.ChangedPixels != 64 || d.DiffPercent != 3.125 || len(d.Regions) != 1 || d.CurrentURI != current {
+		t.Errorf("diff = %+v", d)
+	}
+	for _, line := range []string{
+		"VISUAL DIFF: 3.12% of pixels changed (64 of 2048)\n",
+		"DIFF IMAGE: " + d.DiffURI + "\n",
+		"1. 16x16 at (0, 0), 64 pixels\n",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("report lacks %q:\n%s", line, text)
+		}
+	}
+	diffPNG, ok := s.screenshots.get(d.DiffURI)
+	if !ok {
+		t.Fatalf("diff image %s not stored", d.DiffURI)
+	}
+	if img, err := png.Decode(bytes.NewReader(diffPNG)); err != nil || img.Bounds().Dx() != 64 {
+		t.Errorf("diff image = %v, %v", img, err)
+	}
+
+	for _, tc := range []struct {
+		args CompareScreenshotsArgs
+		want string
+	}{
+		{CompareScreenshotsArgs{Baseline: "browser://screenshots/99", Current: current}, "unknown screenshot browser://screenshots/99"},
+		{CompareScreenshotsArgs{Current: current}, "current needs a baseline"},
+		{CompareScreenshotsArgs{Baseline: baseline, Current: current, Threshold: new(int)}, ""},
+	} {
+		res, text := compareScreenshots(t, s, tc.args)
+		if res.IsError != (tc.want != "") || !strings.Contains(text, tc.want) {
+			t.Errorf("compare_screenshots(%+v) = %q, want an error containing %q", tc.args, text, tc.want)
+		}
+	}
+	big := 256
+	if res, _ := compareScreenshots(t, s, CompareScreenshotsArgs{Baseline: baseline, Current: current, Threshold: &big}); !res.IsError {
+		t.Error("threshold 256 accepted")
+	}
+	invalid := s.screenshots.add([]byte("not a png"))
+	if res, text := compareScreenshots(t, s, CompareScreenshotsArgs{Baseline: baseline, Current: invalid}); !strings.Contains(text, "current screenshot is not a PNG") {
+		t.Errorf("invalid PNG = %v: %s", res.IsError, text)
+	}
+}
@@ -0,0 +1,467 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxClosedWebSockets bounds the closed connections list_websockets reports.
+const maxClosedWebSockets = 20
+
+// Defaults and limits of start_websocket_capture.
+const (
+	defaultWebSocketBufferSize = 500
+	maxWebSocketBufferSize     = 10000
+	defaultMaxPayloadBytes     = 2048
+	maxMaxPayloadBytes         = 1 << 20
+)
+
+// Directions of WebSocket frames.
+const (
+	frameSent     = "sent"
+	frameReceived = "received"
+)
+
+// frameTypes names the WebSocket opcodes.
+var frameTypes = map[float64]string{
+	0:  "continuation",
+	1:  "text",
+	2:  "binary",
+	8:  "close",
+	9:  "ping",
+	10: "pong",
+}
+
+// WebSocketInfo describes a WebSocket connection of the page.
+type WebSocketInfo struct {
+	ID     string `json:"id" jsonschema:"Connection id, to filter get_websocket_frames by"`
+	URL    string `json:"url"`
+	State  string `json:"state" jsonschema:"connecting, open, or closed"`
+	Status int    `json:"status,omitempty" jsonschema:"HTTP status of the handshake response"`
+	// Opened and Closed are when the connection was created and closed.
+	Opened         time.Time  `json:"opened"`
+	Closed         *time.Time `json:"closed,omitempty"`
+	FramesSent     int        `json:"frames_sent"`
+	FramesReceived int        `json:"frames_received"`
+	BytesSent      int        `json:"bytes_sent"`
+	BytesReceived  int        `json:"bytes_received"`
+	LastError      string     `json:"last_error,omitempty"`
+
+	// closedOrdinal orders the closed connections, to forget the oldest.
+	closedOrdinal int
+}
+
+// WebSocketFrame is a captured WebSocket frame.
+type WebSocketFrame struct {
+	Seq        int       `json:"seq"`
+	Time       time.Time `json:"time"`
+	Connection string    `json:"connection"`
+	URL        string    `json:"url"`
+	Direction  string    `json:"direction" jsonschema:"sent or received"`
+	Type       string    `json:"type" jsonschema:"text, binary, close, ping, pong, or continuation"`
+	Size       int       `json:"size" jsonschema:"Size of the payload in bytes"`
+	// Payload is the text of text frames and the base64 of other frames,
+	// cut to the capture's max_payload_bytes.
+	Payload   string `json:"payload"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// String formats f on one line.
+func (f *WebSocketFrame) String() string {
+	arrow := "<-"
+	if f.Direction == frameSent {
+		arrow = "->"
+	}
+	s := fmt.Sprintf("#%d %s %s %s %dB", f.Seq, f.Time.Format("15:04:05.000"), arrow, f.Type, f.Size)
+	if f.Payload != "" {
+		s += " " + f.Payload
+		if f.Truncated {
+			s += "…"
+		}
+	}
+	return s
+}
+
+// webSocketCapture is the filter and limits of a frame capture.
+type webSocketCapture struct {
+	url             string
+	direction       string
+	maxPayloadBytes int
+	bufferSize      int
+}
+
+// matches reports whether the capture keeps a frame of conn going in
+// direction.
+func (c *webSocketCapture) matches(conn *WebSocketInfo, direction string) bool {
+	return strings.Contains(conn.URL, c.url) && (c.direction == "" || c.direction == direction)
+}
+
+// webSocketTracker follows the WebSocket connections of the tab the tools
+// drive, and captures their frames while a capture is active.
+type webSocketTracker struct {
+	mu    sync.Mutex
+	conns map[network.RequestID]*WebSocketInfo
+	// order lists the connections, oldest first.
+	order []network.RequestID
+	// closed counts the closed connections, to forget the oldest.
+	closed int
+	// capture is the active capture, or nil.
+	capture *webSocketCapture
+	frames  []WebSocketFrame
+	seq     int
+	dropped int
+}
+
+// start follows the connections of the tab of tabCtx. chromedp enables the
+// network domain, which reports them, when it attaches to the tab.
+func (w *webSocketTracker) start(tabCtx context.Context) {
+	w.mu.Lock()
+	w.conns = make(map[network.RequestID]*WebSocketInfo)
+	w.order, w.closed = nil, 0
+	w.frames, w.dropped = nil, 0
+	w.mu.Unlock()
+	chromedp.ListenTarget(tabCtx, w.event)
+}
+
+// event updates the connections from a network event. It runs on
+// chromedp's event loop, so it must not block.
+func (w *webSocketTracker) event(ev any) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conns == nil {
+		return
+	}
+	switch ev := ev.(type) {
+	case *network.EventWebSocketCreated:
+		if _, ok := w.conns[ev.RequestID]; !ok {
+			w.order = append(w.order, ev.RequestID)
+		}
+		w.conns[ev.RequestID] = &WebSocketInfo{ID: string(ev.RequestID), URL: ev.URL, State: "connecting", Opened: time.Now()}
+	case *network.EventWebSocketHandshakeResponseReceived:
+		if c, ok := w.conns[ev.RequestID]; ok && ev.Response != nil {
+			c.State, c.Status = "open", int(ev.Response.Status)
+		}
+	case *network.EventWebSocketFrameSent:
+		w.frame(ev.RequestID, frameSent, ev.Response)
+	case *network.EventWebSocketFrameReceived:
+		w.frame(ev.RequestID, frameReceived, ev.Response)
+	case *network.EventWebSocketFrameError:
+		if c, ok := w.conns[ev.RequestID]; ok {
+			c.LastError = ev.ErrorMessage
+		}
+	case *network.EventWebSocketClosed:
+		c, ok := w.conns[ev.RequestID]
+		if !ok || c.Closed != nil {
+			return
+		}
+		now := time.Now()
+		c.State, c.Closed = "closed", &now
+		w.closed++
+		c.closedOrdinal = w.closed
+		w.forgetClosed()
+	}
+}
+
+// forgetClosed drops the oldest closed connections beyond
+// maxClosedWebSockets. w.mu must be held.
+func (w *webSocketTracker) forgetClosed() {
+	w.order = slices.DeleteFunc(w.order, func(id network.RequestID) bool {
+		c := w.conns[id]
+		if c.Closed != nil && c.closedOrdinal <= w.closed-maxClosedWebSockets {
+			delete(w.conns, id)
+			return true
+		}
+		return false
+	})
+}
+
+// frame counts a frame of connection id and captures it if it matches the
+// active capture. w.mu must be held.
+func (w *webSocketTracker) frame(id network.RequestID, direction string, data *network.WebSocketFrame) {
+	c, ok := w.conns[id]
+	if !ok || data == nil {
+		return
+	}
+	f := WebSocketFrame{
+		Time:       time.Now(),
+		Connection: c.ID,
+		URL:        c.URL,
+		Direction:  direction,
+		Type:       frameTypes[data.Opcode],
+		Size:       len(data.PayloadData),
+		Payload:    data.PayloadData,
+	}
+	if f.Type == "" {
+		f.Type = fmt.Sprintf("opcode %g", data.Opcode)
+	}
+	if data.Opcode != 1 {
+		// Other payloads are base64.
+		f.Size = len(data.PayloadData) / 4 * 3
+		if f.Size > 0 {
+			f.Size -= strings.Count(data.PayloadData[len(data.PayloadData)-2:], "=")
+		}
+	}
+	if direction == frameSent {
+		c.FramesSent++
+		c.BytesSent += f.Size
+	} else {
+		c.FramesReceived++
+		c.BytesReceived += f.Size
+	}
+
+	capture := w.capture
+	if capture == nil || !capture.matches(c, direction) {
+		return
+	}
+	f.Payload, f.Truncated = truncatePayload(f.Payload, capture.maxPayloadBytes)
+	w.seq++
+	f.Seq = w.seq
+	w.frames = append(w.frames, f)
+	if over := len(w.frames) - capture.bufferSize; over > 0 {
+		w.frames = slices.Delete(w.frames, 0, over)
+		w.dropped += over
+	}
+}
+
+// truncatePayload cuts payload to at most max bytes without splitting a
+// UTF-8 character.
+func truncatePayload(payload string, max int) (string, bool) {
+	if len(payload) <= max {
+		return payload, false
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(payload[cut]) {
+		cut--
+	}
+	return payload[:cut], true
+}
+
+// connections returns the tracked connections, oldest first, leaving out
+// closed ones unless includeClosed is set.
+func (w *webSocketTracker) connections(includeClosed bool) []WebSocketInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	conns := []WebSocketInfo{}
+	for _, id := range w.order {
+		c := w.conns[id]
+		if c.Closed == nil || includeClosed {
+			conns = append(conns, *c)
+		}
+	}
+	return conns
+}
+
+type ListWebSocketsArgs struct {
+	IncludeClosed bool `json:"include_closed,omitempty" jsonschema:"Also list recently closed connections"`
+}
+
+// WebSocketList is the structured result of list_websockets.
+type WebSocketList struct {
+	Connections []WebSocketInfo `json:"connections"`
+	Capturing   bool            `json:"capturing" jsonschema:"Whether frames are being captured"`
+}
+
+// ListWebSockets tool - lists the WebSocket connections of the page with
+// how much traffic each carried, whether or not frames are captured.
+func (s *CDPBrowserServer) ListWebSockets(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ListWebSocketsArgs]]) (*mcp.CallToolResultFor[WebSocketList], error) {
+	list := WebSocketList{Connections: s.websockets.connections(req.Params.Arguments.IncludeClosed)}
+	s.websockets.mu.Lock()
+	list.Capturing = s.websockets.capture != nil
+	s.websockets.mu.Unlock()
+
+	var b strings.Builder
+	if len(list.Connections) == 0 {
+		b.WriteString("No WebSocket connections")
+	} else {
+		fmt.Fprintf(&b, "%d WebSocket connections:", len(list.Connections))
+	}
+	for _, c := range list.Connections {
+		fmt.Fprintf(&b, "\n- [%s] %s (%s): %d frames sent (%dB), %d received (%dB)", c.ID, c.URL, c.State, c.FramesSent, c.BytesSent, c.FramesReceived, c.BytesReceived)
+		if c.LastError != "" {
+			fmt.Fprintf(&b, "; error: %s", c.LastError)
+		}
+	}
+	if !list.Capturing && len(list.Connections) > 0 {
+		b.WriteString("\nFrames are not being captured; call start_websocket_capture to see their payloads")
+	}
+
+	return &mcp.CallToolResultFor[WebSocketList]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: b.String()},
+		},
+		StructuredContent: list,
+	}, nil
+}
+
+type StartWebSocketCaptureArgs struct {
+	URL             string `json:"url,omitempty" jsonschema:"Only capture frames of connections whose URL contains this text"`
+	Direction       string `json:"direction,omitempty" jsonschema:"Only capture sent or received frames (default: both)"`
+	MaxPayloadBytes int    `json:"max_payload_bytes,omitempty" jsonschema:"Payloads are cut to this many bytes (default: 2048)"`
+	BufferSize      int    `json:"buffer_size,omitempty" jsonschema:"Maximum number of frames kept before the oldest are dropped (default: 500)"`
+}
+
+// check validates the arguments of start_websocket_capture, applying the
+// defaults.
+func (args *StartWebSocketCaptureArgs) check() error {
+	if args.Direction != "" && args.Direction != frameSent && args.Direction != frameReceived {
+		return invalidArgumentError{fmt.Errorf("invalid direction %q: must be %s or %s", args.Direction, frameSent, frameReceived)}
+	}
+	if args.MaxPayloadBytes == 0 {
+		args.MaxPayloadBytes = defaultMaxPayloadBytes
+	}
+	if args.MaxPayloadBytes < 0 || args.MaxPayloadBytes > maxMaxPayloadBytes {
+		return invalidArgumentError{fmt.Errorf("max_payload_bytes must be between 1 and %d, got %d", maxMaxPayloadBytes, args.MaxPayloadBytes)}
+	}
+	if args.BufferSize == 0 {
+		args.BufferSize = defaultWebSocketBufferSize
+	}
+	if args.BufferSize < 0 || args.BufferSize > maxWebSocketBufferSize {
+		return invalidArgumentError{fmt.Errorf("buffer_size must be between 1 and %d, got %d", maxWebSocketBufferSize, args.BufferSize)}
+	}
+	return nil
+}
+
+// StartWebSocketCapture tool - starts capturing the frames of the page's
+// WebSocket connections, replacing any capture in progress.
+func (s *CDPBrowserServer) StartWebSocketCapture(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[StartWebSocketCaptureArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+	args := req.Params.Arguments
+	if err := args.check(); err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error starting the WebSocket capture: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	s.websockets.mu.Lock()
+	s.websockets.capture = &webSocketCapture{
+		url:             args.URL,
+		direction:       args.Direction,
+		maxPayloadBytes: args.MaxPayloadBytes,
+		bufferSize:      args.BufferSize,
+	}
+	s.websockets.frames, s.websockets.dropped = nil, 0
+	s.websockets.mu.Unlock()
+
+	text := "Capturing WebSocket frames"
+	if args.URL != "" {
+		text += fmt.Sprintf(" of connections to URLs containing %q", args.URL)
+	}
+	if args.Direction != "" {
+		text += fmt.Sprintf(", %s only", args.Direction)
+	}
+	text += fmt.Sprintf(" (payloads cut to %d bytes, last %d frames kept). Use get_websocket_frames to read them.", args.MaxPayloadBytes, args.BufferSize)
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}, nil
+}
+
+type GetWebSocketFramesArgs struct {
+	Connection string `json:"connection,omitempty" jsonschema:"Only return frames of the connection with this id, from list_websockets"`
+	Contains   string `json:"contains,omitempty" jsonschema:"Only return frames whose payload contains this text"`
+	Since      int    `json:"since,omitempty" jsonschema:"Only return frames with a greater seq, such as the latest of a previous call"`
+	Limit      int    `json:"limit,omitempty" jsonschema:"Maximum number of frames to return, the most recent (default: 50)"`
+}
+
+// WebSocketFrames is the structured result of get_websocket_frames.
+type WebSocketFrames struct {
+	Frames []WebSocketFrame `json:"frames"`
+	// Latest is the seq of the latest captured frame, to pass as since.
+	Latest  int `json:"latest" jsonschema:"Seq of the latest captured frame; pass it as since to only get later frames"`
+	Omitted int `json:"omitted,omitempty" jsonschema:"Matching frames left out by limit"`
+	Dropped int `json:"dropped,omitempty" jsonschema:"Frames dropped because the buffer was full"`
+}
+
+// String formats f for tool results.
+func (f *WebSocketFrames) String() string {
+	var b strings.Builder
+	if len(f.Frames) == 0 {
+		fmt.Fprintf(&b, "No matching WebSocket frames (latest seq %d)", f.Latest)
+	} else {
+		fmt.Fprintf(&b, "%d WebSocket frames (latest seq %d)", len(f.Frames)+f.Omitted, f.Latest)
+		if f.Omitted > 0 {
+			fmt.Fprintf(&b, ", showing the last %d", len(f.Frames))
+		}
+		b.WriteString(":")
+	}
+	if f.Dropped > 0 {
+		fmt.Fprintf(&b, "\n(%d older frames were dropped; raise buffer_size)", f.Dropped)
+	}
+	for _, frame := range f.Frames {
+		b.WriteString("\n")
+		b.WriteString(frame.String())
+	}
+	return b.String()
+}
+
+// GetWebSocketFrames tool - returns the captured WebSocket frames matching
+// the filters, oldest first.
+func (s *CDPBrowserServer) GetWebSocketFrames(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[GetWebSocketFramesArgs]]) (*mcp.CallToolResultFor[WebSocketFrames], error) {
+	args := req.Params.Arguments
+	if args.Limit <= 0 {
+		args.Limit = 50
+	}
+
+	s.websockets.mu.Lock()
+	capturing := s.websockets.capture != nil
+	result := WebSocketFrames{Frames: []WebSocketFrame{}, Latest: s.websockets.seq, Dropped: s.websockets.dropped}
+	for _, f := range s.websockets.frames {
+		if f.Seq > args.Since && (args.Connection == "" || f.Connection == args.Connection) && strings.Contains(f.Payload, args.Contains) {
+			result.Frames = append(result.Frames, f)
+		}
+	}
+	s.websockets.mu.Unlock()
+	if !capturing && len(result.Frames) == 0 {
+		err := fmt.Errorf("WebSocket frames are not being captured; call start_websocket_capture first")
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[WebSocketFrames]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error getting WebSocket frames: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+	if over := len(result.Frames) - args.Limit; over > 0 {
+		result.Frames, result.Omitted = result.Frames[over:], over
+	}
+
+	return &mcp.CallToolResultFor[WebSocketFrames]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: result.String()},
+		},
+		StructuredContent: result,
+	}, nil
+}
+
+// StopWebSocketCapture tool - stops capturing WebSocket frames. The frames
+// captured so far stay readable until the next capture starts.
+func (s *CDPBrowserServer) StopWebSocketCapture(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[struct{}], error) {
+	s.websockets.mu.Lock()
+	capturing := s.websockets.capture != nil
+	s.websockets.capture = nil
+	frames := len(s.websockets.frames)
+	s.websockets.mu.Unlock()
+
+	text := "WebSocket frames were not being captured"
+	if capturing {
+		text = fmt.Sprintf("Stopped capturing WebSocket frames; %d captured frames remain readable with get_websocket_frames", frames)
+	}
+	return &mcp.CallToolResultFor[struct{}]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}, nil
+}
@@ -0,0 +1,139 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestWebSocketTracker(t *testing.T) {
+	var w webSocketTracker
+	w.conns = make(map[network.RequestID]*WebSocketInfo)
+	text := func(s string) *network.WebSocketFrame { return &network.WebSocketFrame{Opcode: 1, PayloadData: s} }
+
+	w.event(&network.EventWebSocketCreated{RequestID: "1", URL: "wss://chat.example/live"})
+	w.event(&network.EventWebSocketCreated{RequestID: "2", URL: "wss://metrics.example/"})
+	w.event(&network.EventWebSocketHandshakeResponseReceived{RequestID: "1", Response: &network.WebSocketResponse{Status: 101}})
+	// Frames are counted but not kept without a capture.
+	w.event(&network.EventWebSocketFrameSent{RequestID: "1", Response: text("before")})
+	w.capture = &webSocketCapture{url: "chat", maxPayloadBytes: 8, bufferSize: 2}
+	w.event(&network.EventWebSocketFrameSent{RequestID: "1", Response: text("hello")})
+	w.event(&network.EventWebSocketFrameReceived{RequestID: "2", Response: text("ignored")})
+	w.event(&network.EventWebSocketFrameReceived{RequestID: "1", Response: &network.WebSocketFrame{Opcode: 2, PayloadData: "AAECAw=="}})
+	w.event(&network.EventWebSocketFrameReceived{RequestID: "1", Response: text("héllo wörld")})
+	w.event(&network.EventWebSocketFrameError{RequestID: "1", ErrorMessage: "invalid frame"})
+
+	conns := w.connections(false)
+	if len(conns) != 2 {
+		t.Fatalf("connections = %+v", conns)
+	}
+	if c := conns[0]; c.State != "open" || c.Status != 101 || c.FramesSent != 2 || c.BytesSent != 11 || c.FramesReceived != 2 || c.BytesReceived != 4+13 || c.LastError != "invalid frame" {
+		t.Errorf("chat connection = %+v", c)
+	}
+	if len(w.frames) != 2 || w.dropped != 1 || w.seq != 3 {
+		t.Fatalf("captured %+v, %d dropped", w.frames, w.dropped)
+	}
+	if f := w.frames[0]; f.Type != "binary" || f.Size != 4 || f.Direction != frameReceived {
+		t.Errorf("binary frame = %+v", f)
+	}
+	// The payload is cut without splitting "ö".
+	if f := w.frames[1]; f.Payload != "héllo w" || !f.Truncated {
+		t.Errorf("truncated frame = %+v", f)
+	}
+
+	w.event(&network.EventWebSocketClosed{RequestID: "2"})
+	if conns := w.connections(false); len(conns) != 1 || conns[0].ID != "1" {
+		t.Errorf("open connections = %+v", conns)
+	}
+	for i := range maxClosedWebSockets + 1 {
+		id := network.RequestID(fmt.Sprint("closed", i))
+		w.event(&network.EventWebSocketCreated{RequestID: id, URL: "wss://x.example/"})
+		w.event(&network.EventWebSocketClosed{RequestID: id})
+	}
+	conns = w.connections(true)
+	if len(conns) != maxClosedWebSockets+1 || conns[0].ID != "1" || conns[1].ID != "closed1" {
+		t.Errorf("with closed, %d connections from %s", len(conns), conns[1].ID)
+	}
+}
+
+func TestWebSocketTools(t *testing.T) {
+	s := &CDPBrowserServer{}
+	s.websockets.conns = make(map[network.RequestID]*WebSocketInfo)
+	ctx := context.Background()
+	start := func(args StartWebSocketCaptureArgs) *mcp.CallToolResultFor[struct{}] {
+		t.Helper()
+		res, err := s.StartWebSocketCapture(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[StartWebSocketCaptureArgs]]{
+			Params: &mcp.CallToolParamsFor[StartWebSocketCaptureArgs]{Arguments: args},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	get := func(args GetWebSocketFramesArgs) *mcp.CallToolResultFor[WebSocketFrames] {
+		t.Helper()
+		res, err := s.GetWebSocketFrames(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[GetWebSocketFramesArgs]]{
+			Params: &mcp.CallToolParamsFor[GetWebSocketFramesArgs]{Arguments: args},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if res := get(GetWebSocketFramesArgs{}); !res.IsError {
+		t.Error("get_websocket_frames succeeded without a capture")
+	}
+	for _, args := range []StartWebSocketCaptureArgs{{Direction: "both"}, {MaxPayloadBytes: -1}, {BufferSize: maxWebSocketBufferSize + 1}} {
+		if res := start(args); !res.IsError {
+			t.Errorf("start_websocket_capture(%+v) succeeded", args)
+		}
+	}
+	if res := start(StartWebSocketCaptureArgs{Direction: frameReceived}); res.IsError {
+		t.Fatalf("start_websocket_capture failed: %v", res.Content)
+	}
+	if c := s.websockets.capture; c.maxPayloadBytes != defaultMaxPayloadBytes || c.bufferSize != defaultWebSocketBufferSize {
+		t.Errorf("capture defaults = %+v", c)
+	}
+
+	s.websockets.event(&network.EventWebSocketCreated{RequestID: "1", URL: "wss://chat.example/live"})
+	for _, msg := range []string{"ping", `{"type":"message","text":"hi"}`, `{"type":"message","text":"bye"}`} {
+		s.websockets.event(&network.EventWebSocketFrameReceived{RequestID: "1", Response: &network.WebSocketFrame{Opcode: 1, PayloadData: msg}})
+		s.websockets.event(&network.EventWebSocketFrameSent{RequestID: "1", Response: &network.WebSocketFrame{Opcode: 1, PayloadData: msg}})
+	}
+
+	res := get(GetWebSocketFramesArgs{Contains: `"message"`, Limit: 1})
+	got := res.StructuredContent
+	if res.IsError || len(got.Frames) != 1 || got.Frames[0].Seq != 3 || got.Omitted != 1 || got.Latest != 3 {
+		t.Fatalf("get_websocket_frames = %+v", got)
+	}
+	if text := res.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, `<- text 31B {"type":"message","text":"bye"}`) {
+		t.Errorf("text = %q", text)
+	}
+	if got := get(GetWebSocketFramesArgs{Since: 3}).StructuredContent; len(got.Frames) != 0 {
+		t.Errorf("since latest = %+v", got.Frames)
+	}
+	if got := get(GetWebSocketFramesArgs{Connection: "2"}).StructuredContent; len(got.Frames) != 0 {
+		t.Errorf("other connection = %+v", got.Frames)
+	}
+
+	list, err := s.ListWebSockets(ctx, &mcp.ServerRequest[*mcp.CallToolParamsFor[ListWebSocketsArgs]]{Params: &mcp.CallToolParamsFor[ListWebSocketsArgs]{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := list.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, "[1] wss://chat.example/live (connecting): 3 frames sent (65B), 3 received (65B)") {
+		t.Errorf("list_websockets = %q", text)
+	}
+
+	if _, err := s.StopWebSocketCapture(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Captured frames stay readable after the capture stops.
+	if res := get(GetWebSocketFramesArgs{}); res.IsError || len(res.StructuredContent.Frames) != 3 {
+		t.Errorf("after stop = %v", res.Content)
+	}
+}
@@ -0,0 +1,138 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// WindowBounds is the position, size, and state of the browser window, the
+// structured result of the window tools.
+type WindowBounds struct {
+	Left   int64  `json:"left"`
+	Top    int64  `json:"top"`
+	Width  int64  `json:"width"`
+	Height int64  `json:"height"`
+	State  string `json:"state" jsonschema:"normal, minimized, maximized, or fullscreen"`
+}
+
+// String formats b for tool results.
+func (b *WindowBounds) String() string {
+	if b.State != string(browser.WindowStateNormal) {
+		return fmt.Sprintf("Window is %s", b.State)
+	}
+	return fmt.Sprintf("Window is %dx%d at (%d, %d)", b.Width, b.Height, b.Left, b.Top)
+}
+
+// updateWindow calls update with the id and bounds of the window of the
+// current page, then returns the bounds the browser settled on.
+func (s *CDPBrowserServer) updateWindow(ctx context.Context, update func(ctx context.Context, id browser.WindowID, current *browser.Bounds) error) (*WindowBounds, error) {
+	var result WindowBounds
+	err := s.run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		id, current, err := browser.GetWindowForTarget().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("finding the window: %w", err)
+		}
+		if err := update(ctx, id, current); err != nil {
+			return err
+		}
+		got, err := browser.GetWindowBounds(id).Do(ctx)
+		if err != nil {
+			return err
+		}
+		result = WindowBounds{Left: got.Left, Top: got.Top, Width: got.Width, Height: got.Height, State: string(got.WindowState)}
+		return nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// setWindowState returns an update for updateWindow that puts the window in
+// state.
+func setWindowState(state browser.WindowState) func(context.Context, browser.WindowID, *browser.Bounds) error {
+	return func(ctx context.Context, id browser.WindowID, current *browser.Bounds) error {
+		return browser.SetWindowBounds(id, &browser.Bounds{WindowState: state}).Do(ctx)
+	}
+}
+
+// windowResult returns the result of a window tool.
+func windowResult(action string, bounds *WindowBounds, err error) *mcp.CallToolResultFor[WindowBounds] {
+	if err != nil {
+		return &mcp.CallToolResultFor[WindowBounds]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error %s: %v", action, err)},
+			},
+			IsError: true,
+		}
+	}
+	return &mcp.CallToolResultFor[WindowBounds]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: bounds.String()},
+		},
+		StructuredContent: *bounds,
+	}
+}
+
+type SetWindowSizeArgs struct {
+	Width  int64  `json:"width" jsonschema:"Window width in pixels, including the browser UI"`
+	Height int64  `json:"height" jsonschema:"Window height in pixels, including the browser UI"`
+	Left   *int64 `json:"left,omitempty" jsonschema:"Distance of the window from the left of the screen (default: unchanged)"`
+	Top    *int64 `json:"top,omitempty" jsonschema:"Distance of the window from the top of the screen (default: unchanged)"`
+}
+
+// SetWindowSize tool - resizes and optionally moves the browser window
+func (s *CDPBrowserServer) SetWindowSize(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[SetWindowSizeArgs]]) (*mcp.CallToolResultFor[WindowBounds], error) {
+	args := req.Params.Arguments
+	if args.Width <= 0 || args.Height <= 0 {
+		return windowResult("resizing the window", nil, fmt.Errorf("width and height must be positive, got %dx%d", args.Width, args.Height)), nil
+	}
+	bounds := &browser.Bounds{Width: args.Width, Height: args.Height}
+	if args.Left != nil {
+		bounds.Left = *args.Left
+	}
+	if args.Top != nil {
+		bounds.Top = *args.Top
+	}
+	b, err := s.updateWindow(ctx, func(ctx context.Context, id browser.WindowID, current *browser.Bounds) error {
+		// Chrome only moves and resizes windows in the normal state.
+		if current.WindowState != browser.WindowStateNormal {
+			if err := setWindowState(browser.WindowStateNormal)(ctx, id, current); err != nil {
+				return fmt.Errorf("restoring the window: %w", err)
+			}
+		}
+		return browser.SetWindowBounds(id, bounds).Do(ctx)
+	})
+	return windowResult("resizing the window", b, err), nil
+}
+
+// Maximize tool - maximizes the browser window
+func (s *CDPBrowserServer) Maximize(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[WindowBounds], error) {
+	b, err := s.updateWindow(ctx, setWindowState(browser.WindowStateMaximized))
+	return windowResult("maximizing the window", b, err), nil
+}
+
+// Minimize tool - minimizes the browser window
+func (s *CDPBrowserServer) Minimize(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[WindowBounds], error) {
+	b, err := s.updateWindow(ctx, setWindowState(browser.WindowStateMinimized))
+	return windowResult("minimizing the window", b, err), nil
+}
+
+// BringToFront tool - restores the browser window if it is minimized and
+// activates the current tab, so a human can watch the automation
+func (s *CDPBrowserServer) BringToFront(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[struct{}]]) (*mcp.CallToolResultFor[WindowBounds], error) {
+	b, err := s.updateWindow(ctx, func(ctx context.Context, id browser.WindowID, current *browser.Bounds) error {
+		if current.WindowState == browser.WindowStateMinimized {
+			if err := setWindowState(browser.WindowStateNormal)(ctx, id, current); err != nil {
+				return fmt.Errorf("restoring the window: %w", err)
+			}
+		}
+		return page.BringToFront().Do(ctx)
+	})
+	return windowResult("bringing the window to the front", b, err), nil
+}
@@ -0,0 +1,39 @@
+package browserserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestWindowBoundsString(t *testing.T) {
+	for _, tc := range []struct {
+		bounds WindowBounds
+		want   string
+	}{
+		{WindowBounds{Left: 10, Top: 20, Width: 1280, Height: 800, State: "normal"}, "Window is 1280x800 at (10, 20)"},
+		{WindowBounds{Width: 1920, Height: 1080, State: "maximized"}, "Window is maximized"},
+		{WindowBounds{State: "minimized"}, "Window is minimized"},
+	} {
+		if got := tc.bounds.String(); got != tc.want {
+			t.Errorf("%+v.String() = %q, want %q", tc.bounds, got, tc.want)
+		}
+	}
+}
+
+func TestSetWindowSizeValidation(t *testing.T) {
+	s := &CDPBrowserServer{}
+	for _, args := range []SetWindowSizeArgs{{Width: 0, Height: 600}, {Width: 800, Height: -1}} {
+		res, err := s.SetWindowSize(context.Background(), &mcp.ServerRequest[*mcp.CallToolParamsFor[SetWindowSizeArgs]]{
+			Params: &mcp.CallToolParamsFor[SetWindowSizeArgs]{Arguments: args},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := res.Content[0].(*mcp.TextContent).Text; !res.IsError || !strings.Contains(text, "must be positive") {
+			t.Errorf("SetWindowSize(%+v) = %q, want a validation error", args, text)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package browserserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultRetryDelay is how long a workflow waits before retrying a failed
+// step that sets no retry_delay.
+const defaultRetryDelay = time.Second
+
+// workflowExtensions are the file extensions of stored workflows, in lookup
+// order.
+var workflowExtensions = []string{".yaml", ".yml", ".json"}
+
+// Workflow is a declarative sequence of tool calls, run server-side with
+// run_workflow.
+type Workflow struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Variables are the defaults of the {{variable}} placeholders in step
+	// arguments.
+	Variables map[string]any `json:"variables,omitempty" jsonschema:"Default values of the variables referenced in step arguments as {{name}}"`
+	Steps     []WorkflowStep `json:"steps" jsonschema:"Ordered tool calls the workflow performs"`
+}
+
+// WorkflowStep is one tool call in a workflow.
+type WorkflowStep struct {
+	Name            string         `json:"name,omitempty" jsonschema:"Label of the step in the report"`
+	Tool            string         `json:"tool" jsonschema:"Name of the tool to call"`
+	Arguments       map[string]any `json:"arguments,omitempty" jsonschema:"Tool arguments; string values may contain {{variable}} placeholders"`
+	Expect          string         `json:"expect,omitempty" jsonschema:"Text the tool result must contain for the step to pass"`
+	Retries         int            `json:"retries,omitempty" jsonschema:"How many times to retry the step after it fails (default: 0)"`
+	RetryDelay      string         `json:"retry_delay,omitempty" jsonschema:"How long to wait before a retry, as a Go duration such as 500ms (default: 1s)"`
+	ContinueOnError bool           `json:"continue_on_error,omitempty" jsonschema:"Run the remaining steps even if this one fails (default: false)"`
+
+	retryDelay time.Duration
+}
+
+// parseWorkflow parses a YAML or JSON workflow definition, rejecting unknown
+// fields so that typos do not silently drop assertions or retries.
+func parseWorkflow(data []byte) (*Workflow, error) {
+	// YAML is a superset of JSON, so one parser reads both. Round-trip
+	// through JSON to apply the same field names and checks as inline
+	// workflows.
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errors.New("empty workflow")
+	}
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.DisallowUnknownFields()
+	var w Workflow
+	if err := dec.Decode(&w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// loadWorkflow reads the named workflow from dir.
+func loadWorkflow(dir, name string) (*Workflow, error) {
+	if !profileNamePattern.MatchString(name) {
+		return nil, invalidArgumentError{fmt.Errorf("invalid workflow name %q", name)}
+	}
+	for _, ext := range workflowExtensions {
+		data, err := os.ReadFile(filepath.Join(dir, name+ext))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		w, err := parseWorkflow(data)
+		if err != nil {
+			return nil, invalidArgumentError{fmt.Errorf("failed to parse workflow %s%s: %v", name, ext, err)}
+		}
+		if w.Name == "" {
+			w.Name = name
+		}
+		return w, nil
+	}
+	stored := "none"
+	if names := listWorkflows(dir); len(names) > 0 {
+		stored = strings.Join(names, ", ")
+	}
+	return nil, invalidArgumentError{fmt.Errorf("unknown workflow %s (stored workflows: %s)", name, stored)}
+}
+
+// listWorkflows returns the sorted names of the workflows stored in dir.
+func listWorkflows(dir string) []string {
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if !e.IsDir() && slices.Contains(workflowExtensions, ext) {
+			names = append(names, strings.TrimSuffix(e.Name(), ext))
+		}
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+// validateWorkflow checks that every step calls a known tool that is not a
+// macro or workflow tool, that its retry settings are valid, and that every
+// placeholder names a variable in vars.
+func (s *CDPBrowserServer) validateWorkflow(w *Workflow, vars map[string]any) error {
+	if len(w.Steps) == 0 {
+		return errors.New("workflow has no steps")
+	}
+	for i := range w.Steps {
+		step := &w.Steps[i]
+		if macroTools[step.Tool] {
+			return fmt.Errorf("step %d: %s cannot be called from a workflow", i+1, step.Tool)
+		}
+		if !s.hasTool(step.Tool) {
+			return fmt.Errorf("step %d: unknown tool %q", i+1, step.Tool)
+		}
+		if step.Retries < 0 {
+			return fmt.Errorf("step %d: retries must not be negative", i+1)
+		}
+		step.retryDelay = defaultRetryDelay
+		if step.RetryDelay != "" {
+			d, err := time.ParseDuration(step.RetryDelay)
+			if err != nil || d < 0 {
+				return fmt.Errorf("step %d: invalid retry_delay %q", i+1, step.RetryDelay)
+			}
+			step.retryDelay = d
+		}
+		var err error
+		walkStrings(step.Arguments, func(v string) any {
+			for _, match := range placeholderPattern.FindAllStringSubmatch(v, -1) {
+				if _, ok := vars[match[1]]; !ok && err == nil {
+					err = fmt.Errorf("step %d: placeholder {{%s}} is not a defined variable", i+1, match[1])
+				}
+			}
+			return v
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WorkflowStepResult is the outcome of one workflow step.
+type WorkflowStepResult struct {
+	StepResult
+	Name     string `json:"name,omitempty"`
+	Attempts int    `json:"attempts" jsonschema:"How many times the step ran, including retries"`
+}
+
+// WorkflowResult is the structured result of run_workflow.
+type WorkflowResult struct {
+	Workflow string               `json:"workflow"`
+	Passed   bool                 `json:"passed"`
+	Steps    []WorkflowStepResult `json:"steps"`
+	// Skipped is the number of steps not run because an earlier step failed.
+	Skipped int `json:"skipped,omitempty"`
+}
+
+// String formats the per-step report for the text result of run_workflow.
+func (r *WorkflowResult) String() string {
+	var b strings.Builder
+	status := "passed"
+	if !r.Passed {
+		status = "failed"
+		if r.Skipped > 0 {
+			status = fmt.Sprintf("failed at step %d, %d steps skipped", len(r.Steps), r.Skipped)
+		}
+	}
+	fmt.Fprintf(&b, "Workflow %s %s\n", r.Workflow, status)
+	for _, sr := range r.Steps {
+		mark := "ok"
+		if !sr.OK {
+			mark = "FAIL"
+		}
+		if sr.Attempts > 1 {
+			mark += fmt.Sprintf(" after %d attempts", sr.Attempts)
+		}
+		label := sr.Tool
+		if sr.Name != "" {
+			label = fmt.Sprintf("%s (%s)", sr.Name, sr.Tool)
+		}
+		fmt.Fprintf(&b, "%d. [%s] %s: %s\n", sr.Step, mark, label, sr.Message)
+	}
+	return b.String()
+}
+
+// runWorkflow runs the steps of w with the variables vars, retrying failed
+// steps and stopping at the first step that still fails unless it continues
+// on error.
+func (s *CDPBrowserServer) runWorkflow(ctx context.Context, w *Workflow, vars map[string]any) WorkflowResult {
+	result := WorkflowResult{Workflow: w.Name, Passed: true, Steps: []WorkflowStepResult{}}
+	for i, step := range w.Steps {
+		sr := WorkflowStepResult{Name: step.Name}
+		if stepArgs, err := expandArguments(step.Arguments, vars); err != nil {
+			sr.StepResult = StepResult{Step: i + 1, Tool: step.Tool, Message: fmt.Sprintf("invalid arguments: %v", err)}
+		} else {
+		attempts:
+			for {
+				sr.Attempts++
+				sr.StepResult = s.runWorkflowStep(ctx, i+1, &step, stepArgs)
+				if sr.OK || sr.Attempts > step.Retries {
+					break
+				}
+				select {
+				case <-time.After(step.retryDelay):
+				case <-ctx.Done():
+					break attempts
+				}
+			}
+		}
+		result.Steps = append(result.Steps, sr)
+		if !sr.OK {
+			result.Passed = false
+			if !step.ContinueOnError {
+				result.Skipped = len(w.Steps) - i - 1
+				break
+			}
+		}
+	}
+	return result
+}
+
+// runWorkflowStep runs step once as step number n, failing it if its result
+// lacks the expected text.
+func (s *CDPBrowserServer) runWorkflowStep(ctx context.Context, n int, step *WorkflowStep, args json.RawMessage) StepResult {
+	sr := s.invokeStep(ctx, n, step.Tool, args)
+	if sr.OK && !strings.Contains(sr.Message, step.Expect) {
+		sr.OK = false
+		sr.Code = CodeAssertionFailed
+		sr.Message = fmt.Sprintf("expected the result to contain %q, got: %s", step.Expect, sr.Message)
+	}
+	return sr
+}
+
+type RunWorkflowArgs struct {
+	Name      string         `json:"name,omitempty" jsonschema:"Workflow stored in the server's workflows directory, without its .yaml, .yml, or .json extension"`
+	Workflow  *Workflow      `json:"workflow,omitempty" jsonschema:"Inline workflow definition"`
+	Source    string         `json:"source,omitempty" jsonschema:"Inline workflow definition as YAML or JSON text"`
+	Variables map[string]any `json:"variables,omitempty" jsonschema:"Values for the workflow's variables, overriding its defaults"`
+}
+
+// prepareWorkflow returns the workflow args select, checked, and the values
+// of its variables.
+func (s *CDPBrowserServer) prepareWorkflow(args *RunWorkflowArgs) (*Workflow, map[string]any, error) {
+	given := 0
+	for _, ok := range []bool{args.Name != "", args.Workflow != nil, args.Source != ""} {
+		if ok {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, nil, invalidArgumentError{errors.New("give exactly one of name, workflow, and source")}
+	}
+	w := args.Workflow
+	switch {
+	case args.Name != "":
+		if s.workflowsDir == "" {
+			return nil, nil, invalidArgumentError{errors.New("the server has no workflows directory; pass the workflow inline")}
+		}
+		var err error
+		if w, err = loadWorkflow(s.workflowsDir, args.Name); err != nil {
+			return nil, nil, err
+		}
+	case args.Source != "":
+		var err error
+		if w, err = parseWorkflow([]byte(args.Source)); err != nil {
+			return nil, nil, invalidArgumentError{fmt.Errorf("failed to parse workflow: %v", err)}
+		}
+	}
+	if w.Name == "" {
+		w.Name = "inline"
+	}
+
+	vars := make(map[string]any)
+	for k, v := range w.Variables {
+		vars[k] = v
+	}
+	for k, v := range args.Variables {
+		vars[k] = v
+	}
+	if err := s.validateWorkflow(w, vars); err != nil {
+		return nil, nil, invalidArgumentError{fmt.Errorf("invalid workflow %s: %v", w.Name, err)}
+	}
+	return w, vars, nil
+}
+
+// RunWorkflow tool - runs a declarative workflow server-side and reports
+// each step
+func (s *CDPBrowserServer) RunWorkflow(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[RunWorkflowArgs]]) (*mcp.CallToolResultFor[WorkflowResult], error) {
+	w, vars, err := s.prepareWorkflow(&req.Params.Arguments)
+	if err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[WorkflowResult]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error running workflow: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	result := s.runWorkflow(ctx, w, vars)
+	return &mcp.CallToolResultFor[WorkflowResult]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: result.String()},
+		},
+		StructuredContent: result,
+		IsError:           !result.Passed,
+	}, nil
+}
@@ -0,0 +1,162 @@
+package browserserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const checkoutWorkflow = `
+name: checkout
+description: Sign in and pay
+variables:
+  user: alice
+  card: "4242"
+steps:
+  - name: enter the user
+    tool: type_text
+    arguments: {selector: "#user", text: "{{user}}"}
+  - tool: click_button
+    arguments: {selector: "#flaky"}
+    retries: 2
+    retry_delay: 1ms
+  - name: pay
+    tool: type_text
+    arguments: {selector: "#card", text: "card {{card}}"}
+    expect: Typed
+`
+
+// newWorkflowTestServer returns a server whose type_text and click_button
+// tools record their calls in *calls. Clicking #flaky fails twice before it
+// succeeds, and clicking #broken always fails.
+func newWorkflowTestServer(t *testing.T, calls *[]string) *CDPBrowserServer {
+	t.Helper()
+	s := &CDPBrowserServer{workflowsDir: t.TempDir()}
+	srv := mcp.NewServer(&mcp.Implementation{Name: "test"}, nil)
+	addTool(srv, s, &mcp.Tool{Name: "type_text"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[TypeTextArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+		*calls = append(*calls, req.Params.Arguments.Selector+"="+req.Params.Arguments.Text)
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Typed into " + req.Params.Arguments.Selector}},
+		}, nil
+	})
+	flaky := 0
+	addTool(srv, s, &mcp.Tool{Name: "click_button"}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[ClickButtonArgs]]) (*mcp.CallToolResultFor[struct{}], error) {
+		sel := req.Params.Arguments.Selector
+		*calls = append(*calls, "click "+sel)
+		failed := sel == "#broken"
+		if sel == "#flaky" {
+			flaky++
+			failed = flaky <= 2
+		}
+		return &mcp.CallToolResultFor[struct{}]{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Clicked " + sel}},
+			IsError: failed,
+		}, nil
+	})
+	addTool(srv, s, &mcp.Tool{Name: "run_workflow"}, s.RunWorkflow)
+	return s
+}
+
+func runWorkflow(t *testing.T, s *CDPBrowserServer, args RunWorkflowArgs) (*mcp.CallToolResultFor[WorkflowResult], string) {
+	t.Helper()
+	res, err := s.RunWorkflow(context.Background(), &mcp.ServerRequest[*mcp.CallToolParamsFor[RunWorkflowArgs]]{
+		Params: &mcp.CallToolParamsFor[RunWorkflowArgs]{Arguments: args},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, res.Content[0].(*mcp.TextContent).Text
+}
+
+func TestRunStoredWorkflow(t *testing.T) {
+	var calls []string
+	s := newWorkflowTestServer(t, &calls)
+	if err := os.WriteFile(filepath.Join(s.workflowsDir, "checkout.yaml"), []byte(checkoutWorkflow), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	res, text := runWorkflow(t, s, RunWorkflowArgs{Name: "checkout", Variables: map[string]any{"user": "bob"}})
+	if res.IsError || !res.StructuredContent.Passed {
+		t.Fatalf("checkout failed: %s", text)
+	}
+	want := []string{"#user=bob", "click #flaky", "click #flaky", "click #flaky", "#card=card 4242"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+	steps := res.StructuredContent.Steps
+	if len(steps) != 3 || steps[1].Attempts != 3 || steps[0].Name != "enter the user" {
+		t.Errorf("steps = %+v", steps)
+	}
+	for _, line := range []string{
+		"Workflow checkout passed\n",
+		"1. [ok] enter the user (type_text): Typed into #user\n",
+		"2. [ok after 3 attempts] click_button: Clicked #flaky\n",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("report lacks %q:\n%s", line, text)
+		}
+	}
+
+	_, text = runWorkflow(t, s, RunWorkflowArgs{Name: "shipping"})
+	if !strings.Contains(text, "unknown workflow shipping (stored workflows: checkout)") {
+		t.Errorf("unknown workflow = %q", text)
+	}
+	_, text = runWorkflow(t, s, RunWorkflowArgs{Name: "../checkout"})
+	if !strings.Contains(text, "invalid workflow name") {
+		t.Errorf("workflow outside the directory = %q", text)
+	}
+}
+
+func TestRunInlineWorkflow(t *testing.T) {
+	var calls []string
+	s := newWorkflowTestServer(t, &calls)
+
+	// A result without the expected text fails the step, and later steps
+	// are skipped.
+	res, text := runWorkflow(t, s, RunWorkflowArgs{Source: `{"steps": [
+		{"tool": "type_text", "arguments": {"selector": "#q", "text": "shoes"}, "expect": "Searched"},
+		{"tool": "click_button", "arguments": {"selector": "#go"}}
+	]}`})
+	if !res.IsError || res.StructuredContent.Skipped != 1 || res.StructuredContent.Steps[0].Code != CodeAssertionFailed {
+		t.Errorf("expect mismatch = %+v", res.StructuredContent)
+	}
+	if !strings.Contains(text, `Workflow inline failed at step 1, 1 steps skipped`) || !strings.Contains(text, `expected the result to contain "Searched"`) {
+		t.Errorf("expect mismatch report = %q", text)
+	}
+
+	calls = nil
+	res, _ = runWorkflow(t, s, RunWorkflowArgs{Workflow: &Workflow{
+		Name: "cleanup",
+		Steps: []WorkflowStep{
+			{Tool: "click_button", Arguments: map[string]any{"selector": "#broken"}, ContinueOnError: true},
+			{Tool: "click_button", Arguments: map[string]any{"selector": "#done"}},
+		},
+	}})
+	if res.StructuredContent.Passed || len(res.StructuredContent.Steps) != 2 || !slices.Equal(calls, []string{"click #broken", "click #done"}) {
+		t.Errorf("continue_on_error = %+v, calls %q", res.StructuredContent, calls)
+	}
+
+	for _, tc := range []struct {
+		args RunWorkflowArgs
+		want string
+	}{
+		{RunWorkflowArgs{}, "exactly one of name, workflow, and source"},
+		{RunWorkflowArgs{Name: "a", Source: "steps: []"}, "exactly one of name, workflow, and source"},
+		{RunWorkflowArgs{Source: "steps: []"}, "workflow has no steps"},
+		{RunWorkflowArgs{Source: "steps:\n  - tool: type_text\n    expects: ok"}, `unknown field "expects"`},
+		{RunWorkflowArgs{Source: "steps:\n  - tool: teleport"}, `unknown tool "teleport"`},
+		{RunWorkflowArgs{Source: "steps:\n  - tool: run_workflow"}, "run_workflow cannot be called from a workflow"},
+		{RunWorkflowArgs{Source: "steps:\n  - tool: type_text\n    arguments: {text: '{{who}}'}"}, "{{who}} is not a defined variable"},
+		{RunWorkflowArgs{Source: "steps:\n  - tool: type_text\n    retry_delay: soon"}, `invalid retry_delay "soon"`},
+	} {
+		res, text := runWorkflow(t, s, tc.args)
+		if !res.IsError || !strings.Contains(text, tc.want) {
+			t.Errorf("run_workflow(%+v) = %q, want an error containing %q", tc.args, text, tc.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package browserserver
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Ways set_zoom can zoom the page.
+const (
+	// zoomCSS sets the CSS zoom of the document, which lays the page out
+	// again at the new size, like the browser's zoom, so zooming out fits
+	// more of a page in the viewport.
+	zoomCSS = "css"
+	// zoomPinch sets the page scale factor, which magnifies the page like
+	// pinch zoom without laying it out again. It cannot zoom out beyond
+	// the page's own size.
+	zoomPinch = "pinch"
+)
+
+// Limits of the zoom argument.
+const (
+	minZoom = 0.1
+	maxZoom = 5
+)
+
+// cssZoomJS returns a script that sets the CSS zoom of the document to zoom
+// as soon as the document element exists, for
+// Page.addScriptToEvaluateOnNewDocument.
+func cssZoomJS(zoom float64) string {
+	z := strconv.Quote(strconv.FormatFloat(zoom, 'g', -1, 64))
+	return `(function() {
+	const apply = () => {
+		if (!document.documentElement) return false;
+		document.documentElement.style.zoom = ` + z + `;
+		return true;
+	};
+	if (!apply()) {
+		const observer = new MutationObserver(() => { if (apply()) observer.disconnect(); });
+		observer.observe(document, {childList: true});
+	}
+})()`
+}
+
+// zoomStateJS reports the size of the page on screen and of the viewport.
+const zoomStateJS = `(function() {
+	const r = document.documentElement.getBoundingClientRect();
+	const scale = window.visualViewport ? visualViewport.scale : 1;
+	return {
+		page_width: Math.round(Math.max(r.width, document.documentElement.scrollWidth) * scale),
+		page_height: Math.round(Math.max(r.height, document.documentElement.scrollHeight) * scale),
+		viewport_width: innerWidth,
+		viewport_height: innerHeight
+	};
+})()`
+
+// pageZoom is the CSS zoom set_zoom applies to new documents.
+type pageZoom struct {
+	mu sync.Mutex
+	// script is the identifier of the script that applies the zoom to new
+	// documents, or "" if the zoom is 1.
+	script page.ScriptIdentifier
+}
+
+// set returns an action that makes zoom the CSS zoom of the current
+// document and of the documents loaded after it.
+func (z *pageZoom) set(zoom float64) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		z.mu.Lock()
+		defer z.mu.Unlock()
+		if z.script != "" {
+			if err := page.RemoveScriptToEvaluateOnNewDocument(z.script).Do(ctx); err != nil {
+				return err
+			}
+			z.script = ""
+		}
+		if zoom != 1 {
+			id, err := page.AddScriptToEvaluateOnNewDocument(cssZoomJS(zoom)).Do(ctx)
+			if err != nil {
+				return err
+			}
+			z.script = id
+		}
+		js := `document.documentElement.style.zoom = ''`
+		if zoom != 1 {
+			js = cssZoomJS(zoom)
+		}
+		return chromedp.Evaluate(js, nil).Do(ctx)
+	})
+}
+
+type SetZoomArgs struct {
+	Zoom   float64 `json:"zoom" jsonschema:"Zoom factor from 0.1 to 5, such as 0.5 to fit twice as much of a dense page in a screenshot; 1 resets the zoom"`
+	Method string  `json:"method,omitempty" jsonschema:"css to lay the page out again at the new size like browser zoom, which can zoom out; or pinch to magnify it like pinch zoom, which can only zoom in (default: css)"`
+}
+
+// ZoomState is the structured result of set_zoom.
+type ZoomState struct {
+	Zoom           float64 `json:"zoom"`
+	Method         string  `json:"method"`
+	PageWidth      int     `json:"page_width" jsonschema:"Width of the page on screen, in pixels"`
+	PageHeight     int     `json:"page_height" jsonschema:"Height of the page on screen, in pixels"`
+	ViewportWidth  int     `json:"viewport_width"`
+	ViewportHeight int     `json:"viewport_height"`
+}
+
+// String formats z for tool results.
+func (z *ZoomState) String() string {
+	text := fmt.Sprintf("Zoom is %g%% (%s)", z.Zoom*100, z.Method)
+	if z.PageWidth > 0 && z.PageHeight > 0 && z.ViewportHeight > 0 {
+		text += fmt.Sprintf("; the page is %dx%d on screen, %.1f viewports tall", z.PageWidth, z.PageHeight, float64(z.PageHeight)/float64(z.ViewportHeight))
+	}
+	return text
+}
+
+// check validates the arguments of set_zoom, defaulting the method.
+func (args *SetZoomArgs) check() error {
+	if args.Method == "" {
+		args.Method = zoomCSS
+	}
+	if args.Method != zoomCSS && args.Method != zoomPinch {
+		return invalidArgumentError{fmt.Errorf("invalid method %q: must be %s or %s", args.Method, zoomCSS, zoomPinch)}
+	}
+	if args.Zoom < minZoom || args.Zoom > maxZoom {
+		return invalidArgumentError{fmt.Errorf("zoom must be between %g and %g, got %g", float64(minZoom), float64(maxZoom), args.Zoom)}
+	}
+	if args.Method == zoomPinch && args.Zoom < 1 {
+		return invalidArgumentError{fmt.Errorf("pinch zoom cannot zoom out; use method %s for a zoom of %g", zoomCSS, args.Zoom)}
+	}
+	return nil
+}
+
+// SetZoom tool - zooms the page, so that dense dashboards fit in a
+// screenshot or small text can be read. Setting one method resets the other.
+func (s *CDPBrowserServer) SetZoom(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[SetZoomArgs]]) (*mcp.CallToolResultFor[ZoomState], error) {
+	args := req.Params.Arguments
+	state := ZoomState{Zoom: args.Zoom}
+	err := args.check()
+	if err == nil {
+		cssZoom, scale := args.Zoom, 1.0
+		if args.Method == zoomPinch {
+			cssZoom, scale = 1, args.Zoom
+		}
+		state.Method = args.Method
+		err = s.run(ctx,
+			s.zoom.set(cssZoom),
+			emulation.SetPageScaleFactor(scale),
+			chromedp.Evaluate(zoomStateJS, &state),
+		)
+	}
+	if err != nil {
+		noteToolError(ctx, err, "")
+		return &mcp.CallToolResultFor[ZoomState]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Error setting the zoom: %v", err)},
+			},
+			IsError: true,
+		}, nil
+	}
+
+	return &mcp.CallToolResultFor[ZoomState]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: state.String()},
+		},
+		StructuredContent: state,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package browserserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetZoomArgs(t *testing.T) {
+	args := SetZoomArgs{Zoom: 0.5}
+	if err := args.check(); err != nil || args.Method != zoomCSS {
+		t.Errorf("check(0.5) = %v, method %q", err, args.Method)
+	}
+	if err := (&SetZoomArgs{Zoom: 2, Method: zoomPinch}).check(); err != nil {
+		t.Errorf("check(pinch 2) = %v", err)
+	}
+	for _, args := range []SetZoomArgs{
+		{},
+		{Zoom: 6},
+		{Zoom: 1, Method: "browser"},
+		{Zoom: 0.5, Method: zoomPinch},
+	} {
+		if err := args.check(); err == nil {
+			t.Errorf("check(%+v) succeeded", args)
+		}
+	}
+}
+
+func TestZoomState(t *testing.T) {
+	z := ZoomState{Zoom: 0.5, Method: zoomCSS, PageWidth: 640, PageHeight: 1200, ViewportWidth: 1280, ViewportHeight: 800}
+	if got, want := z.String(), "Zoom is 50% (css); the page is 640x1200 on screen, 1.5 viewports tall"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := (&ZoomState{Zoom: 1.25, Method: zoomPinch}).String(); got != "Zoom is 125% (pinch)" {
+		t.Errorf("String() without sizes = %q", got)
+	}
+	if js := cssZoomJS(0.75); !strings.Contains(js, `style.zoom = "0.75"`) {
+		t.Errorf("cssZoomJS(0.75) = %s", js)
+	}
+}
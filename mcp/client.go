@@ -56,6 +56,9 @@ type ClientOptions struct {
 	// Handler for sampling.
 	// Called when a server calls CreateMessage.
 	CreateMessageHandler func(context.Context, *ClientRequest[*CreateMessageParams]) (*CreateMessageResult, error)
+	// Handler for elicitation.
+	// Called when a server calls Elicit.
+	ElicitationHandler func(context.Context, *ClientRequest[*ElicitParams]) (*ElicitResult, error)
 	// Handlers for notifications from the server.
 	ToolListChangedHandler      func(context.Context, *ClientRequest[*ToolListChangedParams])
 	PromptListChangedHandler    func(context.Context, *ClientRequest[*PromptListChangedParams])
@@ -124,6 +127,9 @@ func (c *Client) Connect(ctx context.Context, t Transport, _ *ClientSessionOptio
 	if c.opts.CreateMessageHandler != nil {
 		caps.Sampling = &SamplingCapabilities{}
 	}
+	if c.opts.ElicitationHandler != nil {
+		caps.Elicitation = &ElicitationCapabilities{}
+	}
 
 	params := &InitializeParams{
 		ProtocolVersion: latestProtocolVersion,
@@ -265,6 +271,13 @@ func (c *Client) createMessage(ctx context.Context, req *ClientRequest[*CreateMe
 	return c.opts.CreateMessageHandler(ctx, req)
 }
 
+func (c *Client) elicit(ctx context.Context, req *ClientRequest[*ElicitParams]) (*ElicitResult, error) {
+	if c.opts.ElicitationHandler == nil {
+		return nil, jsonrpc2.NewError(CodeUnsupportedMethod, "client does not support elicitation")
+	}
+	return c.opts.ElicitationHandler(ctx, req)
+}
+
 // AddSendingMiddleware wraps the current sending method handler using the provided
 // middleware. Middleware is applied from right to left, so that the first one is
 // executed first.
@@ -305,6 +318,7 @@ var clientMethodInfos = map[string]methodInfo{
 	methodPing:                      newClientMethodInfo(clientSessionMethod((*ClientSession).ping), missingParamsOK),
 	methodListRoots:                 newClientMethodInfo(clientMethod((*Client).listRoots), missingParamsOK),
 	methodCreateMessage:             newClientMethodInfo(clientMethod((*Client).createMessage), 0),
+	methodElicit:                    newClientMethodInfo(clientMethod((*Client).elicit), 0),
 	notificationCancelled:           newClientMethodInfo(clientSessionMethod((*ClientSession).cancel), notification|missingParamsOK),
 	notificationToolListChanged:     newClientMethodInfo(clientMethod((*Client).callToolChangedHandler), notification|missingParamsOK),
 	notificationPromptListChanged:   newClientMethodInfo(clientMethod((*Client).callPromptChangedHandler), notification|missingParamsOK),
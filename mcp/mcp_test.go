@@ -131,6 +131,9 @@ func TestEndToEnd(t *testing.T) {
 		CreateMessageHandler: func(context.Context, *ClientRequest[*CreateMessageParams]) (*CreateMessageResult, error) {
 			return &CreateMessageResult{Model: "aModel", Content: &TextContent{}}, nil
 		},
+		ElicitationHandler: func(_ context.Context, req *ClientRequest[*ElicitParams]) (*ElicitResult, error) {
+			return &ElicitResult{Action: "accept", Content: map[string]any{"answer": req.Params.Message}}, nil
+		},
 		ToolListChangedHandler: func(context.Context, *ClientRequest[*ToolListChangedParams]) {
 			notificationChans["tools"] <- 0
 		},
@@ -346,6 +349,21 @@ func TestEndToEnd(t *testing.T) {
 			t.Errorf("got %q, want %q", g, w)
 		}
 	})
+	t.Run("elicitation", func(t *testing.T) {
+		res, err := ss.Elicit(ctx, &ElicitParams{
+			Message: "question",
+			RequestedSchema: &jsonschema.Schema{
+				Type:       "object",
+				Properties: map[string]*jsonschema.Schema{"answer": {Type: "string"}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Action != "accept" || res.Content["answer"] != "question" {
+			t.Errorf("got %+v, want accepted answer %q", res, "question")
+		}
+	})
 	t.Run("logging", func(t *testing.T) {
 		want := []*LoggingMessageParams{
 			{
@@ -572,6 +590,17 @@ func basicConnection(t *testing.T, config func(*Server)) (*ServerSession, *Clien
 	return ss, cs
 }
 
+func TestElicitUnsupported(t *testing.T) {
+	ss, cs := basicConnection(t, nil)
+	defer cs.Close()
+	defer ss.Close()
+
+	_, err := ss.Elicit(context.Background(), &ElicitParams{Message: "question"})
+	if got := errorCode(err); got != CodeUnsupportedMethod {
+		t.Errorf("Elicit without a handler: got error %v (code %d), want code %d", err, got, CodeUnsupportedMethod)
+	}
+}
+
 func TestServerClosing(t *testing.T) {
 	cc, cs := basicConnection(t, func(s *Server) {
 		AddTool(s, greetTool(), sayHi)
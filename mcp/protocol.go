@@ -956,7 +956,39 @@ func (*ResourceUpdatedNotificationParams) isParams() {}
 
 // TODO(jba): add CompleteRequest and related types.
 
-// TODO(jba): add ElicitRequest and related types.
+// A request from the server to elicit additional information from the user
+// via the client.
+type ElicitParams struct {
+	// This property is reserved by the protocol to allow clients and servers to
+	// attach additional metadata to their responses.
+	Meta `json:"_meta,omitempty"`
+	// The message to present to the user.
+	Message string `json:"message"`
+	// A restricted subset of JSON Schema describing the information requested.
+	// Only top-level properties with primitive types are allowed, without
+	// nesting.
+	RequestedSchema *jsonschema.Schema `json:"requestedSchema"`
+}
+
+func (x *ElicitParams) isParams()              {}
+func (x *ElicitParams) GetProgressToken() any  { return getProgressToken(x) }
+func (x *ElicitParams) SetProgressToken(t any) { setProgressToken(x, t) }
+
+// The client's response to an elicitation/create request from the server.
+type ElicitResult struct {
+	// This property is reserved by the protocol to allow clients and servers to
+	// attach additional metadata to their responses.
+	Meta `json:"_meta,omitempty"`
+	// The user's response to the request: "accept" if they submitted the
+	// requested information, "decline" if they explicitly declined, or
+	// "cancel" if they dismissed the request without making a choice.
+	Action string `json:"action"`
+	// The submitted information, present only when Action is "accept". It
+	// matches the requested schema.
+	Content map[string]any `json:"content,omitempty"`
+}
+
+func (*ElicitResult) isResult() {}
 
 // An Implementation describes the name and version of an MCP implementation, with an optional
 // title for UI representation.
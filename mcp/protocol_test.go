@@ -10,6 +10,8 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/jsonschema-go/jsonschema"
 )
 
 func TestParamsMeta(t *testing.T) {
@@ -487,6 +489,75 @@ func TestCompleteResult(t *testing.T) {
 	}
 }
 
+func TestElicitParams(t *testing.T) {
+	in := &ElicitParams{
+		Message: "Enter the code",
+		RequestedSchema: &jsonschema.Schema{
+			Type:       "object",
+			Properties: map[string]*jsonschema.Schema{"code": {Type: "string"}},
+			Required:   []string{"code"},
+		},
+	}
+	want := `{"message":"Enter the code","requestedSchema":{"type":"object","required":["code"],"properties":{"code":{"type":"string"}}}}`
+	got, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("json.Marshal(ElicitParams) failed: %v", err)
+	}
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("ElicitParams marshal mismatch (-want +got):\n%s", diff)
+	}
+
+	var out ElicitParams
+	if err := json.Unmarshal(got, &out); err != nil {
+		t.Fatalf("json.Unmarshal(ElicitParams) failed: %v", err)
+	}
+	if diff := cmp.Diff(in, &out, cmpopts.IgnoreUnexported(jsonschema.Schema{})); diff != "" {
+		t.Errorf("ElicitParams round trip mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestElicitResult(t *testing.T) {
+	tests := []struct {
+		name string
+		in   ElicitResult
+		want string
+	}{
+		{
+			name: "Accept",
+			in:   ElicitResult{Action: "accept", Content: map[string]any{"code": "123456", "remember": true}},
+			want: `{"action":"accept","content":{"code":"123456","remember":true}}`,
+		},
+		{
+			name: "Decline",
+			in:   ElicitResult{Action: "decline"},
+			want: `{"action":"decline"}`,
+		},
+		{
+			name: "Cancel",
+			in:   ElicitResult{Action: "cancel"},
+			want: `{"action":"cancel"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := json.Marshal(&test.in)
+			if err != nil {
+				t.Fatalf("json.Marshal(ElicitResult) failed: %v", err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("ElicitResult marshal mismatch (-want +got):\n%s", diff)
+			}
+			var out ElicitResult
+			if err := json.Unmarshal(got, &out); err != nil {
+				t.Fatalf("json.Unmarshal(ElicitResult) failed: %v", err)
+			}
+			if diff := cmp.Diff(test.in, out); diff != "" {
+				t.Errorf("ElicitResult unmarshal mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestContentUnmarshal(t *testing.T) {
 	// Verify that types with a Content field round-trip properly.
 	roundtrip := func(in, out any) {